@@ -560,6 +560,25 @@ func TestDefaultFunctionParsing(t *testing.T) {
 	}
 }
 
+func TestStateMutabilityParsing(t *testing.T) {
+	const definition = `[
+	{ "type" : "function", "name" : "legacy", "constant" : true },
+	{ "type" : "function", "name" : "view", "stateMutability" : "view" },
+	{ "type" : "function", "name" : "pure", "stateMutability" : "pure" },
+	{ "type" : "function", "name" : "nonpayable", "stateMutability" : "nonpayable" },
+	{ "type" : "function", "name" : "payable", "stateMutability" : "payable" }]`
+
+	abi, err := JSON(strings.NewReader(definition))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]bool{"legacy": true, "view": true, "pure": true, "nonpayable": false, "payable": false} {
+		if have := abi.Methods[name].Const; have != want {
+			t.Errorf("method %s: constant mismatch: have %v, want %v", name, have, want)
+		}
+	}
+}
+
 func TestBareEvents(t *testing.T) {
 	const definition = `[
 	{ "type" : "event", "name" : "balance" },
@@ -128,6 +128,19 @@ type Wallet interface {
 	SignTxWithPassphrase(account Account, passphrase string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
 }
 
+// TypedHashSigner is an optional interface for wallets which refuse to sign
+// arbitrary hashes (e.g. hardware wallets), but are able to sign EIP-712 typed
+// data given its domain separator and the hash of its message, showing both to
+// the user for confirmation.
+type TypedHashSigner interface {
+	// SignTypedHash requests the wallet to sign the EIP-712 payload assembled
+	// from the given domain separator and message hash.
+	//
+	// The returned signature is in the same [R || S || V] format as the one from
+	// SignHash, where V is 0 or 1.
+	SignTypedHash(account Account, domainSeparator, structHash []byte) ([]byte, error)
+}
+
 // Backend is a "wallet provider" that may contain a batch of accounts they can
 // sign transactions with and upon request, do so.
 type Backend interface {
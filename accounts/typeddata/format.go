@@ -0,0 +1,175 @@
+// Copyright 2026 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package typeddata
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// NameValueType is a single human readable item of typed data, used to show
+// what is being signed. The value is either a formatted string for atomic and
+// dynamic types, or a list of nested items for structs and arrays.
+type NameValueType struct {
+	Name  string      `json:"name"`
+	Value interface{} `json:"value"`
+	Typ   string      `json:"type"`
+}
+
+// Pprint returns an indented, multi line rendering of the item.
+func (nvt *NameValueType) Pprint(depth int) string {
+	output := bytes.Buffer{}
+	output.WriteString(strings.Repeat(" ", depth*2))
+	output.WriteString(fmt.Sprintf("%s [%s]:", nvt.Name, nvt.Typ))
+	if nvts, ok := nvt.Value.([]*NameValueType); ok {
+		output.WriteString("\n")
+		for _, next := range nvts {
+			output.WriteString(next.Pprint(depth + 1))
+		}
+	} else {
+		output.WriteString(fmt.Sprintf(" %v\n", nvt.Value))
+	}
+	return output.String()
+}
+
+// Format validates the typed data and returns a human readable representation
+// of both the domain and the message, with values formatted according to their
+// declared types.
+func (t *TypedData) Format() ([]*NameValueType, error) {
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+	domain, err := t.formatData(DomainType, t.Domain.Map(t.Types[DomainType]), 0)
+	if err != nil {
+		return nil, err
+	}
+	message, err := t.formatData(t.PrimaryType, t.Message, 0)
+	if err != nil {
+		return nil, err
+	}
+	return []*NameValueType{
+		{Name: DomainType, Typ: "domain", Value: domain},
+		{Name: t.PrimaryType, Typ: "primary type", Value: message},
+	}, nil
+}
+
+// formatData formats the members of a struct value.
+func (t *TypedData) formatData(primaryType string, data Message, depth int) ([]*NameValueType, error) {
+	if depth > maxDepth {
+		return nil, errMaxDepth
+	}
+	var output []*NameValueType
+	for _, field := range t.Types[primaryType] {
+		value, ok := data[field.Name]
+		if !ok {
+			return nil, fmt.Errorf("%s lacks field %q", primaryType, field.Name)
+		}
+		item, err := t.formatValue(field.Name, field.Type, value, depth)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %v", primaryType, field.Name, err)
+		}
+		output = append(output, item)
+	}
+	return output, nil
+}
+
+// formatValue formats a single member value, recursing into arrays and structs.
+func (t *TypedData) formatValue(name, typ string, value interface{}, depth int) (*NameValueType, error) {
+	item := &NameValueType{Name: name, Typ: typ}
+
+	if base, _, err := parseArray(typ); err != nil {
+		return nil, err
+	} else if base != typ {
+		items, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected array for type %s, got %T", typ, value)
+		}
+		list := make([]*NameValueType, 0, len(items))
+		for i, elem := range items {
+			formatted, err := t.formatValue(fmt.Sprintf("[%d]", i), base, elem, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, formatted)
+		}
+		item.Value = list
+		return item, nil
+	}
+	if _, ok := t.Types[typ]; ok {
+		data, ok := asMessage(value)
+		if !ok {
+			return nil, fmt.Errorf("expected object for type %s, got %T", typ, value)
+		}
+		fields, err := t.formatData(typ, data, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		item.Value = fields
+		return item, nil
+	}
+	formatted, err := formatPrimitive(typ, value)
+	if err != nil {
+		return nil, err
+	}
+	item.Value = formatted
+	return item, nil
+}
+
+// formatPrimitive formats an atomic or dynamic value in its canonical form:
+// checksummed addresses, decimal integers and hex encoded bytes.
+func formatPrimitive(typ string, value interface{}) (string, error) {
+	switch typ {
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return "", fmt.Errorf("expected string, got %T", value)
+		}
+		return fmt.Sprintf("%q", str), nil
+
+	case "bool":
+		flag, ok := value.(bool)
+		if !ok {
+			return "", fmt.Errorf("expected bool, got %T", value)
+		}
+		return fmt.Sprintf("%t", flag), nil
+
+	case "address":
+		addr, err := parseAddress(value)
+		if err != nil {
+			return "", err
+		}
+		return addr.Hex(), nil
+	}
+	if _, ok := fixedBytesSize(typ); ok || typ == "bytes" {
+		blob, err := parseBytes(value)
+		if err != nil {
+			return "", err
+		}
+		return hexutil.Encode(blob), nil
+	}
+	if _, _, ok := integerSize(typ); ok {
+		num, err := parseInteger(typ, value)
+		if err != nil {
+			return "", err
+		}
+		return num.String(), nil
+	}
+	return "", fmt.Errorf("unknown type %q", typ)
+}
@@ -0,0 +1,654 @@
+// Copyright 2026 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package typeddata implements the hashing and encoding of typed structured
+// data, as specified by EIP-712.
+//
+// The specification can be found at https://eips.ethereum.org/EIPS/eip-712.
+package typeddata
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// DomainType is the name of the type describing the signing domain, which must
+// be present in every typed data definition.
+const DomainType = "EIP712Domain"
+
+// maxDepth is the maximum nesting of structs and arrays accepted in a message,
+// protecting the encoder against maliciously deep inputs.
+const maxDepth = 32
+
+var (
+	errMissingDomainType  = errors.New("typed data lacks the " + DomainType + " type")
+	errMissingPrimaryType = errors.New("typed data lacks a primary type")
+	errMaxDepth           = errors.New("typed data is nested too deep")
+)
+
+// identifierRegexp matches the names accepted for struct types and fields.
+var identifierRegexp = regexp.MustCompile(`^[a-zA-Z_$][a-zA-Z_$0-9]*$`)
+
+// Type is a single member of a struct type: a field name and its type.
+type Type struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Types maps struct type names to their ordered list of members.
+type Types map[string][]Type
+
+// Message is the JSON representation of a struct value, mapping member names
+// to their values.
+type Message map[string]interface{}
+
+// Domain is the EIP-712 domain, separating the signatures of one application
+// from those of any other. Only the fields declared in the EIP712Domain type
+// take part in the domain separator.
+type Domain struct {
+	Name              string   `json:"name,omitempty"`
+	Version           string   `json:"version,omitempty"`
+	ChainId           *big.Int `json:"chainId,omitempty"`
+	VerifyingContract string   `json:"verifyingContract,omitempty"`
+	Salt              string   `json:"salt,omitempty"`
+}
+
+// domainFields lists the members a domain may contain, along with their types.
+var domainFields = map[string]string{
+	"name":              "string",
+	"version":           "string",
+	"chainId":           "uint256",
+	"verifyingContract": "address",
+	"salt":              "bytes32",
+}
+
+// Map returns the fields set in the domain as a message, ready to be hashed as
+// an EIP712Domain struct with the given members. Since the empty string is a
+// valid name and version, those are included whenever they are declared.
+func (d *Domain) Map(fields []Type) Message {
+	declared := make(map[string]bool)
+	for _, field := range fields {
+		declared[field.Name] = true
+	}
+	msg := make(Message)
+	if d.Name != "" || declared["name"] {
+		msg["name"] = d.Name
+	}
+	if d.Version != "" || declared["version"] {
+		msg["version"] = d.Version
+	}
+	if d.ChainId != nil {
+		msg["chainId"] = d.ChainId
+	}
+	if d.VerifyingContract != "" {
+		msg["verifyingContract"] = d.VerifyingContract
+	}
+	if d.Salt != "" {
+		msg["salt"] = d.Salt
+	}
+	return msg
+}
+
+// UnmarshalJSON implements json.Unmarshaler, accepting the chain id both as a
+// JSON number and as a decimal or hexadecimal string.
+func (d *Domain) UnmarshalJSON(input []byte) error {
+	var dec struct {
+		Name              string      `json:"name"`
+		Version           string      `json:"version"`
+		ChainId           interface{} `json:"chainId"`
+		VerifyingContract string      `json:"verifyingContract"`
+		Salt              string      `json:"salt"`
+	}
+	decoder := json.NewDecoder(bytes.NewReader(input))
+	decoder.UseNumber()
+	if err := decoder.Decode(&dec); err != nil {
+		return err
+	}
+	*d = Domain{
+		Name:              dec.Name,
+		Version:           dec.Version,
+		VerifyingContract: dec.VerifyingContract,
+		Salt:              dec.Salt,
+	}
+	if dec.ChainId != nil {
+		id, err := parseInteger("uint256", dec.ChainId)
+		if err != nil {
+			return fmt.Errorf("invalid chainId: %v", err)
+		}
+		d.ChainId = id
+	}
+	return nil
+}
+
+// TypedData is a request to sign typed structured data: the type definitions,
+// the signing domain and the message itself.
+type TypedData struct {
+	Types       Types   `json:"types"`
+	PrimaryType string  `json:"primaryType"`
+	Domain      Domain  `json:"domain"`
+	Message     Message `json:"message"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler. Numbers within the message are
+// kept in their textual form to avoid losing precision on large integers.
+func (t *TypedData) UnmarshalJSON(input []byte) error {
+	var dec struct {
+		Types       Types           `json:"types"`
+		PrimaryType string          `json:"primaryType"`
+		Domain      Domain          `json:"domain"`
+		Message     json.RawMessage `json:"message"`
+	}
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+	var msg Message
+	if len(dec.Message) > 0 {
+		decoder := json.NewDecoder(bytes.NewReader(dec.Message))
+		decoder.UseNumber()
+		if err := decoder.Decode(&msg); err != nil {
+			return fmt.Errorf("invalid message: %v", err)
+		}
+	}
+	*t = TypedData{
+		Types:       dec.Types,
+		PrimaryType: dec.PrimaryType,
+		Domain:      dec.Domain,
+		Message:     msg,
+	}
+	return nil
+}
+
+// Validate checks that the type definitions are well formed, that the primary
+// type is defined and that the domain matches its declared type.
+func (t *TypedData) Validate() error {
+	if _, ok := t.Types[DomainType]; !ok {
+		return errMissingDomainType
+	}
+	if t.PrimaryType == "" {
+		return errMissingPrimaryType
+	}
+	if _, ok := t.Types[t.PrimaryType]; !ok {
+		return fmt.Errorf("primary type %q is not defined", t.PrimaryType)
+	}
+	for name, fields := range t.Types {
+		if !identifierRegexp.MatchString(name) {
+			return fmt.Errorf("invalid type name %q", name)
+		}
+		if isPrimitive(name) {
+			return fmt.Errorf("type name %q shadows an atomic type", name)
+		}
+		seen := make(map[string]bool)
+		for _, field := range fields {
+			if !identifierRegexp.MatchString(field.Name) {
+				return fmt.Errorf("invalid field name %q in type %s", field.Name, name)
+			}
+			if seen[field.Name] {
+				return fmt.Errorf("duplicate field %q in type %s", field.Name, name)
+			}
+			seen[field.Name] = true
+
+			if err := t.validateType(field.Type); err != nil {
+				return fmt.Errorf("field %s.%s: %v", name, field.Name, err)
+			}
+		}
+	}
+	return t.validateDomain()
+}
+
+// validateType checks that a field type is either atomic, dynamic, a defined
+// struct type or an array of any of these.
+func (t *TypedData) validateType(typ string) error {
+	base, _, err := parseArray(typ)
+	if err != nil {
+		return err
+	}
+	if base != typ {
+		return t.validateType(base)
+	}
+	if _, ok := t.Types[typ]; ok {
+		return nil
+	}
+	if !isPrimitive(typ) {
+		return fmt.Errorf("unknown type %q", typ)
+	}
+	return nil
+}
+
+// validateDomain checks that the declared EIP712Domain type only contains the
+// standard domain fields, and that exactly those fields are set in the domain.
+func (t *TypedData) validateDomain() error {
+	declared := make(map[string]bool)
+	for _, field := range t.Types[DomainType] {
+		typ, ok := domainFields[field.Name]
+		if !ok {
+			return fmt.Errorf("unsupported domain field %q", field.Name)
+		}
+		if field.Type != typ {
+			return fmt.Errorf("domain field %q must be of type %s, not %s", field.Name, typ, field.Type)
+		}
+		declared[field.Name] = true
+	}
+	domain := t.Domain.Map(t.Types[DomainType])
+	for name := range declared {
+		if _, ok := domain[name]; !ok {
+			return fmt.Errorf("domain lacks declared field %q", name)
+		}
+	}
+	for name := range domain {
+		if !declared[name] {
+			return fmt.Errorf("domain field %q is not declared in %s", name, DomainType)
+		}
+	}
+	return nil
+}
+
+// Dependencies returns the struct types referenced by the given type, directly
+// or transitively, including the type itself. The found slice accumulates the
+// types already visited, which also terminates cyclic definitions.
+func (t *TypedData) Dependencies(primaryType string, found []string) []string {
+	primaryType, _, _ = parseArray(primaryType)
+	for _, dep := range found {
+		if dep == primaryType {
+			return found
+		}
+	}
+	if _, ok := t.Types[primaryType]; !ok {
+		return found
+	}
+	found = append(found, primaryType)
+	for _, field := range t.Types[primaryType] {
+		found = t.Dependencies(field.Type, found)
+	}
+	return found
+}
+
+// EncodeType returns the canonical signature of a struct type: the type itself
+// followed by all its dependencies in alphabetical order, e.g.
+//
+//	Mail(Person from,Person to,string contents)Person(string name,address wallet)
+func (t *TypedData) EncodeType(primaryType string) string {
+	deps := t.Dependencies(primaryType, nil)
+	if len(deps) == 0 {
+		return ""
+	}
+	sort.Strings(deps[1:])
+
+	var buffer bytes.Buffer
+	for _, dep := range deps {
+		buffer.WriteString(dep)
+		buffer.WriteString("(")
+		for i, field := range t.Types[dep] {
+			if i > 0 {
+				buffer.WriteString(",")
+			}
+			buffer.WriteString(field.Type)
+			buffer.WriteString(" ")
+			buffer.WriteString(field.Name)
+		}
+		buffer.WriteString(")")
+	}
+	return buffer.String()
+}
+
+// TypeHash returns the keccak256 hash of the encoded struct type.
+func (t *TypedData) TypeHash(primaryType string) common.Hash {
+	return crypto.Keccak256Hash([]byte(t.EncodeType(primaryType)))
+}
+
+// EncodeData returns the encoding of a struct value: its type hash followed by
+// the 32 byte encoding of each member, in declaration order.
+func (t *TypedData) EncodeData(primaryType string, data Message) ([]byte, error) {
+	return t.encodeData(primaryType, data, 0)
+}
+
+func (t *TypedData) encodeData(primaryType string, data Message, depth int) ([]byte, error) {
+	if depth > maxDepth {
+		return nil, errMaxDepth
+	}
+	fields, ok := t.Types[primaryType]
+	if !ok {
+		return nil, fmt.Errorf("unknown struct type %q", primaryType)
+	}
+	if len(data) > len(fields) {
+		for name := range data {
+			if !hasField(fields, name) {
+				return nil, fmt.Errorf("%s has no field %q", primaryType, name)
+			}
+		}
+	}
+	buffer := bytes.NewBuffer(nil)
+	buffer.Write(t.TypeHash(primaryType).Bytes())
+
+	for _, field := range fields {
+		value, ok := data[field.Name]
+		if !ok {
+			return nil, fmt.Errorf("%s lacks field %q", primaryType, field.Name)
+		}
+		enc, err := t.encodeValue(field.Type, value, depth)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %v", primaryType, field.Name, err)
+		}
+		buffer.Write(enc)
+	}
+	return buffer.Bytes(), nil
+}
+
+// HashStruct returns the keccak256 hash of the encoded struct value.
+func (t *TypedData) HashStruct(primaryType string, data Message) (common.Hash, error) {
+	enc, err := t.EncodeData(primaryType, data)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return crypto.Keccak256Hash(enc), nil
+}
+
+// DomainSeparator returns the hash of the signing domain.
+func (t *TypedData) DomainSeparator() (common.Hash, error) {
+	return t.HashStruct(DomainType, t.Domain.Map(t.Types[DomainType]))
+}
+
+// Hashes validates the typed data and returns the two hashes making up the
+// signed payload: the domain separator and the hash of the message.
+func (t *TypedData) Hashes() (domainSeparator common.Hash, structHash common.Hash, err error) {
+	if err = t.Validate(); err != nil {
+		return common.Hash{}, common.Hash{}, err
+	}
+	if domainSeparator, err = t.DomainSeparator(); err != nil {
+		return common.Hash{}, common.Hash{}, fmt.Errorf("invalid domain: %v", err)
+	}
+	if structHash, err = t.HashStruct(t.PrimaryType, t.Message); err != nil {
+		return common.Hash{}, common.Hash{}, fmt.Errorf("invalid message: %v", err)
+	}
+	return domainSeparator, structHash, nil
+}
+
+// SigHash returns the hash to be signed for the given domain separator and
+// message hash, calculated as
+//
+//	keccak256("\x19\x01" ‖ domainSeparator ‖ hashStruct(message)).
+func SigHash(domainSeparator, structHash common.Hash) []byte {
+	return crypto.Keccak256([]byte{0x19, 0x01}, domainSeparator.Bytes(), structHash.Bytes())
+}
+
+// encodeValue returns the 32 byte encoding of a single member value.
+func (t *TypedData) encodeValue(typ string, value interface{}, depth int) ([]byte, error) {
+	// Arrays are encoded as the hash of their concatenated elements
+	if base, size, err := parseArray(typ); err != nil {
+		return nil, err
+	} else if base != typ {
+		items, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected array for type %s, got %T", typ, value)
+		}
+		if size >= 0 && len(items) != size {
+			return nil, fmt.Errorf("expected %d items for type %s, got %d", size, typ, len(items))
+		}
+		if depth+1 > maxDepth {
+			return nil, errMaxDepth
+		}
+		buffer := bytes.NewBuffer(nil)
+		for i, item := range items {
+			enc, err := t.encodeValue(base, item, depth+1)
+			if err != nil {
+				return nil, fmt.Errorf("item %d: %v", i, err)
+			}
+			buffer.Write(enc)
+		}
+		return crypto.Keccak256(buffer.Bytes()), nil
+	}
+	// Structs are encoded as their hash
+	if _, ok := t.Types[typ]; ok {
+		data, ok := asMessage(value)
+		if !ok {
+			return nil, fmt.Errorf("expected object for type %s, got %T", typ, value)
+		}
+		enc, err := t.encodeData(typ, data, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		return crypto.Keccak256(enc), nil
+	}
+	// Dynamic types are encoded as the hash of their contents
+	switch typ {
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected string, got %T", value)
+		}
+		return crypto.Keccak256([]byte(str)), nil
+
+	case "bytes":
+		blob, err := parseBytes(value)
+		if err != nil {
+			return nil, err
+		}
+		return crypto.Keccak256(blob), nil
+
+	case "bool":
+		flag, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("expected bool, got %T", value)
+		}
+		enc := make([]byte, 32)
+		if flag {
+			enc[31] = 1
+		}
+		return enc, nil
+
+	case "address":
+		addr, err := parseAddress(value)
+		if err != nil {
+			return nil, err
+		}
+		return common.LeftPadBytes(addr.Bytes(), 32), nil
+	}
+	if size, ok := fixedBytesSize(typ); ok {
+		blob, err := parseBytes(value)
+		if err != nil {
+			return nil, err
+		}
+		if len(blob) > size {
+			return nil, fmt.Errorf("expected at most %d bytes for type %s, got %d", size, typ, len(blob))
+		}
+		return common.RightPadBytes(blob, 32), nil
+	}
+	if _, _, ok := integerSize(typ); ok {
+		num, err := parseInteger(typ, value)
+		if err != nil {
+			return nil, err
+		}
+		return math.PaddedBigBytes(math.U256(num), 32), nil
+	}
+	return nil, fmt.Errorf("unknown type %q", typ)
+}
+
+// isPrimitive returns whether the type is one of the atomic or dynamic types.
+func isPrimitive(typ string) bool {
+	switch typ {
+	case "string", "bytes", "bool", "address":
+		return true
+	}
+	if _, ok := fixedBytesSize(typ); ok {
+		return true
+	}
+	_, _, ok := integerSize(typ)
+	return ok
+}
+
+// parseArray splits an array type into its element type and length, where the
+// length of dynamic arrays is -1. Non array types are returned as is.
+func parseArray(typ string) (string, int, error) {
+	if !strings.HasSuffix(typ, "]") {
+		return typ, 0, nil
+	}
+	open := strings.LastIndex(typ, "[")
+	if open <= 0 {
+		return "", 0, fmt.Errorf("invalid array type %q", typ)
+	}
+	base, size := typ[:open], typ[open+1:len(typ)-1]
+	if size == "" {
+		return base, -1, nil
+	}
+	n, err := strconv.Atoi(size)
+	if err != nil || n <= 0 {
+		return "", 0, fmt.Errorf("invalid array length in %q", typ)
+	}
+	return base, n, nil
+}
+
+// fixedBytesSize returns the length of a bytes1 to bytes32 type.
+func fixedBytesSize(typ string) (int, bool) {
+	if !strings.HasPrefix(typ, "bytes") || typ == "bytes" {
+		return 0, false
+	}
+	size, err := strconv.Atoi(typ[5:])
+	if err != nil || size < 1 || size > 32 || strconv.Itoa(size) != typ[5:] {
+		return 0, false
+	}
+	return size, true
+}
+
+// integerSize returns the bit size and signedness of an intN or uintN type.
+func integerSize(typ string) (int, bool, bool) {
+	signed := true
+	if strings.HasPrefix(typ, "uint") {
+		typ, signed = typ[4:], false
+	} else if strings.HasPrefix(typ, "int") {
+		typ = typ[3:]
+	} else {
+		return 0, false, false
+	}
+	bits, err := strconv.Atoi(typ)
+	if err != nil || bits < 8 || bits > 256 || bits%8 != 0 || strconv.Itoa(bits) != typ {
+		return 0, false, false
+	}
+	return bits, signed, true
+}
+
+// parseInteger converts a JSON number, a decimal or hexadecimal string or a
+// big integer into a value of the given integer type, checking its range.
+func parseInteger(typ string, value interface{}) (*big.Int, error) {
+	bits, signed, ok := integerSize(typ)
+	if !ok {
+		return nil, fmt.Errorf("invalid integer type %q", typ)
+	}
+	var num *big.Int
+	switch v := value.(type) {
+	case *big.Int:
+		num = new(big.Int).Set(v)
+	case json.Number:
+		if num, ok = new(big.Int).SetString(string(v), 10); !ok {
+			return nil, fmt.Errorf("invalid integer %s", v)
+		}
+	case float64:
+		if v != float64(int64(v)) || v > 1<<53 || v < -(1<<53) {
+			return nil, fmt.Errorf("imprecise integer %v, use a string instead", v)
+		}
+		num = big.NewInt(int64(v))
+	case int:
+		num = big.NewInt(int64(v))
+	case int64:
+		num = big.NewInt(v)
+	case uint64:
+		num = new(big.Int).SetUint64(v)
+	case string:
+		str, negative := v, false
+		if strings.HasPrefix(str, "-") {
+			str, negative = str[1:], true
+		}
+		if num, ok = math.ParseBig256(str); !ok || str == "" {
+			return nil, fmt.Errorf("invalid integer %q", v)
+		}
+		if negative {
+			num.Neg(num)
+		}
+	default:
+		return nil, fmt.Errorf("expected integer, got %T", value)
+	}
+	if signed {
+		limit := new(big.Int).Lsh(common.Big1, uint(bits-1))
+		if num.Cmp(new(big.Int).Neg(limit)) < 0 || num.Cmp(limit) >= 0 {
+			return nil, fmt.Errorf("integer %v overflows %s", num, typ)
+		}
+	} else if num.Sign() < 0 || num.BitLen() > bits {
+		return nil, fmt.Errorf("integer %v overflows %s", num, typ)
+	}
+	return num, nil
+}
+
+// parseBytes converts a hex string or a byte slice into raw bytes.
+func parseBytes(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case []byte:
+		return v, nil
+	case hexutil.Bytes:
+		return v, nil
+	case string:
+		blob, err := hexutil.Decode(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bytes %q: %v", v, err)
+		}
+		return blob, nil
+	}
+	return nil, fmt.Errorf("expected hex bytes, got %T", value)
+}
+
+// parseAddress converts a hex string or an address into an address.
+func parseAddress(value interface{}) (common.Address, error) {
+	switch v := value.(type) {
+	case common.Address:
+		return v, nil
+	case string:
+		if !strings.HasPrefix(v, "0x") || !common.IsHexAddress(v) {
+			return common.Address{}, fmt.Errorf("invalid address %q", v)
+		}
+		return common.HexToAddress(v), nil
+	}
+	return common.Address{}, fmt.Errorf("expected address, got %T", value)
+}
+
+// asMessage converts a decoded JSON object into a message.
+func asMessage(value interface{}) (Message, bool) {
+	switch v := value.(type) {
+	case Message:
+		return v, true
+	case map[string]interface{}:
+		return Message(v), true
+	}
+	return nil, false
+}
+
+// hasField returns whether a member with the given name exists in the list.
+func hasField(fields []Type, name string) bool {
+	for _, field := range fields {
+		if field.Name == name {
+			return true
+		}
+	}
+	return false
+}
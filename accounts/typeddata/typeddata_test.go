@@ -0,0 +1,321 @@
+// Copyright 2026 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package typeddata
+
+import (
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// mailJSON is the example message of the EIP-712 specification.
+const mailJSON = `{
+	"types": {
+		"EIP712Domain": [
+			{"name": "name", "type": "string"},
+			{"name": "version", "type": "string"},
+			{"name": "chainId", "type": "uint256"},
+			{"name": "verifyingContract", "type": "address"}
+		],
+		"Person": [
+			{"name": "name", "type": "string"},
+			{"name": "wallet", "type": "address"}
+		],
+		"Mail": [
+			{"name": "from", "type": "Person"},
+			{"name": "to", "type": "Person"},
+			{"name": "contents", "type": "string"}
+		]
+	},
+	"primaryType": "Mail",
+	"domain": {
+		"name": "Ether Mail",
+		"version": "1",
+		"chainId": 1,
+		"verifyingContract": "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC"
+	},
+	"message": {
+		"from": {
+			"name": "Cow",
+			"wallet": "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826"
+		},
+		"to": {
+			"name": "Bob",
+			"wallet": "0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB"
+		},
+		"contents": "Hello, Bob!"
+	}
+}`
+
+func loadMail(t *testing.T) *TypedData {
+	var typed TypedData
+	if err := json.Unmarshal([]byte(mailJSON), &typed); err != nil {
+		t.Fatalf("failed to decode typed data: %v", err)
+	}
+	return &typed
+}
+
+// Tests the encoding and hashing against the test vectors of the specification.
+func TestEIP712Vectors(t *testing.T) {
+	typed := loadMail(t)
+
+	if have, want := typed.EncodeType("Mail"), "Mail(Person from,Person to,string contents)Person(string name,address wallet)"; have != want {
+		t.Fatalf("encoded type mismatch: have %s, want %s", have, want)
+	}
+	if have, want := typed.TypeHash("Mail").Hex(), "0xa0cedeb2dc280ba39b857546d74f5549c3a1d7bdc2dd96bf881f76108e23dac2"; have != want {
+		t.Fatalf("type hash mismatch: have %s, want %s", have, want)
+	}
+	enc, err := typed.EncodeData("Mail", typed.Message)
+	if err != nil {
+		t.Fatalf("failed to encode message: %v", err)
+	}
+	want := "0xa0cedeb2dc280ba39b857546d74f5549c3a1d7bdc2dd96bf881f76108e23dac2fc71e5fa27ff56c350aa531bc129ebdf613b772b6604664f5d8dbe21b85eb0c8cd54f074a4af31b4411ff6a60c9719dbd559c221c8ac3492d9d872b041d703d1b5aadf3154a261abdd9086fc627b61efca26ae5702701d05cd2305f7c52a2fc8"
+	if have := hexutil.Encode(enc); have != want {
+		t.Fatalf("encoded data mismatch: have %s, want %s", have, want)
+	}
+	domainSeparator, structHash, err := typed.Hashes()
+	if err != nil {
+		t.Fatalf("failed to hash typed data: %v", err)
+	}
+	if have, want := domainSeparator.Hex(), "0xf2cee375fa42b42143804025fc449deafd50cc031ca257e0b194a650a912090f"; have != want {
+		t.Fatalf("domain separator mismatch: have %s, want %s", have, want)
+	}
+	if have, want := structHash.Hex(), "0xc52c0ee5d84264471806290a3f2c4cecfc5490626bf912d01f240d7a274b371e"; have != want {
+		t.Fatalf("struct hash mismatch: have %s, want %s", have, want)
+	}
+	sighash := SigHash(domainSeparator, structHash)
+	if have, want := hexutil.Encode(sighash), "0xbe609aee343fb3c4b28e1df9e632fca64fcfaede20f02e86244efddf30957bd2"; have != want {
+		t.Fatalf("signing hash mismatch: have %s, want %s", have, want)
+	}
+	// Sign with the key of the example and check the signature of the specification
+	key, _ := crypto.ToECDSA(crypto.Keccak256([]byte("cow")))
+	if have, want := crypto.PubkeyToAddress(key.PublicKey), common.HexToAddress("0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826"); have != want {
+		t.Fatalf("signer mismatch: have %x, want %x", have, want)
+	}
+	sig, err := crypto.Sign(sighash, key)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	if have, want := hexutil.Encode(sig[:32]), "0x4355c47d63924e8a72e509b65029052eb6c299d53a04e167c5775fd466751c9d"; have != want {
+		t.Fatalf("signature R mismatch: have %s, want %s", have, want)
+	}
+	if have, want := hexutil.Encode(sig[32:64]), "0x07299936d304c153f6443dfa05f40ff007d72911b6f72307f996231605b91562"; have != want {
+		t.Fatalf("signature S mismatch: have %s, want %s", have, want)
+	}
+	if have, want := sig[64]+27, byte(28); have != want {
+		t.Fatalf("signature V mismatch: have %d, want %d", have, want)
+	}
+}
+
+// Tests that atomic, array and nested values are encoded as specified.
+func TestEncodeValues(t *testing.T) {
+	typed := &TypedData{
+		Types: Types{
+			"Item": {{Name: "id", Type: "uint8"}},
+		},
+	}
+	tests := []struct {
+		typ   string
+		value interface{}
+		want  string
+	}{
+		{"bool", true, "0x0000000000000000000000000000000000000000000000000000000000000001"},
+		{"uint256", json.Number("1"), "0x0000000000000000000000000000000000000000000000000000000000000001"},
+		{"uint256", "0xff", "0x00000000000000000000000000000000000000000000000000000000000000ff"},
+		{"int8", "-1", "0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"},
+		{"int256", big.NewInt(-2), "0xfffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffe"},
+		{"bytes4", "0xdeadbeef", "0xdeadbeef00000000000000000000000000000000000000000000000000000000"},
+		{"address", "0x000000000000000000000000000000000000dEaD", "0x000000000000000000000000000000000000000000000000000000000000dead"},
+		{"string", "", hexutil.Encode(crypto.Keccak256(nil))},
+		{"bytes", "0x", hexutil.Encode(crypto.Keccak256(nil))},
+		{"uint8[]", []interface{}{json.Number("1"), json.Number("2")}, hexutil.Encode(crypto.Keccak256(
+			common.LeftPadBytes([]byte{1}, 32), common.LeftPadBytes([]byte{2}, 32),
+		))},
+		{"Item[1]", []interface{}{map[string]interface{}{"id": json.Number("1")}}, hexutil.Encode(crypto.Keccak256(
+			crypto.Keccak256(typed.TypeHash("Item").Bytes(), common.LeftPadBytes([]byte{1}, 32)),
+		))},
+	}
+	for i, tt := range tests {
+		enc, err := typed.encodeValue(tt.typ, tt.value, 0)
+		if err != nil {
+			t.Errorf("test %d (%s): failed to encode: %v", i, tt.typ, err)
+			continue
+		}
+		if have := hexutil.Encode(enc); have != tt.want {
+			t.Errorf("test %d (%s): encoding mismatch: have %s, want %s", i, tt.typ, have, tt.want)
+		}
+	}
+}
+
+// Tests that values not fitting their declared type are rejected.
+func TestEncodeInvalidValues(t *testing.T) {
+	typed := &TypedData{Types: Types{"Item": {{Name: "id", Type: "uint8"}}}}
+
+	tests := []struct {
+		typ   string
+		value interface{}
+	}{
+		{"bool", "true"},
+		{"uint8", json.Number("256")},
+		{"uint8", "-1"},
+		{"int8", json.Number("128")},
+		{"uint256", 1.5},
+		{"uint256", "one"},
+		{"bytes2", "0xdeadbeef"},
+		{"bytes", "deadbeef"},
+		{"address", "0xdead"},
+		{"string", json.Number("1")},
+		{"uint8[2]", []interface{}{json.Number("1")}},
+		{"Item", map[string]interface{}{}},
+		{"Item", map[string]interface{}{"id": json.Number("1"), "extra": true}},
+		{"Unknown", "value"},
+	}
+	for i, tt := range tests {
+		if _, err := typed.encodeValue(tt.typ, tt.value, 0); err == nil {
+			t.Errorf("test %d (%s): expected error for %v", i, tt.typ, tt.value)
+		}
+	}
+}
+
+// Tests that malformed type definitions and domains are rejected.
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(*TypedData)
+		err    string
+	}{
+		{"missing domain type", func(td *TypedData) { delete(td.Types, DomainType) }, "lacks the EIP712Domain type"},
+		{"missing primary type", func(td *TypedData) { td.PrimaryType = "" }, "lacks a primary type"},
+		{"undefined primary type", func(td *TypedData) { td.PrimaryType = "Letter" }, "is not defined"},
+		{"unknown field type", func(td *TypedData) { td.Types["Mail"][2].Type = "text" }, "unknown type"},
+		{"invalid array", func(td *TypedData) { td.Types["Mail"][2].Type = "string[0]" }, "invalid array length"},
+		{"duplicate field", func(td *TypedData) { td.Types["Mail"][1].Name = "from" }, "duplicate field"},
+		{"invalid field name", func(td *TypedData) { td.Types["Mail"][2].Name = "con tents" }, "invalid field name"},
+		{"shadowing type", func(td *TypedData) { td.Types["uint256"] = nil }, "shadows an atomic type"},
+		{"undeclared domain field", func(td *TypedData) { td.Domain.Salt = "0x01" }, "is not declared"},
+		{"missing domain field", func(td *TypedData) { td.Domain.ChainId = nil }, "lacks declared field"},
+		{"wrong domain field type", func(td *TypedData) { td.Types[DomainType][2].Type = "uint64" }, "must be of type uint256"},
+	}
+	for _, tt := range tests {
+		typed := loadMail(t)
+		tt.mutate(typed)
+
+		err := typed.Validate()
+		if err == nil {
+			t.Errorf("%s: expected error", tt.name)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: error mismatch: have %q, want %q", tt.name, err, tt.err)
+		}
+	}
+}
+
+// Tests that declared domain fields holding the empty string still take part
+// in the domain separator.
+func TestEmptyDomainFields(t *testing.T) {
+	typed := loadMail(t)
+	typed.Domain.Name, typed.Domain.Version = "", ""
+
+	domainSeparator, _, err := typed.Hashes()
+	if err != nil {
+		t.Fatalf("failed to hash typed data: %v", err)
+	}
+	want, err := typed.HashStruct(DomainType, Message{
+		"name":              "",
+		"version":           "",
+		"chainId":           json.Number("1"),
+		"verifyingContract": "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC",
+	})
+	if err != nil {
+		t.Fatalf("failed to hash domain: %v", err)
+	}
+	if domainSeparator != want {
+		t.Fatalf("domain separator mismatch: have %x, want %x", domainSeparator, want)
+	}
+}
+
+// Tests that typed data referencing itself doesn't loop forever.
+func TestRecursiveTypes(t *testing.T) {
+	typed := &TypedData{
+		Types: Types{
+			"Node": {{Name: "value", Type: "uint8"}, {Name: "children", Type: "Node[]"}},
+		},
+	}
+	if have, want := typed.EncodeType("Node"), "Node(uint8 value,Node[] children)"; have != want {
+		t.Fatalf("encoded type mismatch: have %s, want %s", have, want)
+	}
+	var node interface{} = map[string]interface{}{"value": json.Number("1"), "children": []interface{}{}}
+	for i := 0; i < maxDepth; i++ {
+		node = map[string]interface{}{"value": json.Number("1"), "children": []interface{}{node}}
+	}
+	_, err := typed.EncodeData("Node", node.(map[string]interface{}))
+	if err == nil || !strings.HasSuffix(err.Error(), errMaxDepth.Error()) {
+		t.Fatalf("deep nesting error mismatch: have %v, want %v", err, errMaxDepth)
+	}
+}
+
+// Tests that the chain id of the domain may be given as number or string.
+func TestDomainChainId(t *testing.T) {
+	for _, input := range []string{`{"chainId": 1}`, `{"chainId": "1"}`, `{"chainId": "0x1"}`} {
+		var domain Domain
+		if err := json.Unmarshal([]byte(input), &domain); err != nil {
+			t.Fatalf("%s: failed to decode domain: %v", input, err)
+		}
+		if domain.ChainId == nil || domain.ChainId.Int64() != 1 {
+			t.Fatalf("%s: chain id mismatch: have %v, want 1", input, domain.ChainId)
+		}
+	}
+	var domain Domain
+	if err := json.Unmarshal([]byte(`{"chainId": -1}`), &domain); err == nil {
+		t.Fatalf("expected error for negative chain id")
+	}
+}
+
+// Tests the human readable rendering of typed data.
+func TestFormat(t *testing.T) {
+	items, err := loadMail(t).Format()
+	if err != nil {
+		t.Fatalf("failed to format typed data: %v", err)
+	}
+	var output string
+	for _, item := range items {
+		output += item.Pprint(0)
+	}
+	want := `EIP712Domain [domain]:
+  name [string]: "Ether Mail"
+  version [string]: "1"
+  chainId [uint256]: 1
+  verifyingContract [address]: 0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC
+Mail [primary type]:
+  from [Person]:
+    name [string]: "Cow"
+    wallet [address]: 0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826
+  to [Person]:
+    name [string]: "Bob"
+    wallet [address]: 0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB
+  contents [string]: "Hello, Bob!"
+`
+	if output != want {
+		t.Fatalf("formatted output mismatch:\nhave:\n%s\nwant:\n%s", output, want)
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2026 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// This file contains the wire protocol messages for signing EIP-712 typed data.
+// They are newer than the protocol definitions the generated code was built
+// from, so they are maintained by hand until those are regenerated, mirroring
+// the EthereumSignTypedHash and EthereumTypedDataSignature messages of the
+// SatoshiLabs protobuf specs.
+
+package trezor
+
+import "github.com/golang/protobuf/proto"
+
+const (
+	MessageType_MessageType_EthereumTypedDataSignature MessageType = 468
+	MessageType_MessageType_EthereumSignTypedHash      MessageType = 470
+)
+
+func init() {
+	MessageType_name[int32(MessageType_MessageType_EthereumTypedDataSignature)] = "MessageType_EthereumTypedDataSignature"
+	MessageType_name[int32(MessageType_MessageType_EthereumSignTypedHash)] = "MessageType_EthereumSignTypedHash"
+
+	MessageType_value["MessageType_EthereumTypedDataSignature"] = int32(MessageType_MessageType_EthereumTypedDataSignature)
+	MessageType_value["MessageType_EthereumSignTypedHash"] = int32(MessageType_MessageType_EthereumSignTypedHash)
+
+	proto.RegisterType((*EthereumSignTypedHash)(nil), "EthereumSignTypedHash")
+	proto.RegisterType((*EthereumTypedDataSignature)(nil), "EthereumTypedDataSignature")
+}
+
+// *
+// Request: Ask device to sign the hashes of EIP-712 typed data
+// @next EthereumTypedDataSignature
+// @next Failure
+type EthereumSignTypedHash struct {
+	AddressN            []uint32 `protobuf:"varint,1,rep,name=address_n,json=addressN" json:"address_n,omitempty"`
+	DomainSeparatorHash []byte   `protobuf:"bytes,2,req,name=domain_separator_hash,json=domainSeparatorHash" json:"domain_separator_hash,omitempty"`
+	MessageHash         []byte   `protobuf:"bytes,3,opt,name=message_hash,json=messageHash" json:"message_hash,omitempty"`
+	XXX_unrecognized    []byte   `json:"-"`
+}
+
+func (m *EthereumSignTypedHash) Reset()         { *m = EthereumSignTypedHash{} }
+func (m *EthereumSignTypedHash) String() string { return proto.CompactTextString(m) }
+func (*EthereumSignTypedHash) ProtoMessage()    {}
+
+func (m *EthereumSignTypedHash) GetAddressN() []uint32 {
+	if m != nil {
+		return m.AddressN
+	}
+	return nil
+}
+
+func (m *EthereumSignTypedHash) GetDomainSeparatorHash() []byte {
+	if m != nil {
+		return m.DomainSeparatorHash
+	}
+	return nil
+}
+
+func (m *EthereumSignTypedHash) GetMessageHash() []byte {
+	if m != nil {
+		return m.MessageHash
+	}
+	return nil
+}
+
+// *
+// Response: Signed typed data
+// @prev EthereumSignTypedHash
+type EthereumTypedDataSignature struct {
+	Signature        []byte  `protobuf:"bytes,1,req,name=signature" json:"signature,omitempty"`
+	Address          *string `protobuf:"bytes,2,req,name=address" json:"address,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *EthereumTypedDataSignature) Reset()         { *m = EthereumTypedDataSignature{} }
+func (m *EthereumTypedDataSignature) String() string { return proto.CompactTextString(m) }
+func (*EthereumTypedDataSignature) ProtoMessage()    {}
+
+func (m *EthereumTypedDataSignature) GetSignature() []byte {
+	if m != nil {
+		return m.Signature
+	}
+	return nil
+}
+
+func (m *EthereumTypedDataSignature) GetAddress() string {
+	if m != nil && m.Address != nil {
+		return *m.Address
+	}
+	return ""
+}
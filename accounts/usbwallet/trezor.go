@@ -159,6 +159,15 @@ func (w *trezorDriver) SignTx(path accounts.DerivationPath, tx *types.Transactio
 	return w.trezorSign(path, tx, chainID)
 }
 
+// SignTypedHash implements usbwallet.driver, sending the EIP-712 hashes to the
+// Trezor and waiting for the user to confirm or deny the signature.
+func (w *trezorDriver) SignTypedHash(path accounts.DerivationPath, domainSeparator, structHash []byte) ([]byte, error) {
+	if w.device == nil {
+		return nil, accounts.ErrWalletClosed
+	}
+	return w.trezorSignTypedHash(path, domainSeparator, structHash)
+}
+
 // trezorDerive sends a derivation request to the Trezor device and returns the
 // Ethereum address located on that path.
 func (w *trezorDriver) trezorDerive(derivationPath []uint32) (common.Address, error) {
@@ -235,6 +244,29 @@ func (w *trezorDriver) trezorSign(derivationPath []uint32, tx *types.Transaction
 	return sender, signed, nil
 }
 
+// trezorSignTypedHash sends the EIP-712 domain separator and message hash to the
+// Trezor wallet, and waits for the user to confirm or deny the signature.
+func (w *trezorDriver) trezorSignTypedHash(derivationPath []uint32, domainSeparator, structHash []byte) ([]byte, error) {
+	request := &trezor.EthereumSignTypedHash{
+		AddressN:            derivationPath,
+		DomainSeparatorHash: domainSeparator,
+		MessageHash:         structHash,
+	}
+	response := new(trezor.EthereumTypedDataSignature)
+	if _, err := w.trezorExchange(request, response); err != nil {
+		return nil, err
+	}
+	// Extract the Ethereum signature and do a sanity validation
+	signature := common.CopyBytes(response.GetSignature())
+	if len(signature) != 65 {
+		return nil, errors.New("reply lacks signature")
+	}
+	if signature[64] >= 27 {
+		signature[64] -= 27 // Transform V from 27/28 to 0/1
+	}
+	return signature, nil
+}
+
 // trezorExchange performs a data exchange with the Trezor wallet, sending it a
 // message and retrieving the response. If multiple responses are possible, the
 // method will also return the index of the destination object used.
@@ -29,6 +29,7 @@ import (
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/karalabe/hid"
 )
@@ -66,6 +67,11 @@ type driver interface {
 	// SignTx sends the transaction to the USB device and waits for the user to confirm
 	// or deny the transaction.
 	SignTx(path accounts.DerivationPath, tx *types.Transaction, chainID *big.Int) (common.Address, *types.Transaction, error)
+
+	// SignTypedHash sends the EIP-712 domain separator and message hash to the USB
+	// device and waits for the user to confirm or deny the signature. The returned
+	// signature is in [R || S || V] format with V being 0 or 1.
+	SignTypedHash(path accounts.DerivationPath, domainSeparator, structHash []byte) ([]byte, error)
 }
 
 // wallet represents the common functionality shared by all USB hardware
@@ -553,6 +559,57 @@ func (w *wallet) SignTx(account accounts.Account, tx *types.Transaction, chainID
 	return signed, nil
 }
 
+// SignTypedHash implements accounts.TypedHashSigner. It sends the EIP-712 domain
+// separator and message hash over to the hardware wallet to request a confirmation
+// from the user, returning either the signature or a failure if the user denied
+// the request.
+func (w *wallet) SignTypedHash(account accounts.Account, domainSeparator, structHash []byte) ([]byte, error) {
+	w.stateLock.RLock() // Comms have own mutex, this is for the state fields
+	defer w.stateLock.RUnlock()
+
+	// If the wallet is closed, abort
+	if w.device == nil {
+		return nil, accounts.ErrWalletClosed
+	}
+	// Make sure the requested account is contained within
+	path, ok := w.paths[account.Address]
+	if !ok {
+		return nil, accounts.ErrUnknownAccount
+	}
+	if len(domainSeparator) != 32 || len(structHash) != 32 {
+		return nil, fmt.Errorf("invalid typed data hashes: domain separator %d bytes, message hash %d bytes", len(domainSeparator), len(structHash))
+	}
+	// All infos gathered and metadata checks out, request signing
+	<-w.commsLock
+	defer func() { w.commsLock <- struct{}{} }()
+
+	// Ensure the device isn't screwed with while user confirmation is pending
+	// TODO(karalabe): remove if hotplug lands on Windows
+	w.hub.commsLock.Lock()
+	w.hub.commsPend++
+	w.hub.commsLock.Unlock()
+
+	defer func() {
+		w.hub.commsLock.Lock()
+		w.hub.commsPend--
+		w.hub.commsLock.Unlock()
+	}()
+	// Sign the hashes and verify the signer to avoid hardware fault surprises
+	signature, err := w.driver.SignTypedHash(path, domainSeparator, structHash)
+	if err != nil {
+		return nil, err
+	}
+	hash := crypto.Keccak256([]byte{0x19, 0x01}, domainSeparator, structHash)
+	pubkey, err := crypto.SigToPub(hash, signature)
+	if err != nil {
+		return nil, err
+	}
+	if signer := crypto.PubkeyToAddress(*pubkey); signer != account.Address {
+		return nil, fmt.Errorf("signer mismatch: expected %s, got %s", account.Address.Hex(), signer.Hex())
+	}
+	return signature, nil
+}
+
 // SignHashWithPassphrase implements accounts.Wallet, however signing arbitrary
 // data is not supported for Ledger wallets, so this method will always return
 // an error.
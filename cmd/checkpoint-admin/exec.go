@@ -0,0 +1,216 @@
+// Copyright 2026 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/contracts/checkpointoracle"
+	"github.com/ethereum/go-ethereum/contracts/checkpointoracle/contract"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var commandDeploy = cli.Command{
+	Name:  "deploy",
+	Usage: "Deploy a new checkpoint oracle contract",
+	Description: `
+Deploy a new checkpoint oracle contract with the given trusted signers as
+admins. A checkpoint is only accepted by the contract once at least threshold
+of the signers approved it.
+`,
+	Flags: []cli.Flag{
+		nodeURLFlag,
+		keyFileFlag,
+		passwordFileFlag,
+		signersFlag,
+		thresholdFlag,
+	},
+	Action: utils.MigrateFlags(deploy),
+}
+
+var commandSign = cli.Command{
+	Name:  "sign",
+	Usage: "Sign the checkpoint with the specified key",
+	Description: `
+Sign a checkpoint generated by the local indexers of the remote light server
+with the given key, so that it can be published to the checkpoint oracle.
+The latest local checkpoint is signed if no index is specified.
+`,
+	Flags: []cli.Flag{
+		nodeURLFlag,
+		oracleFlag,
+		indexFlag,
+		keyFileFlag,
+		passwordFileFlag,
+	},
+	Action: utils.MigrateFlags(sign),
+}
+
+var commandPublish = cli.Command{
+	Name:  "publish",
+	Usage: "Publish a checkpoint into the oracle",
+	Description: `
+Publish a checkpoint generated by the local indexers of the remote light server
+into the checkpoint oracle, along with the signatures collected from the
+trusted signers. The transaction must be sent by one of the signers.
+`,
+	Flags: []cli.Flag{
+		nodeURLFlag,
+		oracleFlag,
+		indexFlag,
+		keyFileFlag,
+		passwordFileFlag,
+		signaturesFlag,
+	},
+	Action: utils.MigrateFlags(publish),
+}
+
+// deploy deploys the checkpoint oracle contract.
+//
+// Note: since the section size of the checkpoint is fixed by the light
+// client protocol, it can't be modified after the contract is deployed.
+func deploy(ctx *cli.Context) error {
+	// Gather all the addresses that should be permitted to sign
+	var addrs []common.Address
+	for _, account := range strings.Split(ctx.String(signersFlag.Name), ",") {
+		if trimmed := strings.TrimSpace(account); !common.IsHexAddress(trimmed) {
+			utils.Fatalf("Invalid account in --signers: '%s'", trimmed)
+		} else {
+			addrs = append(addrs, common.HexToAddress(trimmed))
+		}
+	}
+	// Retrieve and validate the signing threshold
+	needed := ctx.Int64(thresholdFlag.Name)
+	if needed <= 0 || needed > int64(len(addrs)) {
+		utils.Fatalf("Invalid signature threshold %d", needed)
+	}
+	// Print a summary to ensure the user understands what they're signing
+	fmt.Printf("Deploying new checkpoint oracle:\n\n")
+	for i, addr := range addrs {
+		fmt.Printf("Admin %d => %s\n", i+1, addr.Hex())
+	}
+	fmt.Printf("\nSignatures needed to publish: %d\n", needed)
+
+	// Deploy the contract using the given key
+	key := getKey(ctx)
+	client := ethclient.NewClient(newRPCClient(ctx.GlobalString(nodeURLFlag.Name)))
+
+	oracle, tx, _, err := contract.DeployCheckpointOracle(bind.NewKeyedTransactor(key.PrivateKey), client, addrs, big.NewInt(int64(params.CHTFrequencyClient)), big.NewInt(int64(params.HelperTrieProcessConfirmations)), big.NewInt(needed))
+	if err != nil {
+		utils.Fatalf("Failed to deploy checkpoint oracle: %v", err)
+	}
+	log.Info("Deployed checkpoint oracle", "address", oracle, "tx", tx.Hash().Hex())
+
+	ctxt, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if _, err := bind.WaitDeployed(ctxt, client, tx); err != nil {
+		utils.Fatalf("Failed to wait for the oracle deployment: %v", err)
+	}
+	return nil
+}
+
+// sign creates the signature for specific checkpoint with local key.
+func sign(ctx *cli.Context) error {
+	client := newRPCClient(ctx.GlobalString(nodeURLFlag.Name))
+	addr, oracle := newContract(ctx, client)
+
+	// Retrieve the checkpoint to sign and make sure it's not outdated
+	checkpoint := getCheckpoint(ctx, client)
+	index, _, _, err := oracle.Contract().GetLatestCheckpoint(nil)
+	if err != nil {
+		utils.Fatalf("Failed to retrieve latest registered checkpoint: %v", err)
+	}
+	if index > 0 && checkpoint.SectionIndex <= index {
+		utils.Fatalf("Checkpoint %d is not newer than the registered one %d", checkpoint.SectionIndex, index)
+	}
+	fmt.Printf("Oracle     => %s\n", addr.Hex())
+	fmt.Printf("Index      => %d\n", checkpoint.SectionIndex)
+	fmt.Printf("Hash       => %s\n", checkpoint.Hash().Hex())
+
+	// Sign the checkpoint and convert the signature to ethereum style
+	key := getKey(ctx)
+	sig, err := crypto.Sign(checkpointoracle.SigningHash(addr, checkpoint.SectionIndex, checkpoint.Hash()).Bytes(), key.PrivateKey)
+	if err != nil {
+		utils.Fatalf("Failed to sign checkpoint: %v", err)
+	}
+	sig[64] += 27
+
+	fmt.Printf("Signer     => %s\n", key.Address.Hex())
+	fmt.Printf("Signature  => %s\n", hexutil.Encode(sig))
+	return nil
+}
+
+// publish registers the specified checkpoint which generated by connected
+// node with an authorised private key.
+func publish(ctx *cli.Context) error {
+	// Parse the signatures collected from the trusted signers
+	var sigs [][]byte
+	for _, hexsig := range strings.Split(ctx.String(signaturesFlag.Name), ",") {
+		sig, err := hexutil.Decode(strings.TrimSpace(hexsig))
+		if err != nil {
+			utils.Fatalf("Invalid signature in --signatures: '%s'", hexsig)
+		}
+		sigs = append(sigs, sig)
+	}
+	rpcClient := newRPCClient(ctx.GlobalString(nodeURLFlag.Name))
+	addr, oracle := newContract(ctx, rpcClient)
+
+	checkpoint := getCheckpoint(ctx, rpcClient)
+	sigs, err := checkpointoracle.SortSignatures(addr, checkpoint.SectionIndex, checkpoint.Hash(), sigs)
+	if err != nil {
+		utils.Fatalf("Failed to sort signatures: %v", err)
+	}
+	// Anchor the registration to the current head to prevent replays on forks
+	client := ethclient.NewClient(rpcClient)
+	head, err := client.HeaderByNumber(context.Background(), nil)
+	if err != nil {
+		utils.Fatalf("Failed to retrieve chain head: %v", err)
+	}
+	fmt.Printf("Publishing checkpoint %d (%s) with %d signatures\n", checkpoint.SectionIndex, checkpoint.Hash().Hex(), len(sigs))
+
+	key := getKey(ctx)
+	tx, err := oracle.RegisterCheckpoint(bind.NewKeyedTransactor(key.PrivateKey), checkpoint.SectionIndex, checkpoint.Hash(), head.Number, head.Hash(), sigs)
+	if err != nil {
+		utils.Fatalf("Failed to register checkpoint: %v", err)
+	}
+	log.Info("Sent checkpoint registration", "tx", tx.Hash().Hex())
+
+	ctxt, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	receipt, err := bind.WaitMined(ctxt, client, tx)
+	if err != nil {
+		utils.Fatalf("Failed to wait for the registration: %v", err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		utils.Fatalf("Checkpoint registration rejected by the oracle")
+	}
+	log.Info("Published checkpoint", "index", checkpoint.SectionIndex, "hash", checkpoint.Hash())
+	return nil
+}
@@ -40,6 +40,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/console"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/rpc"
@@ -50,10 +51,10 @@ import (
 )
 
 // ExternalAPIVersion -- see extapi_changelog.md
-const ExternalAPIVersion = "4.0.0"
+const ExternalAPIVersion = "4.1.0"
 
 // InternalAPIVersion -- see intapi_changelog.md
-const InternalAPIVersion = "3.0.0"
+const InternalAPIVersion = "3.1.0"
 
 const legalWarning = `
 WARNING! 
@@ -125,6 +126,10 @@ var (
 		Name:  "stdio-ui-test",
 		Usage: "Mechanism to test interface between Clef and UI. Requires 'stdio-ui'.",
 	}
+	nodeFlag = cli.StringFlag{
+		Name:  "node",
+		Usage: "RPC endpoint of the Ethereum node the rule helpers look up counterparties on",
+	}
 	app         = cli.NewApp()
 	initCommand = cli.Command{
 		Action:    utils.MigrateFlags(initializeSecrets),
@@ -172,6 +177,37 @@ Clef that the file is 'safe' to execute.`,
 remove any stored credential for that address (keyfile)
 `,
 	}
+
+	rulesCommand = cli.Command{
+		Name:  "rules",
+		Usage: "Manage rule files",
+		Subcommands: []cli.Command{
+			{
+				Action:    utils.MigrateFlags(testRules),
+				Name:      "test",
+				Usage:     "Replay recorded transaction requests against a rule file",
+				ArgsUsage: "<rules.js> <fixtures.json>",
+				Flags: []cli.Flag{
+					logLevelFlag,
+				},
+				Description: `
+The test command evaluates the ApproveTx rule of the given rule file on each of the 
+recorded requests of the fixture file, in order, and checks that the rule makes the 
+expected decision: "Approve", "Reject", or "Manual" if the request is left to the user.
+
+The fixture file is a JSON object of the form:
+
+  {
+    "accounts": {"<address>": {"reputation": 1, "nonce": 5, "contract": false}},
+    "tests": [{"name": "...", "time": "<RFC3339 time>", "request": {<SignTxRequest>}, "expect": "Approve"}]
+  }
+
+The accounts are served to the reputation helper in place of a node, and the time of 
+a request sets the clock of the daily limit helpers. The command fails if any of the 
+requests is not decided as expected.`,
+			},
+		},
+	}
 )
 
 func init() {
@@ -198,9 +234,10 @@ func init() {
 		stdiouiFlag,
 		testFlag,
 		advancedMode,
+		nodeFlag,
 	}
 	app.Action = signer
-	app.Commands = []cli.Command{initCommand, attestCommand, setCredentialCommand}
+	app.Commands = []cli.Command{initCommand, attestCommand, setCredentialCommand, rulesCommand}
 
 }
 func main() {
@@ -319,6 +356,44 @@ func setCredential(ctx *cli.Context) error {
 	return nil
 }
 
+func testRules(ctx *cli.Context) error {
+	if len(ctx.Args()) < 2 {
+		utils.Fatalf("This command requires a rule file and a fixture file as arguments.")
+	}
+	log.Root().SetHandler(log.LvlFilterHandler(log.Lvl(ctx.Int(logLevelFlag.Name)), log.StreamHandler(os.Stderr, log.TerminalFormat(true))))
+
+	ruleJS, err := ioutil.ReadFile(ctx.Args().Get(0))
+	if err != nil {
+		return err
+	}
+	suite, err := rules.LoadFixtures(ctx.Args().Get(1))
+	if err != nil {
+		return err
+	}
+	results, err := rules.RunFixtures(string(ruleJS), suite)
+	if err != nil {
+		return err
+	}
+	failed := 0
+	for _, result := range results {
+		if result.Passed() {
+			fmt.Printf("PASS %s\n", result.Fixture.Name)
+			continue
+		}
+		failed++
+		if result.Err != nil {
+			fmt.Printf("FAIL %s: have %s, want %s (%v)\n", result.Fixture.Name, result.Have, result.Fixture.Expect, result.Err)
+		} else {
+			fmt.Printf("FAIL %s: have %s, want %s\n", result.Fixture.Name, result.Have, result.Fixture.Expect)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d fixtures failed", failed, len(results))
+	}
+	fmt.Printf("All %d fixtures passed\n", len(results))
+	return nil
+}
+
 func initialize(c *cli.Context) error {
 	// Set up the logger to print everything
 	logOutput := os.Stdout
@@ -400,6 +475,14 @@ func signer(c *cli.Context) error {
 					utils.Fatalf(err.Error())
 				}
 				ruleEngine.Init(string(ruleJS))
+				if endpoint := c.GlobalString(nodeFlag.Name); endpoint != "" {
+					client, err := ethclient.Dial(endpoint)
+					if err != nil {
+						utils.Fatalf("Could not connect to node: %v", err)
+					}
+					ruleEngine.SetChainBackend(client)
+					log.Info("Rule helpers connected to node", "url", endpoint)
+				}
 				ui = ruleEngine
 				log.Info("Rule engine configured", "file", c.String(ruleFlag.Name))
 			}
@@ -0,0 +1,244 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/console"
+	"github.com/ethereum/go-ethereum/p2p/dnsdisc"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+	"github.com/ethereum/go-ethereum/rlp"
+	"gopkg.in/urfave/cli.v1"
+)
+
+const (
+	nodesFile = "nodes.json"        // List of node records in a tree directory
+	infoFile  = "enrtree-info.json" // Tree metadata in a tree directory
+	txtFile   = "TXT.json"          // Default output file of to-txt
+	enrPrefix = "enr:"              // Prefix of textual node records
+)
+
+var (
+	dnsCommand = cli.Command{
+		Name:  "dns",
+		Usage: "DNS discovery commands",
+		Subcommands: []cli.Command{
+			dnsSignCommand,
+			dnsTXTCommand,
+		},
+	}
+	dnsSignCommand = cli.Command{
+		Name:      "sign",
+		Usage:     "Sign a DNS discovery tree",
+		ArgsUsage: "<tree-directory> <key-file>",
+		Action:    dnsSign,
+		Flags:     []cli.Flag{dnsDomainFlag, dnsSeqFlag, dnsPasswordFlag},
+	}
+	dnsTXTCommand = cli.Command{
+		Name:      "to-txt",
+		Usage:     "Create DNS TXT records for a discovery tree",
+		ArgsUsage: "<tree-directory> [<output-file>]",
+		Action:    dnsToTXT,
+	}
+)
+
+var (
+	dnsDomainFlag = cli.StringFlag{
+		Name:  "domain",
+		Usage: "Domain name of the tree",
+	}
+	dnsSeqFlag = cli.UintFlag{
+		Name:  "seq",
+		Usage: "New sequence number of the tree",
+	}
+	dnsPasswordFlag = cli.StringFlag{
+		Name:  "passwordfile",
+		Usage: "File containing the passphrase of the signing key",
+	}
+)
+
+// treeInfo is the metadata of a tree directory, stored in enrtree-info.json.
+type treeInfo struct {
+	Domain    string   `json:"domain,omitempty"`
+	URL       string   `json:"url,omitempty"`
+	Seq       uint     `json:"seq"`
+	Signature string   `json:"signature,omitempty"`
+	Links     []string `json:"links,omitempty"`
+}
+
+// dnsSign signs the tree in a tree directory, updating its metadata.
+func dnsSign(ctx *cli.Context) error {
+	if ctx.NArg() < 2 {
+		return fmt.Errorf("need tree definition directory and key file as arguments")
+	}
+	var (
+		dir     = ctx.Args().Get(0)
+		keyfile = ctx.Args().Get(1)
+	)
+	info, nodes, err := loadTreeDirectory(dir)
+	if err != nil {
+		return err
+	}
+	if ctx.IsSet(dnsDomainFlag.Name) {
+		info.Domain = ctx.String(dnsDomainFlag.Name)
+	}
+	if info.Domain == "" {
+		return fmt.Errorf("missing tree domain, use --%s to set it", dnsDomainFlag.Name)
+	}
+	if ctx.IsSet(dnsSeqFlag.Name) {
+		info.Seq = ctx.Uint(dnsSeqFlag.Name)
+	} else {
+		info.Seq++
+	}
+	t, err := dnsdisc.MakeTree(info.Seq, nodes, info.Links)
+	if err != nil {
+		return err
+	}
+	keyjson, err := ioutil.ReadFile(keyfile)
+	if err != nil {
+		return fmt.Errorf("failed to read key file: %v", err)
+	}
+	key, err := keystore.DecryptKey(keyjson, getPassphrase(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to decrypt key: %v", err)
+	}
+	url, err := t.Sign(key.PrivateKey, info.Domain)
+	if err != nil {
+		return fmt.Errorf("can't sign: %v", err)
+	}
+	info.URL = url
+	info.Signature = t.Signature()
+	if err := writeJSON(filepath.Join(dir, infoFile), info); err != nil {
+		return err
+	}
+	fmt.Println(url)
+	return nil
+}
+
+// dnsToTXT writes the TXT records of a signed tree directory as JSON.
+func dnsToTXT(ctx *cli.Context) error {
+	if ctx.NArg() < 1 {
+		return fmt.Errorf("need tree definition directory as argument")
+	}
+	dir := ctx.Args().Get(0)
+	output := filepath.Join(dir, txtFile)
+	if ctx.NArg() > 1 {
+		output = ctx.Args().Get(1)
+	}
+	info, nodes, err := loadTreeDirectory(dir)
+	if err != nil {
+		return err
+	}
+	if info.URL == "" || info.Signature == "" {
+		return fmt.Errorf("tree is not signed, run 'devp2p dns sign' first")
+	}
+	domain, pubkey, err := dnsdisc.ParseURL(info.URL)
+	if err != nil {
+		return fmt.Errorf("invalid tree URL: %v", err)
+	}
+	t, err := dnsdisc.MakeTree(info.Seq, nodes, info.Links)
+	if err != nil {
+		return err
+	}
+	if err := t.SetSignature(pubkey, info.Signature); err != nil {
+		return fmt.Errorf("tree signature is not valid, the tree needs to be re-signed: %v", err)
+	}
+	return writeJSON(output, t.ToTXT(domain))
+}
+
+// loadTreeDirectory reads the metadata and node records of a tree directory.
+func loadTreeDirectory(dir string) (*treeInfo, []*enode.Node, error) {
+	info := new(treeInfo)
+	if err := readJSON(filepath.Join(dir, infoFile), info); err != nil && !os.IsNotExist(err) {
+		return nil, nil, err
+	}
+	var records []string
+	if err := readJSON(filepath.Join(dir, nodesFile), &records); err != nil {
+		return nil, nil, err
+	}
+	nodes := make([]*enode.Node, len(records))
+	for i, record := range records {
+		n, err := parseRecord(record)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid node %d in %s: %v", i, nodesFile, err)
+		}
+		nodes[i] = n
+	}
+	return info, nodes, nil
+}
+
+// parseRecord parses a node record in its textual "enr:<base64>" form.
+func parseRecord(text string) (*enode.Node, error) {
+	if !strings.HasPrefix(text, enrPrefix) {
+		return nil, fmt.Errorf("missing %q prefix", enrPrefix)
+	}
+	blob, err := base64.RawURLEncoding.DecodeString(text[len(enrPrefix):])
+	if err != nil {
+		return nil, err
+	}
+	var r enr.Record
+	if err := rlp.DecodeBytes(blob, &r); err != nil {
+		return nil, err
+	}
+	return enode.New(enode.ValidSchemes, &r)
+}
+
+// getPassphrase reads the signing key passphrase from the --passwordfile flag,
+// prompting the user for it if the flag isn't set.
+func getPassphrase(ctx *cli.Context) string {
+	if file := ctx.String(dnsPasswordFlag.Name); file != "" {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			utils.Fatalf("Failed to read passphrase file '%s': %v", file, err)
+		}
+		return strings.TrimRight(string(content), "\r\n")
+	}
+	passphrase, err := console.Stdin.PromptPassword("Passphrase: ")
+	if err != nil {
+		utils.Fatalf("Failed to read passphrase: %v", err)
+	}
+	return passphrase
+}
+
+func readJSON(file string, v interface{}) error {
+	blob, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(blob, v); err != nil {
+		return fmt.Errorf("invalid JSON in %s: %v", file, err)
+	}
+	return nil
+}
+
+func writeJSON(file string, v interface{}) error {
+	blob, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, append(blob, '\n'), 0644)
+}
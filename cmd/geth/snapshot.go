@@ -0,0 +1,164 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state/pruner"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	snapshotCommand = cli.Command{
+		Name:     "snapshot",
+		Usage:    "A set of commands operating on the state snapshot",
+		Category: "MISCELLANEOUS COMMANDS",
+		Description: `
+The snapshot commands operate offline on the state stored in the database. The
+node must not be running while they are executed.`,
+		Subcommands: []cli.Command{
+			{
+				Name:      "prune-state",
+				Usage:     "Prune stale state data from the database",
+				ArgsUsage: "[<root>]",
+				Action:    utils.MigrateFlags(pruneState),
+				Category:  "MISCELLANEOUS COMMANDS",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.SyncModeFlag,
+					utils.AncientThresholdFlag,
+					utils.BloomFilterSizeFlag,
+				},
+				Description: `
+	geth snapshot prune-state [<root>]
+
+deletes all trie nodes and contract codes from the database which are not part
+of the state with the given root, or of the most recent available state of the
+canonical chain if no root is specified. The genesis state is always retained.
+
+The live state is gathered into a bloom filter of --bloomfilter.size megabytes
+first, which is persisted into the data directory before anything is deleted.
+If pruning is interrupted, rerunning the command or starting the node resumes
+it against the original root. Finally the retained state is verified to be
+complete.`,
+			},
+			{
+				Name:      "verify-state",
+				Usage:     "Verify that a state is fully available in the database",
+				ArgsUsage: "[<root>]",
+				Action:    utils.MigrateFlags(verifyState),
+				Category:  "MISCELLANEOUS COMMANDS",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.SyncModeFlag,
+					utils.AncientThresholdFlag,
+				},
+				Description: `
+	geth snapshot verify-state [<root>]
+
+walks the entire state with the given root, or of the head block if no root is
+specified, including all contract storage and code, and reports an error if any
+of it is missing from the database.`,
+			},
+		},
+	}
+)
+
+// pruneState deletes all the state data not belonging to the requested state
+// root from the chain database.
+func pruneState(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+
+	datadir := stack.ResolvePath("")
+	if datadir == "" {
+		utils.Fatalf("State pruning requires a persistent data directory")
+	}
+	chainDb := utils.MakeChainDatabase(ctx, stack)
+	defer chainDb.Close()
+
+	root, err := parseRoot(ctx)
+	if err != nil {
+		utils.Fatalf("%v", err)
+	}
+	if err := pruner.NewPruner(chainDb, datadir, ctx.Uint64(utils.BloomFilterSizeFlag.Name)).Prune(root); err != nil {
+		utils.Fatalf("State pruning failed: %v", err)
+	}
+	return nil
+}
+
+// verifyState checks that the requested state, or the head state by default, is
+// fully available in the chain database.
+func verifyState(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+
+	chainDb := utils.MakeChainDatabase(ctx, stack)
+	defer chainDb.Close()
+
+	root, err := parseRoot(ctx)
+	if err != nil {
+		utils.Fatalf("%v", err)
+	}
+	if root == (common.Hash{}) {
+		if root = headRoot(chainDb); root == (common.Hash{}) {
+			utils.Fatalf("Head block not found")
+		}
+	}
+	if err := pruner.VerifyState(chainDb, root); err != nil {
+		utils.Fatalf("State %x incomplete: %v", root, err)
+	}
+	return nil
+}
+
+// parseRoot retrieves the optional state root argument of the snapshot commands.
+func parseRoot(ctx *cli.Context) (common.Hash, error) {
+	switch ctx.NArg() {
+	case 0:
+		return common.Hash{}, nil
+	case 1:
+		blob, err := hexutil.Decode(ctx.Args().First())
+		if err != nil || len(blob) != common.HashLength {
+			return common.Hash{}, fmt.Errorf("invalid state root %q", ctx.Args().First())
+		}
+		return common.BytesToHash(blob), nil
+	default:
+		return common.Hash{}, errors.New("too many arguments")
+	}
+}
+
+// headRoot returns the state root of the current head block, or the empty hash
+// if it cannot be found.
+func headRoot(db ethdb.Database) common.Hash {
+	hash := rawdb.ReadHeadBlockHash(db)
+	number := rawdb.ReadHeaderNumber(db, hash)
+	if number == nil {
+		return common.Hash{}
+	}
+	header := rawdb.ReadHeader(db, hash, *number)
+	if header == nil {
+		return common.Hash{}
+	}
+	log.Info("Selected head state", "number", *number, "hash", hash, "root", header.Root)
+	return header.Root
+}
@@ -34,6 +34,16 @@
 //     $ p2psim node connect node01 node02
 //     Connected node01 to node02
 //
+// Against a network running the simulated eth service, reputation mining
+// scenarios can be run and their resulting reputation distribution shown:
+//
+//     $ p2psim scenario run selfish
+//     HEAD    0x5e3a...
+//     NUMBER  47
+//
+//     MINER                                       REPUTATION  BLOCKS
+//     ...
+//
 package main
 
 import (
@@ -42,10 +52,12 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sort"
 	"strings"
 	"text/tabwriter"
 
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/ethsim"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/p2p/enode"
 	"github.com/ethereum/go-ethereum/p2p/simulations"
@@ -179,6 +191,24 @@ func main() {
 				},
 			},
 		},
+		{
+			Name:   "scenario",
+			Usage:  "run reputation mining scenarios",
+			Action: listScenarios,
+			Subcommands: []cli.Command{
+				{
+					Name:   "list",
+					Usage:  "list scenarios",
+					Action: listScenarios,
+				},
+				{
+					Name:      "run",
+					ArgsUsage: "<scenario>",
+					Usage:     "run a scenario and check the reputation distribution",
+					Action:    runScenario,
+				},
+			},
+		},
 	}
 	if err := app.Run(os.Args); err != nil {
 		fmt.Fprintln(os.Stderr, err)
@@ -430,3 +460,45 @@ func rpcSubscribe(client *rpc.Client, out io.Writer, method string, args ...stri
 		}
 	}
 }
+
+func listScenarios(ctx *cli.Context) error {
+	if len(ctx.Args()) != 0 {
+		return cli.ShowCommandHelp(ctx, ctx.Command.Name)
+	}
+	names := make([]string, 0, len(ethsim.Scenarios))
+	for name := range ethsim.Scenarios {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := tabwriter.NewWriter(ctx.App.Writer, 1, 2, 2, ' ', 0)
+	defer w.Flush()
+	fmt.Fprintf(w, "NAME\tDESCRIPTION\n")
+	for _, name := range names {
+		fmt.Fprintf(w, "%s\t%s\n", name, ethsim.Scenarios[name].Description)
+	}
+	return nil
+}
+
+func runScenario(ctx *cli.Context) error {
+	args := ctx.Args()
+	if len(args) != 1 {
+		return cli.ShowCommandHelp(ctx, ctx.Command.Name)
+	}
+	scenario, ok := ethsim.Scenarios[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown scenario %q", args[0])
+	}
+	result, err := scenario.Run(client)
+	if result != nil {
+		w := tabwriter.NewWriter(ctx.App.Writer, 1, 2, 2, ' ', 0)
+		fmt.Fprintf(w, "HEAD\t%s\n", result.Head.Hex())
+		fmt.Fprintf(w, "NUMBER\t%d\n\n", result.Number)
+		fmt.Fprintf(w, "MINER\tREPUTATION\tBLOCKS\n")
+		for _, miner := range scenario.Miners {
+			fmt.Fprintf(w, "%s\t%d\t%d\n", miner.Coinbase.Hex(), result.Reputation[miner.Coinbase], result.Blocks[miner.Coinbase])
+		}
+		w.Flush()
+	}
+	return err
+}
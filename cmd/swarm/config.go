@@ -36,6 +36,8 @@ import (
 	"github.com/naoina/toml"
 
 	bzzapi "github.com/ethereum/go-ethereum/swarm/api"
+	"github.com/ethereum/go-ethereum/swarm/storage"
+	"github.com/ethereum/go-ethereum/swarm/storage/encryption"
 )
 
 var (
@@ -66,6 +68,9 @@ const (
 	SWARM_ENV_NETWORK_ID              = "SWARM_NETWORK_ID"
 	SWARM_ENV_SWAP_ENABLE             = "SWARM_SWAP_ENABLE"
 	SWARM_ENV_SWAP_API                = "SWARM_SWAP_API"
+	SWARM_ENV_FEED_REPUTATION_API     = "SWARM_FEED_REPUTATION_API"
+	SWARM_ENV_FEED_REPUTATION_TOPICS  = "SWARM_FEED_REPUTATION_TOPICS"
+	SWARM_ENV_PSS_MAILBOX             = "SWARM_PSS_MAILBOX"
 	SWARM_ENV_SYNC_DISABLE            = "SWARM_SYNC_DISABLE"
 	SWARM_ENV_SYNC_UPDATE_DELAY       = "SWARM_ENV_SYNC_UPDATE_DELAY"
 	SWARM_ENV_MAX_STREAM_PEER_SERVERS = "SWARM_ENV_MAX_STREAM_PEER_SERVERS"
@@ -74,11 +79,13 @@ const (
 	SWARM_ENV_ENS_API                 = "SWARM_ENS_API"
 	SWARM_ENV_ENS_ADDR                = "SWARM_ENS_ADDR"
 	SWARM_ENV_CORS                    = "SWARM_CORS"
+	SWARM_ENV_ENABLE_PINNING          = "SWARM_ENABLE_PINNING"
 	SWARM_ENV_BOOTNODES               = "SWARM_BOOTNODES"
 	SWARM_ENV_PSS_ENABLE              = "SWARM_PSS_ENABLE"
 	SWARM_ENV_STORE_PATH              = "SWARM_STORE_PATH"
 	SWARM_ENV_STORE_CAPACITY          = "SWARM_STORE_CAPACITY"
 	SWARM_ENV_STORE_CACHE_CAPACITY    = "SWARM_STORE_CACHE_CAPACITY"
+	SWARM_ENV_STORE_PARITIES          = "SWARM_STORE_PARITIES"
 	SWARM_ACCESS_PASSWORD             = "SWARM_ACCESS_PASSWORD"
 	SWARM_AUTO_DEFAULTPATH            = "SWARM_AUTO_DEFAULTPATH"
 	GETH_ENV_DATADIR                  = "GETH_DATADIR"
@@ -224,11 +231,27 @@ func cmdLineOverride(currentConfig *bzzapi.Config, ctx *cli.Context) *bzzapi.Con
 		currentConfig.DeliverySkipCheck = true
 	}
 
+	if ctx.GlobalIsSet(SwarmEnablePinningFlag.Name) {
+		currentConfig.PinningEnabled = true
+	}
+
 	currentConfig.SwapAPI = ctx.GlobalString(SwarmSwapAPIFlag.Name)
 	if currentConfig.SwapEnabled && currentConfig.SwapAPI == "" {
 		utils.Fatalf(SWARM_ERR_SWAP_SET_NO_API)
 	}
 
+	if reputationapi := ctx.GlobalString(SwarmFeedReputationAPIFlag.Name); reputationapi != "" {
+		currentConfig.FeedReputationAPI = expandPath(reputationapi)
+	}
+
+	if ctx.GlobalIsSet(SwarmFeedReputationTopicsFlag.Name) {
+		currentConfig.FeedReputationTopics = ctx.GlobalStringSlice(SwarmFeedReputationTopicsFlag.Name)
+	}
+
+	if ctx.GlobalIsSet(SwarmPssMailboxFlag.Name) {
+		currentConfig.Pss.MailboxEnabled = true
+	}
+
 	if ctx.GlobalIsSet(EnsAPIFlag.Name) {
 		ensAPIs := ctx.GlobalStringSlice(EnsAPIFlag.Name)
 		// preserve backward compatibility to disable ENS with --ens-api=""
@@ -258,6 +281,10 @@ func cmdLineOverride(currentConfig *bzzapi.Config, ctx *cli.Context) *bzzapi.Con
 		currentConfig.LocalStoreParams.CacheCapacity = storeCacheCapacity
 	}
 
+	if ctx.GlobalIsSet(SwarmStoreParities.Name) {
+		currentConfig.FileStoreParams.Parities = ctx.GlobalInt(SwarmStoreParities.Name)
+	}
+
 	return currentConfig
 
 }
@@ -352,6 +379,22 @@ func envVarsOverride(currentConfig *bzzapi.Config) (config *bzzapi.Config) {
 		utils.Fatalf(SWARM_ERR_SWAP_SET_NO_API)
 	}
 
+	if reputationapi := os.Getenv(SWARM_ENV_FEED_REPUTATION_API); reputationapi != "" {
+		currentConfig.FeedReputationAPI = reputationapi
+	}
+
+	if topics := os.Getenv(SWARM_ENV_FEED_REPUTATION_TOPICS); topics != "" {
+		currentConfig.FeedReputationTopics = strings.Split(topics, ",")
+	}
+
+	if mailbox := os.Getenv(SWARM_ENV_PSS_MAILBOX); mailbox != "" {
+		enabled, err := strconv.ParseBool(mailbox)
+		if err != nil {
+			utils.Fatalf("invalid environment variable %s: %v", SWARM_ENV_PSS_MAILBOX, err)
+		}
+		currentConfig.Pss.MailboxEnabled = enabled
+	}
+
 	if ensapi := os.Getenv(SWARM_ENV_ENS_API); ensapi != "" {
 		currentConfig.EnsAPIs = strings.Split(ensapi, ",")
 	}
@@ -364,6 +407,14 @@ func envVarsOverride(currentConfig *bzzapi.Config) (config *bzzapi.Config) {
 		currentConfig.Cors = cors
 	}
 
+	if v := os.Getenv(SWARM_ENV_ENABLE_PINNING); v != "" {
+		pinning, err := strconv.ParseBool(v)
+		if err != nil {
+			utils.Fatalf("invalid environment variable %s: %v", SWARM_ENV_ENABLE_PINNING, err)
+		}
+		currentConfig.PinningEnabled = pinning
+	}
+
 	return currentConfig
 }
 
@@ -386,6 +437,12 @@ func dumpConfig(ctx *cli.Context) error {
 
 //validate configuration parameters
 func validateConfig(cfg *bzzapi.Config) (err error) {
+	if cfg.FileStoreParams != nil {
+		// encrypted uploads use the longer references, limiting the parities most
+		if max := storage.MaxParities(storage.AddressLength + encryption.KeyLength); cfg.Parities < 0 || cfg.Parities > max {
+			return fmt.Errorf("invalid number of parity chunks %d, must be between 0 and %d", cfg.Parities, max)
+		}
+	}
 	for _, ensAPI := range cfg.EnsAPIs {
 		if ensAPI != "" {
 			if err := validateEnsAPIs(ensAPI); err != nil {
@@ -31,6 +31,7 @@ import (
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/ethereum/go-ethereum/swarm"
 	"github.com/ethereum/go-ethereum/swarm/api"
+	"github.com/ethereum/go-ethereum/swarm/storage"
 )
 
 func TestConfigDump(t *testing.T) {
@@ -90,6 +91,7 @@ func TestConfigCmdLineOverrides(t *testing.T) {
 		fmt.Sprintf("--%s", CorsStringFlag.Name), "*",
 		fmt.Sprintf("--%s", SwarmAccountFlag.Name), account.Address.String(),
 		fmt.Sprintf("--%s", SwarmDeliverySkipCheckFlag.Name),
+		fmt.Sprintf("--%s", SwarmStoreParities.Name), "4",
 		fmt.Sprintf("--%s", EnsAPIFlag.Name), "",
 		fmt.Sprintf("--%s", utils.DataDirFlag.Name), dir,
 		fmt.Sprintf("--%s", utils.IPCPathFlag.Name), conf.IPCPath,
@@ -138,6 +140,10 @@ func TestConfigCmdLineOverrides(t *testing.T) {
 		t.Fatalf("Expected Cors flag to be set to %s, got %s", "*", info.Cors)
 	}
 
+	if info.Parities != 4 {
+		t.Fatalf("Expected parities to be %d, got %d", 4, info.Parities)
+	}
+
 	node.Shutdown()
 }
 
@@ -550,6 +556,17 @@ func TestConfigValidate(t *testing.T) {
 			}},
 			err: "invalid format [tld:][contract-addr@]url for ENS API endpoint configuration \"@/data/testnet/geth.ipc\": missing contract address",
 		},
+		{
+			cfg: &api.Config{FileStoreParams: &storage.FileStoreParams{Parities: 62}},
+		},
+		{
+			cfg: &api.Config{FileStoreParams: &storage.FileStoreParams{Parities: 63}},
+			err: "invalid number of parity chunks 63, must be between 0 and 62",
+		},
+		{
+			cfg: &api.Config{FileStoreParams: &storage.FileStoreParams{Parities: -1}},
+			err: "invalid number of parity chunks -1, must be between 0 and 62",
+		},
 	} {
 		err := validateConfig(c.cfg)
 		if c.err != "" && err.Error() != c.err {
@@ -55,6 +55,21 @@ var (
 		Usage:  "URL of the Ethereum API provider to use to settle SWAP payments",
 		EnvVar: SWARM_ENV_SWAP_API,
 	}
+	SwarmFeedReputationAPIFlag = cli.StringFlag{
+		Name:   "feed-reputation-api",
+		Usage:  "URL or IPC path of the Ethereum API provider to check feed publisher reputation with",
+		EnvVar: SWARM_ENV_FEED_REPUTATION_API,
+	}
+	SwarmFeedReputationTopicsFlag = cli.StringSliceFlag{
+		Name:   "feed-reputation-topic",
+		Usage:  "Hex encoded feed topic only reputable publishers may update, can be repeated (default all topics)",
+		EnvVar: SWARM_ENV_FEED_REPUTATION_TOPICS,
+	}
+	SwarmPssMailboxFlag = cli.BoolFlag{
+		Name:   "pss-mailbox",
+		Usage:  "Keep pss messages for offline recipients in the node's neighbourhood",
+		EnvVar: SWARM_ENV_PSS_MAILBOX,
+	}
 	SwarmSyncDisabledFlag = cli.BoolTFlag{
 		Name:   "nosync",
 		Usage:  "Disable swarm syncing",
@@ -81,6 +96,11 @@ var (
 		Usage:  "Skip chunk delivery check (default false)",
 		EnvVar: SWARM_ENV_DELIVERY_SKIP_CHECK,
 	}
+	SwarmEnablePinningFlag = cli.BoolFlag{
+		Name:   "enable-pinning",
+		Usage:  "Serve the bzz-pin HTTP API to pin content in the local store, open to every client of the HTTP port (default false)",
+		EnvVar: SWARM_ENV_ENABLE_PINNING,
+	}
 	EnsAPIFlag = cli.StringSliceFlag{
 		Name:   "ens-api",
 		Usage:  "ENS API endpoint for a TLD and with contract address, can be repeated, format [tld:][contract-addr@]url",
@@ -152,6 +172,11 @@ var (
 		Usage:  "Number of recent chunks cached in memory (default 5000)",
 		EnvVar: SWARM_ENV_STORE_CACHE_CAPACITY,
 	}
+	SwarmStoreParities = cli.IntFlag{
+		Name:   "store.parities",
+		Usage:  "Number of Reed-Solomon parity chunks added to every intermediate chunk of uploaded content, 0 disables erasure coding (default 0)",
+		EnvVar: SWARM_ENV_STORE_PARITIES,
+	}
 	SwarmCompressedFlag = cli.BoolFlag{
 		Name:  "compressed",
 		Usage: "Prints encryption keys in compressed form",
@@ -176,4 +201,8 @@ var (
 		Name:  "user",
 		Usage: "Indicates the user who updates the feed",
 	}
+	SwarmPinRawFlag = cli.BoolFlag{
+		Name:  "raw",
+		Usage: "Pin the content as a single raw file instead of a manifest",
+	}
 )
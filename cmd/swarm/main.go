@@ -138,6 +138,8 @@ func init() {
 		fsCommand,
 		// See db.go
 		dbCommand,
+		// See pin.go
+		pinCommand,
 		// See config.go
 		DumpConfigCommand,
 	}
@@ -157,6 +159,7 @@ func init() {
 		utils.NoDiscoverFlag,
 		utils.DiscoveryV5Flag,
 		utils.NetrestrictFlag,
+		utils.RateLimitFlag,
 		utils.NodeKeyFileFlag,
 		utils.NodeKeyHexFlag,
 		utils.MaxPeersFlag,
@@ -170,11 +173,15 @@ func init() {
 		SwarmTomlConfigPathFlag,
 		SwarmSwapEnabledFlag,
 		SwarmSwapAPIFlag,
+		SwarmFeedReputationAPIFlag,
+		SwarmFeedReputationTopicsFlag,
+		SwarmPssMailboxFlag,
 		SwarmSyncDisabledFlag,
 		SwarmSyncUpdateDelay,
 		SwarmMaxStreamPeerServersFlag,
 		SwarmLightNodeEnabled,
 		SwarmDeliverySkipCheckFlag,
+		SwarmEnablePinningFlag,
 		SwarmListenAddrFlag,
 		SwarmPortFlag,
 		SwarmAccountFlag,
@@ -191,6 +198,7 @@ func init() {
 		SwarmStorePath,
 		SwarmStoreCapacity,
 		SwarmStoreCacheCapacity,
+		SwarmStoreParities,
 	}
 	rpcFlags := []cli.Flag{
 		utils.WSEnabledFlag,
@@ -0,0 +1,114 @@
+// Copyright 2026 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	swarm "github.com/ethereum/go-ethereum/swarm/api/client"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var pinCommand = cli.Command{
+	Name:               "pin",
+	CustomHelpTemplate: helpTemplate,
+	Usage:              "manage content pinned in the local store",
+	ArgsUsage:          "pin COMMAND",
+	Description: `Manage content pinned in the local store of the node.
+
+Pinned chunks are exempt from garbage collection, so content uploaded or
+retrieved by this node stays available locally until it is unpinned. The
+node has to be started with --enable-pinning to serve these commands.`,
+	Subcommands: []cli.Command{
+		{
+			Action:             pinAdd,
+			CustomHelpTemplate: helpTemplate,
+			Name:               "add",
+			Usage:              "pin a manifest and all files it references",
+			ArgsUsage:          "<hash>",
+			Flags:              []cli.Flag{SwarmPinRawFlag},
+			Description: `Pins the manifest with the given hash and all files and sub-manifests it
+references. Use --raw to pin content uploaded without a manifest.
+
+    swarm pin add 2477cc8584cc61091b5cc084cdcdb45bf3c6210c263b0143f030cf7d750e894d`,
+		},
+		{
+			Action:             pinRemove,
+			CustomHelpTemplate: helpTemplate,
+			Name:               "rm",
+			Usage:              "release a pin held on content",
+			ArgsUsage:          "<hash>",
+			Description:        "Releases one pin held on the content with the given hash",
+		},
+		{
+			Action:             pinList,
+			CustomHelpTemplate: helpTemplate,
+			Name:               "ls",
+			Usage:              "list pinned content",
+			Description:        "Lists the content pinned in the local store",
+		},
+	},
+}
+
+func pinAdd(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) != 1 {
+		utils.Fatalf("Usage: swarm pin add [--raw] <hash>")
+	}
+	bzzapi := strings.TrimRight(ctx.GlobalString(SwarmApiFlag.Name), "/")
+	client := swarm.NewClient(bzzapi)
+	if err := client.PinFiles(args[0], ctx.Bool(SwarmPinRawFlag.Name)); err != nil {
+		utils.Fatalf("Failed to pin %s: %v", args[0], err)
+	}
+	fmt.Println(args[0])
+}
+
+func pinRemove(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) != 1 {
+		utils.Fatalf("Usage: swarm pin rm <hash>")
+	}
+	bzzapi := strings.TrimRight(ctx.GlobalString(SwarmApiFlag.Name), "/")
+	client := swarm.NewClient(bzzapi)
+	if err := client.UnpinFiles(args[0]); err != nil {
+		utils.Fatalf("Failed to unpin %s: %v", args[0], err)
+	}
+	fmt.Println(args[0])
+}
+
+func pinList(ctx *cli.Context) {
+	if len(ctx.Args()) != 0 {
+		utils.Fatalf("Usage: swarm pin ls")
+	}
+	bzzapi := strings.TrimRight(ctx.GlobalString(SwarmApiFlag.Name), "/")
+	client := swarm.NewClient(bzzapi)
+	list, err := client.ListPinFiles()
+	if err != nil {
+		utils.Fatalf("Failed to list pinned content: %v", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 1, 2, 2, ' ', 0)
+	defer w.Flush()
+	fmt.Fprintln(w, "HASH\tRAW\tPINS\tCHUNKS")
+	for _, info := range list {
+		fmt.Fprintf(w, "%s\t%t\t%d\t%d\n", info.Address.Hex(), info.IsRaw, info.PinCount, info.Chunks)
+	}
+}
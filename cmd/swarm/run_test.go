@@ -42,6 +42,7 @@ import (
 	"github.com/ethereum/go-ethereum/swarm"
 	"github.com/ethereum/go-ethereum/swarm/api"
 	swarmhttp "github.com/ethereum/go-ethereum/swarm/api/http"
+	"github.com/ethereum/go-ethereum/swarm/storage/pin"
 )
 
 var loglevel = flag.Int("loglevel", 3, "verbosity of logs")
@@ -68,8 +69,8 @@ func initCluster(t *testing.T) {
 	})
 }
 
-func serverFunc(api *api.API) swarmhttp.TestServer {
-	return swarmhttp.NewServer(api, "")
+func serverFunc(api *api.API, pinAPI *pin.API) swarmhttp.TestServer {
+	return swarmhttp.NewServer(api, pinAPI, "")
 }
 func TestMain(m *testing.M) {
 	// check if we have been reexec'd
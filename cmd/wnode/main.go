@@ -692,6 +692,10 @@ func requestExpiredMessagesLoop() {
 	peerID = extractIDFromEnode(*argEnode)
 	shh.AllowP2PMessagesFromPeer(peerID)
 
+	responses := make(chan whisper.MailServerResponseEvent, 16)
+	sub := shh.SubscribeMailServerResponses(responses)
+	defer sub.Unsubscribe()
+
 	for {
 		timeLow = scanUint("Please enter the lower limit of the time range (unix timestamp): ")
 		timeUpp = scanUint("Please enter the upper limit of the time range (unix timestamp): ")
@@ -716,33 +720,67 @@ func requestExpiredMessagesLoop() {
 			timeUpp = 0xFFFFFFFF
 		}
 
-		data := make([]byte, 8, 8+whisper.BloomFilterSize)
-		binary.BigEndian.PutUint32(data, timeLow)
-		binary.BigEndian.PutUint32(data[4:], timeUpp)
-		data = append(data, bloom...)
+		var cursor []byte
+		for {
+			data := make([]byte, 8, 8+whisper.BloomFilterSize+4+len(cursor))
+			binary.BigEndian.PutUint32(data, timeLow)
+			binary.BigEndian.PutUint32(data[4:], timeUpp)
+			data = append(data, bloom...)
+			if len(cursor) > 0 {
+				// continue with the next page of the default size
+				data = append(data, 0, 0, 0, 0)
+				data = append(data, cursor...)
+			}
 
-		var params whisper.MessageParams
-		params.PoW = *argServerPoW
-		params.Payload = data
-		params.KeySym = key
-		params.Src = asymKey
-		params.WorkTime = 5
+			var params whisper.MessageParams
+			params.PoW = *argServerPoW
+			params.Payload = data
+			params.KeySym = key
+			params.Src = asymKey
+			params.WorkTime = 5
 
-		msg, err := whisper.NewSentMessage(&params)
-		if err != nil {
-			utils.Fatalf("failed to create new message: %s", err)
-		}
-		env, err := msg.Wrap(&params)
-		if err != nil {
-			utils.Fatalf("Wrap failed: %s", err)
-		}
+			msg, err := whisper.NewSentMessage(&params)
+			if err != nil {
+				utils.Fatalf("failed to create new message: %s", err)
+			}
+			env, err := msg.Wrap(&params)
+			if err != nil {
+				utils.Fatalf("Wrap failed: %s", err)
+			}
 
-		err = shh.RequestHistoricMessages(peerID, env)
-		if err != nil {
-			utils.Fatalf("Failed to send P2P message: %s", err)
+			err = shh.RequestHistoricMessages(peerID, env)
+			if err != nil {
+				utils.Fatalf("Failed to send P2P message: %s", err)
+			}
+
+			cursor = waitMailServerResponse(responses, env.Hash())
+			if len(cursor) == 0 {
+				break
+			}
+			time.Sleep(mailserver.DefaultRequestInterval)
 		}
+	}
+}
 
-		time.Sleep(time.Second * 5)
+// waitMailServerResponse waits for the mail server to complete the request,
+// and returns the cursor of the next page, if any.
+func waitMailServerResponse(responses <-chan whisper.MailServerResponseEvent, request common.Hash) []byte {
+	timeout := time.After(time.Minute)
+	for {
+		select {
+		case ev := <-responses:
+			if ev.Response.RequestID != request {
+				continue
+			}
+			if ev.Response.Error != "" {
+				fmt.Printf("Mail server rejected the request: %s\n", ev.Response.Error)
+				return nil
+			}
+			return ev.Response.Cursor
+		case <-timeout:
+			fmt.Println("Mail server did not respond to the request")
+			return nil
+		}
 	}
 }
 
@@ -73,6 +73,23 @@ type solcOutput struct {
 	Version string
 }
 
+// --combined-output format, solc 0.8.0 and up embed the ABI and docs as JSON
+type solcOutputV8 struct {
+	Contracts map[string]struct {
+		BinRuntime            string `json:"bin-runtime"`
+		SrcMapRuntime         string `json:"srcmap-runtime"`
+		Bin, SrcMap, Metadata string
+		Abi                   interface{}
+		Devdoc                interface{}
+		Userdoc               interface{}
+	}
+	Version string
+}
+
+// evmVersion is the latest hard fork whose instruction set the EVM implements,
+// newer compilers would default to emitting opcodes it doesn't know.
+const evmVersion = "constantinople"
+
 func (s *Solidity) makeArgs() []string {
 	p := []string{
 		"--combined-json", "bin,bin-runtime,srcmap,srcmap-runtime,abi,userdoc,devdoc",
@@ -81,6 +98,9 @@ func (s *Solidity) makeArgs() []string {
 	if s.Major > 0 || s.Minor > 4 || s.Patch > 6 {
 		p[1] += ",metadata"
 	}
+	if s.Major > 0 || s.Minor > 5 || (s.Minor == 5 && s.Patch > 4) {
+		p = append(p, "--evm-version", evmVersion)
+	}
 	return p
 }
 
@@ -154,6 +174,9 @@ func (s *Solidity) run(cmd *exec.Cmd, source string) (map[string]*Contract, erro
 		return nil, fmt.Errorf("solc: %v\n%s", err, stderr.Bytes())
 	}
 
+	if s.Major > 0 || s.Minor > 7 {
+		return parseCombinedJSONV8(stdout.Bytes(), source, s.Version, s.Version, strings.Join(s.makeArgs(), " "))
+	}
 	return ParseCombinedJSON(stdout.Bytes(), source, s.Version, s.Version, strings.Join(s.makeArgs(), " "))
 }
 
@@ -209,6 +232,37 @@ func ParseCombinedJSON(combinedJSON []byte, source string, languageVersion strin
 	return contracts, nil
 }
 
+// parseCombinedJSONV8 is the counterpart of ParseCombinedJSON for the output
+// of solc 0.8.0 and up, which embeds the ABI and docs as JSON, not as strings.
+func parseCombinedJSONV8(combinedJSON []byte, source string, languageVersion string, compilerVersion string, compilerOptions string) (map[string]*Contract, error) {
+	var output solcOutputV8
+	if err := json.Unmarshal(combinedJSON, &output); err != nil {
+		return nil, err
+	}
+	// Compilation succeeded, assemble and return the contracts.
+	contracts := make(map[string]*Contract)
+	for name, info := range output.Contracts {
+		contracts[name] = &Contract{
+			Code:        "0x" + info.Bin,
+			RuntimeCode: "0x" + info.BinRuntime,
+			Info: ContractInfo{
+				Source:          source,
+				Language:        "Solidity",
+				LanguageVersion: languageVersion,
+				CompilerVersion: compilerVersion,
+				CompilerOptions: compilerOptions,
+				SrcMap:          info.SrcMap,
+				SrcMapRuntime:   info.SrcMapRuntime,
+				AbiDefinition:   info.Abi,
+				UserDoc:         info.Userdoc,
+				DeveloperDoc:    info.Devdoc,
+				Metadata:        info.Metadata,
+			},
+		}
+	}
+	return contracts, nil
+}
+
 func slurpFiles(files []string) (string, error) {
 	var concat bytes.Buffer
 	for _, file := range files {
@@ -25,7 +25,7 @@ const (
 	testSource = `
 contract test {
    /// @notice Will multiply ` + "`a`" + ` by 7.
-   function multiply(uint a) returns(uint d) {
+   function multiply(uint a) public returns(uint d) {
        return a * 7;
    }
 }
@@ -75,3 +75,25 @@ func TestCompileError(t *testing.T) {
 	}
 	t.Logf("error: %v", err)
 }
+
+func TestParseCombinedJSONV8(t *testing.T) {
+	const output = `{"contracts":{"<stdin>:test":{"abi":[{"inputs":[],"name":"f","outputs":[],"stateMutability":"view","type":"function"}],"bin":"6080","bin-runtime":"6081","devdoc":{"kind":"dev"},"metadata":"{}","srcmap":"","srcmap-runtime":"","userdoc":{"kind":"user"}}},"version":"0.8.21"}`
+
+	contracts, err := parseCombinedJSONV8([]byte(output), testSource, "0.8.21", "0.8.21", "")
+	if err != nil {
+		t.Fatalf("failed to parse output: %v", err)
+	}
+	c, ok := contracts["<stdin>:test"]
+	if !ok {
+		t.Fatal("info for contract 'test' not present in result")
+	}
+	if c.Code != "0x6080" || c.RuntimeCode != "0x6081" {
+		t.Errorf("code mismatch: have %s/%s, want %s/%s", c.Code, c.RuntimeCode, "0x6080", "0x6081")
+	}
+	if abi, ok := c.Info.AbiDefinition.([]interface{}); !ok || len(abi) != 1 {
+		t.Errorf("abi definition mismatch: have %v", c.Info.AbiDefinition)
+	}
+	if doc, ok := c.Info.UserDoc.(map[string]interface{}); !ok || doc["kind"] != "user" {
+		t.Errorf("user doc mismatch: have %v", c.Info.UserDoc)
+	}
+}
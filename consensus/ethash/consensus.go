@@ -758,10 +758,24 @@ func (ethash *Ethash) verifySeal(chain consensus.ChainReader, header *types.Head
 		return errInvalidMixDigest
 	}
 
+	target, err := ethash.SealTarget(chain, header)
+	if err != nil {
+		return err
+	}
+	if new(big.Int).SetBytes(result).Cmp(target) > 0 {
+		return errInvalidPoW
+	}
+	return nil
+}
+
+// SealTarget returns the proof-of-work target the seal of a header has to meet.
+// The header difficulty is scaled by the reputation of its author, further
+// reduced by the number of blocks the author sealed recently.
+func (ethash *Ethash) SealTarget(chain consensus.ChainReader, header *types.Header) (*big.Int, error) {
 	////NEW change: add reputation
 	author, err := ethash.Author(header)
 	if err != nil {
-		return fmt.Errorf("invalid Author")
+		return nil, fmt.Errorf("invalid Author")
 	}
 	//reputation := ethash.GetReputationByContract(author)
 	//s = state.
@@ -771,7 +785,7 @@ func (ethash *Ethash) verifySeal(chain consensus.ChainReader, header *types.Head
 		if author == ReputationWhiteAddress {
 			reputation = ReputationInit
 		} else {
-			return fmt.Errorf("reputation is too low")
+			return nil, fmt.Errorf("reputation is too low")
 		}
 	}
 
@@ -818,11 +832,7 @@ func (ethash *Ethash) verifySeal(chain consensus.ChainReader, header *types.Head
 	//	target = new(big.Int).Div(two256, new(big.Int).Add(header.Difficulty, new(big.Int).SetUint64((reputation-ReputationInit)*repbase)))
 	//}
 	//println(target.String())
-
-	if new(big.Int).SetBytes(result).Cmp(target) > 0 {
-		return errInvalidPoW
-	}
-	return nil
+	return target, nil
 }
 
 // Prepare implements consensus.Engine, initializing the difficulty field of a
@@ -0,0 +1,272 @@
+// Copyright 2026 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package contract
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/binary"
+	"math/big"
+	"sort"
+	"strings"
+	"testing"
+
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+const (
+	testSectionSize     = 1
+	testProcessConfirms = 1
+	testGasLimit        = 10000000
+)
+
+// testKeys is a list of admin keys, sorted by address so that their votes are
+// accepted in order by the contract.
+type testKeys []*ecdsa.PrivateKey
+
+func (k testKeys) Len() int      { return len(k) }
+func (k testKeys) Swap(i, j int) { k[i], k[j] = k[j], k[i] }
+func (k testKeys) Less(i, j int) bool {
+	return bytes.Compare(crypto.PubkeyToAddress(k[i].PublicKey).Bytes(), crypto.PubkeyToAddress(k[j].PublicKey).Bytes()) < 0
+}
+
+// oracleEnv is a checkpoint oracle deployed on a simulated chain by the first
+// of its admins, requiring a threshold of them to vote for a checkpoint. A funded
+// outsider account is available to test admin checks.
+type oracleEnv struct {
+	backend  *backends.SimulatedBackend
+	oracle   *CheckpointOracle
+	address  common.Address
+	keys     testKeys
+	admins   []common.Address
+	outsider *ecdsa.PrivateKey
+	genesis  common.Hash
+}
+
+func newOracleEnv(t *testing.T, admins int, threshold int) *oracleEnv {
+	env := &oracleEnv{}
+	alloc := make(core.GenesisAlloc)
+	for i := 0; i < admins; i++ {
+		key, _ := crypto.GenerateKey()
+		env.keys = append(env.keys, key)
+	}
+	sort.Sort(env.keys)
+	for _, key := range env.keys {
+		addr := crypto.PubkeyToAddress(key.PublicKey)
+		env.admins = append(env.admins, addr)
+		alloc[addr] = core.GenesisAccount{Balance: big.NewInt(1000000000000000000)}
+	}
+	env.outsider, _ = crypto.GenerateKey()
+	alloc[crypto.PubkeyToAddress(env.outsider.PublicKey)] = core.GenesisAccount{Balance: big.NewInt(1000000000000000000)}
+
+	genesis := core.Genesis{Config: params.ReputationnetChainConfig, GasLimit: testGasLimit, Alloc: alloc}
+	env.genesis = genesis.ToBlock(nil).Hash()
+	env.backend = backends.NewSimulatedBackend(alloc, testGasLimit)
+
+	addr, _, oracle, err := DeployCheckpointOracle(bind.NewKeyedTransactor(env.keys[0]), env.backend, env.admins, big.NewInt(testSectionSize), big.NewInt(testProcessConfirms), big.NewInt(int64(threshold)))
+	if err != nil {
+		t.Fatalf("failed to deploy oracle: %v", err)
+	}
+	env.backend.Commit()
+	env.oracle, env.address = oracle, addr
+	return env
+}
+
+// vote signs a checkpoint with the given admin keys, returning the v, r and s
+// lists expected by SetCheckpoint.
+func (env *oracleEnv) vote(t *testing.T, signers []*ecdsa.PrivateKey, index uint64, hash common.Hash) ([]uint8, [][32]byte, [][32]byte) {
+	msg := make([]byte, 2+common.AddressLength+8+common.HashLength)
+	msg[0] = 0x19
+	copy(msg[2:], env.address.Bytes())
+	binary.BigEndian.PutUint64(msg[2+common.AddressLength:], index)
+	copy(msg[2+common.AddressLength+8:], hash.Bytes())
+
+	var (
+		v    []uint8
+		r, s [][32]byte
+	)
+	for _, key := range signers {
+		sig, err := crypto.Sign(crypto.Keccak256(msg), key)
+		if err != nil {
+			t.Fatalf("failed to sign checkpoint: %v", err)
+		}
+		v = append(v, sig[64]+27)
+		r = append(r, common.BytesToHash(sig[:32]))
+		s = append(s, common.BytesToHash(sig[32:64]))
+	}
+	return v, r, s
+}
+
+// setCheckpoint sends a SetCheckpoint transaction from the given key, voted for
+// by the signers, and mines it, returning the resulting receipt.
+func (env *oracleEnv) setCheckpoint(t *testing.T, key *ecdsa.PrivateKey, signers []*ecdsa.PrivateKey, index uint64, hash common.Hash) *types.Receipt {
+	opts := bind.NewKeyedTransactor(key)
+	opts.GasLimit = 1000000
+
+	v, r, s := env.vote(t, signers, index, hash)
+	tx, err := env.oracle.SetCheckpoint(opts, common.Big0, env.genesis, hash, index, v, r, s)
+	if err != nil {
+		t.Fatalf("failed to send checkpoint: %v", err)
+	}
+	env.backend.Commit()
+
+	receipt, err := env.backend.TransactionReceipt(context.Background(), tx.Hash())
+	if err != nil {
+		t.Fatalf("failed to retrieve receipt: %v", err)
+	}
+	return receipt
+}
+
+// Tests every method of the ABI through the generated binding.
+func TestOracleMethods(t *testing.T) {
+	parsed, err := abi.JSON(strings.NewReader(CheckpointOracleABI))
+	if err != nil {
+		t.Fatalf("failed to parse ABI: %v", err)
+	}
+	tests := map[string]func(t *testing.T, env *oracleEnv){
+		"GetAllAdmin": func(t *testing.T, env *oracleEnv) {
+			admins, err := env.oracle.GetAllAdmin(nil)
+			if err != nil {
+				t.Fatalf("failed to retrieve admins: %v", err)
+			}
+			if len(admins) != len(env.admins) {
+				t.Fatalf("admin count mismatch: have %d, want %d", len(admins), len(env.admins))
+			}
+			for i, admin := range admins {
+				if admin != env.admins[i] {
+					t.Errorf("admin %d mismatch: have %x, want %x", i, admin, env.admins[i])
+				}
+			}
+		},
+		"GetLatestCheckpoint": func(t *testing.T, env *oracleEnv) {
+			index, hash, height, err := env.oracle.GetLatestCheckpoint(nil)
+			if err != nil {
+				t.Fatalf("failed to retrieve latest checkpoint: %v", err)
+			}
+			if index != 0 || hash != (common.Hash{}) || height.Sign() != 0 {
+				t.Fatalf("initial checkpoint mismatch: have %d/%x/%v, want 0/%x/0", index, hash, height, common.Hash{})
+			}
+		},
+		"SetCheckpoint": func(t *testing.T, env *oracleEnv) {
+			hash := common.HexToHash("0xdeadbeef")
+
+			// Votes sent by a non admin are rejected
+			env.backend.Commit()
+			if receipt := env.setCheckpoint(t, env.outsider, env.keys, 0, hash); receipt.Status != types.ReceiptStatusFailed {
+				t.Fatalf("checkpoint accepted from non admin")
+			}
+			// Votes sent by an admin are accepted, emitting a vote event per signature
+			receipt := env.setCheckpoint(t, env.keys[1], env.keys, 0, hash)
+			if receipt.Status != types.ReceiptStatusSuccessful {
+				t.Fatalf("checkpoint registration failed")
+			}
+			index, have, height, err := env.oracle.GetLatestCheckpoint(nil)
+			if err != nil {
+				t.Fatalf("failed to retrieve latest checkpoint: %v", err)
+			}
+			if index != 0 || have != hash || height.Uint64() != 4 {
+				t.Fatalf("checkpoint mismatch: have %d/%x/%v, want 0/%x/4", index, have, height, hash)
+			}
+			it, err := env.oracle.FilterNewCheckpointVote(&bind.FilterOpts{Start: 0}, []uint64{0})
+			if err != nil {
+				t.Fatalf("failed to filter votes: %v", err)
+			}
+			defer it.Close()
+
+			v, r, s := env.vote(t, env.keys, 0, hash)
+			var votes int
+			for ; it.Next(); votes++ {
+				if votes >= len(v) {
+					continue
+				}
+				if it.Event.CheckpointHash != hash || it.Event.V != v[votes] || it.Event.R != r[votes] || it.Event.S != s[votes] {
+					t.Errorf("vote %d mismatch: have %x/%d/%x/%x", votes, it.Event.CheckpointHash, it.Event.V, it.Event.R, it.Event.S)
+				}
+			}
+			if votes != len(v) {
+				t.Fatalf("vote count mismatch: have %d, want %d", votes, len(v))
+			}
+		},
+	}
+	for name := range parsed.Methods {
+		test, ok := tests[name]
+		if !ok {
+			t.Errorf("ABI method %s not tested", name)
+			continue
+		}
+		t.Run(name, func(t *testing.T) { test(t, newOracleEnv(t, 2, 2)) })
+	}
+	if len(tests) != len(parsed.Methods) {
+		t.Errorf("method count mismatch: have %d, want %d", len(tests), len(parsed.Methods))
+	}
+}
+
+// Tests that calls to selectors outside of the ABI are rejected instead of
+// falling through to one of the methods.
+func TestOracleUnknownMethod(t *testing.T) {
+	env := newOracleEnv(t, 2, 2)
+
+	call := ethereum.CallMsg{To: &env.address, Data: []byte{0xde, 0xad, 0xbe, 0xef}}
+	if out, err := env.backend.CallContract(context.Background(), call, nil); err == nil && len(out) != 0 {
+		t.Fatalf("unknown selector returned data: %x", out)
+	}
+}
+
+// Tests that a checkpoint is only registered once a threshold of distinct admins
+// voted for it, with their signatures submitted in address order.
+func TestOracleThreshold(t *testing.T) {
+	env := newOracleEnv(t, 3, 2)
+	env.backend.Commit()
+
+	hash := common.HexToHash("0xdeadbeef")
+	tests := []struct {
+		signers []*ecdsa.PrivateKey
+		success bool
+	}{
+		{[]*ecdsa.PrivateKey{env.keys[0]}, false},               // below the threshold
+		{[]*ecdsa.PrivateKey{env.keys[0], env.keys[0]}, false},  // same admin voting twice
+		{[]*ecdsa.PrivateKey{env.keys[1], env.keys[0]}, false},  // votes out of order
+		{[]*ecdsa.PrivateKey{env.keys[0], env.outsider}, false}, // non admin vote
+		{[]*ecdsa.PrivateKey{env.keys[0], env.keys[2]}, true},   // threshold reached
+	}
+	for i, tt := range tests {
+		receipt := env.setCheckpoint(t, env.keys[0], tt.signers, 0, hash)
+		if success := receipt.Status == types.ReceiptStatusSuccessful; success != tt.success {
+			t.Fatalf("test %d: registration mismatch: have %v, want %v", i, success, tt.success)
+		}
+		_, have, _, err := env.oracle.GetLatestCheckpoint(nil)
+		if err != nil {
+			t.Fatalf("test %d: failed to retrieve latest checkpoint: %v", i, err)
+		}
+		want := common.Hash{}
+		if tt.success {
+			want = hash
+		}
+		if have != want {
+			t.Fatalf("test %d: checkpoint mismatch: have %x, want %x", i, have, want)
+		}
+	}
+}
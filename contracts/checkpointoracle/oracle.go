@@ -0,0 +1,166 @@
+// Copyright 2026 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package checkpointoracle is an on-chain light client checkpoint oracle.
+package checkpointoracle
+
+//go:generate abigen --sol contract/oracle.sol --pkg contract --out contract/oracle.go
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/contracts/checkpointoracle/contract"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var errInvalidSignature = errors.New("invalid checkpoint signature")
+
+// CheckpointOracle is a Go wrapper around an on-chain checkpoint oracle contract.
+type CheckpointOracle struct {
+	address  common.Address
+	contract *contract.CheckpointOracle
+	abi      abi.ABI
+}
+
+// NewCheckpointOracle binds checkpoint contract and returns a registrar instance.
+func NewCheckpointOracle(contractAddr common.Address, backend bind.ContractBackend) (*CheckpointOracle, error) {
+	c, err := contract.NewCheckpointOracle(contractAddr, backend)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := abi.JSON(strings.NewReader(contract.CheckpointOracleABI))
+	if err != nil {
+		return nil, err
+	}
+	return &CheckpointOracle{address: contractAddr, contract: c, abi: parsed}, nil
+}
+
+// ContractAddr returns the address of contract.
+func (oracle *CheckpointOracle) ContractAddr() common.Address {
+	return oracle.address
+}
+
+// Contract returns the underlying contract instance.
+func (oracle *CheckpointOracle) Contract() *contract.CheckpointOracle {
+	return oracle.contract
+}
+
+// SigningHash returns the hash an admin has to sign to vote for a checkpoint,
+// following the EIP-191 "data with intended validator" scheme:
+//
+//   keccak256(0x19 || 0x00 || oracle address || section index || checkpoint hash)
+func SigningHash(oracle common.Address, index uint64, hash common.Hash) common.Hash {
+	buf := make([]byte, 2+common.AddressLength+8+common.HashLength)
+	buf[0] = 0x19
+	copy(buf[2:], oracle.Bytes())
+	binary.BigEndian.PutUint64(buf[2+common.AddressLength:], index)
+	copy(buf[2+common.AddressLength+8:], hash.Bytes())
+	return crypto.Keccak256Hash(buf)
+}
+
+// LookupCheckpointEvents searches checkpoint event for specific section in the
+// given log batches.
+func (oracle *CheckpointOracle) LookupCheckpointEvents(blockLogs [][]*types.Log, section uint64, hash common.Hash) []*contract.CheckpointOracleNewCheckpointVote {
+	var votes []*contract.CheckpointOracleNewCheckpointVote
+
+	event := oracle.abi.Events["NewCheckpointVote"]
+	for _, logs := range blockLogs {
+		for _, log := range logs {
+			if log.Address != oracle.address || len(log.Topics) != 2 || log.Topics[0] != event.Id() {
+				continue
+			}
+			vote := new(contract.CheckpointOracleNewCheckpointVote)
+			if err := oracle.abi.Unpack(vote, "NewCheckpointVote", log.Data); err != nil {
+				continue
+			}
+			vote.Index = new(big.Int).SetBytes(log.Topics[1].Bytes()).Uint64()
+			vote.Raw = *log
+			if vote.Index == section && common.Hash(vote.CheckpointHash) == hash {
+				votes = append(votes, vote)
+			}
+		}
+	}
+	return votes
+}
+
+// RegisterCheckpoint registers the checkpoint with a batch of associated signatures
+// that are collected off-chain and sorted by lexicographical order.
+//
+// Notably all signatures given should be transformed to "ethereum style" which transforms
+// v from 0/1 to 27/28 according to the yellow paper.
+func (oracle *CheckpointOracle) RegisterCheckpoint(opts *bind.TransactOpts, index uint64, hash common.Hash, rnum *big.Int, rhash [32]byte, sigs [][]byte) (*types.Transaction, error) {
+	var (
+		r [][32]byte
+		s [][32]byte
+		v []uint8
+	)
+	for i := 0; i < len(sigs); i++ {
+		if len(sigs[i]) != 65 {
+			return nil, errInvalidSignature
+		}
+		r = append(r, common.BytesToHash(sigs[i][:32]))
+		s = append(s, common.BytesToHash(sigs[i][32:64]))
+		v = append(v, sigs[i][64])
+	}
+	return oracle.contract.SetCheckpoint(opts, rnum, rhash, hash, index, v, r, s)
+}
+
+// SortSignatures sorts the signatures of a checkpoint vote by the address of
+// their signers, as required by the contract to avoid counting a vote twice.
+func SortSignatures(oracle common.Address, index uint64, hash common.Hash, sigs [][]byte) ([][]byte, error) {
+	sighash := SigningHash(oracle, index, hash)
+
+	sorted := make(signatures, len(sigs))
+	for i, sig := range sigs {
+		if len(sig) != 65 || sig[64] < 27 {
+			return nil, errInvalidSignature
+		}
+		plain := common.CopyBytes(sig)
+		plain[64] -= 27
+		pubkey, err := crypto.SigToPub(sighash.Bytes(), plain)
+		if err != nil {
+			return nil, err
+		}
+		sorted[i] = signature{signer: crypto.PubkeyToAddress(*pubkey), sig: sig}
+	}
+	sort.Sort(sorted)
+
+	result := make([][]byte, len(sorted))
+	for i, s := range sorted {
+		result[i] = s.sig
+	}
+	return result, nil
+}
+
+type signature struct {
+	signer common.Address
+	sig    []byte
+}
+
+type signatures []signature
+
+func (s signatures) Len() int           { return len(s) }
+func (s signatures) Less(i, j int) bool { return bytes.Compare(s[i].signer[:], s[j].signer[:]) < 0 }
+func (s signatures) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
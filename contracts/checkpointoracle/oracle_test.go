@@ -0,0 +1,223 @@
+// Copyright 2026 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package checkpointoracle
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/contracts/checkpointoracle/contract"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+const (
+	testSectionSize     = 8
+	testProcessConfirms = 2
+	testThreshold       = 2
+	testGasLimit        = 10000000
+)
+
+// testOracle is a checkpoint oracle deployed on a simulated chain, administered
+// by three admins with a threshold of two signatures.
+type testOracle struct {
+	*CheckpointOracle
+	backend *backends.SimulatedBackend
+	keys    []*ecdsa.PrivateKey
+	genesis common.Hash
+}
+
+func newTestOracle(t *testing.T) *testOracle {
+	var (
+		keys   []*ecdsa.PrivateKey
+		admins []common.Address
+		alloc  = make(core.GenesisAlloc)
+	)
+	for i := 0; i < 3; i++ {
+		key, _ := crypto.GenerateKey()
+		keys = append(keys, key)
+		admins = append(admins, crypto.PubkeyToAddress(key.PublicKey))
+		alloc[admins[i]] = core.GenesisAccount{Balance: big.NewInt(1000000000000000000)}
+	}
+	genesis := core.Genesis{Config: params.ReputationnetChainConfig, GasLimit: testGasLimit, Alloc: alloc}
+	backend := backends.NewSimulatedBackend(alloc, testGasLimit)
+
+	addr, _, _, err := contract.DeployCheckpointOracle(bind.NewKeyedTransactor(keys[0]), backend, admins, big.NewInt(testSectionSize), big.NewInt(testProcessConfirms), big.NewInt(testThreshold))
+	if err != nil {
+		t.Fatalf("failed to deploy oracle: %v", err)
+	}
+	backend.Commit()
+
+	oracle, err := NewCheckpointOracle(addr, backend)
+	if err != nil {
+		t.Fatalf("failed to bind oracle: %v", err)
+	}
+	return &testOracle{CheckpointOracle: oracle, backend: backend, keys: keys, genesis: genesis.ToBlock(nil).Hash()}
+}
+
+// sign creates the votes of the given admins for a checkpoint.
+func (o *testOracle) sign(t *testing.T, index uint64, hash common.Hash, admins ...int) [][]byte {
+	var sigs [][]byte
+	for _, admin := range admins {
+		sig, err := crypto.Sign(SigningHash(o.ContractAddr(), index, hash).Bytes(), o.keys[admin])
+		if err != nil {
+			t.Fatalf("failed to sign checkpoint: %v", err)
+		}
+		sig[64] += 27
+		sigs = append(sigs, sig)
+	}
+	return sigs
+}
+
+// register submits a checkpoint registration from the given admin, returning
+// the receipt of the transaction.
+func (o *testOracle) register(t *testing.T, from int, index uint64, hash common.Hash, sigs [][]byte) *types.Receipt {
+	opts := bind.NewKeyedTransactor(o.keys[from])
+	opts.GasLimit = 1000000
+
+	tx, err := o.RegisterCheckpoint(opts, index, hash, common.Big0, o.genesis, sigs)
+	if err != nil {
+		t.Fatalf("failed to register checkpoint: %v", err)
+	}
+	o.backend.Commit()
+
+	receipt, err := o.backend.TransactionReceipt(context.Background(), tx.Hash())
+	if err != nil {
+		t.Fatalf("failed to retrieve receipt: %v", err)
+	}
+	return receipt
+}
+
+// checkLatest checks the latest checkpoint registered in the oracle.
+func (o *testOracle) checkLatest(t *testing.T, index uint64, hash common.Hash) {
+	have, haveHash, _, err := o.Contract().GetLatestCheckpoint(nil)
+	if err != nil {
+		t.Fatalf("failed to retrieve latest checkpoint: %v", err)
+	}
+	if have != index || common.Hash(haveHash) != hash {
+		t.Fatalf("latest checkpoint mismatch: have %d/%x, want %d/%x", have, haveHash, index, hash)
+	}
+}
+
+func (o *testOracle) commitBlocks(n int) {
+	for i := 0; i < n; i++ {
+		o.backend.Commit()
+	}
+}
+
+func testCheckpoint(index uint64) *params.TrustedCheckpoint {
+	return &params.TrustedCheckpoint{
+		SectionIndex: index,
+		SectionHead:  common.Hash{byte(index), 1},
+		CHTRoot:      common.Hash{byte(index), 2},
+		BloomRoot:    common.Hash{byte(index), 3},
+	}
+}
+
+func TestCheckpointOracleAdmins(t *testing.T) {
+	oracle := newTestOracle(t)
+
+	admins, err := oracle.Contract().GetAllAdmin(nil)
+	if err != nil {
+		t.Fatalf("failed to retrieve admins: %v", err)
+	}
+	if len(admins) != len(oracle.keys) {
+		t.Fatalf("admin count mismatch: have %d, want %d", len(admins), len(oracle.keys))
+	}
+	for i, key := range oracle.keys {
+		if want := crypto.PubkeyToAddress(key.PublicKey); admins[i] != want {
+			t.Errorf("admin %d mismatch: have %x, want %x", i, admins[i], want)
+		}
+	}
+}
+
+func TestCheckpointOracleRegister(t *testing.T) {
+	oracle := newTestOracle(t)
+	oracle.checkLatest(t, 0, common.Hash{})
+
+	cp := testCheckpoint(0)
+	sigs, err := SortSignatures(oracle.ContractAddr(), cp.SectionIndex, cp.Hash(), oracle.sign(t, cp.SectionIndex, cp.Hash(), 0, 1, 2))
+	if err != nil {
+		t.Fatalf("failed to sort signatures: %v", err)
+	}
+	// Checkpoints of sections not yet processed are ignored
+	if receipt := oracle.register(t, 0, cp.SectionIndex, cp.Hash(), sigs); receipt.Status != types.ReceiptStatusSuccessful {
+		t.Fatalf("future checkpoint registration failed")
+	}
+	oracle.checkLatest(t, 0, common.Hash{})
+
+	oracle.commitBlocks(testSectionSize + testProcessConfirms)
+
+	// Not enough, unordered or foreign signatures are rejected
+	if receipt := oracle.register(t, 0, cp.SectionIndex, cp.Hash(), sigs[:1]); receipt.Status != types.ReceiptStatusFailed {
+		t.Errorf("checkpoint accepted below threshold")
+	}
+	if receipt := oracle.register(t, 0, cp.SectionIndex, cp.Hash(), [][]byte{sigs[1], sigs[0]}); receipt.Status != types.ReceiptStatusFailed {
+		t.Errorf("checkpoint accepted with unordered signatures")
+	}
+	if receipt := oracle.register(t, 0, cp.SectionIndex, cp.Hash(), [][]byte{sigs[0], sigs[0]}); receipt.Status != types.ReceiptStatusFailed {
+		t.Errorf("checkpoint accepted with duplicate signatures")
+	}
+	foreign, _ := crypto.GenerateKey()
+	oracle.keys = append(oracle.keys, foreign)
+	if receipt := oracle.register(t, 0, cp.SectionIndex, cp.Hash(), oracle.sign(t, cp.SectionIndex, cp.Hash(), 3, 3)); receipt.Status != types.ReceiptStatusFailed {
+		t.Errorf("checkpoint accepted with foreign signatures")
+	}
+	oracle.checkLatest(t, 0, common.Hash{})
+
+	// Enough signatures register the checkpoint
+	receipt := oracle.register(t, 1, cp.SectionIndex, cp.Hash(), sigs)
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		t.Fatalf("checkpoint registration failed")
+	}
+	oracle.checkLatest(t, cp.SectionIndex, cp.Hash())
+
+	votes := oracle.LookupCheckpointEvents([][]*types.Log{receipt.Logs}, cp.SectionIndex, cp.Hash())
+	if len(votes) != testThreshold {
+		t.Fatalf("vote count mismatch: have %d, want %d", len(votes), testThreshold)
+	}
+	for i, vote := range votes {
+		sig := append(append(vote.R[:], vote.S[:]...), vote.V)
+		if !bytes.Equal(sig, sigs[i]) {
+			t.Errorf("vote %d signature mismatch: have %x, want %x", i, sig, sigs[i])
+		}
+	}
+	if votes := oracle.LookupCheckpointEvents([][]*types.Log{receipt.Logs}, cp.SectionIndex+1, cp.Hash()); len(votes) != 0 {
+		t.Errorf("votes found for unregistered section: %d", len(votes))
+	}
+	// Stale checkpoints are ignored, newer ones accepted
+	stale := testCheckpoint(0)
+	stale.CHTRoot = common.Hash{0xff}
+	oracle.register(t, 0, stale.SectionIndex, stale.Hash(), oracle.sign(t, stale.SectionIndex, stale.Hash(), 0, 1, 2))
+	oracle.checkLatest(t, cp.SectionIndex, cp.Hash())
+
+	next := testCheckpoint(1)
+	oracle.commitBlocks(testSectionSize)
+	sigs, _ = SortSignatures(oracle.ContractAddr(), next.SectionIndex, next.Hash(), oracle.sign(t, next.SectionIndex, next.Hash(), 2, 0))
+	if receipt := oracle.register(t, 2, next.SectionIndex, next.Hash(), sigs); receipt.Status != types.ReceiptStatusSuccessful {
+		t.Fatalf("next checkpoint registration failed")
+	}
+	oracle.checkLatest(t, next.SectionIndex, next.Hash())
+}
@@ -33,6 +33,7 @@ import (
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -61,6 +62,9 @@ const (
 	badBlockLimit       = 10
 	triesInMemory       = 128
 
+	freezerRecheckInterval = time.Minute // Time interval to check for chain data to freeze
+	freezerBatchLimit      = 30000       // Maximum number of blocks to freeze in one round
+
 	// BlockChainVersion ensures that an incompatible database forces a resync from scratch.
 	BlockChainVersion = 3
 )
@@ -72,6 +76,17 @@ type CacheConfig struct {
 	TrieCleanLimit int           // Memory allowance (MB) to use for caching trie nodes in memory
 	TrieDirtyLimit int           // Memory limit (MB) at which to start flushing dirty trie nodes to disk
 	TrieTimeLimit  time.Duration // Time limit after which to flush the current in-memory trie to disk
+
+	AncientThreshold uint64 // Number of recent blocks to keep in the key-value store before freezing (0 = disabled)
+	Snapshot         bool   // Whether to maintain a flat state snapshot for fast state reads
+}
+
+// ancientStore is a database capable of moving immutable chain segments out of
+// the key-value store into an append-only ancient store.
+type ancientStore interface {
+	ethdb.Database
+	rawdb.AncientReader
+	rawdb.AncientWriter
 }
 
 // BlockChain represents the canonical chain given a database with a genesis
@@ -114,6 +129,7 @@ type BlockChain struct {
 	currentFastBlock atomic.Value // Current head of the fast-sync chain (may be above the block chain!)
 
 	stateCache    state.Database // State database to reuse between imports (contains state cache)
+	snaps         *snapshot.Tree // Flat state snapshot for fast state reads (nil if disabled)
 	bodyCache     *lru.Cache     // Cache for the most recent block bodies
 	bodyRLPCache  *lru.Cache     // Cache for the most recent block bodies in RLP encoded format
 	receiptsCache *lru.Cache     // Cache for the most recent receipts per block
@@ -198,6 +214,23 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 			}
 		}
 	}
+	// Load any existing flat state snapshot, regenerating it in the background if unusable
+	if cacheConfig.Snapshot {
+		bc.snaps = snapshot.New(bc.db, bc.stateCache.TrieDB(), bc.CurrentBlock().Root(), true)
+	}
+	// Start moving old chain segments into the ancient store, if one is available
+	if db, ok := db.(ancientStore); ok {
+		// Drop any ancient data above the chain head, left over by a crashed rewind
+		if frozen, _ := db.Ancients(); frozen > bc.CurrentHeader().Number.Uint64()+1 {
+			if err := db.TruncateAncients(bc.CurrentHeader().Number.Uint64() + 1); err != nil {
+				return nil, err
+			}
+		}
+		if cacheConfig.AncientThreshold > 0 {
+			bc.wg.Add(1)
+			go bc.freezeLoop(db)
+		}
+	}
 	// Take ownership of this particular state
 	go bc.update()
 	return bc, nil
@@ -283,6 +316,13 @@ func (bc *BlockChain) SetHead(head uint64) error {
 	bc.hc.SetHead(head, delFn)
 	currentHeader := bc.hc.CurrentHeader()
 
+	// Discard any frozen chain data above the new head
+	if db, ok := bc.db.(ancientStore); ok {
+		if err := db.TruncateAncients(currentHeader.Number.Uint64() + 1); err != nil {
+			log.Error("Failed to truncate ancient store", "err", err)
+		}
+	}
+
 	// Clear out any stale content from the caches
 	bc.bodyCache.Purge()
 	bc.bodyRLPCache.Purge()
@@ -317,7 +357,14 @@ func (bc *BlockChain) SetHead(head uint64) error {
 	rawdb.WriteHeadBlockHash(bc.db, currentBlock.Hash())
 	rawdb.WriteHeadFastBlockHash(bc.db, currentFastBlock.Hash())
 
-	return bc.loadLastState()
+	if err := bc.loadLastState(); err != nil {
+		return err
+	}
+	// The snapshot cannot be rewound, regenerate it for the new head
+	if bc.snaps != nil {
+		bc.snaps.Rebuild(bc.CurrentBlock().Root())
+	}
+	return nil
 }
 
 // FastSyncCommitHead sets the current head block to the one defined by the hash
@@ -392,7 +439,7 @@ func (bc *BlockChain) State() (*state.StateDB, error) {
 
 // StateAt returns a new mutable state based on a particular point in time.
 func (bc *BlockChain) StateAt(root common.Hash) (*state.StateDB, error) {
-	return state.New(root, bc.stateCache)
+	return state.NewWithSnapshot(root, bc.stateCache, bc.snaps)
 }
 
 // StateCache returns the caching database underpinning the blockchain instance.
@@ -691,6 +738,12 @@ func (bc *BlockChain) Stop() {
 
 	bc.wg.Wait()
 
+	// Persist the snapshot diff layers to avoid regenerating it after the restart
+	if bc.snaps != nil {
+		if err := bc.snaps.Journal(bc.CurrentBlock().Root()); err != nil {
+			log.Warn("Failed to journal state snapshot", "err", err)
+		}
+	}
 	// Ensure the state of a recent block is also stored to disk before exiting.
 	// We're writing three different states to catch different restart scenarios:
 	//  - HEAD:     So we don't need to reprocess any blocks in the general case
@@ -1035,6 +1088,13 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 	// Set new head.
 	if status == CanonStatTy {
 		bc.insert(block)
+
+		// Flatten the snapshot diff layers beyond the in-memory tries into the disk layer
+		if bc.snaps != nil {
+			if err := bc.snaps.Cap(block.Root(), triesInMemory); err != nil {
+				log.Warn("Failed to cap snapshot tree", "root", block.Root(), "layers", triesInMemory, "err", err)
+			}
+		}
 	}
 	bc.futureBlocks.Remove(block.Hash())
 	return status, nil
@@ -1183,7 +1243,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool) (int, []
 		if parent == nil {
 			parent = bc.GetBlock(block.ParentHash(), block.NumberU64()-1)
 		}
-		state, err := state.New(parent.Root(), bc.stateCache)
+		state, err := state.NewWithSnapshot(parent.Root(), bc.stateCache, bc.snaps)
 		if err != nil {
 			return it.index, events, coalescedLogs, err
 		}
@@ -1519,6 +1579,140 @@ func (bc *BlockChain) update() {
 	}
 }
 
+// freezeLoop periodically moves canonical chain segments older than the ancient
+// threshold out of the key-value store into the ancient store.
+func (bc *BlockChain) freezeLoop(db ancientStore) {
+	defer bc.wg.Done()
+
+	ticker := time.NewTicker(freezerRecheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-bc.quit:
+			return
+		}
+		for {
+			frozen, err := bc.freeze(db)
+			if err != nil {
+				log.Error("Failed to freeze ancient chain data", "err", err)
+			}
+			// Keep freezing until we caught up or were asked to stop
+			if err != nil || frozen < freezerBatchLimit || atomic.LoadInt32(&bc.procInterrupt) == 1 {
+				break
+			}
+		}
+	}
+}
+
+// freeze moves a batch of canonical blocks older than the ancient threshold out
+// of the key-value store into the ancient store, returning the number of blocks
+// moved. Any side chain blocks at the frozen heights are pruned along the way.
+// The data is only deleted from the key-value store after the ancient store has
+// been flushed to disk, so a crash can never lose chain data.
+func (bc *BlockChain) freeze(db ancientStore) (int, error) {
+	first, err := db.Ancients()
+	if err != nil {
+		return 0, err
+	}
+	var (
+		start  = time.Now()
+		hashes []common.Hash
+	)
+	for number := first; len(hashes) < freezerBatchLimit; number++ {
+		if atomic.LoadInt32(&bc.procInterrupt) == 1 {
+			break
+		}
+		frozen, err := bc.freezeBlock(db, number)
+		if err != nil {
+			return 0, err
+		}
+		if frozen == (common.Hash{}) {
+			break
+		}
+		hashes = append(hashes, frozen)
+	}
+	if len(hashes) == 0 {
+		return 0, nil
+	}
+	if err := db.Sync(); err != nil {
+		return 0, err
+	}
+	// Ancient data is safely on disk, wipe it from the key-value store
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	batch := db.NewBatch()
+	for i, hash := range hashes {
+		number := first + uint64(i)
+
+		rawdb.DeleteCanonicalHash(batch, number)
+		rawdb.DeleteHeaderWithoutNumber(batch, hash, number)
+		rawdb.DeleteBody(batch, hash, number)
+		rawdb.DeleteReceipts(batch, hash, number)
+		rawdb.DeleteTd(batch, hash, number)
+
+		// Side chains at frozen heights can never become canonical again, drop them
+		siblings, err := rawdb.ReadAllHashes(db, number)
+		if err != nil {
+			return 0, err
+		}
+		for _, sibling := range siblings {
+			if sibling != hash {
+				rawdb.DeleteBlock(batch, sibling, number)
+			}
+		}
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return 0, err
+			}
+			batch.Reset()
+		}
+	}
+	if err := batch.Write(); err != nil {
+		return 0, err
+	}
+	log.Info("Moved chain segment into ancient store", "blocks", len(hashes), "from", first, "to", first+uint64(len(hashes))-1, "elapsed", common.PrettyDuration(time.Since(start)))
+	return len(hashes), nil
+}
+
+// freezeBlock moves the canonical block at the given height into the ancient
+// store if it is old enough, returning its hash or the zero hash if it is not.
+func (bc *BlockChain) freezeBlock(db ancientStore, number uint64) (common.Hash, error) {
+	// Hold the chain lock so the head can't be rewound while we're freezing
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	if head := bc.CurrentBlock().NumberU64(); head < bc.cacheConfig.AncientThreshold || number > head-bc.cacheConfig.AncientThreshold {
+		return common.Hash{}, nil
+	}
+	hash := rawdb.ReadCanonicalHash(db, number)
+	if hash == (common.Hash{}) {
+		return common.Hash{}, fmt.Errorf("canonical hash missing, can't freeze block %d", number)
+	}
+	header := rawdb.ReadHeaderRLP(db, hash, number)
+	if len(header) == 0 {
+		return common.Hash{}, fmt.Errorf("block header missing, can't freeze block %d", number)
+	}
+	body := rawdb.ReadBodyRLP(db, hash, number)
+	if len(body) == 0 {
+		return common.Hash{}, fmt.Errorf("block body missing, can't freeze block %d", number)
+	}
+	receipts := rawdb.ReadReceiptsRLP(db, hash, number)
+	if len(receipts) == 0 {
+		return common.Hash{}, fmt.Errorf("block receipts missing, can't freeze block %d", number)
+	}
+	td := rawdb.ReadTdRLP(db, hash, number)
+	if len(td) == 0 {
+		return common.Hash{}, fmt.Errorf("total difficulty missing, can't freeze block %d", number)
+	}
+	if err := db.AppendAncient(number, hash.Bytes(), header, body, receipts, td); err != nil {
+		return common.Hash{}, err
+	}
+	return hash, nil
+}
+
 // BadBlocks returns a list of the last 'bad blocks' that the client has seen on the network
 func (bc *BlockChain) BadBlocks() []*types.Block {
 	blocks := make([]*types.Block, 0, bc.badBlocks.Len())
@@ -18,8 +18,10 @@ package core
 
 import (
 	"fmt"
+	"io/ioutil"
 	"math/big"
 	"math/rand"
+	"os"
 	"sync"
 	"testing"
 	"time"
@@ -1483,3 +1485,171 @@ func BenchmarkBlockChain_1x1000Executions(b *testing.B) {
 
 	benchmarkLargeNumberOfValueToNonexisting(b, numTxs, numBlocks, recipientFn, dataFn)
 }
+
+// Tests that canonical blocks older than the ancient threshold are moved into
+// the ancient store, remain retrievable, and that the ancient store is kept in
+// sync with the chain across rewinds and restarts.
+func TestFreezeAncients(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var (
+		kvdb    = ethdb.NewMemDatabase()
+		gspec   = &Genesis{Config: params.TestChainConfig}
+		genesis = gspec.MustCommit(kvdb)
+		cache   = &CacheConfig{TrieCleanLimit: 256, TrieDirtyLimit: 256, TrieTimeLimit: 5 * time.Minute, AncientThreshold: 16}
+	)
+	blocks, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), ethdb.NewMemDatabase(), 64, nil)
+
+	db, err := rawdb.NewDatabaseWithFreezer(kvdb, dir, "")
+	if err != nil {
+		t.Fatalf("failed to create database with freezer: %v", err)
+	}
+	chain, err := NewBlockChain(db, cache, gspec.Config, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	if n, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("block %d: failed to insert into chain: %v", n, err)
+	}
+	// Freeze the old blocks and ensure they moved out of the key-value store
+	if n, err := chain.freeze(db.(ancientStore)); err != nil {
+		t.Fatalf("failed to freeze blocks: %v", err)
+	} else if n != 49 {
+		t.Fatalf("frozen block count mismatch: have %d, want %d", n, 49)
+	}
+	if n, err := chain.freeze(db.(ancientStore)); err != nil || n != 0 {
+		t.Fatalf("refreeze mismatch: have %d/%v, want %d/nil", n, err, 0)
+	}
+	for i := uint64(0); i <= 48; i++ {
+		hash := rawdb.ReadCanonicalHash(db, i)
+		if rawdb.HasHeader(kvdb, hash, i) || rawdb.HasBody(kvdb, hash, i) || rawdb.HasReceipts(kvdb, hash, i) {
+			t.Fatalf("block %d: frozen data retained in key-value store", i)
+		}
+	}
+	checkBlocks := func(head uint64) {
+		for i := uint64(0); i <= head; i++ {
+			block := chain.GetBlockByNumber(i)
+			if block == nil {
+				t.Fatalf("block %d: missing", i)
+			}
+			if i > 0 && block.Hash() != blocks[i-1].Hash() {
+				t.Fatalf("block %d: hash mismatch: have %x, want %x", i, block.Hash(), blocks[i-1].Hash())
+			}
+			if receipts := chain.GetReceiptsByHash(block.Hash()); receipts == nil {
+				t.Fatalf("block %d: receipts missing", i)
+			}
+			if td := chain.GetTd(block.Hash(), i); td == nil {
+				t.Fatalf("block %d: total difficulty missing", i)
+			}
+		}
+	}
+	checkBlocks(64)
+
+	// Rewind the chain below the frozen limit and ensure the ancients follow
+	if err := chain.SetHead(20); err != nil {
+		t.Fatalf("failed to rewind chain: %v", err)
+	}
+	if frozen, _ := db.(ancientStore).Ancients(); frozen != 21 {
+		t.Fatalf("frozen block count mismatch after rewind: have %d, want %d", frozen, 21)
+	}
+	checkBlocks(20)
+	if block := chain.GetBlockByNumber(21); block != nil {
+		t.Fatalf("rewound block retained: %v", block)
+	}
+	chain.Stop()
+	db.Close()
+
+	// Reopen the database and chain, ensuring everything is retrievable
+	if db, err = rawdb.NewDatabaseWithFreezer(kvdb, dir, ""); err != nil {
+		t.Fatalf("failed to reopen database with freezer: %v", err)
+	}
+	defer db.Close()
+
+	if chain, err = NewBlockChain(db, cache, gspec.Config, ethash.NewFaker(), vm.Config{}, nil); err != nil {
+		t.Fatalf("failed to recreate chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if head := chain.CurrentBlock().NumberU64(); head != 20 {
+		t.Fatalf("chain head mismatch: have %d, want %d", head, 20)
+	}
+	checkBlocks(20)
+}
+
+// Tests that side chain blocks at heights moved into the ancient store are pruned
+// from the key-value store, while those above the ancient threshold are kept.
+func TestFreezeAncientsPrunesSideChains(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var (
+		kvdb    = ethdb.NewMemDatabase()
+		gspec   = &Genesis{Config: params.TestChainConfig}
+		genesis = gspec.MustCommit(kvdb)
+		gendb   = ethdb.NewMemDatabase()
+		cache   = &CacheConfig{TrieCleanLimit: 256, TrieDirtyLimit: 256, TrieTimeLimit: 5 * time.Minute, AncientThreshold: 16}
+	)
+	gspec.MustCommit(gendb)
+	blocks, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), gendb, 64, nil)
+
+	// Fork off two short side chains, one to be frozen and one to be retained
+	frozenSide, _ := GenerateChain(gspec.Config, blocks[9], ethash.NewFaker(), gendb, 5, func(i int, b *BlockGen) {
+		b.SetCoinbase(common.Address{0x01})
+	})
+	recentSide, _ := GenerateChain(gspec.Config, blocks[54], ethash.NewFaker(), gendb, 3, func(i int, b *BlockGen) {
+		b.SetCoinbase(common.Address{0x01})
+	})
+	db, err := rawdb.NewDatabaseWithFreezer(kvdb, dir, "")
+	if err != nil {
+		t.Fatalf("failed to create database with freezer: %v", err)
+	}
+	defer db.Close()
+
+	chain, err := NewBlockChain(db, cache, gspec.Config, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	for _, segment := range [][]*types.Block{blocks, frozenSide, recentSide} {
+		if n, err := chain.InsertChain(segment); err != nil {
+			t.Fatalf("block %d: failed to insert into chain: %v", n, err)
+		}
+	}
+	for _, block := range append(append([]*types.Block{}, frozenSide...), recentSide...) {
+		if !rawdb.HasHeader(kvdb, block.Hash(), block.NumberU64()) {
+			t.Fatalf("side block %d: missing before freezing", block.NumberU64())
+		}
+	}
+	if n, err := chain.freeze(db.(ancientStore)); err != nil {
+		t.Fatalf("failed to freeze blocks: %v", err)
+	} else if n != 49 {
+		t.Fatalf("frozen block count mismatch: have %d, want %d", n, 49)
+	}
+	for _, block := range frozenSide {
+		hash, number := block.Hash(), block.NumberU64()
+		if rawdb.HasHeader(kvdb, hash, number) || rawdb.HasBody(kvdb, hash, number) || rawdb.HasReceipts(kvdb, hash, number) {
+			t.Fatalf("side block %d: retained in key-value store", number)
+		}
+		if rawdb.ReadTd(kvdb, hash, number) != nil || rawdb.ReadHeaderNumber(kvdb, hash) != nil {
+			t.Fatalf("side block %d: metadata retained in key-value store", number)
+		}
+	}
+	for _, block := range recentSide {
+		if !rawdb.HasHeader(kvdb, block.Hash(), block.NumberU64()) || !rawdb.HasBody(kvdb, block.Hash(), block.NumberU64()) {
+			t.Fatalf("side block %d: pruned above the ancient threshold", block.NumberU64())
+		}
+	}
+	for i := uint64(0); i <= 64; i++ {
+		if block := chain.GetBlockByNumber(i); block == nil || (i > 0 && block.Hash() != blocks[i-1].Hash()) {
+			t.Fatalf("canonical block %d: missing or mismatched", i)
+		}
+	}
+}
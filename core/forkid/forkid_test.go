@@ -0,0 +1,95 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package forkid
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that fork identifiers are calculated correctly on mainnet.
+func TestCreation(t *testing.T) {
+	tests := []struct {
+		head uint64
+		want ID
+	}{
+		{0, ID{Hash: [4]byte{0xfc, 0x64, 0xec, 0x04}, Next: 1150000}},       // Unsynced
+		{1149999, ID{Hash: [4]byte{0xfc, 0x64, 0xec, 0x04}, Next: 1150000}}, // Last Frontier block
+		{1150000, ID{Hash: [4]byte{0x97, 0xc2, 0xc3, 0x4c}, Next: 1920000}}, // First Homestead block
+		{1920000, ID{Hash: [4]byte{0x91, 0xd1, 0xf9, 0x48}, Next: 2463000}}, // First DAO block
+		{2463000, ID{Hash: [4]byte{0x7a, 0x64, 0xda, 0x13}, Next: 2675000}}, // First Tangerine block
+		{2675000, ID{Hash: [4]byte{0x3e, 0xdd, 0x5b, 0x10}, Next: 4370000}}, // First Spurious block
+		{4370000, ID{Hash: [4]byte{0xa0, 0x0b, 0xc3, 0x24}, Next: 0}},       // First Byzantium block
+		{6000000, ID{Hash: [4]byte{0xa0, 0x0b, 0xc3, 0x24}, Next: 0}},       // Future Byzantium block
+	}
+	for i, tt := range tests {
+		if have := NewID(params.MainnetChainConfig, params.MainnetGenesisHash, tt.head); have != tt.want {
+			t.Errorf("test %d: fork ID mismatch: have %x, want %x", i, have, tt.want)
+		}
+	}
+}
+
+// Tests that remote fork identifiers are accepted or rejected based on the local
+// chain state.
+func TestValidation(t *testing.T) {
+	tests := []struct {
+		head uint64
+		id   ID
+		err  error
+	}{
+		// Local and remote are on the same fork, no future forks known
+		{4370000, ID{Hash: [4]byte{0xa0, 0x0b, 0xc3, 0x24}, Next: 0}, nil},
+
+		// Local and remote are on the same fork, remote announces an unknown future fork
+		{4370000, ID{Hash: [4]byte{0xa0, 0x0b, 0xc3, 0x24}, Next: 7280000}, nil},
+
+		// Local is synced past a fork remote announces as future, remote is incompatible
+		{4370000, ID{Hash: [4]byte{0x3e, 0xdd, 0x5b, 0x10}, Next: 4370001}, ErrRemoteStale},
+
+		// Remote is a syncing node behind us, announcing the correct next fork
+		{4370000, ID{Hash: [4]byte{0x3e, 0xdd, 0x5b, 0x10}, Next: 4370000}, nil},
+
+		// Local is syncing, remote is ahead on a fork we know about
+		{2675000, ID{Hash: [4]byte{0xa0, 0x0b, 0xc3, 0x24}, Next: 0}, nil},
+
+		// Remote is on a completely different chain
+		{4370000, ID{Hash: [4]byte{0xaf, 0xec, 0x6b, 0x27}, Next: 0}, ErrLocalIncompatibleOrStale},
+
+		// Remote announces a fork we already passed without having it
+		{6000000, ID{Hash: [4]byte{0xa0, 0x0b, 0xc3, 0x24}, Next: 5000000}, ErrLocalIncompatibleOrStale},
+	}
+	for i, tt := range tests {
+		filter := NewFilter(params.MainnetChainConfig, params.MainnetGenesisHash, func() uint64 { return tt.head })
+		if err := filter(tt.id); err != tt.err {
+			t.Errorf("test %d: validation error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+}
+
+// Tests that forks scheduled at the same block or at genesis are not counted
+// multiple times.
+func TestGatherForks(t *testing.T) {
+	forks := gatherForks(params.ReputationnetChainConfig)
+	if len(forks) != 0 {
+		t.Fatalf("genesis forks not omitted: %v", forks)
+	}
+	if id := NewID(params.ReputationnetChainConfig, common.Hash{}, 0); id.Next != 0 {
+		t.Fatalf("next fork mismatch: have %d, want %d", id.Next, 0)
+	}
+}
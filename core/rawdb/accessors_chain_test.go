@@ -18,7 +18,9 @@ package rawdb
 
 import (
 	"bytes"
+	"io/ioutil"
 	"math/big"
+	"os"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -317,3 +319,64 @@ func TestBlockReceiptStorage(t *testing.T) {
 		t.Fatalf("deleted receipts returned: %v", rs)
 	}
 }
+
+// Tests that chain data moved into the ancient store is transparently retrieved
+// by the regular accessors.
+func TestAncientStorage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := NewDatabaseWithFreezer(ethdb.NewMemDatabase(), dir, "")
+	if err != nil {
+		t.Fatalf("failed to create database with freezer: %v", err)
+	}
+	defer db.Close()
+
+	// Create a test block and move it straight into the ancient store
+	block := types.NewBlockWithHeader(&types.Header{
+		Number:      big.NewInt(0),
+		Extra:       []byte("test block"),
+		UncleHash:   types.EmptyUncleHash,
+		TxHash:      types.EmptyRootHash,
+		ReceiptHash: types.EmptyRootHash,
+	})
+	hash, number := block.Hash(), block.NumberU64()
+
+	if HasHeader(db, hash, number) || HasBody(db, hash, number) || HasReceipts(db, hash, number) {
+		t.Fatalf("non existent block data reported")
+	}
+	header, _ := rlp.EncodeToBytes(block.Header())
+	body, _ := rlp.EncodeToBytes(block.Body())
+	receipts, _ := rlp.EncodeToBytes([]*types.ReceiptForStorage{})
+	td, _ := rlp.EncodeToBytes(big.NewInt(1))
+
+	if err := db.(AncientWriter).AppendAncient(number, hash.Bytes(), header, body, receipts, td); err != nil {
+		t.Fatalf("failed to append ancient block: %v", err)
+	}
+	// Verify that all the data is retrievable through the usual accessors
+	if have := ReadCanonicalHash(db, number); have != hash {
+		t.Fatalf("canonical hash mismatch: have %x, want %x", have, hash)
+	}
+	if !HasHeader(db, hash, number) || !HasBody(db, hash, number) || !HasReceipts(db, hash, number) {
+		t.Fatalf("ancient block data not reported")
+	}
+	if entry := ReadHeader(db, hash, number); entry == nil || entry.Hash() != hash {
+		t.Fatalf("ancient header mismatch: have %v, want %v", entry, block.Header())
+	}
+	if entry := ReadBlock(db, hash, number); entry == nil || entry.Hash() != hash {
+		t.Fatalf("ancient block mismatch: have %v, want %v", entry, block)
+	}
+	if entry := ReadReceipts(db, hash, number); entry == nil || len(entry) != 0 {
+		t.Fatalf("ancient receipts mismatch: have %v, want empty receipts", entry)
+	}
+	if entry := ReadTd(db, hash, number); entry == nil || entry.Cmp(big.NewInt(1)) != 0 {
+		t.Fatalf("ancient total difficulty mismatch: have %v, want %v", entry, 1)
+	}
+	// Verify that a different block at the same height isn't served from the ancient store
+	if entry := ReadHeader(db, common.Hash{0x01}, number); entry != nil {
+		t.Fatalf("non-canonical ancient header returned: %v", entry)
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// freezerdb is a database wrapper that enables freezer data retrievals.
+type freezerdb struct {
+	ethdb.Database
+	*freezer
+}
+
+// NewDatabaseWithFreezer creates a high level database on top of a given key-
+// value data store with a freezer moving immutable chain segments into cold
+// storage in the given directory.
+func NewDatabaseWithFreezer(db ethdb.Database, freezer string, namespace string) (ethdb.Database, error) {
+	frdb, err := newFreezer(freezer, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return &freezerdb{
+		Database: db,
+		freezer:  frdb,
+	}, nil
+}
+
+// Close implements ethdb.Database, closing both the fast key-value store as
+// well as the slow ancient tables.
+func (frdb *freezerdb) Close() {
+	if err := frdb.freezer.Close(); err != nil {
+		log.Error("Failed to close ancient database", "err", err)
+	}
+	frdb.Database.Close()
+}
+
+// KeyValueStore returns the key-value store backing db, unwrapping it from any
+// ancient store layered on top of it to give access to backend specifics.
+func KeyValueStore(db ethdb.Database) ethdb.Database {
+	if frdb, ok := db.(*freezerdb); ok {
+		return frdb.Database
+	}
+	return db
+}
+
+// IterateKeys calls fn for every key-value pair with the given prefix stored in
+// the key-value store backing db, aborting at the first error. The key passed to
+// fn may be retained, the value may not.
+func IterateKeys(db ethdb.Database, prefix []byte, fn func(key, value []byte) error) error {
+	switch db := KeyValueStore(db).(type) {
+	case *ethdb.LDBDatabase:
+		it := db.NewIteratorWithPrefix(prefix)
+		defer it.Release()
+
+		for it.Next() {
+			if err := fn(common.CopyBytes(it.Key()), it.Value()); err != nil {
+				return err
+			}
+		}
+		return it.Error()
+
+	case *ethdb.MemDatabase:
+		for _, key := range db.Keys() {
+			if !bytes.HasPrefix(key, prefix) {
+				continue
+			}
+			value, err := db.Get(key)
+			if err != nil {
+				continue // Deleted meanwhile
+			}
+			if err := fn(key, value); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unsupported database type %T", db)
+	}
+}
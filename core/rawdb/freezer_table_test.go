@@ -0,0 +1,245 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// getChunk returns a chunk of data of the given size, filled with the byte b.
+func getChunk(size int, b int) []byte {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(b)
+	}
+	return data
+}
+
+// newTestTable opens a freezer table in the given directory with throwaway meters.
+func newTestTable(t *testing.T, dir string, name string) *freezerTable {
+	table, err := newTable(dir, name, metrics.NewMeter(), metrics.NewMeter())
+	if err != nil {
+		t.Fatalf("failed to open table: %v", err)
+	}
+	return table
+}
+
+// fillTable appends n items of increasing size and content to the table.
+func fillTable(t *testing.T, table *freezerTable, n int) {
+	for i := 0; i < n; i++ {
+		if err := table.Append(uint64(i), getChunk(i+1, i)); err != nil {
+			t.Fatalf("failed to append item %d: %v", i, err)
+		}
+	}
+	if err := table.Sync(); err != nil {
+		t.Fatalf("failed to sync table: %v", err)
+	}
+}
+
+// checkTable verifies that the table contains exactly n items written by fillTable.
+func checkTable(t *testing.T, table *freezerTable, n int) {
+	for i := 0; i < n; i++ {
+		blob, err := table.Retrieve(uint64(i))
+		if err != nil {
+			t.Fatalf("item %d: failed to retrieve: %v", i, err)
+		}
+		if want := getChunk(i+1, i); !bytes.Equal(blob, want) {
+			t.Fatalf("item %d: content mismatch: have %x, want %x", i, blob, want)
+		}
+	}
+	if _, err := table.Retrieve(uint64(n)); err != errOutOfBounds {
+		t.Fatalf("item %d: error mismatch: have %v, want %v", n, err, errOutOfBounds)
+	}
+}
+
+// Tests that items can be appended to and retrieved from a freezer table, also
+// across reopening it.
+func TestFreezerBasics(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	table := newTestTable(t, dir, "test")
+	fillTable(t, table, 255)
+	checkTable(t, table, 255)
+
+	if err := table.Append(300, []byte{0x01}); err != errOutOrderInsertion {
+		t.Fatalf("out of order append error mismatch: have %v, want %v", err, errOutOrderInsertion)
+	}
+	table.Close()
+
+	if _, err := table.Retrieve(0); err != errClosed {
+		t.Fatalf("closed retrieval error mismatch: have %v, want %v", err, errClosed)
+	}
+	table = newTestTable(t, dir, "test")
+	defer table.Close()
+
+	checkTable(t, table, 255)
+}
+
+// Tests that a table recovers from a crash which persisted the data file but
+// only part of the index file.
+func TestFreezerRepairDanglingData(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	table := newTestTable(t, dir, "test")
+	fillTable(t, table, 255)
+	table.Close()
+
+	// Cut the index in the middle of an entry, dropping the last items
+	idx, err := os.OpenFile(filepath.Join(dir, "test.ridx"), os.O_RDWR, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx.Truncate(100*indexEntrySize + 3)
+	idx.Close()
+
+	// Reopen the table and ensure the unindexed data was discarded
+	table = newTestTable(t, dir, "test")
+	checkTable(t, table, 100)
+
+	// Ensure writes continue where the recovered table left off
+	for i := 100; i < 255; i++ {
+		if err := table.Append(uint64(i), getChunk(i+1, i)); err != nil {
+			t.Fatalf("failed to append item %d: %v", i, err)
+		}
+	}
+	table.Close()
+
+	table = newTestTable(t, dir, "test")
+	defer table.Close()
+
+	checkTable(t, table, 255)
+}
+
+// Tests that a table recovers from a crash which persisted the index file but
+// lost the tail of the data file.
+func TestFreezerRepairDanglingIndex(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	table := newTestTable(t, dir, "test")
+	fillTable(t, table, 255)
+	table.Close()
+
+	// Cut the data file so that the last few items are partially lost. The
+	// end offset of item n is (n+1)(n+2)/2, so cutting at 5000 keeps 99 items.
+	dat, err := os.OpenFile(filepath.Join(dir, "test.rdat"), os.O_RDWR, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dat.Truncate(5000)
+	dat.Close()
+
+	// Reopen the table and ensure the dangling index entries were discarded
+	table = newTestTable(t, dir, "test")
+	defer table.Close()
+
+	checkTable(t, table, 99)
+
+	stat, err := os.Stat(filepath.Join(dir, "test.rdat"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := int64(99 * 100 / 2); stat.Size() != want {
+		t.Fatalf("data file size mismatch: have %d, want %d", stat.Size(), want)
+	}
+}
+
+// Tests that truncating a table discards the items above the limit, also across
+// reopening it.
+func TestFreezerTruncate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	table := newTestTable(t, dir, "test")
+	fillTable(t, table, 30)
+
+	if err := table.truncate(10); err != nil {
+		t.Fatalf("failed to truncate table: %v", err)
+	}
+	checkTable(t, table, 10)
+	table.Close()
+
+	table = newTestTable(t, dir, "test")
+	defer table.Close()
+
+	checkTable(t, table, 10)
+}
+
+// Tests that a freezer whose tables went out of sync in a crash realigns them
+// when reopened.
+func TestFreezerRepairTables(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	f, err := newFreezer(dir, "")
+	if err != nil {
+		t.Fatalf("failed to open freezer: %v", err)
+	}
+	for i := uint64(0); i < 10; i++ {
+		if err := f.AppendAncient(i, getChunk(32, int(i)), []byte{0x01}, []byte{0x02}, []byte{0x03}, []byte{0x04}); err != nil {
+			t.Fatalf("failed to append block %d: %v", i, err)
+		}
+	}
+	if err := f.AppendAncient(20, nil, nil, nil, nil, nil); err != errOutOrderInsertion {
+		t.Fatalf("out of order append error mismatch: have %v, want %v", err, errOutOrderInsertion)
+	}
+	// Simulate a crash midway through freezing block 10
+	f.tables[freezerHashTable].Append(10, getChunk(32, 10))
+	f.tables[freezerHeaderTable].Append(10, []byte{0x01})
+	f.Sync()
+	f.Close()
+
+	if f, err = newFreezer(dir, ""); err != nil {
+		t.Fatalf("failed to reopen freezer: %v", err)
+	}
+	defer f.Close()
+
+	if frozen, _ := f.Ancients(); frozen != 10 {
+		t.Fatalf("frozen block count mismatch: have %d, want %d", frozen, 10)
+	}
+	for _, name := range freezerTables {
+		if items := f.tables[name].items; items != 10 {
+			t.Errorf("table %s: item count mismatch: have %d, want %d", name, items, 10)
+		}
+	}
+	if has, _ := f.HasAncient(freezerHashTable, 10); has {
+		t.Fatalf("partially frozen block retained")
+	}
+}
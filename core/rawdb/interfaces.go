@@ -31,3 +31,31 @@ type DatabaseWriter interface {
 type DatabaseDeleter interface {
 	Delete(key []byte) error
 }
+
+// AncientReader wraps the read methods of an append-only store of immutable
+// ancient chain data.
+type AncientReader interface {
+	// HasAncient returns an indicator whether the specified data exists in the
+	// ancient store.
+	HasAncient(kind string, number uint64) (bool, error)
+
+	// Ancient retrieves an ancient binary blob from the append-only immutable files.
+	Ancient(kind string, number uint64) ([]byte, error)
+
+	// Ancients returns the number of blocks stored in the ancient store.
+	Ancients() (uint64, error)
+}
+
+// AncientWriter wraps the write methods of an append-only store of immutable
+// ancient chain data.
+type AncientWriter interface {
+	// AppendAncient injects all binary blobs belonging to a block at the end of
+	// the append-only immutable table files.
+	AppendAncient(number uint64, hash, header, body, receipts, td []byte) error
+
+	// TruncateAncients discards all but the first n ancient blocks.
+	TruncateAncients(n uint64) error
+
+	// Sync flushes all in-memory ancient data to disk.
+	Sync() error
+}
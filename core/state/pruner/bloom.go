@@ -0,0 +1,114 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pruner
+
+import (
+	"encoding/binary"
+	"errors"
+	"io/ioutil"
+	"os"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// stateBloomHashes is the number of bit positions set for every inserted key.
+// The keys are cryptographic hashes themselves, so each position is taken from
+// a distinct 8 byte chunk of the key instead of hashing it again.
+const stateBloomHashes = 4
+
+// errInvalidBloom is returned if a persisted state bloom cannot be decoded.
+var errInvalidBloom = errors.New("invalid state bloom")
+
+// stateBloom is a bloom filter tracking the hashes of all the trie nodes and
+// contract codes belonging to the state being retained. False positives only
+// mean that some stale data survives the pruning, false negatives are not
+// possible.
+type stateBloom struct {
+	bits []byte // Bit array backing the filter
+}
+
+// newStateBloom creates a state bloom of the given size in megabytes.
+func newStateBloom(size uint64) *stateBloom {
+	if size == 0 {
+		size = 1
+	}
+	return &stateBloom{bits: make([]byte, size*1024*1024)}
+}
+
+// add inserts a 32 byte hash into the bloom filter.
+func (b *stateBloom) add(hash []byte) {
+	m := uint64(len(b.bits)) * 8
+	for i := 0; i < stateBloomHashes; i++ {
+		pos := binary.BigEndian.Uint64(hash[i*8:]) % m
+		b.bits[pos/8] |= 1 << (pos % 8)
+	}
+}
+
+// contains returns whether the 32 byte hash may have been inserted into the
+// bloom filter.
+func (b *stateBloom) contains(hash []byte) bool {
+	m := uint64(len(b.bits)) * 8
+	for i := 0; i < stateBloomHashes; i++ {
+		pos := binary.BigEndian.Uint64(hash[i*8:]) % m
+		if b.bits[pos/8]&(1<<(pos%8)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// commit persists the bloom filter together with the state root it was built
+// for into the given file. The file is written under a temporary name first and
+// moved into place afterwards, so a crash never leaves a partial filter behind.
+func (b *stateBloom) commit(path string, root common.Hash) error {
+	tmp := path + ".tmp"
+
+	f, err := os.OpenFile(tmp, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(root[:]); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Write(b.bits); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// loadStateBloom reads a bloom filter and the state root it was built for from
+// the given file.
+func loadStateBloom(path string) (*stateBloom, common.Hash, error) {
+	blob, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, common.Hash{}, err
+	}
+	if len(blob) <= common.HashLength {
+		return nil, common.Hash{}, errInvalidBloom
+	}
+	root := common.BytesToHash(blob[:common.HashLength])
+	return &stateBloom{bits: blob[common.HashLength:]}, root, nil
+}
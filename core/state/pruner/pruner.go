@@ -0,0 +1,277 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package pruner implements offline pruning of stale state data.
+package pruner
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+const (
+	// stateBloomFileName is the name of the file the state bloom is persisted
+	// into, signalling an in-progress pruning that must be finished.
+	stateBloomFileName = "statebloom.bf"
+
+	// maxRootSearchDepth is the number of blocks to search back from the head
+	// for a state that is fully available, if no explicit root was requested.
+	maxRootSearchDepth = 128
+)
+
+// errNoRecentState is returned if no full state could be found amongst the
+// recent blocks to prune against.
+var errNoRecentState = errors.New("no recent state available")
+
+// Pruner is an offline tool to delete all the trie nodes and contract codes
+// from the database which are not reachable from a chosen state root. Live
+// nodes are gathered into a bloom filter, which is persisted before anything
+// is deleted, so an interrupted pruning can be safely resumed.
+//
+// The pruner must only be run while no other process is writing to the
+// database, otherwise freshly written state would be deleted.
+type Pruner struct {
+	db        ethdb.Database // Database to prune stale state from
+	datadir   string         // Directory to persist the state bloom into
+	bloomSize uint64         // Size of the state bloom in megabytes
+}
+
+// NewPruner creates a state pruner for the given database, persisting its
+// progress into the given data directory.
+func NewPruner(db ethdb.Database, datadir string, bloomSize uint64) *Pruner {
+	return &Pruner{
+		db:        db,
+		datadir:   datadir,
+		bloomSize: bloomSize,
+	}
+}
+
+// Prune deletes all state data not belonging to the given state root or to the
+// genesis state. If the root is empty, the most recent available state of the
+// canonical chain is retained. If a previous pruning was interrupted, it is
+// finished first, ignoring the requested root.
+func (p *Pruner) Prune(root common.Hash) error {
+	path := filepath.Join(p.datadir, stateBloomFileName)
+	if common.FileExist(path) {
+		bloom, target, err := loadStateBloom(path)
+		if err != nil {
+			return err
+		}
+		if root != (common.Hash{}) && root != target {
+			log.Warn("Ignoring requested root, resuming interrupted pruning", "requested", root, "root", target)
+		}
+		return prune(p.db, bloom, path, target)
+	}
+	if root == (common.Hash{}) {
+		var err error
+		if root, err = recentRoot(p.db); err != nil {
+			return err
+		}
+	}
+	// Gather all the live state into the bloom, failing if it's incomplete
+	start := time.Now()
+	bloom := newStateBloom(p.bloomSize)
+
+	log.Info("Collecting live state", "root", root)
+	nodes, err := addState(p.db, bloom, root)
+	if err != nil {
+		return fmt.Errorf("state %x incomplete: %v", root, err)
+	}
+	// Retain the genesis state too if it's still available, it's tiny anyway
+	if hash := rawdb.ReadCanonicalHash(p.db, 0); hash != (common.Hash{}) {
+		if genesis := rawdb.ReadHeader(p.db, hash, 0); genesis != nil {
+			if n, err := addState(p.db, bloom, genesis.Root); err != nil {
+				log.Warn("Genesis state unavailable, skipping", "root", genesis.Root, "err", err)
+			} else {
+				nodes += n
+			}
+		}
+	}
+	log.Info("Collected live state", "root", root, "nodes", nodes, "elapsed", common.PrettyDuration(time.Since(start)))
+
+	// Persist the bloom before deleting anything to allow resuming
+	if err := bloom.commit(path, root); err != nil {
+		return err
+	}
+	return prune(p.db, bloom, path, root)
+}
+
+// RecoverPruning finishes a pruning run that was interrupted previously. It's
+// a no-op if no pruning is in progress. It must be called before the database
+// is used by anything else, otherwise newly written state would be deleted.
+func RecoverPruning(datadir string, db ethdb.Database) error {
+	path := filepath.Join(datadir, stateBloomFileName)
+	if !common.FileExist(path) {
+		return nil
+	}
+	bloom, root, err := loadStateBloom(path)
+	if err != nil {
+		return err
+	}
+	log.Info("Resuming interrupted state pruning", "root", root)
+	return prune(db, bloom, path, root)
+}
+
+// VerifyState walks the entire state belonging to the given root, including all
+// contract storage tries and contract codes, and returns an error if any of it
+// is missing from the database.
+func VerifyState(db ethdb.Database, root common.Hash) error {
+	var (
+		start  = time.Now()
+		logged = time.Now()
+	)
+	statedb, err := state.New(root, state.NewDatabase(db))
+	if err != nil {
+		return err
+	}
+	it := state.NewNodeIterator(statedb)
+
+	var nodes int
+	for it.Next() {
+		if it.Hash != (common.Hash{}) {
+			nodes++
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Verifying state", "root", root, "nodes", nodes, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if it.Error != nil {
+		return it.Error
+	}
+	log.Info("Verified state", "root", root, "nodes", nodes, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// recentRoot returns the state root of the most recent canonical block whose
+// state is available in the database.
+func recentRoot(db ethdb.Database) (common.Hash, error) {
+	hash := rawdb.ReadHeadBlockHash(db)
+	for i := 0; i < maxRootSearchDepth; i++ {
+		number := rawdb.ReadHeaderNumber(db, hash)
+		if number == nil {
+			break
+		}
+		header := rawdb.ReadHeader(db, hash, *number)
+		if header == nil {
+			break
+		}
+		if ok, _ := db.Has(header.Root[:]); ok {
+			log.Info("Selected state to retain", "number", *number, "hash", hash, "root", header.Root)
+			return header.Root, nil
+		}
+		if *number == 0 {
+			break
+		}
+		hash = header.ParentHash
+	}
+	return common.Hash{}, errNoRecentState
+}
+
+// addState iterates over the entire state belonging to the given root and adds
+// all trie node and contract code hashes into the bloom, returning the number
+// of entries inserted. An error is returned if any part of the state is missing.
+func addState(db ethdb.Database, bloom *stateBloom, root common.Hash) (int, error) {
+	var (
+		start  = time.Now()
+		logged = time.Now()
+	)
+	statedb, err := state.New(root, state.NewDatabase(db))
+	if err != nil {
+		return 0, err
+	}
+	it := state.NewNodeIterator(statedb)
+
+	var nodes int
+	for it.Next() {
+		if it.Hash != (common.Hash{}) {
+			bloom.add(it.Hash[:])
+			nodes++
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Collecting live state", "root", root, "nodes", nodes, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	return nodes, it.Error
+}
+
+// prune deletes every trie node and contract code from the database that is not
+// contained within the bloom, verifies the retained state and finally removes
+// the persisted bloom to mark the pruning finished.
+func prune(db ethdb.Database, bloom *stateBloom, path string, root common.Hash) error {
+	var (
+		start  = time.Now()
+		logged = time.Now()
+		batch  = db.NewBatch()
+
+		count int
+		size  common.StorageSize
+	)
+	err := rawdb.IterateKeys(db, nil, func(key, value []byte) error {
+		// Trie nodes and contract codes are the only entries keyed by bare hashes
+		if len(key) != common.HashLength || bloom.contains(key) {
+			return nil
+		}
+		batch.Delete(key)
+		count++
+		size += common.StorageSize(len(key) + len(value))
+
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Pruning state data", "nodes", count, "size", size, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	log.Info("Pruned state data", "nodes", count, "size", size, "elapsed", common.PrettyDuration(time.Since(start)))
+
+	// Reclaim the freed up disk space if the database supports it
+	if ldb, ok := rawdb.KeyValueStore(db).(*ethdb.LDBDatabase); ok {
+		cstart := time.Now()
+		log.Info("Compacting database")
+		if err := ldb.LDB().CompactRange(util.Range{}); err != nil {
+			return err
+		}
+		log.Info("Compacted database", "elapsed", common.PrettyDuration(time.Since(cstart)))
+	}
+	// Ensure nothing of the retained state got lost before finishing up
+	if err := VerifyState(db, root); err != nil {
+		return fmt.Errorf("retained state %x incomplete: %v", root, err)
+	}
+	return os.Remove(path)
+}
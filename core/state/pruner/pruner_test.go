@@ -0,0 +1,201 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pruner
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// makeTestStates creates two consecutive states in a fresh database, where the
+// second one modifies most of the accounts and storage slots of the first one.
+func makeTestStates(t *testing.T) (ethdb.Database, common.Hash, common.Hash) {
+	db := ethdb.NewMemDatabase()
+	sdb := state.NewDatabase(db)
+
+	statedb, _ := state.New(common.Hash{}, sdb)
+	for i := byte(0); i < 64; i++ {
+		addr := common.BytesToAddress([]byte{i})
+		statedb.AddBalance(addr, big.NewInt(int64(i)))
+		if i%4 == 0 {
+			statedb.SetCode(addr, []byte{i, i, i})
+		}
+		for j := byte(0); j < i%8; j++ {
+			statedb.SetState(addr, common.BytesToHash([]byte{j}), common.BytesToHash([]byte{i, j}))
+		}
+	}
+	first, err := statedb.Commit(false)
+	if err != nil {
+		t.Fatalf("failed to commit first state: %v", err)
+	}
+	if err := sdb.TrieDB().Commit(first, false); err != nil {
+		t.Fatalf("failed to flush first state: %v", err)
+	}
+	statedb, _ = state.New(first, sdb)
+	for i := byte(0); i < 64; i++ {
+		addr := common.BytesToAddress([]byte{i})
+		statedb.AddBalance(addr, big.NewInt(1))
+		if i%8 == 0 {
+			statedb.SetCode(addr, []byte{i, i})
+		}
+		for j := byte(0); j < i%8; j++ {
+			statedb.SetState(addr, common.BytesToHash([]byte{j}), common.BytesToHash([]byte{j, i}))
+		}
+	}
+	second, err := statedb.Commit(false)
+	if err != nil {
+		t.Fatalf("failed to commit second state: %v", err)
+	}
+	if err := sdb.TrieDB().Commit(second, false); err != nil {
+		t.Fatalf("failed to flush second state: %v", err)
+	}
+	return db, first, second
+}
+
+// Tests that pruning retains the requested state fully while deleting the
+// stale one.
+func TestPruneState(t *testing.T) {
+	datadir, err := ioutil.TempDir("", "pruner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(datadir)
+
+	db, first, second := makeTestStates(t)
+	if err := VerifyState(db, first); err != nil {
+		t.Fatalf("first state incomplete before pruning: %v", err)
+	}
+	before := db.(*ethdb.MemDatabase).Len()
+
+	if err := NewPruner(db, datadir, 1).Prune(second); err != nil {
+		t.Fatalf("failed to prune state: %v", err)
+	}
+	if err := VerifyState(db, second); err != nil {
+		t.Fatalf("retained state incomplete: %v", err)
+	}
+	if err := VerifyState(db, first); err == nil {
+		t.Fatalf("stale state not pruned")
+	}
+	if after := db.(*ethdb.MemDatabase).Len(); after >= before {
+		t.Fatalf("database size mismatch: have %d, want < %d", after, before)
+	}
+	if common.FileExist(filepath.Join(datadir, stateBloomFileName)) {
+		t.Fatalf("state bloom not removed after pruning")
+	}
+}
+
+// Tests that pruning refuses to delete anything if the requested state is not
+// fully available.
+func TestPruneIncompleteState(t *testing.T) {
+	datadir, err := ioutil.TempDir("", "pruner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(datadir)
+
+	db, first, second := makeTestStates(t)
+	db.Delete(second[:])
+	before := db.(*ethdb.MemDatabase).Len()
+
+	if err := NewPruner(db, datadir, 1).Prune(second); err == nil {
+		t.Fatalf("incomplete state pruned against")
+	}
+	if after := db.(*ethdb.MemDatabase).Len(); after != before {
+		t.Fatalf("database size mismatch: have %d, want %d", after, before)
+	}
+	if err := VerifyState(db, first); err != nil {
+		t.Fatalf("first state damaged: %v", err)
+	}
+}
+
+// Tests that an interrupted pruning, which already persisted its state bloom, is
+// resumed against the original root.
+func TestRecoverPruning(t *testing.T) {
+	datadir, err := ioutil.TempDir("", "pruner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(datadir)
+
+	db, first, second := makeTestStates(t)
+
+	// Simulate a crash right after the state bloom was persisted
+	bloom := newStateBloom(1)
+	if _, err := addState(db, bloom, second); err != nil {
+		t.Fatalf("failed to collect state: %v", err)
+	}
+	path := filepath.Join(datadir, stateBloomFileName)
+	if err := bloom.commit(path, second); err != nil {
+		t.Fatalf("failed to persist state bloom: %v", err)
+	}
+	if err := RecoverPruning(datadir, db); err != nil {
+		t.Fatalf("failed to recover pruning: %v", err)
+	}
+	if err := VerifyState(db, second); err != nil {
+		t.Fatalf("retained state incomplete: %v", err)
+	}
+	if err := VerifyState(db, first); err == nil {
+		t.Fatalf("stale state not pruned")
+	}
+	if common.FileExist(path) {
+		t.Fatalf("state bloom not removed after pruning")
+	}
+	// Recovering without an interrupted pruning should be a noop
+	if err := RecoverPruning(datadir, db); err != nil {
+		t.Fatalf("failed to recover without pruning: %v", err)
+	}
+}
+
+// Tests that a persisted state bloom can be loaded back.
+func TestStateBloomPersistence(t *testing.T) {
+	datadir, err := ioutil.TempDir("", "pruner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(datadir)
+
+	var (
+		bloom = newStateBloom(1)
+		root  = common.HexToHash("0xdeadbeef")
+		path  = filepath.Join(datadir, stateBloomFileName)
+	)
+	for i := byte(0); i < 100; i++ {
+		bloom.add(common.BytesToHash([]byte{i, i, i, i}).Bytes())
+	}
+	if err := bloom.commit(path, root); err != nil {
+		t.Fatalf("failed to persist state bloom: %v", err)
+	}
+	loaded, loadedRoot, err := loadStateBloom(path)
+	if err != nil {
+		t.Fatalf("failed to load state bloom: %v", err)
+	}
+	if loadedRoot != root {
+		t.Fatalf("root mismatch: have %x, want %x", loadedRoot, root)
+	}
+	for i := byte(0); i < 100; i++ {
+		if key := common.BytesToHash([]byte{i, i, i, i}); !loaded.contains(key[:]) {
+			t.Fatalf("key %x missing from loaded bloom", key)
+		}
+	}
+}
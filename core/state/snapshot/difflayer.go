@@ -0,0 +1,295 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"encoding/binary"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// diffBloomBits is the size of the aggregated bloom filter of a diff layer.
+	// With 4 bits set per item, the false positive rate stays below 3% for up to
+	// 256K items modified across all the diff layers.
+	diffBloomBits = 1 << 21
+
+	// diffBloomFuncs is the number of bits set in the bloom filter per item.
+	diffBloomFuncs = 4
+)
+
+// destructBloomSalt is mixed into the hash of destructed accounts, so that they
+// can be looked up separately from the modified accounts.
+var destructBloomSalt = common.HexToHash("0xd35790c7d35790c7d35790c7d35790c7d35790c7d35790c7d35790c7d35790c7")
+
+// diffBloom is a bloom filter over the items modified by a diff layer and all
+// the diff layers below it. The items are identified by hashes, so the bits are
+// taken directly from them instead of hashing again.
+type diffBloom []uint64
+
+// newDiffBloom creates an empty bloom filter.
+func newDiffBloom() diffBloom {
+	return make(diffBloom, diffBloomBits/64)
+}
+
+// add inserts an item into the bloom filter.
+func (b diffBloom) add(key common.Hash) {
+	for i := 0; i < diffBloomFuncs; i++ {
+		bit := binary.BigEndian.Uint64(key[i*8:]) % diffBloomBits
+		b[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+// contains returns whether the item might have been inserted into the filter.
+func (b diffBloom) contains(key common.Hash) bool {
+	for i := 0; i < diffBloomFuncs; i++ {
+		bit := binary.BigEndian.Uint64(key[i*8:]) % diffBloomBits
+		if b[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// destructBloomKey returns the bloom filter key of a destructed account.
+func destructBloomKey(accountHash common.Hash) common.Hash {
+	var key common.Hash
+	for i := range key {
+		key[i] = accountHash[i] ^ destructBloomSalt[i]
+	}
+	return key
+}
+
+// storageBloomKey returns the bloom filter key of a storage slot.
+func storageBloomKey(accountHash, storageHash common.Hash) common.Hash {
+	var key common.Hash
+	for i := range key {
+		key[i] = accountHash[i] ^ storageHash[i]
+	}
+	return key
+}
+
+// diffLayer represents a collection of modifications made to a state snapshot
+// after running a block on top. It contains the modified account trie leaves and
+// the modified storage trie leaves of each account, keyed by their hashes.
+//
+// The goal of a diff layer is to act as a journal, tracking recent modifications
+// made to the state, that have not yet graduated into a semi-immutable state.
+type diffLayer struct {
+	parent snapshot    // Parent snapshot modified by this one, never nil
+	origin *diskLayer  // Disk layer at the bottom of the diffs, to skip to on bloom misses
+	root   common.Hash // Root hash to which this snapshot diff belongs to
+	stale  bool        // Signals that the layer became stale (state progressed)
+	diffed diffBloom   // Bloom filter of the items modified by this and the parent diffs
+
+	destructs map[common.Hash]struct{}               // Keyed markers for deleted (and potentially recreated) accounts
+	accounts  map[common.Hash][]byte                 // Keyed accounts for direct retrieval
+	storage   map[common.Hash]map[common.Hash][]byte // Keyed storage slots for direct retrieval, one map per account (empty means deleted)
+
+	lock sync.RWMutex
+}
+
+// newDiffLayer creates a new diff on top of an existing snapshot, whether that's
+// a low level persistent database or a hierarchical diff already.
+func newDiffLayer(parent snapshot, root common.Hash, destructs map[common.Hash]struct{}, accounts map[common.Hash][]byte, storage map[common.Hash]map[common.Hash][]byte) *diffLayer {
+	if destructs == nil {
+		destructs = make(map[common.Hash]struct{})
+	}
+	if accounts == nil {
+		accounts = make(map[common.Hash][]byte)
+	}
+	if storage == nil {
+		storage = make(map[common.Hash]map[common.Hash][]byte)
+	}
+	dl := &diffLayer{
+		parent:    parent,
+		root:      root,
+		destructs: destructs,
+		accounts:  accounts,
+		storage:   storage,
+	}
+	switch parent := parent.(type) {
+	case *diskLayer:
+		dl.rebloom(parent)
+	case *diffLayer:
+		parent.lock.RLock()
+		origin := parent.origin
+		parent.lock.RUnlock()
+		dl.rebloom(origin)
+	}
+	return dl
+}
+
+// rebloom discards the bloom filter of the layer and rebuilds it on top of the
+// parent's one, pointing the layer to the given disk layer as its origin. It's
+// used after flattening to drop the items that made it into the disk layer.
+func (dl *diffLayer) rebloom(origin *diskLayer) {
+	dl.lock.Lock()
+	defer dl.lock.Unlock()
+
+	if parent, ok := dl.parent.(*diffLayer); ok {
+		parent.lock.RLock()
+		dl.diffed = append(diffBloom(nil), parent.diffed...)
+		parent.lock.RUnlock()
+	} else {
+		dl.diffed = newDiffBloom()
+	}
+	for hash := range dl.destructs {
+		dl.diffed.add(hash)
+		dl.diffed.add(destructBloomKey(hash))
+	}
+	for hash := range dl.accounts {
+		dl.diffed.add(hash)
+	}
+	for accountHash, slots := range dl.storage {
+		for storageHash := range slots {
+			dl.diffed.add(storageBloomKey(accountHash, storageHash))
+		}
+	}
+	dl.origin = origin
+}
+
+// Root returns the root hash for which this snapshot was made.
+func (dl *diffLayer) Root() common.Hash {
+	return dl.root
+}
+
+// Parent returns the subsequent layer of a diff layer.
+func (dl *diffLayer) Parent() snapshot {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	return dl.parent
+}
+
+// Stale return whether this layer has become stale (was flattened across) or if
+// it's still live.
+func (dl *diffLayer) Stale() bool {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	return dl.stale
+}
+
+// markStale invalidates the layer, failing all subsequent reads.
+func (dl *diffLayer) markStale() {
+	dl.lock.Lock()
+	defer dl.lock.Unlock()
+
+	dl.stale = true
+}
+
+// AccountRLP directly retrieves the account RLP associated with a particular
+// hash in the snapshot slim data format.
+func (dl *diffLayer) AccountRLP(hash common.Hash) ([]byte, error) {
+	// Accounts not modified by any of the diffs are retrieved from the disk layer
+	// directly, unless it was flattened into meanwhile
+	dl.lock.RLock()
+	stale, hit, origin := dl.stale, dl.diffed.contains(hash), dl.origin
+	dl.lock.RUnlock()
+
+	if !stale && !hit {
+		if blob, err := origin.AccountRLP(hash); err != ErrSnapshotStale {
+			snapshotBloomAccountMissMeter.Mark(1)
+			return blob, err
+		}
+	}
+	return dl.accountRLP(hash)
+}
+
+// accountRLP retrieves the account RLP from the diff layers, walking down the
+// parents until one modifying the account is found.
+func (dl *diffLayer) accountRLP(hash common.Hash) ([]byte, error) {
+	dl.lock.RLock()
+
+	// If the layer was flattened into, consider it invalid (any live reference to
+	// the original should be marked as unusable).
+	if dl.stale {
+		dl.lock.RUnlock()
+		return nil, ErrSnapshotStale
+	}
+	// If the account is known locally, return it
+	if data, ok := dl.accounts[hash]; ok {
+		dl.lock.RUnlock()
+		return data, nil
+	}
+	// If the account is known locally, but deleted, return it
+	if _, ok := dl.destructs[hash]; ok {
+		dl.lock.RUnlock()
+		return nil, nil
+	}
+	// Account unknown to this diff, resolve from parent
+	parent := dl.parent
+	dl.lock.RUnlock()
+
+	return parent.AccountRLP(hash)
+}
+
+// Storage directly retrieves the storage data associated with a particular hash,
+// within a particular account. If the slot is unknown to this diff, it's parent
+// is consulted.
+func (dl *diffLayer) Storage(accountHash, storageHash common.Hash) ([]byte, error) {
+	// Slots not modified by any of the diffs, nor wiped by an account destruction,
+	// are retrieved from the disk layer directly, unless it was flattened into
+	dl.lock.RLock()
+	stale, origin := dl.stale, dl.origin
+	hit := dl.diffed.contains(storageBloomKey(accountHash, storageHash)) || dl.diffed.contains(destructBloomKey(accountHash))
+	dl.lock.RUnlock()
+
+	if !stale && !hit {
+		if blob, err := origin.Storage(accountHash, storageHash); err != ErrSnapshotStale {
+			snapshotBloomStorageMissMeter.Mark(1)
+			return blob, err
+		}
+	}
+	return dl.storageSlot(accountHash, storageHash)
+}
+
+// storageSlot retrieves the storage slot from the diff layers, walking down the
+// parents until one modifying the slot or destructing the account is found.
+func (dl *diffLayer) storageSlot(accountHash, storageHash common.Hash) ([]byte, error) {
+	dl.lock.RLock()
+
+	if dl.stale {
+		dl.lock.RUnlock()
+		return nil, ErrSnapshotStale
+	}
+	// If the account is known locally, try to resolve the slot locally
+	if storage, ok := dl.storage[accountHash]; ok {
+		if data, ok := storage[storageHash]; ok {
+			dl.lock.RUnlock()
+			return data, nil
+		}
+	}
+	// If the account is known locally, but deleted, return an empty slot
+	if _, ok := dl.destructs[accountHash]; ok {
+		dl.lock.RUnlock()
+		return nil, nil
+	}
+	// Storage slot unknown to this diff, resolve from parent
+	parent := dl.parent
+	dl.lock.RUnlock()
+
+	return parent.Storage(accountHash, storageHash)
+}
+
+// Update creates a new layer on top of the existing snapshot diff tree with
+// the specified data items.
+func (dl *diffLayer) Update(blockRoot common.Hash, destructs map[common.Hash]struct{}, accounts map[common.Hash][]byte, storage map[common.Hash]map[common.Hash][]byte) *diffLayer {
+	return newDiffLayer(dl, blockRoot, destructs, accounts, storage)
+}
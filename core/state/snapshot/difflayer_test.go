@@ -0,0 +1,208 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// newTestDisk creates a generated disk layer on top of a fresh memory database,
+// containing the given accounts and storage slots.
+func newTestDisk(root common.Hash, accounts map[common.Hash][]byte, storage map[common.Hash]map[common.Hash][]byte) *diskLayer {
+	db := ethdb.NewMemDatabase()
+	for hash, blob := range accounts {
+		rawdb.WriteAccountSnapshot(db, hash, blob)
+	}
+	for hash, slots := range storage {
+		for key, blob := range slots {
+			rawdb.WriteStorageSnapshot(db, hash, key, blob)
+		}
+	}
+	rawdb.WriteSnapshotRoot(db, root)
+
+	done := make(chan struct{})
+	close(done)
+	return &diskLayer{diskdb: db, root: root, genDone: done}
+}
+
+// checkAccount ensures that the given layer returns the expected account blob.
+func checkAccount(t *testing.T, snap Snapshot, hash common.Hash, want []byte) {
+	t.Helper()
+
+	blob, err := snap.AccountRLP(hash)
+	if err != nil {
+		t.Fatalf("account %x: failed to retrieve: %v", hash, err)
+	}
+	if !bytes.Equal(blob, want) {
+		t.Fatalf("account %x: blob mismatch: have %x, want %x", hash, blob, want)
+	}
+}
+
+// checkStorage ensures that the given layer returns the expected storage blob.
+func checkStorage(t *testing.T, snap Snapshot, account, slot common.Hash, want []byte) {
+	t.Helper()
+
+	blob, err := snap.Storage(account, slot)
+	if err != nil {
+		t.Fatalf("slot %x/%x: failed to retrieve: %v", account, slot, err)
+	}
+	if !bytes.Equal(blob, want) {
+		t.Fatalf("slot %x/%x: blob mismatch: have %x, want %x", account, slot, blob, want)
+	}
+}
+
+// Tests that diff layers resolve accounts and storage slots from the topmost
+// layer modifying them, honouring account destructions.
+func TestDiffLayerLookups(t *testing.T) {
+	var (
+		acc1, acc2, acc3 = common.HexToHash("0x01"), common.HexToHash("0x02"), common.HexToHash("0x03")
+		slot1, slot2     = common.HexToHash("0x11"), common.HexToHash("0x12")
+	)
+	base := newTestDisk(common.HexToHash("0xff"),
+		map[common.Hash][]byte{acc1: {0x01}, acc2: {0x02}},
+		map[common.Hash]map[common.Hash][]byte{acc1: {slot1: {0xa1}, slot2: {0xa2}}, acc2: {slot1: {0xb1}}},
+	)
+	// First diff modifies an account and a slot, and deletes another slot
+	diff1 := base.Update(common.HexToHash("0xf1"), nil,
+		map[common.Hash][]byte{acc2: {0x22}},
+		map[common.Hash]map[common.Hash][]byte{acc1: {slot1: {0xaa}, slot2: nil}},
+	)
+	// Second diff destructs an account and creates a new one
+	diff2 := diff1.Update(common.HexToHash("0xf2"),
+		map[common.Hash]struct{}{acc1: {}},
+		map[common.Hash][]byte{acc3: {0x03}},
+		map[common.Hash]map[common.Hash][]byte{acc3: {slot1: {0xc1}}},
+	)
+	// Third diff resurrects the destructed account with fresh storage
+	diff3 := diff2.Update(common.HexToHash("0xf3"),
+		map[common.Hash]struct{}{acc1: {}},
+		map[common.Hash][]byte{acc1: {0x11}},
+		map[common.Hash]map[common.Hash][]byte{acc1: {slot2: {0xab}}},
+	)
+	checkAccount(t, diff1, acc1, []byte{0x01})
+	checkAccount(t, diff1, acc2, []byte{0x22})
+	checkStorage(t, diff1, acc1, slot1, []byte{0xaa})
+	checkStorage(t, diff1, acc1, slot2, nil)
+	checkStorage(t, diff1, acc2, slot1, []byte{0xb1})
+
+	checkAccount(t, diff2, acc1, nil)
+	checkAccount(t, diff2, acc2, []byte{0x22})
+	checkAccount(t, diff2, acc3, []byte{0x03})
+	checkStorage(t, diff2, acc1, slot1, nil)
+	checkStorage(t, diff2, acc3, slot1, []byte{0xc1})
+
+	checkAccount(t, diff3, acc1, []byte{0x11})
+	checkStorage(t, diff3, acc1, slot1, nil)
+	checkStorage(t, diff3, acc1, slot2, []byte{0xab})
+
+	// Stale layers must refuse serving data
+	diff1.markStale()
+	if _, err := diff1.AccountRLP(acc1); err != ErrSnapshotStale {
+		t.Fatalf("stale account error mismatch: have %v, want %v", err, ErrSnapshotStale)
+	}
+	if _, err := diff3.Storage(acc2, slot1); err != ErrSnapshotStale {
+		t.Fatalf("stale parent error mismatch: have %v, want %v", err, ErrSnapshotStale)
+	}
+}
+
+// Tests that flattening a diff layer into the disk layer persists the same view
+// of the state that the diff layer served.
+func TestDiskLayerFlatten(t *testing.T) {
+	var (
+		acc1, acc2 = common.HexToHash("0x01"), common.HexToHash("0x02")
+		slot1      = common.HexToHash("0x11")
+		slot2      = common.HexToHash("0x12")
+	)
+	base := newTestDisk(common.HexToHash("0xff"),
+		map[common.Hash][]byte{acc1: {0x01}, acc2: {0x02}},
+		map[common.Hash]map[common.Hash][]byte{acc1: {slot1: {0xa1}, slot2: {0xa2}}, acc2: {slot1: {0xb1}}},
+	)
+	diff := base.Update(common.HexToHash("0xf1"),
+		map[common.Hash]struct{}{acc1: {}},
+		map[common.Hash][]byte{acc1: {0x11}},
+		map[common.Hash]map[common.Hash][]byte{acc1: {slot2: {0xab}}, acc2: {slot1: nil}},
+	)
+	disk := base.flatten(diff)
+
+	if !base.Stale() || !diff.Stale() {
+		t.Fatalf("flattened layers not marked stale")
+	}
+	if root := rawdb.ReadSnapshotRoot(disk.diskdb); root != diff.root {
+		t.Fatalf("persisted root mismatch: have %x, want %x", root, diff.root)
+	}
+	checkAccount(t, disk, acc1, []byte{0x11})
+	checkAccount(t, disk, acc2, []byte{0x02})
+	checkStorage(t, disk, acc1, slot1, nil)
+	checkStorage(t, disk, acc1, slot2, []byte{0xab})
+	checkStorage(t, disk, acc2, slot1, nil)
+}
+
+// Tests that the bloom filter of a diff layer aggregates the items modified by
+// all the diffs below it, and that lookups missing it are served from the disk
+// layer directly.
+func TestDiffLayerBloom(t *testing.T) {
+	var (
+		acc1  = common.HexToHash("0xa1a1a1a1a1a1a1a1b1b1b1b1b1b1b1b1c1c1c1c1c1c1c1c1d1d1d1d1d1d1d1d1")
+		acc2  = common.HexToHash("0xa2a2a2a2a2a2a2a2b2b2b2b2b2b2b2b2c2c2c2c2c2c2c2c2d2d2d2d2d2d2d2d2")
+		acc3  = common.HexToHash("0xa3a3a3a3a3a3a3a3b3b3b3b3b3b3b3b3c3c3c3c3c3c3c3c3d3d3d3d3d3d3d3d3")
+		slot1 = common.HexToHash("0x0101010101010101020202020202020203030303030303030404040404040404")
+	)
+	base := newTestDisk(common.HexToHash("0xff"),
+		map[common.Hash][]byte{acc1: {0x01}, acc2: {0x02}, acc3: {0x03}},
+		map[common.Hash]map[common.Hash][]byte{acc1: {slot1: {0xa1}}, acc3: {slot1: {0xc1}}},
+	)
+	diff1 := base.Update(common.HexToHash("0xf1"), nil, map[common.Hash][]byte{acc1: {0x11}}, nil)
+	diff2 := diff1.Update(common.HexToHash("0xf2"), map[common.Hash]struct{}{acc3: {}}, nil, nil)
+
+	if !diff2.diffed.contains(acc1) {
+		t.Errorf("account modified by the parent diff missing from the bloom filter")
+	}
+	if !diff2.diffed.contains(destructBloomKey(acc3)) {
+		t.Errorf("destructed account missing from the bloom filter")
+	}
+	if diff2.diffed.contains(acc2) || diff2.diffed.contains(storageBloomKey(acc1, slot1)) {
+		t.Errorf("unmodified items contained in the bloom filter")
+	}
+	if diff2.origin != base {
+		t.Errorf("origin mismatch: have %p, want %p", diff2.origin, base)
+	}
+	checkAccount(t, diff2, acc1, []byte{0x11})
+	checkAccount(t, diff2, acc2, []byte{0x02})
+	checkStorage(t, diff2, acc1, slot1, []byte{0xa1})
+	checkStorage(t, diff2, acc3, slot1, nil)
+
+	// Flattening the bottom diff drops its items from the rebuilt filter
+	disk := base.flatten(diff1)
+	diff2.lock.Lock()
+	diff2.parent = disk
+	diff2.lock.Unlock()
+	diff2.rebloom(disk)
+
+	if diff2.diffed.contains(acc1) {
+		t.Errorf("flattened account retained in the bloom filter")
+	}
+	if diff2.origin != disk {
+		t.Errorf("origin mismatch: have %p, want %p", diff2.origin, disk)
+	}
+	checkAccount(t, diff2, acc1, []byte{0x11})
+	checkStorage(t, diff2, acc3, slot1, nil)
+}
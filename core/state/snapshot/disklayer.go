@@ -0,0 +1,241 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// diskLayer is a low level persistent snapshot built on top of a key-value store.
+type diskLayer struct {
+	diskdb ethdb.Database // Key-value store containing the base snapshot
+	triedb *trie.Database // Trie node cache for reconstruction purposes
+	root   common.Hash    // Root hash of the base snapshot
+	stale  bool           // Signals that the layer became stale (state progressed)
+
+	genPending bool          // Whether the snapshot is still being generated
+	genMarker  []byte        // Hash of the last account generated, nil if none yet
+	genErr     error         // Error the generation failed with, if any
+	genAbort   chan struct{} // Notification channel to abort generating the snapshot
+	genDone    chan struct{} // Notification channel closed when generation stops
+	abortOnce  sync.Once     // Ensures the abort channel is closed only once
+
+	lock sync.RWMutex
+}
+
+// Root returns root hash for which this snapshot was made.
+func (dl *diskLayer) Root() common.Hash {
+	return dl.root
+}
+
+// Parent always returns nil as there's no layer below the disk.
+func (dl *diskLayer) Parent() snapshot {
+	return nil
+}
+
+// Stale return whether this layer has become stale (was flattened across) or if
+// it's still live.
+func (dl *diskLayer) Stale() bool {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	return dl.stale
+}
+
+// markStale invalidates the layer, failing all subsequent reads.
+func (dl *diskLayer) markStale() {
+	dl.lock.Lock()
+	defer dl.lock.Unlock()
+
+	dl.stale = true
+}
+
+// generating returns whether the snapshot is still being generated.
+func (dl *diskLayer) generating() bool {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	return dl.genPending
+}
+
+// failed returns whether the generation of the snapshot failed.
+func (dl *diskLayer) failed() bool {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	return dl.genErr != nil
+}
+
+// abort stops the generation of the snapshot if it's still running and waits
+// for the generator to terminate.
+func (dl *diskLayer) abort() {
+	if dl.genAbort == nil {
+		return
+	}
+	dl.abortOnce.Do(func() { close(dl.genAbort) })
+	<-dl.genDone
+}
+
+// AccountRLP directly retrieves the account RLP associated with a particular
+// hash in the snapshot slim data format.
+func (dl *diskLayer) AccountRLP(hash common.Hash) ([]byte, error) {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	// If the layer was flattened into, consider it invalid (any live reference to
+	// the original should be marked as unusable).
+	if dl.stale {
+		return nil, ErrSnapshotStale
+	}
+	if !dl.covered(hash) {
+		return nil, ErrNotCoveredYet
+	}
+	blob := rawdb.ReadAccountSnapshot(dl.diskdb, hash)
+	if len(blob) > 0 {
+		snapshotAccountHitMeter.Mark(1)
+	} else {
+		snapshotAccountMissMeter.Mark(1)
+	}
+	return blob, nil
+}
+
+// Storage directly retrieves the storage data associated with a particular hash,
+// within a particular account.
+func (dl *diskLayer) Storage(accountHash, storageHash common.Hash) ([]byte, error) {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	if dl.stale {
+		return nil, ErrSnapshotStale
+	}
+	if !dl.covered(accountHash) {
+		return nil, ErrNotCoveredYet
+	}
+	blob := rawdb.ReadStorageSnapshot(dl.diskdb, accountHash, storageHash)
+	if len(blob) > 0 {
+		snapshotStorageHitMeter.Mark(1)
+	} else {
+		snapshotStorageMissMeter.Mark(1)
+	}
+	return blob, nil
+}
+
+// covered returns whether the account and its storage were already written into
+// the snapshot by the generator, assuming the read lock is held.
+func (dl *diskLayer) covered(hash common.Hash) bool {
+	if dl.genErr != nil {
+		return false
+	}
+	if !dl.genPending {
+		return true
+	}
+	return dl.genMarker != nil && bytes.Compare(hash[:], dl.genMarker) <= 0
+}
+
+// Update creates a new layer on top of the existing snapshot diff tree with
+// the specified data items. Note, the maps are retained by the method to avoid
+// copying everything.
+func (dl *diskLayer) Update(blockHash common.Hash, destructs map[common.Hash]struct{}, accounts map[common.Hash][]byte, storage map[common.Hash]map[common.Hash][]byte) *diffLayer {
+	return newDiffLayer(dl, blockHash, destructs, accounts, storage)
+}
+
+// flatten writes the content of a diff layer directly on top of the disk layer
+// into the database, returning a new disk layer representing the diff's state.
+// Both the current disk layer and the diff layer are marked stale.
+//
+// If the snapshot is still being generated, the generator must be stopped first.
+// Only the items already covered by the generator are written, the rest will be
+// generated from the diff's state trie after the generator is resumed on the
+// returned layer.
+func (dl *diskLayer) flatten(diff *diffLayer) *diskLayer {
+	// Hold the write lock until the flattened layer is fully written, so that
+	// concurrent readers either see the old state or notice the staleness
+	dl.lock.Lock()
+	defer dl.lock.Unlock()
+
+	batch := dl.diskdb.NewBatch()
+	if !dl.genPending {
+		rawdb.WriteSnapshotRoot(batch, diff.root)
+	}
+	diff.lock.RLock()
+	for hash := range diff.destructs {
+		if !dl.covered(hash) {
+			continue
+		}
+		rawdb.DeleteAccountSnapshot(batch, hash)
+
+		prefix := append(append([]byte{}, rawdb.SnapshotStoragePrefix...), hash[:]...)
+		err := rawdb.IterateKeys(dl.diskdb, prefix, func(key, value []byte) error {
+			if len(key) == len(rawdb.SnapshotStoragePrefix)+2*common.HashLength {
+				batch.Delete(key)
+			}
+			return nil
+		})
+		if err != nil {
+			log.Crit("Failed to wipe storage snapshot", "account", hash, "err", err)
+		}
+	}
+	for hash, blob := range diff.accounts {
+		if !dl.covered(hash) {
+			continue
+		}
+		rawdb.WriteAccountSnapshot(batch, hash, blob)
+	}
+	for accountHash, slots := range diff.storage {
+		if !dl.covered(accountHash) {
+			continue
+		}
+		for storageHash, blob := range slots {
+			if len(blob) == 0 {
+				rawdb.DeleteStorageSnapshot(batch, accountHash, storageHash)
+			} else {
+				rawdb.WriteStorageSnapshot(batch, accountHash, storageHash, blob)
+			}
+		}
+	}
+	diff.lock.RUnlock()
+
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to write flattened snapshot", "err", err)
+	}
+	dl.stale = true
+	diff.markStale()
+
+	log.Debug("Flattened snapshot layer", "root", diff.root)
+
+	flat := &diskLayer{
+		diskdb:     dl.diskdb,
+		triedb:     dl.triedb,
+		root:       diff.root,
+		genPending: dl.genPending,
+		genMarker:  dl.genMarker,
+		genDone:    make(chan struct{}),
+	}
+	if flat.genPending {
+		flat.genAbort = make(chan struct{})
+	} else {
+		close(flat.genDone)
+	}
+	return flat
+}
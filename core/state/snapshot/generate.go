@@ -0,0 +1,246 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+var (
+	// emptyRoot is the known root hash of an empty trie.
+	emptyRoot = common.HexToHash("56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421")
+
+	// errAborted is returned if the snapshot generation was aborted.
+	errAborted = errors.New("aborted")
+)
+
+// account is the consensus representation of an account, mirroring the format
+// stored in the account trie leaves. It's only needed to locate storage tries.
+type account struct {
+	Nonce      uint64
+	Balance    *big.Int
+	Reputation uint64
+	Root       common.Hash
+	CodeHash   []byte
+}
+
+// generateSnapshot regenerates a brand new snapshot based on an existing state
+// database and head block asynchronously. The snapshot is returned immediately
+// and reads are rejected until the generation finishes.
+func generateSnapshot(diskdb ethdb.Database, triedb *trie.Database, root common.Hash) *diskLayer {
+	dl := &diskLayer{
+		diskdb:     diskdb,
+		triedb:     triedb,
+		root:       root,
+		genPending: true,
+		genAbort:   make(chan struct{}),
+		genDone:    make(chan struct{}),
+	}
+	go dl.generate()
+	return dl
+}
+
+// generate is a background thread that iterates over the state trie of the disk
+// layer and writes all the accounts and storage slots into the flat snapshot,
+// wiping any previous snapshot data first, or resuming from the generation
+// marker if a previous generator was interrupted to flatten diff layers.
+//
+// An aborted generator leaves the layer pending, so that the generation can be
+// resumed on top of the flattened layer.
+func (dl *diskLayer) generate() {
+	defer close(dl.genDone)
+
+	err := dl.regenerate()
+
+	dl.lock.Lock()
+	switch err {
+	case nil:
+		dl.genPending, dl.genMarker = false, nil
+	case errAborted:
+	default:
+		dl.genPending, dl.genErr = false, err
+	}
+	dl.lock.Unlock()
+
+	if err != nil && err != errAborted {
+		log.Error("Failed to generate state snapshot", "root", dl.root, "err", err)
+	}
+}
+
+// regenerate writes the snapshot for the state of the disk layer's root, wiping
+// any previous snapshot first when starting from scratch. The generation marker
+// is advanced whenever a batch of fully generated accounts hits the database.
+func (dl *diskLayer) regenerate() error {
+	dl.lock.RLock()
+	marker := dl.genMarker
+	dl.lock.RUnlock()
+
+	var (
+		start  = time.Now()
+		logged = time.Now()
+		batch  = dl.diskdb.NewBatch()
+
+		accounts, slots int
+
+		done    []byte      // Hash of the last fully generated account
+		current common.Hash // Hash of the account being generated
+		partial bool        // Whether the current account is only partially written
+	)
+	// flush writes out the batch if it grew large enough or the generation was
+	// aborted. An aborted generator deletes the partially written account, so
+	// that the database only contains accounts up to the marker.
+	flush := func(force bool) error {
+		var aborted bool
+		select {
+		case <-dl.genAbort:
+			aborted = true
+		default:
+		}
+		if batch.ValueSize() < ethdb.IdealBatchSize && !force && !aborted {
+			return nil
+		}
+		if err := batch.Write(); err != nil {
+			return err
+		}
+		batch.Reset()
+
+		if done != nil {
+			dl.lock.Lock()
+			dl.genMarker = done
+			dl.lock.Unlock()
+		}
+		if aborted {
+			if partial {
+				if err := wipeAccount(dl.diskdb, current); err != nil {
+					return err
+				}
+			}
+			return errAborted
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Generating state snapshot", "root", dl.root, "accounts", accounts, "slots", slots, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+		return nil
+	}
+	if marker == nil {
+		// Invalidate and wipe any previous snapshot, in this order to remain crash safe
+		rawdb.DeleteSnapshotRoot(batch)
+		if err := flush(true); err != nil {
+			return err
+		}
+		wipe := func(prefix []byte, length int) error {
+			return rawdb.IterateKeys(dl.diskdb, prefix, func(key, value []byte) error {
+				if len(key) != length {
+					return nil
+				}
+				batch.Delete(key)
+				return flush(false)
+			})
+		}
+		if err := wipe(rawdb.SnapshotAccountPrefix, len(rawdb.SnapshotAccountPrefix)+common.HashLength); err != nil {
+			return err
+		}
+		if err := wipe(rawdb.SnapshotStoragePrefix, len(rawdb.SnapshotStoragePrefix)+2*common.HashLength); err != nil {
+			return err
+		}
+	} else {
+		log.Debug("Resuming state snapshot generation", "root", dl.root, "marker", common.BytesToHash(marker))
+	}
+	// Iterate over the account trie and all storage tries beyond the marker,
+	// copying the leaves
+	accTrie, err := trie.New(dl.root, dl.triedb)
+	if err != nil {
+		return err
+	}
+	accIt := trie.NewIterator(accTrie.NodeIterator(marker))
+	for accIt.Next() {
+		if marker != nil && bytes.Equal(accIt.Key, marker) {
+			continue
+		}
+		accountHash := common.BytesToHash(accIt.Key)
+		current, partial = accountHash, true
+
+		rawdb.WriteAccountSnapshot(batch, accountHash, accIt.Value)
+		accounts++
+
+		var acc account
+		if err := rlp.DecodeBytes(accIt.Value, &acc); err != nil {
+			return err
+		}
+		if acc.Root != emptyRoot {
+			storeTrie, err := trie.New(acc.Root, dl.triedb)
+			if err != nil {
+				return err
+			}
+			storeIt := trie.NewIterator(storeTrie.NodeIterator(nil))
+			for storeIt.Next() {
+				rawdb.WriteStorageSnapshot(batch, accountHash, common.BytesToHash(storeIt.Key), storeIt.Value)
+				slots++
+
+				if err := flush(false); err != nil {
+					return err
+				}
+			}
+			if storeIt.Err != nil {
+				return storeIt.Err
+			}
+		}
+		done, partial = common.CopyBytes(accIt.Key), false
+		if err := flush(false); err != nil {
+			return err
+		}
+	}
+	if accIt.Err != nil {
+		return accIt.Err
+	}
+	// Snapshot fully generated, mark it valid
+	rawdb.WriteSnapshotRoot(batch, dl.root)
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	log.Info("Generated state snapshot", "root", dl.root, "accounts", accounts, "slots", slots, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// wipeAccount deletes an account and all its storage slots from the snapshot.
+func wipeAccount(db ethdb.Database, hash common.Hash) error {
+	batch := db.NewBatch()
+	rawdb.DeleteAccountSnapshot(batch, hash)
+
+	prefix := append(append([]byte{}, rawdb.SnapshotStoragePrefix...), hash[:]...)
+	err := rawdb.IterateKeys(db, prefix, func(key, value []byte) error {
+		if len(key) == len(rawdb.SnapshotStoragePrefix)+2*common.HashLength {
+			batch.Delete(key)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return batch.Write()
+}
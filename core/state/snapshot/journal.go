@@ -0,0 +1,127 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// journalAccount is an account entry in a diffLayer's disk journal.
+type journalAccount struct {
+	Hash common.Hash
+	Blob []byte
+}
+
+// journalStorage is an account's storage map in a diffLayer's disk journal.
+type journalStorage struct {
+	Hash common.Hash
+	Keys []common.Hash
+	Vals [][]byte
+}
+
+// journalLayer is a single diffLayer in the disk journal.
+type journalLayer struct {
+	Root      common.Hash
+	Destructs []common.Hash
+	Accounts  []journalAccount
+	Storage   []journalStorage
+}
+
+// journal is the disk representation of the in-memory diff layers leading from
+// the disk layer up to the chain head.
+type journal struct {
+	Base   common.Hash    // Root of the disk layer the diffs are based on
+	Layers []journalLayer // Diff layers in bottom-up order
+}
+
+// writeJournal serializes the given diff layers (ordered top-down) on top of the
+// disk layer and stores them into the database.
+func (t *Tree) writeJournal(disk *diskLayer, diffs []*diffLayer) error {
+	j := journal{Base: disk.root}
+	for i := len(diffs) - 1; i >= 0; i-- {
+		diff := diffs[i]
+
+		diff.lock.RLock()
+		layer := journalLayer{Root: diff.root}
+		for hash := range diff.destructs {
+			layer.Destructs = append(layer.Destructs, hash)
+		}
+		for hash, blob := range diff.accounts {
+			layer.Accounts = append(layer.Accounts, journalAccount{Hash: hash, Blob: blob})
+		}
+		for hash, slots := range diff.storage {
+			storage := journalStorage{Hash: hash}
+			for key, val := range slots {
+				storage.Keys = append(storage.Keys, key)
+				storage.Vals = append(storage.Vals, val)
+			}
+			layer.Storage = append(layer.Storage, storage)
+		}
+		diff.lock.RUnlock()
+
+		j.Layers = append(j.Layers, layer)
+	}
+	blob, err := rlp.EncodeToBytes(&j)
+	if err != nil {
+		return err
+	}
+	rawdb.WriteSnapshotJournal(t.diskdb, blob)
+	log.Info("Journalled state snapshot", "base", disk.root, "layers", len(diffs), "size", common.StorageSize(len(blob)))
+	return nil
+}
+
+// loadJournal restores the diff layers from a serialized journal on top of the
+// given disk layer.
+func (t *Tree) loadJournal(disk *diskLayer, blob []byte) error {
+	var j journal
+	if err := rlp.DecodeBytes(blob, &j); err != nil {
+		return err
+	}
+	if j.Base != disk.root {
+		return fmt.Errorf("journal base mismatch: have %#x, want %#x", j.Base, disk.root)
+	}
+	var parent snapshot = disk
+	for _, layer := range j.Layers {
+		destructs := make(map[common.Hash]struct{}, len(layer.Destructs))
+		for _, hash := range layer.Destructs {
+			destructs[hash] = struct{}{}
+		}
+		accounts := make(map[common.Hash][]byte, len(layer.Accounts))
+		for _, entry := range layer.Accounts {
+			accounts[entry.Hash] = entry.Blob
+		}
+		storage := make(map[common.Hash]map[common.Hash][]byte, len(layer.Storage))
+		for _, entry := range layer.Storage {
+			if len(entry.Keys) != len(entry.Vals) {
+				return fmt.Errorf("storage journal of %#x corrupted: %d keys, %d values", entry.Hash, len(entry.Keys), len(entry.Vals))
+			}
+			slots := make(map[common.Hash][]byte, len(entry.Keys))
+			for i, key := range entry.Keys {
+				slots[key] = entry.Vals[i]
+			}
+			storage[entry.Hash] = slots
+		}
+		parent = parent.Update(layer.Root, destructs, accounts, storage)
+		t.layers[layer.Root] = parent
+	}
+	return nil
+}
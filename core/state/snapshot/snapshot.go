@@ -0,0 +1,378 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package snapshot implements a flat key-value view of the state, allowing
+// accounts and storage slots to be read in a single database lookup instead of
+// walking the state trie.
+package snapshot
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+var (
+	snapshotAccountHitMeter  = metrics.NewRegisteredMeter("state/snapshot/account/hit", nil)
+	snapshotAccountMissMeter = metrics.NewRegisteredMeter("state/snapshot/account/miss", nil)
+	snapshotStorageHitMeter  = metrics.NewRegisteredMeter("state/snapshot/storage/hit", nil)
+	snapshotStorageMissMeter = metrics.NewRegisteredMeter("state/snapshot/storage/miss", nil)
+
+	snapshotBloomAccountMissMeter = metrics.NewRegisteredMeter("state/snapshot/bloom/account/miss", nil)
+	snapshotBloomStorageMissMeter = metrics.NewRegisteredMeter("state/snapshot/bloom/storage/miss", nil)
+)
+
+var (
+	// ErrSnapshotStale is returned from data accessors if the underlying snapshot
+	// layer had been invalidated due to the chain progressing forward far enough
+	// to not maintain the layer's original state.
+	ErrSnapshotStale = errors.New("snapshot stale")
+
+	// ErrNotCoveredYet is returned from data accessors if the underlying snapshot
+	// is being generated currently and the requested data item is not yet in the
+	// range of accounts covered.
+	ErrNotCoveredYet = errors.New("not covered yet")
+
+	// errSnapshotCycle is returned if a snapshot is attempted to be inserted
+	// that forms a cycle in the snapshot tree.
+	errSnapshotCycle = errors.New("snapshot cycle")
+)
+
+// Snapshot represents the functionality supported by a snapshot storage layer.
+type Snapshot interface {
+	// Root returns the root hash for which this snapshot was made.
+	Root() common.Hash
+
+	// AccountRLP directly retrieves the account RLP associated with a particular
+	// hash in the snapshot slim data format. A nil blob means the account does
+	// not exist.
+	AccountRLP(hash common.Hash) ([]byte, error)
+
+	// Storage directly retrieves the storage data associated with a particular
+	// hash, within a particular account. The data is the RLP encoded storage
+	// trie leaf, a nil blob means the slot is empty.
+	Storage(accountHash, storageHash common.Hash) ([]byte, error)
+}
+
+// snapshot is the internal version of the snapshot data layer that supports some
+// additional methods compared to the public API.
+type snapshot interface {
+	Snapshot
+
+	// Parent returns the subsequent layer of a snapshot, or nil if the base was
+	// reached.
+	Parent() snapshot
+
+	// Update creates a new layer on top of the existing snapshot diff tree with
+	// the specified data items.
+	Update(blockRoot common.Hash, destructs map[common.Hash]struct{}, accounts map[common.Hash][]byte, storage map[common.Hash]map[common.Hash][]byte) *diffLayer
+
+	// Stale return whether this layer has become stale (was flattened across) or
+	// if it's still live.
+	Stale() bool
+}
+
+// Tree is an Ethereum state snapshot tree. It consists of one persistent base
+// layer backed by a key-value store, on top of which arbitrarily many in-memory
+// diff layers are topped. The memory diffs can form a tree with branching, but
+// the disk layer is singleton and common to all. If a reorg goes deeper than the
+// disk layer, everything needs to be deleted.
+//
+// The goal of a state snapshot is to allow direct access to account and storage
+// data to avoid expensive multi-level trie lookups.
+type Tree struct {
+	diskdb ethdb.Database           // Persistent database to store the snapshot
+	triedb *trie.Database           // In-memory cache to access the trie through
+	layers map[common.Hash]snapshot // Collection of all known layers
+	lock   sync.RWMutex
+}
+
+// New attempts to load an already existing snapshot from a persistent key-value
+// store (with a number of memory layers from a journal), ensuring that the head
+// of the snapshot matches the expected one.
+//
+// If the snapshot is missing or inconsistent, the entirety is deleted and will
+// be reconstructed from scratch based on the tries in the key-value store, on a
+// background thread. If async is false, New blocks until the generation is done.
+func New(diskdb ethdb.Database, triedb *trie.Database, root common.Hash, async bool) *Tree {
+	snap := &Tree{
+		diskdb: diskdb,
+		triedb: triedb,
+		layers: make(map[common.Hash]snapshot),
+	}
+	if err := snap.load(root); err != nil {
+		log.Warn("Failed to load snapshot, regenerating", "err", err)
+		snap.Rebuild(root)
+	}
+	if !async {
+		if disk := snap.disklayer(); disk != nil {
+			<-disk.genDone
+		}
+	}
+	return snap
+}
+
+// load opens the persisted disk layer and any journalled diff layers on top of
+// it, ensuring the requested root is contained within.
+func (t *Tree) load(root common.Hash) error {
+	base := rawdb.ReadSnapshotRoot(t.diskdb)
+	if base == (common.Hash{}) {
+		return errors.New("missing or corrupted snapshot")
+	}
+	disk := &diskLayer{
+		diskdb:  t.diskdb,
+		triedb:  t.triedb,
+		root:    base,
+		genDone: make(chan struct{}),
+	}
+	close(disk.genDone)
+	t.layers[base] = disk
+
+	// The journal is only valid for a single restart, consume it right away
+	if journal := rawdb.ReadSnapshotJournal(t.diskdb); len(journal) > 0 {
+		rawdb.DeleteSnapshotJournal(t.diskdb)
+		if err := t.loadJournal(disk, journal); err != nil {
+			log.Warn("Failed to load snapshot journal, discarding", "err", err)
+		}
+	}
+	if t.layers[root] == nil {
+		t.layers = make(map[common.Hash]snapshot)
+		return fmt.Errorf("head doesn't match snapshot: have %#x, want %#x", base, root)
+	}
+	log.Info("Loaded state snapshot", "root", base, "layers", len(t.layers))
+	return nil
+}
+
+// Snapshot retrieves a snapshot belonging to the given block root, or nil if no
+// snapshot is maintained for that block.
+func (t *Tree) Snapshot(blockRoot common.Hash) Snapshot {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if layer, ok := t.layers[blockRoot]; ok {
+		return layer
+	}
+	return nil
+}
+
+// Update adds a new snapshot into the tree, if that can be linked to an existing
+// old parent. It is disallowed to insert a disk layer (the origin of all).
+func (t *Tree) Update(blockRoot common.Hash, parentRoot common.Hash, destructs map[common.Hash]struct{}, accounts map[common.Hash][]byte, storage map[common.Hash]map[common.Hash][]byte) error {
+	// Reject noop updates to avoid self-loops in the snapshot tree. This is a
+	// special case that can only happen for empty blocks, which don't change
+	// the state at all.
+	if blockRoot == parentRoot {
+		return errSnapshotCycle
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	// Generate a new snapshot on top of the parent
+	parent, ok := t.layers[parentRoot]
+	if !ok {
+		return fmt.Errorf("parent [%#x] snapshot missing", parentRoot)
+	}
+	if _, ok := t.layers[blockRoot]; ok {
+		return nil // Same state reached on a side chain, nothing to do
+	}
+	t.layers[blockRoot] = parent.Update(blockRoot, destructs, accounts, storage)
+	return nil
+}
+
+// Cap traverses downwards the snapshot tree from a head block hash until the
+// number of allowed layers are crossed. All layers beyond the permitted number
+// are flattened downwards into the persistent disk layer.
+//
+// While the disk layer is being generated, the generator is interrupted for the
+// duration of the flattening and resumed on top of the new disk layer, so that
+// the diff layers don't accumulate in memory until generation finishes.
+func (t *Tree) Cap(root common.Hash, layers int) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	// Nothing to do if the snapshot was disabled
+	if len(t.layers) == 0 {
+		return nil
+	}
+	// Retrieve the head snapshot to cap from
+	snap, ok := t.layers[root]
+	if !ok {
+		return fmt.Errorf("snapshot [%#x] missing", root)
+	}
+	if _, ok := snap.(*diffLayer); !ok {
+		return nil // Head is the disk layer, nothing to flatten
+	}
+	// Collect the chain of diff layers from the head down to the disk layer
+	var (
+		diffs []*diffLayer
+		disk  *diskLayer
+	)
+	for layer := snap; layer != nil; layer = layer.Parent() {
+		switch layer := layer.(type) {
+		case *diffLayer:
+			diffs = append(diffs, layer)
+		case *diskLayer:
+			disk = layer
+		}
+	}
+	if disk == nil {
+		return fmt.Errorf("snapshot [%#x] detached from disk layer", root)
+	}
+	if disk.failed() {
+		// Generation failed, the snapshot is unusable until rebuilt
+		log.Warn("Disabling failed snapshot", "root", disk.root)
+		for _, layer := range t.layers {
+			layer.(staleMarker).markStale()
+		}
+		t.layers = make(map[common.Hash]snapshot)
+		return nil
+	}
+	if len(diffs) <= layers {
+		return nil
+	}
+	// Flatten all the diff layers beyond the limit into the disk layer, starting
+	// from the bottom most one
+	disk.abort()
+	for i := len(diffs) - 1; i >= layers; i-- {
+		disk = disk.flatten(diffs[i])
+	}
+	if disk.generating() {
+		go disk.generate()
+	}
+	if layers > 0 {
+		diffs[layers-1].lock.Lock()
+		diffs[layers-1].parent = disk
+		diffs[layers-1].lock.Unlock()
+	}
+	// Remove any layer that is stale or no longer reachable from the new disk
+	// layer (side chains forking off below it)
+	children := make(map[common.Hash][]common.Hash)
+	for root, layer := range t.layers {
+		if diff, ok := layer.(*diffLayer); ok {
+			parent := diff.Parent().Root()
+			children[parent] = append(children[parent], root)
+		}
+	}
+	// The bloom filters of the surviving layers are rebuilt, parents first, to
+	// drop the items flattened into the disk layer.
+	live := map[common.Hash]snapshot{disk.root: disk}
+	for queue := []common.Hash{disk.root}; len(queue) > 0; queue = queue[1:] {
+		for _, child := range children[queue[0]] {
+			if layer := t.layers[child]; !layer.Stale() && layer.Parent() == live[queue[0]] {
+				layer.(*diffLayer).rebloom(disk)
+				live[child] = layer
+				queue = append(queue, child)
+			}
+		}
+	}
+	for root, layer := range t.layers {
+		if _, ok := live[root]; !ok {
+			layer.(staleMarker).markStale()
+		}
+	}
+	t.layers = live
+	return nil
+}
+
+// Journal persists the disk layer root and all the diff layers leading up to the
+// given head into the database, so they can be restored after a restart. If the
+// snapshot is still being generated, it's aborted and nothing is journalled.
+func (t *Tree) Journal(root common.Hash) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	snap, ok := t.layers[root]
+	if !ok {
+		return fmt.Errorf("snapshot [%#x] missing", root)
+	}
+	var diffs []*diffLayer
+	for layer := snap; layer != nil; layer = layer.Parent() {
+		switch layer := layer.(type) {
+		case *diffLayer:
+			diffs = append(diffs, layer)
+		case *diskLayer:
+			if layer.generating() {
+				layer.abort()
+				return errors.New("snapshot generation not finished")
+			}
+			return t.writeJournal(layer, diffs)
+		}
+	}
+	return fmt.Errorf("snapshot [%#x] detached from disk layer", root)
+}
+
+// Rebuild wipes all available snapshot data from the persistent database and
+// discards all caches and diff layers. Afterwards, it starts a new snapshot
+// generator with the given root hash.
+func (t *Tree) Rebuild(root common.Hash) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	// Invalidate all the layers and stop any running generator
+	for _, layer := range t.layers {
+		if disk, ok := layer.(*diskLayer); ok {
+			disk.abort()
+		}
+		layer.(staleMarker).markStale()
+	}
+	// Start generating a new snapshot from scratch on a background thread
+	log.Info("Rebuilding state snapshot", "root", root)
+	t.layers = map[common.Hash]snapshot{
+		root: generateSnapshot(t.diskdb, t.triedb, root),
+	}
+}
+
+// Release stops any running background generation of the snapshot.
+func (t *Tree) Release() {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if disk := t.disklayerLocked(); disk != nil {
+		disk.abort()
+	}
+}
+
+// disklayer is an internal helper function to return the disk layer.
+func (t *Tree) disklayer() *diskLayer {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return t.disklayerLocked()
+}
+
+// disklayerLocked returns the disk layer of the tree, assuming the lock is held.
+func (t *Tree) disklayerLocked() *diskLayer {
+	for _, layer := range t.layers {
+		for ; layer != nil; layer = layer.Parent() {
+			if disk, ok := layer.(*diskLayer); ok {
+				return disk
+			}
+		}
+	}
+	return nil
+}
+
+// staleMarker is implemented by all snapshot layers to invalidate them.
+type staleMarker interface {
+	markStale()
+}
@@ -0,0 +1,307 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// makeTestState creates a state trie with a number of accounts, some of them
+// with storage, returning the database, the root and the expected flat leaves.
+func makeTestState(t *testing.T) (ethdb.Database, *trie.Database, common.Hash, map[common.Hash][]byte, map[common.Hash]map[common.Hash][]byte) {
+	var (
+		diskdb   = ethdb.NewMemDatabase()
+		triedb   = trie.NewDatabase(diskdb)
+		accounts = make(map[common.Hash][]byte)
+		storage  = make(map[common.Hash]map[common.Hash][]byte)
+	)
+	accTrie, _ := trie.NewSecure(common.Hash{}, triedb, 0)
+	for i := byte(1); i <= 32; i++ {
+		addr := common.BytesToAddress([]byte{i})
+		acc := account{Balance: big.NewInt(int64(i)), Reputation: uint64(i), Root: emptyRoot, CodeHash: crypto.Keccak256(nil)}
+
+		if i%3 == 0 {
+			storeTrie, _ := trie.NewSecure(common.Hash{}, triedb, 0)
+			slots := make(map[common.Hash][]byte)
+			for j := byte(1); j <= i; j++ {
+				key := common.BytesToHash([]byte{j})
+				val, _ := rlp.EncodeToBytes([]byte{i, j})
+				storeTrie.Update(key[:], val)
+				slots[crypto.Keccak256Hash(key[:])] = val
+			}
+			root, err := storeTrie.Commit(nil)
+			if err != nil {
+				t.Fatalf("failed to commit storage trie: %v", err)
+			}
+			acc.Root = root
+			storage[crypto.Keccak256Hash(addr[:])] = slots
+		}
+		blob, _ := rlp.EncodeToBytes(&acc)
+		accTrie.Update(addr[:], blob)
+		accounts[crypto.Keccak256Hash(addr[:])] = blob
+	}
+	root, err := accTrie.Commit(nil)
+	if err != nil {
+		t.Fatalf("failed to commit account trie: %v", err)
+	}
+	if err := triedb.Commit(root, false); err != nil {
+		t.Fatalf("failed to flush tries: %v", err)
+	}
+	return diskdb, triedb, root, accounts, storage
+}
+
+// Tests that a snapshot generated from the state trie contains exactly the same
+// accounts and storage slots, and that leftovers of old snapshots are wiped.
+func TestGenerateSnapshot(t *testing.T) {
+	diskdb, triedb, root, accounts, storage := makeTestState(t)
+
+	// Inject some junk from a previous snapshot that needs to be deleted
+	junk := common.HexToHash("0xdeadbeef")
+	rawdb.WriteAccountSnapshot(diskdb, junk, []byte{0x01})
+	rawdb.WriteStorageSnapshot(diskdb, junk, junk, []byte{0x02})
+
+	snaps := New(diskdb, triedb, root, false)
+	snap := snaps.Snapshot(root)
+	if snap == nil {
+		t.Fatalf("snapshot not available after generation")
+	}
+	for hash, blob := range accounts {
+		checkAccount(t, snap, hash, blob)
+	}
+	for hash, slots := range storage {
+		for key, blob := range slots {
+			checkStorage(t, snap, hash, key, blob)
+		}
+	}
+	checkAccount(t, snap, junk, nil)
+	checkStorage(t, snap, junk, junk, nil)
+
+	if have := rawdb.ReadSnapshotRoot(diskdb); have != root {
+		t.Fatalf("persisted root mismatch: have %x, want %x", have, root)
+	}
+	// Reopening the snapshot should load it without regenerating
+	reopened := New(diskdb, triedb, root, true)
+	if disk := reopened.disklayer(); disk == nil || disk.generating() {
+		t.Fatalf("persisted snapshot not loaded")
+	}
+}
+
+// Tests that capping the snapshot tree flattens the layers beyond the limit into
+// the disk layer, drops the side chains forking off below it and keeps serving
+// the same data from the head.
+func TestTreeCap(t *testing.T) {
+	var (
+		acc  = common.HexToHash("0x01")
+		slot = common.HexToHash("0x11")
+	)
+	base := newTestDisk(common.HexToHash("0x00"), map[common.Hash][]byte{acc: {0x00}}, nil)
+	snaps := &Tree{
+		diskdb: base.diskdb,
+		layers: map[common.Hash]snapshot{base.root: base},
+	}
+	// Build a chain of 8 diff layers, each modifying the account and a slot
+	parent := base.root
+	for i := byte(1); i <= 8; i++ {
+		root := common.BytesToHash([]byte{i})
+		err := snaps.Update(root, parent, nil,
+			map[common.Hash][]byte{acc: {i}},
+			map[common.Hash]map[common.Hash][]byte{acc: {slot: {i}}},
+		)
+		if err != nil {
+			t.Fatalf("failed to add layer %d: %v", i, err)
+		}
+		parent = root
+	}
+	// Add a side chain forking off from the second layer
+	side := common.HexToHash("0xaa")
+	if err := snaps.Update(side, common.BytesToHash([]byte{2}), nil, nil, nil); err != nil {
+		t.Fatalf("failed to add side layer: %v", err)
+	}
+	if err := snaps.Update(parent, parent, nil, nil, nil); err != errSnapshotCycle {
+		t.Fatalf("noop update error mismatch: have %v, want %v", err, errSnapshotCycle)
+	}
+	stale := snaps.Snapshot(common.BytesToHash([]byte{2}))
+
+	if err := snaps.Cap(parent, 3); err != nil {
+		t.Fatalf("failed to cap tree: %v", err)
+	}
+	if len(snaps.layers) != 4 {
+		t.Fatalf("layer count mismatch: have %d, want %d", len(snaps.layers), 4)
+	}
+	if snaps.Snapshot(side) != nil {
+		t.Fatalf("side chain below the disk layer retained")
+	}
+	if have, want := rawdb.ReadSnapshotRoot(base.diskdb), common.BytesToHash([]byte{5}); have != want {
+		t.Fatalf("persisted root mismatch: have %x, want %x", have, want)
+	}
+	if _, err := stale.AccountRLP(acc); err != ErrSnapshotStale {
+		t.Fatalf("flattened layer error mismatch: have %v, want %v", err, ErrSnapshotStale)
+	}
+	checkAccount(t, snaps.Snapshot(parent), acc, []byte{8})
+	checkStorage(t, snaps.Snapshot(parent), acc, slot, []byte{8})
+	checkAccount(t, snaps.Snapshot(common.BytesToHash([]byte{5})), acc, []byte{5})
+	checkStorage(t, snaps.Snapshot(common.BytesToHash([]byte{6})), acc, slot, []byte{6})
+}
+
+// Tests that capping the snapshot tree while the disk layer is being generated
+// flattens the diff layers into the covered range of the snapshot and resumes
+// the generation on top of the new disk layer.
+func TestTreeCapDuringGeneration(t *testing.T) {
+	diskdb, triedb, root, accounts, storage := makeTestState(t)
+
+	// Simulate an interrupted generator having covered half of the accounts
+	var hashes []common.Hash
+	for hash := range accounts {
+		hashes = append(hashes, hash)
+	}
+	sort.Slice(hashes, func(i, j int) bool { return bytes.Compare(hashes[i][:], hashes[j][:]) < 0 })
+	marker := hashes[len(hashes)/2]
+	for _, hash := range hashes[:len(hashes)/2+1] {
+		rawdb.WriteAccountSnapshot(diskdb, hash, accounts[hash])
+		for key, blob := range storage[hash] {
+			rawdb.WriteStorageSnapshot(diskdb, hash, key, blob)
+		}
+	}
+	done := make(chan struct{})
+	close(done)
+	disk := &diskLayer{
+		diskdb:     diskdb,
+		triedb:     triedb,
+		root:       root,
+		genPending: true,
+		genMarker:  marker[:],
+		genAbort:   make(chan struct{}),
+		genDone:    done,
+	}
+	if _, err := disk.AccountRLP(hashes[0]); err != nil {
+		t.Fatalf("covered account not served: %v", err)
+	}
+	if _, err := disk.AccountRLP(hashes[len(hashes)-1]); err != ErrNotCoveredYet {
+		t.Fatalf("uncovered account error mismatch: have %v, want %v", err, ErrNotCoveredYet)
+	}
+	// Modify and delete accounts on both sides of the marker
+	accTrie, _ := trie.NewSecure(root, triedb, 0)
+	modified := make(map[common.Hash][]byte)
+	destructs := make(map[common.Hash]struct{})
+	for i := byte(1); i <= 32; i++ {
+		addr := common.BytesToAddress([]byte{i})
+		hash := crypto.Keccak256Hash(addr[:])
+
+		switch i % 4 {
+		case 1:
+			var acc account
+			rlp.DecodeBytes(accounts[hash], &acc)
+			acc.Balance = big.NewInt(1000 + int64(i))
+			blob, _ := rlp.EncodeToBytes(&acc)
+			accTrie.Update(addr[:], blob)
+			modified[hash], accounts[hash] = blob, blob
+		case 2:
+			accTrie.Delete(addr[:])
+			destructs[hash] = struct{}{}
+			delete(accounts, hash)
+			delete(storage, hash)
+		}
+	}
+	head, err := accTrie.Commit(nil)
+	if err != nil {
+		t.Fatalf("failed to commit account trie: %v", err)
+	}
+	snaps := &Tree{
+		diskdb: diskdb,
+		triedb: triedb,
+		layers: map[common.Hash]snapshot{root: disk},
+	}
+	if err := snaps.Update(head, root, destructs, modified, nil); err != nil {
+		t.Fatalf("failed to add layer: %v", err)
+	}
+	if err := snaps.Cap(head, 0); err != nil {
+		t.Fatalf("failed to cap tree: %v", err)
+	}
+	if len(snaps.layers) != 1 {
+		t.Fatalf("layer count mismatch: have %d, want %d", len(snaps.layers), 1)
+	}
+	flat := snaps.disklayer()
+	<-flat.genDone
+	if flat.root != head || flat.generating() || flat.failed() {
+		t.Fatalf("generation not resumed on the flattened layer")
+	}
+	if have := rawdb.ReadSnapshotRoot(diskdb); have != head {
+		t.Fatalf("persisted root mismatch: have %x, want %x", have, head)
+	}
+	for _, hash := range hashes {
+		checkAccount(t, flat, hash, accounts[hash])
+	}
+	for hash, slots := range storage {
+		for key, blob := range slots {
+			checkStorage(t, flat, hash, key, blob)
+		}
+	}
+	for hash := range destructs {
+		rawdb.IterateKeys(diskdb, append(append([]byte{}, rawdb.SnapshotStoragePrefix...), hash[:]...), func(key, value []byte) error {
+			t.Errorf("storage of deleted account %x retained: %x", hash, key)
+			return nil
+		})
+	}
+}
+
+// Tests that the diff layers journalled at shutdown are restored when the
+// snapshot is reopened.
+func TestTreeJournal(t *testing.T) {
+	diskdb, triedb, root, accounts, _ := makeTestState(t)
+	snaps := New(diskdb, triedb, root, false)
+
+	var (
+		acc  = common.HexToHash("0x01")
+		slot = common.HexToHash("0x11")
+		head = common.HexToHash("0x02")
+	)
+	if err := snaps.Update(common.HexToHash("0x01"), root, nil, map[common.Hash][]byte{acc: {0x01}}, nil); err != nil {
+		t.Fatalf("failed to add layer: %v", err)
+	}
+	if err := snaps.Update(head, common.HexToHash("0x01"), nil, nil, map[common.Hash]map[common.Hash][]byte{acc: {slot: {0x02}}}); err != nil {
+		t.Fatalf("failed to add layer: %v", err)
+	}
+	if err := snaps.Journal(head); err != nil {
+		t.Fatalf("failed to journal snapshot: %v", err)
+	}
+	reopened := New(diskdb, triedb, head, true)
+	if disk := reopened.disklayer(); disk == nil || disk.generating() {
+		t.Fatalf("journalled snapshot not loaded")
+	}
+	snap := reopened.Snapshot(head)
+	if snap == nil {
+		t.Fatalf("journalled head layer missing")
+	}
+	checkAccount(t, snap, acc, []byte{0x01})
+	checkStorage(t, snap, acc, slot, []byte{0x02})
+	for hash, blob := range accounts {
+		checkAccount(t, snap, hash, blob)
+	}
+	if journal := rawdb.ReadSnapshotJournal(diskdb); len(journal) != 0 {
+		t.Fatalf("journal not consumed on load")
+	}
+}
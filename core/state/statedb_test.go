@@ -31,6 +31,7 @@ import (
 	check "gopkg.in/check.v1"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethdb"
 )
@@ -435,3 +436,70 @@ func TestCopyOfCopy(t *testing.T) {
 		t.Fatalf("2nd copy fail, expected 42, got %v", got)
 	}
 }
+
+// Tests that a state backed by the flat snapshot serves the exact same accounts
+// and storage slots as a trie-only state across many blocks of modifications,
+// including account destructions and resurrections.
+func TestSnapshotConsistency(t *testing.T) {
+	var (
+		diskdb = ethdb.NewMemDatabase()
+		db     = NewDatabase(diskdb)
+		snaps  = snapshot.New(diskdb, db.TrieDB(), types.EmptyRootHash, false)
+		rng    = rand.New(rand.NewSource(1))
+		root   = types.EmptyRootHash
+		addrs  = make([]common.Address, 16)
+		keys   = make([]common.Hash, 8)
+	)
+	for i := range addrs {
+		addrs[i] = common.BytesToAddress([]byte{byte(i + 1)})
+	}
+	for i := range keys {
+		keys[i] = common.BytesToHash([]byte{byte(i + 1)})
+	}
+	for block := 0; block < 64; block++ {
+		state, err := NewWithSnapshot(root, db, snaps)
+		if err != nil {
+			t.Fatalf("block %d: failed to open state: %v", block, err)
+		}
+		for i := 0; i < 8; i++ {
+			addr := addrs[rng.Intn(len(addrs))]
+			switch rng.Intn(4) {
+			case 0:
+				state.Suicide(addr)
+			case 1:
+				state.CreateAccount(addr)
+				state.SetState(addr, keys[rng.Intn(len(keys))], common.BigToHash(big.NewInt(rng.Int63())))
+			case 2:
+				state.SetState(addr, keys[rng.Intn(len(keys))], common.Hash{})
+			default:
+				state.AddBalance(addr, big.NewInt(rng.Int63n(1000)+1))
+				state.SetState(addr, keys[rng.Intn(len(keys))], common.BigToHash(big.NewInt(rng.Int63())))
+			}
+		}
+		if root, err = state.Commit(false); err != nil {
+			t.Fatalf("block %d: failed to commit state: %v", block, err)
+		}
+		if err := snaps.Cap(root, 4); err != nil {
+			t.Fatalf("block %d: failed to cap snapshot: %v", block, err)
+		}
+		// Cross check the snapshot backed reads against the trie
+		snapState, _ := NewWithSnapshot(root, db, snaps)
+		trieState, _ := New(root, db)
+		if snapState.snap == nil {
+			t.Fatalf("block %d: snapshot layer missing", block)
+		}
+		for _, addr := range addrs {
+			if have, want := snapState.Exist(addr), trieState.Exist(addr); have != want {
+				t.Fatalf("block %d: account %x existence mismatch: have %v, want %v", block, addr, have, want)
+			}
+			if have, want := snapState.GetBalance(addr), trieState.GetBalance(addr); have.Cmp(want) != 0 {
+				t.Fatalf("block %d: account %x balance mismatch: have %v, want %v", block, addr, have, want)
+			}
+			for _, key := range keys {
+				if have, want := snapState.GetState(addr, key), trieState.GetState(addr, key); have != want {
+					t.Fatalf("block %d: slot %x/%x mismatch: have %x, want %x", block, addr, key, have, want)
+				}
+			}
+		}
+	}
+}
@@ -26,6 +26,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/eth/downloader"
@@ -91,6 +92,13 @@ type Config struct {
 	LightServ  int `toml:",omitempty"` // Maximum percentage of time allowed for serving LES requests
 	LightPeers int `toml:",omitempty"` // Maximum number of LES client peers
 
+	// Ultra light client options
+	ULC *ULCConfig `toml:",omitempty"`
+
+	// Checkpoint oracle used by light clients and servers to agree on the latest
+	// trusted checkpoint
+	CheckpointOracle *params.CheckpointOracleConfig `toml:",omitempty"`
+
 	// Database options
 	SkipBcVersionCheck bool `toml:"-"`
 	DatabaseHandles    int  `toml:"-"`
@@ -98,6 +106,8 @@ type Config struct {
 	TrieCleanCache     int
 	TrieDirtyCache     int
 	TrieTimeout        time.Duration
+	AncientThreshold   uint64 `toml:",omitempty"` // Number of recent blocks to keep out of the ancient store (0 = disabled)
+	Snapshot           bool   `toml:",omitempty"` // Whether to maintain a flat state snapshot for fast state reads
 
 	// Mining-related options
 	Etherbase      common.Address `toml:",omitempty"`
@@ -112,6 +122,10 @@ type Config struct {
 	// Ethash options
 	Ethash ethash.Config
 
+	// Engine overrides the consensus engine otherwise created from the chain
+	// configuration, allowing simulations to plug in custom sealers.
+	Engine consensus.Engine `toml:"-"`
+
 	// Transaction pool options
 	TxPool core.TxPoolConfig
 
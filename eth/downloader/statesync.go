@@ -26,8 +26,10 @@ import (
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/crypto/sha3"
+	"github.com/ethereum/go-ethereum/eth/snap"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/trie"
 )
 
@@ -57,9 +59,17 @@ type stateSyncStats struct {
 	pending    uint64 // Number of still pending state entries
 }
 
-// syncState starts downloading state with the given root hash.
+// syncState starts downloading state with the given root hash. If any of the
+// peers advertise the snap protocol, the state is retrieved as account and
+// storage ranges, otherwise node by node. The snap syncer waits for the peers
+// to finish their snap handshake, so their registration order doesn't matter.
 func (d *Downloader) syncState(root common.Hash) *stateSync {
 	s := newStateSync(d, root)
+	if d.peers.SnapPeers() > 0 {
+		log.Debug("Syncing state via snap protocol", "root", root)
+		go d.runSnapSync(s, root)
+		return s
+	}
 	select {
 	case d.stateSyncStart <- s:
 	case <-d.quitCh:
@@ -69,6 +79,25 @@ func (d *Downloader) syncState(root common.Hash) *stateSync {
 	return s
 }
 
+// runSnapSync retrieves the state of a sync via the snap protocol, until it is
+// complete, cancelled or the downloader terminates.
+func (d *Downloader) runSnapSync(s *stateSync, root common.Hash) {
+	cancel := make(chan struct{})
+	go func() {
+		select {
+		case <-s.cancel:
+		case <-d.quitCh:
+		case <-s.done:
+			return
+		}
+		close(cancel)
+	}()
+	if s.err = d.SnapSyncer.Sync(root, cancel); s.err == snap.ErrCancelled {
+		s.err = errCancelStateFetch
+	}
+	close(s.done)
+}
+
 // stateFetcher manages the active state sync and accepts requests
 // on its behalf.
 func (d *Downloader) stateFetcher() {
@@ -310,7 +339,7 @@ func (s *stateSync) loop() (err error) {
 				// 2 items are the minimum requested, if even that times out, we've no use of
 				// this peer at the moment.
 				log.Warn("Stalling state sync, dropping peer", "peer", req.peer.id)
-				s.d.dropPeer(req.peer.id)
+				s.d.dropPeer(req.peer.id, p2p.RequestTimeout)
 			}
 			// Process all the received blobs and check for stale delivery
 			delivered, err := s.process(req)
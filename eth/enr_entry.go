@@ -0,0 +1,112 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/forkid"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// errNoReputationLayer is returned by the dial filter if a remote node runs the
+// eth protocol without the reputation layer.
+var errNoReputationLayer = errors.New("reputation layer not supported")
+
+// ethEntry is the "eth" ENR entry which advertises the eth protocol on the
+// discovery network.
+type ethEntry struct {
+	ForkID     forkid.ID // Fork identifier of the chain head
+	ChainID    uint64    // Chain identifier used for replay protection
+	Reputation bool      // Whether the node runs the reputation consensus layer
+
+	// Ignore additional fields (for forward compatibility).
+	Rest []rlp.RawValue `rlp:"tail"`
+}
+
+// ENRKey implements enr.Entry.
+func (e ethEntry) ENRKey() string {
+	return "eth"
+}
+
+// startEthEntryUpdate keeps the eth entry of the local node record up to date,
+// updating the fork ID whenever the chain head changes.
+func (s *Ethereum) startEthEntryUpdate(ln *enode.LocalNode) {
+	var (
+		newHead = make(chan core.ChainHeadEvent, 10)
+		sub     = s.blockchain.SubscribeChainHeadEvent(newHead)
+	)
+	go func() {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case <-newHead:
+				ln.Set(s.currentEthEntry())
+			case <-sub.Err():
+				// Would be nice to sync with s.Stop, but there is no
+				// good way to do that.
+				return
+			}
+		}
+	}()
+}
+
+// currentEthEntry assembles the eth entry of the local node record based on the
+// current chain head.
+func (s *Ethereum) currentEthEntry() *ethEntry {
+	entry := &ethEntry{
+		ForkID:     forkid.NewID(s.chainConfig, s.blockchain.Genesis().Hash(), s.blockchain.CurrentHeader().Number.Uint64()),
+		Reputation: true,
+	}
+	if s.chainConfig.ChainID != nil {
+		entry.ChainID = s.chainConfig.ChainID.Uint64()
+	}
+	return entry
+}
+
+// newDialFilter creates a dial filter rejecting the nodes which advertise an eth
+// entry incompatible with the local chain. Nodes without an eth entry are let
+// through, as their compatibility can only be decided by the handshake.
+func (s *Ethereum) newDialFilter() func(n *enode.Node) error {
+	forkFilter := forkid.NewFilter(s.chainConfig, s.blockchain.Genesis().Hash(), func() uint64 {
+		return s.blockchain.CurrentHeader().Number.Uint64()
+	})
+	return func(n *enode.Node) error {
+		var entry ethEntry
+		if err := n.Load(&entry); err != nil {
+			if enr.IsNotFound(err) {
+				return nil
+			}
+			return err
+		}
+		var chainID uint64
+		if s.chainConfig.ChainID != nil {
+			chainID = s.chainConfig.ChainID.Uint64()
+		}
+		if entry.ChainID != chainID {
+			return fmt.Errorf("chain ID mismatch: have %d, want %d", entry.ChainID, chainID)
+		}
+		if !entry.Reputation {
+			return errNoReputationLayer
+		}
+		return forkFilter(entry.ForkID)
+	}
+}
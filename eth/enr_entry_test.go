@@ -0,0 +1,64 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/forkid"
+	"github.com/ethereum/go-ethereum/eth/downloader"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+)
+
+// Tests that the dial filter only lets through nodes advertising an eth entry
+// compatible with the local chain, or no eth entry at all.
+func TestDialFilter(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 4, nil, nil)
+	defer pm.Stop()
+
+	eth := &Ethereum{chainConfig: pm.chainconfig, blockchain: pm.blockchain}
+	filter := eth.newDialFilter()
+
+	local := eth.currentEthEntry()
+	if !local.Reputation {
+		t.Fatalf("local entry doesn't advertise the reputation layer")
+	}
+	makeNode := func(entry *ethEntry) *enode.Node {
+		var r enr.Record
+		if entry != nil {
+			r.Set(entry)
+		}
+		return enode.SignNull(&r, enode.ID{1})
+	}
+	tests := []struct {
+		entry *ethEntry
+		pass  bool
+	}{
+		{nil, true},
+		{local, true},
+		{&ethEntry{ForkID: local.ForkID, ChainID: local.ChainID + 1, Reputation: true}, false},
+		{&ethEntry{ForkID: local.ForkID, ChainID: local.ChainID, Reputation: false}, false},
+		{&ethEntry{ForkID: forkid.ID{Hash: [4]byte{0xde, 0xad, 0xbe, 0xef}}, ChainID: local.ChainID, Reputation: true}, false},
+	}
+	for i, tt := range tests {
+		err := filter(makeNode(tt.entry))
+		if pass := err == nil; pass != tt.pass {
+			t.Errorf("test %d: filter result mismatch: have %v (err %v), want %v", i, pass, err, tt.pass)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2026 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethsim
+
+import (
+	"math"
+	"math/big"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// two256 is a big integer representing 2^256.
+var two256 = new(big.Int).Exp(big.NewInt(2), big.NewInt(256), big.NewInt(0))
+
+// engine is a fake proof-of-work engine for simulated mining networks. Like the
+// fake ethash mode it accepts all seals, but it delays sealing by a random time
+// modelling the search of a miner with the configured hash power for a nonce
+// meeting the reputation weighted target.
+type engine struct {
+	*ethash.Ethash
+
+	period time.Duration // Average seal time with unit power, initial reputation
+	power  float64       // Hash power of the miner relative to unit power
+	rand   *rand.Rand
+	lock   sync.Mutex // Protects the power and the random source
+}
+
+func newEngine(period time.Duration) *engine {
+	return &engine{
+		Ethash: ethash.NewFaker(),
+		period: period,
+		power:  1,
+		rand:   rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// setPower sets the hash power of the miner, relative to unit power.
+func (e *engine) setPower(power float64) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.power = power
+}
+
+// sealDelay draws the time it takes to seal the given header. Nonce searches are
+// memoryless, so the delay is exponentially distributed with a mean proportional
+// to the reputation weighted difficulty of the header.
+func (e *engine) sealDelay(chain consensus.ChainReader, header *types.Header) (time.Duration, error) {
+	target, err := e.SealTarget(chain, header)
+	if err != nil {
+		return 0, err
+	}
+	weighted := new(big.Float).SetInt(new(big.Int).Div(two256, target))
+	ratio, _ := weighted.Quo(weighted, new(big.Float).SetInt(header.Difficulty)).Float64()
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if e.power <= 0 {
+		return time.Duration(math.MaxInt64), nil
+	}
+	mean := float64(e.period) * ratio / e.power
+	return time.Duration(e.rand.ExpFloat64() * mean), nil
+}
+
+// Seal implements consensus.Engine, sealing the block with a fake proof-of-work
+// after the simulated nonce search finished.
+func (e *engine) Seal(chain consensus.ChainReader, block *types.Block, results chan<- *types.Block, stop <-chan struct{}) error {
+	delay, err := e.sealDelay(chain, block.Header())
+	if err != nil {
+		return err
+	}
+	go func() {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+			if err := e.Ethash.Seal(chain, block, results, stop); err != nil {
+				log.Warn("Simulated block sealing failed", "err", err)
+			}
+		case <-stop:
+		}
+	}()
+	return nil
+}
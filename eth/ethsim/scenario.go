@@ -0,0 +1,453 @@
+// Copyright 2026 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethsim
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/simulations"
+	"github.com/ethereum/go-ethereum/p2p/simulations/adapters"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Timeouts of the scenarios, in block periods of the simulated network, so that
+// scenarios run equally on fast and slow networks.
+const (
+	convergePeriods = 20 // Time the nodes have to agree on the chain head
+	releasePeriods  = 10 // Time a withholding miner waits for its fork to catch up before releasing it
+)
+
+// connectTimeout is the time nodes have to (dis)connect, which depends on the
+// p2p dial backoff rather than the block period.
+const connectTimeout = time.Minute
+
+// Scenario is a scripted run of a simulated mining network, checking the
+// reputation distribution the network converges to.
+type Scenario struct {
+	Name        string
+	Description string
+	Miners      []Miner             // Miners of the network, each running on its own node
+	Steps       []Step              // Actions run on the network in order
+	Check       func(*Result) error // Assertion on the converged network
+}
+
+// Miner configures a mining node of a scenario.
+type Miner struct {
+	Coinbase common.Address // Account credited with the sealed blocks
+	Power    float64        // Hash power relative to unit power
+}
+
+// Step is an action run on a simulated network.
+type Step func(*Sim) error
+
+// Result is the state a simulated network converged to.
+type Result struct {
+	Head       common.Hash               // Head of the canonical chain
+	Number     uint64                    // Number of the head block
+	Reputation map[common.Address]uint64 // Reputation of the miners at the head
+	Blocks     map[common.Address]int    // Number of canonical blocks sealed by each miner
+	Withheld   map[common.Address]int    // Number of blocks each miner withheld and released
+	Adopted    map[common.Address]int    // Number of released withheld blocks that became canonical
+}
+
+// Sim is a running scenario, driven through the simulations HTTP API.
+type Sim struct {
+	client *simulations.Client
+	nodes  []string        // IDs of the nodes, indexed like the miners
+	rpcs   []*rpc.Client   // RPC clients of the nodes
+	conns  map[[2]int]bool // Currently connected node pairs
+	miners []Miner
+	period time.Duration // Average block time of a unit power miner
+
+	forks    map[int]uint64                 // Fork points of the nodes withholding their blocks
+	withheld map[common.Hash]common.Address // Released withheld blocks, mapped to their miner
+}
+
+// Run runs the scenario on the simulation network served by the given client.
+// The network must run the simulated eth service with the default genesis.
+func (s *Scenario) Run(client *simulations.Client) (*Result, error) {
+	sim := &Sim{
+		client:   client,
+		conns:    make(map[[2]int]bool),
+		miners:   s.Miners,
+		forks:    make(map[int]uint64),
+		withheld: make(map[common.Hash]common.Address),
+	}
+	defer sim.close()
+
+	for i := range s.Miners {
+		config := adapters.RandomNodeConfig()
+		config.Name = fmt.Sprintf("%s%02d", s.Name, i)
+		config.Services = []string{ServiceName}
+
+		node, err := client.CreateNode(config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create node %d: %v", i, err)
+		}
+		if err := client.StartNode(node.ID); err != nil {
+			return nil, fmt.Errorf("failed to start node %d: %v", i, err)
+		}
+		rpc, err := client.RPCClient(context.Background(), node.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to node %d: %v", i, err)
+		}
+		sim.nodes = append(sim.nodes, node.ID)
+		sim.rpcs = append(sim.rpcs, rpc)
+	}
+	if err := sim.rpcs[0].Call(&sim.period, "ethsim_period"); err != nil {
+		return nil, fmt.Errorf("failed to retrieve block period: %v", err)
+	}
+	for i, step := range s.Steps {
+		if err := step(sim); err != nil {
+			return nil, fmt.Errorf("step %d failed: %v", i, err)
+		}
+	}
+	// Nodes keep their own block among equally heavy ones, so competing forks
+	// may outlive the scenario. The first miner keeps sealing until its fork
+	// settled them, and the network converges once it stopped too.
+	log.Info("Waiting for simulated network to converge", "scenario", s.Name)
+	if err := sim.StopMining(sim.all()[1:]...); err != nil {
+		return nil, err
+	}
+	if _, err := sim.converge(); err != nil {
+		return nil, err
+	}
+	if err := sim.StopMining(0); err != nil {
+		return nil, err
+	}
+	head, err := sim.converge()
+	if err != nil {
+		return nil, err
+	}
+	result, err := sim.result(context.Background(), head)
+	if err != nil {
+		return nil, err
+	}
+	if s.Check != nil {
+		if err := s.Check(result); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// all returns the indexes of all nodes.
+func (sim *Sim) all() []int {
+	all := make([]int, len(sim.nodes))
+	for i := range all {
+		all[i] = i
+	}
+	return all
+}
+
+// Connect connects all nodes within each of the given groups, and waits for the
+// connections to be established. Without groups, all nodes are connected to
+// each other.
+func (sim *Sim) Connect(groups ...[]int) error {
+	if len(groups) == 0 {
+		groups = [][]int{sim.all()}
+	}
+	var dialed [][2]int
+	for _, group := range groups {
+		for i, one := range group {
+			for _, other := range group[i+1:] {
+				if sim.conns[pair(one, other)] {
+					continue
+				}
+				if err := sim.client.ConnectNode(sim.nodes[one], sim.nodes[other]); err != nil {
+					return fmt.Errorf("failed to connect node %d to %d: %v", one, other, err)
+				}
+				sim.conns[pair(one, other)] = true
+				dialed = append(dialed, [2]int{one, other})
+			}
+		}
+	}
+	return sim.await(dialed, true)
+}
+
+// Partition disconnects the nodes of different groups from each other.
+func (sim *Sim) Partition(groups ...[]int) error {
+	group := make(map[int]int)
+	for i, nodes := range groups {
+		for _, node := range nodes {
+			group[node] = i
+		}
+	}
+	var dropped [][2]int
+	for conn := range sim.conns {
+		if group[conn[0]] == group[conn[1]] {
+			continue
+		}
+		if err := sim.client.DisconnectNode(sim.nodes[conn[0]], sim.nodes[conn[1]]); err != nil {
+			return fmt.Errorf("failed to disconnect node %d from %d: %v", conn[0], conn[1], err)
+		}
+		delete(sim.conns, conn)
+		dropped = append(dropped, conn)
+	}
+	return sim.await(dropped, false)
+}
+
+// await waits until the given node pairs are connected or disconnected. Nodes
+// don't redial a peer for a while after dropping it, so healing a partition may
+// take longer than the scenario's block periods.
+func (sim *Sim) await(pairs [][2]int, up bool) error {
+	timeout := time.After(connectTimeout)
+	for _, conn := range pairs {
+		for {
+			var peers []*p2p.PeerInfo
+			if err := sim.rpcs[conn[0]].Call(&peers, "admin_peers"); err != nil {
+				return fmt.Errorf("failed to retrieve peers of node %d: %v", conn[0], err)
+			}
+			connected := false
+			for _, peer := range peers {
+				if peer.ID == sim.nodes[conn[1]] {
+					connected = true
+				}
+			}
+			if connected == up {
+				break
+			}
+			select {
+			case <-timeout:
+				return fmt.Errorf("timed out waiting for connection change of node %d to %d", conn[0], conn[1])
+			case <-time.After(100 * time.Millisecond):
+			}
+		}
+	}
+	return nil
+}
+
+// StartMining starts the miners of the given nodes, or of all nodes if none
+// are given.
+func (sim *Sim) StartMining(nodes ...int) error {
+	if len(nodes) == 0 {
+		nodes = sim.all()
+	}
+	for _, i := range nodes {
+		miner := sim.miners[i]
+		if err := sim.rpcs[i].Call(nil, "ethsim_setPower", miner.Power); err != nil {
+			return fmt.Errorf("failed to set power of node %d: %v", i, err)
+		}
+		if err := sim.rpcs[i].Call(nil, "miner_setEtherbase", miner.Coinbase); err != nil {
+			return fmt.Errorf("failed to set etherbase of node %d: %v", i, err)
+		}
+		if err := sim.rpcs[i].Call(nil, "miner_start", 1); err != nil {
+			return fmt.Errorf("failed to start miner of node %d: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// Wait lets the network run for the given number of block periods.
+func (sim *Sim) Wait(periods int) {
+	time.Sleep(time.Duration(periods) * sim.period)
+}
+
+// Withhold disconnects the node from all others, so that its miner builds a
+// private fork from the current head.
+func (sim *Sim) Withhold(node int) error {
+	header, err := ethclient.NewClient(sim.rpcs[node]).HeaderByNumber(context.Background(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve head of node %d: %v", node, err)
+	}
+	var rest []int
+	for i := range sim.nodes {
+		if i != node {
+			rest = append(rest, i)
+		}
+	}
+	if err := sim.Partition([]int{node}, rest); err != nil {
+		return err
+	}
+	sim.forks[node] = header.Number.Uint64()
+	return nil
+}
+
+// Release waits until the private fork of a withholding node is at most one
+// block behind the honest lead, or until it gave up on catching up, and then
+// publishes it by connecting the node to all others.
+func (sim *Sim) Release(node int) error {
+	fork, ok := sim.forks[node]
+	if !ok {
+		return fmt.Errorf("node %d is not withholding blocks", node)
+	}
+	timeout := time.After(releasePeriods * sim.period)
+	for released := false; !released; {
+		private, lead, err := sim.lead(context.Background(), node)
+		if err != nil {
+			return err
+		}
+		if private+1 >= lead {
+			break
+		}
+		select {
+		case <-timeout:
+			log.Info("Withheld fork fell behind, releasing", "node", node, "private", private, "lead", lead)
+			released = true
+		case <-time.After(sim.period / 4):
+		}
+	}
+	if err := sim.collect(node, fork); err != nil {
+		return err
+	}
+	delete(sim.forks, node)
+	return sim.Connect()
+}
+
+// lead returns the head number of the given node, and the highest head number
+// of all other nodes.
+func (sim *Sim) lead(ctx context.Context, node int) (private uint64, lead uint64, err error) {
+	for i, rpc := range sim.rpcs {
+		header, err := ethclient.NewClient(rpc).HeaderByNumber(ctx, nil)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to retrieve head of node %d: %v", i, err)
+		}
+		switch number := header.Number.Uint64(); {
+		case i == node:
+			private = number
+		case number > lead:
+			lead = number
+		}
+	}
+	return private, lead, nil
+}
+
+// collect records the blocks of the private fork of a node, sealed after the
+// given fork point.
+func (sim *Sim) collect(node int, fork uint64) error {
+	client := ethclient.NewClient(sim.rpcs[node])
+
+	header, err := client.HeaderByNumber(context.Background(), nil)
+	for ; err == nil && header.Number.Uint64() > fork; header, err = client.HeaderByHash(context.Background(), header.ParentHash) {
+		sim.withheld[header.Hash()] = header.Coinbase
+	}
+	if err != nil {
+		return fmt.Errorf("failed to collect fork of node %d: %v", node, err)
+	}
+	return nil
+}
+
+// StopMining stops the miners of the given nodes, or of all nodes if none are
+// given.
+func (sim *Sim) StopMining(nodes ...int) error {
+	if len(nodes) == 0 {
+		nodes = sim.all()
+	}
+	for _, i := range nodes {
+		if err := sim.rpcs[i].Call(nil, "miner_stop"); err != nil {
+			return fmt.Errorf("failed to stop miner of node %d: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// converge waits until all nodes agree on the chain head.
+func (sim *Sim) converge() (common.Hash, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), convergePeriods*sim.period)
+	defer cancel()
+
+	for {
+		head, err := sim.head(ctx)
+		if err == nil {
+			return head, nil
+		}
+		select {
+		case <-ctx.Done():
+			return common.Hash{}, err
+		case <-time.After(sim.period / 4):
+		}
+	}
+}
+
+// head returns the chain head all nodes agree on.
+func (sim *Sim) head(ctx context.Context) (common.Hash, error) {
+	var head common.Hash
+	for i, rpc := range sim.rpcs {
+		header, err := ethclient.NewClient(rpc).HeaderByNumber(ctx, nil)
+		if err != nil {
+			return common.Hash{}, err
+		}
+		if i == 0 {
+			head = header.Hash()
+		} else if header.Hash() != head {
+			return common.Hash{}, fmt.Errorf("node %d head mismatch: have %x, want %x", i, header.Hash(), head)
+		}
+	}
+	return head, nil
+}
+
+// result collects the reputation distribution at the given head.
+func (sim *Sim) result(ctx context.Context, head common.Hash) (*Result, error) {
+	client := ethclient.NewClient(sim.rpcs[0])
+
+	header, err := client.HeaderByHash(ctx, head)
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{
+		Head:       head,
+		Number:     header.Number.Uint64(),
+		Reputation: make(map[common.Address]uint64),
+		Blocks:     make(map[common.Address]int),
+		Withheld:   make(map[common.Address]int),
+		Adopted:    make(map[common.Address]int),
+	}
+	for _, miner := range sim.miners {
+		var reputation uint64
+		if err := sim.rpcs[0].CallContext(ctx, &reputation, "eth_getReputation", miner.Coinbase, hexutil.EncodeBig(header.Number)); err != nil {
+			return nil, err
+		}
+		result.Reputation[miner.Coinbase] = reputation
+	}
+	for n := uint64(1); n <= result.Number; n++ {
+		header, err := client.HeaderByNumber(ctx, new(big.Int).SetUint64(n))
+		if err != nil {
+			return nil, err
+		}
+		result.Blocks[header.Coinbase]++
+		if _, ok := sim.withheld[header.Hash()]; ok {
+			result.Adopted[header.Coinbase]++
+		}
+	}
+	for _, miner := range sim.withheld {
+		result.Withheld[miner]++
+	}
+	return result, nil
+}
+
+// close disconnects the RPC clients of the nodes.
+func (sim *Sim) close() {
+	for _, rpc := range sim.rpcs {
+		rpc.Close()
+	}
+}
+
+// pair returns the key of a connection between two nodes.
+func pair(one, other int) [2]int {
+	if one > other {
+		one, other = other, one
+	}
+	return [2]int{one, other}
+}
@@ -0,0 +1,68 @@
+// Copyright 2026 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethsim
+
+import (
+	"flag"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/p2p/simulations"
+	"github.com/ethereum/go-ethereum/p2p/simulations/adapters"
+)
+
+// The scenarios mine on the wall clock, so they only run if requested. Blocks
+// have a timestamp resolution of a second, so shorter periods only cause ties.
+var runScenarios = flag.Bool("scenarios", false, "run the mining simulation scenarios")
+
+// testPeriod is the block period of the simulated network.
+const testPeriod = time.Second
+
+// Tests that the predefined scenarios converge to the expected reputation
+// distribution when run through the simulations HTTP API.
+func TestScenarios(t *testing.T) {
+	if !*runScenarios {
+		t.Skip("skipping mining simulations, enable with -scenarios")
+	}
+	adapter := adapters.NewSimAdapter(adapters.Services{
+		ServiceName: NewServiceFunc(Genesis(), testPeriod),
+	})
+	network := simulations.NewNetwork(adapter, &simulations.NetworkConfig{DefaultService: ServiceName})
+	defer network.Shutdown()
+
+	server := httptest.NewServer(simulations.NewServer(network))
+	defer server.Close()
+
+	// Run the scenarios in parallel within a group, so the network outlives them
+	t.Run("group", func(t *testing.T) {
+		for name, scenario := range Scenarios {
+			scenario := scenario
+			t.Run(name, func(t *testing.T) {
+				t.Parallel()
+
+				result, err := scenario.Run(simulations.NewClient(server.URL))
+				if err != nil {
+					t.Fatalf("scenario failed: %v", err)
+				}
+				for _, miner := range scenario.Miners {
+					t.Logf("miner %x: reputation %d, blocks %d, withheld %d", miner.Coinbase, result.Reputation[miner.Coinbase], result.Blocks[miner.Coinbase], result.Withheld[miner.Coinbase])
+				}
+			})
+		}
+	})
+}
@@ -0,0 +1,203 @@
+// Copyright 2026 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethsim
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+)
+
+// Scenarios are the predefined scenarios, keyed by name.
+var Scenarios = map[string]*Scenario{
+	"partition": {
+		Name:        "partition",
+		Description: "Honest miners are split into two partitions that later heal",
+		Miners:      honest(4),
+		Steps: []Step{
+			Connect(),
+			Mine(),
+			Wait(2),
+			Partition([]int{0, 1}, []int{2, 3}),
+			Wait(5),
+			Heal(),
+			Wait(10),
+		},
+		Check: checkPartition,
+	},
+	"selfish": {
+		Name:        "selfish",
+		Description: "A strong miner withholds a private fork and releases it to compete with the honest lead",
+		Miners: append(honest(3),
+			Miner{Coinbase: HonestMiners[3], Power: 2},
+		),
+		Steps: []Step{
+			Connect(),
+			Mine(),
+			Wait(2),
+			Withhold(3),
+			Wait(5),
+			Release(3),
+			StopMining(3),
+			Wait(5),
+		},
+		Check: checkSelfish,
+	},
+	"collusion": {
+		Name:        "collusion",
+		Description: "Colluding low reputation miners build a fork apart from the honest miners",
+		Miners: append(honest(3),
+			Miner{Coinbase: LowReputationMiners[0], Power: 1},
+			Miner{Coinbase: LowReputationMiners[1], Power: 1},
+		),
+		Steps: []Step{
+			Connect([]int{0, 1, 2}, []int{3, 4}),
+			Mine(),
+			Wait(10),
+			StopMining(3, 4),
+			Heal(),
+			Wait(3),
+		},
+		Check: checkCollusion,
+	},
+}
+
+// honest returns n honest miners with unit hash power.
+func honest(n int) []Miner {
+	miners := make([]Miner, n)
+	for i := range miners {
+		miners[i] = Miner{Coinbase: HonestMiners[i], Power: 1}
+	}
+	return miners
+}
+
+// Connect returns a step connecting the nodes within each group, or all nodes
+// if no groups are given.
+func Connect(groups ...[]int) Step {
+	return func(sim *Sim) error { return sim.Connect(groups...) }
+}
+
+// Partition returns a step disconnecting the nodes of different groups.
+func Partition(groups ...[]int) Step {
+	return func(sim *Sim) error { return sim.Partition(groups...) }
+}
+
+// Heal returns a step connecting all nodes to each other.
+func Heal() Step {
+	return Connect()
+}
+
+// Mine returns a step starting the miners of the given nodes, or of all nodes
+// if none are given.
+func Mine(nodes ...int) Step {
+	return func(sim *Sim) error { return sim.StartMining(nodes...) }
+}
+
+// StopMining returns a step stopping the miners of the given nodes, or of all
+// nodes if none are given.
+func StopMining(nodes ...int) Step {
+	return func(sim *Sim) error { return sim.StopMining(nodes...) }
+}
+
+// Withhold returns a step disconnecting the node from all others, so that its
+// miner builds a private fork.
+func Withhold(node int) Step {
+	return func(sim *Sim) error { return sim.Withhold(node) }
+}
+
+// Release returns a step publishing the private fork of a withholding node once
+// it is about to be overtaken by the honest lead.
+func Release(node int) Step {
+	return func(sim *Sim) error { return sim.Release(node) }
+}
+
+// Wait returns a step letting the network run for the given number of block
+// periods.
+func Wait(periods int) Step {
+	return func(sim *Sim) error {
+		sim.Wait(periods)
+		return nil
+	}
+}
+
+// checkBounds checks that the reputation of the given miners is within bounds.
+func checkBounds(result *Result, miners ...common.Address) error {
+	for _, miner := range miners {
+		if rep := result.Reputation[miner]; rep > ethash.ReputationHighThreshold {
+			return fmt.Errorf("miner %x reputation above threshold: have %d, want <= %d", miner, rep, ethash.ReputationHighThreshold)
+		}
+	}
+	return nil
+}
+
+// checkPartition checks that both partitions contributed to the healed chain.
+func checkPartition(result *Result) error {
+	if err := checkBounds(result, HonestMiners[:4]...); err != nil {
+		return err
+	}
+	left := result.Blocks[HonestMiners[0]] + result.Blocks[HonestMiners[1]]
+	right := result.Blocks[HonestMiners[2]] + result.Blocks[HonestMiners[3]]
+	if left == 0 || right == 0 {
+		return fmt.Errorf("partition missing from healed chain: have %d and %d blocks", left, right)
+	}
+	return nil
+}
+
+// checkSelfish checks that none of the withheld blocks made it into the chain,
+// leaving the selfish miner behind the honest ones once its reputation decayed.
+func checkSelfish(result *Result) error {
+	if err := checkBounds(result, HonestMiners[:4]...); err != nil {
+		return err
+	}
+	selfish := HonestMiners[3]
+	if blocks := result.Adopted[selfish]; blocks != 0 {
+		return fmt.Errorf("withheld blocks adopted: have %d of %d, want 0", blocks, result.Withheld[selfish])
+	}
+	if result.Number < uint64(ethash.ReputationBlackBlockCount) {
+		return nil
+	}
+	rep := result.Reputation[selfish]
+	for _, miner := range HonestMiners[:3] {
+		if result.Reputation[miner] <= rep {
+			return fmt.Errorf("honest miner %x reputation not above selfish: have %d, selfish %d", miner, result.Reputation[miner], rep)
+		}
+	}
+	return nil
+}
+
+// checkCollusion checks that the colluders' fork was discarded without gaining
+// them any reputation, while the honest miners kept theirs.
+func checkCollusion(result *Result) error {
+	if err := checkBounds(result, HonestMiners[:3]...); err != nil {
+		return err
+	}
+	for _, miner := range LowReputationMiners[:2] {
+		if blocks := result.Blocks[miner]; blocks != 0 {
+			return fmt.Errorf("colluder %x blocks mismatch: have %d, want 0", miner, blocks)
+		}
+		if rep := result.Reputation[miner]; rep != LowReputation {
+			return fmt.Errorf("colluder %x reputation mismatch: have %d, want %d", miner, rep, LowReputation)
+		}
+	}
+	for _, miner := range HonestMiners[:3] {
+		if rep := result.Reputation[miner]; rep < ethash.ReputationInit*9/10 {
+			return fmt.Errorf("honest miner %x reputation too low: have %d, want >= %d", miner, rep, ethash.ReputationInit*9/10)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+// Copyright 2026 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package ethsim runs eth nodes in p2p simulation networks, mining with a fake
+// proof-of-work weighted by the miners' reputation.
+package ethsim
+
+import (
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/eth/downloader"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/p2p/simulations/adapters"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// ServiceName is the name of the simulated eth service.
+	ServiceName = "eth"
+
+	// DefaultPeriod is the average time it takes a miner with unit hash power and
+	// the initial reputation to seal a block.
+	DefaultPeriod = 3 * time.Second
+
+	// LowReputation is the genesis reputation of the low reputation miners.
+	LowReputation = 100
+)
+
+var (
+	// HonestMiners are the coinbases of miners starting with the initial
+	// reputation. They are also the miners subject to reputation decay.
+	HonestMiners = ethash.MinersListTest
+
+	// LowReputationMiners are the coinbases of miners starting with a tenth of
+	// the initial reputation.
+	LowReputationMiners = []common.Address{
+		common.HexToAddress("0000000000000000000000000000000000000006"),
+		common.HexToAddress("0000000000000000000000000000000000000007"),
+		common.HexToAddress("0000000000000000000000000000000000000008"),
+	}
+)
+
+var errNegativePower = errors.New("negative hash power")
+
+// Genesis returns the genesis block of simulated mining networks, allocating the
+// reputation of the honest and low reputation miners.
+func Genesis() *core.Genesis {
+	alloc := make(core.GenesisAlloc)
+	for _, miner := range HonestMiners {
+		alloc[miner] = core.GenesisAccount{Balance: new(big.Int), Reputation: ethash.ReputationInit}
+	}
+	for _, miner := range LowReputationMiners {
+		alloc[miner] = core.GenesisAccount{Balance: new(big.Int), Reputation: LowReputation}
+	}
+	return &core.Genesis{
+		Config:     params.ReputationnetChainConfig,
+		GasLimit:   params.GenesisGasLimit,
+		Difficulty: params.MinimumDifficulty,
+		Alloc:      alloc,
+	}
+}
+
+// Service is a full eth node mining with the simulated proof-of-work.
+type Service struct {
+	*eth.Ethereum
+	engine *engine
+}
+
+// NewServiceFunc returns a service initializer running eth nodes on the given
+// genesis block, sealing blocks every period on average with unit hash power.
+func NewServiceFunc(genesis *core.Genesis, period time.Duration) adapters.ServiceFunc {
+	return func(ctx *adapters.ServiceContext) (node.Service, error) {
+		engine := newEngine(period)
+
+		config := eth.DefaultConfig
+		config.Genesis = genesis
+		config.NetworkId = genesis.Config.ChainID.Uint64()
+		config.SyncMode = downloader.FullSync
+		config.TxPool.Journal = ""
+		config.Engine = engine
+
+		ethereum, err := eth.New(ctx.NodeContext, &config)
+		if err != nil {
+			return nil, err
+		}
+		return &Service{Ethereum: ethereum, engine: engine}, nil
+	}
+}
+
+// APIs implements node.Service, extending the eth APIs with the simulation
+// controls.
+func (s *Service) APIs() []rpc.API {
+	return append(s.Ethereum.APIs(), rpc.API{
+		Namespace: "ethsim",
+		Version:   "1.0",
+		Service:   &PrivateSimAPI{engine: s.engine},
+		Public:    false,
+	})
+}
+
+// PrivateSimAPI provides an API to control the simulated mining of a node.
+type PrivateSimAPI struct {
+	engine *engine
+}
+
+// SetPower sets the hash power of the node's miner relative to unit power. A
+// miner without power never seals a block.
+func (api *PrivateSimAPI) SetPower(power float64) error {
+	if power < 0 {
+		return errNegativePower
+	}
+	api.engine.setPower(power)
+	return nil
+}
+
+// Period returns the average time it takes the node's miner to seal a block
+// with unit power and the initial reputation.
+func (api *PrivateSimAPI) Period() time.Duration {
+	return api.engine.period
+}
@@ -40,4 +40,14 @@ var (
 	headerFilterOutMeter = metrics.NewRegisteredMeter("eth/fetcher/filter/headers/out", nil)
 	bodyFilterInMeter    = metrics.NewRegisteredMeter("eth/fetcher/filter/bodies/in", nil)
 	bodyFilterOutMeter   = metrics.NewRegisteredMeter("eth/fetcher/filter/bodies/out", nil)
+
+	txAnnounceInMeter    = metrics.NewRegisteredMeter("eth/fetcher/transaction/announces/in", nil)
+	txAnnounceKnownMeter = metrics.NewRegisteredMeter("eth/fetcher/transaction/announces/known", nil)
+	txAnnounceDOSMeter   = metrics.NewRegisteredMeter("eth/fetcher/transaction/announces/dos", nil)
+
+	txBroadcastInMeter      = metrics.NewRegisteredMeter("eth/fetcher/transaction/broadcasts/in", nil)
+	txReplyInMeter          = metrics.NewRegisteredMeter("eth/fetcher/transaction/replies/in", nil)
+	txReplyUnrequestedMeter = metrics.NewRegisteredMeter("eth/fetcher/transaction/replies/unrequested", nil)
+	txRequestOutMeter       = metrics.NewRegisteredMeter("eth/fetcher/transaction/request/out", nil)
+	txRequestTimeoutMeter   = metrics.NewRegisteredMeter("eth/fetcher/transaction/request/timeout", nil)
 )
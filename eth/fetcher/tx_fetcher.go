@@ -0,0 +1,403 @@
+// Copyright 2026 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package fetcher
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// MaxTransactionFetch is the maximum number of transactions that can be
+	// requested from, or served to, a peer in a single retrieval.
+	MaxTransactionFetch = 256
+
+	// MaxTransactionAnnounce is the maximum number of unfetched transactions a
+	// peer may have announced. Announcements beyond it are ignored, so senders
+	// should not announce more transactions in a single message.
+	MaxTransactionAnnounce = 4096
+
+	txFetchTimeout = 5 * time.Second // Maximum allotted time to return an explicitly requested transaction
+)
+
+// ErrUnrequestedTxs is returned if a peer replies with transactions that were not
+// requested from it. Such replies are not imported.
+var ErrUnrequestedTxs = errors.New("unrequested transactions")
+
+// txPoolHasFn is a callback type for checking whether a transaction is already
+// known to the local pool.
+type txPoolHasFn func(common.Hash) bool
+
+// txPoolAddFn is a callback type for inserting a batch of transactions into the
+// local pool.
+type txPoolAddFn func([]*types.Transaction) []error
+
+// txRequesterFn is a callback type for sending a transaction retrieval request.
+type txRequesterFn func(peer string, hashes []common.Hash) error
+
+// txAnnounce is the hash notification of the availability of a batch of new
+// transactions at a remote peer.
+type txAnnounce struct {
+	origin string        // Identifier of the peer originating the notification
+	hashes []common.Hash // Hashes of the transactions being announced
+}
+
+// txDelivery is the notification that a batch of transactions arrived, either
+// as a reply to a retrieval request or as a direct broadcast.
+type txDelivery struct {
+	origin string        // Identifier of the peer delivering the transactions
+	hashes []common.Hash // Hashes of the delivered transactions
+	reply  bool          // Whether the delivery is a reply to a retrieval request
+}
+
+// txReplyCheck is a query whether a batch of transactions was requested from the
+// peer delivering it.
+type txReplyCheck struct {
+	origin string        // Identifier of the peer delivering the transactions
+	hashes []common.Hash // Hashes of the delivered transactions
+	result chan bool     // Channel to report whether all transactions were requested
+}
+
+// txRequest is an in-flight transaction retrieval request.
+type txRequest struct {
+	hashes []common.Hash  // Transactions requested from the peer
+	time   mclock.AbsTime // Timestamp of the request
+}
+
+// covers checks whether all the given transactions are part of the request.
+func (req *txRequest) covers(hashes []common.Hash) bool {
+	if req == nil {
+		return false
+	}
+	wanted := make(map[common.Hash]struct{}, len(req.hashes))
+	for _, hash := range req.hashes {
+		wanted[hash] = struct{}{}
+	}
+	for _, hash := range hashes {
+		if _, ok := wanted[hash]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// TxFetcher is responsible for retrieving new transactions based on hash
+// announcements. Every announced transaction is retrieved only once, from one
+// of the peers announcing it, falling back to the others if the retrieval
+// fails or times out.
+type TxFetcher struct {
+	// Various event channels
+	notify  chan *txAnnounce
+	check   chan *txReplyCheck
+	cleanup chan *txDelivery
+	drop    chan string
+	quit    chan struct{}
+
+	// Announce states
+	announces map[string]map[common.Hash]struct{} // Unfetched transactions announced by each peer
+	announced map[common.Hash]map[string]struct{} // Peers announcing each unfetched transaction
+	fetching  map[common.Hash]string              // Transactions being retrieved, with the peer asked
+	requests  map[string]*txRequest               // In-flight retrieval request of each peer
+	timeouts  map[string]*txRequest               // Timed out request of each peer, still accepting late replies
+
+	// Callbacks
+	hasTx    txPoolHasFn   // Checks whether a transaction is already in the pool
+	addTxs   txPoolAddFn   // Inserts a batch of transactions into the pool
+	fetchTxs txRequesterFn // Requests a batch of transactions from a peer
+	clock    mclock.Clock  // Time source for request timeouts
+}
+
+// NewTxFetcher creates a transaction fetcher to retrieve transactions based on
+// hash announcements.
+func NewTxFetcher(hasTx txPoolHasFn, addTxs txPoolAddFn, fetchTxs txRequesterFn) *TxFetcher {
+	return NewTxFetcherForTests(hasTx, addTxs, fetchTxs, mclock.System{})
+}
+
+// NewTxFetcherForTests is a testing method to create a transaction fetcher with
+// a custom clock.
+func NewTxFetcherForTests(hasTx txPoolHasFn, addTxs txPoolAddFn, fetchTxs txRequesterFn, clock mclock.Clock) *TxFetcher {
+	return &TxFetcher{
+		notify:    make(chan *txAnnounce),
+		check:     make(chan *txReplyCheck),
+		cleanup:   make(chan *txDelivery),
+		drop:      make(chan string),
+		quit:      make(chan struct{}),
+		announces: make(map[string]map[common.Hash]struct{}),
+		announced: make(map[common.Hash]map[string]struct{}),
+		fetching:  make(map[common.Hash]string),
+		requests:  make(map[string]*txRequest),
+		timeouts:  make(map[string]*txRequest),
+		hasTx:     hasTx,
+		addTxs:    addTxs,
+		fetchTxs:  fetchTxs,
+		clock:     clock,
+	}
+}
+
+// Start boots up the announcement based transaction retrieval, processing hash
+// notifications and deliveries until termination requested.
+func (f *TxFetcher) Start() {
+	go f.loop()
+}
+
+// Stop terminates the announcement based transaction retrieval, canceling all
+// pending operations.
+func (f *TxFetcher) Stop() {
+	close(f.quit)
+}
+
+// Notify announces the fetcher of the potential availability of a batch of new
+// transactions in the network.
+func (f *TxFetcher) Notify(peer string, hashes []common.Hash) error {
+	txAnnounceInMeter.Mark(int64(len(hashes)))
+
+	// Skip any transactions already known to the pool
+	unknown := make([]common.Hash, 0, len(hashes))
+	for _, hash := range hashes {
+		if !f.hasTx(hash) {
+			unknown = append(unknown, hash)
+		}
+	}
+	txAnnounceKnownMeter.Mark(int64(len(hashes) - len(unknown)))
+	if len(unknown) == 0 {
+		return nil
+	}
+	select {
+	case f.notify <- &txAnnounce{origin: peer, hashes: unknown}:
+		return nil
+	case <-f.quit:
+		return errTerminated
+	}
+}
+
+// Enqueue imports a batch of received transactions into the pool, returning the
+// pool errors of the individual transactions. The transactions are marked as
+// retrieved, and if they are a reply to a retrieval request, the request of the
+// peer is completed.
+//
+// Replies are only imported if all their transactions were requested from the
+// peer by its in-flight request, otherwise ErrUnrequestedTxs is returned.
+func (f *TxFetcher) Enqueue(peer string, txs []*types.Transaction, reply bool) ([]error, error) {
+	hashes := make([]common.Hash, len(txs))
+	for i, tx := range txs {
+		hashes[i] = tx.Hash()
+	}
+	if reply {
+		check := &txReplyCheck{origin: peer, hashes: hashes, result: make(chan bool, 1)}
+		select {
+		case f.check <- check:
+		case <-f.quit:
+			return nil, errTerminated
+		}
+		if !<-check.result {
+			txReplyUnrequestedMeter.Mark(int64(len(txs)))
+			return nil, ErrUnrequestedTxs
+		}
+		txReplyInMeter.Mark(int64(len(txs)))
+	} else {
+		txBroadcastInMeter.Mark(int64(len(txs)))
+	}
+	errs := f.addTxs(txs)
+
+	select {
+	case f.cleanup <- &txDelivery{origin: peer, hashes: hashes, reply: reply}:
+	case <-f.quit:
+	}
+	return errs, nil
+}
+
+// Drop should be called when a peer disconnects. It forgets the transactions
+// announced by the peer and reschedules its in-flight request to other peers.
+func (f *TxFetcher) Drop(peer string) error {
+	select {
+	case f.drop <- peer:
+		return nil
+	case <-f.quit:
+		return errTerminated
+	}
+}
+
+// loop is the main fetcher loop, scheduling retrievals based on the processed
+// notification events.
+func (f *TxFetcher) loop() {
+	var timeout <-chan time.Time
+
+	for {
+		select {
+		case <-f.quit:
+			return
+
+		case ann := <-f.notify:
+			// Transactions were announced, make sure the peer isn't DOSing us
+			announces := f.announces[ann.origin]
+			if announces == nil {
+				announces = make(map[common.Hash]struct{})
+				f.announces[ann.origin] = announces
+			}
+			for i, hash := range ann.hashes {
+				if len(announces) >= MaxTransactionAnnounce {
+					log.Debug("Peer exceeded unfetched transaction announces", "peer", ann.origin, "limit", MaxTransactionAnnounce)
+					txAnnounceDOSMeter.Mark(int64(len(ann.hashes) - i))
+					break
+				}
+				announces[hash] = struct{}{}
+				if f.announced[hash] == nil {
+					f.announced[hash] = make(map[string]struct{})
+				}
+				f.announced[hash][ann.origin] = struct{}{}
+			}
+
+		case check := <-f.check:
+			// Transactions were delivered as a reply, make sure they were requested
+			check.result <- f.requested(check.origin, check.hashes)
+
+		case delivery := <-f.cleanup:
+			// Transactions arrived, stop tracking them and complete any request
+			for _, hash := range delivery.hashes {
+				f.forgetHash(hash)
+			}
+			if delivery.reply {
+				if f.requests[delivery.origin].covers(delivery.hashes) {
+					f.forgetRequest(delivery.origin)
+				} else {
+					delete(f.timeouts, delivery.origin)
+				}
+			}
+
+		case peer := <-f.drop:
+			// Peer disconnected, forget its announces and reschedule its request
+			for hash := range f.announces[peer] {
+				f.forgetAnnounce(peer, hash)
+			}
+			f.forgetRequest(peer)
+			delete(f.timeouts, peer)
+
+		case <-timeout:
+			// Some requests might have timed out, release their transactions to
+			// be retrieved from other peers
+			timeout = nil
+
+			now := f.clock.Now()
+			for peer, req := range f.requests {
+				if time.Duration(now-req.time) >= txFetchTimeout {
+					log.Debug("Transaction retrieval timed out", "peer", peer, "count", len(req.hashes))
+					txRequestTimeoutMeter.Mark(int64(len(req.hashes)))
+					f.forgetRequest(peer)
+					f.timeouts[peer] = req
+				}
+			}
+		}
+		f.schedule()
+
+		// Make sure the oldest in-flight request is checked for timeout
+		if timeout == nil && len(f.requests) > 0 {
+			oldest := f.clock.Now()
+			for _, req := range f.requests {
+				if req.time < oldest {
+					oldest = req.time
+				}
+			}
+			timeout = f.clock.After(txFetchTimeout - time.Duration(f.clock.Now()-oldest))
+		}
+	}
+}
+
+// schedule requests the announced transactions not yet being retrieved from
+// the idle peers announcing them.
+func (f *TxFetcher) schedule() {
+	for peer, announces := range f.announces {
+		if _, ok := f.requests[peer]; ok {
+			continue
+		}
+		var hashes []common.Hash
+		for hash := range announces {
+			if _, ok := f.fetching[hash]; ok {
+				continue
+			}
+			hashes = append(hashes, hash)
+			if len(hashes) == MaxTransactionFetch {
+				break
+			}
+		}
+		if len(hashes) == 0 {
+			continue
+		}
+		for _, hash := range hashes {
+			f.forgetAnnounce(peer, hash)
+			f.fetching[hash] = peer
+		}
+		f.requests[peer] = &txRequest{hashes: hashes, time: f.clock.Now()}
+		txRequestOutMeter.Mark(int64(len(hashes)))
+
+		go func(peer string, hashes []common.Hash) {
+			if err := f.fetchTxs(peer, hashes); err != nil {
+				log.Debug("Transaction retrieval failed", "peer", peer, "err", err)
+			}
+		}(peer, hashes)
+	}
+}
+
+// requested checks whether all the given transactions are part of the in-flight
+// request of the peer, or of its last timed out one.
+func (f *TxFetcher) requested(peer string, hashes []common.Hash) bool {
+	return f.requests[peer].covers(hashes) || f.timeouts[peer].covers(hashes)
+}
+
+// forgetAnnounce removes the announcement of a transaction by a peer.
+func (f *TxFetcher) forgetAnnounce(peer string, hash common.Hash) {
+	if announces := f.announces[peer]; announces != nil {
+		delete(announces, hash)
+		if len(announces) == 0 {
+			delete(f.announces, peer)
+		}
+	}
+	if announcers := f.announced[hash]; announcers != nil {
+		delete(announcers, peer)
+		if len(announcers) == 0 {
+			delete(f.announced, hash)
+		}
+	}
+}
+
+// forgetHash removes all traces of a transaction from the fetcher's internal
+// state.
+func (f *TxFetcher) forgetHash(hash common.Hash) {
+	for peer := range f.announced[hash] {
+		f.forgetAnnounce(peer, hash)
+	}
+	delete(f.fetching, hash)
+}
+
+// forgetRequest completes the in-flight request of a peer, releasing the
+// transactions not delivered to be retrieved from other announcers.
+func (f *TxFetcher) forgetRequest(peer string) {
+	req := f.requests[peer]
+	if req == nil {
+		return
+	}
+	for _, hash := range req.hashes {
+		if f.fetching[hash] == peer {
+			delete(f.fetching, hash)
+		}
+	}
+	delete(f.requests, peer)
+}
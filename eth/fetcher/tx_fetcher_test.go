@@ -0,0 +1,291 @@
+// Copyright 2026 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package fetcher
+
+import (
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// txFetcherTester is a test simulator for mocking out the local transaction pool
+// and the remote peers serving transaction retrievals.
+type txFetcherTester struct {
+	fetcher *TxFetcher
+	clock   *mclock.Simulated
+
+	pool     map[common.Hash]*types.Transaction // Transactions imported into the pool
+	requests chan *txFetchRequest               // Retrieval requests sent to the peers
+	lock     sync.RWMutex
+}
+
+// txFetchRequest is a retrieval request issued by the fetcher.
+type txFetchRequest struct {
+	peer   string
+	hashes []common.Hash
+}
+
+// newTxFetcherTester creates a new transaction fetcher test mocker.
+func newTxFetcherTester() *txFetcherTester {
+	tester := &txFetcherTester{
+		clock:    new(mclock.Simulated),
+		pool:     make(map[common.Hash]*types.Transaction),
+		requests: make(chan *txFetchRequest, 64),
+	}
+	tester.fetcher = NewTxFetcherForTests(tester.hasTx, tester.addTxs, tester.fetchTxs, tester.clock)
+	tester.fetcher.Start()
+
+	return tester
+}
+
+// hasTx checks whether a transaction is already imported into the pool.
+func (f *txFetcherTester) hasTx(hash common.Hash) bool {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	_, ok := f.pool[hash]
+	return ok
+}
+
+// addTxs imports a batch of transactions into the pool.
+func (f *txFetcherTester) addTxs(txs []*types.Transaction) []error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	for _, tx := range txs {
+		f.pool[tx.Hash()] = tx
+	}
+	return make([]error, len(txs))
+}
+
+// fetchTxs records a retrieval request sent to a peer.
+func (f *txFetcherTester) fetchTxs(peer string, hashes []common.Hash) error {
+	f.requests <- &txFetchRequest{peer: peer, hashes: hashes}
+	return nil
+}
+
+// expectRequest waits for a retrieval request and checks its contents.
+func (f *txFetcherTester) expectRequest(t *testing.T, peer string, hashes []common.Hash) {
+	t.Helper()
+
+	select {
+	case req := <-f.requests:
+		if req.peer != peer {
+			t.Fatalf("request peer mismatch: have %s, want %s", req.peer, peer)
+		}
+		have, want := sortHashes(req.hashes), sortHashes(hashes)
+		if len(have) != len(want) {
+			t.Fatalf("request length mismatch: have %d, want %d", len(have), len(want))
+		}
+		for i := range have {
+			if have[i] != want[i] {
+				t.Fatalf("request hash %d mismatch: have %x, want %x", i, have[i], want[i])
+			}
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("retrieval request from %s timed out", peer)
+	}
+}
+
+// expectNoRequest checks that no retrieval request is sent.
+func (f *txFetcherTester) expectNoRequest(t *testing.T) {
+	t.Helper()
+
+	select {
+	case req := <-f.requests:
+		t.Fatalf("unexpected request to %s: %d hashes", req.peer, len(req.hashes))
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// sortHashes returns a sorted copy of a hash list.
+func sortHashes(hashes []common.Hash) []common.Hash {
+	sorted := append([]common.Hash{}, hashes...)
+	sort.Sort(hashSorter(sorted))
+	return sorted
+}
+
+type hashSorter []common.Hash
+
+func (s hashSorter) Len() int           { return len(s) }
+func (s hashSorter) Less(i, j int) bool { return s[i].Big().Cmp(s[j].Big()) < 0 }
+func (s hashSorter) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// makeTxs creates a batch of distinct transactions and their hashes.
+func makeTxs(n int) ([]*types.Transaction, []common.Hash) {
+	txs := make([]*types.Transaction, n)
+	hashes := make([]common.Hash, n)
+	for i := range txs {
+		txs[i] = types.NewTransaction(uint64(i), common.Address{}, nil, 0, nil, nil)
+		hashes[i] = txs[i].Hash()
+	}
+	return txs, hashes
+}
+
+// Tests that announced transactions are requested from the announcing peer and
+// imported into the pool on reply.
+func TestTxFetcherRetrieval(t *testing.T) {
+	tester := newTxFetcherTester()
+	defer tester.fetcher.Stop()
+
+	txs, hashes := makeTxs(2)
+	tester.fetcher.Notify("A", hashes)
+	tester.expectRequest(t, "A", hashes)
+
+	tester.fetcher.Enqueue("A", txs, true)
+	for _, hash := range hashes {
+		if !tester.hasTx(hash) {
+			t.Fatalf("transaction %x not imported", hash)
+		}
+	}
+	// Known transactions must not be requested again
+	tester.fetcher.Notify("B", hashes)
+	tester.expectNoRequest(t)
+}
+
+// Tests that a transaction announced by multiple peers is only requested once,
+// and that it is requested from another announcer if the retrieval times out.
+func TestTxFetcherTimeout(t *testing.T) {
+	tester := newTxFetcherTester()
+	defer tester.fetcher.Stop()
+
+	txs, hashes := makeTxs(1)
+	tester.fetcher.Notify("A", hashes)
+	tester.expectRequest(t, "A", hashes)
+
+	tester.fetcher.Notify("B", hashes)
+	tester.expectNoRequest(t)
+
+	tester.clock.WaitForTimers(1)
+	tester.clock.Run(txFetchTimeout)
+	tester.expectRequest(t, "B", hashes)
+
+	// A late reply from the timed out peer still delivers the transaction
+	tester.fetcher.Enqueue("A", txs, true)
+	if !tester.hasTx(hashes[0]) {
+		t.Fatalf("transaction %x not imported", hashes[0])
+	}
+}
+
+// Tests that transactions not included in a reply are requested from another
+// announcer, and that the replying peer is free to serve new requests.
+func TestTxFetcherPartialReply(t *testing.T) {
+	tester := newTxFetcherTester()
+	defer tester.fetcher.Stop()
+
+	txs, hashes := makeTxs(3)
+	tester.fetcher.Notify("A", hashes[:2])
+	tester.expectRequest(t, "A", hashes[:2])
+	tester.fetcher.Notify("B", hashes[:2])
+
+	tester.fetcher.Enqueue("A", txs[:1], true)
+	tester.expectRequest(t, "B", hashes[1:2])
+
+	tester.fetcher.Notify("A", hashes[2:])
+	tester.expectRequest(t, "A", hashes[2:])
+}
+
+// Tests that the in-flight request of a dropped peer is rescheduled to another
+// announcer, and that the dropped peer's announcements are forgotten.
+func TestTxFetcherDrop(t *testing.T) {
+	tester := newTxFetcherTester()
+	defer tester.fetcher.Stop()
+
+	_, hashes := makeTxs(2)
+	tester.fetcher.Notify("A", hashes[:1])
+	tester.expectRequest(t, "A", hashes[:1])
+	tester.fetcher.Notify("B", hashes[:1])
+	tester.fetcher.Notify("A", hashes[1:])
+
+	tester.fetcher.Drop("A")
+	tester.expectRequest(t, "B", hashes[:1])
+	tester.expectNoRequest(t)
+}
+
+// Tests that replies with transactions that were never requested from the peer
+// are rejected without being imported.
+func TestTxFetcherUnrequestedReply(t *testing.T) {
+	tester := newTxFetcherTester()
+	defer tester.fetcher.Stop()
+
+	txs, hashes := makeTxs(2)
+
+	// Replies without any request are rejected
+	if _, err := tester.fetcher.Enqueue("A", txs[:1], true); err != ErrUnrequestedTxs {
+		t.Fatalf("unrequested reply error mismatch: have %v, want %v", err, ErrUnrequestedTxs)
+	}
+	// Replies with transactions requested from another peer are rejected
+	tester.fetcher.Notify("B", hashes[1:])
+	tester.expectRequest(t, "B", hashes[1:])
+	tester.fetcher.Notify("A", hashes[:1])
+	tester.expectRequest(t, "A", hashes[:1])
+
+	if _, err := tester.fetcher.Enqueue("A", txs, true); err != ErrUnrequestedTxs {
+		t.Fatalf("unrequested reply error mismatch: have %v, want %v", err, ErrUnrequestedTxs)
+	}
+	for _, hash := range hashes {
+		if tester.hasTx(hash) {
+			t.Fatalf("unrequested transaction %x imported", hash)
+		}
+	}
+	// Broadcasts don't need to be requested
+	if _, err := tester.fetcher.Enqueue("C", txs[1:], false); err != nil {
+		t.Fatalf("failed to import broadcast: %v", err)
+	}
+	if !tester.hasTx(hashes[1]) {
+		t.Fatalf("broadcast transaction %x not imported", hashes[1])
+	}
+}
+
+// Tests that a peer cannot make the fetcher track more than the allowed number
+// of unfetched announcements.
+func TestTxFetcherAnnounceLimit(t *testing.T) {
+	tester := newTxFetcherTester()
+	defer tester.fetcher.Stop()
+
+	_, hashes := makeTxs(MaxTransactionAnnounce + 16)
+	tester.fetcher.Notify("A", hashes)
+
+	requested := make(map[common.Hash]bool)
+	for len(requested) < MaxTransactionAnnounce {
+		select {
+		case req := <-tester.requests:
+			if len(req.hashes) > MaxTransactionFetch {
+				t.Fatalf("request size mismatch: have %d, want <= %d", len(req.hashes), MaxTransactionFetch)
+			}
+			for _, hash := range req.hashes {
+				requested[hash] = true
+			}
+			if _, err := tester.fetcher.Enqueue("A", nil, true); err != nil {
+				t.Fatalf("failed to deliver reply: %v", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("retrieval request timed out: have %d hashes, want %d", len(requested), MaxTransactionAnnounce)
+		}
+	}
+	tester.expectNoRequest(t)
+	for _, hash := range hashes[MaxTransactionAnnounce:] {
+		if requested[hash] {
+			t.Fatalf("announcement %x above limit requested", hash)
+		}
+	}
+}
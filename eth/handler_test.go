@@ -36,6 +36,7 @@ import (
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
 )
 
 // Tests that protocol versions and modes of operations are matched up properly.
@@ -46,8 +47,8 @@ func TestProtocolCompatibility(t *testing.T) {
 		mode       downloader.SyncMode
 		compatible bool
 	}{
-		{61, downloader.FullSync, true}, {62, downloader.FullSync, true}, {63, downloader.FullSync, true},
-		{61, downloader.FastSync, false}, {62, downloader.FastSync, false}, {63, downloader.FastSync, true},
+		{61, downloader.FullSync, true}, {62, downloader.FullSync, true}, {63, downloader.FullSync, true}, {65, downloader.FullSync, true},
+		{61, downloader.FastSync, false}, {62, downloader.FastSync, false}, {63, downloader.FastSync, true}, {65, downloader.FastSync, true},
 	}
 	// Make sure anything we screw up is restored
 	backup := ProtocolVersions
@@ -611,3 +612,124 @@ outer:
 		t.Errorf("block broadcast to %d peers, expected %d", receivedCount, broadcastExpected)
 	}
 }
+
+// Tests that announcing transactions to eth/65 peers instead of pushing the full
+// transactions to all of them reduces the broadcast bandwidth, including the
+// traffic of the peers retrieving the announced transactions they miss.
+func TestBroadcastTransactionsBandwidth(t *testing.T) {
+	legacy := testBroadcastTransactionsTraffic(t, eth63)
+	announced := testBroadcastTransactionsTraffic(t, eth65)
+
+	if announced >= legacy {
+		t.Fatalf("broadcast traffic not reduced: have %d bytes, legacy %d bytes", announced, legacy)
+	}
+	t.Logf("broadcast traffic: eth/63 %d bytes, eth/65 %d bytes (%.1f%% saved)", legacy, announced, 100*float64(legacy-announced)/float64(legacy))
+}
+
+// broadcastTestPool is a transaction pool serving the retrieval of transactions
+// without reporting them as pending, so they are only propagated by explicit
+// broadcasts and not by the transaction sync of new peers.
+type broadcastTestPool struct {
+	*testTxPool
+}
+
+func (p *broadcastTestPool) Pending() (map[common.Address]types.Transactions, error) {
+	return nil, nil
+}
+
+// testBroadcastTransactionsTraffic broadcasts a batch of transactions to a set
+// of peers running the given protocol version, and returns the number of bytes
+// exchanged until all peers have all transactions. Every peer already received
+// three quarters of the transactions from elsewhere in the network, announced
+// transactions are only retrieved if missing.
+func testBroadcastTransactionsTraffic(t *testing.T, protocol int) int {
+	const (
+		totalPeers = 25
+		totalTxs   = 16
+	)
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	defer pm.Stop()
+
+	txs := make([]*types.Transaction, totalTxs)
+	for nonce := range txs {
+		txs[nonce] = newTestTransaction(testAccount, uint64(nonce), 1024)
+	}
+	pool := &broadcastTestPool{new(testTxPool)}
+	pool.AddRemotes(txs)
+	pm.txpool = pool
+
+	var peers []*testPeer
+	for i := 0; i < totalPeers; i++ {
+		peer, _ := newTestPeer(fmt.Sprintf("peer %d", i), protocol, pm, true)
+		defer peer.close()
+		peers = append(peers, peer)
+	}
+	pm.BroadcastTxs(txs)
+
+	// Drain the broadcasts from every peer, retrieving the missing announced
+	// transactions, until it has all transactions
+	traffic := make(chan int, totalPeers)
+	for i, peer := range peers {
+		go func(i int, p *testPeer) {
+			var (
+				have  = make(map[common.Hash]bool)
+				bytes int
+			)
+			for nonce, tx := range txs {
+				if (nonce+i)%4 != 0 {
+					have[tx.Hash()] = true
+				}
+			}
+			for len(have) < totalTxs {
+				msg, err := p.app.ReadMsg()
+				if err != nil {
+					t.Errorf("%v: read error: %v", p.Peer, err)
+					break
+				}
+				bytes += int(msg.Size)
+
+				switch msg.Code {
+				case TxMsg, PooledTransactionsMsg:
+					var txs []*types.Transaction
+					if err := msg.Decode(&txs); err != nil {
+						t.Errorf("%v: %v", p.Peer, err)
+					}
+					for _, tx := range txs {
+						have[tx.Hash()] = true
+					}
+				case NewPooledTransactionHashesMsg:
+					var hashes, missing []common.Hash
+					if err := msg.Decode(&hashes); err != nil {
+						t.Errorf("%v: %v", p.Peer, err)
+					}
+					for _, hash := range hashes {
+						if !have[hash] {
+							missing = append(missing, hash)
+						}
+					}
+					if len(missing) == 0 {
+						break
+					}
+					size, _, _ := rlp.EncodeToReader(missing)
+					bytes += size
+					if err := p2p.Send(p.app, GetPooledTransactionsMsg, missing); err != nil {
+						t.Errorf("%v: request error: %v", p.Peer, err)
+					}
+				default:
+					msg.Discard()
+				}
+			}
+			traffic <- bytes
+		}(i, peer)
+	}
+	var total int
+	for range peers {
+		select {
+		case bytes := <-traffic:
+			total += bytes
+		case <-time.After(2 * time.Second):
+			t.Fatalf("eth/%d: transaction broadcast timed out", protocol)
+		}
+	}
+	return total
+}
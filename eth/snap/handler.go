@@ -0,0 +1,332 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snap
+
+import (
+	"bytes"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/light"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+const (
+	// softResponseLimit is the maximum size of a response served, regardless of
+	// the limit requested by the remote peer.
+	softResponseLimit = 2 * 1024 * 1024
+
+	// maxCodeLookups is the maximum number of bytecodes to serve per request.
+	maxCodeLookups = 1024
+
+	// maxTrieNodeLookups is the maximum number of trie nodes to serve per request.
+	maxTrieNodeLookups = 1024
+)
+
+// MakeProtocols constructs the snap protocols, serving state from the given
+// trie database and delivering the responses of remote peers to the syncer.
+func MakeProtocols(triedb *trie.Database, syncer *Syncer) []p2p.Protocol {
+	protocols := make([]p2p.Protocol, len(ProtocolVersions))
+	for i, version := range ProtocolVersions {
+		version := version // Closure for the run
+		protocols[i] = p2p.Protocol{
+			Name:    ProtocolName,
+			Version: version,
+			Length:  ProtocolLengths[i],
+			Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+				return handle(triedb, syncer, newPeer(version, p, rw))
+			},
+		}
+	}
+	return protocols
+}
+
+// handle is the callback invoked to manage the life cycle of a snap peer. When
+// this function terminates, the peer is disconnected.
+func handle(triedb *trie.Database, syncer *Syncer, p *peer) error {
+	p.Log().Debug("Snapshot peer connected", "name", p.Name())
+
+	syncer.Register(p)
+	defer syncer.Unregister(p.id)
+
+	for {
+		if err := handleMsg(triedb, syncer, p); err != nil {
+			p.Log().Debug("Snapshot message handling failed", "err", err)
+			return err
+		}
+	}
+}
+
+// handleMsg is invoked whenever an inbound message is received from a remote
+// peer. The remote connection is torn down upon returning any error.
+func handleMsg(triedb *trie.Database, syncer *Syncer, p *peer) error {
+	// Read the next message from the remote peer, and ensure it's fully consumed
+	msg, err := p.rw.ReadMsg()
+	if err != nil {
+		return err
+	}
+	if msg.Size > ProtocolMaxMsgSize {
+		return errResp(ErrMsgTooLarge, "%v > %v", msg.Size, ProtocolMaxMsgSize)
+	}
+	defer msg.Discard()
+
+	// Handle the message depending on its contents
+	switch msg.Code {
+	case GetAccountRangeMsg:
+		var req getAccountRangeData
+		if err := msg.Decode(&req); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		return p2p.Send(p.rw, AccountRangeMsg, answerGetAccountRange(triedb, &req))
+
+	case AccountRangeMsg:
+		var res accountRangeData
+		if err := msg.Decode(&res); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		if err := syncer.onAccounts(p.id, res.ID, res.Accounts, res.Proof); err != nil {
+			log.Debug("Failed to deliver account range", "err", err)
+		}
+
+	case GetStorageRangesMsg:
+		var req getStorageRangesData
+		if err := msg.Decode(&req); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		return p2p.Send(p.rw, StorageRangesMsg, answerGetStorageRanges(triedb, &req))
+
+	case StorageRangesMsg:
+		var res storageRangesData
+		if err := msg.Decode(&res); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		if err := syncer.onStorage(p.id, res.ID, res.Slots, res.Proof); err != nil {
+			log.Debug("Failed to deliver storage ranges", "err", err)
+		}
+
+	case GetByteCodesMsg:
+		var req getByteCodesData
+		if err := msg.Decode(&req); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		return p2p.Send(p.rw, ByteCodesMsg, answerGetByteCodes(triedb, &req))
+
+	case ByteCodesMsg:
+		var res byteCodesData
+		if err := msg.Decode(&res); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		if err := syncer.onByteCodes(p.id, res.ID, res.Codes); err != nil {
+			log.Debug("Failed to deliver byte codes", "err", err)
+		}
+
+	case GetTrieNodesMsg:
+		var req getTrieNodesData
+		if err := msg.Decode(&req); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		return p2p.Send(p.rw, TrieNodesMsg, answerGetTrieNodes(triedb, &req))
+
+	case TrieNodesMsg:
+		var res trieNodesData
+		if err := msg.Decode(&res); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		if err := syncer.onTrieNodes(p.id, res.ID, res.Nodes); err != nil {
+			log.Debug("Failed to deliver trie nodes", "err", err)
+		}
+
+	default:
+		return errResp(ErrInvalidMsgCode, "%v", msg.Code)
+	}
+	return nil
+}
+
+// answerGetAccountRange assembles the response to an account range query. If
+// the requested state is not available, an empty response without proofs is
+// returned.
+func answerGetAccountRange(triedb *trie.Database, req *getAccountRangeData) *accountRangeData {
+	res := &accountRangeData{ID: req.ID}
+
+	tr, err := trie.New(req.Root, triedb)
+	if err != nil {
+		return res
+	}
+	// Gather the accounts until the limit hash or the size cap is reached. The
+	// first account past the limit is included too, proving the range ends.
+	var (
+		limit = responseLimit(req.Bytes)
+		size  uint64
+		it    = trie.NewIterator(tr.NodeIterator(req.Origin[:]))
+	)
+	for it.Next() {
+		hash := common.BytesToHash(it.Key)
+		res.Accounts = append(res.Accounts, &accountData{Hash: hash, Body: common.CopyBytes(it.Value)})
+		size += uint64(common.HashLength + len(it.Value))
+
+		if bytes.Compare(hash[:], req.Limit[:]) >= 0 || size >= limit {
+			break
+		}
+	}
+	if it.Err != nil {
+		return &accountRangeData{ID: req.ID}
+	}
+	// Prove the edges of the returned range
+	proof := light.NewNodeSet()
+	if err := tr.Prove(req.Origin[:], 0, proof); err != nil {
+		return &accountRangeData{ID: req.ID}
+	}
+	if len(res.Accounts) > 0 {
+		if err := tr.Prove(res.Accounts[len(res.Accounts)-1].Hash[:], 0, proof); err != nil {
+			return &accountRangeData{ID: req.ID}
+		}
+	}
+	res.Proof = proofList(proof)
+	return res
+}
+
+// answerGetStorageRanges assembles the response to a storage range query. The
+// storage tries are served in order until one is unavailable or the size cap is
+// reached. A proof is attached for the last served trie if its range is partial.
+func answerGetStorageRanges(triedb *trie.Database, req *getStorageRangesData) *storageRangesData {
+	res := &storageRangesData{ID: req.ID}
+
+	var (
+		limit = responseLimit(req.Bytes)
+		size  uint64
+	)
+	for i, root := range req.Roots {
+		if size >= limit {
+			break
+		}
+		tr, err := trie.New(root, triedb)
+		if err != nil {
+			break
+		}
+		// The origin only applies to the first trie, the limit to the last one
+		var origin common.Hash
+		if i == 0 {
+			origin = req.Origin
+		}
+		last := common.HexToHash("0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff")
+		if i == len(req.Roots)-1 {
+			last = req.Limit
+		}
+		var (
+			slots   []*storageData
+			partial = origin != (common.Hash{})
+			it      = trie.NewIterator(tr.NodeIterator(origin[:]))
+		)
+		for it.Next() {
+			if len(slots) > 0 && size >= limit {
+				partial = true
+				break
+			}
+			hash := common.BytesToHash(it.Key)
+			slots = append(slots, &storageData{Hash: hash, Body: common.CopyBytes(it.Value)})
+			size += uint64(common.HashLength + len(it.Value))
+
+			if bytes.Compare(hash[:], last[:]) >= 0 {
+				partial = true
+				break
+			}
+		}
+		if it.Err != nil {
+			break
+		}
+		res.Slots = append(res.Slots, slots)
+
+		// Partial ranges need to be proven, and can only be the last in the response
+		if partial {
+			proof := light.NewNodeSet()
+			if err := tr.Prove(origin[:], 0, proof); err != nil {
+				log.Warn("Failed to prove storage range", "root", root, "origin", origin, "err", err)
+				return &storageRangesData{ID: req.ID}
+			}
+			if len(slots) > 0 {
+				if err := tr.Prove(slots[len(slots)-1].Hash[:], 0, proof); err != nil {
+					log.Warn("Failed to prove storage range", "root", root, "last", slots[len(slots)-1].Hash, "err", err)
+					return &storageRangesData{ID: req.ID}
+				}
+			}
+			res.Proof = proofList(proof)
+			break
+		}
+	}
+	return res
+}
+
+// answerGetByteCodes assembles the response to a bytecode query, skipping the
+// unavailable codes.
+func answerGetByteCodes(triedb *trie.Database, req *getByteCodesData) *byteCodesData {
+	res := &byteCodesData{ID: req.ID}
+
+	var (
+		limit = responseLimit(req.Bytes)
+		size  uint64
+	)
+	for i, hash := range req.Hashes {
+		if i >= maxCodeLookups || size >= limit {
+			break
+		}
+		if code, err := triedb.Node(hash); err == nil {
+			res.Codes = append(res.Codes, code)
+			size += uint64(len(code))
+		}
+	}
+	return res
+}
+
+// answerGetTrieNodes assembles the response to a trie node query, skipping the
+// unavailable nodes.
+func answerGetTrieNodes(triedb *trie.Database, req *getTrieNodesData) *trieNodesData {
+	res := &trieNodesData{ID: req.ID}
+
+	var (
+		limit = responseLimit(req.Bytes)
+		size  uint64
+	)
+	for i, hash := range req.Hashes {
+		if i >= maxTrieNodeLookups || size >= limit {
+			break
+		}
+		if node, err := triedb.Node(hash); err == nil {
+			res.Nodes = append(res.Nodes, node)
+			size += uint64(len(node))
+		}
+	}
+	return res
+}
+
+// responseLimit caps the response size requested by a remote peer.
+func responseLimit(requested uint64) uint64 {
+	if requested > softResponseLimit {
+		return softResponseLimit
+	}
+	return requested
+}
+
+// proofList flattens a proof node set into a list of trie nodes.
+func proofList(proof *light.NodeSet) [][]byte {
+	nodes := proof.NodeList()
+	list := make([][]byte, len(nodes))
+	for i, node := range nodes {
+		list[i] = node
+	}
+	return list
+}
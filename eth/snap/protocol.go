@@ -0,0 +1,164 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package snap implements the snap state synchronisation protocol, which
+// retrieves the state as contiguous ranges of accounts and storage slots with
+// merkle range proofs, instead of downloading the trie node by node.
+package snap
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// Constants to match up protocol versions and messages
+const (
+	snap1 = 1
+)
+
+// ProtocolName is the official short name of the protocol used during capability negotiation.
+var ProtocolName = "snap"
+
+// ProtocolVersions are the supported versions of the snap protocol (first is primary).
+var ProtocolVersions = []uint{snap1}
+
+// ProtocolLengths are the number of implemented message corresponding to different protocol versions.
+var ProtocolLengths = []uint64{8}
+
+const ProtocolMaxMsgSize = 10 * 1024 * 1024 // Maximum cap on the size of a protocol message
+
+// snap protocol message codes
+const (
+	GetAccountRangeMsg  = 0x00
+	AccountRangeMsg     = 0x01
+	GetStorageRangesMsg = 0x02
+	StorageRangesMsg    = 0x03
+	GetByteCodesMsg     = 0x04
+	ByteCodesMsg        = 0x05
+	GetTrieNodesMsg     = 0x06
+	TrieNodesMsg        = 0x07
+)
+
+type errCode int
+
+const (
+	ErrMsgTooLarge = iota
+	ErrDecode
+	ErrInvalidMsgCode
+)
+
+func (e errCode) String() string {
+	return errorToString[int(e)]
+}
+
+// XXX change once legacy code is out
+var errorToString = map[int]string{
+	ErrMsgTooLarge:    "Message too long",
+	ErrDecode:         "Invalid message",
+	ErrInvalidMsgCode: "Invalid message code",
+}
+
+func errResp(code errCode, format string, v ...interface{}) error {
+	return fmt.Errorf("%v - %v", code, fmt.Sprintf(format, v...))
+}
+
+// ErrCancelled is returned by Sync if the sync was cancelled before completion.
+var ErrCancelled = errors.New("sync cancelled")
+
+var (
+	errUnrequested = errors.New("unrequested response")
+	errBadResponse = errors.New("invalid response")
+)
+
+// getAccountRangeData represents an account range query: all the accounts of
+// the state trie with the given root, between the origin and the limit hashes,
+// up to a soft cap on the response size.
+type getAccountRangeData struct {
+	ID     uint64      // Request ID to match up responses with
+	Root   common.Hash // Root hash of the account trie to serve
+	Origin common.Hash // Hash of the first account to retrieve
+	Limit  common.Hash // Hash of the last account to retrieve
+	Bytes  uint64      // Soft limit at which to stop returning data
+}
+
+// accountRangeData is the response to an account range query.
+type accountRangeData struct {
+	ID       uint64         // ID of the request this is a response for
+	Accounts []*accountData // List of consecutive accounts from the trie
+	Proof    [][]byte       // List of trie nodes proving the account range
+}
+
+// accountData represents a single account in an account range response.
+type accountData struct {
+	Hash common.Hash  // Hash of the account
+	Body rlp.RawValue // RLP encoding of the account
+}
+
+// getStorageRangesData represents a storage slot query for the storage tries
+// with the given roots. The origin applies to the first trie and the limit to
+// the last one, the tries in between are retrieved entirely.
+type getStorageRangesData struct {
+	ID     uint64        // Request ID to match up responses with
+	Roots  []common.Hash // Root hashes of the storage tries to serve
+	Origin common.Hash   // Hash of the first storage slot to retrieve
+	Limit  common.Hash   // Hash of the last storage slot to retrieve
+	Bytes  uint64        // Soft limit at which to stop returning data
+}
+
+// storageRangesData is the response to a storage range query. Only the last
+// returned storage trie may be incomplete, in which case the proof is attached.
+// A proof is also attached if the origin of the first trie is not zero.
+type storageRangesData struct {
+	ID    uint64           // ID of the request this is a response for
+	Slots [][]*storageData // Lists of consecutive storage slots per trie
+	Proof [][]byte         // List of trie nodes proving the last slot range
+}
+
+// storageData represents a single storage slot in a storage range response.
+type storageData struct {
+	Hash common.Hash // Hash of the storage slot
+	Body []byte      // Value of the storage slot, as stored in the trie
+}
+
+// getByteCodesData represents a contract bytecode query.
+type getByteCodesData struct {
+	ID     uint64        // Request ID to match up responses with
+	Hashes []common.Hash // Code hashes to retrieve the code for
+	Bytes  uint64        // Soft limit at which to stop returning data
+}
+
+// byteCodesData is the response to a bytecode query.
+type byteCodesData struct {
+	ID    uint64   // ID of the request this is a response for
+	Codes [][]byte // Requested contract bytecodes
+}
+
+// getTrieNodesData represents a trie node query, used for healing the state
+// trie after the ranges have been downloaded.
+type getTrieNodesData struct {
+	ID     uint64        // Request ID to match up responses with
+	Hashes []common.Hash // Hashes of the trie nodes to retrieve
+	Bytes  uint64        // Soft limit at which to stop returning data
+}
+
+// trieNodesData is the response to a trie node query.
+type trieNodesData struct {
+	ID    uint64   // ID of the request this is a response for
+	Nodes [][]byte // Requested state trie nodes
+}
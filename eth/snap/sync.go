@@ -0,0 +1,968 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snap
+
+import (
+	"bytes"
+	"math/big"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/light"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+var (
+	// emptyRoot is the known root hash of an empty trie.
+	emptyRoot = common.HexToHash("56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421")
+
+	// emptyCode is the known hash of the empty EVM bytecode.
+	emptyCode = crypto.Keccak256Hash(nil)
+
+	// maxHash is the largest possible account or storage slot hash.
+	maxHash = common.HexToHash("0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff")
+)
+
+const (
+	// maxRequestSize is the soft response size limit requested from peers.
+	maxRequestSize = 512 * 1024
+
+	// maxStorageSetRequestCount is the maximum number of storage tries to
+	// request at once.
+	maxStorageSetRequestCount = 128
+
+	// maxCodeRequestCount is the maximum number of bytecodes to request at once.
+	maxCodeRequestCount = 384
+
+	// maxTrieRequestCount is the maximum number of trie nodes to request at once
+	// during the healing phase.
+	maxTrieRequestCount = 384
+
+	// requestTimeout is the maximum time a peer is allowed to spend on serving
+	// a single request.
+	requestTimeout = 10 * time.Second
+
+	// accountConcurrency is the number of chunks the account trie is split into
+	// for retrieving it concurrently from multiple peers.
+	accountConcurrency = 16
+)
+
+// SyncPeer is the interface of a remote peer the syncer retrieves state from.
+type SyncPeer interface {
+	// ID retrieves the peer's unique identifier.
+	ID() string
+
+	// RequestAccountRange fetches a batch of accounts of the account trie with
+	// the given root, starting with the origin.
+	RequestAccountRange(id uint64, root, origin, limit common.Hash, bytes uint64) error
+
+	// RequestStorageRanges fetches the slots of a batch of storage tries.
+	RequestStorageRanges(id uint64, roots []common.Hash, origin, limit common.Hash, bytes uint64) error
+
+	// RequestByteCodes fetches a batch of contract bytecodes by hash.
+	RequestByteCodes(id uint64, hashes []common.Hash, bytes uint64) error
+
+	// RequestTrieNodes fetches a batch of trie nodes by hash.
+	RequestTrieNodes(id uint64, hashes []common.Hash, bytes uint64) error
+}
+
+// Request kinds tracked by the syncer.
+const (
+	accountRequest = iota
+	storageRequest
+	codeRequest
+	trieRequest
+)
+
+// request is a pending data retrieval request sent to a peer.
+type request struct {
+	id   uint64
+	kind int
+	peer string
+
+	root    common.Hash    // State root the accounts are requested for (account requests)
+	account *accountTask   // Account range being retrieved (account requests)
+	storage []*storageTask // Storage tries being retrieved (storage requests)
+	hashes  []common.Hash  // Bytecode or trie node hashes (code and trie requests)
+
+	timer *time.Timer   // Timer to fire when the request times out
+	quit  chan struct{} // Channel closed when the sync the request belongs to ends
+}
+
+// response is the outcome of a request: the delivered data, a timeout or the
+// peer dropping off.
+type response struct {
+	req *request
+
+	accounts []*accountData   // Delivered accounts (account requests)
+	slots    [][]*storageData // Delivered storage slots (storage requests)
+	proof    [][]byte         // Delivered range proof (account and storage requests)
+	blobs    [][]byte         // Delivered bytecodes or trie nodes
+
+	failed bool // Whether the request timed out or the peer dropped
+}
+
+// accountTask is a chunk of the account trie to retrieve.
+type accountTask struct {
+	next common.Hash // Next account hash to retrieve
+	last common.Hash // Last account hash belonging to this chunk
+	req  *request    // Pending request, nil if none
+	done bool        // Whether the chunk is fully retrieved
+}
+
+// pendingAccount is a retrieved account which can't be inserted into the account
+// trie until its storage and code are fully retrieved. Accounts are only ever
+// inserted once complete, so any node of the assembled trie written to disk is
+// the root of a complete subtrie.
+type pendingAccount struct {
+	hash    common.Hash // Hash of the account
+	body    []byte      // Account trie leaf to insert once complete
+	needs   int         // Number of storage tries and bytecodes missing
+	dropped bool        // Whether some data couldn't be retrieved and the account left for healing
+}
+
+// storageTask is a storage trie to retrieve, shared by all the accounts having
+// the same storage root.
+type storageTask struct {
+	root      common.Hash         // Root hash of the storage trie
+	next      common.Hash         // Next slot hash to retrieve
+	trie      *trie.Trie          // Storage trie assembled so far
+	waiters   []*pendingAccount   // Accounts waiting for the storage trie
+	stateless map[string]struct{} // Peers which failed to serve this trie
+	req       *request            // Pending request, nil if none
+}
+
+// codeTask is a contract bytecode to retrieve.
+type codeTask struct {
+	waiters   []*pendingAccount   // Accounts waiting for the bytecode
+	stateless map[string]struct{} // Peers which failed to serve this code
+	req       *request            // Pending request, nil if none
+}
+
+// Syncer retrieves the state of a given root via the snap protocol. First the
+// account trie is downloaded as contiguous ranges along with the storage tries
+// and bytecodes, proven by merkle range proofs. As the sync target moves while
+// the ranges are retrieved, the assembled trie is then healed by retrieving the
+// missing trie nodes one by one.
+//
+// The progress is retained between syncs, so a sync interrupted by a new sync
+// target continues where it left off.
+type Syncer struct {
+	db     ethdb.Database // Database to store the retrieved state into
+	triedb *trie.Database // Trie database used for assembling the tries
+
+	root         common.Hash                  // Current state root being synced
+	accountTasks []*accountTask               // Chunks of the account trie to retrieve
+	storageTasks map[common.Hash]*storageTask // Storage tries to retrieve by root
+	codeTasks    map[common.Hash]*codeTask    // Bytecodes to retrieve by hash
+	accountTrie  *trie.Trie                   // Account trie assembled from the ranges
+	uncommitted  int                          // Number of accounts not yet flushed, zero after every response
+
+	healer    *trie.Sync           // State trie healer, created once the ranges are done
+	healTasks map[common.Hash]bool // Trie nodes being healed, and whether they're assigned
+
+	stateless map[string]bool     // Peers not having the current state root
+	busy      map[string]bool     // Peers with a pending request
+	active    map[uint64]*request // Requests pending, tracked by the sync loop
+
+	accountSynced  uint64 // Number of accounts retrieved
+	storageSynced  uint64 // Number of storage slots retrieved
+	bytecodeSynced uint64 // Number of bytecodes retrieved
+	trienodeHealed uint64 // Number of trie nodes retrieved during healing
+	logTime        time.Time
+
+	peers     map[string]SyncPeer // Currently registered peers
+	requests  map[uint64]*request // Pending requests, used for matching responses
+	update    chan struct{}       // Notification channel for new peers
+	responses chan *response      // Delivered responses, timeouts and drops
+	lock      sync.RWMutex        // Protects peers and requests
+}
+
+// NewSyncer creates a new snap syncer storing the retrieved state into the
+// given database.
+func NewSyncer(db ethdb.Database) *Syncer {
+	return &Syncer{
+		db:        db,
+		triedb:    trie.NewDatabase(db),
+		peers:     make(map[string]SyncPeer),
+		requests:  make(map[uint64]*request),
+		update:    make(chan struct{}, 1),
+		responses: make(chan *response),
+	}
+}
+
+// Register injects a new data source into the syncer's peerset.
+func (s *Syncer) Register(peer SyncPeer) {
+	s.lock.Lock()
+	s.peers[peer.ID()] = peer
+	s.lock.Unlock()
+
+	select {
+	case s.update <- struct{}{}:
+	default:
+	}
+}
+
+// Unregister removes a data source from the syncer's peerset, failing all its
+// pending requests.
+func (s *Syncer) Unregister(id string) {
+	s.lock.Lock()
+	delete(s.peers, id)
+
+	var dropped []*request
+	for reqid, req := range s.requests {
+		if req.peer == id {
+			req.timer.Stop()
+			delete(s.requests, reqid)
+			dropped = append(dropped, req)
+		}
+	}
+	s.lock.Unlock()
+
+	for _, req := range dropped {
+		select {
+		case s.responses <- &response{req: req, failed: true}:
+		case <-req.quit:
+		}
+	}
+}
+
+// Peers returns the number of registered peers.
+func (s *Syncer) Peers() int {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return len(s.peers)
+}
+
+// Sync retrieves the state of the given root, blocking until it's complete or
+// the sync is cancelled. The progress of a cancelled sync is kept, to be picked
+// up by the next one, even if it targets a different root.
+func (s *Syncer) Sync(root common.Hash, cancel chan struct{}) error {
+	if s.accountTasks == nil {
+		s.resetProgress()
+	}
+	if root != s.root {
+		s.root = root
+		s.stateless = make(map[string]bool)
+		s.healer, s.healTasks = nil, nil
+	}
+	s.busy = make(map[string]bool)
+	s.active = make(map[uint64]*request)
+
+	quit := make(chan struct{})
+	defer func() {
+		s.revertRequests()
+		close(quit)
+	}()
+	log.Debug("Starting snapshot sync cycle", "root", root)
+
+	for {
+		// Switch over to healing once all the ranges are retrieved, and finish
+		// when there is nothing left to heal
+		if s.healer == nil && s.rangesDone() {
+			if err := s.commitAccounts(); err != nil {
+				return err
+			}
+			s.healer = state.NewStateSync(root, s.db)
+			s.healTasks = make(map[common.Hash]bool)
+		}
+		if s.healer != nil {
+			for _, hash := range s.healer.Missing(0) {
+				s.healTasks[hash] = false
+			}
+			if len(s.healTasks) == 0 && s.healer.Pending() == 0 {
+				log.Info("Snapshot sync complete", "root", root, "accounts", s.accountSynced, "slots", s.storageSynced,
+					"codes", s.bytecodeSynced, "healed", s.trienodeHealed)
+				s.accountTasks = nil
+				return nil
+			}
+		}
+		s.assignTasks(quit)
+		s.reportProgress()
+
+		select {
+		case <-s.update:
+		case res := <-s.responses:
+			s.processResponse(res)
+		case <-cancel:
+			return ErrCancelled
+		}
+	}
+}
+
+// resetProgress discards all the retrieval progress, starting a fresh sync.
+func (s *Syncer) resetProgress() {
+	s.accountTasks = nil
+
+	var next common.Hash
+	step := new(big.Int).Div(new(big.Int).Exp(common.Big2, common.Big256, nil), big.NewInt(accountConcurrency))
+	for i := 0; i < accountConcurrency; i++ {
+		last := common.BigToHash(new(big.Int).Sub(new(big.Int).Add(next.Big(), step), common.Big1))
+		if i == accountConcurrency-1 {
+			last = maxHash
+		}
+		s.accountTasks = append(s.accountTasks, &accountTask{next: next, last: last})
+		next = incHash(last)
+	}
+	s.storageTasks = make(map[common.Hash]*storageTask)
+	s.codeTasks = make(map[common.Hash]*codeTask)
+	s.accountTrie, _ = trie.New(common.Hash{}, s.triedb)
+	s.uncommitted = 0
+	s.healer, s.healTasks = nil, nil
+}
+
+// rangesDone returns whether all the accounts, storage tries and bytecodes are
+// retrieved.
+func (s *Syncer) rangesDone() bool {
+	for _, task := range s.accountTasks {
+		if !task.done {
+			return false
+		}
+	}
+	return len(s.storageTasks) == 0 && len(s.codeTasks) == 0
+}
+
+// revertRequests cancels all the pending requests of the sync, releasing the
+// tasks assigned to them.
+func (s *Syncer) revertRequests() {
+	s.lock.Lock()
+	for id, req := range s.requests {
+		req.timer.Stop()
+		delete(s.requests, id)
+	}
+	s.lock.Unlock()
+
+	for _, req := range s.active {
+		s.revertRequest(req)
+	}
+}
+
+// revertRequest releases the tasks assigned to a request, so they can be
+// assigned to another peer.
+func (s *Syncer) revertRequest(req *request) {
+	delete(s.active, req.id)
+	delete(s.busy, req.peer)
+
+	switch req.kind {
+	case accountRequest:
+		req.account.req = nil
+	case storageRequest:
+		for _, task := range req.storage {
+			task.req = nil
+		}
+	case codeRequest:
+		for _, hash := range req.hashes {
+			if task := s.codeTasks[hash]; task != nil {
+				task.req = nil
+			}
+		}
+	case trieRequest:
+		for _, hash := range req.hashes {
+			if _, ok := s.healTasks[hash]; ok {
+				s.healTasks[hash] = false
+			}
+		}
+	}
+}
+
+// assignTasks sends requests for the pending tasks to all the idle peers.
+func (s *Syncer) assignTasks(quit chan struct{}) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.dropUnservedTasks()
+	for id, peer := range s.peers {
+		if s.busy[id] {
+			continue
+		}
+		req := s.nextRequest(id)
+		if req == nil {
+			continue
+		}
+		req.peer, req.quit = id, quit
+		for {
+			req.id = rand.Uint64()
+			if _, ok := s.requests[req.id]; !ok {
+				break
+			}
+		}
+		s.busy[id] = true
+		s.active[req.id] = req
+		s.requests[req.id] = req
+
+		req.timer = time.AfterFunc(requestTimeout, func() {
+			s.lock.Lock()
+			if s.requests[req.id] != req {
+				s.lock.Unlock()
+				return
+			}
+			delete(s.requests, req.id)
+			s.lock.Unlock()
+
+			log.Debug("Snapshot request timed out", "peer", req.peer, "reqid", req.id)
+			select {
+			case s.responses <- &response{req: req, failed: true}:
+			case <-quit:
+			}
+		})
+		go s.sendRequest(peer, req)
+	}
+}
+
+// nextRequest assembles the next request for the given peer, assigning the
+// retrieval tasks to it. Nil is returned if there is nothing to request.
+func (s *Syncer) nextRequest(peer string) *request {
+	// Retrieve the accounts first, then the storage and code they need, finally
+	// heal the trie. Peers not having the current root can still serve the
+	// storage tries and bytecodes though.
+	if !s.stateless[peer] {
+		for _, task := range s.accountTasks {
+			if !task.done && task.req == nil {
+				req := &request{kind: accountRequest, root: s.root, account: task}
+				task.req = req
+				return req
+			}
+		}
+	}
+	if req := s.nextStorageRequest(peer); req != nil {
+		return req
+	}
+	if req := s.nextCodeRequest(peer); req != nil {
+		return req
+	}
+	if s.healTasks != nil && !s.stateless[peer] {
+		var hashes []common.Hash
+		for hash, assigned := range s.healTasks {
+			if !assigned {
+				hashes = append(hashes, hash)
+				s.healTasks[hash] = true
+				if len(hashes) >= maxTrieRequestCount {
+					break
+				}
+			}
+		}
+		if len(hashes) > 0 {
+			return &request{kind: trieRequest, hashes: hashes}
+		}
+	}
+	return nil
+}
+
+// nextStorageRequest assembles a storage request for the given peer. Partially
+// retrieved tries are requested on their own, fresh ones in batches.
+func (s *Syncer) nextStorageRequest(peer string) *request {
+	var tasks []*storageTask
+	for _, task := range s.storageTasks {
+		if task.req != nil {
+			continue
+		}
+		if _, failed := task.stateless[peer]; failed {
+			continue
+		}
+		if task.next != (common.Hash{}) {
+			tasks = []*storageTask{task}
+			break
+		}
+		tasks = append(tasks, task)
+		if len(tasks) >= maxStorageSetRequestCount {
+			break
+		}
+	}
+	if len(tasks) == 0 {
+		return nil
+	}
+	req := &request{kind: storageRequest, storage: tasks}
+	for _, task := range tasks {
+		task.req = req
+	}
+	return req
+}
+
+// nextCodeRequest assembles a bytecode request for the given peer.
+func (s *Syncer) nextCodeRequest(peer string) *request {
+	var hashes []common.Hash
+	for hash, task := range s.codeTasks {
+		if task.req != nil {
+			continue
+		}
+		if _, failed := task.stateless[peer]; failed {
+			continue
+		}
+		hashes = append(hashes, hash)
+		if len(hashes) >= maxCodeRequestCount {
+			break
+		}
+	}
+	if len(hashes) == 0 {
+		return nil
+	}
+	req := &request{kind: codeRequest, hashes: hashes}
+	for _, hash := range hashes {
+		s.codeTasks[hash].req = req
+	}
+	return req
+}
+
+// sendRequest sends a request to the remote peer. Failures are handled by the
+// request timing out.
+func (s *Syncer) sendRequest(peer SyncPeer, req *request) {
+	var err error
+	switch req.kind {
+	case accountRequest:
+		err = peer.RequestAccountRange(req.id, req.root, req.account.next, req.account.last, maxRequestSize)
+	case storageRequest:
+		roots := make([]common.Hash, len(req.storage))
+		for i, task := range req.storage {
+			roots[i] = task.root
+		}
+		err = peer.RequestStorageRanges(req.id, roots, req.storage[0].next, maxHash, maxRequestSize)
+	case codeRequest:
+		err = peer.RequestByteCodes(req.id, req.hashes, maxRequestSize)
+	case trieRequest:
+		err = peer.RequestTrieNodes(req.id, req.hashes, maxRequestSize)
+	}
+	if err != nil {
+		log.Debug("Failed to send snapshot request", "peer", req.peer, "reqid", req.id, "err", err)
+	}
+}
+
+// dropUnservedTasks drops the storage and code tasks which none of the peers
+// is able to serve. The accounts waiting for them are left for healing.
+func (s *Syncer) dropUnservedTasks() {
+	if len(s.peers) == 0 {
+		return
+	}
+	unserved := func(stateless map[string]struct{}) bool {
+		for id := range s.peers {
+			if _, failed := stateless[id]; !failed {
+				return false
+			}
+		}
+		return true
+	}
+	for root, task := range s.storageTasks {
+		if task.req == nil && unserved(task.stateless) {
+			log.Debug("Leaving storage trie for healing", "root", root)
+			s.dropWaiters(task.waiters)
+			delete(s.storageTasks, root)
+		}
+	}
+	for hash, task := range s.codeTasks {
+		if task.req == nil && unserved(task.stateless) {
+			log.Debug("Leaving bytecode for healing", "hash", hash)
+			s.dropWaiters(task.waiters)
+			delete(s.codeTasks, hash)
+		}
+	}
+}
+
+// deliver matches a response against the pending requests and forwards it to
+// the sync loop.
+func (s *Syncer) deliver(peer string, id uint64, res *response) error {
+	s.lock.Lock()
+	req := s.requests[id]
+	if req == nil || req.peer != peer {
+		s.lock.Unlock()
+		return errUnrequested
+	}
+	req.timer.Stop()
+	delete(s.requests, id)
+	s.lock.Unlock()
+
+	res.req = req
+	select {
+	case s.responses <- res:
+	case <-req.quit:
+	}
+	return nil
+}
+
+// onAccounts is invoked when a peer delivers an account range response.
+func (s *Syncer) onAccounts(peer string, id uint64, accounts []*accountData, proof [][]byte) error {
+	return s.deliver(peer, id, &response{accounts: accounts, proof: proof})
+}
+
+// onStorage is invoked when a peer delivers a storage ranges response.
+func (s *Syncer) onStorage(peer string, id uint64, slots [][]*storageData, proof [][]byte) error {
+	return s.deliver(peer, id, &response{slots: slots, proof: proof})
+}
+
+// onByteCodes is invoked when a peer delivers a bytecode response.
+func (s *Syncer) onByteCodes(peer string, id uint64, codes [][]byte) error {
+	return s.deliver(peer, id, &response{blobs: codes})
+}
+
+// onTrieNodes is invoked when a peer delivers a trie node response.
+func (s *Syncer) onTrieNodes(peer string, id uint64, nodes [][]byte) error {
+	return s.deliver(peer, id, &response{blobs: nodes})
+}
+
+// processResponse integrates a response into the sync progress.
+func (s *Syncer) processResponse(res *response) {
+	req := res.req
+	if s.active[req.id] != req {
+		return // Stale response of a previous sync
+	}
+	s.revertRequest(req)
+	if res.failed {
+		return
+	}
+	var err error
+	switch req.kind {
+	case accountRequest:
+		err = s.processAccounts(req, res)
+	case storageRequest:
+		err = s.processStorage(req, res)
+	case codeRequest:
+		err = s.processByteCodes(req, res)
+	case trieRequest:
+		err = s.processTrieNodes(req, res)
+	}
+	if err != nil {
+		log.Warn("Invalid snapshot response", "peer", req.peer, "err", err)
+		s.stateless[req.peer] = true
+	}
+	// Flush the accounts completed by the response right away, so the memory
+	// use doesn't grow with the size of the state
+	if s.uncommitted > 0 {
+		if err := s.commitAccounts(); err != nil {
+			log.Error("Failed to commit snapshot accounts", "err", err)
+		}
+	}
+}
+
+// processAccounts verifies an account range response and integrates the
+// accounts into the assembled trie.
+func (s *Syncer) processAccounts(req *request, res *response) error {
+	task := req.account
+
+	// An empty response without proofs signals the peer doesn't have the state
+	if len(res.accounts) == 0 && len(res.proof) == 0 {
+		s.stateless[req.peer] = true
+		return nil
+	}
+	keys := make([][]byte, len(res.accounts))
+	values := make([][]byte, len(res.accounts))
+	for i, account := range res.accounts {
+		keys[i], values[i] = common.CopyBytes(account.Hash[:]), account.Body
+	}
+	more, err := trie.VerifyRangeProof(req.root, task.next[:], keys, values, proofSet(res.proof))
+	if err != nil {
+		return err
+	}
+	for _, account := range res.accounts {
+		if bytes.Compare(account.Hash[:], task.last[:]) > 0 {
+			break // Belongs to the next chunk
+		}
+		var acc state.Account
+		if err := rlp.DecodeBytes(account.Body, &acc); err != nil {
+			return err
+		}
+		if err := s.addAccount(account.Hash, account.Body, &acc); err != nil {
+			return err
+		}
+	}
+	s.accountSynced += uint64(len(res.accounts))
+
+	if !more || len(res.accounts) == 0 || bytes.Compare(res.accounts[len(res.accounts)-1].Hash[:], task.last[:]) >= 0 {
+		task.done = true
+	} else {
+		task.next = incHash(res.accounts[len(res.accounts)-1].Hash)
+	}
+	return nil
+}
+
+// addAccount schedules the retrieval of the storage and code of an account,
+// or inserts it into the account trie if nothing is missing.
+func (s *Syncer) addAccount(hash common.Hash, body []byte, acc *state.Account) error {
+	pending := &pendingAccount{hash: hash, body: common.CopyBytes(body)}
+
+	if acc.Root != emptyRoot {
+		if ok, _ := s.db.Has(acc.Root[:]); !ok {
+			task := s.storageTasks[acc.Root]
+			if task == nil {
+				task = &storageTask{root: acc.Root, stateless: make(map[string]struct{})}
+				s.storageTasks[acc.Root] = task
+			}
+			task.waiters = append(task.waiters, pending)
+			pending.needs++
+		}
+	}
+	if codeHash := common.BytesToHash(acc.CodeHash); codeHash != emptyCode {
+		if ok, _ := s.db.Has(codeHash[:]); !ok {
+			task := s.codeTasks[codeHash]
+			if task == nil {
+				task = &codeTask{stateless: make(map[string]struct{})}
+				s.codeTasks[codeHash] = task
+			}
+			task.waiters = append(task.waiters, pending)
+			pending.needs++
+		}
+	}
+	if pending.needs == 0 {
+		return s.insertAccount(pending)
+	}
+	return nil
+}
+
+// insertAccount inserts a complete account into the account trie.
+func (s *Syncer) insertAccount(account *pendingAccount) error {
+	if err := s.accountTrie.TryUpdate(account.hash[:], account.body); err != nil {
+		return err
+	}
+	s.uncommitted++
+	return nil
+}
+
+// fulfilWaiters marks one missing item of the given accounts as retrieved,
+// inserting the accounts which became complete.
+func (s *Syncer) fulfilWaiters(waiters []*pendingAccount) error {
+	for _, account := range waiters {
+		account.needs--
+		if account.needs == 0 && !account.dropped {
+			if err := s.insertAccount(account); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// dropWaiters marks the given accounts as incomplete, leaving them to be
+// retrieved by healing.
+func (s *Syncer) dropWaiters(waiters []*pendingAccount) {
+	for _, account := range waiters {
+		account.dropped = true
+	}
+}
+
+// commitAccounts flushes the account trie assembled so far to the database.
+func (s *Syncer) commitAccounts() error {
+	root, err := s.accountTrie.Commit(nil)
+	if err != nil {
+		return err
+	}
+	if root != emptyRoot {
+		if err := s.triedb.Commit(root, false); err != nil {
+			return err
+		}
+	}
+	s.accountTrie, err = trie.New(root, s.triedb)
+	s.uncommitted = 0
+	return err
+}
+
+// processStorage verifies a storage ranges response and integrates the slots
+// into the assembled storage tries.
+func (s *Syncer) processStorage(req *request, res *response) error {
+	// An empty response signals the peer doesn't have the first storage trie
+	if len(res.slots) == 0 {
+		req.storage[0].stateless[req.peer] = struct{}{}
+		return nil
+	}
+	if len(res.slots) > len(req.storage) {
+		return errBadResponse
+	}
+	for i, slots := range res.slots {
+		task := req.storage[i]
+
+		keys := make([][]byte, len(slots))
+		values := make([][]byte, len(slots))
+		for j, slot := range slots {
+			keys[j], values[j] = common.CopyBytes(slot.Hash[:]), slot.Body
+		}
+		// Only the last trie can be partial, in which case it's proven
+		var (
+			origin common.Hash
+			proof  trie.DatabaseReader
+		)
+		if i == 0 {
+			origin = task.next
+		}
+		if i == len(res.slots)-1 && len(res.proof) > 0 {
+			proof = proofSet(res.proof)
+		}
+		more, err := trie.VerifyRangeProof(task.root, origin[:], keys, values, proof)
+		if err != nil {
+			return err
+		}
+		if task.trie == nil {
+			task.trie, _ = trie.New(common.Hash{}, s.triedb)
+		}
+		for j, key := range keys {
+			if err := task.trie.TryUpdate(key, values[j]); err != nil {
+				return err
+			}
+		}
+		s.storageSynced += uint64(len(slots))
+
+		if more {
+			// Flush the partial trie, the rest of it is retrieved by later requests
+			task.next = incHash(slots[len(slots)-1].Hash)
+			if err := s.flushStorage(task); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := s.completeStorage(task); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flushStorage writes a partially retrieved storage trie to the database and
+// reopens it, so it isn't held in memory until complete.
+func (s *Syncer) flushStorage(task *storageTask) error {
+	root, err := task.trie.Commit(nil)
+	if err != nil {
+		return err
+	}
+	if err := s.triedb.Commit(root, false); err != nil {
+		return err
+	}
+	task.trie, err = trie.New(root, s.triedb)
+	return err
+}
+
+// completeStorage flushes a fully retrieved storage trie to the database and
+// releases the accounts waiting for it.
+func (s *Syncer) completeStorage(task *storageTask) error {
+	delete(s.storageTasks, task.root)
+
+	root, err := task.trie.Commit(nil)
+	if err != nil {
+		return err
+	}
+	if root != task.root {
+		log.Error("Storage trie root mismatch", "have", root, "want", task.root)
+		s.dropWaiters(task.waiters)
+		return nil
+	}
+	if err := s.triedb.Commit(root, false); err != nil {
+		return err
+	}
+	return s.fulfilWaiters(task.waiters)
+}
+
+// processByteCodes verifies a bytecode response and stores the codes.
+func (s *Syncer) processByteCodes(req *request, res *response) error {
+	requested := make(map[common.Hash]bool)
+	for _, hash := range req.hashes {
+		requested[hash] = true
+	}
+	for _, code := range res.blobs {
+		hash := crypto.Keccak256Hash(code)
+		if !requested[hash] {
+			return errBadResponse
+		}
+		delete(requested, hash)
+
+		if err := s.db.Put(hash[:], code); err != nil {
+			return err
+		}
+		s.bytecodeSynced++
+		if task := s.codeTasks[hash]; task != nil {
+			delete(s.codeTasks, hash)
+			if err := s.fulfilWaiters(task.waiters); err != nil {
+				return err
+			}
+		}
+	}
+	// The codes not delivered are not available from this peer
+	for hash := range requested {
+		if task := s.codeTasks[hash]; task != nil {
+			task.stateless[req.peer] = struct{}{}
+		}
+	}
+	return nil
+}
+
+// processTrieNodes verifies a trie node response and feeds the nodes into the
+// healer.
+func (s *Syncer) processTrieNodes(req *request, res *response) error {
+	requested := make(map[common.Hash]bool)
+	for _, hash := range req.hashes {
+		requested[hash] = true
+	}
+	results := make([]trie.SyncResult, 0, len(res.blobs))
+	for _, node := range res.blobs {
+		hash := crypto.Keccak256Hash(node)
+		if !requested[hash] {
+			return errBadResponse
+		}
+		delete(requested, hash)
+		delete(s.healTasks, hash)
+		results = append(results, trie.SyncResult{Hash: hash, Data: node})
+	}
+	if len(results) == 0 {
+		s.stateless[req.peer] = true
+		return nil
+	}
+	if _, index, err := s.healer.Process(results); err != nil {
+		log.Warn("Failed to process healed trie node", "hash", results[index].Hash, "err", err)
+	}
+	batch := s.db.NewBatch()
+	if _, err := s.healer.Commit(batch); err != nil {
+		return err
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	s.trienodeHealed += uint64(len(results))
+	return nil
+}
+
+// reportProgress logs the sync progress every few seconds.
+func (s *Syncer) reportProgress() {
+	if time.Since(s.logTime) < 8*time.Second {
+		return
+	}
+	s.logTime = time.Now()
+
+	var chunks int
+	for _, task := range s.accountTasks {
+		if task.done {
+			chunks++
+		}
+	}
+	log.Info("State sync in progress", "chunks", chunks, "accounts", s.accountSynced, "slots", s.storageSynced,
+		"codes", s.bytecodeSynced, "healed", s.trienodeHealed, "pending", len(s.storageTasks)+len(s.codeTasks)+len(s.healTasks))
+}
+
+// proofSet converts a list of proof nodes into a node set usable for proof
+// verification.
+func proofSet(proof [][]byte) trie.DatabaseReader {
+	nodes := make(light.NodeList, len(proof))
+	for i, node := range proof {
+		nodes[i] = node
+	}
+	return nodes.NodeSet()
+}
+
+// incHash returns the hash following the given one.
+func incHash(h common.Hash) common.Hash {
+	return common.BigToHash(new(big.Int).Add(h.Big(), common.Big1))
+}
@@ -0,0 +1,332 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snap
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// testPeer is a snap peer serving the state of a local trie database.
+type testPeer struct {
+	id     string
+	triedb *trie.Database
+	syncer *Syncer
+	bytes  uint64 // Response size cap overriding the requested one, if non-zero
+
+	// Number of account requests to serve before going silent, if non-zero
+	accountLimit int
+	silent       chan struct{}
+}
+
+func newTestPeer(id string, triedb *trie.Database, syncer *Syncer) *testPeer {
+	return &testPeer{id: id, triedb: triedb, syncer: syncer}
+}
+
+func (p *testPeer) ID() string { return p.id }
+
+func (p *testPeer) limit(bytes uint64) uint64 {
+	if p.bytes != 0 {
+		return p.bytes
+	}
+	return bytes
+}
+
+func (p *testPeer) RequestAccountRange(id uint64, root, origin, limit common.Hash, bytes uint64) error {
+	if p.accountLimit != 0 {
+		if p.accountLimit--; p.accountLimit == 0 {
+			close(p.silent)
+		}
+	}
+	res := answerGetAccountRange(p.triedb, &getAccountRangeData{ID: id, Root: root, Origin: origin, Limit: limit, Bytes: p.limit(bytes)})
+	go p.syncer.onAccounts(p.id, id, res.Accounts, res.Proof)
+	return nil
+}
+
+func (p *testPeer) RequestStorageRanges(id uint64, roots []common.Hash, origin, limit common.Hash, bytes uint64) error {
+	if p.muted() {
+		return nil
+	}
+	res := answerGetStorageRanges(p.triedb, &getStorageRangesData{ID: id, Roots: roots, Origin: origin, Limit: limit, Bytes: p.limit(bytes)})
+	go p.syncer.onStorage(p.id, id, res.Slots, res.Proof)
+	return nil
+}
+
+func (p *testPeer) RequestByteCodes(id uint64, hashes []common.Hash, bytes uint64) error {
+	if p.muted() {
+		return nil
+	}
+	res := answerGetByteCodes(p.triedb, &getByteCodesData{ID: id, Hashes: hashes, Bytes: p.limit(bytes)})
+	go p.syncer.onByteCodes(p.id, id, res.Codes)
+	return nil
+}
+
+func (p *testPeer) RequestTrieNodes(id uint64, hashes []common.Hash, bytes uint64) error {
+	if p.muted() {
+		return nil
+	}
+	res := answerGetTrieNodes(p.triedb, &getTrieNodesData{ID: id, Hashes: hashes, Bytes: p.limit(bytes)})
+	go p.syncer.onTrieNodes(p.id, id, res.Nodes)
+	return nil
+}
+
+// muted returns whether the peer only serves a limited number of account ranges
+// and nothing else.
+func (p *testPeer) muted() bool {
+	return p.silent != nil
+}
+
+// makeTestState creates a state with plain accounts, contracts with code and
+// accounts with storage, some of which share the same storage trie.
+func makeTestState(t *testing.T, db state.Database, accounts int) common.Hash {
+	statedb, err := state.New(common.Hash{}, db)
+	if err != nil {
+		t.Fatalf("failed to create state: %v", err)
+	}
+	for i := 0; i < accounts; i++ {
+		addr := common.BigToAddress(big.NewInt(int64(i + 1)))
+		statedb.SetBalance(addr, big.NewInt(int64(1000*i+1)))
+		statedb.SetNonce(addr, uint64(i))
+		if i%3 == 0 {
+			statedb.SetCode(addr, []byte{0x60, byte(i), byte(i >> 8)})
+		}
+		if i%4 == 0 {
+			// Every other storage account has the same storage contents
+			slots := 10 + i%7
+			if i%8 == 0 {
+				slots = 25
+			}
+			for j := 0; j < slots; j++ {
+				value := common.BigToHash(big.NewInt(int64(j + 1)))
+				if i%8 != 0 {
+					value = common.BigToHash(big.NewInt(int64(i*100 + j + 1)))
+				}
+				statedb.SetState(addr, common.BigToHash(big.NewInt(int64(j))), value)
+			}
+		}
+	}
+	return commitTestState(t, db, statedb)
+}
+
+// updateTestState modifies every few accounts of a test state, returning the
+// new state root.
+func updateTestState(t *testing.T, db state.Database, root common.Hash, accounts int) common.Hash {
+	statedb, err := state.New(root, db)
+	if err != nil {
+		t.Fatalf("failed to create state: %v", err)
+	}
+	for i := 0; i < accounts; i += 5 {
+		addr := common.BigToAddress(big.NewInt(int64(i + 1)))
+		statedb.AddBalance(addr, big.NewInt(1))
+		if i%4 == 0 {
+			statedb.SetState(addr, common.Hash{}, common.BigToHash(big.NewInt(int64(i))))
+		}
+	}
+	return commitTestState(t, db, statedb)
+}
+
+// commitTestState flushes a test state into its database.
+func commitTestState(t *testing.T, db state.Database, statedb *state.StateDB) common.Hash {
+	root, err := statedb.Commit(false)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	if err := db.TrieDB().Commit(root, false); err != nil {
+		t.Fatalf("failed to flush state: %v", err)
+	}
+	return root
+}
+
+// checkState verifies that the synced state is complete and matches the source.
+func checkState(t *testing.T, srcdb state.Database, dstdb ethdb.Database, root common.Hash) {
+	dst, err := state.New(root, state.NewDatabase(dstdb))
+	if err != nil {
+		t.Fatalf("synced state unavailable: %v", err)
+	}
+	it := state.NewNodeIterator(dst)
+	for it.Next() {
+	}
+	if it.Error != nil {
+		t.Fatalf("synced state incomplete: %v", it.Error)
+	}
+	src := trie.NewIterator(mustOpenTrie(t, srcdb.TrieDB(), root).NodeIterator(nil))
+	synced := mustOpenTrie(t, trie.NewDatabase(dstdb), root)
+	for src.Next() {
+		if have := synced.Get(src.Key); !bytes.Equal(have, src.Value) {
+			t.Fatalf("account %x mismatch: have %x, want %x", src.Key, have, src.Value)
+		}
+	}
+}
+
+func mustOpenTrie(t *testing.T, db *trie.Database, root common.Hash) *trie.Trie {
+	tr, err := trie.New(root, db)
+	if err != nil {
+		t.Fatalf("failed to open trie: %v", err)
+	}
+	return tr
+}
+
+// runSync runs a sync to the given root, failing the test if it doesn't
+// complete in time.
+func runSync(t *testing.T, syncer *Syncer, root common.Hash) {
+	done := make(chan error, 1)
+	cancel := make(chan struct{})
+	go func() { done <- syncer.Sync(root, cancel) }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("sync failed: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		close(cancel)
+		t.Fatalf("sync timed out")
+	}
+}
+
+// Tests that a state can be retrieved from a single peer.
+func TestSync(t *testing.T) {
+	srcdb := state.NewDatabase(ethdb.NewMemDatabase())
+	root := makeTestState(t, srcdb, 1000)
+
+	dstdb := ethdb.NewMemDatabase()
+	syncer := NewSyncer(dstdb)
+	syncer.Register(newTestPeer("source", srcdb.TrieDB(), syncer))
+
+	runSync(t, syncer, root)
+	checkState(t, srcdb, dstdb, root)
+
+	if syncer.trienodeHealed != 0 {
+		t.Errorf("healed trie nodes mismatch: have %d, want 0", syncer.trienodeHealed)
+	}
+}
+
+// Tests that a state can be retrieved if the responses are split into many
+// small chunks, requiring range continuations of the accounts and storage.
+func TestSyncSmallResponses(t *testing.T) {
+	srcdb := state.NewDatabase(ethdb.NewMemDatabase())
+	root := makeTestState(t, srcdb, 500)
+
+	dstdb := ethdb.NewMemDatabase()
+	syncer := NewSyncer(dstdb)
+	for _, id := range []string{"a", "b", "c"} {
+		peer := newTestPeer(id, srcdb.TrieDB(), syncer)
+		peer.bytes = 200
+		syncer.Register(peer)
+	}
+	runSync(t, syncer, root)
+	checkState(t, srcdb, dstdb, root)
+}
+
+// Tests that a sync interrupted by a new sync target keeps the state retrieved
+// so far, healing it to the new root.
+func TestSyncHealing(t *testing.T) {
+	srcdb := state.NewDatabase(ethdb.NewMemDatabase())
+	root1 := makeTestState(t, srcdb, 1000)
+	root2 := updateTestState(t, srcdb, root1, 1000)
+
+	dstdb := ethdb.NewMemDatabase()
+	syncer := NewSyncer(dstdb)
+
+	// Retrieve a few account ranges of the original state and stop
+	partial := newTestPeer("partial", srcdb.TrieDB(), syncer)
+	partial.accountLimit, partial.silent = 4, make(chan struct{})
+	syncer.Register(partial)
+
+	done := make(chan error, 1)
+	cancel := make(chan struct{})
+	go func() { done <- syncer.Sync(root1, cancel) }()
+	select {
+	case <-partial.silent:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("partial sync timed out")
+	}
+	close(cancel)
+	if err := <-done; err != ErrCancelled {
+		t.Fatalf("cancelled sync error mismatch: have %v, want %v", err, ErrCancelled)
+	}
+	syncer.Unregister(partial.id)
+
+	// The accounts retrieved so far must be on disk, not only in memory
+	if syncer.uncommitted != 0 {
+		t.Fatalf("uncommitted accounts after responses: have %d, want 0", syncer.uncommitted)
+	}
+	it := trie.NewIterator(mustOpenTrie(t, trie.NewDatabase(dstdb), syncer.accountTrie.Hash()).NodeIterator(nil))
+	var stored int
+	for it.Next() {
+		stored++
+	}
+	if it.Err != nil {
+		t.Fatalf("assembled account trie not on disk: %v", it.Err)
+	}
+	if stored == 0 {
+		t.Fatalf("no accounts stored by the partial sync")
+	}
+
+	// Sync the updated state, which needs healing of the ranges retrieved before
+	syncer.Register(newTestPeer("full", srcdb.TrieDB(), syncer))
+	runSync(t, syncer, root2)
+	checkState(t, srcdb, dstdb, root2)
+
+	if syncer.trienodeHealed == 0 {
+		t.Errorf("no trie nodes healed")
+	}
+}
+
+// Tests that peers not having the requested state are skipped.
+func TestSyncStatelessPeer(t *testing.T) {
+	srcdb := state.NewDatabase(ethdb.NewMemDatabase())
+	root := makeTestState(t, srcdb, 100)
+
+	dstdb := ethdb.NewMemDatabase()
+	syncer := NewSyncer(dstdb)
+	syncer.Register(newTestPeer("empty", trie.NewDatabase(ethdb.NewMemDatabase()), syncer))
+	syncer.Register(newTestPeer("source", srcdb.TrieDB(), syncer))
+
+	runSync(t, syncer, root)
+	checkState(t, srcdb, dstdb, root)
+
+	if !syncer.stateless["empty"] {
+		t.Errorf("peer without state not marked stateless")
+	}
+}
+
+// Tests that unavailable state is served as an empty response without proofs.
+func TestServeUnavailableState(t *testing.T) {
+	triedb := trie.NewDatabase(ethdb.NewMemDatabase())
+	root := common.HexToHash("0x01")
+
+	accounts := answerGetAccountRange(triedb, &getAccountRangeData{ID: 1, Root: root, Limit: maxHash, Bytes: maxRequestSize})
+	if len(accounts.Accounts) != 0 || len(accounts.Proof) != 0 {
+		t.Errorf("account range mismatch: have %d accounts, %d proof nodes, want none", len(accounts.Accounts), len(accounts.Proof))
+	}
+	storage := answerGetStorageRanges(triedb, &getStorageRangesData{ID: 2, Roots: []common.Hash{root}, Limit: maxHash, Bytes: maxRequestSize})
+	if len(storage.Slots) != 0 || len(storage.Proof) != 0 {
+		t.Errorf("storage ranges mismatch: have %d tries, %d proof nodes, want none", len(storage.Slots), len(storage.Proof))
+	}
+	codes := answerGetByteCodes(triedb, &getByteCodesData{ID: 3, Hashes: []common.Hash{root}, Bytes: maxRequestSize})
+	if len(codes.Codes) != 0 {
+		t.Errorf("bytecodes mismatch: have %d, want none", len(codes.Codes))
+	}
+}
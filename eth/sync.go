@@ -24,6 +24,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/eth/downloader"
+	"github.com/ethereum/go-ethereum/eth/fetcher"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p/enode"
 )
@@ -43,6 +44,8 @@ type txsync struct {
 }
 
 // syncTransactions starts sending all currently pending transactions to the given peer.
+// Peers supporting transaction announcements are only sent the transaction hashes, in
+// batches no larger than the number of announcements a peer is willing to track.
 func (pm *ProtocolManager) syncTransactions(p *peer) {
 	var txs types.Transactions
 	pending, _ := pm.txpool.Pending()
@@ -52,6 +55,21 @@ func (pm *ProtocolManager) syncTransactions(p *peer) {
 	if len(txs) == 0 {
 		return
 	}
+	if p.version >= eth65 {
+		for len(txs) > 0 {
+			batch := len(txs)
+			if batch > fetcher.MaxTransactionAnnounce {
+				batch = fetcher.MaxTransactionAnnounce
+			}
+			hashes := make([]common.Hash, batch)
+			for i, tx := range txs[:batch] {
+				hashes[i] = tx.Hash()
+			}
+			p.AsyncSendPooledTransactionHashes(hashes)
+			txs = txs[batch:]
+		}
+		return
+	}
 	select {
 	case pm.txsyncCh <- &txsync{p, txs}:
 	case <-pm.quitSync:
@@ -14,19 +14,63 @@
 // You should have received a copy of the GNU Lesser General Public License
 // along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
 
-// Package tracers is a collection of JavaScript transaction tracers.
+// Package tracers is a collection of JavaScript and native Go transaction tracers.
 package tracers
 
 import (
+	"encoding/json"
 	"strings"
+	"sync"
 	"unicode"
 
+	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/eth/tracers/internal/tracers"
 )
 
 // all contains all the built in JavaScript tracers by name.
 var all = make(map[string]string)
 
+// Interface is the common API exposed by both JavaScript and native tracers,
+// allowing callers to drive either of them without caring about the type.
+type Interface interface {
+	vm.Tracer
+
+	// GetResult returns the JSON encoded outcome of the tracing operation, or
+	// any error that was accumulated during execution.
+	GetResult() (json.RawMessage, error)
+
+	// Stop terminates execution of the tracer at the first opportune moment.
+	Stop(err error)
+}
+
+var (
+	nativeLock sync.RWMutex
+	native     = make(map[string]func() Interface) // Native tracer constructors by name
+)
+
+// RegisterNative makes a native Go tracer available under the given name. If
+// a JavaScript tracer with the same name exists, the native one takes priority
+// when tracers are resolved through NewTracer.
+func RegisterNative(name string, ctor func() Interface) {
+	nativeLock.Lock()
+	defer nativeLock.Unlock()
+
+	native[name] = ctor
+}
+
+// NewTracer instantiates a tracer by name or code. Natively registered tracers
+// are preferred, falling back to evaluating code as a JavaScript tracer.
+func NewTracer(code string) (Interface, error) {
+	nativeLock.RLock()
+	ctor, ok := native[code]
+	nativeLock.RUnlock()
+
+	if ok {
+		return ctor(), nil
+	}
+	return New(code)
+}
+
 // camel converts a snake cased input string into a camel cased output.
 func camel(str string) string {
 	pieces := strings.Split(str, "_")
@@ -17,11 +17,14 @@
 package tracers
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"math/big"
 	"path/filepath"
 	"reflect"
+	"regexp"
 	"strings"
 	"testing"
 
@@ -119,6 +122,16 @@ type callTracerTest struct {
 // Iterates over all the input-output datasets in the tracer test harness and
 // runs the JavaScript tracers against them.
 func TestCallTracer(t *testing.T) {
+	testCallTracer(t, func() (Interface, error) { return New("callTracer") })
+}
+
+// Iterates over all the input-output datasets in the tracer test harness and
+// runs the native Go tracers against them.
+func TestNativeCallTracer(t *testing.T) {
+	testCallTracer(t, func() (Interface, error) { return NewTracer("callTracer") })
+}
+
+func testCallTracer(t *testing.T, newTracer func() (Interface, error)) {
 	files, err := ioutil.ReadDir("testdata")
 	if err != nil {
 		t.Fatalf("failed to retrieve tracer test suite: %v", err)
@@ -132,55 +145,18 @@ func TestCallTracer(t *testing.T) {
 			t.Parallel()
 
 			// Call tracer test found, read if from disk
-			blob, err := ioutil.ReadFile(filepath.Join("testdata", file.Name()))
-			if err != nil {
-				t.Fatalf("failed to read testcase: %v", err)
-			}
-			test := new(callTracerTest)
-			if err := json.Unmarshal(blob, test); err != nil {
-				t.Fatalf("failed to parse testcase: %v", err)
-			}
-			// Configure a blockchain with the given prestate
-			tx := new(types.Transaction)
-			if err := rlp.DecodeBytes(common.FromHex(test.Input), tx); err != nil {
-				t.Fatalf("failed to parse testcase input: %v", err)
-			}
-			signer := types.MakeSigner(test.Genesis.Config, new(big.Int).SetUint64(uint64(test.Context.Number)))
-			origin, _ := signer.Sender(tx)
-
-			context := vm.Context{
-				CanTransfer: core.CanTransfer,
-				Transfer:    core.Transfer,
-				Origin:      origin,
-				Coinbase:    test.Context.Miner,
-				BlockNumber: new(big.Int).SetUint64(uint64(test.Context.Number)),
-				Time:        new(big.Int).SetUint64(uint64(test.Context.Time)),
-				Difficulty:  (*big.Int)(test.Context.Difficulty),
-				GasLimit:    uint64(test.Context.GasLimit),
-				GasPrice:    tx.GasPrice(),
-			}
-			statedb := tests.MakePreState(ethdb.NewMemDatabase(), test.Genesis.Alloc)
+			test := loadCallTracerTest(t, file.Name())
 
-			// Create the tracer, the EVM environment and run it
-			tracer, err := New("callTracer")
+			// Create the tracer, run it and retrieve the trace result
+			tracer, err := newTracer()
 			if err != nil {
 				t.Fatalf("failed to create call tracer: %v", err)
 			}
-			evm := vm.NewEVM(context, statedb, test.Genesis.Config, vm.Config{Debug: true, Tracer: tracer})
-
-			msg, err := tx.AsMessage(signer)
-			if err != nil {
-				t.Fatalf("failed to prepare transaction for tracing: %v", err)
-			}
-			st := core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(tx.Gas()))
-			if _, _, _, err = st.TransitionDb(); err != nil {
-				t.Fatalf("failed to execute transaction: %v", err)
-			}
-			// Retrieve the trace result and compare against the etalon
-			res, err := tracer.GetResult()
+			res, err := runCallTracerTest(test, tracer)
 			if err != nil {
-				t.Fatalf("failed to retrieve trace result: %v", err)
+				t.Fatalf("failed to run call tracer: %v", err)
 			}
+			// Compare the result against the etalon
 			ret := new(callTrace)
 			if err := json.Unmarshal(res, ret); err != nil {
 				t.Fatalf("failed to unmarshal trace result: %v", err)
@@ -191,3 +167,123 @@ func TestCallTracer(t *testing.T) {
 		})
 	}
 }
+
+// Tests that the native call tracer produces byte-for-byte the same output as
+// the JavaScript one, apart from the measured execution time.
+func TestNativeCallTracerEquivalence(t *testing.T) {
+	files, err := ioutil.ReadDir("testdata")
+	if err != nil {
+		t.Fatalf("failed to retrieve tracer test suite: %v", err)
+	}
+	timeRe := regexp.MustCompile(`"time":"[^"]*"`)
+
+	for _, file := range files {
+		if !strings.HasPrefix(file.Name(), "call_tracer_") {
+			continue
+		}
+		test := loadCallTracerTest(t, file.Name())
+
+		jsTracer, err := New("callTracer")
+		if err != nil {
+			t.Fatalf("%s: failed to create JavaScript call tracer: %v", file.Name(), err)
+		}
+		want, err := runCallTracerTest(test, jsTracer)
+		if err != nil {
+			t.Fatalf("%s: failed to run JavaScript call tracer: %v", file.Name(), err)
+		}
+		have, err := runCallTracerTest(test, newCallTracer())
+		if err != nil {
+			t.Fatalf("%s: failed to run native call tracer: %v", file.Name(), err)
+		}
+		want, have = timeRe.ReplaceAll(want, nil), timeRe.ReplaceAll(have, nil)
+		if !bytes.Equal(have, want) {
+			t.Errorf("%s: trace mismatch:\nhave %s\nwant %s", file.Name(), have, want)
+		}
+	}
+}
+
+// Benchmarks the JavaScript call tracer against all the tracer test datasets.
+func BenchmarkCallTracer(b *testing.B) {
+	benchmarkCallTracer(b, func() (Interface, error) { return New("callTracer") })
+}
+
+// Benchmarks the native call tracer against all the tracer test datasets.
+func BenchmarkNativeCallTracer(b *testing.B) {
+	benchmarkCallTracer(b, func() (Interface, error) { return NewTracer("callTracer") })
+}
+
+func benchmarkCallTracer(b *testing.B, newTracer func() (Interface, error)) {
+	files, err := ioutil.ReadDir("testdata")
+	if err != nil {
+		b.Fatalf("failed to retrieve tracer test suite: %v", err)
+	}
+	for _, file := range files {
+		if !strings.HasPrefix(file.Name(), "call_tracer_") {
+			continue
+		}
+		test := loadCallTracerTest(b, file.Name())
+
+		b.Run(camel(strings.TrimSuffix(strings.TrimPrefix(file.Name(), "call_tracer_"), ".json")), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				tracer, err := newTracer()
+				if err != nil {
+					b.Fatalf("failed to create call tracer: %v", err)
+				}
+				if _, err := runCallTracerTest(test, tracer); err != nil {
+					b.Fatalf("failed to run call tracer: %v", err)
+				}
+			}
+		})
+	}
+}
+
+// loadCallTracerTest reads and parses a call tracer test case from disk.
+func loadCallTracerTest(t testing.TB, name string) *callTracerTest {
+	blob, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("failed to read testcase: %v", err)
+	}
+	test := new(callTracerTest)
+	if err := json.Unmarshal(blob, test); err != nil {
+		t.Fatalf("failed to parse testcase: %v", err)
+	}
+	return test
+}
+
+// runCallTracerTest configures a blockchain with the prestate of the given test,
+// executes its transaction with the tracer attached and returns the result.
+func runCallTracerTest(test *callTracerTest, tracer Interface) (json.RawMessage, error) {
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(common.FromHex(test.Input), tx); err != nil {
+		return nil, fmt.Errorf("failed to parse testcase input: %v", err)
+	}
+	signer := types.MakeSigner(test.Genesis.Config, new(big.Int).SetUint64(uint64(test.Context.Number)))
+	origin, _ := signer.Sender(tx)
+
+	context := vm.Context{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		Origin:      origin,
+		Coinbase:    test.Context.Miner,
+		BlockNumber: new(big.Int).SetUint64(uint64(test.Context.Number)),
+		Time:        new(big.Int).SetUint64(uint64(test.Context.Time)),
+		Difficulty:  (*big.Int)(test.Context.Difficulty),
+		GasLimit:    uint64(test.Context.GasLimit),
+		GasPrice:    tx.GasPrice(),
+	}
+	statedb := tests.MakePreState(ethdb.NewMemDatabase(), test.Genesis.Alloc)
+
+	// Create the EVM environment and run the transaction
+	evm := vm.NewEVM(context, statedb, test.Genesis.Config, vm.Config{Debug: true, Tracer: tracer})
+
+	msg, err := tx.AsMessage(signer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare transaction for tracing: %v", err)
+	}
+	st := core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(tx.Gas()))
+	if _, _, _, err = st.TransitionDb(); err != nil {
+		return nil, fmt.Errorf("failed to execute transaction: %v", err)
+	}
+	return tracer.GetResult()
+}
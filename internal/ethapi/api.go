@@ -28,6 +28,7 @@ import (
 	"github.com/davecgh/go-spew/spew"
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/accounts/typeddata"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/common/math"
@@ -1348,6 +1349,47 @@ func (s *PublicTransactionPoolAPI) Sign(addr common.Address, data hexutil.Bytes)
 	return signature, err
 }
 
+// SignTypedData calculates an ECDSA signature for EIP-712 typed structured data:
+// keccak256("\x19\x01" ‖ domainSeparator ‖ hashStruct(message)).
+//
+// Note, the produced signature conforms to the secp256k1 curve R, S and V values,
+// where the V value will be 27 or 28 for legacy reasons.
+//
+// The account associated with addr must be unlocked, or reside on a hardware
+// wallet able to sign typed data.
+//
+// https://eips.ethereum.org/EIPS/eip-712
+func (s *PublicTransactionPoolAPI) SignTypedData(addr common.Address, data typeddata.TypedData) (hexutil.Bytes, error) {
+	domainSeparator, structHash, err := data.Hashes()
+	if err != nil {
+		return nil, err
+	}
+	// Refuse signing for a domain bound to a different chain
+	if id := data.Domain.ChainId; id != nil {
+		if chainID := s.b.ChainConfig().ChainID; chainID != nil && id.Cmp(chainID) != 0 {
+			return nil, fmt.Errorf("typed data domain is bound to chain %v, not %v", id, chainID)
+		}
+	}
+	// Look up the wallet containing the requested signer
+	account := accounts.Account{Address: addr}
+
+	wallet, err := s.b.AccountManager().Find(account)
+	if err != nil {
+		return nil, err
+	}
+	// Sign the typed data, letting hardware wallets display the hashes themselves
+	var signature []byte
+	if signer, ok := wallet.(accounts.TypedHashSigner); ok {
+		signature, err = signer.SignTypedHash(account, domainSeparator.Bytes(), structHash.Bytes())
+	} else {
+		signature, err = wallet.SignHash(account, typeddata.SigHash(domainSeparator, structHash))
+	}
+	if err == nil {
+		signature[64] += 27 // Transform V from 0/1 to 27/28 according to the yellow paper
+	}
+	return signature, err
+}
+
 // SignTransactionResult represents a RLP encoded signed transaction.
 type SignTransactionResult struct {
 	Raw hexutil.Bytes      `json:"raw"`
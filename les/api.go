@@ -0,0 +1,163 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	errNoPriorityPool    = errors.New("priority client pool not running")
+	errNoCheckpoint      = errors.New("no local checkpoint available")
+	errNotActivated      = errors.New("checkpoint oracle is not activated")
+	errUnknownCheckpoint = errors.New("checkpoint not generated locally")
+)
+
+// PrivateLightServerAPI provides an API to manage the priority clients of a
+// light server.
+type PrivateLightServerAPI struct {
+	server *LesServer
+}
+
+// NewPrivateLightServerAPI creates a new LES server API.
+func NewPrivateLightServerAPI(server *LesServer) *PrivateLightServerAPI {
+	return &PrivateLightServerAPI{server: server}
+}
+
+// pool returns the priority client pool of the server, if running.
+func (api *PrivateLightServerAPI) pool() (*priorityClientPool, error) {
+	if pool := api.server.protocolManager.priorityPool; pool != nil {
+		return pool, nil
+	}
+	return nil, errNoPriorityPool
+}
+
+// TotalCapacity returns the total capacity of the server, shared by the priority
+// and free clients.
+func (api *PrivateLightServerAPI) TotalCapacity() (hexutil.Uint64, error) {
+	pool, err := api.pool()
+	if err != nil {
+		return 0, err
+	}
+	return hexutil.Uint64(pool.totalCap), nil
+}
+
+// FreeCapacity returns the capacity currently left for free clients.
+func (api *PrivateLightServerAPI) FreeCapacity() (hexutil.Uint64, error) {
+	pool, err := api.pool()
+	if err != nil {
+		return 0, err
+	}
+	return hexutil.Uint64(pool.freeCapacity()), nil
+}
+
+// SetClientCapacity assigns the capacity of a priority client. A zero capacity
+// revokes the priority status of the client.
+func (api *PrivateLightServerAPI) SetClientCapacity(id enode.ID, capacity hexutil.Uint64) error {
+	pool, err := api.pool()
+	if err != nil {
+		return err
+	}
+	return pool.setCapacity(id, uint64(capacity))
+}
+
+// AddBalance adds the given amount to the token balance of a client, or deducts
+// it if negative, returning the new balance.
+func (api *PrivateLightServerAPI) AddBalance(id enode.ID, amount int64) (hexutil.Uint64, error) {
+	pool, err := api.pool()
+	if err != nil {
+		return 0, err
+	}
+	balance, err := pool.addBalance(id, amount)
+	return hexutil.Uint64(balance), err
+}
+
+// ClientInfo returns the capacity, balance and connection status of a priority
+// client.
+func (api *PrivateLightServerAPI) ClientInfo(id enode.ID) (*PriorityClientInfo, error) {
+	pool, err := api.pool()
+	if err != nil {
+		return nil, err
+	}
+	return pool.info(id)
+}
+
+// PriorityClients returns the capacity, balance and connection status of all
+// the known priority clients.
+func (api *PrivateLightServerAPI) PriorityClients() (map[enode.ID]*PriorityClientInfo, error) {
+	pool, err := api.pool()
+	if err != nil {
+		return nil, err
+	}
+	return pool.clientList(), nil
+}
+
+// PrivateLightAPI provides an API to access the checkpoints generated by the
+// local indexers and the ones registered in the checkpoint oracle.
+type PrivateLightAPI struct {
+	backend *lesCommons
+}
+
+// NewPrivateLightAPI creates a new LES service API.
+func NewPrivateLightAPI(backend *lesCommons) *PrivateLightAPI {
+	return &PrivateLightAPI{backend: backend}
+}
+
+// LatestCheckpoint returns the latest local checkpoint package.
+//
+// The checkpoint package consists of 4 strings:
+//
+//	result[0], hex encoded latest section index
+//	result[1], 32 bytes hex encoded latest section head hash
+//	result[2], 32 bytes hex encoded latest section canonical hash trie root hash
+//	result[3], 32 bytes hex encoded latest section bloom trie root hash
+func (api *PrivateLightAPI) LatestCheckpoint() ([4]string, error) {
+	var res [4]string
+	sections := api.backend.localSections()
+	if sections == 0 {
+		return res, errNoCheckpoint
+	}
+	cp := api.backend.getLocalCheckpoint(sections - 1)
+	res[0] = hexutil.EncodeUint64(cp.SectionIndex)
+	res[1], res[2], res[3] = cp.SectionHead.Hex(), cp.CHTRoot.Hex(), cp.BloomRoot.Hex()
+	return res, nil
+}
+
+// GetCheckpoint returns the local checkpoint of the given section index.
+func (api *PrivateLightAPI) GetCheckpoint(index uint64) (*params.TrustedCheckpoint, error) {
+	if index >= api.backend.localSections() {
+		return nil, errUnknownCheckpoint
+	}
+	cp := api.backend.getLocalCheckpoint(index)
+	if cp.Empty() {
+		return nil, errUnknownCheckpoint
+	}
+	return &cp, nil
+}
+
+// GetCheckpointContractAddress returns the address of the checkpoint oracle.
+func (api *PrivateLightAPI) GetCheckpointContractAddress() (common.Address, error) {
+	if api.backend.oracle == nil {
+		return common.Address{}, errNotActivated
+	}
+	return api.backend.oracle.config.Address, nil
+}
@@ -0,0 +1,111 @@
+// Copyright 2026 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/contracts/checkpointoracle"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// checkpointOracle is responsible for offering the latest stable checkpoint
+// registered in the on-chain checkpoint oracle by the trusted signers.
+//
+// Servers advertise the registered checkpoint to their clients if it matches the
+// one generated by their local indexers. Clients accept an advertised checkpoint
+// once they find it registered in the oracle.
+type checkpointOracle struct {
+	config   *params.CheckpointOracleConfig
+	contract *checkpointoracle.CheckpointOracle
+
+	running  int32                                 // Flag whether the contract backend is set
+	getLocal func(uint64) params.TrustedCheckpoint // Function used to retrieve a local checkpoint
+}
+
+// newCheckpointOracle creates a checkpoint oracle handler with the given config
+// and local checkpoint retrieval function. A nil config disables the oracle.
+func newCheckpointOracle(config *params.CheckpointOracleConfig, getLocal func(uint64) params.TrustedCheckpoint) *checkpointOracle {
+	if config == nil {
+		log.Info("Checkpoint oracle is not enabled")
+		return nil
+	}
+	if config.Address == (common.Address{}) || uint64(len(config.Signers)) < config.Threshold {
+		log.Warn("Invalid checkpoint oracle config")
+		return nil
+	}
+	log.Info("Configured checkpoint oracle", "address", config.Address, "signers", len(config.Signers), "threshold", config.Threshold)
+	return &checkpointOracle{
+		config:   config,
+		getLocal: getLocal,
+	}
+}
+
+// start binds the oracle contract to the given backend, after which the oracle
+// can be queried.
+func (reg *checkpointOracle) start(backend bind.ContractBackend) {
+	contract, err := checkpointoracle.NewCheckpointOracle(reg.config.Address, backend)
+	if err != nil {
+		log.Error("Failed to bind checkpoint oracle", "err", err)
+		return
+	}
+	reg.contract = contract
+	atomic.StoreInt32(&reg.running, 1)
+}
+
+// isRunning returns whether the oracle contract is bound.
+func (reg *checkpointOracle) isRunning() bool {
+	return atomic.LoadInt32(&reg.running) == 1
+}
+
+// latestCheckpoint returns the section index and hash of the latest checkpoint
+// registered in the oracle, along with the block height of the registration.
+func (reg *checkpointOracle) latestCheckpoint() (uint64, common.Hash, uint64, bool) {
+	if !reg.isRunning() {
+		return 0, common.Hash{}, 0, false
+	}
+	index, hash, height, err := reg.contract.Contract().GetLatestCheckpoint(nil)
+	if err != nil || (index == 0 && hash == [32]byte{}) {
+		return 0, common.Hash{}, 0, false
+	}
+	return index, common.Hash(hash), height.Uint64(), true
+}
+
+// stableCheckpoint returns the latest registered checkpoint if it matches the
+// one generated by the local indexers.
+func (reg *checkpointOracle) stableCheckpoint() (*params.TrustedCheckpoint, uint64) {
+	index, hash, height, ok := reg.latestCheckpoint()
+	if !ok {
+		return nil, 0
+	}
+	local := reg.getLocal(index)
+	if local.Empty() || local.Hash() != hash {
+		log.Debug("Registered checkpoint not generated locally", "index", index, "hash", hash)
+		return nil, 0
+	}
+	return &local, height
+}
+
+// verifyCheckpoint returns whether the given checkpoint is the latest one
+// registered in the oracle.
+func (reg *checkpointOracle) verifyCheckpoint(cp *params.TrustedCheckpoint) bool {
+	index, hash, _, ok := reg.latestCheckpoint()
+	return ok && !cp.Empty() && cp.SectionIndex == index && cp.Hash() == hash
+}
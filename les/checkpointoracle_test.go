@@ -0,0 +1,100 @@
+// Copyright 2026 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/contracts/checkpointoracle"
+	"github.com/ethereum/go-ethereum/contracts/checkpointoracle/contract"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestCheckpointOracle(t *testing.T) {
+	// Deploy an oracle administered by a single signer
+	key, _ := crypto.GenerateKey()
+	signer := crypto.PubkeyToAddress(key.PublicKey)
+	alloc := core.GenesisAlloc{signer: {Balance: big.NewInt(1000000000000000000)}}
+	genesis := core.Genesis{Config: params.ReputationnetChainConfig, GasLimit: 10000000, Alloc: alloc}
+	backend := backends.NewSimulatedBackend(alloc, 10000000)
+
+	addr, _, _, err := contract.DeployCheckpointOracle(bind.NewKeyedTransactor(key), backend, []common.Address{signer}, big.NewInt(8), big.NewInt(2), big.NewInt(1))
+	if err != nil {
+		t.Fatalf("failed to deploy oracle: %v", err)
+	}
+	for i := 0; i < 11; i++ {
+		backend.Commit()
+	}
+	// Create the oracle handler, serving a single local checkpoint
+	local := params.TrustedCheckpoint{
+		SectionIndex: 0,
+		SectionHead:  common.Hash{1},
+		CHTRoot:      common.Hash{2},
+		BloomRoot:    common.Hash{3},
+	}
+	getLocal := func(index uint64) params.TrustedCheckpoint {
+		if index != local.SectionIndex {
+			return params.TrustedCheckpoint{}
+		}
+		return local
+	}
+	if oracle := newCheckpointOracle(&params.CheckpointOracleConfig{Address: addr, Signers: []common.Address{signer}, Threshold: 2}, getLocal); oracle != nil {
+		t.Fatalf("oracle created with unreachable threshold")
+	}
+	oracle := newCheckpointOracle(&params.CheckpointOracleConfig{Address: addr, Signers: []common.Address{signer}, Threshold: 1}, getLocal)
+	if oracle.isRunning() {
+		t.Fatalf("oracle running without contract backend")
+	}
+	oracle.start(backend)
+	if cp, _ := oracle.stableCheckpoint(); cp != nil {
+		t.Fatalf("stable checkpoint found in empty oracle: %v", cp)
+	}
+	// Register the local checkpoint and ensure it's advertised and accepted
+	sig, _ := crypto.Sign(checkpointoracle.SigningHash(addr, local.SectionIndex, local.Hash()).Bytes(), key)
+	sig[64] += 27
+
+	opts := bind.NewKeyedTransactor(key)
+	opts.GasLimit = 1000000
+	if _, err := oracle.contract.RegisterCheckpoint(opts, local.SectionIndex, local.Hash(), common.Big0, genesis.ToBlock(nil).Hash(), [][]byte{sig}); err != nil {
+		t.Fatalf("failed to register checkpoint: %v", err)
+	}
+	backend.Commit()
+
+	cp, _ := oracle.stableCheckpoint()
+	if cp == nil || *cp != local {
+		t.Fatalf("stable checkpoint mismatch: have %v, want %v", cp, local)
+	}
+	if !oracle.verifyCheckpoint(cp) {
+		t.Fatalf("registered checkpoint rejected")
+	}
+	forged := local
+	forged.CHTRoot = common.Hash{0xff}
+	if oracle.verifyCheckpoint(&forged) {
+		t.Fatalf("forged checkpoint accepted")
+	}
+	// Servers don't advertise registered checkpoints they didn't generate
+	local.BloomRoot = common.Hash{0xff}
+	if cp, _ := oracle.stableCheckpoint(); cp != nil {
+		t.Fatalf("foreign checkpoint advertised: %v", cp)
+	}
+}
@@ -29,12 +29,14 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/light"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p"
 )
 
 const (
 	blockDelayTimeout    = time.Second * 10 // timeout for a peer to announce a head that has already been confirmed by others
 	maxNodeCount         = 20               // maximum number of fetcherTreeNode entries remembered for each peer
 	serverStateAvailable = 100              // number of recent blocks where state availability is assumed
+	ulcCheckFrequency    = 100              // one in how many headers the seal is verified in ultra light mode
 )
 
 // lightFetcher implements retrieval of newly announced headers. It also provides a peerHasBlock function for the
@@ -182,7 +184,7 @@ func (f *lightFetcher) syncLoop() {
 			if ok {
 				f.pm.serverPool.adjustResponseTime(req.peer.poolEntry, time.Duration(mclock.Now()-req.sent), true)
 				req.peer.Log().Debug("Fetching data timed out hard")
-				go f.pm.removePeer(req.peer.id)
+				go f.pm.penalizePeer(req.peer.id, p2p.RequestTimeout)
 			}
 		case resp := <-f.deliverChn:
 			f.reqMu.Lock()
@@ -200,7 +202,7 @@ func (f *lightFetcher) syncLoop() {
 			f.lock.Lock()
 			if !ok || !(f.syncing || f.processResponse(req, resp)) {
 				resp.peer.Log().Debug("Failed processing response")
-				go f.pm.removePeer(resp.peer.id)
+				go f.pm.penalizePeer(resp.peer.id, p2p.UselessResponse)
 			}
 			f.lock.Unlock()
 		case p := <-f.syncDone:
@@ -257,7 +259,7 @@ func (f *lightFetcher) announce(p *peer, head *announceData) {
 	if fp.lastAnnounced != nil && head.Td.Cmp(fp.lastAnnounced.td) <= 0 {
 		// announced tds should be strictly monotonic
 		p.Log().Debug("Received non-monotonic td", "current", head.Td, "previous", fp.lastAnnounced.td)
-		go f.pm.removePeer(p.id)
+		go f.pm.penalizePeer(p.id, p2p.InvalidMessage)
 		return
 	}
 
@@ -415,6 +417,9 @@ func (f *lightFetcher) nextRequest() (*distReq, uint64) {
 
 	for p, fp := range f.peers {
 		for hash, n := range fp.nodeByHash {
+			if !f.isTrustedHash(hash) {
+				continue
+			}
 			if !f.checkKnownNode(p, n) && !n.requested && (bestTd == nil || n.td.Cmp(bestTd) >= 0) {
 				amount := f.requestAmount(p, n)
 				if bestTd == nil || n.td.Cmp(bestTd) > 0 || amount < bestAmount {
@@ -503,6 +508,22 @@ func (f *lightFetcher) nextRequest() (*distReq, uint64) {
 	return rq, reqID
 }
 
+// isTrustedHash returns whether a head announced with the given hash can be
+// downloaded. In ultra light mode a head is only accepted once enough trusted
+// servers have announced it, otherwise every announced head is acceptable.
+func (f *lightFetcher) isTrustedHash(hash common.Hash) bool {
+	if f.pm.ulc == nil {
+		return true
+	}
+	agreed := 0
+	for p, fp := range f.peers {
+		if p.isTrusted && fp.nodeByHash[hash] != nil {
+			agreed++
+		}
+	}
+	return f.pm.ulc.quorum(agreed)
+}
+
 // deliverHeaders delivers header download request responses for processing
 func (f *lightFetcher) deliverHeaders(peer *peer, reqID uint64, headers []*types.Header) {
 	f.deliverChn <- fetchResponse{reqID: reqID, headers: headers, peer: peer}
@@ -518,7 +539,13 @@ func (f *lightFetcher) processResponse(req fetchRequest, resp fetchResponse) boo
 	for i, header := range resp.headers {
 		headers[int(req.amount)-1-i] = header
 	}
-	if _, err := f.chain.InsertHeaderChain(headers, 1); err != nil {
+	// In ultra light mode the head has been vouched for by the trusted servers,
+	// only verify the seals of a sample of the headers
+	checkFreq := 1
+	if f.pm.ulc != nil {
+		checkFreq = ulcCheckFrequency
+	}
+	if _, err := f.chain.InsertHeaderChain(headers, checkFreq); err != nil {
 		if err == consensus.ErrFutureBlock {
 			return true
 		}
@@ -545,7 +572,7 @@ func (f *lightFetcher) newHeaders(headers []*types.Header, tds []*big.Int) {
 	for p, fp := range f.peers {
 		if !f.checkAnnouncedHeaders(fp, headers, tds) {
 			p.Log().Debug("Inconsistent announcement")
-			go f.pm.removePeer(p.id)
+			go f.pm.penalizePeer(p.id, p2p.InvalidMessage)
 		}
 		if fp.confirmedTd != nil && (maxTd == nil || maxTd.Cmp(fp.confirmedTd) > 0) {
 			maxTd = fp.confirmedTd
@@ -680,7 +707,7 @@ func (f *lightFetcher) checkKnownNode(p *peer, n *fetcherTreeNode) bool {
 	}
 	if !f.checkAnnouncedHeaders(fp, []*types.Header{header}, []*big.Int{td}) {
 		p.Log().Debug("Inconsistent announcement")
-		go f.pm.removePeer(p.id)
+		go f.pm.penalizePeer(p.id, p2p.InvalidMessage)
 	}
 	if fp.confirmedTd != nil {
 		f.updateMaxConfirmedTd(fp.confirmedTd)
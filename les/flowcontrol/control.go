@@ -54,6 +54,25 @@ func (peer *ClientNode) Remove(cm *ClientManager) {
 	cm.removeNode(peer.cmNode)
 }
 
+// Params returns the current flow control parameters of the client.
+func (peer *ClientNode) Params() *ServerParams {
+	peer.lock.Lock()
+	defer peer.lock.Unlock()
+
+	return peer.params
+}
+
+// UpdateParams changes the flow control parameters of the client, adjusting its
+// share of the server's recharge capacity accordingly.
+func (peer *ClientNode) UpdateParams(params *ServerParams) {
+	peer.lock.Lock()
+	defer peer.lock.Unlock()
+
+	peer.recalcBV(mclock.Now())
+	peer.params = params
+	peer.cm.setWeight(peer.cmNode, params.MinRecharge)
+}
+
 func (peer *ClientNode) recalcBV(time mclock.AbsTime) {
 	dt := uint64(time - peer.lastTime)
 	if time < peer.lastTime {
@@ -123,6 +142,19 @@ func (peer *ServerNode) recalcBLE(time mclock.AbsTime) {
 	peer.lastTime = time
 }
 
+// UpdateParams changes the flow control parameters announced by the server. The
+// estimated buffer value is capped by the new buffer limit.
+func (peer *ServerNode) UpdateParams(params *ServerParams) {
+	peer.lock.Lock()
+	defer peer.lock.Unlock()
+
+	peer.recalcBLE(mclock.Now())
+	peer.params = params
+	if peer.bufEstimate > params.BufLimit {
+		peer.bufEstimate = params.BufLimit
+	}
+}
+
 // safetyMargin is added to the flow control waiting time when estimated buffer value is low
 const safetyMargin = time.Millisecond
 
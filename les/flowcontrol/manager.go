@@ -104,7 +104,7 @@ func (self *ClientManager) addNode(cnode *ClientNode) *cmNode {
 		node:           cnode,
 		lastUpdate:     time,
 		finishRecharge: time,
-		rcWeight:       1,
+		rcWeight:       cnode.params.MinRecharge,
 	}
 	self.lock.Lock()
 	defer self.lock.Unlock()
@@ -124,6 +124,26 @@ func (self *ClientManager) removeNode(node *cmNode) {
 	self.update(time)
 }
 
+// setWeight changes the share of a node from the total recharge capacity of
+// the server. Nodes with a higher minimum recharge rate get a larger share.
+func (self *ClientManager) setWeight(node *cmNode, weight uint64) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	time := mclock.Now()
+	self.update(time)
+	if node.recharging {
+		self.sumWeight += weight - node.rcWeight
+	}
+	node.rcWeight = weight
+	for n := range self.nodes {
+		if n.recharging {
+			n.set(n.serving, self.simReqCnt, self.sumWeight)
+		}
+	}
+	self.update(time)
+}
+
 // recalc sumWeight
 func (self *ClientManager) updateNodes(time mclock.AbsTime) (rce bool) {
 	var sumWeight, rcSum uint64
@@ -33,6 +33,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
@@ -89,21 +90,24 @@ type txPool interface {
 }
 
 type ProtocolManager struct {
-	lightSync   bool
-	txpool      txPool
-	txrelay     *LesTxRelay
-	networkId   uint64
-	chainConfig *params.ChainConfig
-	iConfig     *light.IndexerConfig
-	blockchain  BlockChain
-	chainDb     ethdb.Database
-	odr         *LesOdr
-	server      *LesServer
-	serverPool  *serverPool
-	clientPool  *freeClientPool
-	lesTopic    discv5.Topic
-	reqDist     *requestDistributor
-	retriever   *retrieveManager
+	lightSync    bool
+	txpool       txPool
+	txrelay      *LesTxRelay
+	networkId    uint64
+	chainConfig  *params.ChainConfig
+	iConfig      *light.IndexerConfig
+	blockchain   BlockChain
+	chainDb      ethdb.Database
+	odr          *LesOdr
+	server       *LesServer
+	serverPool   *serverPool
+	clientPool   *freeClientPool
+	priorityPool *priorityClientPool
+	lesTopic     discv5.Topic
+	reqDist      *requestDistributor
+	retriever    *retrieveManager
+	ulc          *ulc              // Ultra light client mode state, nil if disabled
+	oracle       *checkpointOracle // Checkpoint oracle of light clients, nil if disabled
 
 	downloader *downloader.Downloader
 	fetcher    *lightFetcher
@@ -124,7 +128,7 @@ type ProtocolManager struct {
 
 // NewProtocolManager returns a new ethereum sub protocol manager. The Ethereum sub protocol manages peers capable
 // with the ethereum network.
-func NewProtocolManager(chainConfig *params.ChainConfig, indexerConfig *light.IndexerConfig, lightSync bool, networkId uint64, mux *event.TypeMux, engine consensus.Engine, peers *peerSet, blockchain BlockChain, txpool txPool, chainDb ethdb.Database, odr *LesOdr, txrelay *LesTxRelay, serverPool *serverPool, quitSync chan struct{}, wg *sync.WaitGroup) (*ProtocolManager, error) {
+func NewProtocolManager(chainConfig *params.ChainConfig, indexerConfig *light.IndexerConfig, lightSync bool, networkId uint64, mux *event.TypeMux, engine consensus.Engine, peers *peerSet, blockchain BlockChain, txpool txPool, chainDb ethdb.Database, odr *LesOdr, txrelay *LesTxRelay, serverPool *serverPool, quitSync chan struct{}, wg *sync.WaitGroup, ulcConfig *eth.ULCConfig) (*ProtocolManager, error) {
 	// Create the protocol manager with the base fields
 	manager := &ProtocolManager{
 		lightSync:   lightSync,
@@ -144,14 +148,20 @@ func NewProtocolManager(chainConfig *params.ChainConfig, indexerConfig *light.In
 		wg:          wg,
 		noMorePeers: make(chan struct{}),
 	}
+	ulc, err := newULC(ulcConfig)
+	if err != nil {
+		return nil, err
+	}
+	manager.ulc = ulc
+
 	if odr != nil {
 		manager.retriever = odr.retriever
 		manager.reqDist = odr.retriever.dist
 	}
 
-	removePeer := manager.removePeer
+	removePeer := manager.penalizePeer
 	if disableClientRemovePeer {
-		removePeer = func(id string) {}
+		removePeer = func(id string, m p2p.Misbehaviour) {}
 	}
 
 	if lightSync {
@@ -168,6 +178,15 @@ func (pm *ProtocolManager) removePeer(id string) {
 	pm.peers.Unregister(id)
 }
 
+// penalizePeer reports the misbehaviour of a peer to the p2p layer before
+// removing it from the peer set
+func (pm *ProtocolManager) penalizePeer(id string, m p2p.Misbehaviour) {
+	if peer := pm.peers.Peer(id); peer != nil {
+		peer.Report(m)
+	}
+	pm.removePeer(id)
+}
+
 func (pm *ProtocolManager) Start(maxPeers int) {
 	pm.maxPeers = maxPeers
 
@@ -175,6 +194,10 @@ func (pm *ProtocolManager) Start(maxPeers int) {
 		go pm.syncer()
 	} else {
 		pm.clientPool = newFreeClientPool(pm.chainDb, maxPeers, 10000, mclock.System{})
+		if pm.server != nil {
+			freeCap := pm.server.defParams.MinRecharge
+			pm.priorityPool = newPriorityClientPool(pm.chainDb, uint64(maxPeers)*freeCap, freeCap, pm.clientPool)
+		}
 		go func() {
 			for range pm.newPeerCh {
 			}
@@ -195,6 +218,9 @@ func (pm *ProtocolManager) Stop() {
 	if pm.clientPool != nil {
 		pm.clientPool.stop()
 	}
+	if pm.priorityPool != nil {
+		pm.priorityPool.stop()
+	}
 
 	// Disconnect existing sessions.
 	// This also closes the gate for any new registrations on the peer set.
@@ -234,7 +260,9 @@ func (pm *ProtocolManager) runPeer(version uint, p *p2p.Peer, rw p2p.MsgReadWrit
 }
 
 func (pm *ProtocolManager) newPeer(pv int, nv uint64, p *p2p.Peer, rw p2p.MsgReadWriter) *peer {
-	return newPeer(pv, nv, p, newMeteredMsgWriter(rw))
+	peer := newPeer(pv, nv, p, newMeteredMsgWriter(rw))
+	peer.isTrusted = pm.ulc != nil && pm.ulc.isTrusted(p.ID())
+	return peer
 }
 
 // handle is the callback invoked to manage the life cycle of a les peer. When
@@ -256,21 +284,54 @@ func (pm *ProtocolManager) handle(p *peer) error {
 		number  = head.Number.Uint64()
 		td      = pm.blockchain.GetTd(hash, number)
 	)
+	// Priority clients are granted their assigned capacity
+	var priorityCap uint64
+	if pm.priorityPool != nil {
+		if capacity, ok := pm.priorityPool.capacity(p.ID()); ok {
+			priorityCap = capacity
+			p.fcParams = pm.server.priorityParams(capacity)
+		}
+	}
 	if err := p.Handshake(td, hash, number, genesis.Hash(), pm.server); err != nil {
 		p.Log().Debug("Light Ethereum handshake failed", "err", err)
 		return err
 	}
 
-	if !pm.lightSync && !p.Peer.Info().Network.Trusted {
-		addr, ok := p.RemoteAddr().(*net.TCPAddr)
-		// test peer address is not a tcp address, don't use client pool if can not typecast
-		if ok {
-			id := addr.IP.String()
-			if !pm.clientPool.connect(id, func() { go pm.removePeer(p.id) }) {
-				return p2p.DiscTooManyPeers
+	freeID, useFreePool := pm.freeClientID(p)
+	if priorityCap != 0 {
+		// Downgraded priority clients are checked into the free client pool. The
+		// flag is set under the lock of the priority pool, so it can be read once
+		// the client was disconnected from there.
+		var downgraded bool
+		downgradeFn := func() {
+			if useFreePool && !pm.clientPool.connect(freeID, func() { go pm.removePeer(p.id) }) {
+				go pm.removePeer(p.id)
+				return
+			}
+			downgraded = true
+			if !p.updateClientParams(pm.server.defParams) {
+				go pm.removePeer(p.id)
 			}
-			defer pm.clientPool.disconnect(id)
 		}
+		updateFn := func(capacity uint64) {
+			if !p.updateClientParams(pm.server.priorityParams(capacity)) {
+				go pm.removePeer(p.id)
+			}
+		}
+		if !pm.priorityPool.connect(p.ID(), priorityCap, downgradeFn, updateFn) {
+			return p2p.DiscTooManyPeers
+		}
+		defer func() {
+			pm.priorityPool.disconnect(p.ID())
+			if downgraded && useFreePool {
+				pm.clientPool.disconnect(freeID)
+			}
+		}()
+	} else if useFreePool {
+		if !pm.clientPool.connect(freeID, func() { go pm.removePeer(p.id) }) {
+			return p2p.DiscTooManyPeers
+		}
+		defer pm.clientPool.disconnect(freeID)
 	}
 
 	if rw, ok := p.rw.(*meteredMsgReadWriter); ok {
@@ -324,6 +385,30 @@ func (pm *ProtocolManager) handle(p *peer) error {
 	}
 }
 
+// freeClientID returns the identifier of a client in the free client pool and
+// whether the pool limits its connection at all.
+func (pm *ProtocolManager) freeClientID(p *peer) (string, bool) {
+	if pm.lightSync || p.Peer.Info().Network.Trusted {
+		return "", false
+	}
+	// test peer address is not a tcp address, don't use client pool if can not typecast
+	addr, ok := p.RemoteAddr().(*net.TCPAddr)
+	if !ok {
+		return "", false
+	}
+	return addr.IP.String(), true
+}
+
+// requestProcessed updates the flow control buffer of a client after serving a
+// request, debiting the cost from its balance if it's a priority client.
+func (pm *ProtocolManager) requestProcessed(p *peer, cost uint64) (bv, realCost uint64) {
+	bv, realCost = p.fcClient.RequestProcessed(cost)
+	if pm.priorityPool != nil {
+		pm.priorityPool.charge(p.ID(), cost)
+	}
+	return bv, realCost
+}
+
 var reqList = []uint64{GetBlockHeadersMsg, GetBlockBodiesMsg, GetCodeMsg, GetReceiptsMsg, GetProofsV1Msg, SendTxMsg, SendTxV2Msg, GetTxStatusMsg, GetHeaderProofsMsg, GetProofsV2Msg, GetHelperTrieProofsMsg}
 
 // handleMsg is invoked whenever an inbound message is received from a remote
@@ -342,12 +427,13 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			return true
 		}
 		bufValue, _ := p.fcClient.AcceptRequest()
+		params := p.fcClient.Params()
 		cost := costs.baseCost + reqCnt*costs.reqCost
-		if cost > pm.server.defParams.BufLimit {
-			cost = pm.server.defParams.BufLimit
+		if cost > params.BufLimit {
+			cost = params.BufLimit
 		}
 		if cost > bufValue {
-			recharge := time.Duration((cost - bufValue) * 1000000 / pm.server.defParams.MinRecharge)
+			recharge := time.Duration((cost - bufValue) * 1000000 / params.MinRecharge)
 			p.Log().Error("Request came too early", "recharge", common.PrettyDuration(recharge))
 			return true
 		}
@@ -379,6 +465,17 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		if err := msg.Decode(&req); err != nil {
 			return errResp(ErrDecode, "%v: %v", msg, err)
 		}
+		// Announcements without a block carry updated flow control parameters
+		if req.Hash == (common.Hash{}) {
+			if p.fcServer == nil {
+				return errResp(ErrUnexpectedResponse, "")
+			}
+			if err := p.updateServerParams(req.Update.decode()); err != nil {
+				return errResp(ErrInvalidResponse, "%v", err)
+			}
+			p.Log().Debug("Flow control parameters updated", "bl", p.fcServerParams.BufLimit, "mrr", p.fcServerParams.MinRecharge)
+			break
+		}
 
 		if p.requestAnnounceType == announceTypeSigned {
 			if err := req.checkSignature(p.ID()); err != nil {
@@ -489,7 +586,7 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			}
 		}
 
-		bv, rcost := p.fcClient.RequestProcessed(costs.baseCost + query.Amount*costs.reqCost)
+		bv, rcost := pm.requestProcessed(p, costs.baseCost+query.Amount*costs.reqCost)
 		pm.server.fcCostStats.update(msg.Code, query.Amount, rcost)
 		return p.SendBlockHeaders(req.ReqID, bv, headers)
 
@@ -548,7 +645,7 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 				}
 			}
 		}
-		bv, rcost := p.fcClient.RequestProcessed(costs.baseCost + uint64(reqCnt)*costs.reqCost)
+		bv, rcost := pm.requestProcessed(p, costs.baseCost+uint64(reqCnt)*costs.reqCost)
 		pm.server.fcCostStats.update(msg.Code, uint64(reqCnt), rcost)
 		return p.SendBlockBodiesRLP(req.ReqID, bv, bodies)
 
@@ -613,7 +710,7 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 				}
 			}
 		}
-		bv, rcost := p.fcClient.RequestProcessed(costs.baseCost + uint64(reqCnt)*costs.reqCost)
+		bv, rcost := pm.requestProcessed(p, costs.baseCost+uint64(reqCnt)*costs.reqCost)
 		pm.server.fcCostStats.update(msg.Code, uint64(reqCnt), rcost)
 		return p.SendCode(req.ReqID, bv, data)
 
@@ -679,7 +776,7 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 				bytes += len(encoded)
 			}
 		}
-		bv, rcost := p.fcClient.RequestProcessed(costs.baseCost + uint64(reqCnt)*costs.reqCost)
+		bv, rcost := pm.requestProcessed(p, costs.baseCost+uint64(reqCnt)*costs.reqCost)
 		pm.server.fcCostStats.update(msg.Code, uint64(reqCnt), rcost)
 		return p.SendReceiptsRLP(req.ReqID, bv, receipts)
 
@@ -753,7 +850,7 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 				}
 			}
 		}
-		bv, rcost := p.fcClient.RequestProcessed(costs.baseCost + uint64(reqCnt)*costs.reqCost)
+		bv, rcost := pm.requestProcessed(p, costs.baseCost+uint64(reqCnt)*costs.reqCost)
 		pm.server.fcCostStats.update(msg.Code, uint64(reqCnt), rcost)
 		return p.SendProofs(req.ReqID, bv, proofs)
 
@@ -815,7 +912,7 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 				break
 			}
 		}
-		bv, rcost := p.fcClient.RequestProcessed(costs.baseCost + uint64(reqCnt)*costs.reqCost)
+		bv, rcost := pm.requestProcessed(p, costs.baseCost+uint64(reqCnt)*costs.reqCost)
 		pm.server.fcCostStats.update(msg.Code, uint64(reqCnt), rcost)
 		return p.SendProofsV2(req.ReqID, bv, nodes.NodeList())
 
@@ -902,7 +999,7 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 				}
 			}
 		}
-		bv, rcost := p.fcClient.RequestProcessed(costs.baseCost + uint64(reqCnt)*costs.reqCost)
+		bv, rcost := pm.requestProcessed(p, costs.baseCost+uint64(reqCnt)*costs.reqCost)
 		pm.server.fcCostStats.update(msg.Code, uint64(reqCnt), rcost)
 		return p.SendHeaderProofs(req.ReqID, bv, proofs)
 
@@ -963,7 +1060,7 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 				break
 			}
 		}
-		bv, rcost := p.fcClient.RequestProcessed(costs.baseCost + uint64(reqCnt)*costs.reqCost)
+		bv, rcost := pm.requestProcessed(p, costs.baseCost+uint64(reqCnt)*costs.reqCost)
 		pm.server.fcCostStats.update(msg.Code, uint64(reqCnt), rcost)
 		return p.SendHelperTrieProofs(req.ReqID, bv, HelperTrieResps{Proofs: nodes.NodeList(), AuxData: auxData})
 
@@ -1023,7 +1120,7 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		}
 		pm.txpool.AddRemotes(txs)
 
-		_, rcost := p.fcClient.RequestProcessed(costs.baseCost + uint64(reqCnt)*costs.reqCost)
+		_, rcost := pm.requestProcessed(p, costs.baseCost+uint64(reqCnt)*costs.reqCost)
 		pm.server.fcCostStats.update(msg.Code, uint64(reqCnt), rcost)
 
 	case SendTxV2Msg:
@@ -1058,7 +1155,7 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			}
 		}
 
-		bv, rcost := p.fcClient.RequestProcessed(costs.baseCost + uint64(reqCnt)*costs.reqCost)
+		bv, rcost := pm.requestProcessed(p, costs.baseCost+uint64(reqCnt)*costs.reqCost)
 		pm.server.fcCostStats.update(msg.Code, uint64(reqCnt), rcost)
 
 		return p.SendTxStatus(req.ReqID, bv, stats)
@@ -1079,7 +1176,7 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		if reject(uint64(reqCnt), MaxTxStatus) {
 			return errResp(ErrRequestRejected, "")
 		}
-		bv, rcost := p.fcClient.RequestProcessed(costs.baseCost + uint64(reqCnt)*costs.reqCost)
+		bv, rcost := pm.requestProcessed(p, costs.baseCost+uint64(reqCnt)*costs.reqCost)
 		pm.server.fcCostStats.update(msg.Code, uint64(reqCnt), rcost)
 
 		return p.SendTxStatus(req.ReqID, bv, pm.txStatus(req.Hashes))
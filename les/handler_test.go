@@ -31,6 +31,7 @@ import (
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/les/flowcontrol"
 	"github.com/ethereum/go-ethereum/light"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/params"
@@ -581,3 +582,25 @@ func TestTransactionStatusLes2(t *testing.T) {
 	test(tx1, false, txStatus{Status: core.TxStatusPending})
 	test(tx2, false, txStatus{Status: core.TxStatusPending})
 }
+
+// Tests that the flow control parameters granted to a client can be changed
+// while it's connected, the client applying the announced update.
+func TestUpdateFlowControlParams(t *testing.T) {
+	server, client, tearDown := newClientServerEnv(t, 0, 2, nil, true)
+	defer tearDown()
+
+	params := &flowcontrol.ServerParams{BufLimit: 3 * testBufLimit, MinRecharge: 3}
+	if !server.rPeer.updateClientParams(params) {
+		t.Fatalf("failed to queue the flow control update")
+	}
+	for i := 0; i < 20; i++ {
+		client.rPeer.lock.RLock()
+		have := *client.rPeer.fcServerParams
+		client.rPeer.lock.RUnlock()
+		if have == *params {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatalf("flow control parameters not updated on the client")
+}
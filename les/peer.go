@@ -30,6 +30,7 @@ import (
 	"github.com/ethereum/go-ethereum/les/flowcontrol"
 	"github.com/ethereum/go-ethereum/light"
 	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
 )
 
@@ -58,7 +59,10 @@ type peer struct {
 
 	announceType, requestAnnounceType uint64
 
-	id string
+	id        string
+	isTrusted bool // Trusted server of an ultra light client, announcing signed heads
+
+	checkpoint params.TrustedCheckpoint // Oracle registered checkpoint advertised by the server
 
 	headInfo *announceData
 	lock     sync.RWMutex
@@ -70,8 +74,9 @@ type peer struct {
 	hasBlock       func(common.Hash, uint64, bool) bool
 	responseErrors int
 
-	fcClient       *flowcontrol.ClientNode // nil if the peer is server only
-	fcServer       *flowcontrol.ServerNode // nil if the peer is client only
+	fcClient       *flowcontrol.ClientNode   // nil if the peer is server only
+	fcParams       *flowcontrol.ServerParams // flow control parameters granted to the client, server defaults if nil
+	fcServer       *flowcontrol.ServerNode   // nil if the peer is client only
 	fcServerParams *flowcontrol.ServerParams
 	fcCosts        requestCostTable
 }
@@ -184,6 +189,42 @@ func (p *peer) SendAnnounce(request announceData) error {
 	return p2p.Send(p.rw, AnnounceMsg, request)
 }
 
+// updateClientParams changes the flow control parameters granted to a client,
+// notifying it with an announcement carrying no block, only the new parameters.
+// False is returned if the announcement queue of the client is full.
+func (p *peer) updateClientParams(params *flowcontrol.ServerParams) bool {
+	p.lock.Lock()
+	p.fcParams = params
+	p.lock.Unlock()
+	p.fcClient.UpdateParams(params)
+
+	var update keyValueList
+	update = update.add("flowControl/BL", params.BufLimit)
+	update = update.add("flowControl/MRR", params.MinRecharge)
+	select {
+	case p.announceChn <- announceData{Update: update}:
+		return true
+	default:
+		return false
+	}
+}
+
+// updateServerParams applies the flow control parameters announced by a server.
+func (p *peer) updateServerParams(update keyValueMap) error {
+	params := &flowcontrol.ServerParams{}
+	if err := update.get("flowControl/BL", &params.BufLimit); err != nil {
+		return err
+	}
+	if err := update.get("flowControl/MRR", &params.MinRecharge); err != nil {
+		return err
+	}
+	p.lock.Lock()
+	p.fcServerParams = params
+	p.lock.Unlock()
+	p.fcServer.UpdateParams(params)
+	return nil
+}
+
 // SendBlockHeaders sends a batch of block headers to the remote peer.
 func (p *peer) SendBlockHeaders(reqID, bv uint64, headers []*types.Header) error {
 	return sendResponse(p.rw, BlockHeadersMsg, reqID, bv, headers)
@@ -401,17 +442,31 @@ func (p *peer) Handshake(td *big.Int, head common.Hash, headNum uint64, genesis
 	send = send.add("headNum", headNum)
 	send = send.add("genesisHash", genesis)
 	if server != nil {
+		if p.fcParams == nil {
+			p.fcParams = server.defParams
+		}
 		send = send.add("serveHeaders", nil)
 		send = send.add("serveChainSince", uint64(0))
 		send = send.add("serveStateSince", uint64(0))
 		send = send.add("txRelay", nil)
-		send = send.add("flowControl/BL", server.defParams.BufLimit)
-		send = send.add("flowControl/MRR", server.defParams.MinRecharge)
+		send = send.add("flowControl/BL", p.fcParams.BufLimit)
+		send = send.add("flowControl/MRR", p.fcParams.MinRecharge)
 		list := server.fcCostStats.getCurrentList()
 		send = send.add("flowControl/MRC", list)
 		p.fcCosts = list.decode()
+
+		// Advertise the stable checkpoint registered in the oracle, if any
+		if server.oracle != nil {
+			if cp, _ := server.oracle.stableCheckpoint(); cp != nil {
+				send = send.add("checkpoint/value", cp)
+			}
+		}
 	} else {
-		p.requestAnnounceType = announceTypeSimple // set to default until "very light" client mode is implemented
+		// Ultra light clients need signed announcements from their trusted servers
+		p.requestAnnounceType = announceTypeSimple
+		if p.isTrusted {
+			p.requestAnnounceType = announceTypeSigned
+		}
 		send = send.add("announceType", p.requestAnnounceType)
 	}
 	recvList, err := p.sendReceiveHandshake(send)
@@ -460,7 +515,7 @@ func (p *peer) Handshake(td *big.Int, head common.Hash, headNum uint64, genesis
 		if recv.get("announceType", &p.announceType) != nil {
 			p.announceType = announceTypeSimple
 		}
-		p.fcClient = flowcontrol.NewClientNode(server.fcManager, server.defParams)
+		p.fcClient = flowcontrol.NewClientNode(server.fcManager, p.fcParams)
 	} else {
 		if recv.get("serveChainSince", nil) != nil {
 			return errResp(ErrUselessPeer, "peer cannot serve chain")
@@ -471,6 +526,9 @@ func (p *peer) Handshake(td *big.Int, head common.Hash, headNum uint64, genesis
 		if recv.get("txRelay", nil) != nil {
 			return errResp(ErrUselessPeer, "peer cannot relay transactions")
 		}
+		// The checkpoint is optional, older servers don't advertise it
+		recv.get("checkpoint/value", &p.checkpoint)
+
 		params := &flowcontrol.ServerParams{}
 		if err := recv.get("flowControl/BL", &params.BufLimit); err != nil {
 			return err
@@ -0,0 +1,386 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+var (
+	errNoPriorityClient = errors.New("unknown priority client")
+	errCapacityExceeded = errors.New("total capacity exceeded")
+	errBalanceUnderflow = errors.New("balance would become negative")
+)
+
+var priorityClientPoolKey = []byte("priorityClientPool")
+
+// priorityClientSaveInterval is the interval at which the balances charged for
+// the served requests are persisted.
+const priorityClientSaveInterval = time.Minute
+
+// priorityClientPool manages the clients paying for their service with a token
+// balance. Each priority client is assigned a capacity by the server operator,
+// which is the minimum recharge rate of its flow control buffer. The cost of
+// every request served is debited from the client's balance and the client is
+// downgraded to a free client once its balance runs out or its capacity is
+// revoked. Changing the capacity of a connected client takes effect right away,
+// the client being notified about its new flow control parameters.
+//
+// Connected priority clients are guaranteed their capacity, the remaining server
+// capacity is shared by the free clients, each getting the same capacity. The
+// connection limit of the free client pool is adjusted accordingly whenever the
+// priority capacity in use changes.
+//
+// Unlike free clients, priority clients are identified by their node ID, as
+// being known is of value for them.
+type priorityClientPool struct {
+	db      ethdb.Database
+	lock    sync.Mutex
+	closed  bool
+	unsaved bool // Balances were charged since the last database save
+	quit    chan struct{}
+
+	totalCap, freeClientCap uint64 // Total server capacity and capacity of a single free client
+	connectedCap            uint64 // Capacity used by the connected priority clients
+
+	clients  map[enode.ID]*priorityClient
+	freePool *freeClientPool // Free client pool sharing the remaining capacity, may be nil
+}
+
+// priorityClient represents a client known by the priority pool.
+type priorityClient struct {
+	id       enode.ID
+	capacity uint64
+	balance  uint64
+
+	connected    bool
+	connectedCap uint64                // Capacity currently granted to the connected client
+	downgradeFn  func()                // Callback to downgrade the client to a free one, must not block
+	updateFn     func(capacity uint64) // Callback to change the capacity of the connected client
+}
+
+// PriorityClientInfo is the information about a priority client exposed via
+// the admin API.
+type PriorityClientInfo struct {
+	Capacity  uint64 `json:"capacity"`
+	Balance   uint64 `json:"balance"`
+	Connected bool   `json:"connected"`
+}
+
+// newPriorityClientPool creates a new priority client pool, restoring the known
+// clients from the database.
+func newPriorityClientPool(db ethdb.Database, totalCap, freeClientCap uint64, freePool *freeClientPool) *priorityClientPool {
+	pool := &priorityClientPool{
+		db:            db,
+		totalCap:      totalCap,
+		freeClientCap: freeClientCap,
+		clients:       make(map[enode.ID]*priorityClient),
+		freePool:      freePool,
+		quit:          make(chan struct{}),
+	}
+	pool.loadFromDb()
+	go pool.saveLoop()
+	return pool
+}
+
+func (p *priorityClientPool) stop() {
+	p.lock.Lock()
+	p.closed = true
+	close(p.quit)
+	p.saveToDb()
+	p.lock.Unlock()
+}
+
+// saveLoop periodically persists the balances charged since the last save, so
+// that a crash doesn't lose the payments of the connected clients.
+func (p *priorityClientPool) saveLoop() {
+	ticker := time.NewTicker(priorityClientSaveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.lock.Lock()
+			if p.unsaved {
+				p.saveToDb()
+			}
+			p.lock.Unlock()
+		case <-p.quit:
+			return
+		}
+	}
+}
+
+// capacity returns the capacity a client would be granted as a priority client,
+// or false if the client is unknown, has no capacity assigned or no balance.
+func (p *priorityClientPool) capacity(id enode.ID) (uint64, bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	c := p.clients[id]
+	if p.closed || c == nil || c.capacity == 0 || c.balance == 0 {
+		return 0, false
+	}
+	return c.capacity, true
+}
+
+// connect should be called after a successful handshake of a priority client,
+// using the capacity it was granted. The connection is rejected if the capacity
+// changed meanwhile or there is not enough free capacity left.
+//
+// The downgradeFn callback is invoked after the priority capacity of the client
+// was released, it should either turn the client into a free client or drop it.
+// The updateFn callback applies a changed capacity to the connected client.
+//
+// Note: the callbacks should not block.
+func (p *priorityClientPool) connect(id enode.ID, capacity uint64, downgradeFn func(), updateFn func(uint64)) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	c := p.clients[id]
+	if p.closed || c == nil || c.connected || c.capacity != capacity || c.balance == 0 {
+		return false
+	}
+	if p.connectedCap+capacity > p.totalCap {
+		log.Debug("Priority client rejected", "id", id, "capacity", capacity)
+		return false
+	}
+	c.connected, c.connectedCap = true, capacity
+	c.downgradeFn, c.updateFn = downgradeFn, updateFn
+	p.connectedCap += capacity
+	p.updateFreeLimit()
+
+	log.Debug("Priority client accepted", "id", id, "capacity", capacity)
+	return true
+}
+
+// disconnect should be called when the connection of a priority client is
+// terminated. The balance charged during the connection is persisted.
+func (p *priorityClientPool) disconnect(id enode.ID) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	c := p.clients[id]
+	if c == nil || !c.connected {
+		return
+	}
+	p.release(c)
+	p.saveToDb()
+
+	log.Debug("Priority client disconnected", "id", id)
+}
+
+// release frees up the capacity of a connected priority client.
+func (p *priorityClientPool) release(c *priorityClient) {
+	c.connected = false
+	c.downgradeFn, c.updateFn = nil, nil
+	p.connectedCap -= c.connectedCap
+	p.updateFreeLimit()
+}
+
+// downgrade releases the capacity of a connected priority client and turns it
+// into a free client. The capacity is released first, making room for the
+// client in the free client pool.
+func (p *priorityClientPool) downgrade(c *priorityClient) {
+	downgradeFn := c.downgradeFn
+	p.release(c)
+	downgradeFn()
+
+	log.Debug("Priority client downgraded", "id", c.id)
+}
+
+// charge debits the cost of a served request from the balance of a client,
+// downgrading it to a free client if the balance runs out.
+func (p *priorityClientPool) charge(id enode.ID, cost uint64) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	c := p.clients[id]
+	if c == nil || !c.connected || c.balance == 0 {
+		return
+	}
+	if cost < c.balance {
+		c.balance -= cost
+		p.unsaved = true
+		return
+	}
+	c.balance = 0
+	log.Debug("Priority client balance exhausted", "id", id)
+	p.downgrade(c)
+	p.saveToDb()
+}
+
+// setCapacity assigns the capacity of a priority client, adding it to the pool
+// if unknown. A changed capacity is applied to a connected client right away,
+// unless raising it would exceed the total capacity, in which case the client
+// keeps its current capacity until it reconnects. Setting a zero capacity
+// revokes the priority status of the client, downgrading it to a free client.
+func (p *priorityClientPool) setCapacity(id enode.ID, capacity uint64) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if capacity > p.totalCap {
+		return errCapacityExceeded
+	}
+	c := p.clients[id]
+	if c == nil {
+		if capacity == 0 {
+			return nil
+		}
+		c = &priorityClient{id: id}
+		p.clients[id] = c
+	}
+	if c.connected && capacity != c.connectedCap {
+		switch {
+		case capacity == 0:
+			p.downgrade(c)
+		case p.connectedCap-c.connectedCap+capacity <= p.totalCap:
+			p.connectedCap = p.connectedCap - c.connectedCap + capacity
+			c.connectedCap = capacity
+			c.updateFn(capacity)
+			p.updateFreeLimit()
+		default:
+			log.Debug("Priority client capacity raise deferred", "id", id, "capacity", capacity)
+		}
+	}
+	c.capacity = capacity
+	if c.capacity == 0 && c.balance == 0 && !c.connected {
+		delete(p.clients, id)
+	}
+	p.saveToDb()
+	return nil
+}
+
+// addBalance adds the given amount to the balance of a client, or deducts it
+// if negative. The new balance is returned.
+func (p *priorityClientPool) addBalance(id enode.ID, amount int64) (uint64, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	c := p.clients[id]
+	if c == nil {
+		c = &priorityClient{id: id}
+	}
+	if amount < 0 && uint64(-amount) > c.balance {
+		return c.balance, errBalanceUnderflow
+	}
+	if amount < 0 {
+		c.balance -= uint64(-amount)
+	} else {
+		c.balance += uint64(amount)
+	}
+	p.clients[id] = c
+	if c.balance == 0 && c.connected {
+		p.downgrade(c)
+	}
+	if c.capacity == 0 && c.balance == 0 && !c.connected {
+		delete(p.clients, id)
+	}
+	p.saveToDb()
+	return c.balance, nil
+}
+
+// info returns the information about a priority client.
+func (p *priorityClientPool) info(id enode.ID) (*PriorityClientInfo, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	c := p.clients[id]
+	if c == nil {
+		return nil, errNoPriorityClient
+	}
+	return &PriorityClientInfo{Capacity: c.capacity, Balance: c.balance, Connected: c.connected}, nil
+}
+
+// clientList returns the information about all known priority clients.
+func (p *priorityClientPool) clientList() map[enode.ID]*PriorityClientInfo {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	list := make(map[enode.ID]*PriorityClientInfo, len(p.clients))
+	for id, c := range p.clients {
+		list[id] = &PriorityClientInfo{Capacity: c.capacity, Balance: c.balance, Connected: c.connected}
+	}
+	return list
+}
+
+// freeCapacity returns the capacity left for the free clients.
+func (p *priorityClientPool) freeCapacity() uint64 {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.totalCap - p.connectedCap
+}
+
+// updateFreeLimit adjusts the connection limit of the free client pool to the
+// capacity left by the priority clients.
+func (p *priorityClientPool) updateFreeLimit() {
+	if p.freePool != nil {
+		p.freePool.setConnectedLimit(int((p.totalCap - p.connectedCap) / p.freeClientCap))
+	}
+}
+
+// priorityClientPoolEntry is the RLP representation of a priority client in the
+// database storage.
+type priorityClientPoolEntry struct {
+	ID       enode.ID
+	Capacity uint64
+	Balance  uint64
+}
+
+// loadFromDb restores the known priority clients from the database storage
+// (automatically called at initialization)
+func (p *priorityClientPool) loadFromDb() {
+	enc, err := p.db.Get(priorityClientPoolKey)
+	if err != nil {
+		return
+	}
+	var list []priorityClientPoolEntry
+	if err := rlp.DecodeBytes(enc, &list); err != nil {
+		log.Error("Failed to decode priority client list", "err", err)
+		return
+	}
+	for _, e := range list {
+		log.Debug("Loaded priority client record", "id", e.ID, "capacity", e.Capacity, "balance", e.Balance)
+		p.clients[e.ID] = &priorityClient{id: e.ID, capacity: e.Capacity, balance: e.Balance}
+	}
+}
+
+// saveToDb saves the known priority clients to the database storage (called on
+// every administrative change, on disconnection, periodically while balances
+// are being charged and during shutdown)
+func (p *priorityClientPool) saveToDb() {
+	p.unsaved = false
+
+	list := make([]priorityClientPoolEntry, 0, len(p.clients))
+	for _, c := range p.clients {
+		list = append(list, priorityClientPoolEntry{ID: c.id, Capacity: c.capacity, Balance: c.balance})
+	}
+	enc, err := rlp.EncodeToBytes(list)
+	if err != nil {
+		log.Error("Failed to encode priority client list", "err", err)
+		return
+	}
+	p.db.Put(priorityClientPoolKey, enc)
+}
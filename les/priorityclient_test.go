@@ -0,0 +1,307 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+const (
+	testFreeClientCap = 100
+	testFreeClients   = 10
+)
+
+// testPriorityPool is a priority client pool sharing the capacity with a free
+// client pool, tracking the clients disconnected or downgraded by the pools.
+type testPriorityPool struct {
+	*priorityClientPool
+	free         *freeClientPool
+	disconnected map[string]bool
+	downgraded   map[enode.ID]bool
+}
+
+func newTestPriorityPool(db ethdb.Database) *testPriorityPool {
+	free := newFreeClientPool(db, testFreeClients, 10000, &mclock.Simulated{})
+	return &testPriorityPool{
+		priorityClientPool: newPriorityClientPool(db, testFreeClients*testFreeClientCap, testFreeClientCap, free),
+		free:               free,
+		disconnected:       make(map[string]bool),
+		downgraded:         make(map[enode.ID]bool),
+	}
+}
+
+func (p *testPriorityPool) disconnectFn(name string) func() {
+	return func() { p.disconnected[name] = true }
+}
+
+// connectFree connects free clients until the free pool refuses them, returning
+// the number of clients connected.
+func (p *testPriorityPool) connectFree(prefix string) int {
+	for i := 0; ; i++ {
+		name := fmt.Sprintf("%s #%d", prefix, i)
+		if !p.free.connect(name, p.disconnectFn(name)) {
+			return i
+		}
+	}
+}
+
+// connectPriority connects a priority client with the capacity it's entitled to.
+func (p *testPriorityPool) connectPriority(id enode.ID) bool {
+	capacity, ok := p.capacity(id)
+	if !ok {
+		return false
+	}
+	return p.connect(id, capacity, p.downgradeFn(id), func(uint64) {})
+}
+
+// downgradeFn checks a downgraded priority client into the free client pool,
+// the way the protocol manager does.
+func (p *testPriorityPool) downgradeFn(id enode.ID) func() {
+	return func() {
+		name := id.String()
+		if p.free.connect(name, p.disconnectFn(name)) {
+			p.downgraded[id] = true
+		} else {
+			p.disconnected[name] = true
+		}
+	}
+}
+
+func (p *testPriorityPool) stop() {
+	p.priorityClientPool.stop()
+	p.free.stop()
+}
+
+func testClientID(i byte) enode.ID {
+	return enode.ID{i}
+}
+
+// Tests that connected priority clients are granted their capacity, the free
+// clients sharing the rest.
+func TestPriorityClientPoolCapacity(t *testing.T) {
+	pool := newTestPriorityPool(ethdb.NewMemDatabase())
+	defer pool.stop()
+
+	if n := pool.connectFree("free"); n != testFreeClients {
+		t.Fatalf("connected free clients mismatch: have %d, want %d", n, testFreeClients)
+	}
+	// Priority clients push out the free clients
+	for i, capacity := range []uint64{3 * testFreeClientCap, 5 * testFreeClientCap, 3 * testFreeClientCap} {
+		if err := pool.setCapacity(testClientID(byte(i)), capacity); err != nil {
+			t.Fatalf("failed to set capacity of client %d: %v", i, err)
+		}
+		if _, err := pool.addBalance(testClientID(byte(i)), 1000); err != nil {
+			t.Fatalf("failed to add balance of client %d: %v", i, err)
+		}
+	}
+	if !pool.connectPriority(testClientID(0)) {
+		t.Fatalf("priority client 0 rejected")
+	}
+	if !pool.connectPriority(testClientID(1)) {
+		t.Fatalf("priority client 1 rejected")
+	}
+	if len(pool.disconnected) != 8 {
+		t.Errorf("kicked out free clients mismatch: have %d, want %d", len(pool.disconnected), 8)
+	}
+	if free := pool.freeCapacity(); free != 2*testFreeClientCap {
+		t.Errorf("free capacity mismatch: have %d, want %d", free, 2*testFreeClientCap)
+	}
+	// The next priority client doesn't fit, neither do more free clients
+	if pool.connectPriority(testClientID(2)) {
+		t.Errorf("priority client accepted over total capacity")
+	}
+	for name := range pool.disconnected {
+		pool.free.disconnect(name)
+	}
+	if n := pool.connectFree("late"); n != 0 {
+		t.Errorf("free clients accepted over free capacity: %d", n)
+	}
+	// Disconnected priority clients free up their capacity
+	pool.disconnect(testClientID(1))
+	if !pool.connectPriority(testClientID(2)) {
+		t.Errorf("priority client 2 rejected after capacity was freed up")
+	}
+	if free := pool.freeCapacity(); free != 4*testFreeClientCap {
+		t.Errorf("free capacity mismatch: have %d, want %d", free, 4*testFreeClientCap)
+	}
+	if n := pool.connectFree("later"); n != 2 {
+		t.Errorf("connected free clients mismatch: have %d, want %d", n, 2)
+	}
+}
+
+// Tests that priority clients are charged for the requests served and downgraded
+// to free clients once their balance runs out.
+func TestPriorityClientPoolBalance(t *testing.T) {
+	pool := newTestPriorityPool(ethdb.NewMemDatabase())
+	defer pool.stop()
+
+	id := testClientID(1)
+	if pool.connectPriority(id) {
+		t.Fatalf("unknown client accepted as priority client")
+	}
+	pool.setCapacity(id, testFreeClientCap)
+	if pool.connectPriority(id) {
+		t.Fatalf("client without balance accepted as priority client")
+	}
+	pool.addBalance(id, 1000)
+	if !pool.connectPriority(id) {
+		t.Fatalf("priority client rejected")
+	}
+	for i := 0; i < 9; i++ {
+		pool.charge(id, 100)
+	}
+	if info, _ := pool.info(id); info.Balance != 100 {
+		t.Errorf("balance mismatch: have %d, want %d", info.Balance, 100)
+	}
+	if pool.downgraded[id] {
+		t.Fatalf("priority client downgraded with balance left")
+	}
+	pool.charge(id, 150)
+	if !pool.downgraded[id] {
+		t.Fatalf("priority client not downgraded after balance ran out")
+	}
+	if info, _ := pool.info(id); info.Connected {
+		t.Errorf("downgraded client still connected as priority client")
+	}
+	if free := pool.freeCapacity(); free != pool.totalCap {
+		t.Errorf("free capacity mismatch: have %d, want %d", free, pool.totalCap)
+	}
+	if _, ok := pool.capacity(id); ok {
+		t.Errorf("client without balance granted priority capacity")
+	}
+	if _, err := pool.addBalance(id, -1); err != errBalanceUnderflow {
+		t.Errorf("negative balance error mismatch: have %v, want %v", err, errBalanceUnderflow)
+	}
+}
+
+// Tests that the capacity of connected priority clients can be changed.
+func TestPriorityClientPoolSetCapacity(t *testing.T) {
+	pool := newTestPriorityPool(ethdb.NewMemDatabase())
+	defer pool.stop()
+
+	id := testClientID(1)
+	pool.setCapacity(id, 2*testFreeClientCap)
+	pool.addBalance(id, 1000)
+
+	var updated uint64
+	if !pool.connect(id, 2*testFreeClientCap, pool.downgradeFn(id), func(capacity uint64) { updated = capacity }) {
+		t.Fatalf("priority client rejected")
+	}
+	// Raising the capacity is applied to the connected client
+	if err := pool.setCapacity(id, 4*testFreeClientCap); err != nil {
+		t.Fatalf("failed to raise capacity: %v", err)
+	}
+	if updated != 4*testFreeClientCap {
+		t.Errorf("updated capacity mismatch: have %d, want %d", updated, 4*testFreeClientCap)
+	}
+	if free := pool.freeCapacity(); free != 6*testFreeClientCap {
+		t.Errorf("free capacity mismatch: have %d, want %d", free, 6*testFreeClientCap)
+	}
+	// Lowering the capacity is applied to the connected client too
+	if err := pool.setCapacity(id, testFreeClientCap); err != nil {
+		t.Fatalf("failed to lower capacity: %v", err)
+	}
+	if updated != testFreeClientCap {
+		t.Errorf("updated capacity mismatch: have %d, want %d", updated, testFreeClientCap)
+	}
+	if free := pool.freeCapacity(); free != 9*testFreeClientCap {
+		t.Errorf("free capacity mismatch: have %d, want %d", free, 9*testFreeClientCap)
+	}
+	// A raise not fitting into the free capacity is deferred until reconnection
+	other := testClientID(2)
+	pool.setCapacity(other, 8*testFreeClientCap)
+	pool.addBalance(other, 1000)
+	if !pool.connectPriority(other) {
+		t.Fatalf("second priority client rejected")
+	}
+	if err := pool.setCapacity(id, 3*testFreeClientCap); err != nil {
+		t.Fatalf("failed to raise capacity: %v", err)
+	}
+	if updated != testFreeClientCap {
+		t.Errorf("capacity raised over total capacity: have %d, want %d", updated, testFreeClientCap)
+	}
+	if capacity, _ := pool.capacity(id); capacity != 3*testFreeClientCap {
+		t.Errorf("capacity mismatch: have %d, want %d", capacity, 3*testFreeClientCap)
+	}
+	// Revoking the capacity downgrades the client to a free client
+	if err := pool.setCapacity(id, 0); err != nil {
+		t.Fatalf("failed to revoke capacity: %v", err)
+	}
+	if !pool.downgraded[id] {
+		t.Errorf("client not downgraded after revoking its capacity")
+	}
+	if free := pool.freeCapacity(); free != 2*testFreeClientCap {
+		t.Errorf("free capacity mismatch: have %d, want %d", free, 2*testFreeClientCap)
+	}
+	if err := pool.setCapacity(id, 11*testFreeClientCap); err != errCapacityExceeded {
+		t.Errorf("capacity over total error mismatch: have %v, want %v", err, errCapacityExceeded)
+	}
+}
+
+// Tests that the priority clients are persisted across restarts.
+func TestPriorityClientPoolPersistence(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	pool := newTestPriorityPool(db)
+	pool.setCapacity(testClientID(1), testFreeClientCap)
+	pool.addBalance(testClientID(1), 500)
+	pool.addBalance(testClientID(2), 700)
+	pool.stop()
+
+	pool = newTestPriorityPool(db)
+	defer pool.stop()
+
+	clients := pool.clientList()
+	if len(clients) != 2 {
+		t.Fatalf("priority client count mismatch: have %d, want %d", len(clients), 2)
+	}
+	if info := clients[testClientID(1)]; info.Capacity != testFreeClientCap || info.Balance != 500 {
+		t.Errorf("client 1 mismatch: have capacity %d balance %d, want %d and %d", info.Capacity, info.Balance, testFreeClientCap, 500)
+	}
+	if info := clients[testClientID(2)]; info.Capacity != 0 || info.Balance != 700 {
+		t.Errorf("client 2 mismatch: have capacity %d balance %d, want %d and %d", info.Capacity, info.Balance, 0, 700)
+	}
+}
+
+// Tests that the balance charged during a connection is persisted when the
+// client disconnects.
+func TestPriorityClientPoolSaveOnDisconnect(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	pool := newTestPriorityPool(db)
+	defer pool.stop()
+
+	id := testClientID(1)
+	pool.setCapacity(id, testFreeClientCap)
+	pool.addBalance(id, 1000)
+	if !pool.connectPriority(id) {
+		t.Fatalf("priority client rejected")
+	}
+	pool.charge(id, 300)
+	pool.disconnect(id)
+
+	// Load the persisted state into a separate pool without stopping the first
+	restored := newTestPriorityPool(db)
+	defer restored.stop()
+
+	if info, err := restored.info(id); err != nil || info.Balance != 700 {
+		t.Errorf("persisted balance mismatch: have %v (%v), want %d", info, err, 700)
+	}
+}
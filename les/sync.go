@@ -72,8 +72,17 @@ func (pm *ProtocolManager) synchronise(peer *peer) {
 		return
 	}
 
+	// Adopt the peer's checkpoint if it's newer than ours and registered in the
+	// checkpoint oracle by the trusted signers.
+	lc := pm.blockchain.(*light.LightChain)
+	if pm.oracle != nil && pm.oracle.isRunning() && !peer.checkpoint.Empty() {
+		sections, _, _ := pm.odr.ChtIndexer().Sections()
+		if peer.checkpoint.SectionIndex >= sections && pm.oracle.verifyCheckpoint(&peer.checkpoint) {
+			lc.AddTrustedCheckpoint(&peer.checkpoint)
+		}
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
 	defer cancel()
-	pm.blockchain.(*light.LightChain).SyncCht(ctx)
+	lc.SyncCht(ctx)
 	pm.downloader.Synchronise(peer.id, peer.Head(), peer.Td(), downloader.LightSync)
 }
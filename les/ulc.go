@@ -0,0 +1,75 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+// ulc holds the state of the ultra light client mode: the set of trusted
+// servers and the fraction of them needed to agree on a head.
+type ulc struct {
+	servers  map[enode.ID]*enode.Node
+	fraction int
+}
+
+// newULC parses the ultra light client configuration. A nil config disables the
+// ultra light mode.
+func newULC(config *eth.ULCConfig) (*ulc, error) {
+	if config == nil || len(config.TrustedServers) == 0 {
+		return nil, nil
+	}
+	u := &ulc{
+		servers:  make(map[enode.ID]*enode.Node),
+		fraction: config.MinTrustedFraction,
+	}
+	if u.fraction <= 0 || u.fraction > 100 {
+		u.fraction = eth.DefaultULCMinTrustedFraction
+	}
+	for _, url := range config.TrustedServers {
+		node, err := enode.ParseV4(url)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted server %q: %v", url, err)
+		}
+		u.servers[node.ID()] = node
+	}
+	return u, nil
+}
+
+// isTrusted returns whether the given node is one of the trusted servers.
+func (u *ulc) isTrusted(id enode.ID) bool {
+	_, ok := u.servers[id]
+	return ok
+}
+
+// trustedNodes returns the trusted servers to keep connected.
+func (u *ulc) trustedNodes() []*enode.Node {
+	nodes := make([]*enode.Node, 0, len(u.servers))
+	for _, n := range u.servers {
+		nodes = append(nodes, n)
+	}
+	return nodes
+}
+
+// quorum returns whether the given number of trusted servers is enough to
+// accept a head.
+func (u *ulc) quorum(agreed int) bool {
+	return agreed*100 >= u.fraction*len(u.servers)
+}
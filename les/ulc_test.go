@@ -0,0 +1,111 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"net"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+// newTestULCServers creates n random server nodes.
+func newTestULCServers(t *testing.T, n int) []*enode.Node {
+	nodes := make([]*enode.Node, n)
+	for i := range nodes {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatalf("failed to generate key: %v", err)
+		}
+		nodes[i] = enode.NewV4(&key.PublicKey, net.IP{127, 0, 0, 1}, 30303, 30303)
+	}
+	return nodes
+}
+
+func TestULCConfig(t *testing.T) {
+	if u, err := newULC(nil); u != nil || err != nil {
+		t.Fatalf("nil config mismatch: have %v %v, want disabled", u, err)
+	}
+	servers := newTestULCServers(t, 2)
+	u, err := newULC(&eth.ULCConfig{TrustedServers: []string{servers[0].String(), servers[1].String()}})
+	if err != nil {
+		t.Fatalf("failed to parse config: %v", err)
+	}
+	if u.fraction != eth.DefaultULCMinTrustedFraction {
+		t.Errorf("fraction mismatch: have %d, want %d", u.fraction, eth.DefaultULCMinTrustedFraction)
+	}
+	for _, n := range servers {
+		if !u.isTrusted(n.ID()) {
+			t.Errorf("server %x not trusted", n.ID())
+		}
+	}
+	if u.isTrusted(newTestULCServers(t, 1)[0].ID()) {
+		t.Errorf("unknown server trusted")
+	}
+	if _, err := newULC(&eth.ULCConfig{TrustedServers: []string{"enode://invalid"}}); err == nil {
+		t.Errorf("invalid server accepted")
+	}
+}
+
+// Tests that in ultra light mode announced heads are only accepted once the
+// required fraction of the trusted servers has announced them.
+func TestULCTrustedHash(t *testing.T) {
+	servers := newTestULCServers(t, 4)
+	config := &eth.ULCConfig{MinTrustedFraction: 75}
+	for _, n := range servers {
+		config.TrustedServers = append(config.TrustedServers, n.String())
+	}
+	u, err := newULC(config)
+	if err != nil {
+		t.Fatalf("failed to parse config: %v", err)
+	}
+	f := &lightFetcher{
+		pm:    &ProtocolManager{ulc: u},
+		peers: make(map[*peer]*fetcherPeerInfo),
+	}
+	head := common.Hash{1}
+	announce := func(trusted bool) {
+		fp := &fetcherPeerInfo{nodeByHash: make(map[common.Hash]*fetcherTreeNode)}
+		fp.nodeByHash[head] = &fetcherTreeNode{hash: head}
+		f.peers[&peer{isTrusted: trusted}] = fp
+	}
+	// Untrusted announcements don't count towards the quorum
+	for i := 0; i < 5; i++ {
+		announce(false)
+	}
+	for i := 0; i < 2; i++ {
+		announce(true)
+		if f.isTrustedHash(head) {
+			t.Fatalf("head accepted after %d trusted announcements", i+1)
+		}
+	}
+	announce(true)
+	if !f.isTrustedHash(head) {
+		t.Fatalf("head rejected after quorum was reached")
+	}
+	if f.isTrustedHash(common.Hash{2}) {
+		t.Errorf("unannounced head accepted")
+	}
+	// Without ultra light mode any announced head is accepted
+	f.pm.ulc = nil
+	if !f.isTrustedHash(common.Hash{2}) {
+		t.Errorf("head rejected in light mode")
+	}
+}
@@ -71,6 +71,15 @@ type NodeConfig struct {
 	// It has the form "nodename:secret@host:port"
 	EthereumNetStats string
 
+	// EthereumTrustedServers are the LES servers trusted to announce the chain
+	// head. If set, the node runs in ultra light client mode, following the head
+	// announced by a quorum of these servers without verifying every header.
+	EthereumTrustedServers *Enodes
+
+	// EthereumTrustedFraction is the minimum percentage of the trusted servers
+	// needed to announce a head before it is accepted (1-100).
+	EthereumTrustedFraction int
+
 	// WhisperEnabled specifies whether the node should run the Whisper protocol.
 	WhisperEnabled bool
 
@@ -81,11 +90,12 @@ type NodeConfig struct {
 // defaultNodeConfig contains the default node configuration values to use if all
 // or some fields are missing from the user's specified list.
 var defaultNodeConfig = &NodeConfig{
-	BootstrapNodes:        FoundationBootnodes(),
-	MaxPeers:              25,
-	EthereumEnabled:       true,
-	EthereumNetworkID:     1,
-	EthereumDatabaseCache: 16,
+	BootstrapNodes:          FoundationBootnodes(),
+	MaxPeers:                25,
+	EthereumEnabled:         true,
+	EthereumNetworkID:       1,
+	EthereumDatabaseCache:   16,
+	EthereumTrustedFraction: eth.DefaultULCMinTrustedFraction,
 }
 
 // NewNodeConfig creates a new node option set, initialized to the default values.
@@ -160,6 +170,12 @@ func NewNode(datadir string, config *NodeConfig) (stack *Node, _ error) {
 		ethConf.SyncMode = downloader.LightSync
 		ethConf.NetworkId = uint64(config.EthereumNetworkID)
 		ethConf.DatabaseCache = config.EthereumDatabaseCache
+		if config.EthereumTrustedServers != nil && config.EthereumTrustedServers.Size() > 0 {
+			ethConf.ULC = &eth.ULCConfig{MinTrustedFraction: config.EthereumTrustedFraction}
+			for _, n := range config.EthereumTrustedServers.nodes {
+				ethConf.ULC.TrustedServers = append(ethConf.ULC.TrustedServers, n.String())
+			}
+		}
 		if err := rawStack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
 			return les.New(ctx, &ethConf)
 		}); err != nil {
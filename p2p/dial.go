@@ -37,6 +37,9 @@ const (
 	// once every few seconds.
 	lookupInterval = 4 * time.Second
 
+	// Number of nodes requested from DNS node lists per discovery lookup.
+	dnsLookupBatch = 16
+
 	// If no peers are found for this amount of time, the initial bootnodes are
 	// attempted to be connected.
 	fallbackInterval = 20 * time.Second
@@ -82,6 +85,9 @@ type dialstate struct {
 
 	start     time.Time     // time when the dialer was first used
 	bootnodes []*enode.Node // default dials when there are no peers
+
+	banned func(enode.ID) bool     // reports nodes that may not be dialed dynamically
+	filter func(*enode.Node) error // rejects dynamic dial candidates based on their records
 }
 
 type discoverTable interface {
@@ -91,6 +97,12 @@ type discoverTable interface {
 	ReadRandomNodes([]*enode.Node) int
 }
 
+// enrRequester is implemented by discovery tables which can fetch the current
+// record of a node from the node itself.
+type enrRequester interface {
+	RequestENR(*enode.Node) (*enode.Node, error)
+}
+
 // the dial history remembers recent dials.
 type dialHistory []pastDial
 
@@ -166,7 +178,14 @@ func (s *dialstate) newTasks(nRunning int, peers map[enode.ID]*Peer, now time.Ti
 
 	var newtasks []task
 	addDial := func(flag connFlag, n *enode.Node) bool {
-		if err := s.checkDial(n, peers); err != nil {
+		err := s.checkDial(n, peers)
+		if err == nil && s.banned != nil && s.banned(n.ID()) {
+			err = errBanned
+		}
+		if err == nil && s.filter != nil {
+			err = s.filter(n)
+		}
+		if err != nil {
 			log.Trace("Skipping dial candidate", "id", n.ID(), "addr", &net.TCPAddr{IP: n.IP(), Port: n.TCP()}, "err", err)
 			return false
 		}
@@ -218,7 +237,7 @@ func (s *dialstate) newTasks(nRunning int, peers map[enode.ID]*Peer, now time.Ti
 	// Use random nodes from the table for half of the necessary
 	// dynamic dials.
 	randomCandidates := needDynDials / 2
-	if randomCandidates > 0 {
+	if randomCandidates > 0 && s.ntab != nil {
 		n := s.ntab.ReadRandomNodes(s.randomNodes)
 		for i := 0; i < randomCandidates && i < n; i++ {
 			if addDial(dynDialedConn, s.randomNodes[i]) {
@@ -258,6 +277,7 @@ var (
 	errAlreadyConnected = errors.New("already connected")
 	errRecentlyDialed   = errors.New("recently dialed")
 	errNotWhitelisted   = errors.New("not contained in netrestrict whitelist")
+	errBanned           = errors.New("banned for misbehaviour")
 )
 
 func (s *dialstate) checkDial(n *enode.Node, peers map[enode.ID]*Peer) error {
@@ -294,6 +314,12 @@ func (t *dialTask) Do(srv *Server) {
 			return
 		}
 	}
+	if t.flags&dynDialedConn != 0 {
+		if err := t.checkRecord(srv); err != nil {
+			log.Trace("Skipping dial candidate", "id", t.dest.ID(), "addr", &net.TCPAddr{IP: t.dest.IP(), Port: t.dest.TCP()}, "err", err)
+			return
+		}
+	}
 	err := t.dial(srv, t.dest)
 	if err != nil {
 		log.Trace("Dial error", "task", t, "err", err)
@@ -340,6 +366,28 @@ func (t *dialTask) resolve(srv *Server) bool {
 	return true
 }
 
+// checkRecord fetches the current record of a dynamic dial candidate and checks
+// it against the dial filters of the protocols. Candidates found by discovery
+// only carry their endpoint, not the entries the filters decide on.
+//
+// Nodes which don't serve their record are accepted, as the filters accept nodes
+// without any information about their protocols.
+func (t *dialTask) checkRecord(srv *Server) error {
+	if !srv.hasDialFilter() {
+		return nil
+	}
+	requester, ok := srv.ntab.(enrRequester)
+	if !ok {
+		return nil
+	}
+	n, err := requester.RequestENR(t.dest)
+	if err != nil {
+		log.Trace("Can't fetch node record", "id", t.dest.ID(), "err", err)
+		return nil
+	}
+	return srv.dialFilter(n)
+}
+
 type dialError struct {
 	error
 }
@@ -368,7 +416,12 @@ func (t *discoverTask) Do(srv *Server) {
 		time.Sleep(next.Sub(now))
 	}
 	srv.lastLookup = time.Now()
-	t.results = srv.ntab.LookupRandom()
+	if srv.ntab != nil {
+		t.results = srv.ntab.LookupRandom()
+	}
+	if srv.dnsdisc != nil {
+		t.results = append(t.results, srv.dnsdisc.RandomNodes(dnsLookupBatch)...)
+	}
 }
 
 func (t *discoverTask) String() string {
@@ -18,6 +18,7 @@ package p2p
 
 import (
 	"encoding/binary"
+	"errors"
 	"net"
 	"reflect"
 	"testing"
@@ -442,6 +443,91 @@ func TestDialStateNetRestrict(t *testing.T) {
 	})
 }
 
+// This test checks that banned nodes and nodes rejected by the protocol dial
+// filters are not dialed dynamically.
+func TestDialStateFilter(t *testing.T) {
+	// This table always returns the same random nodes
+	// in the order given below.
+	table := fakeTable{
+		newNode(uintID(1), nil),
+		newNode(uintID(2), nil),
+		newNode(uintID(3), nil),
+		newNode(uintID(4), nil),
+		newNode(uintID(5), nil),
+		newNode(uintID(6), nil),
+	}
+	dialer := newDialState(enode.ID{}, nil, nil, table, 10, nil)
+	dialer.banned = func(id enode.ID) bool { return id == uintID(1) }
+	dialer.filter = func(n *enode.Node) error {
+		if n.ID() == uintID(3) {
+			return errors.New("incompatible")
+		}
+		return nil
+	}
+	runDialTest(t, dialtest{
+		init: dialer,
+		rounds: []round{
+			{
+				new: []task{
+					&dialTask{flags: dynDialedConn, dest: table[1]},
+					&dialTask{flags: dynDialedConn, dest: table[3]},
+					&dialTask{flags: dynDialedConn, dest: table[4]},
+					&discoverTask{},
+				},
+			},
+		},
+	})
+}
+
+// This test checks that discovered nodes are rejected by the protocol dial filters
+// based on the entries of their records, which are fetched before dialing.
+func TestDialRecordFilter(t *testing.T) {
+	var (
+		rejected = newNode(uintID(1), net.IP{127, 0, 0, 1})
+		accepted = newNode(uintID(2), net.IP{127, 0, 0, 2})
+		unknown  = newNode(uintID(3), net.IP{127, 0, 0, 3})
+	)
+	var r enr.Record
+	r.Set(enr.IP{127, 0, 0, 1})
+	r.Set(enr.WithEntry("reject", true))
+	table := &recordMock{
+		fakeTable: fakeTable{rejected, accepted, unknown},
+		records: map[enode.ID]*enode.Node{
+			rejected.ID(): enode.SignNull(&r, rejected.ID()),
+			accepted.ID(): accepted,
+		},
+	}
+	dialer := &recordDialer{}
+	srv := &Server{ntab: table, Config: Config{
+		Dialer: dialer,
+		Protocols: []Protocol{{
+			Name: "test",
+			DialFilter: func(n *enode.Node) error {
+				var reject bool
+				if n.Load(enr.WithEntry("reject", &reject)) == nil && reject {
+					return errors.New("rejected")
+				}
+				return nil
+			},
+		}},
+	}}
+	// The discovered nodes carry no entries, so they pass the dial state filter.
+	state := newDialState(enode.ID{}, nil, nil, table, 10, nil)
+	state.filter = srv.dialFilter
+	for _, task := range state.newTasks(0, nil, time.Time{}) {
+		if task, ok := task.(*dialTask); ok {
+			task.Do(srv)
+		}
+	}
+	want := []enode.ID{accepted.ID(), unknown.ID()}
+	if !reflect.DeepEqual(dialer.dialed, want) {
+		t.Fatalf("dialed nodes mismatch: have %v, want %v", dialer.dialed, want)
+	}
+	if !reflect.DeepEqual(table.requested, []enode.ID{rejected.ID(), accepted.ID(), unknown.ID()}) {
+		t.Fatalf("record requests mismatch: have %v", table.requested)
+	}
+}
+
 // This test checks that static dials are launched.
 func TestDialStateStaticDial(t *testing.T) {
 	wantStatic := []*enode.Node{
@@ -703,3 +789,28 @@ func (t *resolveMock) Self() *enode.Node                     { return new(enode.
 func (t *resolveMock) Close()                                {}
 func (t *resolveMock) LookupRandom() []*enode.Node           { return nil }
 func (t *resolveMock) ReadRandomNodes(buf []*enode.Node) int { return 0 }
+
+// implements discoverTable and enrRequester for TestDialRecordFilter
+type recordMock struct {
+	fakeTable
+	records   map[enode.ID]*enode.Node
+	requested []enode.ID
+}
+
+func (t *recordMock) RequestENR(n *enode.Node) (*enode.Node, error) {
+	t.requested = append(t.requested, n.ID())
+	if r, ok := t.records[n.ID()]; ok {
+		return r, nil
+	}
+	return nil, errors.New("timeout")
+}
+
+// recordDialer records the dialed nodes, failing all dials.
+type recordDialer struct {
+	dialed []enode.ID
+}
+
+func (d *recordDialer) Dial(n *enode.Node) (net.Conn, error) {
+	d.dialed = append(d.dialed, n.ID())
+	return nil, errors.New("dial failed")
+}
@@ -88,6 +88,7 @@ type transport interface {
 	self() *enode.Node
 	ping(enode.ID, *net.UDPAddr) error
 	findnode(toid enode.ID, addr *net.UDPAddr, target encPubkey) ([]*node, error)
+	requestENR(toid enode.ID, addr *net.UDPAddr) (*enode.Node, error)
 	close()
 }
 
@@ -237,6 +238,12 @@ func (tab *Table) Resolve(n *enode.Node) *enode.Node {
 	return nil
 }
 
+// RequestENR retrieves the current record of the given node from the node itself.
+// Table entries only hold the endpoint of the nodes, not their full records.
+func (tab *Table) RequestENR(n *enode.Node) (*enode.Node, error) {
+	return tab.net.requestENR(n.ID(), &net.UDPAddr{IP: n.IP(), Port: n.UDP()})
+}
+
 // LookupRandom finds random nodes in the network.
 func (tab *Table) LookupRandom() []*enode.Node {
 	var target encPubkey
@@ -109,6 +109,10 @@ func (t *pingRecorder) findnode(toid enode.ID, toaddr *net.UDPAddr, target encPu
 	return nil, nil
 }
 
+func (t *pingRecorder) requestENR(toid enode.ID, toaddr *net.UDPAddr) (*enode.Node, error) {
+	return nil, errTimeout
+}
+
 func (t *pingRecorder) waitping(from enode.ID) error {
 	return nil // remote always pings
 }
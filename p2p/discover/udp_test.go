@@ -37,6 +37,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
 	"github.com/ethereum/go-ethereum/rlp"
 )
 
@@ -131,6 +132,8 @@ func TestUDP_packetErrors(t *testing.T) {
 	test.packetIn(errUnsolicitedReply, pongPacket, &pong{ReplyTok: []byte{}, Expiration: futureExp})
 	test.packetIn(errUnknownNode, findnodePacket, &findnode{Expiration: futureExp})
 	test.packetIn(errUnsolicitedReply, neighborsPacket, &neighbors{Expiration: futureExp})
+	test.packetIn(errUnknownNode, enrRequestPacket, &enrRequest{Expiration: futureExp})
+	test.packetIn(errUnsolicitedReply, enrResponsePacket, &enrResponse{ReplyTok: []byte{}, Record: *test.udp.self().Record()})
 }
 
 func TestUDP_pingTimeout(t *testing.T) {
@@ -329,6 +332,85 @@ func TestUDP_findnodeMultiReply(t *testing.T) {
 	}
 }
 
+func TestUDP_enrRequest(t *testing.T) {
+	test := newUDPTest(t)
+	defer test.table.Close()
+
+	// ensure there's a bond with the test node,
+	// enrRequest won't be accepted otherwise.
+	remoteID := encodePubkey(&test.remotekey.PublicKey).id()
+	test.table.db.UpdateLastPongReceived(remoteID, time.Now())
+
+	test.udp.localNode.Set(enr.WithEntry("foo", "bar"))
+	test.packetIn(nil, enrRequestPacket, &enrRequest{Expiration: futureExp})
+	hash := crypto.Keccak256(test.sent[len(test.sent)-1][macSize:])
+	test.waitPacketOut(func(p *enrResponse) {
+		if !bytes.Equal(p.ReplyTok, hash) {
+			t.Errorf("wrong reply token: got %x, want %x", p.ReplyTok, hash)
+		}
+		n, err := enode.New(enode.ValidSchemes, &p.Record)
+		if err != nil {
+			t.Fatalf("invalid record: %v", err)
+		}
+		if n.ID() != test.udp.self().ID() {
+			t.Errorf("wrong ID in response: got %v, want %v", n.ID(), test.udp.self().ID())
+		}
+		var foo string
+		if err := n.Load(enr.WithEntry("foo", &foo)); err != nil || foo != "bar" {
+			t.Errorf("wrong entry in response: got %q (%v), want %q", foo, err, "bar")
+		}
+	})
+}
+
+func TestUDP_requestENR(t *testing.T) {
+	test := newUDPTest(t)
+	defer test.table.Close()
+
+	rid := enode.PubkeyToIDV4(&test.remotekey.PublicKey)
+	test.table.db.UpdateLastPingReceived(rid, time.Now())
+
+	// create the record of the remote node
+	var record enr.Record
+	record.Set(enr.WithEntry("foo", "bar"))
+	if err := enode.SignV4(&record, test.remotekey); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		record  enr.Record
+		wantErr error
+	}{
+		{record: record},
+		{record: *test.udp.self().Record(), wantErr: errIDMismatch},
+	} {
+		resultc, errc := make(chan *enode.Node, 1), make(chan error, 1)
+		go func() {
+			n, err := test.udp.requestENR(rid, test.remoteaddr)
+			if err != nil {
+				errc <- err
+			} else {
+				resultc <- n
+			}
+		}()
+		hash, _ := test.waitPacketOut(func(p *enrRequest) {})
+		test.packetIn(nil, enrResponsePacket, &enrResponse{ReplyTok: hash, Record: tt.record})
+
+		select {
+		case n := <-resultc:
+			if tt.wantErr != nil {
+				t.Errorf("expected error %q, got record %v", tt.wantErr, n)
+			} else if n.ID() != rid {
+				t.Errorf("wrong ID: got %v, want %v", n.ID(), rid)
+			}
+		case err := <-errc:
+			if err != tt.wantErr {
+				t.Errorf("error mismatch: got %q, want %v", err, tt.wantErr)
+			}
+		case <-time.After(5 * time.Second):
+			t.Error("requestENR did not return within 5 seconds")
+		}
+	}
+}
+
 func TestUDP_successfulPing(t *testing.T) {
 	test := newUDPTest(t)
 	added := make(chan *node, 1)
@@ -0,0 +1,295 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dnsdisc
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+const (
+	defaultTimeout         = 5 * time.Second
+	defaultRecheckInterval = 30 * time.Minute
+	defaultCacheLimit      = 1000
+	maxLinkDepth           = 8 // Maximum number of link hops followed from a configured tree
+)
+
+// Resolver is a DNS resolver that can query TXT records. It is satisfied by
+// *net.Resolver.
+type Resolver interface {
+	LookupTXT(ctx context.Context, domain string) ([]string, error)
+}
+
+// Config holds configuration options for the DNS discovery client.
+type Config struct {
+	Timeout         time.Duration      // timeout used for DNS lookups (default 5s)
+	RecheckInterval time.Duration      // time between tree root update checks (default 30min)
+	CacheLimit      int                // maximum number of cached tree entries (default 1000)
+	ValidSchemes    enr.IdentityScheme // acceptable ENR identity schemes (default enode.ValidSchemes)
+	Resolver        Resolver           // the DNS resolver to use (defaults to system DNS)
+	Logger          log.Logger         // destination of client log messages (defaults to root logger)
+}
+
+func (cfg Config) withDefaults() Config {
+	if cfg.Timeout == 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	if cfg.RecheckInterval == 0 {
+		cfg.RecheckInterval = defaultRecheckInterval
+	}
+	if cfg.CacheLimit == 0 {
+		cfg.CacheLimit = defaultCacheLimit
+	}
+	if cfg.ValidSchemes == nil {
+		cfg.ValidSchemes = enode.ValidSchemes
+	}
+	if cfg.Resolver == nil {
+		cfg.Resolver = new(net.Resolver)
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = log.Root()
+	}
+	return cfg
+}
+
+// Client discovers nodes by querying DNS servers.
+type Client struct {
+	cfg     Config
+	urls    []string
+	entries *lru.Cache // cached tree entries, keyed by full domain name
+
+	lock  sync.Mutex
+	trees map[string]*clientTree // last synced trees, keyed by URL
+}
+
+// clientTree is a synced tree along with the time it was last checked.
+type clientTree struct {
+	tree    *Tree
+	checked time.Time
+}
+
+// NewClient creates a DNS discovery client fetching nodes from the trees at
+// the given URLs.
+func NewClient(cfg Config, urls ...string) (*Client, error) {
+	for _, url := range urls {
+		if _, err := parseLink(url); err != nil {
+			return nil, fmt.Errorf("invalid enrtree URL %q: %v", url, err)
+		}
+	}
+	cfg = cfg.withDefaults()
+	cache, err := lru.New(cfg.CacheLimit)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		cfg:     cfg,
+		urls:    urls,
+		entries: cache,
+		trees:   make(map[string]*clientTree),
+	}, nil
+}
+
+// SyncTree downloads the entire node tree at the given URL. The root signature
+// is verified against the public key contained in the URL.
+func (c *Client) SyncTree(url string) (*Tree, error) {
+	le, err := parseLink(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid enrtree URL %q: %v", url, err)
+	}
+	root, err := c.resolveRoot(le)
+	if err != nil {
+		return nil, err
+	}
+	t := &Tree{root: &root, entries: make(map[string]entry)}
+	if err := c.syncSubtree(t, le.domain, root.eroot); err != nil {
+		return nil, err
+	}
+	if err := c.syncSubtree(t, le.domain, root.lroot); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// RandomNodes returns up to n random nodes from the configured trees and the
+// trees linked from them. Trees are re-synced if they haven't been checked for
+// longer than the recheck interval. If a tree can't be synced, the previously
+// synced version of it is used.
+func (c *Client) RandomNodes(n int) []*enode.Node {
+	var (
+		nodes   []*enode.Node
+		visited = make(map[string]bool)
+		queue   = append([]string{}, c.urls...)
+		depth   = make(map[string]int)
+	)
+	for len(queue) > 0 {
+		url := queue[0]
+		queue = queue[1:]
+		if visited[url] {
+			continue
+		}
+		visited[url] = true
+
+		t := c.tree(url)
+		if t == nil {
+			continue
+		}
+		nodes = append(nodes, t.Nodes()...)
+		if depth[url] < maxLinkDepth {
+			for _, link := range t.Links() {
+				if _, ok := depth[link]; !ok {
+					depth[link] = depth[url] + 1
+				}
+				queue = append(queue, link)
+			}
+		}
+	}
+	// Deduplicate and pick a random subset
+	seen := make(map[enode.ID]bool)
+	unique := nodes[:0]
+	for _, node := range nodes {
+		if !seen[node.ID()] {
+			seen[node.ID()] = true
+			unique = append(unique, node)
+		}
+	}
+	rand.Shuffle(len(unique), func(i, j int) { unique[i], unique[j] = unique[j], unique[i] })
+	if len(unique) > n {
+		unique = unique[:n]
+	}
+	return unique
+}
+
+// tree returns the synced tree at the given URL, re-syncing it if necessary.
+func (c *Client) tree(url string) *Tree {
+	c.lock.Lock()
+	ct := c.trees[url]
+	c.lock.Unlock()
+
+	if ct != nil && time.Since(ct.checked) < c.cfg.RecheckInterval {
+		return ct.tree
+	}
+	t, err := c.SyncTree(url)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	switch {
+	case err != nil:
+		c.cfg.Logger.Debug("Failed to sync DNS node tree", "url", url, "err", err)
+		if ct != nil {
+			ct.checked = time.Now()
+			return ct.tree
+		}
+		return nil
+	case ct != nil && t.Seq() < ct.tree.Seq():
+		c.cfg.Logger.Debug("Ignoring stale DNS node tree", "url", url, "seq", t.Seq(), "have", ct.tree.Seq())
+		ct.checked = time.Now()
+		return ct.tree
+	default:
+		if ct == nil || t.Seq() != ct.tree.Seq() {
+			c.cfg.Logger.Debug("Synced DNS node tree", "url", url, "seq", t.Seq(), "nodes", len(t.Nodes()))
+		}
+		c.trees[url] = &clientTree{tree: t, checked: time.Now()}
+		return t
+	}
+}
+
+// syncSubtree fetches the subtree rooted at the given hash into t.
+func (c *Client) syncSubtree(t *Tree, domain, hash string) error {
+	e, err := c.resolveEntry(domain, hash)
+	if err != nil {
+		return err
+	}
+	t.entries[hash] = e
+	if branch, ok := e.(*branchEntry); ok {
+		for _, child := range branch.children {
+			if _, ok := t.entries[child]; ok {
+				continue
+			}
+			if err := c.syncSubtree(t, domain, child); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// resolveRoot retrieves the root entry of a tree and verifies its signature.
+func (c *Client) resolveRoot(le *linkEntry) (rootEntry, error) {
+	txts, err := c.lookupTXT(le.domain)
+	if err != nil {
+		return rootEntry{}, err
+	}
+	for _, txt := range txts {
+		if !strings.HasPrefix(txt, rootPrefix) {
+			continue
+		}
+		root, err := parseRoot(txt)
+		if err != nil {
+			return rootEntry{}, err
+		}
+		if !root.verifySignature(le.pubkey) {
+			return rootEntry{}, entryError{"root", errInvalidSig}
+		}
+		return root, nil
+	}
+	return rootEntry{}, fmt.Errorf("no root entry found at %s", le.domain)
+}
+
+// resolveEntry retrieves an entry from the cache or fetches it from the network
+// if it isn't cached. The entry content is checked against the hash.
+func (c *Client) resolveEntry(domain, hash string) (entry, error) {
+	name := hash + "." + domain
+	if e, ok := c.entries.Get(name); ok {
+		return e.(entry), nil
+	}
+	txts, err := c.lookupTXT(name)
+	if err != nil {
+		return nil, err
+	}
+	for _, txt := range txts {
+		e, err := parseEntry(txt, c.cfg.ValidSchemes)
+		if err == errUnknownEntry {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !checkHash(hash, txt) {
+			return nil, entryError{"tree", errHashMismatch}
+		}
+		c.entries.Add(name, e)
+		return e, nil
+	}
+	return nil, fmt.Errorf("no tree entry found at %s", name)
+}
+
+// lookupTXT performs a DNS TXT query with the configured timeout.
+func (c *Client) lookupTXT(name string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.cfg.Timeout)
+	defer cancel()
+	return c.cfg.Resolver.LookupTXT(ctx, name)
+}
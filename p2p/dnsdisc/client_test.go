@@ -0,0 +1,239 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dnsdisc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+)
+
+// mapResolver is an in-memory DNS resolver serving TXT records from a map.
+type mapResolver map[string]string
+
+func (mr mapResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	if record, ok := mr[name]; ok {
+		return []string{record}, nil
+	}
+	return nil, fmt.Errorf("no such host: %s", name)
+}
+
+func (mr mapResolver) add(records map[string]string) {
+	for name, record := range records {
+		mr[name] = record
+	}
+}
+
+func testKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func testNodes(t *testing.T, n int) []*enode.Node {
+	nodes := make([]*enode.Node, n)
+	for i := range nodes {
+		var r enr.Record
+		r.Set(enr.IP{127, 0, 0, 1})
+		r.Set(enr.TCP(30303 + i))
+		r.SetSeq(uint64(i))
+		if err := enode.SignV4(&r, testKey(t)); err != nil {
+			t.Fatal(err)
+		}
+		node, err := enode.New(enode.ValidSchemes, &r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nodes[i] = node
+	}
+	return nodes
+}
+
+// makeSignedTree creates and signs a tree, returning it with its URL.
+func makeSignedTree(t *testing.T, key *ecdsa.PrivateKey, domain string, seq uint, nodes []*enode.Node, links []string) (*Tree, string) {
+	tree, err := MakeTree(seq, nodes, links)
+	if err != nil {
+		t.Fatal(err)
+	}
+	url, err := tree.Sign(key, domain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tree, url
+}
+
+func nodeIDs(nodes []*enode.Node) []string {
+	ids := make([]string, len(nodes))
+	for i, n := range nodes {
+		ids[i] = n.ID().String()
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Tests that a tree spanning multiple branch levels can be published and synced.
+func TestSyncTree(t *testing.T) {
+	var (
+		key       = testKey(t)
+		nodes     = testNodes(t, 3*maxChildren)
+		_, link   = makeSignedTree(t, testKey(t), "other.example.org", 1, nil, nil)
+		tree, url = makeSignedTree(t, key, "n.example.org", 7, nodes, []string{link})
+		resolver  = make(mapResolver)
+	)
+	resolver.add(tree.ToTXT("n.example.org"))
+
+	for name, record := range resolver {
+		if len(record) > maxTXTLength {
+			t.Errorf("record %s too long: %d bytes", name, len(record))
+		}
+	}
+	c, err := NewClient(Config{Resolver: resolver}, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	synced, err := c.SyncTree(url)
+	if err != nil {
+		t.Fatal("sync error:", err)
+	}
+	if !reflect.DeepEqual(nodeIDs(synced.Nodes()), nodeIDs(nodes)) {
+		t.Errorf("node mismatch: have %v, want %v", nodeIDs(synced.Nodes()), nodeIDs(nodes))
+	}
+	if !reflect.DeepEqual(synced.Links(), []string{link}) {
+		t.Errorf("link mismatch: have %v, want %v", synced.Links(), []string{link})
+	}
+	if synced.Seq() != 7 {
+		t.Errorf("seq mismatch: have %d, want %d", synced.Seq(), 7)
+	}
+	if synced.Signature() != tree.Signature() {
+		t.Errorf("signature mismatch: have %s, want %s", synced.Signature(), tree.Signature())
+	}
+}
+
+// Tests that trees signed by a key other than the one in the URL are rejected.
+func TestSyncTreeBadSignature(t *testing.T) {
+	var (
+		tree, _  = makeSignedTree(t, testKey(t), "n.example.org", 1, testNodes(t, 3), nil)
+		_, url   = makeSignedTree(t, testKey(t), "n.example.org", 1, nil, nil)
+		resolver = make(mapResolver)
+	)
+	resolver.add(tree.ToTXT("n.example.org"))
+
+	c, _ := NewClient(Config{Resolver: resolver}, url)
+	_, err := c.SyncTree(url)
+	if want := (entryError{"root", errInvalidSig}); err != want {
+		t.Fatalf("sync error mismatch: have %v, want %v", err, want)
+	}
+}
+
+// Tests that tampered tree entries are detected.
+func TestSyncTreeHashMismatch(t *testing.T) {
+	var (
+		nodes     = testNodes(t, 2)
+		tree, url = makeSignedTree(t, testKey(t), "n.example.org", 1, nodes, nil)
+		resolver  = make(mapResolver)
+	)
+	resolver.add(tree.ToTXT("n.example.org"))
+
+	// Replace one of the records with a different one.
+	other := (&enrEntry{testNodes(t, 1)[0]}).String()
+	for name, record := range resolver {
+		if strings.HasPrefix(record, enrPrefix) {
+			resolver[name] = other
+			break
+		}
+	}
+	c, _ := NewClient(Config{Resolver: resolver}, url)
+	_, err := c.SyncTree(url)
+	if want := (entryError{"tree", errHashMismatch}); err != want {
+		t.Fatalf("sync error mismatch: have %v, want %v", err, want)
+	}
+}
+
+// Tests that RandomNodes follows links between trees, including cyclic ones.
+func TestRandomNodesLinks(t *testing.T) {
+	var (
+		keyA, keyB = testKey(t), testKey(t)
+		nodesA     = testNodes(t, 4)
+		nodesB     = testNodes(t, 5)
+		urlA       = (&linkEntry{"a.example.org", &keyA.PublicKey}).String()
+		urlB       = (&linkEntry{"b.example.org", &keyB.PublicKey}).String()
+		treeA, _   = makeSignedTree(t, keyA, "a.example.org", 1, nodesA, []string{urlB})
+		treeB, _   = makeSignedTree(t, keyB, "b.example.org", 1, nodesB, []string{urlA})
+		resolver   = make(mapResolver)
+	)
+	resolver.add(treeA.ToTXT("a.example.org"))
+	resolver.add(treeB.ToTXT("b.example.org"))
+
+	c, err := NewClient(Config{Resolver: resolver}, urlA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	have := c.RandomNodes(100)
+	want := append(append([]*enode.Node{}, nodesA...), nodesB...)
+	if !reflect.DeepEqual(nodeIDs(have), nodeIDs(want)) {
+		t.Fatalf("node mismatch: have %v, want %v", nodeIDs(have), nodeIDs(want))
+	}
+	if n := len(c.RandomNodes(3)); n != 3 {
+		t.Fatalf("wrong number of nodes: have %d, want %d", n, 3)
+	}
+}
+
+// Tests that RandomNodes picks up tree updates and ignores stale roots.
+func TestRandomNodesUpdate(t *testing.T) {
+	var (
+		key        = testKey(t)
+		nodes1     = testNodes(t, 3)
+		nodes2     = testNodes(t, 4)
+		tree1, url = makeSignedTree(t, key, "n.example.org", 2, nodes1, nil)
+		resolver   = make(mapResolver)
+	)
+	resolver.add(tree1.ToTXT("n.example.org"))
+
+	c, _ := NewClient(Config{Resolver: resolver, RecheckInterval: -1}, url)
+	if have := c.RandomNodes(10); !reflect.DeepEqual(nodeIDs(have), nodeIDs(nodes1)) {
+		t.Fatalf("node mismatch: have %v, want %v", nodeIDs(have), nodeIDs(nodes1))
+	}
+	// Publish an older tree, it should be ignored.
+	tree0, _ := makeSignedTree(t, key, "n.example.org", 1, nodes2, nil)
+	resolver.add(tree0.ToTXT("n.example.org"))
+	if have := c.RandomNodes(10); !reflect.DeepEqual(nodeIDs(have), nodeIDs(nodes1)) {
+		t.Fatalf("node mismatch after stale update: have %v, want %v", nodeIDs(have), nodeIDs(nodes1))
+	}
+	// Publish a newer tree, it should replace the old one.
+	tree2, _ := makeSignedTree(t, key, "n.example.org", 3, nodes2, nil)
+	resolver.add(tree2.ToTXT("n.example.org"))
+	if have := c.RandomNodes(10); !reflect.DeepEqual(nodeIDs(have), nodeIDs(nodes2)) {
+		t.Fatalf("node mismatch after update: have %v, want %v", nodeIDs(have), nodeIDs(nodes2))
+	}
+	// Remove the tree from DNS, the last synced version should be served.
+	for name := range resolver {
+		delete(resolver, name)
+	}
+	if have := c.RandomNodes(10); !reflect.DeepEqual(nodeIDs(have), nodeIDs(nodes2)) {
+		t.Fatalf("node mismatch after removal: have %v, want %v", nodeIDs(have), nodeIDs(nodes2))
+	}
+}
@@ -39,6 +39,7 @@ import (
 const (
 	dbVersionKey = "version" // Version of the database to flush if changes
 	dbItemPrefix = "n:"      // Identifier to prefix node entries with
+	dbPeerPrefix = "p:"      // Identifier to prefix peer reputation entries with, kept across node expiry
 
 	dbDiscoverRoot      = ":discover"
 	dbDiscoverSeq       = dbDiscoverRoot + ":seq"
@@ -47,6 +48,10 @@ const (
 	dbDiscoverFindFails = dbDiscoverRoot + ":findfail"
 	dbLocalRoot         = ":local"
 	dbLocalSeq          = dbLocalRoot + ":seq"
+	dbPeerRoot          = ":peer"
+	dbPeerScore         = dbPeerRoot + ":score"
+	dbPeerScoreTime     = dbPeerRoot + ":scoretime"
+	dbPeerBanned        = dbPeerRoot + ":banned"
 )
 
 var (
@@ -128,6 +133,12 @@ func makeKey(id ID, field string) []byte {
 	return append([]byte(dbItemPrefix), append(id[:], field...)...)
 }
 
+// makePeerKey generates the leveldb key-blob of a peer reputation field. These
+// are kept apart from the node entries so they outlive the expiry of the node.
+func makePeerKey(id ID, field string) []byte {
+	return append([]byte(dbPeerPrefix), append(id[:], field...)...)
+}
+
 // splitKey tries to split a database key into a node id and a field part.
 func splitKey(key []byte) (id ID, field string) {
 	// If the key is not of a node, return it plainly
@@ -326,6 +337,55 @@ func (db *DB) UpdateFindFails(id ID, fails int) error {
 	return db.storeInt64(makeKey(id, dbDiscoverFindFails), int64(fails))
 }
 
+// PeerScore retrieves the reputation score of a remote node, along with the time
+// it was last updated.
+func (db *DB) PeerScore(id ID) (int64, time.Time) {
+	return db.fetchInt64(makePeerKey(id, dbPeerScore)), time.Unix(db.fetchInt64(makePeerKey(id, dbPeerScoreTime)), 0)
+}
+
+// UpdatePeerScore updates the reputation score of a remote node.
+func (db *DB) UpdatePeerScore(id ID, score int64, instance time.Time) error {
+	if err := db.storeInt64(makePeerKey(id, dbPeerScore), score); err != nil {
+		return err
+	}
+	return db.storeInt64(makePeerKey(id, dbPeerScoreTime), instance.Unix())
+}
+
+// BannedUntil retrieves the time until which a remote node is banned.
+func (db *DB) BannedUntil(id ID) time.Time {
+	return time.Unix(db.fetchInt64(makePeerKey(id, dbPeerBanned)), 0)
+}
+
+// UpdateBannedUntil updates the time until which a remote node is banned.
+func (db *DB) UpdateBannedUntil(id ID, instance time.Time) error {
+	return db.storeInt64(makePeerKey(id, dbPeerBanned), instance.Unix())
+}
+
+// ScoredNodes retrieves the ids of all the nodes that have a reputation score
+// or a ban entry in the database.
+func (db *DB) ScoredNodes() []ID {
+	var (
+		ids  []ID
+		seen = make(map[ID]bool)
+		it   = db.lvl.NewIterator(util.BytesPrefix([]byte(dbPeerPrefix)), nil)
+	)
+	defer it.Release()
+
+	for it.Next() {
+		key := it.Key()[len(dbPeerPrefix):]
+		if len(key) < len(ID{}) {
+			continue
+		}
+		var id ID
+		copy(id[:], key)
+		if field := string(key[len(id):]); (field == dbPeerScore || field == dbPeerBanned) && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
 // LocalSeq retrieves the local record sequence counter.
 func (db *DB) localSeq(id ID) uint64 {
 	return db.fetchUint64(makeKey(id, dbLocalSeq))
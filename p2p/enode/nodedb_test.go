@@ -142,6 +142,29 @@ func TestDBFetchStore(t *testing.T) {
 	if stored := db.FindFails(node.ID()); stored != num {
 		t.Errorf("find-node fails: value mismatch: have %v, want %v", stored, num)
 	}
+	// Check fetch/store operations on a node score object
+	if stored, _ := db.PeerScore(node.ID()); stored != 0 {
+		t.Errorf("score: non-existing object: %v", stored)
+	}
+	if err := db.UpdatePeerScore(node.ID(), -int64(num), inst); err != nil {
+		t.Errorf("score: failed to update: %v", err)
+	}
+	if stored, updated := db.PeerScore(node.ID()); stored != -int64(num) || updated.Unix() != inst.Unix() {
+		t.Errorf("score: value mismatch: have %v/%v, want %v/%v", stored, updated, -num, inst)
+	}
+	// Check fetch/store operations on a node ban object
+	if stored := db.BannedUntil(node.ID()); stored.Unix() != 0 {
+		t.Errorf("ban: non-existing object: %v", stored)
+	}
+	if err := db.UpdateBannedUntil(node.ID(), inst); err != nil {
+		t.Errorf("ban: failed to update: %v", err)
+	}
+	if stored := db.BannedUntil(node.ID()); stored.Unix() != inst.Unix() {
+		t.Errorf("ban: value mismatch: have %v, want %v", stored, inst)
+	}
+	if ids := db.ScoredNodes(); len(ids) != 1 || ids[0] != node.ID() {
+		t.Errorf("scored nodes: mismatch: have %v, want [%v]", ids, node.ID())
+	}
 	// Check fetch/store operations on an actual node object
 	if stored := db.Node(node.ID()); stored != nil {
 		t.Errorf("node: non-existing object: %v", stored)
@@ -359,6 +382,13 @@ func TestDBExpiration(t *testing.T) {
 			t.Fatalf("node %d: failed to update bondTime: %v", i, err)
 		}
 	}
+	// Ban all of them, the reputation must survive the expiry
+	banned := time.Now().Add(time.Hour)
+	for i, seed := range nodeDBExpirationNodes {
+		if err := db.UpdateBannedUntil(seed.node.ID(), banned); err != nil {
+			t.Fatalf("node %d: failed to update ban: %v", i, err)
+		}
+	}
 	// Expire some of them, and check the rest
 	if err := db.expireNodes(); err != nil {
 		t.Fatalf("failed to expire nodes: %v", err)
@@ -368,5 +398,8 @@ func TestDBExpiration(t *testing.T) {
 		if (node == nil && !seed.exp) || (node != nil && seed.exp) {
 			t.Errorf("node %d: expiration mismatch: have %v, want %v", i, node, seed.exp)
 		}
+		if until := db.BannedUntil(seed.node.ID()); until.Unix() != banned.Unix() {
+			t.Errorf("node %d: ban mismatch: have %v, want %v", i, until, banned)
+		}
 	}
 }
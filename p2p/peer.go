@@ -23,6 +23,7 @@ import (
 	"net"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common/mclock"
@@ -113,6 +114,9 @@ type Peer struct {
 
 	// events receives message send / receive events if set
 	events *event.Feed
+
+	// scorer tracks the reputation of the peer if set
+	scorer *peerScorer
 }
 
 // NewPeer returns a peer for testing purposes.
@@ -165,6 +169,19 @@ func (p *Peer) Disconnect(reason DiscReason) {
 	}
 }
 
+// Report penalizes the peer for a protocol misbehaviour. If its reputation drops
+// too low, the peer is disconnected and banned for a while. Trusted peers are
+// penalized but never banned.
+func (p *Peer) Report(m Misbehaviour) {
+	if p.scorer == nil {
+		return
+	}
+	if p.scorer.report(p.ID(), m, p.rw.is(trustedConn)) {
+		p.log.Debug("Disconnecting misbehaving peer", "reason", m)
+		p.Disconnect(DiscUselessPeer)
+	}
+}
+
 // String implements fmt.Stringer.
 func (p *Peer) String() string {
 	id := p.ID()
@@ -190,6 +207,17 @@ func newPeer(conn *conn, protocols []Protocol) *Peer {
 	return p
 }
 
+// setRateLimits configures the inbound traffic limits of the running protocols.
+// It must be called before the peer is started.
+func (p *Peer) setRateLimits(limits map[string]RateLimit, clock mclock.Clock) {
+	for name, limit := range limits {
+		if proto := p.running[name]; proto != nil && limit.Rate > 0 {
+			proto.limiter = newTokenBucket(limit, clock)
+			proto.queue = make(chan Msg, throttleQueueSize)
+		}
+	}
+}
+
 func (p *Peer) Log() log.Logger {
 	return p.log
 }
@@ -298,6 +326,21 @@ func (p *Peer) handle(msg Msg) error {
 		if err != nil {
 			return fmt.Errorf("msg code out of range: %v", msg.Code)
 		}
+		proto.traffic.ingress(msg.Code-proto.offset, msg.Size)
+
+		// Rate limited protocols are delivered by their own throttle loop, so
+		// that waiting for their allowance doesn't hold up the other protocols.
+		if proto.queue != nil {
+			if atomic.AddInt64(&proto.queued, int64(msg.Size)) > proto.limiter.backlog() {
+				return fmt.Errorf("%s/%d traffic limit exceeded", proto.Name, proto.Version)
+			}
+			select {
+			case proto.queue <- msg:
+				return nil
+			default:
+				return fmt.Errorf("%s/%d traffic limit exceeded", proto.Name, proto.Version)
+			}
+		}
 		select {
 		case proto.in <- msg:
 			return nil
@@ -335,7 +378,7 @@ outer:
 					offset -= old.Length
 				}
 				// Assign the new match
-				result[cap.Name] = &protoRW{Protocol: proto, offset: offset, in: make(chan Msg), w: rw}
+				result[cap.Name] = &protoRW{Protocol: proto, offset: offset, in: make(chan Msg), w: rw, traffic: newProtoTraffic(proto)}
 				offset += proto.Length
 
 				continue outer
@@ -352,6 +395,10 @@ func (p *Peer) startProtocols(writeStart <-chan struct{}, writeErr chan<- error)
 		proto.closed = p.closed
 		proto.wstart = writeStart
 		proto.werr = writeErr
+		if proto.queue != nil {
+			p.wg.Add(1)
+			go p.throttleLoop(proto)
+		}
 		var rw MsgReadWriter = proto
 		if p.events != nil {
 			rw = newMsgEventer(rw, p.events, p.ID(), proto.Name)
@@ -371,6 +418,34 @@ func (p *Peer) startProtocols(writeStart <-chan struct{}, writeErr chan<- error)
 	}
 }
 
+// throttleLoop delivers the messages of a rate limited protocol, delaying them
+// while the protocol exceeds its traffic allowance.
+func (p *Peer) throttleLoop(proto *protoRW) {
+	defer p.wg.Done()
+
+	for {
+		select {
+		case msg := <-proto.queue:
+			if wait := proto.limiter.take(msg.Size); wait > 0 {
+				proto.traffic.throttle(wait)
+				select {
+				case <-proto.limiter.clock.After(wait):
+				case <-p.closed:
+					return
+				}
+			}
+			select {
+			case proto.in <- msg:
+				atomic.AddInt64(&proto.queued, -int64(msg.Size))
+			case <-p.closed:
+				return
+			}
+		case <-p.closed:
+			return
+		}
+	}
+}
+
 // getProto finds the protocol responsible for handling
 // the given message code.
 func (p *Peer) getProto(code uint64) (*protoRW, error) {
@@ -383,23 +458,32 @@ func (p *Peer) getProto(code uint64) (*protoRW, error) {
 }
 
 type protoRW struct {
+	queued int64 // bytes waiting for the limiter, first for 64-bit atomic alignment
+
 	Protocol
-	in     chan Msg        // receives read messages
-	closed <-chan struct{} // receives when peer is shutting down
-	wstart <-chan struct{} // receives when write may start
-	werr   chan<- error    // for write results
-	offset uint64
-	w      MsgWriter
+	in      chan Msg        // receives read messages
+	closed  <-chan struct{} // receives when peer is shutting down
+	wstart  <-chan struct{} // receives when write may start
+	werr    chan<- error    // for write results
+	offset  uint64
+	w       MsgWriter
+	traffic *protoTraffic // traffic accounting of the protocol
+	limiter *tokenBucket  // inbound traffic limiter, nil if unlimited
+	queue   chan Msg      // messages waiting for the limiter, nil if unlimited
 }
 
 func (rw *protoRW) WriteMsg(msg Msg) (err error) {
 	if msg.Code >= rw.Length {
 		return newPeerError(errInvalidMsgCode, "not handled")
 	}
+	code, size := msg.Code, msg.Size
 	msg.Code += rw.offset
 	select {
 	case <-rw.wstart:
 		err = rw.w.WriteMsg(msg)
+		if err == nil {
+			rw.traffic.egress(code, size)
+		}
 		// Report write status back to Peer.run. It will initiate
 		// shutdown if the error is non-nil and unblock the next write
 		// otherwise. The calling protocol code should exit for errors
@@ -436,7 +520,8 @@ type PeerInfo struct {
 		Trusted       bool   `json:"trusted"`
 		Static        bool   `json:"static"`
 	} `json:"network"`
-	Protocols map[string]interface{} `json:"protocols"` // Sub-protocol specific metadata fields
+	Protocols map[string]interface{}      `json:"protocols"` // Sub-protocol specific metadata fields
+	Traffic   map[string]*ProtocolTraffic `json:"traffic"`   // Sub-protocol message traffic
 }
 
 // Info gathers and returns a collection of metadata known about a peer.
@@ -453,6 +538,7 @@ func (p *Peer) Info() *PeerInfo {
 		Name:      p.Name(),
 		Caps:      caps,
 		Protocols: make(map[string]interface{}),
+		Traffic:   make(map[string]*ProtocolTraffic),
 	}
 	info.Network.LocalAddress = p.LocalAddr().String()
 	info.Network.RemoteAddress = p.RemoteAddr().String()
@@ -471,6 +557,7 @@ func (p *Peer) Info() *PeerInfo {
 			}
 		}
 		info.Protocols[proto.Name] = protoInfo
+		info.Traffic[proto.Name] = proto.traffic.info()
 	}
 	return info
 }
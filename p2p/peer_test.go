@@ -24,6 +24,8 @@ import (
 	"reflect"
 	"testing"
 	"time"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
 )
 
 var discard = Protocol{
@@ -44,6 +46,12 @@ var discard = Protocol{
 }
 
 func testPeer(protos []Protocol) (func(), *conn, *Peer, <-chan error) {
+	return testLimitedPeer(protos, nil, nil)
+}
+
+// testLimitedPeer is like testPeer, but applies inbound rate limits to the
+// protocols before starting the peer.
+func testLimitedPeer(protos []Protocol, limits map[string]RateLimit, clock mclock.Clock) (func(), *conn, *Peer, <-chan error) {
 	fd1, fd2 := net.Pipe()
 	c1 := &conn{fd: fd1, node: newNode(randomID(), nil), transport: newTestTransport(&newkey().PublicKey, fd1)}
 	c2 := &conn{fd: fd2, node: newNode(randomID(), nil), transport: newTestTransport(&newkey().PublicKey, fd2)}
@@ -53,6 +61,7 @@ func testPeer(protos []Protocol) (func(), *conn, *Peer, <-chan error) {
 	}
 
 	peer := newPeer(c1, protos)
+	peer.setRateLimits(limits, clock)
 	errc := make(chan error, 1)
 	go func() {
 		_, err := peer.run()
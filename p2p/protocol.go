@@ -56,6 +56,11 @@ type Protocol struct {
 
 	// Attributes contains protocol specific information for the node record.
 	Attributes []enr.Entry
+
+	// DialFilter is an optional helper method to reject dial candidates whose
+	// node record shows they are incompatible with the protocol. Nodes which
+	// don't advertise any information about the protocol should be accepted.
+	DialFilter func(n *enode.Node) error
 }
 
 func (p Protocol) cap() Cap {
@@ -0,0 +1,186 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+const (
+	// defaultScoreThreshold is the score at or below which a peer is disconnected
+	// and banned if the server configuration doesn't specify one.
+	defaultScoreThreshold = -100
+
+	// defaultBanDuration is the time a misbehaving peer is banned for if the server
+	// configuration doesn't specify one.
+	defaultBanDuration = time.Hour
+
+	// scoreRecoveryInterval is the time it takes for a penalized peer to recover
+	// a single point of its score, allowing occasional faults to be forgotten.
+	scoreRecoveryInterval = time.Minute
+)
+
+// Misbehaviour enumerates the faults sub-protocols can report about remote peers.
+// They are reported by eth and les when syncing and fetching, by whisper/v6 for
+// malformed or abusive envelope traffic and by the swarm stream protocol for
+// invalid chunk deliveries.
+type Misbehaviour int
+
+const (
+	BadBlock           Misbehaviour = iota // Peer propagated an invalid block or chain
+	InvalidTransaction                     // Peer propagated an invalid transaction
+	RequestTimeout                         // Peer failed to answer a request in time
+	UselessResponse                        // Peer answered with data that wasn't requested
+	InvalidMessage                         // Peer sent an invalid protocol message or content
+)
+
+var misbehaviourPenalties = [...]int64{
+	BadBlock:           100,
+	InvalidTransaction: 10,
+	RequestTimeout:     5,
+	UselessResponse:    2,
+	InvalidMessage:     20,
+}
+
+var misbehaviourToString = [...]string{
+	BadBlock:           "bad block",
+	InvalidTransaction: "invalid transaction",
+	RequestTimeout:     "request timeout",
+	UselessResponse:    "useless response",
+	InvalidMessage:     "invalid message",
+}
+
+func (m Misbehaviour) String() string {
+	if int(m) < len(misbehaviourToString) {
+		return misbehaviourToString[m]
+	}
+	return fmt.Sprintf("unknown misbehaviour %d", int(m))
+}
+
+// penalty returns the number of points a peer loses for the misbehaviour.
+func (m Misbehaviour) penalty() int64 {
+	if int(m) < len(misbehaviourPenalties) {
+		return misbehaviourPenalties[m]
+	}
+	return 0
+}
+
+// PeerScore is the reputation summary of a remote node, as tracked by the server.
+type PeerScore struct {
+	ID          string     `json:"id"`                    // Unique node identifier
+	Score       int64      `json:"score"`                 // Current reputation score, zero being neutral
+	BannedUntil *time.Time `json:"bannedUntil,omitempty"` // Expiration of the ban, if currently banned
+}
+
+// peerScorer tracks the reputation of remote nodes based on the misbehaviours
+// reported by the sub-protocols, banning them if their score sinks too low. The
+// scores and bans are persisted in the node database.
+type peerScorer struct {
+	db        *enode.DB
+	threshold int64
+	banTime   time.Duration
+	lock      sync.Mutex // Serializes read-modify-write score updates
+}
+
+func newPeerScorer(db *enode.DB, threshold int64, banTime time.Duration) *peerScorer {
+	if threshold >= 0 {
+		threshold = defaultScoreThreshold
+	}
+	if banTime <= 0 {
+		banTime = defaultBanDuration
+	}
+	return &peerScorer{db: db, threshold: threshold, banTime: banTime}
+}
+
+// score retrieves the current score of a node, applying the time based recovery
+// since its last update.
+func (s *peerScorer) score(id enode.ID, now time.Time) int64 {
+	score, updated := s.db.PeerScore(id)
+	if score < 0 {
+		score += int64(now.Sub(updated) / scoreRecoveryInterval)
+		if score > 0 {
+			score = 0
+		}
+	}
+	return score
+}
+
+// report penalizes a node for a misbehaviour. If the score drops to or below the
+// threshold, the node is banned and its score reset. The return value reports
+// whether the node got banned and needs to be disconnected.
+func (s *peerScorer) report(id enode.ID, m Misbehaviour, exempt bool) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := time.Now()
+	score := s.score(id, now) - m.penalty()
+	if score > s.threshold || exempt {
+		if err := s.db.UpdatePeerScore(id, score, now); err != nil {
+			log.Warn("Failed to store peer score", "id", id, "err", err)
+		}
+		log.Debug("Penalized peer", "id", id, "reason", m, "score", score)
+		return false
+	}
+	if err := s.db.UpdateBannedUntil(id, now.Add(s.banTime)); err != nil {
+		log.Warn("Failed to store peer ban", "id", id, "err", err)
+	}
+	if err := s.db.UpdatePeerScore(id, 0, now); err != nil {
+		log.Warn("Failed to store peer score", "id", id, "err", err)
+	}
+	log.Debug("Banned misbehaving peer", "id", id, "reason", m, "duration", s.banTime)
+	return true
+}
+
+// banned returns whether the node is currently banned.
+func (s *peerScorer) banned(id enode.ID) bool {
+	return s.db.BannedUntil(id).After(time.Now())
+}
+
+// scores returns the reputation summaries of all the nodes in the database,
+// sorted by node identifier.
+func (s *peerScorer) scores() []*PeerScore {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var (
+		now    = time.Now()
+		scores []*PeerScore
+	)
+	for _, id := range s.db.ScoredNodes() {
+		score := &PeerScore{ID: id.String(), Score: s.score(id, now)}
+		if until := s.db.BannedUntil(id); until.After(now) {
+			score.BannedUntil = &until
+		}
+		scores = append(scores, score)
+	}
+	sort.Sort(peerScoresByID(scores))
+	return scores
+}
+
+// peerScoresByID implements sort.Interface to order reputation summaries by
+// node identifier.
+type peerScoresByID []*PeerScore
+
+func (s peerScoresByID) Len() int           { return len(s) }
+func (s peerScoresByID) Less(i, j int) bool { return s[i].ID < s[j].ID }
+func (s peerScoresByID) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
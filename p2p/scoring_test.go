@@ -0,0 +1,98 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+// Tests that misbehaviours lower a peer's score until it gets banned, and that
+// the ban resets the score.
+func TestPeerScorerBan(t *testing.T) {
+	db, _ := enode.OpenDB("")
+	defer db.Close()
+
+	scorer := newPeerScorer(db, -20, time.Hour)
+	id := enode.ID{1}
+
+	for i := 0; i < 3; i++ {
+		if scorer.report(id, RequestTimeout, false) {
+			t.Fatalf("report %d: peer banned too early", i)
+		}
+	}
+	if score := scorer.score(id, time.Now()); score != -15 {
+		t.Fatalf("score mismatch: have %d, want %d", score, -15)
+	}
+	if scorer.banned(id) {
+		t.Fatalf("peer banned before reaching threshold")
+	}
+	if !scorer.report(id, RequestTimeout, false) {
+		t.Fatalf("peer not banned at threshold")
+	}
+	if !scorer.banned(id) {
+		t.Fatalf("ban not persisted")
+	}
+	if score := scorer.score(id, time.Now()); score != 0 {
+		t.Fatalf("score not reset after ban: have %d, want %d", score, 0)
+	}
+	scores := scorer.scores()
+	if len(scores) != 1 || scores[0].ID != id.String() || scores[0].BannedUntil == nil {
+		t.Fatalf("score summary mismatch: have %+v", scores)
+	}
+}
+
+// Tests that exempt peers are penalized but never banned.
+func TestPeerScorerExempt(t *testing.T) {
+	db, _ := enode.OpenDB("")
+	defer db.Close()
+
+	scorer := newPeerScorer(db, 0, 0)
+	id := enode.ID{1}
+
+	for i := 0; i < 3; i++ {
+		if scorer.report(id, BadBlock, true) {
+			t.Fatalf("report %d: exempt peer banned", i)
+		}
+	}
+	if scorer.banned(id) {
+		t.Fatalf("exempt peer banned")
+	}
+	if score := scorer.score(id, time.Now()); score != -3*BadBlock.penalty() {
+		t.Fatalf("score mismatch: have %d, want %d", score, -3*BadBlock.penalty())
+	}
+}
+
+// Tests that penalized peers slowly recover their score over time.
+func TestPeerScorerRecovery(t *testing.T) {
+	db, _ := enode.OpenDB("")
+	defer db.Close()
+
+	scorer := newPeerScorer(db, 0, 0)
+	id := enode.ID{1}
+
+	now := time.Now()
+	db.UpdatePeerScore(id, -50, now.Add(-10*scoreRecoveryInterval))
+	if score := scorer.score(id, now); score != -40 {
+		t.Fatalf("score mismatch: have %d, want %d", score, -40)
+	}
+	if score := scorer.score(id, now.Add(time.Hour)); score != 0 {
+		t.Fatalf("score recovered above neutral: have %d, want %d", score, 0)
+	}
+}
@@ -36,6 +36,7 @@ import (
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p/discover"
 	"github.com/ethereum/go-ethereum/p2p/discv5"
+	"github.com/ethereum/go-ethereum/p2p/dnsdisc"
 	"github.com/ethereum/go-ethereum/p2p/enode"
 	"github.com/ethereum/go-ethereum/p2p/enr"
 	"github.com/ethereum/go-ethereum/p2p/nat"
@@ -101,6 +102,11 @@ type Config struct {
 	// protocol.
 	BootstrapNodesV5 []*discv5.Node `toml:",omitempty"`
 
+	// DiscoveryDNS is a list of enrtree:// URLs of DNS node lists (EIP-1459)
+	// which are used as an additional source of dial candidates. DNS
+	// discovery works even if NoDiscovery is set.
+	DiscoveryDNS []string `toml:",omitempty"`
+
 	// Static nodes are used as pre-configured connections which are always
 	// maintained and re-connected on disconnects.
 	StaticNodes []*enode.Node
@@ -143,6 +149,20 @@ type Config struct {
 	// If NoDial is true, the server will not dial any peers.
 	NoDial bool `toml:",omitempty"`
 
+	// PeerScoreThreshold is the reputation score at or below which a misbehaving
+	// peer is disconnected and banned. It must be negative, zero defaults to
+	// preset values.
+	PeerScoreThreshold int64 `toml:",omitempty"`
+
+	// PeerBanDuration is the time a misbehaving peer is banned for. Zero defaults
+	// to preset values.
+	PeerBanDuration time.Duration `toml:",omitempty"`
+
+	// ProtocolRateLimits limits the inbound message traffic of each peer on the
+	// named sub-protocols, so that a single busy protocol cannot starve the
+	// others. Messages over the limit are delayed, throttling the peer.
+	ProtocolRateLimits map[string]RateLimit `toml:",omitempty"`
+
 	// If EnableMsgEvents is set then the server will emit PeerEvents
 	// whenever a message is sent to or received from a peer
 	EnableMsgEvents bool
@@ -165,8 +185,10 @@ type Server struct {
 	running bool
 
 	nodedb       *enode.DB
+	scorer       *peerScorer
 	localnode    *enode.LocalNode
 	ntab         discoverTable
+	dnsdisc      *dnsdisc.Client
 	listener     net.Listener
 	ourHandshake *protoHandshake
 	lastLookup   time.Time
@@ -345,6 +367,38 @@ func (srv *Server) RemoveTrustedPeer(node *enode.Node) {
 	}
 }
 
+// ReportPeer penalizes the given node for a protocol misbehaviour, disconnecting
+// and banning it if its reputation drops too low.
+func (srv *Server) ReportPeer(id enode.ID, m Misbehaviour) {
+	srv.lock.Lock()
+	scorer := srv.scorer
+	srv.lock.Unlock()
+
+	if scorer == nil {
+		return
+	}
+	for _, p := range srv.Peers() {
+		if p.ID() == id {
+			p.Report(m)
+			return
+		}
+	}
+	scorer.report(id, m, false)
+}
+
+// PeerScores returns the reputation summaries of all the nodes the server has
+// scored, including the ones currently banned.
+func (srv *Server) PeerScores() []*PeerScore {
+	srv.lock.Lock()
+	scorer := srv.scorer
+	srv.lock.Unlock()
+
+	if scorer == nil {
+		return nil
+	}
+	return scorer.scores()
+}
+
 // SubscribePeers subscribes the given channel to peer events
 func (srv *Server) SubscribeEvents(ch chan *PeerEvent) event.Subscription {
 	return srv.peerFeed.Subscribe(ch)
@@ -362,6 +416,15 @@ func (srv *Server) Self() *enode.Node {
 	return ln.Node()
 }
 
+// LocalNode returns the local node record, which protocols may update with their
+// own entries while the server is running.
+func (srv *Server) LocalNode() *enode.LocalNode {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	return srv.localnode
+}
+
 // Stop terminates the server and all active peer connections.
 // It blocks until all active connections have been closed.
 func (srv *Server) Stop() {
@@ -458,6 +521,8 @@ func (srv *Server) Start() (err error) {
 
 	dynPeers := srv.maxDialedConns()
 	dialer := newDialState(srv.localnode.ID(), srv.StaticNodes, srv.BootstrapNodes, srv.ntab, dynPeers, srv.NetRestrict)
+	dialer.banned = srv.scorer.banned
+	dialer.filter = srv.dialFilter
 	srv.loopWG.Add(1)
 	go srv.run(dialer)
 	return nil
@@ -478,6 +543,7 @@ func (srv *Server) setupLocalNode() error {
 		return err
 	}
 	srv.nodedb = db
+	srv.scorer = newPeerScorer(db, srv.PeerScoreThreshold, srv.PeerBanDuration)
 	srv.localnode = enode.NewLocalNode(db, srv.PrivateKey)
 	srv.localnode.SetFallbackIP(net.IP{127, 0, 0, 1})
 	srv.localnode.Set(capsByNameAndVersion(srv.ourHandshake.Caps))
@@ -509,6 +575,14 @@ func (srv *Server) setupLocalNode() error {
 }
 
 func (srv *Server) setupDiscovery() error {
+	// DNS discovery
+	if len(srv.DiscoveryDNS) > 0 {
+		client, err := dnsdisc.NewClient(dnsdisc.Config{Logger: srv.log}, srv.DiscoveryDNS...)
+		if err != nil {
+			return err
+		}
+		srv.dnsdisc = client
+	}
 	if srv.NoDiscovery && !srv.DiscoveryV5 {
 		return nil
 	}
@@ -725,6 +799,8 @@ running:
 			if err == nil {
 				// The handshakes are done and it passed all checks.
 				p := newPeer(c, srv.Protocols)
+				p.scorer = srv.scorer
+				p.setRateLimits(srv.ProtocolRateLimits, mclock.System{})
 				// If message events are enabled, pass the peerFeed
 				// to the peer
 				if srv.EnableMsgEvents {
@@ -800,16 +876,42 @@ func (srv *Server) encHandshakeChecks(peers map[enode.ID]*Peer, inboundCount int
 		return DiscAlreadyConnected
 	case c.node.ID() == srv.localnode.ID():
 		return DiscSelf
+	case !c.is(trustedConn) && srv.scorer != nil && srv.scorer.banned(c.node.ID()):
+		return DiscUselessPeer
 	default:
 		return nil
 	}
 }
 
+// hasDialFilter reports whether any of the running protocols filters dial candidates.
+func (srv *Server) hasDialFilter() bool {
+	for _, p := range srv.Protocols {
+		if p.DialFilter != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// dialFilter checks a dynamic dial candidate against the dial filters of all the
+// running protocols, rejecting it if any of them deems it incompatible.
+func (srv *Server) dialFilter(n *enode.Node) error {
+	for _, p := range srv.Protocols {
+		if p.DialFilter == nil {
+			continue
+		}
+		if err := p.DialFilter(n); err != nil {
+			return fmt.Errorf("incompatible %s node: %v", p.Name, err)
+		}
+	}
+	return nil
+}
+
 func (srv *Server) maxInboundConns() int {
 	return srv.MaxPeers - srv.maxDialedConns()
 }
 func (srv *Server) maxDialedConns() int {
-	if srv.NoDiscovery || srv.NoDial {
+	if srv.NoDial || (srv.NoDiscovery && len(srv.DiscoveryDNS) == 0) {
 		return 0
 	}
 	r := srv.DialRatio
@@ -0,0 +1,54 @@
+// Copyright 2026 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"flag"
+	"net/http"
+	"os"
+
+	"github.com/ethereum/go-ethereum/eth/ethsim"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/simulations"
+	"github.com/ethereum/go-ethereum/p2p/simulations/adapters"
+)
+
+var (
+	addr   = flag.String("addr", ":8888", "simulation API listening address")
+	period = flag.Duration("period", ethsim.DefaultPeriod, "average block time of a unit power miner")
+)
+
+// main() starts a simulation network which contains eth nodes mining with a
+// reputation weighted fake proof-of-work
+func main() {
+	flag.Parse()
+
+	log.Root().SetHandler(log.LvlFilterHandler(log.LvlInfo, log.StreamHandler(os.Stderr, log.TerminalFormat(false))))
+
+	services := map[string]adapters.ServiceFunc{
+		ethsim.ServiceName: ethsim.NewServiceFunc(ethsim.Genesis(), *period),
+	}
+	adapter := adapters.NewSimAdapter(services)
+
+	log.Info("starting simulation server", "addr", *addr)
+	network := simulations.NewNetwork(adapter, &simulations.NetworkConfig{
+		DefaultService: ethsim.ServiceName,
+	})
+	if err := http.ListenAndServe(*addr, simulations.NewServer(network)); err != nil {
+		log.Crit("error starting simulation server", "err", err)
+	}
+}
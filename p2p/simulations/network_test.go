@@ -22,6 +22,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/p2p/enode"
 	"github.com/ethereum/go-ethereum/p2p/simulations/adapters"
 )
@@ -140,6 +141,82 @@ func TestNetworkSimulation(t *testing.T) {
 	}
 }
 
+// TestNetworkPeerBanning creates a two node simulation network, reports protocol
+// misbehaviours of one node to the other and checks that the offending node is
+// disconnected and banned once its score drops below the threshold.
+func TestNetworkPeerBanning(t *testing.T) {
+	adapter := adapters.NewSimAdapter(adapters.Services{
+		"test": newTestService,
+	})
+	network := NewNetwork(adapter, &NetworkConfig{
+		DefaultService: "test",
+	})
+	defer network.Shutdown()
+
+	ids := make([]enode.ID, 2)
+	for i := range ids {
+		node, err := network.NewNodeWithConfig(adapters.RandomNodeConfig())
+		if err != nil {
+			t.Fatalf("error creating node: %s", err)
+		}
+		if err := network.Start(node.ID()); err != nil {
+			t.Fatalf("error starting node: %s", err)
+		}
+		ids[i] = node.ID()
+	}
+	judge, _ := adapter.GetNode(ids[0])
+	culprit, _ := adapter.GetNode(ids[1])
+
+	// waitPeers waits until the judge has the expected number of peers
+	waitPeers := func(want int) {
+		t.Helper()
+		for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(10 * time.Millisecond) {
+			if judge.Server().PeerCount() == want {
+				return
+			}
+		}
+		t.Fatalf("peer count mismatch: have %d, want %d", judge.Server().PeerCount(), want)
+	}
+	if err := network.Connect(ids[0], ids[1]); err != nil {
+		t.Fatalf("error connecting nodes: %s", err)
+	}
+	waitPeers(1)
+
+	// Report a minor misbehaviour, which should only lower the score
+	judge.Server().ReportPeer(ids[1], p2p.RequestTimeout)
+
+	client, err := judge.Client()
+	if err != nil {
+		t.Fatalf("error getting node client: %s", err)
+	}
+	var scores []*p2p.PeerScore
+	if err := client.Call(&scores, "admin_peerScores"); err != nil {
+		t.Fatalf("error retrieving peer scores: %s", err)
+	}
+	if len(scores) != 1 || scores[0].ID != ids[1].String() || scores[0].Score >= 0 || scores[0].BannedUntil != nil {
+		t.Fatalf("unexpected scores after minor misbehaviour: %+v", scores)
+	}
+	if count := judge.Server().PeerCount(); count != 1 {
+		t.Fatalf("peer dropped after minor misbehaviour")
+	}
+	// Report a bad block, which should disconnect and ban the culprit
+	judge.Server().ReportPeer(ids[1], p2p.BadBlock)
+	waitPeers(0)
+
+	if err := client.Call(&scores, "admin_peerScores"); err != nil {
+		t.Fatalf("error retrieving peer scores: %s", err)
+	}
+	if len(scores) != 1 || scores[0].BannedUntil == nil {
+		t.Fatalf("culprit not reported banned: %+v", scores)
+	}
+	// Ensure the banned node cannot reconnect on its own
+	culprit.Server().AddPeer(judge.Node())
+	time.Sleep(500 * time.Millisecond)
+	if count := judge.Server().PeerCount(); count != 0 {
+		t.Fatalf("banned peer reconnected")
+	}
+}
+
 func triggerChecks(ctx context.Context, ids []enode.ID, trigger chan enode.ID, interval time.Duration) {
 	tick := time.NewTicker(interval)
 	defer tick.Stop()
@@ -0,0 +1,264 @@
+// Copyright 2026 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+const (
+	MetricsInboundMessages  = "p2p/InboundMessages"  // Prefix of the per-protocol inbound message meters
+	MetricsOutboundMessages = "p2p/OutboundMessages" // Prefix of the per-protocol outbound message meters
+	MetricsThrottled        = "p2p/Throttled"        // Prefix of the per-protocol throttled message meters
+)
+
+const (
+	// throttleQueueSize is the maximum number of messages of a rate limited
+	// protocol waiting for their delivery.
+	throttleQueueSize = 1024
+
+	// throttleBacklog is the maximum time the messages waiting for delivery
+	// may take to drain at the allowed rate of a protocol. A peer exceeding
+	// either limit is flooding the protocol and gets disconnected.
+	throttleBacklog = 10 * time.Second
+)
+
+// RateLimit is a token bucket limit on the inbound message traffic of a single
+// sub-protocol with a peer. The bucket is refilled with Rate bytes per second up
+// to Burst bytes, and every message received takes its size from the bucket.
+type RateLimit struct {
+	Rate  uint64 // Sustained traffic allowed, in bytes per second
+	Burst uint64 // Traffic allowed in a single burst, in bytes
+}
+
+// ParseRateLimits parses a comma separated list of per-protocol rate limits in
+// the <protocol>=<rate>[:<burst>] format, with both values given in bytes.
+func ParseRateLimits(s string) (map[string]RateLimit, error) {
+	limits := make(map[string]RateLimit)
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		parts := strings.SplitN(item, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid rate limit %q, want <protocol>=<rate>[:<burst>]", item)
+		}
+		var (
+			limit  RateLimit
+			err    error
+			values = strings.SplitN(parts[1], ":", 2)
+		)
+		if limit.Rate, err = strconv.ParseUint(values[0], 10, 64); err != nil || limit.Rate == 0 {
+			return nil, fmt.Errorf("invalid rate in rate limit %q", item)
+		}
+		if len(values) == 2 {
+			if limit.Burst, err = strconv.ParseUint(values[1], 10, 64); err != nil {
+				return nil, fmt.Errorf("invalid burst in rate limit %q", item)
+			}
+		}
+		limits[parts[0]] = limit
+	}
+	return limits, nil
+}
+
+// MsgTraffic is the traffic summary of a single message code of a sub-protocol.
+type MsgTraffic struct {
+	IngressBytes   uint64 `json:"ingressBytes"`
+	IngressPackets uint64 `json:"ingressPackets"`
+	EgressBytes    uint64 `json:"egressBytes"`
+	EgressPackets  uint64 `json:"egressPackets"`
+}
+
+// ProtocolTraffic is the traffic summary of a sub-protocol with a peer.
+type ProtocolTraffic struct {
+	MsgTraffic
+	Throttled time.Duration          `json:"throttled"` // Time spent waiting for the rate limiter
+	Codes     map[string]*MsgTraffic `json:"codes"`     // Traffic by message code
+}
+
+// protoTraffic tracks the traffic of a sub-protocol with a peer and meters it in
+// the per-protocol and per-message code metrics.
+type protoTraffic struct {
+	total     MsgTraffic
+	throttled time.Duration
+	codes     map[uint64]*MsgTraffic
+	lock      sync.Mutex // Protects the counters, reads and writes run concurrently
+
+	ingressMeters []codeMeters // Inbound meters, indexed by message code
+	egressMeters  []codeMeters // Outbound meters, indexed by message code
+	throttleMeter metrics.Meter
+}
+
+// codeMeters are the byte and packet meters of a single message code.
+type codeMeters struct {
+	bytes   metrics.Meter
+	packets metrics.Meter
+}
+
+// newProtoTraffic creates the traffic counters of a sub-protocol, along with the
+// meters of all of its message codes, so they don't need to be looked up in the
+// metrics registry for every message.
+func newProtoTraffic(proto Protocol) *protoTraffic {
+	t := &protoTraffic{
+		codes:         make(map[uint64]*MsgTraffic),
+		ingressMeters: make([]codeMeters, proto.Length),
+		egressMeters:  make([]codeMeters, proto.Length),
+		throttleMeter: newTrafficMeter(fmt.Sprintf("%s/%s/%d", MetricsThrottled, proto.Name, proto.Version)),
+	}
+	for code := uint64(0); code < proto.Length; code++ {
+		t.ingressMeters[code] = newCodeMeters(MetricsInboundMessages, proto, code)
+		t.egressMeters[code] = newCodeMeters(MetricsOutboundMessages, proto, code)
+	}
+	return t
+}
+
+func newCodeMeters(prefix string, proto Protocol, code uint64) codeMeters {
+	name := fmt.Sprintf("%s/%s/%d/%#02x", prefix, proto.Name, proto.Version, code)
+	return codeMeters{
+		bytes:   newTrafficMeter(name),
+		packets: newTrafficMeter(name + "/packets"),
+	}
+}
+
+// newTrafficMeter retrieves a meter from the default registry, or a no-op meter
+// if metrics collection is disabled.
+func newTrafficMeter(name string) metrics.Meter {
+	if !metrics.Enabled {
+		return metrics.NilMeter{}
+	}
+	return metrics.GetOrRegisterMeter(name, nil)
+}
+
+// code returns the counters of the given message code, creating them if needed.
+// The caller must hold the lock.
+func (t *protoTraffic) code(code uint64) *MsgTraffic {
+	c := t.codes[code]
+	if c == nil {
+		c = new(MsgTraffic)
+		t.codes[code] = c
+	}
+	return c
+}
+
+// ingress accounts for a message received from the peer.
+func (t *protoTraffic) ingress(code uint64, size uint32) {
+	t.lock.Lock()
+	c := t.code(code)
+	c.IngressBytes += uint64(size)
+	c.IngressPackets++
+	t.total.IngressBytes += uint64(size)
+	t.total.IngressPackets++
+	t.lock.Unlock()
+
+	t.ingressMeters[code].bytes.Mark(int64(size))
+	t.ingressMeters[code].packets.Mark(1)
+}
+
+// egress accounts for a message sent to the peer.
+func (t *protoTraffic) egress(code uint64, size uint32) {
+	t.lock.Lock()
+	c := t.code(code)
+	c.EgressBytes += uint64(size)
+	c.EgressPackets++
+	t.total.EgressBytes += uint64(size)
+	t.total.EgressPackets++
+	t.lock.Unlock()
+
+	t.egressMeters[code].bytes.Mark(int64(size))
+	t.egressMeters[code].packets.Mark(1)
+}
+
+// throttle accounts for the time a message was delayed by the rate limiter.
+func (t *protoTraffic) throttle(wait time.Duration) {
+	t.lock.Lock()
+	t.throttled += wait
+	t.lock.Unlock()
+
+	t.throttleMeter.Mark(1)
+}
+
+// info returns a copy of the traffic summary.
+func (t *protoTraffic) info() *ProtocolTraffic {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	info := &ProtocolTraffic{
+		MsgTraffic: t.total,
+		Throttled:  t.throttled,
+		Codes:      make(map[string]*MsgTraffic, len(t.codes)),
+	}
+	for code, c := range t.codes {
+		copy := *c
+		info.Codes[fmt.Sprintf("%#02x", code)] = &copy
+	}
+	return info
+}
+
+// tokenBucket is a rate limiter allowing a steady traffic rate with bursts. It
+// isn't safe for concurrent use, messages are delivered by a single goroutine.
+type tokenBucket struct {
+	rate   float64 // Tokens refilled per second
+	burst  float64 // Maximum number of tokens
+	tokens float64 // Tokens currently available, negative if in debt
+	last   mclock.AbsTime
+	clock  mclock.Clock
+}
+
+func newTokenBucket(limit RateLimit, clock mclock.Clock) *tokenBucket {
+	burst := float64(limit.Burst)
+	if burst < float64(limit.Rate) {
+		burst = float64(limit.Rate)
+	}
+	return &tokenBucket{
+		rate:   float64(limit.Rate),
+		burst:  burst,
+		tokens: burst,
+		last:   clock.Now(),
+		clock:  clock,
+	}
+}
+
+// take removes n tokens from the bucket and returns how long the caller needs to
+// wait until the bucket is no longer in debt. Messages larger than the burst are
+// let through after waiting, rather than blocking the peer forever.
+func (b *tokenBucket) take(n uint32) time.Duration {
+	now := b.clock.Now()
+	b.tokens += b.rate * float64(now-b.last) / float64(time.Second)
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+
+	b.tokens -= float64(n)
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// backlog returns the number of bytes that may be waiting for the bucket before
+// the traffic is considered a flood.
+func (b *tokenBucket) backlog() int64 {
+	return int64(b.burst + b.rate*throttleBacklog.Seconds())
+}
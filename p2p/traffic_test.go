@@ -0,0 +1,218 @@
+// Copyright 2026 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+)
+
+func TestTokenBucket(t *testing.T) {
+	clock := new(mclock.Simulated)
+	bucket := newTokenBucket(RateLimit{Rate: 100, Burst: 200}, clock)
+
+	// The burst allowance can be used up front
+	if wait := bucket.take(200); wait != 0 {
+		t.Fatalf("burst wait mismatch: have %v, want 0", wait)
+	}
+	// Once depleted, the bucket refills at the configured rate
+	if wait := bucket.take(50); wait != 500*time.Millisecond {
+		t.Fatalf("depleted wait mismatch: have %v, want %v", wait, 500*time.Millisecond)
+	}
+	clock.Run(time.Second)
+	if wait := bucket.take(50); wait != 0 {
+		t.Fatalf("refilled wait mismatch: have %v, want 0", wait)
+	}
+	// Idle time doesn't accumulate more than the burst allowance
+	clock.Run(time.Minute)
+	if wait := bucket.take(300); wait != time.Second {
+		t.Fatalf("oversized wait mismatch: have %v, want %v", wait, time.Second)
+	}
+}
+
+func TestPeerTraffic(t *testing.T) {
+	proto := Protocol{
+		Name:    "a",
+		Version: 1,
+		Length:  5,
+		Run: func(peer *Peer, rw MsgReadWriter) error {
+			for i := 0; i < 2; i++ {
+				if err := ExpectMsg(rw, 2, []uint{1}); err != nil {
+					t.Error(err)
+				}
+			}
+			return SendItems(rw, 3, "foo")
+		},
+	}
+	closer, rw, peer, errc := testPeer([]Protocol{proto})
+	defer closer()
+
+	Send(rw, baseProtocolLength+2, []uint{1})
+	Send(rw, baseProtocolLength+2, []uint{1})
+	if err := ExpectMsg(rw, baseProtocolLength+3, []string{"foo"}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-errc:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("protocol timeout")
+	}
+	traffic := peer.Info().Traffic["a"]
+	if traffic == nil {
+		t.Fatalf("missing protocol traffic")
+	}
+	if traffic.IngressPackets != 2 || traffic.EgressPackets != 1 {
+		t.Errorf("packet count mismatch: have %d/%d, want 2/1", traffic.IngressPackets, traffic.EgressPackets)
+	}
+	if code := traffic.Codes["0x02"]; code == nil || code.IngressBytes != traffic.IngressBytes || code.IngressBytes == 0 {
+		t.Errorf("ingress code traffic mismatch: have %+v, want %d bytes", code, traffic.IngressBytes)
+	}
+	if code := traffic.Codes["0x03"]; code == nil || code.EgressPackets != 1 {
+		t.Errorf("egress code traffic mismatch: have %+v", code)
+	}
+}
+
+// throttleTestProtocol creates a protocol reporting the delivery of each of its
+// messages on the given channel.
+func throttleTestProtocol(name string, delivered chan<- string) Protocol {
+	return Protocol{
+		Name:    name,
+		Version: 1,
+		Length:  1,
+		Run: func(peer *Peer, rw MsgReadWriter) error {
+			for {
+				msg, err := rw.ReadMsg()
+				if err != nil {
+					return err
+				}
+				msg.Discard()
+				delivered <- name
+			}
+		},
+	}
+}
+
+// Tests that a protocol waiting for its traffic allowance doesn't hold up the
+// delivery of the other protocols of the peer.
+func TestPeerThrottleIsolation(t *testing.T) {
+	var (
+		clock     = new(mclock.Simulated)
+		delivered = make(chan string, 3)
+		protos    = []Protocol{throttleTestProtocol("a", delivered), throttleTestProtocol("b", delivered)}
+		limits    = map[string]RateLimit{"a": {Rate: 100, Burst: 100}}
+	)
+	closer, rw, _, _ := testLimitedPeer(protos, limits, clock)
+	defer closer()
+
+	expect := func(want string) {
+		select {
+		case have := <-delivered:
+			if have != want {
+				t.Fatalf("delivered protocol mismatch: have %s, want %s", have, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("message of %s not delivered", want)
+		}
+	}
+	// The first message fits in the burst allowance of a, the second one waits
+	payload := make([]byte, 80)
+	for i := 0; i < 2; i++ {
+		if err := Send(rw, baseProtocolLength, payload); err != nil {
+			t.Fatalf("failed to send message: %v", err)
+		}
+	}
+	expect("a")
+	clock.WaitForTimers(1)
+
+	// Messages of b are delivered while a is throttled
+	if err := Send(rw, baseProtocolLength+1, payload); err != nil {
+		t.Fatalf("failed to send message: %v", err)
+	}
+	expect("b")
+
+	select {
+	case name := <-delivered:
+		t.Fatalf("throttled message of %s delivered early", name)
+	case <-time.After(50 * time.Millisecond):
+	}
+	clock.Run(time.Second)
+	expect("a")
+}
+
+// Tests that a peer sending more than the backlog of a rate limited protocol is
+// disconnected.
+func TestPeerThrottleFlood(t *testing.T) {
+	var (
+		clock     = new(mclock.Simulated)
+		delivered = make(chan string, 3)
+		protos    = []Protocol{throttleTestProtocol("a", delivered)}
+		limits    = map[string]RateLimit{"a": {Rate: 10, Burst: 10}}
+	)
+	closer, rw, _, errc := testLimitedPeer(protos, limits, clock)
+	defer closer()
+
+	// The allowance and backlog of a are 110 bytes, two more messages overflow
+	payload := make([]byte, 80)
+	for i := 0; i < 3; i++ {
+		if err := Send(rw, baseProtocolLength, payload); err != nil {
+			break // peer already disconnected
+		}
+	}
+	select {
+	case err := <-errc:
+		if err == nil || !strings.Contains(err.Error(), "traffic limit exceeded") {
+			t.Fatalf("disconnect error mismatch: have %v, want traffic limit exceeded", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("flooding peer not disconnected")
+	}
+}
+
+func TestParseRateLimits(t *testing.T) {
+	tests := []struct {
+		input  string
+		limits map[string]RateLimit
+		fail   bool
+	}{
+		{input: "", limits: map[string]RateLimit{}},
+		{input: "shh=1024", limits: map[string]RateLimit{"shh": {Rate: 1024}}},
+		{input: "shh=1024:4096, bzz=2048", limits: map[string]RateLimit{"shh": {Rate: 1024, Burst: 4096}, "bzz": {Rate: 2048}}},
+		{input: "shh", fail: true},
+		{input: "=1024", fail: true},
+		{input: "shh=0", fail: true},
+		{input: "shh=fast", fail: true},
+		{input: "shh=1024:big", fail: true},
+	}
+	for _, tt := range tests {
+		limits, err := ParseRateLimits(tt.input)
+		if tt.fail {
+			if err == nil {
+				t.Errorf("input %q: expected error, have %v", tt.input, limits)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("input %q: unexpected error: %v", tt.input, err)
+		} else if !reflect.DeepEqual(limits, tt.limits) {
+			t.Errorf("input %q: limits mismatch: have %v, want %v", tt.input, limits, tt.limits)
+		}
+	}
+}
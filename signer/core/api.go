@@ -27,6 +27,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/accounts/typeddata"
 	"github.com/ethereum/go-ethereum/accounts/usbwallet"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
@@ -49,6 +50,8 @@ type ExternalAPI interface {
 	SignTransaction(ctx context.Context, args SendTxArgs, methodSelector *string) (*ethapi.SignTransactionResult, error)
 	// Sign - request to sign the given data (plus prefix)
 	Sign(ctx context.Context, addr common.MixedcaseAddress, data hexutil.Bytes) (hexutil.Bytes, error)
+	// SignTypedData - request to sign the given EIP-712 typed structured data
+	SignTypedData(ctx context.Context, addr common.MixedcaseAddress, data typeddata.TypedData) (hexutil.Bytes, error)
 	// Export - request to export an account
 	Export(ctx context.Context, addr common.Address) (json.RawMessage, error)
 	// Import - request to import an account
@@ -170,11 +173,13 @@ type (
 		NewPassword string `json:"new_password"`
 	}
 	SignDataRequest struct {
-		Address common.MixedcaseAddress `json:"address"`
-		Rawdata hexutil.Bytes           `json:"raw_data"`
-		Message string                  `json:"message"`
-		Hash    hexutil.Bytes           `json:"hash"`
-		Meta    Metadata                `json:"meta"`
+		Address  common.MixedcaseAddress    `json:"address"`
+		Rawdata  hexutil.Bytes              `json:"raw_data"`
+		Message  string                     `json:"message"`
+		Messages []*typeddata.NameValueType `json:"messages,omitempty"`
+		Hash     hexutil.Bytes              `json:"hash"`
+		Callinfo []ValidationInfo           `json:"call_info,omitempty"`
+		Meta     Metadata                   `json:"meta"`
 	}
 	SignDataResponse struct {
 		Approved bool `json:"approved"`
@@ -548,6 +553,79 @@ func (api *SignerAPI) Sign(ctx context.Context, addr common.MixedcaseAddress, da
 	return signature, nil
 }
 
+// SignTypedData calculates an Ethereum ECDSA signature for EIP-712 typed
+// structured data:
+// keccak256("\x19\x01" ‖ domainSeparator ‖ hashStruct(message))
+//
+// The typed data is validated and shown to the user in a human readable form
+// before signing. Hardware wallets are handed the domain separator and message
+// hash, so that the user can verify them on the device.
+//
+// Note, the produced signature conforms to the secp256k1 curve R, S and V values,
+// where the V value will be 27 or 28 for legacy reasons.
+//
+// https://eips.ethereum.org/EIPS/eip-712
+func (api *SignerAPI) SignTypedData(ctx context.Context, addr common.MixedcaseAddress, data typeddata.TypedData) (hexutil.Bytes, error) {
+	msgs, err := api.validator.ValidateTypedData(&data, api.chainID)
+	if err != nil {
+		return nil, err
+	}
+	// If we are in 'rejectMode', then reject rather than show the user warnings
+	if api.rejectMode {
+		if err := msgs.getWarnings(); err != nil {
+			return nil, err
+		}
+	}
+	domainSeparator, structHash, err := data.Hashes()
+	if err != nil {
+		return nil, err
+	}
+	messages, err := data.Format()
+	if err != nil {
+		return nil, err
+	}
+	var (
+		sighash = typeddata.SigHash(domainSeparator, structHash)
+		rawdata = append(append([]byte{0x19, 0x01}, domainSeparator.Bytes()...), structHash.Bytes()...)
+	)
+	// We make the request prior to looking up if we actually have the account, to prevent
+	// account-enumeration via the API
+	req := &SignDataRequest{
+		Address:  addr,
+		Rawdata:  rawdata,
+		Messages: messages,
+		Hash:     sighash,
+		Callinfo: msgs.Messages,
+		Meta:     MetadataFromContext(ctx),
+	}
+	res, err := api.UI.ApproveSignData(req)
+	if err != nil {
+		return nil, err
+	}
+	if !res.Approved {
+		return nil, ErrRequestDenied
+	}
+	// Look up the wallet containing the requested signer
+	account := accounts.Account{Address: addr.Address()}
+	wallet, err := api.am.Find(account)
+	if err != nil {
+		return nil, err
+	}
+	// Assemble sign the data with the wallet, hardware wallets sign the hashes
+	var signature []byte
+	if signer, ok := wallet.(accounts.TypedHashSigner); ok {
+		signature, err = signer.SignTypedHash(account, domainSeparator.Bytes(), structHash.Bytes())
+	} else {
+		signature, err = wallet.SignHashWithPassphrase(account, res.Password, sighash)
+	}
+	if err != nil {
+		api.UI.ShowError(err.Error())
+		return nil, err
+	}
+	signature[64] += 27 // Transform V from 0/1 to 27/28 according to the yellow paper
+	return signature, nil
+}
+
 // SignHash is a helper function that calculates a hash for the given message that can be
 // safely used to calculate a signature from.
 //
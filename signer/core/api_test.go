@@ -29,10 +29,12 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/accounts/typeddata"
 	"github.com/ethereum/go-ethereum/cmd/utils"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/rlp"
 )
@@ -284,6 +286,83 @@ func TestSignData(t *testing.T) {
 		t.Errorf("Expected 65 byte signature (got %d bytes)", len(h))
 	}
 }
+// mkTypedData creates EIP-712 typed data for a mail message bound to the given
+// chain, mirroring the example of the specification.
+func mkTypedData(chainID int64) typeddata.TypedData {
+	return typeddata.TypedData{
+		Types: typeddata.Types{
+			"EIP712Domain": {
+				{Name: "name", Type: "string"},
+				{Name: "version", Type: "string"},
+				{Name: "chainId", Type: "uint256"},
+				{Name: "verifyingContract", Type: "address"},
+			},
+			"Person": {{Name: "name", Type: "string"}, {Name: "wallet", Type: "address"}},
+			"Mail":   {{Name: "from", Type: "Person"}, {Name: "to", Type: "Person"}, {Name: "contents", Type: "string"}},
+		},
+		PrimaryType: "Mail",
+		Domain: typeddata.Domain{
+			Name:              "Ether Mail",
+			Version:           "1",
+			ChainId:           big.NewInt(chainID),
+			VerifyingContract: "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC",
+		},
+		Message: typeddata.Message{
+			"from":     map[string]interface{}{"name": "Cow", "wallet": "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826"},
+			"to":       map[string]interface{}{"name": "Bob", "wallet": "0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB"},
+			"contents": "Hello, Bob!",
+		},
+	}
+}
+
+func TestSignTypedData(t *testing.T) {
+	api, control := setup(t)
+	createAccount(control, api, t)
+	control <- "A"
+	list, err := api.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := common.NewMixedcaseAddress(list[0])
+
+	// Typed data bound to another chain must be rejected before reaching the UI
+	if _, err := api.SignTypedData(context.Background(), a, mkTypedData(2)); err == nil {
+		t.Errorf("Expected error for typed data of another chain")
+	}
+	control <- "No way"
+	h, err := api.SignTypedData(context.Background(), a, mkTypedData(1))
+	if h != nil {
+		t.Errorf("Expected nil-data, got %x", h)
+	}
+	if err != ErrRequestDenied {
+		t.Errorf("Expected ErrRequestDenied! %v", err)
+	}
+	control <- "Y"
+	control <- "a_long_password"
+	h, err = api.SignTypedData(context.Background(), a, mkTypedData(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h == nil || len(h) != 65 {
+		t.Fatalf("Expected 65 byte signature (got %d bytes)", len(h))
+	}
+	// Verify the signature was made over the EIP-712 hash by the requested account
+	data := mkTypedData(1)
+	domainSeparator, structHash, err := data.Hashes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := common.CopyBytes(h)
+	sig[64] -= 27
+	pubkey, err := crypto.SigToPub(typeddata.SigHash(domainSeparator, structHash), sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signer := crypto.PubkeyToAddress(*pubkey); signer != a.Address() {
+		t.Errorf("signer mismatch: have %x, want %x", signer, a.Address())
+	}
+}
+
 func mkTestTx(from common.MixedcaseAddress) SendTxArgs {
 	to := common.NewMixedcaseAddress(common.HexToAddress("0x1337"))
 	gas := hexutil.Uint64(21000)
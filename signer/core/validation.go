@@ -23,6 +23,7 @@ import (
 	"math/big"
 	"regexp"
 
+	"github.com/ethereum/go-ethereum/accounts/typeddata"
 	"github.com/ethereum/go-ethereum/common"
 )
 
@@ -96,6 +97,38 @@ func (v *Validator) validateCallData(msgs *ValidationMessages, data []byte, meth
 	}
 }
 
+// ValidateTypedData checks that the EIP-712 typed data is well formed, and that
+// its domain is bound to the chain the signer is configured for. It generates
+// warnings for domains which make the signature replayable.
+func (v *Validator) ValidateTypedData(data *typeddata.TypedData, chainID *big.Int) (*ValidationMessages, error) {
+	if err := data.Validate(); err != nil {
+		return nil, err
+	}
+	msgs := &ValidationMessages{}
+	domain := data.Domain
+	if domain.ChainId == nil {
+		msgs.warn("Typed data domain lacks a chainId, the signature is valid on every chain")
+	} else if chainID != nil && domain.ChainId.Cmp(chainID) != 0 {
+		// This is a showstopper
+		return nil, fmt.Errorf("Typed data domain is bound to chain %v, signer is on chain %v", domain.ChainId, chainID)
+	}
+	if domain.VerifyingContract != "" {
+		contract, err := common.NewMixedcaseAddressFromString(domain.VerifyingContract)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid verifying contract in typed data domain: %v", err)
+		}
+		if !contract.ValidChecksum() {
+			msgs.warn("Invalid checksum on verifying contract of typed data domain")
+		}
+	} else if domain.Salt == "" {
+		msgs.info("Typed data domain is not bound to a verifying contract or salt")
+	}
+	if data.PrimaryType == typeddata.DomainType {
+		msgs.crit("Typed data asks to sign a bare domain, not a message")
+	}
+	return msgs, nil
+}
+
 // validateSemantics checks if the transactions 'makes sense', and generate warnings for a couple of typical scenarios
 func (v *Validator) validate(msgs *ValidationMessages, txargs *SendTxArgs, methodSelector *string) error {
 	// Prevent accidental erroneous usage of both 'input' and 'data'
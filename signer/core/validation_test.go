@@ -21,6 +21,7 @@ import (
 	"math/big"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/accounts/typeddata"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 )
@@ -138,6 +139,44 @@ func TestValidator(t *testing.T) {
 	}
 }
 
+func TestTypedDataValidator(t *testing.T) {
+	v := NewValidator(nil)
+	chainID := big.NewInt(1)
+
+	// A domain bound to chain and contract raises no warnings
+	data := mkTypedData(1)
+	msgs, err := v.ValidateTypedData(&data, chainID)
+	if err != nil {
+		t.Fatalf("validation failed: %v", err)
+	}
+	if err := msgs.getWarnings(); err != nil {
+		t.Errorf("unexpected warnings: %v", err)
+	}
+	// A domain bound to another chain is rejected outright
+	data = mkTypedData(2)
+	if _, err := v.ValidateTypedData(&data, chainID); err == nil {
+		t.Errorf("expected error for domain of another chain")
+	}
+	// A domain without chain is replayable, and a badly checksummed contract suspicious
+	data = mkTypedData(1)
+	data.Domain.ChainId = nil
+	data.Domain.VerifyingContract = "0xcccccccccccccccccccccccccccccccccccccccc"
+	data.Types["EIP712Domain"] = []typeddata.Type{
+		{Name: "name", Type: "string"},
+		{Name: "version", Type: "string"},
+		{Name: "verifyingContract", Type: "address"},
+	}
+	if msgs, err = v.ValidateTypedData(&data, chainID); err != nil {
+		t.Fatalf("validation failed: %v", err)
+	}
+	if have, want := len(msgs.Messages), 2; have != want {
+		t.Errorf("validation message count mismatch: have %d, want %d: %v", have, want, msgs.Messages)
+	}
+	if err := msgs.getWarnings(); err == nil {
+		t.Errorf("expected warnings")
+	}
+}
+
 func TestPasswordValidation(t *testing.T) {
 	testcases := []struct {
 		pw         string
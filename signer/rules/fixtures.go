@@ -0,0 +1,184 @@
+// Copyright 2026 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package rules
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/signer/core"
+	"github.com/ethereum/go-ethereum/signer/storage"
+)
+
+// Decisions the rules can make on a request.
+const (
+	DecisionApprove = "Approve"
+	DecisionReject  = "Reject"
+	DecisionManual  = "Manual" // no decision, the request goes to the regular UI
+)
+
+// Fixture is a recorded transaction signing request, along with the decision
+// the rules are expected to make on it.
+type Fixture struct {
+	Name    string              `json:"name"`
+	Time    *time.Time          `json:"time,omitempty"` // time of the request, now if not set
+	Request *core.SignTxRequest `json:"request"`
+	Expect  string              `json:"expect"`
+}
+
+// FixtureAccount is the chain state of a counterparty, served to the
+// reputation helper in place of a node.
+type FixtureAccount struct {
+	Reputation uint64 `json:"reputation"`
+	Nonce      uint64 `json:"nonce"`
+	Contract   bool   `json:"contract"`
+}
+
+// FixtureSuite is a set of fixtures, replayed in order, and the chain state
+// they are evaluated against.
+type FixtureSuite struct {
+	Accounts map[common.Address]*FixtureAccount `json:"accounts"`
+	Tests    []*Fixture                         `json:"tests"`
+}
+
+// FixtureResult is the outcome of replaying a fixture.
+type FixtureResult struct {
+	Fixture *Fixture
+	Have    string // Decision made by the rules
+	Err     error  // Evaluation error, if the rules made no decision
+}
+
+// Passed reports whether the rules made the expected decision.
+func (r *FixtureResult) Passed() bool {
+	return r.Have == r.Fixture.Expect
+}
+
+// LoadFixtures reads a fixture suite from a JSON file.
+func LoadFixtures(path string) (*FixtureSuite, error) {
+	blob, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var suite FixtureSuite
+	if err := json.Unmarshal(blob, &suite); err != nil {
+		return nil, fmt.Errorf("invalid fixture file %s: %v", path, err)
+	}
+	for i, test := range suite.Tests {
+		if test.Request == nil {
+			return nil, fmt.Errorf("fixture %d (%s) has no request", i, test.Name)
+		}
+		switch test.Expect {
+		case DecisionApprove, DecisionReject, DecisionManual:
+		default:
+			return nil, fmt.Errorf("fixture %d (%s) expects unknown decision %q", i, test.Name, test.Expect)
+		}
+	}
+	return &suite, nil
+}
+
+// RunFixtures replays the fixtures of the suite in order against the rules.
+// The fixtures share an ephemeral storage, and the approved ones are reported
+// to OnApprovedTx, so that stateful rules can be tested across requests.
+func RunFixtures(jsRules string, suite *FixtureSuite) ([]*FixtureResult, error) {
+	r, err := NewRuleEvaluator(nil, storage.NewEphemeralStorage(), storage.NewEphemeralStorage())
+	if err != nil {
+		return nil, err
+	}
+	r.Init(jsRules)
+	r.SetChainBackend(fixtureChain(suite.Accounts))
+
+	results := make([]*FixtureResult, 0, len(suite.Tests))
+	for _, test := range suite.Tests {
+		r.now = time.Now
+		if test.Time != nil {
+			t := *test.Time
+			r.now = func() time.Time { return t }
+		}
+		result := &FixtureResult{Fixture: test}
+
+		jsonreq, err := json.Marshal(test.Request)
+		approved, err := r.checkApproval("ApproveTx", jsonreq, err)
+		switch {
+		case err != nil:
+			result.Have, result.Err = DecisionManual, err
+		case approved:
+			result.Have = DecisionApprove
+			r.replayApproved(test.Request)
+		default:
+			result.Have = DecisionReject
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// replayApproved reports an approved request to the rules as if it had been
+// signed. The transaction is left unsigned, so its sender is taken from the request.
+func (r *rulesetUI) replayApproved(request *core.SignTxRequest) {
+	args := request.Transaction
+	var data []byte
+	if args.Data != nil {
+		data = *args.Data
+	} else if args.Input != nil {
+		data = *args.Input
+	}
+	var tx *types.Transaction
+	if args.To == nil {
+		tx = types.NewContractCreation(uint64(args.Nonce), (*big.Int)(&args.Value), uint64(args.Gas), (*big.Int)(&args.GasPrice), data)
+	} else {
+		tx = types.NewTransaction(uint64(args.Nonce), args.To.Address(), (*big.Int)(&args.Value), uint64(args.Gas), (*big.Int)(&args.GasPrice), data)
+	}
+	r.recordSpending(args.From.Address(), tx.Value())
+
+	jsonTx, err := json.Marshal(ethapi.SignTransactionResult{Tx: tx})
+	if err == nil {
+		r.execute("OnApprovedTx", string(jsonTx))
+	}
+}
+
+// fixtureChain serves the accounts of a fixture suite to the rule helpers.
+// Unknown accounts have no reputation, no nonce and no code.
+type fixtureChain map[common.Address]*FixtureAccount
+
+func (c fixtureChain) account(address common.Address) *FixtureAccount {
+	if account, ok := c[address]; ok {
+		return account
+	}
+	return new(FixtureAccount)
+}
+
+func (c fixtureChain) ReputationAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	return c.account(account).Reputation, nil
+}
+
+func (c fixtureChain) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	return c.account(account).Nonce, nil
+}
+
+func (c fixtureChain) CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error) {
+	if c.account(account).Contract {
+		return []byte{0x00}, nil
+	}
+	return nil, nil
+}
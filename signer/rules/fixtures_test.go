@@ -0,0 +1,169 @@
+// Copyright 2026 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package rules
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+)
+
+// helperRules approves transfers of up to 1 ether a day to reputable accounts
+// and contracts, and rejects transfers to anyone else.
+const helperRules = `
+function ApproveTx(req) {
+	var tx = req.transaction;
+	var to = reputation(tx.to);
+	if (to.reputation == 0 && !to.contract) {
+		return "Reject";
+	}
+	if (limits.withinDaily(tx.from, tx.value, "1000000000000000000")) {
+		return "Approve";
+	}
+}
+`
+
+const helperFixtures = `{
+	"accounts": {
+		"0x000000000000000000000000000000000000beef": {"reputation": 3, "nonce": 10},
+		"0x000000000000000000000000000000000000c0de": {"contract": true}
+	},
+	"tests": [
+		{
+			"name": "first transfer of the day",
+			"time": "2018-10-19T09:00:00Z",
+			"request": {"transaction": {"from": "0x000000000000000000000000000000000000dead", "to": "0x000000000000000000000000000000000000beef", "value": "0x58d15e176280000", "gas": "0x5208", "gasPrice": "0x1", "nonce": "0x0"}},
+			"expect": "Approve"
+		},
+		{
+			"name": "second transfer within the daily limit",
+			"time": "2018-10-19T12:00:00Z",
+			"request": {"transaction": {"from": "0x000000000000000000000000000000000000dead", "to": "0x000000000000000000000000000000000000c0de", "value": "0x58d15e176280000", "gas": "0x5208", "gasPrice": "0x1", "nonce": "0x1"}},
+			"expect": "Approve"
+		},
+		{
+			"name": "third transfer exceeding the daily limit",
+			"time": "2018-10-19T18:00:00Z",
+			"request": {"transaction": {"from": "0x000000000000000000000000000000000000dead", "to": "0x000000000000000000000000000000000000beef", "value": "0x58d15e176280000", "gas": "0x5208", "gasPrice": "0x1", "nonce": "0x2"}},
+			"expect": "Manual"
+		},
+		{
+			"name": "transfer from another account",
+			"time": "2018-10-19T18:00:00Z",
+			"request": {"transaction": {"from": "0x000000000000000000000000000000000000f00d", "to": "0x000000000000000000000000000000000000beef", "value": "0x58d15e176280000", "gas": "0x5208", "gasPrice": "0x1", "nonce": "0x0"}},
+			"expect": "Approve"
+		},
+		{
+			"name": "transfer on the next day",
+			"time": "2018-10-20T09:00:00Z",
+			"request": {"transaction": {"from": "0x000000000000000000000000000000000000dead", "to": "0x000000000000000000000000000000000000beef", "value": "0x58d15e176280000", "gas": "0x5208", "gasPrice": "0x1", "nonce": "0x2"}},
+			"expect": "Approve"
+		},
+		{
+			"name": "transfer to an unknown account",
+			"time": "2018-10-20T09:00:00Z",
+			"request": {"transaction": {"from": "0x000000000000000000000000000000000000dead", "to": "0x0000000000000000000000000000000000000bad", "value": "0x1", "gas": "0x5208", "gasPrice": "0x1", "nonce": "0x3"}},
+			"expect": "Reject"
+		}
+	]
+}`
+
+func TestFixtures(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clef-fixtures")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "fixtures.json")
+	if err := ioutil.WriteFile(path, []byte(helperFixtures), 0600); err != nil {
+		t.Fatal(err)
+	}
+	suite, err := LoadFixtures(path)
+	if err != nil {
+		t.Fatalf("failed to load fixtures: %v", err)
+	}
+	results, err := RunFixtures(helperRules, suite)
+	if err != nil {
+		t.Fatalf("failed to run fixtures: %v", err)
+	}
+	if len(results) != len(suite.Tests) {
+		t.Fatalf("result count mismatch: have %d, want %d", len(results), len(suite.Tests))
+	}
+	for _, result := range results {
+		if !result.Passed() {
+			t.Errorf("fixture %q: decision mismatch: have %s, want %s (err: %v)", result.Fixture.Name, result.Have, result.Fixture.Expect, result.Err)
+		}
+	}
+}
+
+func TestFixturesInvalidExpectation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clef-fixtures")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "fixtures.json")
+	blob := `{"tests": [{"name": "typo", "request": {"transaction": {}}, "expect": "Approved"}]}`
+	if err := ioutil.WriteFile(path, []byte(blob), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFixtures(path); err == nil {
+		t.Fatal("expected error for unknown decision")
+	}
+}
+
+func TestReputationWithoutNode(t *testing.T) {
+	r, err := initRuleEngine(helperRules)
+	if err != nil {
+		t.Fatalf("failed to create evaluator: %v", err)
+	}
+	approved, err := r.checkApproval("ApproveTx", []byte(`{"transaction": {"from": "0x000000000000000000000000000000000000dead", "to": "0x000000000000000000000000000000000000beef", "value": "0x1"}}`), nil)
+	if err == nil {
+		t.Fatalf("expected reputation lookup to fail without a node, approved: %v", approved)
+	}
+}
+
+func TestDailySpendingOfSignedTx(t *testing.T) {
+	r, err := initRuleEngine(helperRules)
+	if err != nil {
+		t.Fatalf("failed to create evaluator: %v", err)
+	}
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	signer := types.NewEIP155Signer(big.NewInt(1))
+
+	for i := 0; i < 2; i++ {
+		tx := types.NewTransaction(uint64(i), common.HexToAddress("0xbeef"), big.NewInt(1000), 21000, big.NewInt(1), nil)
+		tx, err = types.SignTx(tx, signer, key)
+		if err != nil {
+			t.Fatalf("failed to sign transaction: %v", err)
+		}
+		r.OnApprovedTx(ethapi.SignTransactionResult{Tx: tx})
+	}
+	if have := r.dailySpent(from); have.Cmp(big.NewInt(2000)) != 0 {
+		t.Fatalf("daily spending mismatch: have %v, want %v", have, 2000)
+	}
+}
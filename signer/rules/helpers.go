@@ -0,0 +1,193 @@
+// Copyright 2026 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package rules
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/robertkrimen/otto"
+)
+
+const (
+	// dailyLimitPrefix is the storage key prefix of the daily spending records.
+	dailyLimitPrefix = "limits:daily:"
+
+	// chainTimeout is the maximum time spent on a chain lookup.
+	chainTimeout = 5 * time.Second
+)
+
+// ChainBackend is the access to the chain the rule helpers need to look up
+// counterparties. It is implemented by ethclient.Client.
+type ChainBackend interface {
+	ReputationAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+	CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error)
+}
+
+// dailySpending is the value spent by an account on a given (UTC) day.
+type dailySpending struct {
+	Day   string `json:"day"`
+	Spent string `json:"spent"` // decimal wei
+}
+
+// setHelpers installs the native rule helpers in the vm:
+//
+//   limits.dailySpent(address)              value sent by the account today, as a decimal string
+//   limits.withinDaily(address, value, max) whether sending value keeps today's total within max
+//   reputation(address)                     the {reputation, nonce, contract} of the account on chain
+//
+// Values are decimal or 0x-prefixed hexadecimal strings.
+func (r *rulesetUI) setHelpers(vm *otto.Otto) {
+	limits, _ := vm.Object("({})")
+	limits.Set("dailySpent", func(call otto.FunctionCall) otto.Value {
+		address, err := helperAddress(call.Argument(0))
+		if err != nil {
+			throwError(call, err)
+		}
+		v, _ := call.Otto.ToValue(r.dailySpent(address).String())
+		return v
+	})
+	limits.Set("withinDaily", func(call otto.FunctionCall) otto.Value {
+		address, err := helperAddress(call.Argument(0))
+		if err != nil {
+			throwError(call, err)
+		}
+		value, err := helperBig(call.Argument(1))
+		if err != nil {
+			throwError(call, err)
+		}
+		max, err := helperBig(call.Argument(2))
+		if err != nil {
+			throwError(call, err)
+		}
+		total := new(big.Int).Add(r.dailySpent(address), value)
+		v, _ := call.Otto.ToValue(total.Cmp(max) <= 0)
+		return v
+	})
+	vm.Set("limits", limits)
+
+	vm.Set("reputation", func(call otto.FunctionCall) otto.Value {
+		address, err := helperAddress(call.Argument(0))
+		if err != nil {
+			throwError(call, err)
+		}
+		info, err := r.lookupAccount(address)
+		if err != nil {
+			throwError(call, err)
+		}
+		v, _ := call.Otto.ToValue(info)
+		return v
+	})
+}
+
+// lookupAccount retrieves the reputation, nonce and kind of an account from the
+// configured chain backend.
+func (r *rulesetUI) lookupAccount(address common.Address) (map[string]interface{}, error) {
+	if r.chain == nil {
+		return nil, fmt.Errorf("no node configured")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), chainTimeout)
+	defer cancel()
+
+	reputation, err := r.chain.ReputationAt(ctx, address, nil)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := r.chain.NonceAt(ctx, address, nil)
+	if err != nil {
+		return nil, err
+	}
+	code, err := r.chain.CodeAt(ctx, address, nil)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"reputation": reputation,
+		"nonce":      nonce,
+		"contract":   len(code) > 0,
+	}, nil
+}
+
+// dailySpent returns the value sent by the account on the current day.
+func (r *rulesetUI) dailySpent(address common.Address) *big.Int {
+	var record dailySpending
+	if blob := r.storage.Get(dailyLimitKey(address)); blob != "" {
+		if err := json.Unmarshal([]byte(blob), &record); err != nil {
+			log.Warn("Invalid daily spending record", "address", address, "err", err)
+		}
+	}
+	spent, ok := new(big.Int).SetString(record.Spent, 10)
+	if !ok || record.Day != r.today() {
+		return new(big.Int)
+	}
+	return spent
+}
+
+// recordSpending adds the value to the spending of the account on the current day.
+func (r *rulesetUI) recordSpending(address common.Address, value *big.Int) {
+	record := dailySpending{
+		Day:   r.today(),
+		Spent: new(big.Int).Add(r.dailySpent(address), value).String(),
+	}
+	blob, err := json.Marshal(record)
+	if err != nil {
+		log.Warn("Failed to encode daily spending record", "err", err)
+		return
+	}
+	r.storage.Put(dailyLimitKey(address), string(blob))
+}
+
+func (r *rulesetUI) today() string {
+	return r.now().UTC().Format("2006-01-02")
+}
+
+func dailyLimitKey(address common.Address) string {
+	return dailyLimitPrefix + strings.ToLower(address.Hex())
+}
+
+func helperAddress(v otto.Value) (common.Address, error) {
+	s, err := v.ToString()
+	if err != nil || !common.IsHexAddress(s) {
+		return common.Address{}, fmt.Errorf("invalid address %v", v)
+	}
+	return common.HexToAddress(s), nil
+}
+
+func helperBig(v otto.Value) (*big.Int, error) {
+	s, err := v.ToString()
+	if err != nil {
+		return nil, err
+	}
+	n, ok := math.ParseBig256(s)
+	if !ok {
+		return nil, fmt.Errorf("invalid number %v", v)
+	}
+	return n, nil
+}
+
+// throwError raises a javascript exception, aborting the rule evaluation.
+func throwError(call otto.FunctionCall, err error) {
+	panic(call.Otto.MakeCustomError("Error", err.Error()))
+}
@@ -21,8 +21,10 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/signer/core"
@@ -52,7 +54,9 @@ type rulesetUI struct {
 	next        core.SignerUI // The next handler, for manual processing
 	storage     storage.Storage
 	credentials storage.Storage
-	jsRules     string // The rules to use
+	jsRules     string           // The rules to use
+	chain       ChainBackend     // Node the rule helpers look up counterparties on, nil if none
+	now         func() time.Time // Clock of the daily limits, replaced when replaying fixtures
 }
 
 func NewRuleEvaluator(next core.SignerUI, jsbackend, credentialsBackend storage.Storage) (*rulesetUI, error) {
@@ -61,6 +65,7 @@ func NewRuleEvaluator(next core.SignerUI, jsbackend, credentialsBackend storage.
 		storage:     jsbackend,
 		credentials: credentialsBackend,
 		jsRules:     "",
+		now:         time.Now,
 	}
 
 	return c, nil
@@ -70,6 +75,13 @@ func (r *rulesetUI) Init(javascriptRules string) error {
 	r.jsRules = javascriptRules
 	return nil
 }
+
+// SetChainBackend configures the node used by the rule helpers to look up
+// the counterparties of the requests.
+func (r *rulesetUI) SetChainBackend(chain ChainBackend) {
+	r.chain = chain
+}
+
 func (r *rulesetUI) execute(jsfunc string, jsarg interface{}) (otto.Value, error) {
 
 	// Instantiate a fresh vm engine every time
@@ -79,6 +91,7 @@ func (r *rulesetUI) execute(jsfunc string, jsarg interface{}) (otto.Value, error
 	consoleObj.Object().Set("log", consoleOutput)
 	consoleObj.Object().Set("error", consoleOutput)
 	vm.Set("storage", r.storage)
+	r.setHelpers(vm)
 
 	// Load bootstrap libraries
 	script, err := vm.Compile("bignumber.js", BigNumber_JS)
@@ -242,6 +255,19 @@ func (r *rulesetUI) OnSignerStartup(info core.StartupInfo) {
 }
 
 func (r *rulesetUI) OnApprovedTx(tx ethapi.SignTransactionResult) {
+	// Account for the value sent in the daily limits, whichever way the
+	// transaction was approved
+	if tx.Tx != nil {
+		var signer types.Signer = types.HomesteadSigner{}
+		if tx.Tx.Protected() {
+			signer = types.NewEIP155Signer(tx.Tx.ChainId())
+		}
+		if from, err := types.Sender(signer, tx.Tx); err == nil {
+			r.recordSpending(from, tx.Tx.Value())
+		} else {
+			log.Warn("Failed to recover sender of approved transaction", "err", err)
+		}
+	}
 	jsonTx, err := json.Marshal(tx)
 	if err != nil {
 		log.Warn("failed marshalling transaction", "tx", tx)
@@ -31,6 +31,7 @@ import (
 	"net/http"
 	"path"
 	"strings"
+	"sync"
 
 	"bytes"
 	"mime"
@@ -240,6 +241,9 @@ type API struct {
 	fileStore *storage.FileStore
 	dns       Resolver
 	Decryptor func(context.Context, string) DecryptFunc
+
+	signer     feed.Signer // signs bucket feed updates, nil without a node key
+	bucketLock sync.Mutex  // serialises bucket manifest updates
 }
 
 // NewAPI the api constructor initialises a new API instance.
@@ -252,6 +256,9 @@ func NewAPI(fileStore *storage.FileStore, dns Resolver, feedHandler *feed.Handle
 			return self.doDecrypt(ctx, credentials, pk)
 		},
 	}
+	if pk != nil {
+		self.signer = feed.NewGenericSigner(pk)
+	}
 	return
 }
 
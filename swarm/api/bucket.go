@@ -0,0 +1,240 @@
+// Copyright 2026 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/swarm/log"
+	"github.com/ethereum/go-ethereum/swarm/multihash"
+	"github.com/ethereum/go-ethereum/swarm/storage"
+	"github.com/ethereum/go-ethereum/swarm/storage/feed"
+	"github.com/ethereum/go-ethereum/swarm/storage/feed/lookup"
+)
+
+var (
+	// ErrNoBucketSigner is returned by bucket operations on a node without
+	// a key to sign bucket feed updates with
+	ErrNoBucketSigner = errors.New("no key to sign bucket updates")
+	// ErrBucketNotFound is returned when a bucket has never been created
+	ErrBucketNotFound = errors.New("bucket not found")
+	// ErrBucketExists is returned when creating a bucket which already exists
+	ErrBucketExists = errors.New("bucket already exists")
+)
+
+// bucketTopicPrefix separates bucket feed topics from other feeds of the node
+const bucketTopicPrefix = "swarm-bucket:"
+
+// BucketObject describes an object stored in a bucket
+type BucketObject struct {
+	Key         string
+	Hash        string
+	ContentType string
+	Size        int64
+	ModTime     time.Time
+}
+
+type bucketObjectsByKey []*BucketObject
+
+func (b bucketObjectsByKey) Len() int           { return len(b) }
+func (b bucketObjectsByKey) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+func (b bucketObjectsByKey) Less(i, j int) bool { return b[i].Key < b[j].Key }
+
+// BucketFeed returns the feed holding the manifest of the named bucket.
+// Buckets are owned by the node key, the topic is derived from the name.
+func (a *API) BucketFeed(name string) (*feed.Feed, error) {
+	if a.signer == nil || a.feed == nil {
+		return nil, ErrNoBucketSigner
+	}
+	topic, err := feed.NewTopic("", crypto.Keccak256([]byte(bucketTopicPrefix+name)))
+	if err != nil {
+		return nil, err
+	}
+	return &feed.Feed{
+		Topic: topic,
+		User:  a.signer.Address(),
+	}, nil
+}
+
+// BucketManifest returns the address of the latest manifest of the named bucket
+func (a *API) BucketManifest(ctx context.Context, name string) (storage.Address, error) {
+	fd, err := a.BucketFeed(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := a.FeedsLookup(ctx, feed.NewQueryLatest(fd, lookup.NoClue))
+	if err != nil {
+		if ferr, ok := err.(*feed.Error); ok && ferr.Code() == feed.ErrNotFound {
+			return nil, ErrBucketNotFound
+		}
+		return nil, err
+	}
+	addr, err := multihash.FromMultihash(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bucket feed update: %v", err)
+	}
+	return storage.Address(addr), nil
+}
+
+// CreateBucket creates the named bucket with an empty manifest
+func (a *API) CreateBucket(ctx context.Context, name string) (storage.Address, error) {
+	a.bucketLock.Lock()
+	defer a.bucketLock.Unlock()
+
+	if _, err := a.BucketManifest(ctx, name); err == nil {
+		return nil, ErrBucketExists
+	} else if err != ErrBucketNotFound {
+		return nil, err
+	}
+	addr, err := a.NewManifest(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.publishBucket(ctx, name, addr); err != nil {
+		return nil, err
+	}
+	return addr, nil
+}
+
+// UpdateBucket applies update to the manifest of the named bucket and
+// publishes the resulting manifest on the bucket feed.
+// Updates of all buckets are serialised so that none of them is lost.
+func (a *API) UpdateBucket(ctx context.Context, name string, update func(mw *ManifestWriter) error) (storage.Address, error) {
+	a.bucketLock.Lock()
+	defer a.bucketLock.Unlock()
+
+	addr, err := a.BucketManifest(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	addr, err = a.UpdateManifest(ctx, addr, update)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.publishBucket(ctx, name, addr); err != nil {
+		return nil, err
+	}
+	return addr, nil
+}
+
+// publishBucket points the bucket feed at the given manifest, the caller
+// must hold bucketLock
+func (a *API) publishBucket(ctx context.Context, name string, addr storage.Address) error {
+	fd, err := a.BucketFeed(name)
+	if err != nil {
+		return err
+	}
+	request, err := a.FeedsNewRequest(ctx, fd)
+	if err != nil {
+		return err
+	}
+	request.SetData(multihash.ToMultihash(addr))
+	if err := request.Sign(a.signer); err != nil {
+		return err
+	}
+	if _, err := a.FeedsUpdate(ctx, request); err != nil {
+		return err
+	}
+	log.Debug("bucket updated", "bucket", name, "manifest", addr)
+	return nil
+}
+
+// DeleteBucketObject removes the object stored under exactly the given key
+// from the named bucket. Deleting a missing object is not an error.
+func (a *API) DeleteBucketObject(ctx context.Context, name, key string) error {
+	manifestAddr, err := a.BucketManifest(ctx, name)
+	if err != nil {
+		return err
+	}
+	if _, err := a.BucketObject(ctx, manifestAddr, key); err == ErrNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	_, err = a.UpdateBucket(ctx, name, func(mw *ManifestWriter) error {
+		// the object may have been removed meanwhile, and removing a path
+		// which is not a file would drop the whole subtree beneath it
+		if mw.trie.getFileEntry(key) == nil {
+			return nil
+		}
+		return mw.RemoveEntry(key)
+	})
+	return err
+}
+
+// BucketObject returns the object stored under exactly the given key in the
+// manifest of a bucket
+func (a *API) BucketObject(ctx context.Context, manifestAddr storage.Address, key string) (*BucketObject, error) {
+	trie, err := loadManifest(ctx, a.fileStore, manifestAddr, nil, NOOPDecrypt)
+	if err != nil {
+		return nil, err
+	}
+	entry := trie.getFileEntry(key)
+	if entry == nil {
+		return nil, ErrNotFound
+	}
+	return newBucketObject(&entry.ManifestEntry), nil
+}
+
+// BucketObjects returns the objects of a bucket manifest whose keys start
+// with prefix, sorted by key
+func (a *API) BucketObjects(ctx context.Context, manifestAddr storage.Address, prefix string) ([]*BucketObject, error) {
+	walker, err := a.NewManifestWalker(ctx, manifestAddr, NOOPDecrypt, nil)
+	if err != nil {
+		return nil, err
+	}
+	var objects []*BucketObject
+	err = walker.Walk(func(entry *ManifestEntry) error {
+		if entry.ContentType == ManifestType {
+			if strings.HasPrefix(entry.Path, prefix) || strings.HasPrefix(prefix, entry.Path) {
+				return nil
+			}
+			return ErrSkipManifest
+		}
+		if strings.HasPrefix(entry.Path, prefix) {
+			objects = append(objects, newBucketObject(entry))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Sort(bucketObjectsByKey(objects))
+	return objects, nil
+}
+
+func newBucketObject(entry *ManifestEntry) *BucketObject {
+	return &BucketObject{
+		Key:         entry.Path,
+		Hash:        entry.Hash,
+		ContentType: entry.ContentType,
+		Size:        entry.Size,
+		ModTime:     entry.ModTime,
+	}
+}
+
+// Addr returns the address of the object content
+func (o *BucketObject) Addr() storage.Address {
+	return common.Hex2Bytes(o.Hash)
+}
@@ -36,6 +36,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/swarm/api"
 	"github.com/ethereum/go-ethereum/swarm/storage/feed"
+	"github.com/ethereum/go-ethereum/swarm/storage/pin"
 )
 
 var (
@@ -45,6 +46,7 @@ var (
 
 var (
 	ErrUnauthorized = errors.New("unauthorized")
+	ErrNotPinned    = errors.New("content is not pinned")
 )
 
 func NewClient(gateway string) *Client {
@@ -417,6 +419,62 @@ func (c *Client) List(hash, prefix, credentials string) (*api.ManifestList, erro
 	return &list, nil
 }
 
+// PinFiles pins the manifest with the given hash and all files it references
+// in the local store of the node, or just the raw file if raw is set
+func (c *Client) PinFiles(hash string, raw bool) error {
+	uri := c.Gateway + "/bzz-pin:/" + hash
+	if raw {
+		uri += "?raw=true"
+	}
+	res, err := http.DefaultClient.Post(uri, "", nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected HTTP status: %s", res.Status)
+	}
+	return nil
+}
+
+// UnpinFiles releases one pin held on the content with the given hash
+func (c *Client) UnpinFiles(hash string) error {
+	req, err := http.NewRequest(http.MethodDelete, c.Gateway+"/bzz-pin:/"+hash, nil)
+	if err != nil {
+		return err
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	switch res.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+		return ErrNotPinned
+	default:
+		return fmt.Errorf("unexpected HTTP status: %s", res.Status)
+	}
+}
+
+// ListPinFiles lists the content pinned on the node
+func (c *Client) ListPinFiles() ([]*pin.FileInfo, error) {
+	res, err := http.DefaultClient.Get(c.Gateway + "/bzz-pin:/")
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected HTTP status: %s", res.Status)
+	}
+	var list []*pin.FileInfo
+	if err := json.NewDecoder(res.Body).Decode(&list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
 // Uploader uploads files to swarm using a provided UploadFn
 type Uploader interface {
 	Upload(UploadFn) error
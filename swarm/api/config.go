@@ -68,7 +68,10 @@ type Config struct {
 	LightNodeEnabled     bool
 	SyncUpdateDelay      time.Duration
 	SwapAPI              string
+	FeedReputationAPI    string   // chain endpoint checked for feed publisher reputation
+	FeedReputationTopics []string // hex encoded feed topics restricted to reputable publishers
 	Cors                 string
+	PinningEnabled       bool // serve the bzz-pin scheme, which lets every HTTP client pin content
 	BzzAccount           string
 	privateKey           *ecdsa.PrivateKey
 }
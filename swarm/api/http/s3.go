@@ -0,0 +1,454 @@
+// Copyright 2026 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+/*
+An S3-compatible gateway to Swarm.
+
+Buckets are manifests published on a feed owned by the node key, so a bucket
+keeps its name while its content changes. The gateway implements the subset of
+the S3 REST API needed by common clients using path-style addressing:
+
+	PUT    /s3/<bucket>                      CreateBucket
+	HEAD   /s3/<bucket>                      HeadBucket
+	GET    /s3/<bucket>?list-type=2          ListObjectsV2
+	PUT    /s3/<bucket>/<key>                PutObject
+	GET    /s3/<bucket>/<key>                GetObject, Range requests are supported
+	HEAD   /s3/<bucket>/<key>                HeadObject
+	DELETE /s3/<bucket>/<key>                DeleteObject
+
+Request signatures are not verified, the gateway has the same access rules as
+the rest of the HTTP API. Object ETags are Swarm content hashes rather than MD5
+digests.
+*/
+package http
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/swarm/api"
+	"github.com/ethereum/go-ethereum/swarm/log"
+	"github.com/ethereum/go-ethereum/swarm/storage"
+)
+
+var (
+	s3CreateBucketCount = metrics.NewRegisteredCounter("api.http.s3.createbucket.count", nil)
+	s3CreateBucketFail  = metrics.NewRegisteredCounter("api.http.s3.createbucket.fail", nil)
+	s3PutObjectCount    = metrics.NewRegisteredCounter("api.http.s3.putobject.count", nil)
+	s3PutObjectFail     = metrics.NewRegisteredCounter("api.http.s3.putobject.fail", nil)
+	s3GetObjectCount    = metrics.NewRegisteredCounter("api.http.s3.getobject.count", nil)
+	s3GetObjectFail     = metrics.NewRegisteredCounter("api.http.s3.getobject.fail", nil)
+	s3ListObjectsCount  = metrics.NewRegisteredCounter("api.http.s3.listobjects.count", nil)
+	s3ListObjectsFail   = metrics.NewRegisteredCounter("api.http.s3.listobjects.fail", nil)
+	s3DeleteObjectCount = metrics.NewRegisteredCounter("api.http.s3.deleteobject.count", nil)
+	s3DeleteObjectFail  = metrics.NewRegisteredCounter("api.http.s3.deleteobject.fail", nil)
+)
+
+const (
+	s3Prefix       = "/s3/"
+	s3Namespace    = "http://s3.amazonaws.com/doc/2006-03-01/"
+	s3MaxKeys      = 1000
+	s3TimeFormat   = "2006-01-02T15:04:05.000Z"
+	s3StorageClass = "STANDARD"
+)
+
+// bucketNameRegexp matches the DNS compatible bucket names S3 accepts
+var bucketNameRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+
+// s3Error is the body of S3 error responses
+type s3Error struct {
+	XMLName   xml.Name `xml:"Error"`
+	Code      string   `xml:"Code"`
+	Message   string   `xml:"Message"`
+	Resource  string   `xml:"Resource"`
+	RequestID string   `xml:"RequestId"`
+}
+
+// s3ListBucketResult is the body of ListObjectsV2 responses
+type s3ListBucketResult struct {
+	XMLName               xml.Name         `xml:"ListBucketResult"`
+	Xmlns                 string           `xml:"xmlns,attr"`
+	Name                  string           `xml:"Name"`
+	Prefix                string           `xml:"Prefix"`
+	Delimiter             string           `xml:"Delimiter,omitempty"`
+	StartAfter            string           `xml:"StartAfter,omitempty"`
+	ContinuationToken     string           `xml:"ContinuationToken,omitempty"`
+	NextContinuationToken string           `xml:"NextContinuationToken,omitempty"`
+	MaxKeys               int              `xml:"MaxKeys"`
+	KeyCount              int              `xml:"KeyCount"`
+	IsTruncated           bool             `xml:"IsTruncated"`
+	Contents              []s3Object       `xml:"Contents"`
+	CommonPrefixes        []s3CommonPrefix `xml:"CommonPrefixes"`
+}
+
+type s3Object struct {
+	Key          string `xml:"Key"`
+	LastModified string `xml:"LastModified"`
+	ETag         string `xml:"ETag"`
+	Size         int64  `xml:"Size"`
+	StorageClass string `xml:"StorageClass"`
+}
+
+type s3CommonPrefix struct {
+	Prefix string `xml:"Prefix"`
+}
+
+// parseS3Path splits the request path into a bucket name and an object key
+func parseS3Path(r *http.Request) (bucket, key string) {
+	path := strings.TrimPrefix(r.URL.Path, s3Prefix)
+	if i := strings.Index(path, "/"); i >= 0 {
+		return path[:i], path[i+1:]
+	}
+	return path, ""
+}
+
+// respondS3Error writes an S3 error response
+func respondS3Error(w http.ResponseWriter, r *http.Request, code string, msg string, status int) {
+	log.Debug("respondS3Error", "ruid", GetRUID(r.Context()), "path", r.URL.Path, "code", code, "msg", msg)
+	w.Header().Del("ETag")
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	if r.Method == http.MethodHead {
+		return
+	}
+	io.WriteString(w, xml.Header)
+	xml.NewEncoder(w).Encode(&s3Error{
+		Code:      code,
+		Message:   msg,
+		Resource:  r.URL.Path,
+		RequestID: GetRUID(r.Context()),
+	})
+}
+
+// respondS3BucketError writes the S3 error response matching an error
+// returned by a bucket operation
+func respondS3BucketError(w http.ResponseWriter, r *http.Request, err error) {
+	switch err {
+	case api.ErrBucketNotFound:
+		respondS3Error(w, r, "NoSuchBucket", "The specified bucket does not exist", http.StatusNotFound)
+	case api.ErrBucketExists:
+		respondS3Error(w, r, "BucketAlreadyOwnedByYou", "Your previous request to create the named bucket succeeded", http.StatusConflict)
+	case api.ErrNoBucketSigner:
+		respondS3Error(w, r, "NotImplemented", "The node has no key to sign bucket updates", http.StatusNotImplemented)
+	default:
+		respondS3Error(w, r, "InternalError", err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// HandleS3Get handles a GET request to the S3 gateway, listing the objects
+// of a bucket or returning the content of an object
+func (s *Server) HandleS3Get(w http.ResponseWriter, r *http.Request) {
+	bucket, key := parseS3Path(r)
+	switch {
+	case bucket == "":
+		respondS3Error(w, r, "NotImplemented", "Listing buckets is not supported", http.StatusNotImplemented)
+	case key == "":
+		s.handleS3ListObjects(w, r, bucket)
+	default:
+		s.handleS3GetObject(w, r, bucket, key)
+	}
+}
+
+// HandleS3Head handles a HEAD request to the S3 gateway, checking a bucket
+// exists or returning the metadata of an object
+func (s *Server) HandleS3Head(w http.ResponseWriter, r *http.Request) {
+	bucket, key := parseS3Path(r)
+	switch {
+	case bucket == "":
+		respondS3Error(w, r, "NotImplemented", "Listing buckets is not supported", http.StatusNotImplemented)
+	case key == "":
+		if _, err := s.api.BucketManifest(r.Context(), bucket); err != nil {
+			respondS3BucketError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	default:
+		s.handleS3GetObject(w, r, bucket, key)
+	}
+}
+
+// HandleS3Put handles a PUT request to the S3 gateway, creating a bucket or
+// storing an object
+func (s *Server) HandleS3Put(w http.ResponseWriter, r *http.Request) {
+	bucket, key := parseS3Path(r)
+	switch {
+	case bucket == "":
+		respondS3Error(w, r, "MethodNotAllowed", "A bucket name is required", http.StatusMethodNotAllowed)
+	case key == "":
+		s.handleS3CreateBucket(w, r, bucket)
+	default:
+		s.handleS3PutObject(w, r, bucket, key)
+	}
+}
+
+// HandleS3Delete handles a DELETE request to the S3 gateway, removing an
+// object from a bucket
+func (s *Server) HandleS3Delete(w http.ResponseWriter, r *http.Request) {
+	bucket, key := parseS3Path(r)
+	if bucket == "" || key == "" {
+		respondS3Error(w, r, "NotImplemented", "Deleting buckets is not supported", http.StatusNotImplemented)
+		return
+	}
+	s.handleS3DeleteObject(w, r, bucket, key)
+}
+
+func (s *Server) handleS3CreateBucket(w http.ResponseWriter, r *http.Request, bucket string) {
+	log.Debug("handle.s3.createbucket", "ruid", GetRUID(r.Context()), "bucket", bucket)
+	s3CreateBucketCount.Inc(1)
+
+	if !bucketNameRegexp.MatchString(bucket) {
+		s3CreateBucketFail.Inc(1)
+		respondS3Error(w, r, "InvalidBucketName", "The specified bucket is not valid", http.StatusBadRequest)
+		return
+	}
+	if _, err := s.api.CreateBucket(r.Context(), bucket); err != nil {
+		s3CreateBucketFail.Inc(1)
+		respondS3BucketError(w, r, err)
+		return
+	}
+	w.Header().Set("Location", "/"+bucket)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) handleS3PutObject(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	ruid := GetRUID(r.Context())
+	log.Debug("handle.s3.putobject", "ruid", ruid, "bucket", bucket, "key", key)
+	s3PutObjectCount.Inc(1)
+
+	if api.RegularSlashes(key) != key {
+		s3PutObjectFail.Inc(1)
+		respondS3Error(w, r, "InvalidArgument", "Object keys must not contain empty path segments", http.StatusBadRequest)
+		return
+	}
+	if r.Header.Get("X-Amz-Copy-Source") != "" {
+		s3PutObjectFail.Inc(1)
+		respondS3Error(w, r, "NotImplemented", "Copying objects is not supported", http.StatusNotImplemented)
+		return
+	}
+	if strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		s3PutObjectFail.Inc(1)
+		respondS3Error(w, r, "NotImplemented", "Chunked payload signing is not supported", http.StatusNotImplemented)
+		return
+	}
+
+	body, size := io.Reader(r.Body), r.ContentLength
+	if size < 0 {
+		data, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			s3PutObjectFail.Inc(1)
+			respondS3Error(w, r, "IncompleteBody", err.Error(), http.StatusBadRequest)
+			return
+		}
+		body, size = bytes.NewReader(data), int64(len(data))
+	}
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	// store the content before taking the bucket lock, so that uploads only
+	// serialise on the manifest update and not on the transfer of the body
+	if _, err := s.api.BucketManifest(r.Context(), bucket); err != nil {
+		s3PutObjectFail.Inc(1)
+		respondS3BucketError(w, r, err)
+		return
+	}
+	addr, wait, err := s.api.Store(r.Context(), body, size, false)
+	if err == nil {
+		err = wait(r.Context())
+	}
+	if err != nil {
+		s3PutObjectFail.Inc(1)
+		respondS3Error(w, r, "InternalError", err.Error(), http.StatusInternalServerError)
+		return
+	}
+	hash := addr.Hex()
+	_, err = s.api.UpdateBucket(r.Context(), bucket, func(mw *api.ManifestWriter) error {
+		_, err := mw.AddEntry(r.Context(), nil, &api.ManifestEntry{
+			Hash:        hash,
+			Path:        key,
+			ContentType: contentType,
+			Mode:        0644,
+			Size:        size,
+			ModTime:     time.Now(),
+		})
+		return err
+	})
+	if err != nil {
+		s3PutObjectFail.Inc(1)
+		respondS3BucketError(w, r, err)
+		return
+	}
+	log.Debug("stored s3 object", "ruid", ruid, "bucket", bucket, "key", key, "hash", hash)
+	w.Header().Set("ETag", fmt.Sprintf("%q", hash))
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) handleS3GetObject(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	log.Debug("handle.s3.getobject", "ruid", GetRUID(r.Context()), "bucket", bucket, "key", key)
+	s3GetObjectCount.Inc(1)
+
+	manifestAddr, err := s.api.BucketManifest(r.Context(), bucket)
+	if err != nil {
+		s3GetObjectFail.Inc(1)
+		respondS3BucketError(w, r, err)
+		return
+	}
+	object, err := s.api.BucketObject(r.Context(), manifestAddr, key)
+	if err == api.ErrNotFound {
+		s3GetObjectFail.Inc(1)
+		respondS3Error(w, r, "NoSuchKey", "The specified key does not exist", http.StatusNotFound)
+		return
+	} else if err != nil {
+		s3GetObjectFail.Inc(1)
+		respondS3Error(w, r, "InternalError", err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// the retrieval is lazy, a missing object surfaces when its root chunk
+	// is fetched for the size
+	reader, _ := s.api.Retrieve(r.Context(), object.Addr())
+	size, err := reader.Size(r.Context(), nil)
+	if err == storage.ErrChunkNotFound {
+		s3GetObjectFail.Inc(1)
+		respondS3Error(w, r, "NoSuchKey", "The content of the specified key is not available", http.StatusNotFound)
+		return
+	} else if err != nil {
+		s3GetObjectFail.Inc(1)
+		respondS3Error(w, r, "InternalError", err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", object.ContentType)
+	w.Header().Set("ETag", fmt.Sprintf("%q", object.Hash))
+	http.ServeContent(w, r, "", object.ModTime, io.NewSectionReader(reader, 0, size))
+}
+
+func (s *Server) handleS3ListObjects(w http.ResponseWriter, r *http.Request, bucket string) {
+	log.Debug("handle.s3.listobjects", "ruid", GetRUID(r.Context()), "bucket", bucket)
+	s3ListObjectsCount.Inc(1)
+
+	query := r.URL.Query()
+	if query.Get("list-type") != "2" {
+		s3ListObjectsFail.Inc(1)
+		respondS3Error(w, r, "NotImplemented", "Only ListObjectsV2 is supported", http.StatusNotImplemented)
+		return
+	}
+	result := &s3ListBucketResult{
+		Xmlns:             s3Namespace,
+		Name:              bucket,
+		Prefix:            query.Get("prefix"),
+		Delimiter:         query.Get("delimiter"),
+		StartAfter:        query.Get("start-after"),
+		ContinuationToken: query.Get("continuation-token"),
+		MaxKeys:           s3MaxKeys,
+	}
+	if v := query.Get("max-keys"); v != "" {
+		maxKeys, err := strconv.Atoi(v)
+		if err != nil || maxKeys < 0 {
+			s3ListObjectsFail.Inc(1)
+			respondS3Error(w, r, "InvalidArgument", "Invalid max-keys", http.StatusBadRequest)
+			return
+		}
+		if maxKeys < s3MaxKeys {
+			result.MaxKeys = maxKeys
+		}
+	}
+	marker := result.StartAfter
+	if result.ContinuationToken != "" {
+		token, err := base64.URLEncoding.DecodeString(result.ContinuationToken)
+		if err != nil {
+			s3ListObjectsFail.Inc(1)
+			respondS3Error(w, r, "InvalidArgument", "Invalid continuation token", http.StatusBadRequest)
+			return
+		}
+		marker = string(token)
+	}
+
+	manifestAddr, err := s.api.BucketManifest(r.Context(), bucket)
+	if err != nil {
+		s3ListObjectsFail.Inc(1)
+		respondS3BucketError(w, r, err)
+		return
+	}
+	objects, err := s.api.BucketObjects(r.Context(), manifestAddr, result.Prefix)
+	if err != nil {
+		s3ListObjectsFail.Inc(1)
+		respondS3Error(w, r, "InternalError", err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// objects are sorted by key, so keys rolled up into a common prefix are
+	// adjacent and the listing can resume after the last returned item
+	var last string
+	for _, object := range objects {
+		item, isPrefix := object.Key, false
+		if result.Delimiter != "" {
+			rest := object.Key[len(result.Prefix):]
+			if i := strings.Index(rest, result.Delimiter); i >= 0 {
+				item, isPrefix = result.Prefix+rest[:i+len(result.Delimiter)], true
+			}
+		}
+		if item <= marker || (isPrefix && item == last) {
+			continue
+		}
+		if result.KeyCount == result.MaxKeys {
+			result.IsTruncated = true
+			result.NextContinuationToken = base64.URLEncoding.EncodeToString([]byte(last))
+			break
+		}
+		if isPrefix {
+			result.CommonPrefixes = append(result.CommonPrefixes, s3CommonPrefix{Prefix: item})
+		} else {
+			result.Contents = append(result.Contents, s3Object{
+				Key:          object.Key,
+				LastModified: object.ModTime.UTC().Format(s3TimeFormat),
+				ETag:         fmt.Sprintf("%q", object.Hash),
+				Size:         object.Size,
+				StorageClass: s3StorageClass,
+			})
+		}
+		result.KeyCount++
+		last = item
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	io.WriteString(w, xml.Header)
+	if err := xml.NewEncoder(w).Encode(result); err != nil {
+		log.Error("error encoding s3 listing", "ruid", GetRUID(r.Context()), "err", err)
+	}
+}
+
+func (s *Server) handleS3DeleteObject(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	log.Debug("handle.s3.deleteobject", "ruid", GetRUID(r.Context()), "bucket", bucket, "key", key)
+	s3DeleteObjectCount.Inc(1)
+
+	if err := s.api.DeleteBucketObject(r.Context(), bucket, key); err != nil {
+		s3DeleteObjectFail.Inc(1)
+		respondS3BucketError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,369 @@
+// Copyright 2026 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/swarm/api"
+	"github.com/ethereum/go-ethereum/swarm/storage/pin"
+)
+
+// s3Client is a minimal path-style S3 client. Requests carry the headers of a
+// Signature Version 4 request as sent by the AWS SDKs, which the gateway has to
+// accept without verifying them.
+type s3Client struct {
+	endpoint string
+}
+
+// s3ClientError is an S3 error response received by s3Client
+type s3ClientError struct {
+	Status int
+	Code   string
+}
+
+func (e *s3ClientError) Error() string {
+	return fmt.Sprintf("%d %s", e.Status, e.Code)
+}
+
+func (c *s3Client) do(method, path string, query url.Values, header http.Header, body []byte) (*http.Response, []byte, error) {
+	u := c.endpoint + s3Prefix + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, u, reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now().UTC()
+	payloadHash := sha256.Sum256(body)
+	req.Header.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/%s/us-east-1/s3/aws4_request, SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature=%x", now.Format("20060102"), payloadHash))
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	if res.StatusCode >= 300 {
+		serr := &s3ClientError{Status: res.StatusCode}
+		if method != http.MethodHead {
+			var body s3Error
+			if err := xml.Unmarshal(data, &body); err != nil {
+				return nil, nil, fmt.Errorf("invalid error response %q: %v", data, err)
+			}
+			serr.Code = body.Code
+		}
+		return res, nil, serr
+	}
+	return res, data, nil
+}
+
+func (c *s3Client) CreateBucket(bucket string) error {
+	_, _, err := c.do(http.MethodPut, bucket, nil, nil, nil)
+	return err
+}
+
+func (c *s3Client) HeadBucket(bucket string) error {
+	_, _, err := c.do(http.MethodHead, bucket, nil, nil, nil)
+	return err
+}
+
+func (c *s3Client) PutObject(bucket, key string, data []byte, contentType string) (string, error) {
+	header := http.Header{"Content-Type": {contentType}}
+	res, _, err := c.do(http.MethodPut, bucket+"/"+key, nil, header, data)
+	if err != nil {
+		return "", err
+	}
+	return res.Header.Get("ETag"), nil
+}
+
+func (c *s3Client) GetObject(bucket, key, byteRange string) (*http.Response, []byte, error) {
+	header := http.Header{}
+	if byteRange != "" {
+		header.Set("Range", byteRange)
+	}
+	return c.do(http.MethodGet, bucket+"/"+key, nil, header, nil)
+}
+
+func (c *s3Client) ListObjectsV2(bucket string, query url.Values) (*s3ListBucketResult, error) {
+	if query == nil {
+		query = url.Values{}
+	}
+	query.Set("list-type", "2")
+	_, data, err := c.do(http.MethodGet, bucket, query, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	result := &s3ListBucketResult{}
+	if err := xml.Unmarshal(data, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (c *s3Client) DeleteObject(bucket, key string) error {
+	_, _, err := c.do(http.MethodDelete, bucket+"/"+key, nil, nil, nil)
+	return err
+}
+
+// listKeys returns the keys and common prefixes of a listing
+func listKeys(result *s3ListBucketResult) (keys []string, prefixes []string) {
+	for _, object := range result.Contents {
+		keys = append(keys, object.Key)
+	}
+	for _, prefix := range result.CommonPrefixes {
+		prefixes = append(prefixes, prefix.Prefix)
+	}
+	return keys, prefixes
+}
+
+func checkS3Error(t *testing.T, err error, status int, code string) {
+	t.Helper()
+	serr, ok := err.(*s3ClientError)
+	if !ok {
+		t.Fatalf("expected S3 error %s, got %v", code, err)
+	}
+	if serr.Status != status || serr.Code != code {
+		t.Fatalf("S3 error mismatch: have %d %s, want %d %s", serr.Status, serr.Code, status, code)
+	}
+}
+
+// TestS3Gateway tests creating a bucket, storing, reading, listing and
+// deleting objects through the S3 gateway
+func TestS3Gateway(t *testing.T) {
+	srv := NewTestSwarmServer(t, serverFunc, nil)
+	defer srv.Close()
+
+	client := &s3Client{endpoint: srv.URL}
+	bucket := "photos"
+
+	if err := client.HeadBucket(bucket); err == nil {
+		t.Fatal("expected missing bucket")
+	}
+	checkS3Error(t, client.CreateBucket("No_Such.Bucket"), http.StatusBadRequest, "InvalidBucketName")
+	_, err := client.PutObject(bucket, "a.txt", []byte("a"), "text/plain")
+	checkS3Error(t, err, http.StatusNotFound, "NoSuchBucket")
+
+	if err := client.CreateBucket(bucket); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.HeadBucket(bucket); err != nil {
+		t.Fatal(err)
+	}
+	srv.CurrentTime++
+	checkS3Error(t, client.CreateBucket(bucket), http.StatusConflict, "BucketAlreadyOwnedByYou")
+
+	objects := map[string]string{
+		"a.txt":         "the quick brown fox",
+		"dir/b.txt":     "jumps over",
+		"dir/c.txt":     "the lazy dog",
+		"dir/sub/d.txt": "lorem ipsum",
+		"z.txt":         "dolor sit amet",
+	}
+	etags := make(map[string]string)
+	for key, content := range objects {
+		srv.CurrentTime++
+		etag, err := client.PutObject(bucket, key, []byte(content), "text/plain")
+		if err != nil {
+			t.Fatalf("error putting %s: %v", key, err)
+		}
+		etags[key] = etag
+	}
+
+	// get whole objects
+	for key, content := range objects {
+		res, data, err := client.GetObject(bucket, key, "")
+		if err != nil {
+			t.Fatalf("error getting %s: %v", key, err)
+		}
+		if string(data) != content {
+			t.Fatalf("content mismatch for %s: have %q, want %q", key, data, content)
+		}
+		if have := res.Header.Get("Content-Type"); have != "text/plain" {
+			t.Fatalf("content type mismatch for %s: have %s, want text/plain", key, have)
+		}
+		if have := res.Header.Get("ETag"); have != etags[key] {
+			t.Fatalf("ETag mismatch for %s: have %s, want %s", key, have, etags[key])
+		}
+	}
+
+	// get a byte range
+	res, data, err := client.GetObject(bucket, "a.txt", "bytes=4-8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusPartialContent {
+		t.Fatalf("status mismatch: have %d, want %d", res.StatusCode, http.StatusPartialContent)
+	}
+	if string(data) != "quick" {
+		t.Fatalf("range content mismatch: have %q, want %q", data, "quick")
+	}
+
+	// keys only match exactly
+	_, _, err = client.GetObject(bucket, "dir", "")
+	checkS3Error(t, err, http.StatusNotFound, "NoSuchKey")
+	_, _, err = client.GetObject(bucket, "a.tx", "")
+	checkS3Error(t, err, http.StatusNotFound, "NoSuchKey")
+
+	for _, test := range []struct {
+		query    url.Values
+		keys     []string
+		prefixes []string
+	}{
+		{
+			query: nil,
+			keys:  []string{"a.txt", "dir/b.txt", "dir/c.txt", "dir/sub/d.txt", "z.txt"},
+		},
+		{
+			query:    url.Values{"delimiter": {"/"}},
+			keys:     []string{"a.txt", "z.txt"},
+			prefixes: []string{"dir/"},
+		},
+		{
+			query:    url.Values{"prefix": {"dir/"}, "delimiter": {"/"}},
+			keys:     []string{"dir/b.txt", "dir/c.txt"},
+			prefixes: []string{"dir/sub/"},
+		},
+		{
+			query: url.Values{"prefix": {"dir/s"}},
+			keys:  []string{"dir/sub/d.txt"},
+		},
+		{
+			query: url.Values{"start-after": {"dir/b.txt"}},
+			keys:  []string{"dir/c.txt", "dir/sub/d.txt", "z.txt"},
+		},
+	} {
+		result, err := client.ListObjectsV2(bucket, test.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys, prefixes := listKeys(result)
+		if !reflect.DeepEqual(keys, test.keys) {
+			t.Fatalf("listing %v keys mismatch: have %v, want %v", test.query, keys, test.keys)
+		}
+		if !reflect.DeepEqual(prefixes, test.prefixes) {
+			t.Fatalf("listing %v prefixes mismatch: have %v, want %v", test.query, prefixes, test.prefixes)
+		}
+	}
+
+	// page through the listing
+	var (
+		pages []string
+		token string
+	)
+	for {
+		query := url.Values{"max-keys": {"2"}, "delimiter": {"/"}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		result, err := client.ListObjectsV2(bucket, query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys, prefixes := listKeys(result)
+		pages = append(pages, strings.Join(append(keys, prefixes...), ","))
+		if !result.IsTruncated {
+			break
+		}
+		token = result.NextContinuationToken
+	}
+	if want := []string{"a.txt,dir/", "z.txt"}; !reflect.DeepEqual(pages, want) {
+		t.Fatalf("pages mismatch: have %v, want %v", pages, want)
+	}
+
+	// delete an object, deleting it again succeeds
+	srv.CurrentTime++
+	if err := client.DeleteObject(bucket, "dir/b.txt"); err != nil {
+		t.Fatal(err)
+	}
+	srv.CurrentTime++
+	if err := client.DeleteObject(bucket, "dir/b.txt"); err != nil {
+		t.Fatal(err)
+	}
+	// deleting a common prefix doesn't remove the objects beneath it
+	srv.CurrentTime++
+	if err := client.DeleteObject(bucket, "dir/"); err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = client.GetObject(bucket, "dir/b.txt", "")
+	checkS3Error(t, err, http.StatusNotFound, "NoSuchKey")
+	result, err := client.ListObjectsV2(bucket, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, _ := listKeys(result)
+	if want := []string{"a.txt", "dir/c.txt", "dir/sub/d.txt", "z.txt"}; !reflect.DeepEqual(keys, want) {
+		t.Fatalf("keys mismatch after delete: have %v, want %v", keys, want)
+	}
+}
+
+// TestS3GatewayMissingContent tests that getting an object whose content is
+// not available responds with an S3 error
+func TestS3GatewayMissingContent(t *testing.T) {
+	var swarmAPI *api.API
+	srv := NewTestSwarmServer(t, func(a *api.API, pinAPI *pin.API) TestServer {
+		swarmAPI = a
+		return NewServer(a, pinAPI, "")
+	}, nil)
+	defer srv.Close()
+
+	client := &s3Client{endpoint: srv.URL}
+	bucket := "photos"
+	if err := client.CreateBucket(bucket); err != nil {
+		t.Fatal(err)
+	}
+	srv.CurrentTime++
+	_, err := swarmAPI.UpdateBucket(context.TODO(), bucket, func(mw *api.ManifestWriter) error {
+		_, err := mw.AddEntry(context.TODO(), nil, &api.ManifestEntry{
+			Hash:        hex.EncodeToString(make([]byte, 32)),
+			Path:        "a.txt",
+			ContentType: "text/plain",
+			Size:        1,
+		})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = client.GetObject(bucket, "a.txt", "")
+	checkS3Error(t, err, http.StatusNotFound, "NoSuchKey")
+}
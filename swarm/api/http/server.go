@@ -41,6 +41,7 @@ import (
 	"github.com/ethereum/go-ethereum/swarm/log"
 	"github.com/ethereum/go-ethereum/swarm/storage"
 	"github.com/ethereum/go-ethereum/swarm/storage/feed"
+	"github.com/ethereum/go-ethereum/swarm/storage/pin"
 	"github.com/rs/cors"
 )
 
@@ -51,6 +52,8 @@ var (
 	postFilesFail   = metrics.NewRegisteredCounter("api.http.post.files.fail", nil)
 	deleteCount     = metrics.NewRegisteredCounter("api.http.delete.count", nil)
 	deleteFail      = metrics.NewRegisteredCounter("api.http.delete.fail", nil)
+	patchCount      = metrics.NewRegisteredCounter("api.http.patch.count", nil)
+	patchFail       = metrics.NewRegisteredCounter("api.http.patch.fail", nil)
 	getCount        = metrics.NewRegisteredCounter("api.http.get.count", nil)
 	getFail         = metrics.NewRegisteredCounter("api.http.get.fail", nil)
 	getFileCount    = metrics.NewRegisteredCounter("api.http.get.file.count", nil)
@@ -58,6 +61,10 @@ var (
 	getFileFail     = metrics.NewRegisteredCounter("api.http.get.file.fail", nil)
 	getListCount    = metrics.NewRegisteredCounter("api.http.get.list.count", nil)
 	getListFail     = metrics.NewRegisteredCounter("api.http.get.list.fail", nil)
+	pinCount        = metrics.NewRegisteredCounter("api.http.pin.count", nil)
+	pinFail         = metrics.NewRegisteredCounter("api.http.pin.fail", nil)
+	unpinCount      = metrics.NewRegisteredCounter("api.http.unpin.count", nil)
+	unpinFail       = metrics.NewRegisteredCounter("api.http.unpin.fail", nil)
 )
 
 type methodHandler map[string]http.Handler
@@ -71,19 +78,19 @@ func (m methodHandler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 	rw.WriteHeader(http.StatusMethodNotAllowed)
 }
 
-func NewServer(api *api.API, corsString string) *Server {
+func NewServer(api *api.API, pinAPI *pin.API, corsString string) *Server {
 	var allowedOrigins []string
 	for _, domain := range strings.Split(corsString, ",") {
 		allowedOrigins = append(allowedOrigins, strings.TrimSpace(domain))
 	}
 	c := cors.New(cors.Options{
 		AllowedOrigins: allowedOrigins,
-		AllowedMethods: []string{http.MethodPost, http.MethodGet, http.MethodDelete, http.MethodPatch, http.MethodPut},
+		AllowedMethods: []string{http.MethodPost, http.MethodGet, http.MethodDelete, http.MethodPatch, http.MethodPut, http.MethodHead},
 		MaxAge:         600,
 		AllowedHeaders: []string{"*"},
 	})
 
-	server := &Server{api: api}
+	server := &Server{api: api, pinAPI: pinAPI}
 
 	defaultMiddlewares := []Adapter{
 		RecoverPanic,
@@ -108,6 +115,10 @@ func NewServer(api *api.API, corsString string) *Server {
 			http.HandlerFunc(server.HandleDelete),
 			defaultMiddlewares...,
 		),
+		"PATCH": Adapt(
+			http.HandlerFunc(server.HandlePatch),
+			defaultMiddlewares...,
+		),
 	})
 	mux.Handle("/bzz-raw:/", methodHandler{
 		"GET": Adapt(
@@ -147,6 +158,47 @@ func NewServer(api *api.API, corsString string) *Server {
 			defaultMiddlewares...,
 		),
 	})
+	// the bzz-pin scheme is only served if pinning is enabled on the node
+	if pinAPI != nil {
+		mux.Handle("/bzz-pin:/", methodHandler{
+			"GET": Adapt(
+				http.HandlerFunc(server.HandleGetPins),
+				defaultMiddlewares...,
+			),
+			"POST": Adapt(
+				http.HandlerFunc(server.HandlePin),
+				defaultMiddlewares...,
+			),
+			"DELETE": Adapt(
+				http.HandlerFunc(server.HandleUnpin),
+				defaultMiddlewares...,
+			),
+		})
+	}
+
+	s3Middlewares := []Adapter{
+		RecoverPanic,
+		SetRequestID,
+		InitLoggingResponseWriter,
+	}
+	mux.Handle(s3Prefix, methodHandler{
+		"GET": Adapt(
+			http.HandlerFunc(server.HandleS3Get),
+			s3Middlewares...,
+		),
+		"HEAD": Adapt(
+			http.HandlerFunc(server.HandleS3Head),
+			s3Middlewares...,
+		),
+		"PUT": Adapt(
+			http.HandlerFunc(server.HandleS3Put),
+			s3Middlewares...,
+		),
+		"DELETE": Adapt(
+			http.HandlerFunc(server.HandleS3Delete),
+			s3Middlewares...,
+		),
+	})
 
 	mux.Handle("/", methodHandler{
 		"GET": Adapt(
@@ -172,6 +224,7 @@ func (s *Server) ListenAndServe(addr string) error {
 type Server struct {
 	http.Handler
 	api        *api.API
+	pinAPI     *pin.API
 	listenAddr string
 }
 
@@ -448,6 +501,139 @@ func (s *Server) HandleDelete(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprint(w, newKey)
 }
 
+// HandlePatch handles a PATCH request to bzz:/<manifest>/<path> with a JSON
+// encoded list of api.ManifestPatch operations in the body. The operations
+// are applied to the manifest in a single update, with their paths taken
+// relative to <path>, and the address of the new manifest is returned.
+//
+// If the request carries an If-Match header, the update is only applied if
+// <manifest> still resolves to the given manifest hash, otherwise it is
+// rejected with 412 Precondition Failed so that the client can retry on top
+// of the current manifest.
+func (s *Server) HandlePatch(w http.ResponseWriter, r *http.Request) {
+	ruid := GetRUID(r.Context())
+	uri := GetURI(r.Context())
+	log.Debug("handle.patch", "ruid", ruid, "uri", uri)
+	patchCount.Inc(1)
+
+	var patches []api.ManifestPatch
+	if err := json.NewDecoder(r.Body).Decode(&patches); err != nil {
+		patchFail.Inc(1)
+		respondError(w, r, fmt.Sprintf("invalid manifest patch: %v", err), http.StatusBadRequest)
+		return
+	}
+	if uri.Path != "" {
+		for i := range patches {
+			patches[i].Path = uri.Path + "/" + patches[i].Path
+			if patches[i].From != "" {
+				patches[i].From = uri.Path + "/" + patches[i].From
+			}
+		}
+	}
+
+	addr, err := s.api.Resolve(r.Context(), uri.Addr)
+	if err != nil {
+		patchFail.Inc(1)
+		respondError(w, r, fmt.Sprintf("cannot resolve %s: %s", uri.Addr, err), http.StatusNotFound)
+		return
+	}
+	if base := r.Header.Get("If-Match"); base != "" && strings.Trim(base, `"`) != addr.Hex() {
+		patchFail.Inc(1)
+		respondError(w, r, fmt.Sprintf("manifest %s was modified, current manifest is %s", strings.Trim(base, `"`), addr), http.StatusPreconditionFailed)
+		return
+	}
+
+	newAddr, err := s.api.UpdateManifest(r.Context(), addr, func(mw *api.ManifestWriter) error {
+		return mw.Patch(r.Context(), patches)
+	})
+	if err != nil {
+		patchFail.Inc(1)
+		if _, ok := err.(*api.ManifestPatchError); ok {
+			respondError(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+		respondError(w, r, fmt.Sprintf("could not patch manifest: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.Header().Set("ETag", fmt.Sprintf("%q", newAddr.Hex()))
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, newAddr)
+}
+
+// HandlePin handles a POST request to bzz-pin:/<addr> and pins the manifest
+// at <addr> together with all files it references in the local store. With
+// the raw=true query parameter <addr> is pinned as a single raw file.
+func (s *Server) HandlePin(w http.ResponseWriter, r *http.Request) {
+	ruid := GetRUID(r.Context())
+	uri := GetURI(r.Context())
+	log.Debug("handle.pin", "ruid", ruid, "uri", uri)
+	pinCount.Inc(1)
+
+	addr, err := s.api.Resolve(r.Context(), uri.Addr)
+	if err != nil {
+		pinFail.Inc(1)
+		respondError(w, r, fmt.Sprintf("cannot resolve %s: %s", uri.Addr, err), http.StatusNotFound)
+		return
+	}
+	raw := r.URL.Query().Get("raw") == "true"
+	if err := s.pinAPI.PinFiles(r.Context(), addr, raw); err != nil {
+		pinFail.Inc(1)
+		respondError(w, r, fmt.Sprintf("could not pin %s: %v", addr, err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, addr)
+}
+
+// HandleUnpin handles a DELETE request to bzz-pin:/<addr> and releases one
+// pin held on the content at <addr>
+func (s *Server) HandleUnpin(w http.ResponseWriter, r *http.Request) {
+	ruid := GetRUID(r.Context())
+	uri := GetURI(r.Context())
+	log.Debug("handle.unpin", "ruid", ruid, "uri", uri)
+	unpinCount.Inc(1)
+
+	addr, err := s.api.Resolve(r.Context(), uri.Addr)
+	if err != nil {
+		unpinFail.Inc(1)
+		respondError(w, r, fmt.Sprintf("cannot resolve %s: %s", uri.Addr, err), http.StatusNotFound)
+		return
+	}
+	if err := s.pinAPI.UnpinFiles(r.Context(), addr); err != nil {
+		unpinFail.Inc(1)
+		if err == pin.ErrNotPinned {
+			respondError(w, r, err.Error(), http.StatusNotFound)
+			return
+		}
+		respondError(w, r, fmt.Sprintf("could not unpin %s: %v", addr, err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, addr)
+}
+
+// HandleGetPins handles a GET request to bzz-pin:/ and responds with the list
+// of pinned content as JSON
+func (s *Server) HandleGetPins(w http.ResponseWriter, r *http.Request) {
+	ruid := GetRUID(r.Context())
+	log.Debug("handle.get.pins", "ruid", ruid)
+
+	list, err := s.pinAPI.ListPinFiles()
+	if err != nil {
+		respondError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
 // Handles feed manifest creation and feed updates
 // The POST request admits a JSON structure as defined in the feeds package: `feed.updateRequestJSON`
 // The requests can be to a) create a feed manifest, b) update a feed or c) both a+b: create a feed manifest and publish a first update
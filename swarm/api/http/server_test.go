@@ -20,6 +20,7 @@ import (
 	"archive/tar"
 	"bytes"
 	"context"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"flag"
@@ -48,6 +49,7 @@ import (
 	"github.com/ethereum/go-ethereum/swarm/multihash"
 	"github.com/ethereum/go-ethereum/swarm/storage"
 	"github.com/ethereum/go-ethereum/swarm/storage/feed"
+	"github.com/ethereum/go-ethereum/swarm/storage/pin"
 	"github.com/ethereum/go-ethereum/swarm/testutil"
 )
 
@@ -57,8 +59,8 @@ func init() {
 	log.Root().SetHandler(log.CallerFileHandler(log.LvlFilterHandler(log.Lvl(*loglevel), log.StreamHandler(os.Stderr, log.TerminalFormat(true)))))
 }
 
-func serverFunc(api *api.API) TestServer {
-	return NewServer(api, "")
+func serverFunc(api *api.API, pinAPI *pin.API) TestServer {
+	return NewServer(api, pinAPI, "")
 }
 
 func newTestSigner() (*feed.GenericSigner, error) {
@@ -1067,7 +1069,7 @@ func TestModify(t *testing.T) {
 			uri:                fmt.Sprintf("%s/bzz:/%s", srv.URL, hash),
 			method:             "PATCH",
 			headers:            map[string]string{},
-			expectedStatusCode: http.StatusMethodNotAllowed,
+			expectedStatusCode: http.StatusBadRequest,
 			verbose:            false,
 		},
 		{
@@ -1141,6 +1143,206 @@ func TestMultiPartUpload(t *testing.T) {
 	}
 }
 
+// TestBzzPatch tests applying batch updates to a manifest, including the
+// optimistic concurrency check against the base manifest hash
+func TestBzzPatch(t *testing.T) {
+	resolver := newTestResolveValidator("")
+	srv := NewTestSwarmServer(t, serverFunc, resolver)
+	defer srv.Close()
+
+	files := map[string]string{
+		"a.txt":     "aaa",
+		"b.txt":     "bbb",
+		"dir/c.txt": "ccc",
+	}
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	for name, content := range files {
+		hdr := &tar.Header{
+			Name:    name,
+			Mode:    0644,
+			Size:    int64(len(content)),
+			ModTime: time.Now(),
+			Xattrs: map[string]string{
+				"user.swarm.content-type": "text/plain",
+			},
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(tw, content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	res, hash := httpDo("POST", srv.URL+"/bzz:/", buf, map[string]string{"Content-Type": "application/x-tar"}, false, t)
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("upload status code mismatch: have %d, want %d", res.StatusCode, http.StatusOK)
+	}
+	res, rawHash := httpDo("POST", srv.URL+"/bzz-raw:/", strings.NewReader("ddd"), nil, false, t)
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("upload status code mismatch: have %d, want %d", res.StatusCode, http.StatusOK)
+	}
+	base := common.HexToHash(hash)
+	resolver.hash = &base
+
+	patch := func(uri, ifMatch string, patches []api.ManifestPatch) (*http.Response, string) {
+		body, err := json.Marshal(patches)
+		if err != nil {
+			t.Fatal(err)
+		}
+		headers := map[string]string{"Content-Type": "application/json"}
+		if ifMatch != "" {
+			headers["If-Match"] = fmt.Sprintf("%q", ifMatch)
+		}
+		return httpDo("PATCH", srv.URL+"/bzz:/"+uri, bytes.NewReader(body), headers, false, t)
+	}
+	checkFiles := func(manifest string, want map[string]string) {
+		for name, content := range want {
+			res, body := httpDo("GET", srv.URL+"/bzz:/"+manifest+"/"+name, nil, nil, false, t)
+			if content == "" {
+				if res.StatusCode != http.StatusNotFound {
+					t.Fatalf("%s status code mismatch: have %d, want %d", name, res.StatusCode, http.StatusNotFound)
+				}
+				continue
+			}
+			if res.StatusCode != http.StatusOK {
+				t.Fatalf("%s status code mismatch: have %d, want %d", name, res.StatusCode, http.StatusOK)
+			}
+			if body != content {
+				t.Fatalf("%s content mismatch: have %q, want %q", name, body, content)
+			}
+		}
+	}
+
+	// apply all operations in one request against the resolved base manifest
+	res, newHash := patch("somebogusensname", hash, []api.ManifestPatch{
+		{Op: api.PatchAdd, Path: "d.txt", Hash: rawHash, ContentType: "text/plain"},
+		{Op: api.PatchRemove, Path: "a.txt"},
+		{Op: api.PatchRename, From: "b.txt", Path: "dir/b.txt"},
+	})
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("patch status code mismatch: have %d, want %d: %s", res.StatusCode, http.StatusOK, newHash)
+	}
+	if etag := res.Header.Get("ETag"); etag != fmt.Sprintf("%q", newHash) {
+		t.Fatalf("etag mismatch: have %s, want %q", etag, newHash)
+	}
+	checkFiles(newHash, map[string]string{
+		"a.txt":     "",
+		"b.txt":     "",
+		"d.txt":     "ddd",
+		"dir/b.txt": "bbb",
+		"dir/c.txt": "ccc",
+	})
+	checkFiles(hash, files)
+
+	// a patch based on a stale manifest must be rejected
+	newBase := common.HexToHash(newHash)
+	resolver.hash = &newBase
+	res, _ = patch("somebogusensname", hash, []api.ManifestPatch{{Op: api.PatchRemove, Path: "d.txt"}})
+	if res.StatusCode != http.StatusPreconditionFailed {
+		t.Fatalf("stale patch status code mismatch: have %d, want %d", res.StatusCode, http.StatusPreconditionFailed)
+	}
+
+	// invalid operations reject the whole patch
+	for _, patches := range [][]api.ManifestPatch{
+		{{Op: api.PatchRename, From: "missing.txt", Path: "x.txt"}},
+		{{Op: api.PatchRemove, Path: "d.txt"}, {Op: api.PatchRemove, Path: "missing.txt"}},
+		{{Op: api.PatchAdd, Path: "x.txt"}},
+		{{Op: "bogus", Path: "x.txt"}},
+	} {
+		res, _ := patch(newHash, "", patches)
+		if res.StatusCode != http.StatusBadRequest {
+			t.Fatalf("invalid patch %v status code mismatch: have %d, want %d", patches, res.StatusCode, http.StatusBadRequest)
+		}
+	}
+
+	// operation paths are relative to the path in the URI
+	res, dirHash := patch(newHash+"/dir", newHash, []api.ManifestPatch{{Op: api.PatchRemove, Path: "c.txt"}})
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("patch status code mismatch: have %d, want %d: %s", res.StatusCode, http.StatusOK, dirHash)
+	}
+	checkFiles(dirHash, map[string]string{
+		"d.txt":     "ddd",
+		"dir/b.txt": "bbb",
+		"dir/c.txt": "",
+	})
+}
+
+// TestBzzPin tests pinning, listing and unpinning content through the
+// bzz-pin scheme
+func TestBzzPin(t *testing.T) {
+	srv := NewTestSwarmServer(t, serverFunc, nil)
+	defer srv.Close()
+
+	buf := new(bytes.Buffer)
+	form := multipart.NewWriter(buf)
+	file, _ := form.CreateFormFile("cv", "cv.txt")
+	file.Write([]byte("John Doe's Credentials"))
+	form.Close()
+
+	headers := map[string]string{
+		"Content-Type":   form.FormDataContentType(),
+		"Content-Length": strconv.Itoa(buf.Len()),
+	}
+	res, hash := httpDo("POST", srv.URL+"/bzz:/", buf, headers, false, t)
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("status code mismatch: have %d, want %d", res.StatusCode, http.StatusOK)
+	}
+	addr := storage.Address(common.Hex2Bytes(hash))
+
+	res, _ = httpDo("POST", srv.URL+"/bzz-pin:/"+hash, nil, nil, false, t)
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("pin status code mismatch: have %d, want %d", res.StatusCode, http.StatusOK)
+	}
+	if cnt := srv.LocalStore.DbStore.PinCount(addr); cnt != 1 {
+		t.Fatalf("pin count mismatch: have %d, want %d", cnt, 1)
+	}
+
+	res, body := httpDo("GET", srv.URL+"/bzz-pin:/", nil, nil, false, t)
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("list status code mismatch: have %d, want %d", res.StatusCode, http.StatusOK)
+	}
+	var list []*pin.FileInfo
+	if err := json.Unmarshal([]byte(body), &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || !bytes.Equal(list[0].Address, addr) || list[0].PinCount != 1 {
+		t.Fatalf("pin list mismatch: have %v", body)
+	}
+
+	res, _ = httpDo("DELETE", srv.URL+"/bzz-pin:/"+hash, nil, nil, false, t)
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("unpin status code mismatch: have %d, want %d", res.StatusCode, http.StatusOK)
+	}
+	if cnt := srv.LocalStore.DbStore.PinCount(addr); cnt != 0 {
+		t.Fatalf("pin count mismatch: have %d, want %d", cnt, 0)
+	}
+	res, _ = httpDo("DELETE", srv.URL+"/bzz-pin:/"+hash, nil, nil, false, t)
+	if res.StatusCode != http.StatusNotFound {
+		t.Fatalf("unpin status code mismatch: have %d, want %d", res.StatusCode, http.StatusNotFound)
+	}
+}
+
+// TestBzzPinDisabled tests that the bzz-pin scheme is not served if pinning
+// is not enabled
+func TestBzzPinDisabled(t *testing.T) {
+	srv := NewTestSwarmServer(t, func(api *api.API, _ *pin.API) TestServer {
+		return NewServer(api, nil, "")
+	}, nil)
+	defer srv.Close()
+
+	hash := hex.EncodeToString(make([]byte, 32))
+	for _, method := range []string{"POST", "DELETE"} {
+		res, _ := httpDo(method, srv.URL+"/bzz-pin:/"+hash, nil, nil, false, t)
+		if res.StatusCode != http.StatusMethodNotAllowed {
+			t.Fatalf("%s status code mismatch: have %d, want %d", method, res.StatusCode, http.StatusMethodNotAllowed)
+		}
+	}
+}
+
 // TestBzzGetFileWithResolver tests fetching a file using a mocked ENS resolver
 func TestBzzGetFileWithResolver(t *testing.T) {
 	resolver := newTestResolveValidator("")
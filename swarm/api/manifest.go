@@ -144,6 +144,88 @@ func (m *ManifestWriter) RemoveEntry(path string) error {
 	return nil
 }
 
+// RenameEntry moves the file entry at path from to path to, replacing any
+// file already stored at to
+func (m *ManifestWriter) RenameEntry(from, to string) error {
+	from, to = RegularSlashes(from), RegularSlashes(to)
+	entry := m.trie.getFileEntry(from)
+	if entry == nil {
+		return fmt.Errorf("%s: %v", from, ErrNotFound)
+	}
+	moved := entry.ManifestEntry
+	moved.Path = to
+	m.trie.deleteEntry(from, m.quitC)
+	return m.trie.addEntry(newManifestTrieEntry(&moved, nil), m.quitC)
+}
+
+// Manifest patch operations
+const (
+	PatchAdd    = "add"
+	PatchRemove = "remove"
+	PatchRename = "rename"
+)
+
+// ManifestPatch is a single operation of a batch manifest update. Add stores
+// an entry referencing already uploaded content at Path, remove deletes the
+// entry at Path and rename moves the entry at From to Path.
+type ManifestPatch struct {
+	Op          string `json:"op"`
+	Path        string `json:"path"`
+	From        string `json:"from,omitempty"`
+	Hash        string `json:"hash,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+	Mode        int64  `json:"mode,omitempty"`
+	Size        int64  `json:"size,omitempty"`
+}
+
+// ManifestPatchError is returned when a patch operation is malformed or does
+// not apply to the manifest
+type ManifestPatchError struct {
+	Index int // index of the failing operation
+	Err   error
+}
+
+func (e *ManifestPatchError) Error() string {
+	return fmt.Sprintf("patch operation %d: %v", e.Index, e.Err)
+}
+
+// Patch applies the operations to the manifest in order, stopping at the
+// first one that fails
+func (m *ManifestWriter) Patch(ctx context.Context, patches []ManifestPatch) error {
+	for i, patch := range patches {
+		var err error
+		switch patch.Op {
+		case PatchAdd:
+			if patch.Hash == "" {
+				err = errors.New("missing entry hash")
+				break
+			}
+			_, err = m.AddEntry(ctx, nil, &ManifestEntry{
+				Hash:        patch.Hash,
+				Path:        patch.Path,
+				ContentType: patch.ContentType,
+				Mode:        patch.Mode,
+				Size:        patch.Size,
+				ModTime:     time.Now(),
+			})
+		case PatchRemove:
+			if m.trie.getFileEntry(RegularSlashes(patch.Path)) == nil {
+				err = fmt.Errorf("%s: %v", patch.Path, ErrNotFound)
+				break
+			}
+			err = m.RemoveEntry(RegularSlashes(patch.Path))
+		case PatchRename:
+			err = m.RenameEntry(patch.From, patch.Path)
+		default:
+			err = fmt.Errorf("unknown operation %q", patch.Op)
+		}
+		if err != nil {
+			return &ManifestPatchError{Index: i, Err: err}
+		}
+	}
+	return nil
+}
+
 // Store stores the manifest, returning the resulting storage address
 func (m *ManifestWriter) Store() (storage.Address, error) {
 	return m.trie.ref, m.trie.recalcAndStore()
@@ -562,6 +644,20 @@ func (mt *manifestTrie) findPrefixOf(path string, quitC chan bool) (entry *manif
 	return nil, 0
 }
 
+// getFileEntry returns the file entry stored at exactly the given path, or nil
+// if there is none. Unlike getEntry it doesn't match path prefixes or fall back
+// to the default entry.
+func (mt *manifestTrie) getFileEntry(path string) *manifestTrieEntry {
+	entry, fullpath := mt.getEntry(path)
+	if entry == nil || fullpath != path || entry.ContentType == ManifestType {
+		return nil
+	}
+	if !strings.HasSuffix(path, entry.Path) || (entry.Path == "" && path != "") {
+		return nil
+	}
+	return entry
+}
+
 // file system manifest always contains regularized paths
 // no leading or trailing slashes, only single slashes inside
 func RegularSlashes(path string) (res string) {
@@ -31,6 +31,7 @@ import (
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/ethereum/go-ethereum/swarm/log"
 	"github.com/ethereum/go-ethereum/swarm/state"
+	"github.com/ethereum/go-ethereum/swarm/swap"
 )
 
 const (
@@ -44,10 +45,11 @@ const (
 // BzzSpec is the spec of the generic swarm handshake
 var BzzSpec = &protocols.Spec{
 	Name:       "bzz",
-	Version:    7,
+	Version:    8,
 	MaxMsgSize: 10 * 1024 * 1024,
 	Messages: []interface{}{
 		HandshakeMsg{},
+		swap.ChequeMsg{},
 	},
 }
 
@@ -69,6 +71,7 @@ type BzzConfig struct {
 	HiveParams   *HiveParams
 	NetworkID    uint64
 	LightNode    bool
+	Swap         *swap.Swap // settles balances with peers, nil if swap is disabled
 }
 
 // Bzz is the swarm protocol bundle
@@ -81,6 +84,7 @@ type Bzz struct {
 	handshakes   map[enode.ID]*HandshakeMsg
 	streamerSpec *protocols.Spec
 	streamerRun  func(*BzzPeer) error
+	swap         *swap.Swap
 }
 
 // NewBzz is the swarm protocol constructor
@@ -97,6 +101,7 @@ func NewBzz(config *BzzConfig, kad *Kademlia, store state.Store, streamerSpec *p
 		handshakes:   make(map[enode.ID]*HandshakeMsg),
 		streamerRun:  streamerRun,
 		streamerSpec: streamerSpec,
+		swap:         config.Swap,
 	}
 }
 
@@ -209,6 +214,7 @@ func (b *Bzz) performHandshake(p *protocols.Peer, handshake *HandshakeMsg) error
 	}
 	handshake.peerAddr = rsh.(*HandshakeMsg).Addr
 	handshake.LightNode = rsh.(*HandshakeMsg).LightNode
+	handshake.peerSwap = rsh.(*HandshakeMsg).Swap
 	return nil
 }
 
@@ -228,6 +234,10 @@ func (b *Bzz) runBzz(p *p2p.Peer, rw p2p.MsgReadWriter) error {
 
 		return err
 	}
+	// with swap enabled the bzz connection carries the cheques settling balances
+	if b.swap != nil {
+		return b.swap.Run(swap.NewPeer(peer, handshake.peerSwap))
+	}
 	// fail if we get another handshake
 	msg, err := rw.ReadMsg()
 	if err != nil {
@@ -269,15 +279,19 @@ func (p *BzzPeer) ID() enode.ID {
 * Version: 8 byte integer version of the protocol
 * NetworkID: 8 byte integer network identifier
 * Addr: the address advertised by the node including underlay and overlay connecctions
+* Swap: the swap profile of the node, nil if it does not issue cheques
 */
 type HandshakeMsg struct {
 	Version   uint64
 	NetworkID uint64
 	Addr      *BzzAddr
 	LightNode bool
+	Swap      *swap.Profile `rlp:"nil"`
 
 	// peerAddr is the address received in the peer handshake
 	peerAddr *BzzAddr
+	// peerSwap is the swap profile received in the peer handshake
+	peerSwap *swap.Profile
 
 	init chan bool
 	done chan struct{}
@@ -323,6 +337,9 @@ func (b *Bzz) GetHandshake(peerID enode.ID) (*HandshakeMsg, bool) {
 			init:      make(chan bool, 1),
 			done:      make(chan struct{}),
 		}
+		if b.swap != nil {
+			handshake.Swap = b.swap.Profile()
+		}
 		// when handhsake is first created for a remote peer
 		// it is initialised with the init
 		handshake.init <- true
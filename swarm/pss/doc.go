@@ -58,4 +58,10 @@
 //
 // If it is a "new" connection, the protocol will be "run" on the remote peer, in the same manner as if it was pre-emptively added.
 //
+// MAILBOX
+//
+// Messages to a recipient who is offline are lost with mere forwarding. Nodes with the mailbox enabled (`PssParams.MailboxEnabled`) keep the messages addressed to a full overlay address if the recipient is not connected to them and none of their peers is nearer to it. The messages are persisted in the node's state store for `PssParams.MailboxTTL`, with at most `PssParams.MailboxQuota` messages kept per originating node. To be accounted to their originating node, messages addressed to a full overlay address carry its signature; messages without it are not kept.
+//
+// Whenever a pss node connects to a peer it asks for the messages kept for it with a request signed by its key. The request is only served if the hash of the signing key is the requested overlay address, which holds for swarm nodes.
+//
 package pss
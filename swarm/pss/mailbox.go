@@ -0,0 +1,413 @@
+// Copyright 2026 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pss
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/protocols"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/swarm/log"
+	"github.com/ethereum/go-ethereum/swarm/network"
+	"github.com/ethereum/go-ethereum/swarm/pot"
+	"github.com/ethereum/go-ethereum/swarm/state"
+)
+
+const (
+	defaultMailboxTTL    = time.Hour * 24
+	defaultMailboxQuota  = 1000
+	mailboxRequestWindow = time.Minute * 5 // maximum age of a mailbox request
+	mailboxIndexKey      = "pss-mailbox"
+	mailboxKeyPrefix     = "pss-mailbox-"
+)
+
+var (
+	errMailboxQuota = errors.New("mailbox quota exceeded")
+)
+
+// mailboxRequestMsg asks a peer for the messages it keeps for the recipient at Addr
+//
+// The request is signed with the recipient's key, whose hash is the recipient's overlay address.
+// Time is the unix time of the request in nanoseconds, guarding against replays: a request is
+// only served if it is newer than the last one served for the same recipient.
+type mailboxRequestMsg struct {
+	Addr []byte
+	Time uint64
+	Sig  []byte
+}
+
+// mailboxMsg delivers the messages a peer kept for the recipient
+type mailboxMsg struct {
+	Msgs []*PssMsg
+}
+
+// newMailboxRequest creates a mailbox request for addr signed with key
+func newMailboxRequest(addr []byte, key *ecdsa.PrivateKey) (*mailboxRequestMsg, error) {
+	req := &mailboxRequestMsg{
+		Addr: addr,
+		Time: uint64(time.Now().UnixNano()),
+	}
+	sig, err := crypto.Sign(req.hash(), key)
+	if err != nil {
+		return nil, err
+	}
+	req.Sig = sig
+	return req, nil
+}
+
+// hash returns the digest of the request fields that is signed
+func (req *mailboxRequestMsg) hash() []byte {
+	t := make([]byte, 8)
+	binary.BigEndian.PutUint64(t, req.Time)
+	return crypto.Keccak256(req.Addr, t)
+}
+
+// verify checks that the request is recent and signed by the key the address belongs to
+func (req *mailboxRequestMsg) verify() error {
+	if age := time.Since(time.Unix(0, int64(req.Time))); age > mailboxRequestWindow || age < -mailboxRequestWindow {
+		return fmt.Errorf("mailbox request expired: %v", age)
+	}
+	pubkey, err := crypto.SigToPub(req.hash(), req.Sig)
+	if err != nil {
+		return fmt.Errorf("invalid mailbox request signature: %v", err)
+	}
+	if !bytes.Equal(crypto.Keccak256(crypto.FromECDSAPub(pubkey)), req.Addr) {
+		return fmt.Errorf("mailbox request signer mismatch for %x", req.Addr)
+	}
+	return nil
+}
+
+// originHash returns the digest of the message fields signed by its originating node
+func originHash(msg *PssMsg) []byte {
+	expire := make([]byte, 4)
+	binary.BigEndian.PutUint32(expire, msg.Expire)
+	return crypto.Keccak256(msg.To, expire, msg.Payload.Hash().Bytes())
+}
+
+// signOrigin signs the message with the key of the originating node, allowing
+// mailboxes to account the message to it
+//
+// Only messages with a full recipient address are signed, as only those can be
+// kept by mailboxes. Senders addressing a partial address stay anonymous.
+func signOrigin(msg *PssMsg, key *ecdsa.PrivateKey) error {
+	if len(msg.To) != addressLength {
+		return nil
+	}
+	sig, err := crypto.Sign(originHash(msg), key)
+	if err != nil {
+		return err
+	}
+	msg.Origin = sig
+	return nil
+}
+
+// origin returns the address of the node that originated the message
+func origin(msg *PssMsg) (common.Address, error) {
+	if len(msg.Origin) == 0 {
+		return common.Address{}, errors.New("unsigned message")
+	}
+	pubkey, err := crypto.SigToPub(originHash(msg), msg.Origin)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("invalid origin signature: %v", err)
+	}
+	return crypto.PubkeyToAddress(*pubkey), nil
+}
+
+// mailboxEntry is a message kept for an offline recipient
+type mailboxEntry struct {
+	Origin  common.Address // address of the node that originated the message
+	Expires int64          // unix time after which the message is dropped
+	Msg     []byte         // rlp encoded PssMsg
+}
+
+// mailbox keeps messages for offline recipients in the node's neighbourhood
+// until they ask for them
+//
+// Messages are persisted in the state store by recipient, together with an index of the recipients.
+type mailbox struct {
+	store    state.Store
+	ttl      time.Duration
+	quota    int
+	mu       sync.Mutex
+	index    map[string]bool        // hex addresses of recipients with messages
+	counts   map[common.Address]int // number of messages kept per originating node
+	requests map[string]uint64      // time of the last request served per recipient
+}
+
+// newMailbox creates a mailbox persisting messages in store
+// each message is kept for ttl, and at most quota messages are kept per originating node
+func newMailbox(store state.Store, ttl time.Duration, quota int) (*mailbox, error) {
+	m := &mailbox{
+		store:    store,
+		ttl:      ttl,
+		quota:    quota,
+		index:    make(map[string]bool),
+		counts:   make(map[common.Address]int),
+		requests: make(map[string]uint64),
+	}
+	var recipients []string
+	if err := store.Get(mailboxIndexKey, &recipients); err != nil && err != state.ErrNotFound {
+		return nil, err
+	}
+	for _, recipient := range recipients {
+		m.index[recipient] = true
+	}
+	// drop the expired messages and count the rest
+	if err := m.clean(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// deposit keeps the message originated by sender for its recipient
+func (m *mailbox) deposit(sender common.Address, msg *PssMsg) error {
+	data, err := rlp.EncodeToBytes(msg)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.counts[sender] >= m.quota {
+		metrics.GetOrRegisterCounter("pss.mailbox.quota", nil).Inc(1)
+		return errMailboxQuota
+	}
+	recipient := common.Bytes2Hex(msg.To)
+	entries, err := m.load(recipient)
+	if err != nil {
+		return err
+	}
+	entries = append(entries, mailboxEntry{
+		Origin:  sender,
+		Expires: time.Now().Add(m.ttl).Unix(),
+		Msg:     data,
+	})
+	if err := m.save(recipient, entries); err != nil {
+		return err
+	}
+	m.counts[sender]++
+	metrics.GetOrRegisterCounter("pss.mailbox.deposit", nil).Inc(1)
+	return nil
+}
+
+// collect removes and returns the unexpired messages kept for the recipient
+// the request must be verified by the caller
+func (m *mailbox) collect(req *mailboxRequestMsg) ([]*PssMsg, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	recipient := common.Bytes2Hex(req.Addr)
+	if req.Time <= m.requests[recipient] {
+		return nil, fmt.Errorf("mailbox request replayed for %x", req.Addr)
+	}
+	m.requests[recipient] = req.Time
+	entries, err := m.load(recipient)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().Unix()
+	var msgs []*PssMsg
+	for _, entry := range entries {
+		m.counts[entry.Origin]--
+		if entry.Expires < now {
+			continue
+		}
+		msg := &PssMsg{}
+		if err := rlp.DecodeBytes(entry.Msg, msg); err != nil {
+			log.Warn("invalid message in mailbox", "recipient", recipient, "err", err)
+			continue
+		}
+		msgs = append(msgs, msg)
+	}
+	if err := m.save(recipient, nil); err != nil {
+		return nil, err
+	}
+	metrics.GetOrRegisterCounter("pss.mailbox.collect", nil).Inc(int64(len(msgs)))
+	return msgs, nil
+}
+
+// clean drops the expired messages and recounts the messages kept per originating node
+func (m *mailbox) clean() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now().Unix()
+	counts := make(map[common.Address]int)
+	for recipient := range m.index {
+		entries, err := m.load(recipient)
+		if err != nil {
+			return err
+		}
+		var kept []mailboxEntry
+		for _, entry := range entries {
+			if entry.Expires >= now {
+				kept = append(kept, entry)
+				counts[entry.Origin]++
+			}
+		}
+		if len(kept) < len(entries) {
+			if err := m.save(recipient, kept); err != nil {
+				return err
+			}
+		}
+	}
+	m.counts = counts
+	return nil
+}
+
+// load returns the messages kept for the recipient
+// the caller must hold m.mu
+func (m *mailbox) load(recipient string) (entries []mailboxEntry, err error) {
+	err = m.store.Get(mailboxKeyPrefix+recipient, &entries)
+	if err == state.ErrNotFound {
+		return nil, nil
+	}
+	return entries, err
+}
+
+// save persists the messages kept for the recipient, updating the index if
+// the recipient is added or removed
+// the caller must hold m.mu
+func (m *mailbox) save(recipient string, entries []mailboxEntry) error {
+	if len(entries) == 0 {
+		if err := m.store.Delete(mailboxKeyPrefix + recipient); err != nil {
+			return err
+		}
+		if !m.index[recipient] {
+			return nil
+		}
+		delete(m.index, recipient)
+		return m.saveIndex()
+	}
+	if err := m.store.Put(mailboxKeyPrefix+recipient, entries); err != nil {
+		return err
+	}
+	if m.index[recipient] {
+		return nil
+	}
+	m.index[recipient] = true
+	return m.saveIndex()
+}
+
+// saveIndex persists the addresses of the recipients with messages
+// the caller must hold m.mu
+func (m *mailbox) saveIndex() error {
+	recipients := make([]string, 0, len(m.index))
+	for recipient := range m.index {
+		recipients = append(recipients, recipient)
+	}
+	return m.store.Put(mailboxIndexKey, recipients)
+}
+
+/////////////////////////////////////////////////////////////////////
+// SECTION: Pss mailbox handling
+/////////////////////////////////////////////////////////////////////
+
+// keeps the message received from the peer if the recipient is offline and the
+// node is nearest to it among its connections
+//
+// Only messages with a full recipient address and signed by their originating
+// node are kept, the quota is accounted to the latter.
+func (p *Pss) keepForRecipient(peer enode.ID, msg *PssMsg) {
+	if len(msg.To) != addressLength || p.isSelfRecipient(msg) {
+		return
+	}
+	if int64(msg.Expire) < time.Now().Unix() || p.checkFwdCache(msg) {
+		return
+	}
+	base := p.BaseAddr()
+	nearest := true
+	p.Kademlia.EachConn(msg.To, 255, func(sp *network.Peer, po int, isproxbin bool) bool {
+		// the recipient is online if connected, otherwise a closer peer keeps the message
+		nearest = !bytes.Equal(sp.Address(), msg.To) && pot.ProxCmp(msg.To, sp.Address(), base) >= 0
+		return false
+	})
+	if !nearest {
+		return
+	}
+	sender, err := origin(msg)
+	if err != nil {
+		log.Debug("pss mailbox rejected message", "to", common.ToHex(msg.To), "peer", peer, "err", err)
+		return
+	}
+	if err := p.mailbox.deposit(sender, msg); err != nil {
+		log.Debug("pss mailbox deposit failed", "to", common.ToHex(msg.To), "sender", sender, "err", err)
+		return
+	}
+	log.Trace("pss mailbox kept message", "to", common.ToHex(msg.To), "sender", sender)
+}
+
+// asks the peer for the messages it kept for the node while it was offline
+func (p *Pss) requestMailbox(pp *protocols.Peer) {
+	req, err := newMailboxRequest(p.BaseAddr(), p.privateKey)
+	if err != nil {
+		log.Error("pss mailbox request failed", "err", err)
+		return
+	}
+	if err := pp.Send(context.TODO(), req); err != nil {
+		log.Debug("pss mailbox request failed", "peer", pp.ID(), "err", err)
+	}
+}
+
+// serves a mailbox request of the peer, ignored if the node does not keep messages
+func (p *Pss) handleMailboxRequest(ctx context.Context, pp *protocols.Peer, req *mailboxRequestMsg) error {
+	if p.mailbox == nil {
+		return nil
+	}
+	if err := req.verify(); err != nil {
+		metrics.GetOrRegisterCounter("pss.mailbox.request.invalid", nil).Inc(1)
+		log.Debug("invalid pss mailbox request", "peer", pp.ID(), "err", err)
+		return nil
+	}
+	msgs, err := p.mailbox.collect(req)
+	if err != nil {
+		log.Debug("pss mailbox request failed", "peer", pp.ID(), "err", err)
+		return nil
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+	log.Trace("pss mailbox delivering messages", "to", common.ToHex(req.Addr), "count", len(msgs))
+	return pp.Send(ctx, &mailboxMsg{Msgs: msgs})
+}
+
+// processes the messages a peer kept for the node
+func (p *Pss) handleMailboxMsg(msg *mailboxMsg) error {
+	for _, pssmsg := range msg.Msgs {
+		if !p.isSelfRecipient(pssmsg) {
+			log.Debug("pss mailbox delivered message for someone else", "to", common.ToHex(pssmsg.To))
+			continue
+		}
+		// the same message may be kept by several peers
+		if p.checkFwdCache(pssmsg) {
+			continue
+		}
+		p.addFwdCache(pssmsg)
+		if err := p.process(pssmsg); err != nil {
+			log.Debug("pss mailbox message processing failed", "err", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,362 @@
+// Copyright 2026 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pss
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/simulations/adapters"
+	"github.com/ethereum/go-ethereum/swarm/network"
+	"github.com/ethereum/go-ethereum/swarm/network/simulation"
+	"github.com/ethereum/go-ethereum/swarm/pot"
+	"github.com/ethereum/go-ethereum/swarm/state"
+	whisper "github.com/ethereum/go-ethereum/whisper/whisperv5"
+)
+
+// creates a message for the recipient address
+func newMailboxTestMsg(to []byte, data string) *PssMsg {
+	msg := newPssMsg(&msgParams{raw: true})
+	msg.To = to
+	msg.Expire = uint32(time.Now().Add(DefaultMsgTTL).Unix())
+	msg.Payload = &whisper.Envelope{
+		Data: []byte(data),
+	}
+	return msg
+}
+
+// checks the quota per originating node and the collection of the messages with signed requests
+func TestMailbox(t *testing.T) {
+	store := state.NewInmemoryStore()
+	defer store.Close()
+	mb, err := newMailbox(store, time.Minute, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := crypto.Keccak256(crypto.FromECDSAPub(&key.PublicKey))
+	sender := common.Address{1}
+	for i := 0; i < 2; i++ {
+		if err := mb.deposit(sender, newMailboxTestMsg(addr, fmt.Sprintf("msg %d", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := mb.deposit(sender, newMailboxTestMsg(addr, "msg 2")); err != errMailboxQuota {
+		t.Fatalf("deposit error mismatch: have %v, want %v", err, errMailboxQuota)
+	}
+	if err := mb.deposit(common.Address{2}, newMailboxTestMsg(addr, "msg 3")); err != nil {
+		t.Fatal(err)
+	}
+
+	// the messages and the quotas are restored from the store
+	mb, err = newMailbox(store, time.Minute, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mb.deposit(sender, newMailboxTestMsg(addr, "msg 4")); err != errMailboxQuota {
+		t.Fatalf("deposit error mismatch: have %v, want %v", err, errMailboxQuota)
+	}
+
+	// a request must be signed by the key of the address
+	otherkey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := newMailboxRequest(addr, otherkey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := req.verify(); err == nil {
+		t.Fatal("expected request signed with another key to fail verification")
+	}
+	req, err = newMailboxRequest(addr, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := req.verify(); err != nil {
+		t.Fatal(err)
+	}
+	msgs, err := mb.collect(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, msg := range msgs {
+		if want := fmt.Sprintf("msg %d", []int{0, 1, 3}[i]); string(msg.Payload.Data) != want {
+			t.Fatalf("message %d mismatch: have %q, want %q", i, msg.Payload.Data, want)
+		}
+	}
+	if len(msgs) != 3 {
+		t.Fatalf("message count mismatch: have %d, want %d", len(msgs), 3)
+	}
+
+	// the request cannot be replayed
+	if _, err := mb.collect(req); err == nil {
+		t.Fatal("expected replayed request to fail")
+	}
+	// a new request is served right away
+	req, err = newMailboxRequest(addr, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mb.collect(req); err != nil {
+		t.Fatal(err)
+	}
+
+	// the collected messages no longer count against the quota
+	if err := mb.deposit(sender, newMailboxTestMsg(addr, "msg 5")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// checks that messages are accounted to the node that signed them
+func TestMailboxOrigin(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := crypto.Keccak256(crypto.FromECDSAPub(&key.PublicKey))
+
+	msg := newMailboxTestMsg(addr, "signed")
+	if _, err := origin(msg); err == nil {
+		t.Fatal("expected unsigned message to have no origin")
+	}
+	if err := signOrigin(msg, key); err != nil {
+		t.Fatal(err)
+	}
+	have, err := origin(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := crypto.PubkeyToAddress(key.PublicKey); have != want {
+		t.Fatalf("origin mismatch: have %x, want %x", have, want)
+	}
+	// a forwarding peer cannot alter the message without changing its origin
+	altered := newMailboxTestMsg(addr, "altered")
+	altered.Expire, altered.Origin = msg.Expire, msg.Origin
+	if have, err := origin(altered); err == nil && have == crypto.PubkeyToAddress(key.PublicKey) {
+		t.Fatal("expected altered message to change origin")
+	}
+	// messages to partial addresses are not signed
+	msg = newMailboxTestMsg(addr[:4], "anonymous")
+	if err := signOrigin(msg, key); err != nil {
+		t.Fatal(err)
+	}
+	if len(msg.Origin) != 0 {
+		t.Fatal("expected message to partial address to be unsigned")
+	}
+}
+
+// checks that expired messages are dropped
+func TestMailboxExpiry(t *testing.T) {
+	store := state.NewInmemoryStore()
+	defer store.Close()
+	mb, err := newMailbox(store, -time.Second, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := crypto.Keccak256(crypto.FromECDSAPub(&key.PublicKey))
+	if err := mb.deposit(common.Address{1}, newMailboxTestMsg(addr, "expired")); err != nil {
+		t.Fatal(err)
+	}
+	if err := mb.clean(); err != nil {
+		t.Fatal(err)
+	}
+	if len(mb.index) != 0 {
+		t.Fatalf("recipient count mismatch: have %d, want 0", len(mb.index))
+	}
+	if err := mb.deposit(common.Address{1}, newMailboxTestMsg(addr, "expired")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// checks that a message sent to an offline recipient is kept by the node nearest to it,
+// and delivered when the recipient reconnects
+//
+// the sender and the recipient are connected through the mailbox node only
+func TestMailboxDelivery(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 3)
+	addrs := make([][]byte, 3)
+	for i := range keys {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[i] = key
+		addrs[i] = crypto.Keccak256(crypto.FromECDSAPub(&key.PublicKey))
+	}
+	// the mailbox node must be nearer to the recipient than the sender
+	if pot.ProxCmp(addrs[2], addrs[0], addrs[1]) < 0 {
+		keys[0], keys[1] = keys[1], keys[0]
+		addrs[0], addrs[1] = addrs[1], addrs[0]
+	}
+	recipientAddr := addrs[2]
+
+	var mu sync.Mutex
+	kademlias := make(map[enode.ID]*network.Kademlia)
+	kademlia := func(ctx *adapters.ServiceContext) *network.Kademlia {
+		mu.Lock()
+		defer mu.Unlock()
+		if k, ok := kademlias[ctx.Config.ID]; ok {
+			return k
+		}
+		addr := crypto.Keccak256(crypto.FromECDSAPub(&ctx.Config.PrivateKey.PublicKey))
+		kademlias[ctx.Config.ID] = network.NewKademlia(addr, network.NewKadParams())
+		return kademlias[ctx.Config.ID]
+	}
+	sim := simulation.New(map[string]simulation.ServiceFunc{
+		"bzz": func(ctx *adapters.ServiceContext, b *sync.Map) (node.Service, func(), error) {
+			kad := kademlia(ctx)
+			hp := network.NewHiveParams()
+			hp.Discovery = false
+			config := &network.BzzConfig{
+				OverlayAddr:  kad.BaseAddr(),
+				UnderlayAddr: []byte(ctx.Config.Node().String()),
+				HiveParams:   hp,
+			}
+			return network.NewBzz(config, kad, nil, nil, nil), nil, nil
+		},
+		pssProtocolName: func(ctx *adapters.ServiceContext, b *sync.Map) (node.Service, func(), error) {
+			params := NewPssParams().WithPrivateKey(ctx.Config.PrivateKey)
+			params.MailboxEnabled = true
+			store := state.NewInmemoryStore()
+			ps, err := NewPss(kademlia(ctx), params.WithMailboxStore(store))
+			if err != nil {
+				return nil, nil, err
+			}
+			return ps, func() { store.Close() }, nil
+		},
+	})
+	defer sim.Close()
+
+	ids := make([]enode.ID, 3)
+	for i, key := range keys {
+		key := key
+		id, err := sim.AddNode(func(conf *adapters.NodeConfig) {
+			conf.PrivateKey = key
+			conf.ID = enode.PubkeyToIDV4(&key.PublicKey)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[i] = id
+	}
+	senderID, mailboxID, recipientID := ids[0], ids[1], ids[2]
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	result := sim.Run(ctx, func(ctx context.Context, sim *simulation.Simulation) error {
+		if err := sim.ConnectNodesChain(ids); err != nil {
+			return err
+		}
+		if err := waitConns(ctx, kademlias[mailboxID], 2); err != nil {
+			return err
+		}
+		if err := sim.StopNode(recipientID); err != nil {
+			return err
+		}
+		if err := waitConns(ctx, kademlias[mailboxID], 1); err != nil {
+			return err
+		}
+
+		topic := BytesToTopic([]byte("mailbox"))
+		sender := sim.Service(pssProtocolName, senderID).(*Pss)
+		pubkeyid := common.ToHex(crypto.FromECDSAPub(&keys[2].PublicKey))
+		if err := sender.SetPeerPublicKey(&keys[2].PublicKey, topic, (*PssAddress)(&recipientAddr)); err != nil {
+			return err
+		}
+		if err := sender.SendAsym(pubkeyid, topic, []byte("while you were away")); err != nil {
+			return err
+		}
+		mailbox := sim.Service(pssProtocolName, mailboxID).(*Pss).mailbox
+		for {
+			mailbox.mu.Lock()
+			kept := mailbox.counts[crypto.PubkeyToAddress(keys[0].PublicKey)]
+			mailbox.mu.Unlock()
+			if kept == 1 {
+				break
+			}
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("message not kept by mailbox: %v", ctx.Err())
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+
+		if err := sim.StartNode(recipientID); err != nil {
+			return err
+		}
+		recipient := sim.Service(pssProtocolName, recipientID).(*Pss)
+		msgC := make(chan []byte, 1)
+		recipient.Register(&topic, func(msg []byte, p *p2p.Peer, asymmetric bool, keyid string) error {
+			if keyid != common.ToHex(crypto.FromECDSAPub(&keys[0].PublicKey)) {
+				return fmt.Errorf("sender key mismatch: have %s", keyid)
+			}
+			msgC <- msg
+			return nil
+		})
+		if err := sim.Net.Connect(mailboxID, recipientID); err != nil {
+			return err
+		}
+		select {
+		case msg := <-msgC:
+			if string(msg) != "while you were away" {
+				return fmt.Errorf("message mismatch: have %q, want %q", msg, "while you were away")
+			}
+		case <-ctx.Done():
+			return fmt.Errorf("message not delivered: %v", ctx.Err())
+		}
+		return nil
+	})
+	if result.Error != nil {
+		t.Fatal(result.Error)
+	}
+}
+
+// waits until the kademlia has the given number of connections
+func waitConns(ctx context.Context, kad *network.Kademlia, n int) error {
+	for {
+		var conns int
+		kad.EachConn(nil, 255, func(*network.Peer, int, bool) bool {
+			conns++
+			return true
+		})
+		if conns == n {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("connection count mismatch: have %d, want %d", conns, n)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
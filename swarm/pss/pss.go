@@ -36,6 +36,7 @@ import (
 	"github.com/ethereum/go-ethereum/swarm/log"
 	"github.com/ethereum/go-ethereum/swarm/network"
 	"github.com/ethereum/go-ethereum/swarm/pot"
+	"github.com/ethereum/go-ethereum/swarm/state"
 	"github.com/ethereum/go-ethereum/swarm/storage"
 	whisper "github.com/ethereum/go-ethereum/whisper/whisperv5"
 )
@@ -52,7 +53,7 @@ const (
 	defaultCleanInterval       = time.Second * 60 * 10
 	defaultOutboxCapacity      = 100000
 	pssProtocolName            = "pss"
-	pssVersion                 = 2
+	pssVersion                 = 3
 	hasherCount                = 8
 )
 
@@ -90,6 +91,10 @@ type PssParams struct {
 	privateKey          *ecdsa.PrivateKey
 	SymKeyCacheCapacity int
 	AllowRaw            bool // If true, enables sending and receiving messages without builtin pss encryption
+	MailboxEnabled      bool // If true, keeps messages for offline recipients in the node's neighbourhood
+	MailboxTTL          time.Duration
+	MailboxQuota        int // maximum number of messages kept per originating node
+	mailboxStore        state.Store
 }
 
 // Sane defaults for Pss
@@ -98,6 +103,8 @@ func NewPssParams() *PssParams {
 		MsgTTL:              DefaultMsgTTL,
 		CacheTTL:            defaultDigestCacheTTL,
 		SymKeyCacheCapacity: defaultSymKeyCacheCapacity,
+		MailboxTTL:          defaultMailboxTTL,
+		MailboxQuota:        defaultMailboxQuota,
 	}
 }
 
@@ -106,6 +113,12 @@ func (params *PssParams) WithPrivateKey(privatekey *ecdsa.PrivateKey) *PssParams
 	return params
 }
 
+// WithMailboxStore sets the store persisting the messages kept for offline recipients
+func (params *PssParams) WithMailboxStore(store state.Store) *PssParams {
+	params.mailboxStore = store
+	return params
+}
+
 // Toplevel pss object, takes care of message sending, receiving, decryption and encryption, message handler dispatchers and message forwarding.
 //
 // Implements node.Service
@@ -141,6 +154,9 @@ type Pss struct {
 	allowRaw   bool
 	hashPool   sync.Pool
 
+	// messages kept for offline recipients, nil if disabled
+	mailbox *mailbox
+
 	// process
 	quitC chan struct{}
 }
@@ -189,6 +205,17 @@ func NewPss(k *network.Kademlia, params *PssParams) (*Pss, error) {
 		},
 	}
 
+	if params.MailboxEnabled {
+		if params.mailboxStore == nil {
+			return nil, errors.New("missing mailbox store for pss")
+		}
+		mb, err := newMailbox(params.mailboxStore, params.MailboxTTL, params.MailboxQuota)
+		if err != nil {
+			return nil, err
+		}
+		ps.mailbox = mb
+	}
+
 	for i := 0; i < hasherCount; i++ {
 		hashfunc := storage.MakeHashFunc(storage.DefaultHash)()
 		ps.hashPool.Put(hashfunc)
@@ -213,6 +240,11 @@ func (p *Pss) Start(srv *p2p.Server) error {
 				p.cleanFwdCache()
 			case <-ticker.C:
 				p.cleanKeys()
+				if p.mailbox != nil {
+					if err := p.mailbox.clean(); err != nil {
+						log.Error("pss mailbox cleanup failed", "err", err)
+					}
+				}
 			case <-p.quitC:
 				return
 			}
@@ -249,6 +281,8 @@ var pssSpec = &protocols.Spec{
 	MaxMsgSize: defaultMaxMsgSize,
 	Messages: []interface{}{
 		PssMsg{},
+		mailboxRequestMsg{},
+		mailboxMsg{},
 	},
 }
 
@@ -268,7 +302,11 @@ func (p *Pss) Run(peer *p2p.Peer, rw p2p.MsgReadWriter) error {
 	p.fwdPoolMu.Lock()
 	p.fwdPool[peer.Info().ID] = pp
 	p.fwdPoolMu.Unlock()
-	return pp.Run(p.handlePssMsg)
+	// collect the messages kept for us while we were offline
+	go p.requestMailbox(pp)
+	return pp.Run(func(ctx context.Context, msg interface{}) error {
+		return p.handleMsg(ctx, pp, msg)
+	})
 }
 
 func (p *Pss) APIs() []rpc.API {
@@ -342,6 +380,21 @@ func (p *Pss) getHandlers(topic Topic) map[*Handler]bool {
 	return p.handlers[topic]
 }
 
+// Dispatches the messages received from the peer
+func (p *Pss) handleMsg(ctx context.Context, pp *protocols.Peer, msg interface{}) error {
+	switch msg := msg.(type) {
+	case *mailboxRequestMsg:
+		return p.handleMailboxRequest(ctx, pp, msg)
+	case *mailboxMsg:
+		return p.handleMailboxMsg(msg)
+	case *PssMsg:
+		if p.mailbox != nil {
+			p.keepForRecipient(pp.ID(), msg)
+		}
+	}
+	return p.handlePssMsg(ctx, msg)
+}
+
 // Filters incoming messages for processing or forwarding.
 // Check if address partially matches
 // If yes, it CAN be for us, and we process it
@@ -698,6 +751,9 @@ func (p *Pss) SendRaw(address PssAddress, topic Topic, msg []byte) error {
 	pssMsg.To = address
 	pssMsg.Expire = uint32(time.Now().Add(p.msgTTL).Unix())
 	pssMsg.Payload = payload
+	if err := signOrigin(pssMsg, p.privateKey); err != nil {
+		return err
+	}
 	p.addFwdCache(pssMsg)
 	return p.enqueue(pssMsg)
 }
@@ -800,6 +856,9 @@ func (p *Pss) send(to []byte, topic Topic, msg []byte, asymmetric bool, key []by
 	pssMsg.To = to
 	pssMsg.Expire = uint32(time.Now().Add(p.msgTTL).Unix())
 	pssMsg.Payload = envelope
+	if err := signOrigin(pssMsg, p.privateKey); err != nil {
+		return err
+	}
 	return p.enqueue(pssMsg)
 }
 
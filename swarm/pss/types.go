@@ -123,6 +123,7 @@ type PssMsg struct {
 	Control []byte
 	Expire  uint32
 	Payload *whisper.Envelope
+	Origin  []byte // signature of the originating node, set for full recipient addresses only
 }
 
 func newPssMsg(param *msgParams) *PssMsg {
@@ -27,6 +27,7 @@ import (
 	ch "github.com/ethereum/go-ethereum/swarm/chunk"
 	"github.com/ethereum/go-ethereum/swarm/log"
 	"github.com/ethereum/go-ethereum/swarm/spancontext"
+	"github.com/ethereum/go-ethereum/swarm/storage/erasure"
 	opentracing "github.com/opentracing/opentracing-go"
 	olog "github.com/opentracing/opentracing-go/log"
 )
@@ -53,6 +54,14 @@ data_{i} := size(subtree_{i}) || key_{j} || key_{j+1} .... || key_{j+n-1}
   branches^l length (except the last one).
   key = hash(int64(size) + key(slice0) + key(slice1) + ...)
 
+6 optionally, p Reed-Solomon parity chunks are computed over the children of
+  every branching node, and their keys are appended to the child keys. The
+  children then take only branches-p slots, and the highest byte of the size
+  records p:
+  key = hash(int64(size | p<<56) + key(slice0) + ... + key(parity0) + ...)
+  Any p missing children of a branching node can be reconstructed from the
+  remaining children and the parity chunks.
+
  The underlying hash function is configurable
 */
 
@@ -66,6 +75,7 @@ The hashing itself does use extra copies and allocation though, since it does ne
 
 var (
 	errAppendOppNotSuported = errors.New("Append operation not supported")
+	errInvalidParities      = errors.New("invalid number of parity chunks")
 )
 
 type ChunkerParams struct {
@@ -82,7 +92,8 @@ type SplitterParams struct {
 
 type TreeSplitterParams struct {
 	SplitterParams
-	size int64
+	size     int64
+	parities int // number of erasure coding parity chunks of intermediate chunks
 }
 
 type JoinerParams struct {
@@ -106,6 +117,7 @@ type TreeChunker struct {
 	depth       int
 	hashSize    int64        // self.hashFunc.New().Size()
 	chunkSize   int64        // hashSize* branches
+	parities    int          // erasure coding parity chunks of intermediate chunks
 	workerCount int64        // the number of worker routines used
 	workerLock  sync.RWMutex // lock for the worker count
 	jobC        chan *hashJob
@@ -162,6 +174,37 @@ func TreeSplit(ctx context.Context, data io.Reader, size int64, putter Putter) (
 	return NewTreeSplitter(tsp).Split(ctx)
 }
 
+// MaxParities returns the maximum number of parity chunks of an intermediate
+// chunk holding references of the given size. At least two children have to fit
+// next to the parity references.
+func MaxParities(refSize int64) int {
+	return int(ch.DefaultSize/refSize) - 2
+}
+
+/*
+	TreeSplitErasure splits data like TreeSplit, adding the given number of
+	Reed-Solomon parity chunks to every intermediate chunk of the tree. Any
+	parities children of an intermediate chunk can be lost without losing data.
+*/
+func TreeSplitErasure(ctx context.Context, data io.Reader, size int64, putter Putter, parities int) (k Address, wait func(context.Context) error, err error) {
+	if parities < 0 || parities > MaxParities(putter.RefSize()) {
+		return nil, nil, errInvalidParities
+	}
+	tsp := &TreeSplitterParams{
+		SplitterParams: SplitterParams{
+			ChunkerParams: ChunkerParams{
+				chunkSize: ch.DefaultSize,
+				hashSize:  putter.RefSize(),
+			},
+			reader: data,
+			putter: putter,
+		},
+		size:     size,
+		parities: parities,
+	}
+	return NewTreeSplitter(tsp).Split(ctx)
+}
+
 func NewTreeJoiner(params *JoinerParams) *TreeChunker {
 	tc := &TreeChunker{}
 	tc.hashSize = params.hashSize
@@ -186,7 +229,8 @@ func NewTreeSplitter(params *TreeSplitterParams) *TreeChunker {
 	tc.data = params.reader
 	tc.dataSize = params.size
 	tc.hashSize = params.hashSize
-	tc.branches = params.chunkSize / params.hashSize
+	tc.parities = params.parities
+	tc.branches = params.chunkSize/params.hashSize - int64(params.parities)
 	tc.addr = params.addr
 	tc.chunkSize = params.chunkSize
 	tc.putter = params.putter
@@ -267,7 +311,9 @@ func (tc *TreeChunker) Split(ctx context.Context) (k Address, wait func(context.
 	return key, tc.putter.Wait, nil
 }
 
-func (tc *TreeChunker) split(ctx context.Context, depth int, treeSize int64, addr Address, size int64, parentWg *sync.WaitGroup) {
+// split stores the tree of the next size bytes of data and returns the chunk
+// at its root, or nil if splitting failed
+func (tc *TreeChunker) split(ctx context.Context, depth int, treeSize int64, addr Address, size int64, parentWg *sync.WaitGroup) []byte {
 
 	//
 
@@ -286,24 +332,29 @@ func (tc *TreeChunker) split(ctx context.Context, depth int, treeSize int64, add
 			readBytes += int64(n)
 			if err != nil && !(err == io.EOF && readBytes == size) {
 				tc.errC <- err
-				return
+				return nil
 			}
 		}
 		select {
 		case tc.jobC <- &hashJob{addr, chunkData, size, parentWg}:
 		case <-tc.quitC:
+			return nil
 		}
-		return
+		return chunkData
 	}
 	// dept > 0
 	// intermediate chunk containing child nodes hashes
 	branchCnt := (size + treeSize - 1) / treeSize
 
-	var chunk = make([]byte, branchCnt*tc.hashSize+8)
+	var chunk = make([]byte, (branchCnt+int64(tc.parities))*tc.hashSize+8)
 	var pos, i int64
 
-	binary.LittleEndian.PutUint64(chunk[0:8], uint64(size))
+	binary.LittleEndian.PutUint64(chunk[0:8], uint64(size)|uint64(tc.parities)<<56)
 
+	var children [][]byte
+	if tc.parities > 0 {
+		children = make([][]byte, 0, branchCnt)
+	}
 	childrenWg := &sync.WaitGroup{}
 	var secSize int64
 	for i < branchCnt {
@@ -317,11 +368,26 @@ func (tc *TreeChunker) split(ctx context.Context, depth int, treeSize int64, add
 		subTreeAddress := chunk[8+i*tc.hashSize : 8+(i+1)*tc.hashSize]
 
 		childrenWg.Add(1)
-		tc.split(ctx, depth-1, treeSize/tc.branches, subTreeAddress, secSize, childrenWg)
+		child := tc.split(ctx, depth-1, treeSize/tc.branches, subTreeAddress, secSize, childrenWg)
+		if child == nil {
+			return nil
+		}
+		if children != nil {
+			children = append(children, child)
+		}
 
 		i++
 		pos += treeSize
 	}
+	if children != nil {
+		if err := tc.putParities(chunk, children, childrenWg); err != nil {
+			select {
+			case tc.errC <- err:
+			case <-tc.quitC:
+			}
+			return nil
+		}
+	}
 	// wait for all the children to complete calculating their hashes and copying them onto sections of the chunk
 	// parentWg.Add(1)
 	// go func() {
@@ -335,7 +401,55 @@ func (tc *TreeChunker) split(ctx context.Context, depth int, treeSize int64, add
 	select {
 	case tc.jobC <- &hashJob{addr, chunk, size, parentWg}:
 	case <-tc.quitC:
+		return nil
+	}
+	return chunk
+}
+
+// putParities computes the Reed-Solomon parity chunks over the payloads of the
+// children of an intermediate chunk and stores them. Their references are
+// written after the references of the children.
+func (tc *TreeChunker) putParities(chunk []byte, children [][]byte, wg *sync.WaitGroup) error {
+	enc, err := erasure.New(len(children), tc.parities)
+	if err != nil {
+		return err
+	}
+	// shorter payloads are padded with zeros to the longest one
+	var shardSize int
+	for _, child := range children {
+		if len(child)-8 > shardSize {
+			shardSize = len(child) - 8
+		}
+	}
+	shards := make([][]byte, len(children)+tc.parities)
+	for i, child := range children {
+		shards[i] = child[8:]
+		if len(shards[i]) < shardSize {
+			shards[i] = make([]byte, shardSize)
+			copy(shards[i], child[8:])
+		}
+	}
+	parityChunks := make([][]byte, tc.parities)
+	for i := range parityChunks {
+		parityChunks[i] = make([]byte, shardSize+8)
+		binary.LittleEndian.PutUint64(parityChunks[i][:8], uint64(shardSize))
+		shards[len(children)+i] = parityChunks[i][8:]
 	}
+	if err := enc.Encode(shards); err != nil {
+		return err
+	}
+
+	refs := chunk[8+int64(len(children))*tc.hashSize:]
+	for i, parityChunk := range parityChunks {
+		wg.Add(1)
+		select {
+		case tc.jobC <- &hashJob{refs[int64(i)*tc.hashSize : int64(i+1)*tc.hashSize], parityChunk, int64(shardSize), wg}:
+		case <-tc.quitC:
+			wg.Done()
+			return nil
+		}
+	}
+	return nil
 }
 
 func (tc *TreeChunker) runWorker(ctx context.Context) {
@@ -377,6 +491,7 @@ type LazyChunkReader struct {
 	chunkSize int64 // inherit from chunker
 	branches  int64 // inherit from chunker
 	hashSize  int64 // inherit from chunker
+	parities  int   // erasure coding parity chunks of intermediate chunks, read from the root chunk
 	depth     int
 	getter    Getter
 }
@@ -415,6 +530,9 @@ func (r *LazyChunkReader) Size(ctx context.Context, quitC chan bool) (n int64, e
 			return 0, err
 		}
 		r.chunkData = chunkData
+		// parity references take up branches of every intermediate chunk
+		r.parities = chunkData.Parities()
+		r.branches = r.chunkSize/r.hashSize - int64(r.parities)
 		s := r.chunkData.Size()
 		log.Debug("lazychunkreader.size", "key", r.addr, "size", s)
 		if s < 0 {
@@ -518,7 +636,7 @@ func (r *LazyChunkReader) join(b []byte, off int64, eoff int64, depth int, treeS
 	end := (eoff + treeSize - 1) / treeSize
 
 	// last non-leaf chunk can be shorter than default chunk size, let's not read it further then its end
-	currentBranches := int64(len(chunkData)-8) / r.hashSize
+	currentBranches := int64(len(chunkData)-8)/r.hashSize - int64(chunkData.Parities())
 	if end > currentBranches {
 		end = currentBranches
 	}
@@ -542,7 +660,11 @@ func (r *LazyChunkReader) join(b []byte, off int64, eoff int64, depth int, treeS
 		wg.Add(1)
 		go func(j int64) {
 			childAddress := chunkData[8+j*r.hashSize : 8+(j+1)*r.hashSize]
-			chunkData, err := r.getter.Get(r.ctx, Reference(childAddress))
+			childData, err := r.getter.Get(r.ctx, Reference(childAddress))
+			if err != nil && chunkData.Parities() > 0 {
+				log.Debug("lazychunkreader.join recovering", "key", fmt.Sprintf("%x", childAddress), "err", err)
+				childData, err = r.recoverChunk(chunkData, j, depth, treeSize)
+			}
 			if err != nil {
 				log.Debug("lazychunkreader.join", "key", fmt.Sprintf("%x", childAddress), "err", err)
 				select {
@@ -551,7 +673,7 @@ func (r *LazyChunkReader) join(b []byte, off int64, eoff int64, depth int, treeS
 				}
 				return
 			}
-			if l := len(chunkData); l < 9 {
+			if l := len(childData); l < 9 {
 				select {
 				case errC <- fmt.Errorf("chunk %v-%v incomplete; key: %s, data length %v", off, off+treeSize, fmt.Sprintf("%x", childAddress), l):
 				case <-quitC:
@@ -561,11 +683,120 @@ func (r *LazyChunkReader) join(b []byte, off int64, eoff int64, depth int, treeS
 			if soff < off {
 				soff = off
 			}
-			r.join(b[soff-off:seoff-off], soff-roff, seoff-roff, depth-1, treeSize/r.branches, chunkData, wg, errC, quitC)
+			r.join(b[soff-off:seoff-off], soff-roff, seoff-roff, depth-1, treeSize/r.branches, childData, wg, errC, quitC)
 		}(i)
 	} //for
 }
 
+// treeBranchCount returns the number of children of an intermediate chunk
+// spanning size bytes in a tree with the given branching factor
+func treeBranchCount(size, chunkSize, branches int64) int64 {
+	treeSize := chunkSize
+	for treeSize*branches < size {
+		treeSize *= branches
+	}
+	return (size + treeSize - 1) / treeSize
+}
+
+// recoverChunk reconstructs the j-th child of an intermediate chunk from the
+// other children and the parity chunks. depth and treeSize are the ones of the
+// intermediate chunk.
+func (r *LazyChunkReader) recoverChunk(chunkData ChunkData, j int64, depth int, treeSize int64) (ChunkData, error) {
+	metrics.GetOrRegisterCounter("lazychunkreader.recover", nil).Inc(1)
+
+	parities := chunkData.Parities()
+	refs := chunkData.Data()
+	total := int64(len(refs)) / r.hashSize
+	enc, err := erasure.New(int(total)-parities, parities)
+	if err != nil {
+		return nil, err
+	}
+	size := int64(chunkData.Size())
+	childSize := func(i int64) int64 {
+		if rest := size - i*treeSize; rest < treeSize {
+			return rest
+		}
+		return treeSize
+	}
+	// the first child is the largest, payloads were padded to its length
+	shardSize, _ := r.chunkLength(childSize(0), depth-1, treeSize/r.branches)
+
+	ctx, cancel := context.WithCancel(r.ctx)
+	defer cancel()
+	type shard struct {
+		index int64
+		data  ChunkData
+	}
+	shardC := make(chan shard, total)
+	for i := int64(0); i < total; i++ {
+		if i == j {
+			continue
+		}
+		go func(i int64) {
+			data, err := r.getter.Get(ctx, Reference(refs[i*r.hashSize:(i+1)*r.hashSize]))
+			if err != nil || len(data) < 8 {
+				data = nil
+			}
+			shardC <- shard{i, data}
+		}(i)
+	}
+	// stop fetching once enough shards arrived for the reconstruction
+	shards := make([][]byte, total)
+	found := 0
+	for received := int64(1); received < total && found < enc.DataShards(); received++ {
+		s := <-shardC
+		if s.data == nil {
+			continue
+		}
+		shards[s.index] = make([]byte, shardSize)
+		copy(shards[s.index], s.data[8:])
+		found++
+	}
+	if err := enc.Reconstruct(shards); err != nil {
+		metrics.GetOrRegisterCounter("lazychunkreader.recover.fail", nil).Inc(1)
+		return nil, err
+	}
+
+	span := uint64(childSize(j))
+	length, isData := r.chunkLength(int64(span), depth-1, treeSize/r.branches)
+	if !isData {
+		span |= uint64(parities) << 56
+	}
+	child := make(ChunkData, length+8)
+	binary.LittleEndian.PutUint64(child[:8], span)
+	copy(child[8:], shards[j])
+
+	// a corrupt shard reconstructs garbage, only accept the chunk it was stored as
+	verifier, ok := r.getter.(chunkVerifier)
+	if !ok {
+		return nil, errors.New("recovered chunk can't be verified")
+	}
+	if err := verifier.verify(Reference(refs[j*r.hashSize:(j+1)*r.hashSize]), child); err != nil {
+		metrics.GetOrRegisterCounter("lazychunkreader.recover.invalid", nil).Inc(1)
+		return nil, err
+	}
+	return child, nil
+}
+
+// chunkVerifier is implemented by getters able to check that chunk data matches
+// its reference, which is required to use chunks recovered from parities
+type chunkVerifier interface {
+	verify(ref Reference, chunkData ChunkData) error
+}
+
+// chunkLength returns the payload length of a chunk spanning size bytes at the
+// given depth of the tree, and whether it is a data chunk
+func (r *LazyChunkReader) chunkLength(size int64, depth int, treeSize int64) (int64, bool) {
+	for size < treeSize && depth > r.depth {
+		treeSize /= r.branches
+		depth--
+	}
+	if depth == r.depth {
+		return size, true
+	}
+	return ((size+treeSize-1)/treeSize + int64(r.parities)) * r.hashSize, false
+}
+
 // Read keeps a cursor so cannot be called simulateously, see ReadAt
 func (r *LazyChunkReader) Read(b []byte) (read int, err error) {
 	log.Debug("lazychunkreader.read", "key", r.addr)
@@ -22,9 +22,11 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
+	"math/rand"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/crypto/sha3"
+	ch "github.com/ethereum/go-ethereum/swarm/chunk"
 	"github.com/ethereum/go-ethereum/swarm/testutil"
 )
 
@@ -263,6 +265,145 @@ func TestRandomBrokenData(t *testing.T) {
 	}
 }
 
+// TestErasureRecovery stores erasure coded data in a MemStore, deletes as many
+// random chunks under every intermediate chunk as there are parity chunks and
+// checks that the data is still retrieved
+func TestErasureRecovery(t *testing.T) {
+	for _, encrypted := range []bool{false, true} {
+		for _, size := range []int{4097, 3*4096 + 100, 124 * 4096, 300*4096 + 17} {
+			testErasureRecovery(t, size, 4, encrypted)
+		}
+	}
+}
+
+func testErasureRecovery(t *testing.T, size, parities int, encrypted bool) {
+	ctx := context.Background()
+	input := testutil.RandomBytes(size, size)
+	store := NewMemStore(NewStoreParams(0, 100000, nil, nil), nil)
+	putGetter := NewHasherStore(store, MakeHashFunc(BMTHash), encrypted)
+
+	addr, wait, err := TreeSplitErasure(ctx, bytes.NewReader(input), int64(size), putGetter, parities)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// pick parities random children of every intermediate chunk for deletion
+	rng := rand.New(rand.NewSource(int64(size)))
+	refSize := int(putGetter.RefSize())
+	var deletions []Address
+	var pick func(ref Reference)
+	pick = func(ref Reference) {
+		chunkData, err := putGetter.Get(ctx, ref)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if chunkData.Size() <= ch.DefaultSize {
+			return
+		}
+		if have := chunkData.Parities(); have != parities {
+			t.Fatalf("parities mismatch: have %d, want %d", have, parities)
+		}
+		refs := chunkData.Data()
+		count := len(refs) / refSize
+		for i := 0; i < count; i++ {
+			pick(Reference(refs[i*refSize : (i+1)*refSize]))
+		}
+		for _, i := range rng.Perm(count)[:parities] {
+			deletions = append(deletions, Address(refs[i*refSize:i*refSize+int(putGetter.hashSize)]))
+		}
+	}
+	pick(Reference(addr))
+	if len(deletions) == 0 {
+		t.Fatalf("size %d: no intermediate chunks", size)
+	}
+	for _, addr := range deletions {
+		store.Delete(addr)
+	}
+
+	reader := TreeJoin(ctx, addr, putGetter, 0)
+	output := make([]byte, size)
+	if n, err := reader.ReadAt(output, 0); n != size || err != io.EOF {
+		t.Fatalf("size %d, encrypted %v: read error: read %d, err %v", size, encrypted, n, err)
+	}
+	if !bytes.Equal(output, input) {
+		t.Fatalf("size %d, encrypted %v: recovered data mismatch", size, encrypted)
+	}
+
+	// one more missing child of the root can't be recovered
+	root, err := putGetter.Get(ctx, Reference(addr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	refs := root.Data()
+	for i := 0; i <= parities; i++ {
+		store.Delete(Address(refs[i*refSize : i*refSize+int(putGetter.hashSize)]))
+	}
+	reader = TreeJoin(ctx, addr, putGetter, 0)
+	if _, err := reader.ReadAt(output, 0); err == nil || err == io.EOF {
+		t.Fatalf("size %d, encrypted %v: expected read error", size, encrypted)
+	}
+}
+
+// TestErasureRecoveryCorruptParity checks that a chunk reconstructed from a
+// corrupt parity chunk is rejected instead of being returned as content
+func TestErasureRecoveryCorruptParity(t *testing.T) {
+	for _, encrypted := range []bool{false, true} {
+		ctx := context.Background()
+		size, parities := 3*4096+100, 4
+		input := testutil.RandomBytes(size, size)
+		store := NewMemStore(NewStoreParams(0, 100000, nil, nil), nil)
+		putGetter := NewHasherStore(store, MakeHashFunc(BMTHash), encrypted)
+
+		addr, wait, err := TreeSplitErasure(ctx, bytes.NewReader(input), int64(size), putGetter, parities)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := wait(ctx); err != nil {
+			t.Fatal(err)
+		}
+		root, err := putGetter.Get(ctx, Reference(addr))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// leave exactly as many shards as needed, one of them corrupt
+		refSize, hashSize := int(putGetter.RefSize()), int(putGetter.hashSize)
+		refs := root.Data()
+		address := func(i int) Address {
+			return Address(refs[i*refSize : i*refSize+hashSize])
+		}
+		count := len(refs) / refSize
+		store.Delete(address(0))
+		for i := count - parities + 1; i < count; i++ {
+			store.Delete(address(i))
+		}
+		parity, err := store.Get(ctx, address(count-parities))
+		if err != nil {
+			t.Fatal(err)
+		}
+		corrupted := append([]byte{}, parity.Data()...)
+		corrupted[len(corrupted)-1] ^= 0xff
+		store.Put(ctx, NewChunk(parity.Address(), corrupted))
+
+		reader := TreeJoin(ctx, addr, putGetter, 0)
+		output := make([]byte, size)
+		if _, err := reader.ReadAt(output, 0); err == nil || err == io.EOF {
+			t.Fatalf("encrypted %v: expected read error", encrypted)
+		}
+	}
+}
+
+func TestErasureInvalidParities(t *testing.T) {
+	putGetter := newTestHasherStore(NewMapChunkStore(), BMTHash)
+	for _, parities := range []int{-1, 127} {
+		if _, _, err := TreeSplitErasure(context.Background(), bytes.NewReader(make([]byte, 10)), 10, putGetter, parities); err != errInvalidParities {
+			t.Fatalf("parities %d: error mismatch: have %v, want %v", parities, err, errInvalidParities)
+		}
+	}
+}
+
 func benchReadAll(reader LazySectionReader) {
 	size, _ := reader.Size(context.TODO(), nil)
 	output := make([]byte, 1000)
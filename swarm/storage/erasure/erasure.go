@@ -0,0 +1,174 @@
+// Copyright 2026 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package erasure implements systematic Reed-Solomon erasure coding over
+// GF(2^8), used to add parity chunks to Swarm chunk trees.
+//
+// An Encoder for k data shards and p parity shards computes the p parity
+// shards from the data shards. The data can then be restored from any k of
+// the k+p shards.
+package erasure
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MaxShards is the maximum number of data and parity shards of an Encoder
+const MaxShards = 256
+
+var (
+	// ErrShardCount is returned when the number of shards passed does not
+	// match the Encoder
+	ErrShardCount = errors.New("invalid number of shards")
+	// ErrShardSize is returned when shards differ in length
+	ErrShardSize = errors.New("shards differ in size")
+	// ErrTooFewShards is returned when there are not enough shards left to
+	// reconstruct the data
+	ErrTooFewShards = errors.New("too few shards to reconstruct data")
+
+	errSingular = errors.New("matrix is singular")
+)
+
+// Encoder computes and recovers from Reed-Solomon parity shards
+type Encoder struct {
+	dataShards   int
+	parityShards int
+	matrix       matrix // (dataShards+parityShards)*dataShards, the top rows are the identity
+}
+
+// New creates an Encoder for the given number of data and parity shards
+func New(dataShards, parityShards int) (*Encoder, error) {
+	if dataShards <= 0 || parityShards < 0 || dataShards+parityShards > MaxShards {
+		return nil, fmt.Errorf("%v: %d data and %d parity shards", ErrShardCount, dataShards, parityShards)
+	}
+	total := dataShards + parityShards
+	// multiplying a vandermonde matrix with the inverse of its top square
+	// gives a systematic code which keeps any dataShards rows independent
+	v := vandermonde(total, dataShards)
+	top, err := v[:dataShards].invert()
+	if err != nil {
+		return nil, err
+	}
+	return &Encoder{
+		dataShards:   dataShards,
+		parityShards: parityShards,
+		matrix:       v.multiply(top),
+	}, nil
+}
+
+// DataShards returns the number of data shards
+func (e *Encoder) DataShards() int {
+	return e.dataShards
+}
+
+// ParityShards returns the number of parity shards
+func (e *Encoder) ParityShards() int {
+	return e.parityShards
+}
+
+// Encode computes the parity shards. shards holds the data shards followed by
+// the parity shards, all of them allocated and of the same length.
+func (e *Encoder) Encode(shards [][]byte) error {
+	if len(shards) != e.dataShards+e.parityShards {
+		return ErrShardCount
+	}
+	size := len(shards[0])
+	for _, shard := range shards {
+		if len(shard) != size {
+			return ErrShardSize
+		}
+	}
+	e.codeShards(e.matrix[e.dataShards:], shards[:e.dataShards], shards[e.dataShards:])
+	return nil
+}
+
+// Reconstruct restores the missing data shards, which are marked nil, from
+// any dataShards of the shards present. Missing parity shards stay nil.
+func (e *Encoder) Reconstruct(shards [][]byte) error {
+	if len(shards) != e.dataShards+e.parityShards {
+		return ErrShardCount
+	}
+	size := -1
+	var (
+		present = make([]int, 0, e.dataShards)
+		missing []int
+	)
+	for i, shard := range shards {
+		if shard == nil {
+			if i < e.dataShards {
+				missing = append(missing, i)
+			}
+			continue
+		}
+		if size == -1 {
+			size = len(shard)
+		} else if len(shard) != size {
+			return ErrShardSize
+		}
+		if len(present) < e.dataShards {
+			present = append(present, i)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	if len(present) < e.dataShards {
+		return ErrTooFewShards
+	}
+
+	// the rows of the present shards map the data to them, so the inverse
+	// maps the present shards back to the data
+	sub := make(matrix, e.dataShards)
+	inputs := make([][]byte, e.dataShards)
+	for i, index := range present {
+		sub[i] = e.matrix[index]
+		inputs[i] = shards[index]
+	}
+	inv, err := sub.invert()
+	if err != nil {
+		return err
+	}
+	rows := make(matrix, len(missing))
+	outputs := make([][]byte, len(missing))
+	for i, index := range missing {
+		rows[i] = inv[index]
+		outputs[i] = make([]byte, size)
+		shards[index] = outputs[i]
+	}
+	e.codeShards(rows, inputs, outputs)
+	return nil
+}
+
+// codeShards sets each output to the linear combination of the inputs given
+// by the corresponding row
+func (e *Encoder) codeShards(rows matrix, inputs, outputs [][]byte) {
+	for i, out := range outputs {
+		for j := range out {
+			out[j] = 0
+		}
+		for c, in := range inputs {
+			coef := rows[i][c]
+			if coef == 0 {
+				continue
+			}
+			mul := &mulTable[coef]
+			for j, b := range in {
+				out[j] ^= mul[b]
+			}
+		}
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2026 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package erasure
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestGaloisField(t *testing.T) {
+	for a := 1; a < 256; a++ {
+		inv := galDiv(1, byte(a))
+		if have := galMul(byte(a), inv); have != 1 {
+			t.Fatalf("inverse of %d mismatch: have %d*%d = %d, want 1", a, a, inv, have)
+		}
+		for b := 1; b < 256; b++ {
+			if have := galDiv(galMul(byte(a), byte(b)), byte(b)); have != byte(a) {
+				t.Fatalf("(%d*%d)/%d mismatch: have %d, want %d", a, b, b, have, a)
+			}
+		}
+	}
+}
+
+func TestEncodeReconstruct(t *testing.T) {
+	for _, test := range []struct {
+		data, parity, size int
+	}{
+		{1, 1, 10},
+		{4, 2, 100},
+		{10, 4, 1},
+		{120, 8, 4096},
+		{128, 128, 64},
+	} {
+		enc, err := New(test.data, test.parity)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rng := rand.New(rand.NewSource(int64(test.data)))
+		shards := make([][]byte, test.data+test.parity)
+		for i := range shards {
+			shards[i] = make([]byte, test.size)
+			if i < test.data {
+				rng.Read(shards[i])
+			}
+		}
+		if err := enc.Encode(shards); err != nil {
+			t.Fatal(err)
+		}
+		want := make([][]byte, test.data)
+		for i := range want {
+			want[i] = append([]byte{}, shards[i]...)
+		}
+
+		// drop as many random shards as there are parity shards
+		for _, i := range rng.Perm(len(shards))[:test.parity] {
+			shards[i] = nil
+		}
+		if err := enc.Reconstruct(shards); err != nil {
+			t.Fatal(err)
+		}
+		for i := range want {
+			if !bytes.Equal(shards[i], want[i]) {
+				t.Fatalf("%d/%d: shard %d mismatch after reconstruction", test.data, test.parity, i)
+			}
+		}
+
+		// one more missing shard is too many
+		for _, i := range rng.Perm(len(shards))[:test.parity+1] {
+			shards[i] = nil
+		}
+		if err := enc.Reconstruct(shards); err != ErrTooFewShards {
+			t.Fatalf("%d/%d: error mismatch: have %v, want %v", test.data, test.parity, err, ErrTooFewShards)
+		}
+	}
+}
+
+func TestInvalidShards(t *testing.T) {
+	if _, err := New(0, 1); err == nil {
+		t.Fatal("expected error for no data shards")
+	}
+	if _, err := New(200, 57); err == nil {
+		t.Fatal("expected error for too many shards")
+	}
+	enc, err := New(2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Encode(make([][]byte, 2)); err != ErrShardCount {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrShardCount)
+	}
+	if err := enc.Encode([][]byte{make([]byte, 2), make([]byte, 3), make([]byte, 2)}); err != ErrShardSize {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrShardSize)
+	}
+}
+
+func BenchmarkEncode(b *testing.B) {
+	enc, err := New(120, 8)
+	if err != nil {
+		b.Fatal(err)
+	}
+	shards := make([][]byte, 128)
+	for i := range shards {
+		shards[i] = make([]byte, 4096)
+	}
+	b.SetBytes(120 * 4096)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		enc.Encode(shards)
+	}
+}
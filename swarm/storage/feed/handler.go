@@ -38,11 +38,18 @@ type Handler struct {
 	cacheLock       sync.RWMutex
 	storeTimeout    time.Duration
 	queryMaxPeriods uint32
+	reputation      *reputationFilter
 }
 
 // HandlerParams pass parameters to the Handler constructor NewHandler
 // Signer and TimestampProvider are mandatory parameters
 type HandlerParams struct {
+	// Reputation, if set, restricts publishing feed updates to users with
+	// non-zero mining reputation on the backend chain
+	Reputation ReputationBackend
+	// ReputationTopics lists the topics restricted by Reputation. If empty,
+	// all topics are restricted.
+	ReputationTopics []Topic
 }
 
 // hashPool contains a pool of ready hashers
@@ -62,6 +69,9 @@ func NewHandler(params *HandlerParams) *Handler {
 	fh := &Handler{
 		cache: make(map[uint64]*cacheEntry),
 	}
+	if params.Reputation != nil {
+		fh.reputation = newReputationFilter(params.Reputation, params.ReputationTopics)
+	}
 
 	for i := 0; i < hasherCount; i++ {
 		hashfunc := storage.MakeHashFunc(feedsHashAlgorithm)()
@@ -107,6 +117,12 @@ func (h *Handler) Validate(chunkAddr storage.Address, data []byte) bool {
 		return false
 	}
 
+	// In reputation mode, only reputable users may publish to guarded topics
+	if h.reputation != nil && !h.reputation.allowed(&r.Feed) {
+		log.Debug("Feed update from user without reputation", "user", r.Feed.User, "topic", r.Feed.Topic.Hex())
+		return false
+	}
+
 	return true
 }
 
@@ -252,6 +268,10 @@ func (h *Handler) Update(ctx context.Context, r *Request) (updateAddr storage.Ad
 		return nil, NewError(ErrInit, "Call Handler.SetStore() before updating")
 	}
 
+	if h.reputation != nil && !h.reputation.allowed(&r.Feed) {
+		return nil, NewErrorf(ErrUnauthorized, "user %s has no reputation to publish to topic %s", r.Feed.User.Hex(), r.Feed.Topic.Hex())
+	}
+
 	feedUpdate := h.get(&r.Feed)
 	if feedUpdate != nil && feedUpdate.Epoch.Equals(r.Epoch) { // This is the only cheap check we can do for sure
 		return nil, NewError(ErrInvalidValue, "A former update in this epoch is already known to exist")
@@ -0,0 +1,127 @@
+// Copyright 2026 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package feed
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/swarm/log"
+)
+
+const (
+	reputationCacheTTL = time.Minute      // how long a reputation lookup result is trusted
+	reputationFailTTL  = 10 * time.Second // how long a failed reputation lookup denies publishing
+	reputationTimeout  = 5 * time.Second  // maximum time spent on a reputation lookup
+)
+
+// ReputationBackend retrieves the mining reputation of an account. It is
+// implemented by ethclient.Client, so both remote nodes and a local eth node
+// attached over IPC or in-process RPC can serve reputation lookups.
+type ReputationBackend interface {
+	ReputationAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+}
+
+// reputationFilter only lets users with non-zero reputation on the backend
+// chain publish updates to the guarded topics.
+type reputationFilter struct {
+	backend ReputationBackend
+	topics  map[Topic]bool // guarded topics, all topics if empty
+	ttl     time.Duration
+	failTTL time.Duration
+
+	cache   map[common.Address]reputationCacheEntry
+	pending map[common.Address]*reputationLookup // lookups in flight, shared by concurrent callers
+	lock    sync.Mutex
+}
+
+type reputationCacheEntry struct {
+	reputable bool
+	expires   time.Time
+}
+
+// reputationLookup is a backend lookup in flight, done is closed once
+// reputable is set.
+type reputationLookup struct {
+	done      chan struct{}
+	reputable bool
+}
+
+func newReputationFilter(backend ReputationBackend, topics []Topic) *reputationFilter {
+	f := &reputationFilter{
+		backend: backend,
+		topics:  make(map[Topic]bool),
+		ttl:     reputationCacheTTL,
+		failTTL: reputationFailTTL,
+		cache:   make(map[common.Address]reputationCacheEntry),
+		pending: make(map[common.Address]*reputationLookup),
+	}
+	for _, topic := range topics {
+		f.topics[topic] = true
+	}
+	return f
+}
+
+// allowed reports whether the owner of the feed may publish updates to it.
+// Lookup failures deny publishing until failTTL has passed. The backend is
+// queried without holding the lock, concurrent checks of the same user wait
+// for a single lookup.
+func (f *reputationFilter) allowed(feed *Feed) bool {
+	if len(f.topics) > 0 && !f.topics[feed.Topic] {
+		return true
+	}
+
+	f.lock.Lock()
+	if entry, ok := f.cache[feed.User]; ok && time.Now().Before(entry.expires) {
+		f.lock.Unlock()
+		return entry.reputable
+	}
+	if lookup, ok := f.pending[feed.User]; ok {
+		f.lock.Unlock()
+		<-lookup.done
+		return lookup.reputable
+	}
+	lookup := &reputationLookup{done: make(chan struct{})}
+	f.pending[feed.User] = lookup
+	f.lock.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), reputationTimeout)
+	reputation, err := f.backend.ReputationAt(ctx, feed.User, nil)
+	cancel()
+	if err != nil {
+		log.Warn("Feed reputation lookup failed", "user", feed.User, "err", err)
+	}
+	lookup.reputable = err == nil && reputation > 0
+
+	f.lock.Lock()
+	ttl := f.ttl
+	if err != nil {
+		ttl = f.failTTL
+	}
+	f.cache[feed.User] = reputationCacheEntry{
+		reputable: lookup.reputable,
+		expires:   time.Now().Add(ttl),
+	}
+	delete(f.pending, feed.User)
+	f.lock.Unlock()
+	close(lookup.done)
+
+	return lookup.reputable
+}
@@ -0,0 +1,289 @@
+// Copyright 2026 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package feed
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// MockChain serves reputation lookups like the eth namespace of a node.
+type MockChain struct {
+	reputation map[common.Address]uint64
+	lookups    int
+	fail       bool
+	lock       sync.Mutex
+}
+
+func (c *MockChain) GetReputation(ctx context.Context, address common.Address, blockNr rpc.BlockNumber) (uint64, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.lookups++
+	if c.fail {
+		return 0, errors.New("chain unavailable")
+	}
+	return c.reputation[address], nil
+}
+
+func (c *MockChain) setReputation(address common.Address, reputation uint64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.reputation[address] = reputation
+}
+
+func (c *MockChain) setFail(fail bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.fail = fail
+}
+
+func (c *MockChain) lookupCount() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.lookups
+}
+
+// newMockChainBackend returns a reputation backend talking to the mock chain
+// through ethclient over an in-process RPC connection.
+func newMockChainBackend(t *testing.T, chain *MockChain) (ReputationBackend, func()) {
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", chain); err != nil {
+		t.Fatal(err)
+	}
+	client := ethclient.NewClient(rpc.DialInProc(server))
+	return client, func() {
+		client.Close()
+		server.Stop()
+	}
+}
+
+func setupReputationTest(t *testing.T, params *HandlerParams) (*TestHandler, func()) {
+	TimestampProvider = &fakeTimeProvider{
+		currentTime: startTime.Time,
+	}
+	datadir, err := ioutil.TempDir("", "fh-reputation")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fh, err := NewTestHandler(datadir, params)
+	if err != nil {
+		os.RemoveAll(datadir)
+		t.Fatal(err)
+	}
+	return fh, func() {
+		fh.Close()
+		os.RemoveAll(datadir)
+	}
+}
+
+// signedUpdate creates the first update of the signer's feed on the topic.
+func signedUpdate(t *testing.T, topic Topic, signer Signer) *Request {
+	r := NewFirstRequest(topic)
+	r.SetData([]byte("pool announcement"))
+	if err := r.Sign(signer); err != nil {
+		t.Fatalf("sign fail: %v", err)
+	}
+	return r
+}
+
+func TestReputationValidator(t *testing.T) {
+	alice, bob := newAliceSigner(), newBobSigner()
+	chain := &MockChain{
+		reputation: map[common.Address]uint64{alice.Address(): 3},
+	}
+	backend, closeBackend := newMockChainBackend(t, chain)
+	defer closeBackend()
+
+	fh, teardown := setupReputationTest(t, &HandlerParams{Reputation: backend})
+	defer teardown()
+
+	topic, _ := NewTopic(subtopicName, nil)
+	for _, test := range []struct {
+		signer Signer
+		valid  bool
+	}{
+		{alice, true},
+		{bob, false},
+	} {
+		r := signedUpdate(t, topic, test.signer)
+		chunk, err := r.toChunk()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 2; i++ {
+			if valid := fh.Validate(chunk.Address(), chunk.Data()); valid != test.valid {
+				t.Fatalf("validation mismatch for %x: have %v, want %v", test.signer.Address(), valid, test.valid)
+			}
+		}
+		_, err = fh.Update(context.TODO(), r)
+		if test.valid && err != nil {
+			t.Fatalf("update by %x failed: %v", test.signer.Address(), err)
+		}
+		if !test.valid {
+			if ferr, ok := err.(*Error); !ok || ferr.Code() != ErrUnauthorized {
+				t.Fatalf("update error mismatch for %x: have %v, want unauthorized", test.signer.Address(), err)
+			}
+		}
+	}
+	// lookups are cached per user
+	if lookups := chain.lookupCount(); lookups != 2 {
+		t.Fatalf("lookup count mismatch: have %d, want %d", lookups, 2)
+	}
+
+	// users gaining reputation may publish once the cached result expires
+	fh.reputation.ttl = 0
+	fh.reputation.cache = make(map[common.Address]reputationCacheEntry)
+	chunk, err := signedUpdate(t, topic, bob).toChunk()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fh.Validate(chunk.Address(), chunk.Data()) {
+		t.Fatal("expected update by user without reputation to be invalid")
+	}
+	chain.setReputation(bob.Address(), 1)
+	if !fh.Validate(chunk.Address(), chunk.Data()) {
+		t.Fatal("expected update by user with reputation to be valid")
+	}
+
+	// updates are rejected while reputation can't be checked, and failed
+	// lookups are cached too
+	fh.reputation.failTTL = time.Minute
+	chain.setFail(true)
+	lookups := chain.lookupCount()
+	for i := 0; i < 2; i++ {
+		if fh.Validate(chunk.Address(), chunk.Data()) {
+			t.Fatal("expected update to be invalid without reputation backend")
+		}
+	}
+	if have := chain.lookupCount(); have != lookups+1 {
+		t.Fatalf("lookup count mismatch: have %d, want %d", have, lookups+1)
+	}
+}
+
+// blockingBackend is a reputation backend whose lookups of one account block
+// until released.
+type blockingBackend struct {
+	blocked common.Address
+	started chan struct{}
+	release chan struct{}
+	lookups int32
+}
+
+func (b *blockingBackend) ReputationAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	atomic.AddInt32(&b.lookups, 1)
+	if account == b.blocked {
+		b.started <- struct{}{}
+		<-b.release
+	}
+	return 1, nil
+}
+
+// Tests that a slow lookup neither blocks checks of other users nor is
+// repeated by concurrent checks of the same user.
+func TestReputationFilterConcurrentLookups(t *testing.T) {
+	alice, bob := newAliceSigner(), newBobSigner()
+	backend := &blockingBackend{
+		blocked: alice.Address(),
+		started: make(chan struct{}, 1),
+		release: make(chan struct{}),
+	}
+	filter := newReputationFilter(backend, nil)
+	topic, _ := NewTopic(subtopicName, nil)
+
+	const checks = 5
+	results := make(chan bool, checks)
+	for i := 0; i < checks; i++ {
+		go func() {
+			results <- filter.allowed(&Feed{Topic: topic, User: alice.Address()})
+		}()
+	}
+	<-backend.started
+
+	// the lookup of alice is in flight, bob is served meanwhile
+	done := make(chan bool)
+	go func() {
+		done <- filter.allowed(&Feed{Topic: topic, User: bob.Address()})
+	}()
+	select {
+	case allowed := <-done:
+		if !allowed {
+			t.Fatal("expected user with reputation to be allowed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("check blocked by a lookup of another user")
+	}
+
+	close(backend.release)
+	for i := 0; i < checks; i++ {
+		if !<-results {
+			t.Fatal("expected user with reputation to be allowed")
+		}
+	}
+	if lookups := atomic.LoadInt32(&backend.lookups); lookups != 2 {
+		t.Fatalf("lookup count mismatch: have %d, want %d", lookups, 2)
+	}
+}
+
+func TestReputationValidatorTopics(t *testing.T) {
+	bob := newBobSigner()
+	chain := &MockChain{
+		reputation: make(map[common.Address]uint64),
+	}
+	backend, closeBackend := newMockChainBackend(t, chain)
+	defer closeBackend()
+
+	guarded, _ := NewTopic("pool.announcements", nil)
+	open, _ := NewTopic(subtopicName, nil)
+	fh, teardown := setupReputationTest(t, &HandlerParams{
+		Reputation:       backend,
+		ReputationTopics: []Topic{guarded},
+	})
+	defer teardown()
+
+	chunk, err := signedUpdate(t, open, bob).toChunk()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fh.Validate(chunk.Address(), chunk.Data()) {
+		t.Fatal("expected update to unguarded topic to be valid")
+	}
+	chunk, err = signedUpdate(t, guarded, bob).toChunk()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fh.Validate(chunk.Address(), chunk.Data()) {
+		t.Fatal("expected update to guarded topic to be invalid")
+	}
+	if lookups := chain.lookupCount(); lookups != 1 {
+		t.Fatalf("lookup count mismatch: have %d, want %d", lookups, 1)
+	}
+}
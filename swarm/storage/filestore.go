@@ -19,6 +19,8 @@ package storage
 import (
 	"context"
 	"io"
+
+	ch "github.com/ethereum/go-ethereum/swarm/chunk"
 )
 
 /*
@@ -42,10 +44,14 @@ const (
 type FileStore struct {
 	ChunkStore
 	hashFunc SwarmHasher
+	parities int
 }
 
 type FileStoreParams struct {
 	Hash string
+	// Parities is the number of Reed-Solomon parity chunks added to every
+	// intermediate chunk of stored content, 0 disables erasure coding
+	Parities int
 }
 
 func NewFileStoreParams() *FileStoreParams {
@@ -71,6 +77,7 @@ func NewFileStore(store ChunkStore, params *FileStoreParams) *FileStore {
 	return &FileStore{
 		ChunkStore: store,
 		hashFunc:   hashFunc,
+		parities:   params.Parities,
 	}
 }
 
@@ -88,11 +95,46 @@ func (f *FileStore) Retrieve(ctx context.Context, addr Address) (reader *LazyChu
 
 // Public API. Main entry point for document storage directly. Used by the
 // FS-aware API and httpaccess
+// Content of known size is erasure coded if the FileStore has parities set.
 func (f *FileStore) Store(ctx context.Context, data io.Reader, size int64, toEncrypt bool) (addr Address, wait func(context.Context) error, err error) {
 	putter := NewHasherStore(f.ChunkStore, f.hashFunc, toEncrypt)
+	if f.parities > 0 && size >= 0 {
+		return TreeSplitErasure(ctx, data, size, putter, f.parities)
+	}
 	return PyramidSplit(ctx, data, putter, putter)
 }
 
+// WalkChunks calls walkFn with the address of every chunk in the tree rooted
+// at addr, intermediate chunks included. Chunks are fetched through the
+// underlying ChunkStore, so chunks missing locally are retrieved from the network.
+func (f *FileStore) WalkChunks(ctx context.Context, addr Address, walkFn func(Address) error) error {
+	isEncrypted := len(addr) > f.hashFunc().Size()
+	getter := NewHasherStore(f.ChunkStore, f.hashFunc, isEncrypted)
+	return walkChunks(ctx, getter, Reference(addr), walkFn)
+}
+
+func walkChunks(ctx context.Context, getter *hasherStore, ref Reference, walkFn func(Address) error) error {
+	if err := walkFn(Address(ref[:getter.hashSize])); err != nil {
+		return err
+	}
+	chunkData, err := getter.Get(ctx, ref)
+	if err != nil {
+		return err
+	}
+	// data chunks span at most one chunk, anything larger holds child references
+	if chunkData.Size() <= ch.DefaultSize {
+		return nil
+	}
+	refs := chunkData.Data()
+	refSize := int(getter.RefSize())
+	for i := 0; i+refSize <= len(refs); i += refSize {
+		if err := walkChunks(ctx, getter, Reference(refs[i:i+refSize]), walkFn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (f *FileStore) HashSize() int {
 	return f.hashFunc().Size()
 }
@@ -17,7 +17,9 @@
 package storage
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"sync/atomic"
 
@@ -26,6 +28,11 @@ import (
 	"github.com/ethereum/go-ethereum/swarm/storage/encryption"
 )
 
+var (
+	errChunkMismatch     = errors.New("chunk data does not match its reference")
+	errChunkUnverifiable = errors.New("encrypted chunk data too long to verify")
+)
+
 type hasherStore struct {
 	store     ChunkStore
 	toEncrypt bool
@@ -149,6 +156,34 @@ func (h *hasherStore) Wait(ctx context.Context) error {
 	}
 }
 
+// verify checks that the chunk data is the one stored under the given reference.
+// Encrypted chunk data is encrypted again with the key of the reference, which
+// reproduces the stored chunk as its padding is encrypted zeros.
+func (h *hasherStore) verify(ref Reference, chunkData ChunkData) error {
+	addr, encryptionKey, err := parseReference(ref, h.hashSize)
+	if err != nil {
+		return err
+	}
+	if len(chunkData) < 8 {
+		return fmt.Errorf("Invalid ChunkData, min length 8 got %v", len(chunkData))
+	}
+	c := chunkData
+	if encryptionKey != nil {
+		if len(chunkData)-8 > ch.DefaultSize {
+			return errChunkUnverifiable
+		}
+		encryptedSpan, encryptedData, err := h.encryptWithKey(chunkData, encryptionKey)
+		if err != nil {
+			return err
+		}
+		c = append(ChunkData(encryptedSpan), encryptedData...)
+	}
+	if !bytes.Equal(h.createHash(c), addr) {
+		return errChunkMismatch
+	}
+	return nil
+}
+
 func (h *hasherStore) createHash(chunkData ChunkData) Address {
 	hasher := h.hashFunc()
 	hasher.ResetWithLength(chunkData[:8]) // 8 bytes of length
@@ -189,10 +224,10 @@ func (h *hasherStore) decryptChunkData(chunkData ChunkData, encryptionKey encryp
 
 	// removing extra bytes which were just added for padding
 	length := ChunkData(decryptedSpan).Size()
-	for length > ch.DefaultSize {
-		length = length + (ch.DefaultSize - 1)
-		length = length / ch.DefaultSize
-		length *= uint64(h.refSize)
+	if length > ch.DefaultSize {
+		parities := int64(ChunkData(decryptedSpan).Parities())
+		branches := ch.DefaultSize/h.refSize - parities
+		length = uint64((treeBranchCount(int64(length), ch.DefaultSize, branches) + parities) * h.refSize)
 	}
 
 	c := make(ChunkData, length+8)
@@ -208,15 +243,31 @@ func (h *hasherStore) RefSize() int64 {
 
 func (h *hasherStore) encrypt(chunkData ChunkData) (encryption.Key, []byte, []byte, error) {
 	key := encryption.GenerateRandomKey(encryption.KeyLength)
-	encryptedSpan, err := h.newSpanEncryption(key).Encrypt(chunkData[:8])
+	encryptedSpan, encryptedData, err := h.encryptWithKey(chunkData, key)
 	if err != nil {
 		return nil, nil, nil, err
 	}
-	encryptedData, err := h.newDataEncryption(key).Encrypt(chunkData[8:])
+	return key, encryptedSpan, encryptedData, nil
+}
+
+// encryptWithKey encrypts the span and the data of a chunk with the given key.
+// The data is padded with zeros before the encryption instead of letting it be
+// padded with random bytes, so that encrypting it again yields the same chunk.
+func (h *hasherStore) encryptWithKey(chunkData ChunkData, key encryption.Key) ([]byte, []byte, error) {
+	encryptedSpan, err := h.newSpanEncryption(key).Encrypt(chunkData[:8])
 	if err != nil {
-		return nil, nil, nil, err
+		return nil, nil, err
 	}
-	return key, encryptedSpan, encryptedData, nil
+	data := chunkData[8:]
+	if len(data) < ch.DefaultSize {
+		data = make([]byte, ch.DefaultSize)
+		copy(data, chunkData[8:])
+	}
+	encryptedData, err := h.newDataEncryption(key).Encrypt(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return encryptedSpan, encryptedData, nil
 }
 
 func (h *hasherStore) decrypt(chunkData ChunkData, key encryption.Key) ([]byte, []byte, error) {
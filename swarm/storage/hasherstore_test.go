@@ -122,3 +122,34 @@ func TestHasherStore(t *testing.T) {
 		}
 	}
 }
+
+// TestHasherStoreVerify checks that chunk data is verified against the reference
+// it was stored under, encrypted chunk data included
+func TestHasherStoreVerify(t *testing.T) {
+	for _, toEncrypt := range []bool{false, true} {
+		for _, chunkLength := range []int{10, 1000, 4096} {
+			hasherStore := NewHasherStore(NewMapChunkStore(), MakeHashFunc(DefaultHash), toEncrypt)
+
+			chunkData := GenerateRandomChunk(int64(chunkLength)).Data()
+			ctx, cancel := context.WithTimeout(context.Background(), getTimeout)
+			defer cancel()
+			ref, err := hasherStore.Put(ctx, chunkData)
+			if err != nil {
+				t.Fatalf("Expected no error got \"%v\"", err)
+			}
+			hasherStore.Close()
+			if err := hasherStore.Wait(ctx); err != nil {
+				t.Fatalf("Expected no error got \"%v\"", err)
+			}
+
+			if err := hasherStore.verify(ref, chunkData); err != nil {
+				t.Fatalf("length %d, encrypted %v: stored chunk not verified: %v", chunkLength, toEncrypt, err)
+			}
+			corrupted := append(ChunkData{}, chunkData...)
+			corrupted[len(corrupted)-1] ^= 0xff
+			if err := hasherStore.verify(ref, corrupted); err != errChunkMismatch {
+				t.Fatalf("length %d, encrypted %v: error mismatch: have %v, want %v", chunkLength, toEncrypt, err, errChunkMismatch)
+			}
+		}
+	}
+}
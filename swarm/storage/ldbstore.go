@@ -63,11 +63,13 @@ var (
 	keyData        = byte(6)
 	keyDistanceCnt = byte(7)
 	keySchema      = []byte{8}
-	keyGCIdx       = byte(9) // access to chunk data index, used by garbage collection in ascending order from first entry
+	keyGCIdx       = byte(9)  // access to chunk data index, used by garbage collection in ascending order from first entry
+	keyPinCnt      = byte(10) // number of pins held on a chunk, pinned chunks are exempt from garbage collection
 )
 
 var (
-	ErrDBClosed = errors.New("LDBStore closed")
+	ErrDBClosed  = errors.New("LDBStore closed")
+	ErrNotPinned = errors.New("chunk is not pinned")
 )
 
 type LDBStoreParams struct {
@@ -340,24 +342,35 @@ func (s *LDBStore) collectGarbage() error {
 	log.Debug("collectGarbage", "target", s.gc.target, "entryCnt", entryCnt)
 
 	var totalDeleted int
-	for s.gc.count < s.gc.target {
+	// pinned chunks are skipped, so every batch resumes after the last visited
+	// entry. Pinned entries count towards the batch size to bound the time the
+	// lock is held, and the round ends early if the index runs out of entries or
+	// a whole batch holds nothing but pinned chunks.
+	from := []byte{keyGCIdx}
+	for done := false; !done && s.gc.count < s.gc.target; {
 		it := s.db.NewIterator()
-		ok := it.Seek([]byte{keyGCIdx})
-		var singleIterationCount int
+		ok := it.Seek(from)
+		var singleIterationCount, visited int
 
 		// every batch needs a lock so we avoid entries changing accessidx in the meantime
 		s.lock.Lock()
-		for ; ok && (singleIterationCount < s.gc.maxBatch); ok = it.Next() {
+		for ; ok && (visited < s.gc.maxBatch); ok = it.Next() {
 
 			// quit if no more access index keys
 			itkey := it.Key()
 			if (itkey == nil) || (itkey[0] != keyGCIdx) {
+				done = true
 				break
 			}
+			from = append(append(from[:0], itkey...), 0)
+			visited++
 
 			// get chunk data entry from access index
 			val := it.Value()
 			index, po, hash := parseGCIdxEntry(itkey[1:], val)
+			if s.pinCount(hash) > 0 {
+				continue
+			}
 			keyIdx := make([]byte, 33)
 			keyIdx[0] = keyIndex
 			copy(keyIdx[1:], hash)
@@ -374,6 +387,13 @@ func (s *LDBStore) collectGarbage() error {
 			}
 		}
 
+		if !ok {
+			done = true
+		}
+		if visited > 0 && singleIterationCount == 0 {
+			log.Warn("garbage collect found only pinned chunks", "visited", visited)
+			done = true
+		}
 		s.writeBatch(s.gc.batch, wEntryCnt)
 		s.lock.Unlock()
 		it.Release()
@@ -771,6 +791,56 @@ func (s *LDBStore) delete(batch *leveldb.Batch, idx *dpaDBIndex, idxKey []byte,
 	batch.Put(cntKey, U64ToBytes(s.bucketCnt[po]))
 }
 
+func getPinKey(addr Address) []byte {
+	key := make([]byte, len(addr)+1)
+	key[0] = keyPinCnt
+	copy(key[1:], addr)
+	return key
+}
+
+// pinCount returns the number of pins held on the chunk, the caller must hold
+// the store lock.
+func (s *LDBStore) pinCount(addr Address) uint64 {
+	data, err := s.db.Get(getPinKey(addr))
+	if err != nil {
+		return 0
+	}
+	return BytesToU64(data)
+}
+
+// Pin increments the pin counter of the chunk. A chunk with a non-zero pin
+// counter is never removed by garbage collection.
+func (s *LDBStore) Pin(addr Address) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.db.Put(getPinKey(addr), U64ToBytes(s.pinCount(addr)+1))
+}
+
+// Unpin decrements the pin counter of the chunk, making it subject to garbage
+// collection again once the counter drops to zero.
+func (s *LDBStore) Unpin(addr Address) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	cnt := s.pinCount(addr)
+	if cnt == 0 {
+		return ErrNotPinned
+	}
+	if cnt == 1 {
+		return s.db.Delete(getPinKey(addr))
+	}
+	return s.db.Put(getPinKey(addr), U64ToBytes(cnt-1))
+}
+
+// PinCount returns the number of pins held on the chunk.
+func (s *LDBStore) PinCount(addr Address) uint64 {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.pinCount(addr)
+}
+
 func (s *LDBStore) BinIndex(po uint8) uint64 {
 	s.lock.RLock()
 	defer s.lock.RUnlock()
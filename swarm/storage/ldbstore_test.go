@@ -625,6 +625,116 @@ func TestLDBStoreCollectGarbageAccessUnlikeIndex(t *testing.T) {
 	log.Info("ldbstore", "total", n, "missing", missing, "entrycnt", ldb.entryCnt, "accesscnt", ldb.accessCnt)
 }
 
+// TestLDBStoreCollectGarbagePinned tests that pinned chunks are skipped by
+// garbage collection even if they are the least recently accessed
+func TestLDBStoreCollectGarbagePinned(t *testing.T) {
+
+	capacity := defaultMaxGCRound / 100 * 2
+	n := capacity - 1
+
+	ldb, cleanup := newLDBStore(t)
+	ldb.setCapacity(uint64(capacity))
+	defer cleanup()
+
+	chunks, err := mputRandomChunks(ldb, n, int64(ch.DefaultSize))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// pin the oldest chunks, which are the first in line for collection
+	pinned := chunks[:capacity/2]
+	for _, ch := range pinned {
+		if err := ldb.Pin(ch.Address()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err = mputRandomChunks(ldb, 2, int64(ch.DefaultSize))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// wait for garbage collection to kick in on the responsible actor
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	waitGc(ctx, ldb)
+
+	for i, ch := range pinned {
+		ret, err := ldb.Get(context.TODO(), ch.Address())
+		if err != nil {
+			t.Fatalf("fail find pinned chunk #%d - %s: %v", i, ch.Address(), err)
+		}
+		if !bytes.Equal(ret.Data(), ch.Data()) {
+			t.Fatal("expected to get the same data back, but got smth else")
+		}
+	}
+	if ldb.entryCnt >= uint64(n+2) {
+		t.Fatalf("expected unpinned chunks to be collected, entrycnt %d", ldb.entryCnt)
+	}
+
+	// pin counters are reference counts
+	addr := pinned[0].Address()
+	if err := ldb.Pin(addr); err != nil {
+		t.Fatal(err)
+	}
+	if cnt := ldb.PinCount(addr); cnt != 2 {
+		t.Fatalf("pin count mismatch: have %d, want %d", cnt, 2)
+	}
+	for i := 0; i < 2; i++ {
+		if err := ldb.Unpin(addr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ldb.Unpin(addr); err != ErrNotPinned {
+		t.Fatalf("unpin error mismatch: have %v, want %v", err, ErrNotPinned)
+	}
+}
+
+// TestLDBStoreCollectGarbageOnlyPinned tests that a garbage collection round
+// ends after a batch holding only pinned chunks instead of walking the whole
+// index
+func TestLDBStoreCollectGarbageOnlyPinned(t *testing.T) {
+	n := 50
+
+	ldb, cleanup := newLDBStore(t)
+	ldb.setCapacity(uint64(n * 2))
+	defer cleanup()
+
+	chunks, err := mputRandomChunks(ldb, n, int64(ch.DefaultSize))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	for _, ch := range chunks {
+		if err := ldb.Pin(ch.Address()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ldb.gc.maxBatch = 10
+
+	done := make(chan error)
+	go func() {
+		done <- ldb.collectGarbage()
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("garbage collection did not finish")
+	}
+	if ldb.gc.count != 0 {
+		t.Fatalf("collected chunk count mismatch: have %d, want %d", ldb.gc.count, 0)
+	}
+	if ldb.entryCnt != uint64(n) {
+		t.Fatalf("entry count mismatch: have %d, want %d", ldb.entryCnt, n)
+	}
+	for i, ch := range chunks {
+		if _, err := ldb.Get(context.TODO(), ch.Address()); err != nil {
+			t.Fatalf("fail find pinned chunk #%d - %s: %v", i, ch.Address(), err)
+		}
+	}
+}
+
 func TestCleanIndex(t *testing.T) {
 	capacity := 5000
 	n := 3
@@ -69,6 +69,14 @@ func (m *MemStore) Put(_ context.Context, c Chunk) error {
 	return nil
 }
 
+// Delete removes the chunk with the given address from the MemStore
+func (m *MemStore) Delete(addr Address) {
+	if m.disabled {
+		return
+	}
+	m.cache.Remove(string(addr))
+}
+
 func (m *MemStore) setCapacity(n int) {
 	if n <= 0 {
 		m.disabled = true
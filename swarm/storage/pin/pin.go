@@ -0,0 +1,208 @@
+// Copyright 2026 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package pin implements local pinning of swarm content. Every chunk of a
+// pinned file or collection carries a reference count in the local chunk
+// store, which keeps it out of garbage collection until the last pin on it is
+// released.
+package pin
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/swarm/api"
+	"github.com/ethereum/go-ethereum/swarm/log"
+	"github.com/ethereum/go-ethereum/swarm/state"
+	"github.com/ethereum/go-ethereum/swarm/storage"
+)
+
+// pinsKey is the state store key holding the records of all pinned content.
+const pinsKey = "pin_files"
+
+var (
+	// ErrNotPinned is returned when unpinning content which is not pinned.
+	ErrNotPinned = errors.New("content is not pinned")
+)
+
+// FileInfo describes a piece of pinned content.
+type FileInfo struct {
+	Address  storage.Address `json:"address"`
+	IsRaw    bool            `json:"isRaw"`
+	PinCount uint64          `json:"pinCount"`
+	Chunks   uint64          `json:"chunks"`
+}
+
+// API pins and unpins content in the local chunk store.
+type API struct {
+	db        *storage.LDBStore
+	fileStore *storage.FileStore
+	api       *api.API
+	state     state.Store
+	lock      sync.Mutex // serialises pin state modifications
+}
+
+// NewAPI creates a pinning API on top of the given local store. Missing
+// chunks are retrieved through fileStore while walking pinned content.
+func NewAPI(lstore *storage.LocalStore, stateStore state.Store, fileStore *storage.FileStore, api *api.API) *API {
+	return &API{
+		db:        lstore.DbStore,
+		fileStore: fileStore,
+		api:       api,
+		state:     stateStore,
+	}
+}
+
+// PinFiles pins the content at addr. Unless raw is set, addr must refer to
+// a manifest and all files and sub-manifests reachable from it are pinned
+// along with the manifest itself. Pinning the same content again increments
+// its pin counter.
+func (p *API) PinFiles(ctx context.Context, addr storage.Address, raw bool) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	pins, err := p.loadPins()
+	if err != nil {
+		return err
+	}
+	info, ok := pins[addr.Hex()]
+	if ok && info.IsRaw != raw {
+		return fmt.Errorf("content %s already pinned with raw=%v", addr.Hex(), info.IsRaw)
+	}
+
+	// roll back the chunks pinned so far if the content can't be walked fully
+	var pinned []storage.Address
+	err = p.walk(ctx, addr, raw, func(chunkAddr storage.Address) error {
+		if err := p.db.Pin(chunkAddr); err != nil {
+			return err
+		}
+		pinned = append(pinned, chunkAddr)
+		return nil
+	})
+	if err != nil {
+		for _, chunkAddr := range pinned {
+			p.db.Unpin(chunkAddr)
+		}
+		return err
+	}
+
+	if !ok {
+		info = &FileInfo{Address: addr, IsRaw: raw, Chunks: uint64(len(pinned))}
+		pins[addr.Hex()] = info
+	}
+	info.PinCount++
+	log.Debug("pinned content", "addr", addr, "raw", raw, "chunks", len(pinned), "pins", info.PinCount)
+	return p.state.Put(pinsKey, pins)
+}
+
+// UnpinFiles releases one pin held on the content at addr.
+func (p *API) UnpinFiles(ctx context.Context, addr storage.Address) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	pins, err := p.loadPins()
+	if err != nil {
+		return err
+	}
+	info, ok := pins[addr.Hex()]
+	if !ok {
+		return ErrNotPinned
+	}
+	// restore the chunks unpinned so far if the content can't be walked fully
+	var unpinned []storage.Address
+	err = p.walk(ctx, addr, info.IsRaw, func(chunkAddr storage.Address) error {
+		switch err := p.db.Unpin(chunkAddr); err {
+		case nil:
+			unpinned = append(unpinned, chunkAddr)
+		case storage.ErrNotPinned:
+		default:
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		for _, chunkAddr := range unpinned {
+			p.db.Pin(chunkAddr)
+		}
+		return err
+	}
+
+	info.PinCount--
+	if info.PinCount == 0 {
+		delete(pins, addr.Hex())
+	}
+	log.Debug("unpinned content", "addr", addr, "pins", info.PinCount)
+	return p.state.Put(pinsKey, pins)
+}
+
+// ListPinFiles returns the records of all pinned content.
+func (p *API) ListPinFiles() ([]*FileInfo, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	pins, err := p.loadPins()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*FileInfo, 0, len(pins))
+	for _, info := range pins {
+		list = append(list, info)
+	}
+	sort.Sort(byAddress(list))
+	return list, nil
+}
+
+// loadPins returns the pin records keyed by hex encoded content address.
+func (p *API) loadPins() (map[string]*FileInfo, error) {
+	pins := make(map[string]*FileInfo)
+	if err := p.state.Get(pinsKey, &pins); err != nil && err != state.ErrNotFound {
+		return nil, err
+	}
+	return pins, nil
+}
+
+// walk calls walkFn for every chunk of the content at addr, descending into
+// manifest entries unless raw is set.
+func (p *API) walk(ctx context.Context, addr storage.Address, raw bool, walkFn func(storage.Address) error) error {
+	if err := p.fileStore.WalkChunks(ctx, addr, walkFn); err != nil {
+		return err
+	}
+	if raw {
+		return nil
+	}
+	walker, err := p.api.NewManifestWalker(ctx, addr, api.NOOPDecrypt, nil)
+	if err != nil {
+		return err
+	}
+	return walker.Walk(func(entry *api.ManifestEntry) error {
+		// feed entries reference mutable content which isn't pinned
+		if entry.Hash == "" {
+			return nil
+		}
+		return p.fileStore.WalkChunks(ctx, common.Hex2Bytes(entry.Hash), walkFn)
+	})
+}
+
+type byAddress []*FileInfo
+
+func (s byAddress) Len() int           { return len(s) }
+func (s byAddress) Less(i, j int) bool { return bytes.Compare(s[i].Address, s[j].Address) < 0 }
+func (s byAddress) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
@@ -0,0 +1,220 @@
+// Copyright 2026 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pin
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/swarm/api"
+	"github.com/ethereum/go-ethereum/swarm/state"
+	"github.com/ethereum/go-ethereum/swarm/storage"
+	"github.com/ethereum/go-ethereum/swarm/testutil"
+)
+
+func newTestAPI(t *testing.T) (*API, *api.API, *storage.LDBStore, func()) {
+	dir, err := ioutil.TempDir("", "swarm-pin-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := storage.NewDefaultLocalStoreParams()
+	params.Init(dir)
+	lstore, err := storage.NewLocalStore(params, nil)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	fileStore := storage.NewFileStore(lstore, storage.NewFileStoreParams())
+	a := api.NewAPI(fileStore, nil, nil, nil)
+	cleanup := func() {
+		lstore.Close()
+		os.RemoveAll(dir)
+	}
+	return NewAPI(lstore, state.NewInmemoryStore(), fileStore, a), a, lstore.DbStore, cleanup
+}
+
+// chunkAddrs returns the addresses of all chunks in the tree rooted at addr.
+func chunkAddrs(t *testing.T, p *API, addr storage.Address) []storage.Address {
+	var addrs []storage.Address
+	err := p.fileStore.WalkChunks(context.TODO(), addr, func(chunkAddr storage.Address) error {
+		addrs = append(addrs, chunkAddr)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return addrs
+}
+
+func checkPinCounts(t *testing.T, db *storage.LDBStore, addrs []storage.Address, want uint64) {
+	t.Helper()
+	for _, addr := range addrs {
+		if have := db.PinCount(addr); have != want {
+			t.Fatalf("pin count mismatch for %s: have %d, want %d", addr, have, want)
+		}
+	}
+}
+
+func TestPinFiles(t *testing.T) {
+	p, a, db, cleanup := newTestAPI(t)
+	defer cleanup()
+
+	ctx := context.TODO()
+	data := testutil.RandomBytes(1, 3*4096+100)
+	manifestAddr, err := a.NewManifest(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mw, err := a.NewManifestWriter(ctx, manifestAddr, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileAddr, err := mw.AddEntry(ctx, bytes.NewReader(data), &api.ManifestEntry{
+		Path:        "file.bin",
+		ContentType: "application/octet-stream",
+		Size:        int64(len(data)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifestAddr, err = mw.Store()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fileChunks := chunkAddrs(t, p, fileAddr)
+	if len(fileChunks) != 5 {
+		t.Fatalf("file chunk count mismatch: have %d, want %d", len(fileChunks), 5)
+	}
+	allChunks := append(chunkAddrs(t, p, manifestAddr), fileChunks...)
+
+	// pinning is reference counted
+	for i := 0; i < 2; i++ {
+		if err := p.PinFiles(ctx, manifestAddr, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkPinCounts(t, db, allChunks, 2)
+
+	// raw pins only cover the chunks of the file
+	if err := p.PinFiles(ctx, fileAddr, true); err != nil {
+		t.Fatal(err)
+	}
+	checkPinCounts(t, db, fileChunks, 3)
+	if err := p.PinFiles(ctx, fileAddr, false); err == nil {
+		t.Fatal("expected error pinning raw content as manifest")
+	}
+
+	list, err := p.ListPinFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("pin list length mismatch: have %d, want %d", len(list), 2)
+	}
+	for _, info := range list {
+		switch {
+		case bytes.Equal(info.Address, manifestAddr):
+			if info.IsRaw || info.PinCount != 2 || info.Chunks != uint64(len(allChunks)) {
+				t.Fatalf("manifest pin mismatch: have %+v", info)
+			}
+		case bytes.Equal(info.Address, fileAddr):
+			if !info.IsRaw || info.PinCount != 1 || info.Chunks != uint64(len(fileChunks)) {
+				t.Fatalf("file pin mismatch: have %+v", info)
+			}
+		default:
+			t.Fatalf("unexpected pin %s", info.Address)
+		}
+	}
+
+	// unpinning releases the chunks once all pins are gone
+	for i := 0; i < 2; i++ {
+		if err := p.UnpinFiles(ctx, manifestAddr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkPinCounts(t, db, fileChunks, 1)
+	if err := p.UnpinFiles(ctx, manifestAddr); err != ErrNotPinned {
+		t.Fatalf("unpin error mismatch: have %v, want %v", err, ErrNotPinned)
+	}
+	if err := p.UnpinFiles(ctx, fileAddr); err != nil {
+		t.Fatal(err)
+	}
+	checkPinCounts(t, db, allChunks, 0)
+
+	list, err = p.ListPinFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 0 {
+		t.Fatalf("pin list length mismatch: have %d, want %d", len(list), 0)
+	}
+}
+
+func TestUnpinFilesRollback(t *testing.T) {
+	p, a, db, cleanup := newTestAPI(t)
+	defer cleanup()
+
+	ctx := context.TODO()
+	data := testutil.RandomBytes(1, 3*4096+100)
+	manifestAddr, err := a.NewManifest(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mw, err := a.NewManifestWriter(ctx, manifestAddr, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileAddr, err := mw.AddEntry(ctx, bytes.NewReader(data), &api.ManifestEntry{
+		Path:        "file.bin",
+		ContentType: "application/octet-stream",
+		Size:        int64(len(data)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifestAddr, err = mw.Store()
+	if err != nil {
+		t.Fatal(err)
+	}
+	allChunks := append(chunkAddrs(t, p, manifestAddr), chunkAddrs(t, p, fileAddr)...)
+	if err := p.PinFiles(ctx, manifestAddr, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// drop the file root from disk and bypass the memory cache, so the walk
+	// fails after the manifest chunks have been unpinned
+	if err := db.Delete(fileAddr); err != nil {
+		t.Fatal(err)
+	}
+	p.fileStore = storage.NewFileStore(db, storage.NewFileStoreParams())
+	if err := p.UnpinFiles(ctx, manifestAddr); err == nil {
+		t.Fatal("expected error unpinning incomplete content")
+	}
+	checkPinCounts(t, db, allChunks, 1)
+
+	list, err := p.ListPinFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].PinCount != 1 {
+		t.Fatalf("pin list mismatch: have %+v", list)
+	}
+}
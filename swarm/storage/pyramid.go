@@ -64,6 +64,7 @@ import (
 var (
 	errLoadingTreeRootChunk = errors.New("LoadTree Error: Could not load root chunk")
 	errLoadingTreeChunk     = errors.New("LoadTree Error: Could not load chunk")
+	errAppendErasure        = errors.New("LoadTree Error: Appending to erasure coded trees is not supported")
 )
 
 const (
@@ -242,7 +243,9 @@ func (pc *PyramidChunker) Split(ctx context.Context) (k Address, wait func(conte
 func (pc *PyramidChunker) Append(ctx context.Context) (k Address, wait func(context.Context) error, err error) {
 	log.Debug("pyramid.chunker: Append()")
 	// Load the right most unfinished tree chunks in every level
-	pc.loadTree(ctx)
+	if err := pc.loadTree(ctx); err != nil {
+		return nil, nil, err
+	}
 
 	pc.wg.Add(1)
 	pc.prepareChunks(ctx, true)
@@ -312,6 +315,9 @@ func (pc *PyramidChunker) loadTree(ctx context.Context) error {
 	if err != nil {
 		return errLoadingTreeRootChunk
 	}
+	if chunkData.Parities() > 0 {
+		return errAppendErasure
+	}
 	chunkSize := int64(chunkData.Size())
 	log.Trace("pyramid.chunker: root chunk", "chunk.Size", chunkSize, "pc.chunkSize", pc.chunkSize)
 
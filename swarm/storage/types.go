@@ -214,7 +214,7 @@ func (c *chunk) SpanBytes() []byte {
 
 func (c *chunk) Span() int64 {
 	if c.span == -1 {
-		c.span = int64(binary.LittleEndian.Uint64(c.sdata[:8]))
+		c.span = int64(binary.LittleEndian.Uint64(c.sdata[:8]) & spanSizeMask)
 	}
 	return c.span
 }
@@ -317,9 +317,19 @@ type Getter interface {
 	Get(context.Context, Reference) (ChunkData, error)
 }
 
+// spanSizeMask selects the size of the data under a chunk from its span. The
+// highest byte of the span of an intermediate chunk holds the number of
+// erasure coding parity references following the references of its children.
+const spanSizeMask = 1<<56 - 1
+
 // NOTE: this returns invalid data if chunk is encrypted
 func (c ChunkData) Size() uint64 {
-	return binary.LittleEndian.Uint64(c[:8])
+	return binary.LittleEndian.Uint64(c[:8]) & spanSizeMask
+}
+
+// Parities returns the number of parity chunk references of an intermediate chunk
+func (c ChunkData) Parities() int {
+	return int(c[7])
 }
 
 func (c ChunkData) Data() []byte {
@@ -0,0 +1,130 @@
+// Copyright 2026 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package swap
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/contracts/chequebook"
+	"github.com/ethereum/go-ethereum/p2p/protocols"
+	"github.com/ethereum/go-ethereum/swarm/log"
+)
+
+// Peer is a connected peer cheques are exchanged with
+type Peer struct {
+	*protocols.Peer
+	profile *Profile          // swap profile the peer advertised in the handshake
+	lock    sync.Mutex        // lock the inbox and the pending cheque
+	inbox   *chequebook.Inbox // cheques received, created on the first cheque
+	pending *chequebook.Cheque
+	chequeC chan struct{} // signals a pending cheque to the send loop
+	quit    chan struct{}
+
+	remainder *big.Int // amount received below one accounting unit, credited with the next cheque
+}
+
+// NewPeer creates a swap peer for the bzz protocol peer p with the swap profile
+// it advertised in the handshake, profile is nil if the peer has no chequebook
+func NewPeer(p *protocols.Peer, profile *Profile) *Peer {
+	return &Peer{
+		Peer:      p,
+		profile:   profile,
+		chequeC:   make(chan struct{}, 1),
+		quit:      make(chan struct{}),
+		remainder: new(big.Int),
+	}
+}
+
+// Run registers the peer and handles the cheques it sends until the connection is closed
+// it blocks for the duration of the bzz protocol session
+func (s *Swap) Run(p *Peer) error {
+	s.peersLock.Lock()
+	s.peers[p.ID()] = p
+	s.peersLock.Unlock()
+
+	go p.sendLoop()
+	defer func() {
+		s.peersLock.Lock()
+		delete(s.peers, p.ID())
+		s.peersLock.Unlock()
+		close(p.quit)
+		p.lock.Lock()
+		if p.inbox != nil {
+			p.inbox.Stop()
+		}
+		p.lock.Unlock()
+	}()
+
+	return p.Peer.Run(func(ctx context.Context, msg interface{}) error {
+		switch msg := msg.(type) {
+		case *ChequeMsg:
+			return s.receive(ctx, p, msg)
+		default:
+			return fmt.Errorf("unknown message type: %T", msg)
+		}
+	})
+}
+
+// getInbox returns the inbox for the peer's cheques, creating it if needed
+func (p *Peer) getInbox(ctx context.Context, s *Swap) (*chequebook.Inbox, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.inbox == nil {
+		inbox, err := s.newInbox(ctx, p.profile)
+		if err != nil {
+			return nil, err
+		}
+		p.inbox = inbox
+	}
+	return p.inbox, nil
+}
+
+// queueCheque schedules the cheque to be sent to the peer
+// cheques are cumulative, so a pending cheque not yet sent is superseded
+func (p *Peer) queueCheque(cheque *chequebook.Cheque) {
+	p.lock.Lock()
+	p.pending = cheque
+	p.lock.Unlock()
+	select {
+	case p.chequeC <- struct{}{}:
+	default:
+	}
+}
+
+// sendLoop sends the queued cheques to the peer in the order they were issued
+func (p *Peer) sendLoop() {
+	for {
+		select {
+		case <-p.chequeC:
+			p.lock.Lock()
+			cheque := p.pending
+			p.pending = nil
+			p.lock.Unlock()
+			if cheque == nil {
+				continue
+			}
+			if err := p.Send(context.TODO(), &ChequeMsg{Cheque: cheque}); err != nil {
+				log.Warn("unable to send cheque", "peer", p.ID(), "err", err)
+			}
+		case <-p.quit:
+			return
+		}
+	}
+}
@@ -17,36 +17,119 @@
 package swap
 
 import (
+	"context"
+	"crypto/ecdsa"
 	"errors"
 	"fmt"
+	"math/big"
 	"strconv"
 	"sync"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/contracts/chequebook"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/p2p/enode"
 	"github.com/ethereum/go-ethereum/p2p/protocols"
 	"github.com/ethereum/go-ethereum/swarm/log"
 	"github.com/ethereum/go-ethereum/swarm/state"
 )
 
+var (
+	defaultPrice            = big.NewInt(20000000000)    // price of an accounting unit (wei)
+	defaultPaymentThreshold = int64(100)                 // debt that triggers issuing a cheque (units)
+	defaultCashThreshold    = big.NewInt(50000000000000) // uncashed payout that triggers cashing (wei)
+)
+
+// Params are the prices and thresholds settling balances with peers
+type Params struct {
+	Price            *big.Int // price of an accounting unit in wei
+	PaymentThreshold int64    // debt in units at which a cheque is issued to the peer
+	CashThreshold    *big.Int // uncashed amount in wei at which a received cheque is cashed
+}
+
+// NewParams returns the default swap parameters
+func NewParams() *Params {
+	return &Params{
+		Price:            defaultPrice,
+		PaymentThreshold: defaultPaymentThreshold,
+		CashThreshold:    defaultCashThreshold,
+	}
+}
+
+// Profile is the swap setup a node advertises to its peers in the bzz handshake
+type Profile struct {
+	PublicKey   []byte         // key signing the cheques of the chequebook
+	Contract    common.Address // chequebook contract issuing cheques, zero if none
+	Beneficiary common.Address // address cheques to the node are issued to, zero if cashing is unavailable
+}
+
+// ChequeMsg is the bzz protocol message delivering a cheque to the beneficiary peer
+type ChequeMsg struct {
+	Cheque *chequebook.Cheque
+}
+
 // SwAP Swarm Accounting Protocol
 // a peer to peer micropayment system
 // A node maintains an individual balance with every peer
 // Only messages which have a price will be accounted for
 type Swap struct {
-	stateStore state.Store        //stateStore is needed in order to keep balances across sessions
-	lock       sync.RWMutex       //lock the balances
-	balances   map[enode.ID]int64 //map of balances for each peer
+	stateStore state.Store            //stateStore is needed in order to keep balances across sessions
+	lock       sync.RWMutex           //lock the balances
+	balances   map[enode.ID]int64     //map of balances for each peer
+	params     *Params                //prices and settlement thresholds
+	peers      map[enode.ID]*Peer     //connected peers settlement is possible with
+	prvKey     *ecdsa.PrivateKey      //key cashing received cheques
+	chequebook *chequebook.Chequebook //local chequebook issuing cheques, nil until set
+	backend    chequebook.Backend     //backend to validate and cash peers' cheques
+	peersLock  sync.RWMutex           //lock the peers
 }
 
 // New - swap constructor
-func New(stateStore state.Store) (swap *Swap) {
+// if params is nil, the default parameters are used
+func New(stateStore state.Store, params *Params) (swap *Swap) {
+	if params == nil {
+		params = NewParams()
+	}
 	swap = &Swap{
 		stateStore: stateStore,
 		balances:   make(map[enode.ID]int64),
+		params:     params,
+		peers:      make(map[enode.ID]*Peer),
 	}
 	return
 }
 
+// SetChequebook enables on-chain settlement
+// cheques are issued from chbook once a peer's debt reaches the payment threshold,
+// and cheques received are verified against and cashed via backend using prvKey
+// chbook is nil if the node only receives payments
+func (s *Swap) SetChequebook(prvKey *ecdsa.PrivateKey, chbook *chequebook.Chequebook, backend chequebook.Backend) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.prvKey = prvKey
+	s.chequebook = chbook
+	s.backend = backend
+}
+
+// Profile returns the local swap profile advertised in the handshake
+// it returns nil if settlement is not enabled
+func (s *Swap) Profile() *Profile {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	if s.prvKey == nil {
+		return nil
+	}
+	profile := &Profile{}
+	if s.chequebook != nil {
+		profile.PublicKey = crypto.FromECDSAPub(&s.prvKey.PublicKey)
+		profile.Contract = s.chequebook.Address()
+	}
+	if s.backend != nil {
+		profile.Beneficiary = crypto.PubkeyToAddress(s.prvKey.PublicKey)
+	}
+	return profile
+}
+
 //Swap implements the protocols.Balance interface
 //Add is the (sole) accounting function
 func (s *Swap) Add(amount int64, peer *protocols.Peer) (err error) {
@@ -61,6 +144,10 @@ func (s *Swap) Add(amount int64, peer *protocols.Peer) (err error) {
 	//adjust the balance
 	//if amount is negative, it will decrease, otherwise increase
 	s.balances[peer.ID()] += amount
+	//settle the debt if it reached the payment threshold
+	if s.balances[peer.ID()] <= -s.params.PaymentThreshold {
+		s.pay(peer.ID())
+	}
 	//save the new balance to the state store
 	peerBalance := s.balances[peer.ID()]
 	err = s.stateStore.Put(peer.ID().String(), &peerBalance)
@@ -69,6 +156,120 @@ func (s *Swap) Add(amount int64, peer *protocols.Peer) (err error) {
 	return err
 }
 
+// pay issues a cheque covering the debt to the peer and queues it for sending
+// the balance is only reset if the cheque was issued
+// the caller must hold s.lock
+func (s *Swap) pay(id enode.ID) {
+	if s.chequebook == nil {
+		return
+	}
+	s.peersLock.RLock()
+	p := s.peers[id]
+	s.peersLock.RUnlock()
+	if p == nil || p.profile == nil || p.profile.Beneficiary == (common.Address{}) {
+		return
+	}
+	debt := -s.balances[id]
+	amount := new(big.Int).Mul(big.NewInt(debt), s.params.Price)
+	cheque, err := s.chequebook.Issue(p.profile.Beneficiary, amount)
+	if err != nil {
+		log.Warn("unable to issue cheque", "peer", id, "amount", amount, "err", err)
+		return
+	}
+	s.balances[id] = 0
+	p.queueCheque(cheque)
+	log.Debug("issued cheque", "peer", id, "units", debt, "amount", amount)
+}
+
+// receive verifies the cheque sent by the peer, cashing it if the uncashed amount
+// exceeds the cash threshold, and credits the amount paid to the peer's balance
+// the part of the amount below one accounting unit is carried over to the next cheque
+func (s *Swap) receive(ctx context.Context, p *Peer, msg *ChequeMsg) error {
+	if msg.Cheque == nil {
+		return errors.New("empty cheque")
+	}
+	inbox, err := p.getInbox(ctx, s)
+	if err != nil {
+		return err
+	}
+	amount, err := inbox.Receive(msg.Cheque)
+	if err != nil {
+		return fmt.Errorf("invalid cheque: %v", err)
+	}
+	amount = new(big.Int).Add(amount, p.remainder)
+	quo, rem := new(big.Int).QuoRem(amount, s.params.Price, new(big.Int))
+	units := quo.Int64()
+	p.remainder = rem
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	err = s.loadState(p.Peer)
+	if err != nil && err != state.ErrNotFound {
+		return err
+	}
+	s.balances[p.ID()] -= units
+	peerBalance := s.balances[p.ID()]
+	log.Debug("received cheque", "peer", p.ID(), "units", units, "amount", amount, "balance", peerBalance)
+	return s.stateStore.Put(p.ID().String(), &peerBalance)
+}
+
+// newInbox creates the inbox for cheques from the peer's chequebook
+// it checks that the contract is a chequebook owned by the advertised key
+func (s *Swap) newInbox(ctx context.Context, profile *Profile) (*chequebook.Inbox, error) {
+	s.lock.RLock()
+	prvKey, backend := s.prvKey, s.backend
+	s.lock.RUnlock()
+	if backend == nil {
+		return nil, errors.New("no chequebook backend")
+	}
+	if profile == nil || profile.Contract == (common.Address{}) {
+		return nil, errors.New("peer has no chequebook")
+	}
+	signer, err := crypto.UnmarshalPubkey(profile.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chequebook key: %v", err)
+	}
+	ok, err := chequebook.ValidateCode(ctx, backend, profile.Contract)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("invalid chequebook contract %v", profile.Contract.Hex())
+	}
+	owner, err := chequebookOwner(ctx, backend, profile.Contract)
+	if err != nil {
+		return nil, err
+	}
+	if owner != crypto.PubkeyToAddress(*signer) {
+		return nil, fmt.Errorf("chequebook owner mismatch: %v != %v", owner.Hex(), crypto.PubkeyToAddress(*signer).Hex())
+	}
+	inbox, err := chequebook.NewInbox(prvKey, profile.Contract, crypto.PubkeyToAddress(prvKey.PublicKey), signer, backend)
+	if err != nil {
+		return nil, err
+	}
+	inbox.AutoCash(0, s.params.CashThreshold)
+	return inbox, nil
+}
+
+// storageBackend is implemented by backends giving access to contract storage
+type storageBackend interface {
+	StorageAt(ctx context.Context, contract common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error)
+}
+
+// chequebookOwner reads the owner of the chequebook contract
+// which is the first storage slot of the contract
+func chequebookOwner(ctx context.Context, backend chequebook.Backend, contract common.Address) (common.Address, error) {
+	b, ok := backend.(storageBackend)
+	if !ok {
+		return common.Address{}, errors.New("backend cannot access contract storage")
+	}
+	owner, err := b.StorageAt(ctx, contract, common.Hash{}, nil)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return common.BytesToAddress(owner), nil
+}
+
 //GetPeerBalance returns the balance for a given peer
 func (swap *Swap) GetPeerBalance(peer enode.ID) (int64, error) {
 	swap.lock.RLock()
@@ -17,16 +17,26 @@
 package swap
 
 import (
+	"crypto/ecdsa"
 	"flag"
 	"fmt"
 	"io/ioutil"
+	"math/big"
 	mrand "math/rand"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/contracts/chequebook"
+	"github.com/ethereum/go-ethereum/contracts/chequebook/contract"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
 	"github.com/ethereum/go-ethereum/p2p/protocols"
 	"github.com/ethereum/go-ethereum/p2p/simulations/adapters"
 	"github.com/ethereum/go-ethereum/swarm/state"
@@ -165,7 +175,7 @@ func createTestSwap(t *testing.T) (*Swap, string) {
 	if err2 != nil {
 		t.Fatal(err2)
 	}
-	swap := New(stateStore)
+	swap := New(stateStore, nil)
 	return swap, dir
 }
 
@@ -182,3 +192,235 @@ func newDummyPeer() *dummyPeer {
 	}
 	return dummy
 }
+
+var testChequeSpec = &protocols.Spec{
+	Name:       "bzz",
+	Version:    8,
+	MaxMsgSize: 10 * 1024 * 1024,
+	Messages: []interface{}{
+		ChequeMsg{},
+	},
+}
+
+//a swap node settling on a simulated chain
+type testSwapNode struct {
+	*Swap
+	id  enode.ID
+	key *ecdsa.PrivateKey
+	dir string
+}
+
+//create a swap node with a funded account on the simulated backend
+//if deposit is not nil, a chequebook holding deposit is deployed for the node
+func newTestSwapNode(t *testing.T, backend *backends.SimulatedBackend, key *ecdsa.PrivateKey, params *Params, deposit *big.Int) *testSwapNode {
+	swap, dir := createTestSwap(t)
+	swap.params = params
+	var chbook *chequebook.Chequebook
+	if deposit != nil {
+		addr, _, _, err := contract.DeployChequebook(bind.NewKeyedTransactor(key), backend)
+		if err != nil {
+			t.Fatal(err)
+		}
+		backend.Commit()
+		chbook, err = chequebook.NewChequebook(filepath.Join(dir, "chequebook.json"), addr, key, backend)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := chbook.Deposit(deposit); err != nil {
+			t.Fatal(err)
+		}
+		backend.Commit()
+	}
+	swap.SetChequebook(key, chbook, backend)
+	return &testSwapNode{
+		Swap: swap,
+		id:   adapters.RandomNodeConfig().ID,
+		key:  key,
+		dir:  dir,
+	}
+}
+
+//connect two swap nodes advertising the given profiles
+//it returns the protocol peers representing the remote node on either side,
+//a channel receiving the error b's session terminates with and a function
+//closing the connection
+func connectTestSwapNodes(a, b *testSwapNode, profileA, profileB *Profile) (*protocols.Peer, *protocols.Peer, chan error, func()) {
+	rwa, rwb := p2p.MsgPipe()
+	peerB := protocols.NewPeer(p2p.NewPeer(b.id, "b", nil), rwa, testChequeSpec)
+	peerA := protocols.NewPeer(p2p.NewPeer(a.id, "a", nil), rwb, testChequeSpec)
+	errc := make(chan error, 1)
+	go a.Run(NewPeer(peerB, profileB))
+	go func() { errc <- b.Run(NewPeer(peerA, profileA)) }()
+	//wait for the peers to be registered
+	for a.peer(b.id) == nil || b.peer(a.id) == nil {
+		time.Sleep(10 * time.Millisecond)
+	}
+	return peerA, peerB, errc, func() {
+		rwa.Close()
+		rwb.Close()
+	}
+}
+
+func (s *Swap) peer(id enode.ID) *Peer {
+	s.peersLock.RLock()
+	defer s.peersLock.RUnlock()
+	return s.peers[id]
+}
+
+func newTestBackend(keys ...*ecdsa.PrivateKey) *backends.SimulatedBackend {
+	alloc := core.GenesisAlloc{}
+	for _, key := range keys {
+		alloc[crypto.PubkeyToAddress(key.PublicKey)] = core.GenesisAccount{Balance: big.NewInt(1000000000), Reputation: 1000}
+	}
+	return backends.NewSimulatedBackend(alloc, 10000000)
+}
+
+//wait until the balance for the peer reaches want
+func waitBalance(t *testing.T, s *Swap, peer enode.ID, want int64) {
+	var have int64
+	for i := 0; i < 100; i++ {
+		have, _ = s.GetPeerBalance(peer)
+		if have == want {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("balance mismatch: have %v, want %v", have, want)
+}
+
+//Test that reaching the payment threshold issues a cheque to the peer,
+//which the peer credits and cashes on chain once above the cash threshold
+func TestSettlement(t *testing.T) {
+	keyA, _ := crypto.GenerateKey()
+	keyB, _ := crypto.GenerateKey()
+	backend := newTestBackend(keyA, keyB)
+	params := &Params{
+		Price:            big.NewInt(2),
+		PaymentThreshold: 10,
+		CashThreshold:    big.NewInt(30),
+	}
+	a := newTestSwapNode(t, backend, keyA, params, big.NewInt(1000))
+	defer os.RemoveAll(a.dir)
+	b := newTestSwapNode(t, backend, keyB, params, nil)
+	defer os.RemoveAll(b.dir)
+
+	peerA, peerB, _, disconnect := connectTestSwapNodes(a, b, a.Profile(), b.Profile())
+	defer disconnect()
+
+	//below the payment threshold no cheque is issued
+	a.Add(-9, peerB)
+	b.Add(9, peerA)
+	if balance, _ := a.GetPeerBalance(b.id); balance != -9 {
+		t.Fatalf("balance mismatch: have %v, want %v", balance, -9)
+	}
+
+	//the debt of 12 units is paid with a cheque of 24 wei,
+	//which is below the cash threshold
+	a.Add(-3, peerB)
+	b.Add(3, peerA)
+	waitBalance(t, a.Swap, b.id, 0)
+	waitBalance(t, b.Swap, a.id, 0)
+
+	chbook, err := contract.NewChequebook(a.chequebook.Address(), backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	beneficiary := crypto.PubkeyToAddress(keyB.PublicKey)
+	backend.Commit()
+	sent, err := chbook.Sent(nil, beneficiary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent.Sign() != 0 {
+		t.Fatalf("cashed amount mismatch: have %v, want 0", sent)
+	}
+
+	//the next cheque brings the uncashed amount to 48 wei, which is cashed
+	a.Add(-12, peerB)
+	b.Add(15, peerA)
+	waitBalance(t, a.Swap, b.id, 0)
+	waitBalance(t, b.Swap, a.id, 3)
+	backend.Commit()
+	sent, err = chbook.Sent(nil, beneficiary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent.Cmp(big.NewInt(48)) != 0 {
+		t.Fatalf("cashed amount mismatch: have %v, want %v", sent, 48)
+	}
+
+	//the settled balances are persisted
+	var balance int64
+	if err := b.stateStore.Get(a.id.String(), &balance); err != nil {
+		t.Fatal(err)
+	}
+	if balance != 3 {
+		t.Fatalf("stored balance mismatch: have %v, want %v", balance, 3)
+	}
+}
+
+//Test that the part of a cheque below one accounting unit of the receiver
+//is not lost but credited together with the next cheque
+func TestSettlementRemainder(t *testing.T) {
+	keyA, _ := crypto.GenerateKey()
+	keyB, _ := crypto.GenerateKey()
+	backend := newTestBackend(keyA, keyB)
+	paramsA := &Params{
+		Price:            big.NewInt(3),
+		PaymentThreshold: 11,
+		CashThreshold:    big.NewInt(1000),
+	}
+	paramsB := &Params{
+		Price:            big.NewInt(2),
+		PaymentThreshold: 11,
+		CashThreshold:    big.NewInt(1000),
+	}
+	a := newTestSwapNode(t, backend, keyA, paramsA, big.NewInt(1000))
+	defer os.RemoveAll(a.dir)
+	b := newTestSwapNode(t, backend, keyB, paramsB, nil)
+	defer os.RemoveAll(b.dir)
+
+	_, peerB, _, disconnect := connectTestSwapNodes(a, b, a.Profile(), b.Profile())
+	defer disconnect()
+
+	//the first cheque of 33 wei is worth 16 units with 1 wei left over
+	a.Add(-11, peerB)
+	waitBalance(t, b.Swap, a.id, -16)
+
+	//the second cheque of 33 wei plus the remainder is worth 17 units
+	a.Add(-11, peerB)
+	waitBalance(t, b.Swap, a.id, -33)
+}
+
+//Test that cheques from a chequebook not owned by the advertised key
+//are rejected and the peer is dropped
+func TestSettlementOwnerMismatch(t *testing.T) {
+	keyA, _ := crypto.GenerateKey()
+	keyB, _ := crypto.GenerateKey()
+	backend := newTestBackend(keyA, keyB)
+	params := &Params{
+		Price:            big.NewInt(1),
+		PaymentThreshold: 10,
+		CashThreshold:    big.NewInt(0),
+	}
+	a := newTestSwapNode(t, backend, keyA, params, big.NewInt(1000))
+	defer os.RemoveAll(a.dir)
+	b := newTestSwapNode(t, backend, keyB, params, nil)
+	defer os.RemoveAll(b.dir)
+
+	//a advertises a key that does not own its chequebook
+	profile := a.Profile()
+	profile.PublicKey = crypto.FromECDSAPub(&keyB.PublicKey)
+	_, peerB, errc, disconnect := connectTestSwapNodes(a, b, profile, b.Profile())
+	defer disconnect()
+
+	a.Add(-10, peerB)
+	select {
+	case err := <-errc:
+		if err == nil {
+			t.Fatal("expected cheque to be rejected")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for cheque to be rejected")
+	}
+}
@@ -51,6 +51,7 @@ import (
 	"github.com/ethereum/go-ethereum/swarm/storage"
 	"github.com/ethereum/go-ethereum/swarm/storage/feed"
 	"github.com/ethereum/go-ethereum/swarm/storage/mock"
+	"github.com/ethereum/go-ethereum/swarm/storage/pin"
 	"github.com/ethereum/go-ethereum/swarm/swap"
 	"github.com/ethereum/go-ethereum/swarm/tracing"
 )
@@ -68,6 +69,7 @@ var (
 type Swarm struct {
 	config      *api.Config        // swarm configuration
 	api         *api.API           // high level api layer (fs/manifest)
+	pinAPI      *pin.API           // local content pinning
 	dns         api.Resolver       // DNS registrar
 	fileStore   *storage.FileStore // distributed preimage archive, the local API to the storage with document level storage/retrieval support
 	streamer    *stream.Registry
@@ -178,7 +180,12 @@ func NewSwarm(config *api.Config, mockStore *mock.NodeStore) (self *Swarm, err e
 		if err != nil {
 			return nil, err
 		}
-		self.swap = swap.New(balancesStore)
+		self.swap = swap.New(balancesStore, &swap.Params{
+			Price:            config.Swap.SellAt,
+			PaymentThreshold: int64(config.Swap.PayAt),
+			CashThreshold:    config.Swap.AutoCashThreshold,
+		})
+		bzzconfig.Swap = self.swap
 	}
 
 	var nodeID enode.ID
@@ -210,6 +217,20 @@ func NewSwarm(config *api.Config, mockStore *mock.NodeStore) (self *Swarm, err e
 
 	var feedsHandler *feed.Handler
 	fhParams := &feed.HandlerParams{}
+	if config.FeedReputationAPI != "" {
+		log.Info("connecting to feed reputation API", "url", config.FeedReputationAPI)
+		fhParams.Reputation, err = ethclient.Dial(config.FeedReputationAPI)
+		if err != nil {
+			return nil, fmt.Errorf("error connecting to feed reputation API %s: %s", config.FeedReputationAPI, err)
+		}
+		for _, hex := range config.FeedReputationTopics {
+			var topic feed.Topic
+			if err := topic.FromHex(hex); err != nil {
+				return nil, fmt.Errorf("invalid feed reputation topic %q: %v", hex, err)
+			}
+			fhParams.ReputationTopics = append(fhParams.ReputationTopics, topic)
+		}
+	}
 
 	feedsHandler = feed.NewHandler(fhParams)
 	feedsHandler.SetStore(self.netStore)
@@ -229,7 +250,7 @@ func NewSwarm(config *api.Config, mockStore *mock.NodeStore) (self *Swarm, err e
 	self.bzz = network.NewBzz(bzzconfig, to, stateStore, self.streamer.GetSpec(), self.streamer.Run)
 
 	// Pss = postal service over swarm (devp2p over bzz)
-	self.ps, err = pss.NewPss(to, config.Pss)
+	self.ps, err = pss.NewPss(to, config.Pss.WithMailboxStore(stateStore))
 	if err != nil {
 		return nil, err
 	}
@@ -238,6 +259,7 @@ func NewSwarm(config *api.Config, mockStore *mock.NodeStore) (self *Swarm, err e
 	}
 
 	self.api = api.NewAPI(self.fileStore, self.dns, feedsHandler, self.privateKey)
+	self.pinAPI = pin.NewAPI(lstore, stateStore, self.fileStore, self.api)
 
 	self.sfs = fuse.NewSwarmFS(self.api)
 	log.Debug("Initialized FUSE filesystem")
@@ -392,7 +414,13 @@ func (self *Swarm) Start(srv *p2p.Server) error {
 	// start swarm http proxy server
 	if self.config.Port != "" {
 		addr := net.JoinHostPort(self.config.ListenAddr, self.config.Port)
-		server := httpapi.NewServer(self.api, self.config.Cors)
+		// pinning lets any client fill the local store beyond its capacity,
+		// so it is only served if explicitly enabled
+		var pinAPI *pin.API
+		if self.config.PinningEnabled {
+			pinAPI = self.pinAPI
+		}
+		server := httpapi.NewServer(self.api, pinAPI, self.config.Cors)
 
 		if self.config.Cors != "" {
 			log.Debug("Swarm HTTP proxy CORS headers", "allowedOrigins", self.config.Cors)
@@ -526,6 +554,9 @@ func (self *Swarm) SetChequebook(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	if self.swap != nil {
+		self.swap.SetChequebook(self.config.Swap.PrivateKey(), self.config.Swap.Chequebook(), self.backend)
+	}
 	log.Info(fmt.Sprintf("new chequebook set (%v): saving config file, resetting all connections in the hive", self.config.Swap.Contract.Hex()))
 	return nil
 }
@@ -20,6 +20,7 @@ import (
 	"bytes"
 	crand "crypto/rand"
 	mrand "math/rand"
+	"sort"
 	"testing"
 	"time"
 
@@ -192,6 +193,172 @@ func BenchmarkVerifyProof(b *testing.B) {
 	}
 }
 
+// Tests that contiguous ranges of the trie can be proven with edge proofs.
+func TestRangeProof(t *testing.T) {
+	trie, vals := randomTrie(4096)
+	entries := sortedEntries(vals)
+
+	for i := 0; i < 500; i++ {
+		start := mrand.Intn(len(entries))
+		end := mrand.Intn(len(entries)-start) + start + 1
+
+		proof := ethdb.NewMemDatabase()
+		if err := trie.Prove(entries[start].k, 0, proof); err != nil {
+			t.Fatalf("failed to prove the first node: %v", err)
+		}
+		if err := trie.Prove(entries[end-1].k, 0, proof); err != nil {
+			t.Fatalf("failed to prove the last node: %v", err)
+		}
+		keys, values := splitEntries(entries[start:end])
+		more, err := VerifyRangeProof(trie.Hash(), keys[0], keys, values, proof)
+		if err != nil {
+			t.Fatalf("case %d(%d->%d): %v", i, start, end-1, err)
+		}
+		if want := end < len(entries); more != want {
+			t.Fatalf("case %d(%d->%d): more mismatch: have %v, want %v", i, start, end-1, more, want)
+		}
+	}
+}
+
+// Tests that ranges can be proven from an origin which is not in the trie.
+func TestRangeProofWithNonExistentOrigin(t *testing.T) {
+	trie, vals := randomTrie(4096)
+	entries := sortedEntries(vals)
+
+	for i := 0; i < 500; i++ {
+		start := mrand.Intn(len(entries))
+		end := mrand.Intn(len(entries)-start) + start + 1
+
+		origin := decreaseKey(common.CopyBytes(entries[start].k))
+		if bytes.Compare(origin, entries[start].k) >= 0 || (start > 0 && bytes.Equal(origin, entries[start-1].k)) {
+			continue // wrapped around or hit the previous key
+		}
+		proof := ethdb.NewMemDatabase()
+		if err := trie.Prove(origin, 0, proof); err != nil {
+			t.Fatalf("failed to prove the origin: %v", err)
+		}
+		if err := trie.Prove(entries[end-1].k, 0, proof); err != nil {
+			t.Fatalf("failed to prove the last node: %v", err)
+		}
+		keys, values := splitEntries(entries[start:end])
+		if _, err := VerifyRangeProof(trie.Hash(), origin, keys, values, proof); err != nil {
+			t.Fatalf("case %d(%d->%d): %v", i, start, end-1, err)
+		}
+	}
+}
+
+// Tests that tampered ranges are rejected.
+func TestBadRangeProof(t *testing.T) {
+	trie, vals := randomTrie(4096)
+	entries := sortedEntries(vals)
+
+	for i := 0; i < 500; i++ {
+		start := mrand.Intn(len(entries))
+		end := mrand.Intn(len(entries)-start) + start + 1
+		if end-start < 3 {
+			continue
+		}
+		proof := ethdb.NewMemDatabase()
+		trie.Prove(entries[start].k, 0, proof)
+		trie.Prove(entries[end-1].k, 0, proof)
+
+		keys, values := splitEntries(entries[start:end])
+		index := mrand.Intn(end - start)
+		switch mrand.Intn(3) {
+		case 0:
+			// Modified value
+			values[index] = randBytes(20)
+		case 1:
+			// Gap in the middle of the range
+			index = mrand.Intn(end-start-2) + 1
+			keys = append(keys[:index:index], keys[index+1:]...)
+			values = append(values[:index:index], values[index+1:]...)
+		case 2:
+			// Out of order entries
+			index = mrand.Intn(end-start-2) + 1
+			keys[index], keys[index+1] = keys[index+1], keys[index]
+			values[index], values[index+1] = values[index+1], values[index]
+		}
+		if _, err := VerifyRangeProof(trie.Hash(), keys[0], keys, values, proof); err == nil {
+			t.Fatalf("case %d(%d->%d): expected error for tampered range", i, start, end-1)
+		}
+	}
+}
+
+// Tests the special cases of single element, empty and complete ranges.
+func TestRangeProofSpecialCases(t *testing.T) {
+	trie, vals := randomTrie(4096)
+	entries := sortedEntries(vals)
+	root := trie.Hash()
+
+	// Single element proven with a plain merkle proof
+	proof := ethdb.NewMemDatabase()
+	trie.Prove(entries[10].k, 0, proof)
+	more, err := VerifyRangeProof(root, entries[10].k, [][]byte{entries[10].k}, [][]byte{entries[10].v}, proof)
+	if err != nil || !more {
+		t.Fatalf("single element: have (%v, %v), want (true, nil)", more, err)
+	}
+	// Empty range after the last element
+	origin := bytes.Repeat([]byte{0xff}, 32)
+	proof = ethdb.NewMemDatabase()
+	trie.Prove(origin, 0, proof)
+	if more, err := VerifyRangeProof(root, origin, nil, nil, proof); err != nil || more {
+		t.Fatalf("empty tail range: have (%v, %v), want (false, nil)", more, err)
+	}
+	// Empty range hiding some elements
+	proof = ethdb.NewMemDatabase()
+	trie.Prove(entries[100].k, 0, proof)
+	if _, err := VerifyRangeProof(root, entries[100].k, nil, nil, proof); err == nil {
+		t.Fatalf("empty range hiding elements: expected error")
+	}
+	// Complete range without any proofs
+	keys, values := splitEntries(entries)
+	if more, err := VerifyRangeProof(root, nil, keys, values, nil); err != nil || more {
+		t.Fatalf("complete range: have (%v, %v), want (false, nil)", more, err)
+	}
+	if _, err := VerifyRangeProof(root, nil, keys[1:], values[1:], nil); err == nil {
+		t.Fatalf("incomplete range without proofs: expected error")
+	}
+}
+
+// sortedEntries returns the key-value pairs of a random trie in key order.
+func sortedEntries(vals map[string]*kv) []*kv {
+	entries := make([]*kv, 0, len(vals))
+	for _, kv := range vals {
+		entries = append(entries, kv)
+	}
+	sort.Sort(entrySlice(entries))
+	return entries
+}
+
+// splitEntries splits key-value pairs into separate key and value lists.
+func splitEntries(entries []*kv) ([][]byte, [][]byte) {
+	keys := make([][]byte, len(entries))
+	values := make([][]byte, len(entries))
+	for i, kv := range entries {
+		keys[i], values[i] = kv.k, kv.v
+	}
+	return keys, values
+}
+
+// decreaseKey returns the key preceding the given one.
+func decreaseKey(key []byte) []byte {
+	for i := len(key) - 1; i >= 0; i-- {
+		if key[i] > 0 {
+			key[i]--
+			break
+		}
+		key[i] = 0xff
+	}
+	return key
+}
+
+type entrySlice []*kv
+
+func (p entrySlice) Len() int           { return len(p) }
+func (p entrySlice) Less(i, j int) bool { return bytes.Compare(p[i].k, p[j].k) < 0 }
+func (p entrySlice) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+
 func randomTrie(n int) (*Trie, map[string]*kv) {
 	trie := new(Trie)
 	vals := make(map[string]*kv)
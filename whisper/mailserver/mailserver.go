@@ -19,24 +19,40 @@ package mailserver
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/log"
-	"github.com/ethereum/go-ethereum/rlp"
 	whisper "github.com/ethereum/go-ethereum/whisper/whisperv6"
-	"github.com/syndtr/goleveldb/leveldb"
-	"github.com/syndtr/goleveldb/leveldb/opt"
-	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+const (
+	// DefaultRequestInterval is the minimum time a peer has to wait between
+	// two requests for historic messages.
+	DefaultRequestInterval = time.Second
+
+	// MaxPageSize is the maximum number of envelopes delivered in response
+	// to a single request, the rest can be requested with the returned cursor.
+	MaxPageSize = 1000
+)
+
+var (
+	errRateLimited    = errors.New("request rate limit exceeded")
+	errInvalidRequest = errors.New("invalid request")
 )
 
 // WMailServer represents the state data of the mailserver.
 type WMailServer struct {
-	db  *leveldb.DB
-	w   *whisper.Whisper
-	pow float64
-	key []byte
+	storage Storage
+	w       *whisper.Whisper
+	pow     float64
+	key     []byte
+
+	limiter *rateLimiter
 }
 
 type DBKey struct {
@@ -58,24 +74,37 @@ func NewDbKey(t uint32, h common.Hash) *DBKey {
 	return &k
 }
 
-// Init initializes the mail server.
+// Init initializes the mail server, archiving the envelopes in a LevelDB
+// database at the given path.
 func (s *WMailServer) Init(shh *whisper.Whisper, path string, password string, pow float64) error {
-	var err error
 	if len(path) == 0 {
 		return fmt.Errorf("DB file is not specified")
 	}
-
 	if len(password) == 0 {
 		return fmt.Errorf("password is not specified")
 	}
-
-	s.db, err = leveldb.OpenFile(path, &opt.Options{OpenFilesCacheCapacity: 32})
+	storage, err := NewLevelDBStorage(path)
 	if err != nil {
 		return fmt.Errorf("open DB file: %s", err)
 	}
+	if err := s.InitWithStorage(shh, storage, password, pow); err != nil {
+		storage.Close()
+		return err
+	}
+	return nil
+}
 
+// InitWithStorage initializes the mail server with the given archive.
+func (s *WMailServer) InitWithStorage(shh *whisper.Whisper, storage Storage, password string, pow float64) error {
+	if len(password) == 0 {
+		return fmt.Errorf("password is not specified")
+	}
+	s.storage = storage
 	s.w = shh
 	s.pow = pow
+	if s.limiter == nil {
+		s.limiter = newRateLimiter(DefaultRequestInterval)
+	}
 
 	MailServerKeyID, err := s.w.AddSymKeyFromPassword(password)
 	if err != nil {
@@ -88,89 +117,93 @@ func (s *WMailServer) Init(shh *whisper.Whisper, path string, password string, p
 	return nil
 }
 
+// SetRequestInterval sets the minimum time a peer has to wait between two
+// requests, zero disables the rate limit.
+func (s *WMailServer) SetRequestInterval(interval time.Duration) {
+	s.limiter = newRateLimiter(interval)
+}
+
 // Close cleans up before shutdown.
 func (s *WMailServer) Close() {
-	if s.db != nil {
-		s.db.Close()
+	if s.storage != nil {
+		s.storage.Close()
 	}
 }
 
-// Archive stores the
+// Archive stores the envelope.
 func (s *WMailServer) Archive(env *whisper.Envelope) {
-	key := NewDbKey(env.Expiry-env.TTL, env.Hash())
-	rawEnvelope, err := rlp.EncodeToBytes(env)
-	if err != nil {
-		log.Error(fmt.Sprintf("rlp.EncodeToBytes failed: %s", err))
-	} else {
-		err = s.db.Put(key.raw, rawEnvelope, nil)
-		if err != nil {
-			log.Error(fmt.Sprintf("Writing to DB failed: %s", err))
-		}
+	if err := s.storage.Put(env); err != nil {
+		log.Error(fmt.Sprintf("Archiving envelope failed: %s", err))
 	}
 }
 
 // DeliverMail responds with saved messages upon request by the
-// messages' owner.
+// messages' owner, followed by the completion signal of the request.
 func (s *WMailServer) DeliverMail(peer *whisper.Peer, request *whisper.Envelope) {
 	if peer == nil {
 		log.Error("Whisper peer is nil")
 		return
 	}
 
-	ok, lower, upper, bloom := s.validateRequest(peer.ID(), request)
-	if ok {
-		s.processRequest(peer, lower, upper, bloom)
+	var (
+		cursor []byte
+		err    error
+	)
+	if !s.limiter.allow(peer.ID()) {
+		err = errRateLimited
+	} else if ok, query := s.validateRequest(peer.ID(), request); !ok {
+		err = errInvalidRequest
+	} else if _, cursor, err = s.processRequest(peer, query); err != nil {
+		log.Error(fmt.Sprintf("Failed to deliver historic messages: %s", err))
 	}
-}
-
-func (s *WMailServer) processRequest(peer *whisper.Peer, lower, upper uint32, bloom []byte) []*whisper.Envelope {
-	ret := make([]*whisper.Envelope, 0)
-	var err error
-	var zero common.Hash
-	kl := NewDbKey(lower, zero)
-	ku := NewDbKey(upper+1, zero) // LevelDB is exclusive, while the Whisper API is inclusive
-	i := s.db.NewIterator(&util.Range{Start: kl.raw, Limit: ku.raw}, nil)
-	defer i.Release()
-
-	for i.Next() {
-		var envelope whisper.Envelope
-		err = rlp.DecodeBytes(i.Value(), &envelope)
-		if err != nil {
-			log.Error(fmt.Sprintf("RLP decoding failed: %s", err))
-		}
 
-		if whisper.BloomFilterMatch(bloom, envelope.Bloom()) {
-			if peer == nil {
-				// used for test purposes
-				ret = append(ret, &envelope)
-			} else {
-				err = s.w.SendP2PDirect(peer, &envelope)
-				if err != nil {
-					log.Error(fmt.Sprintf("Failed to send direct message to peer: %s", err))
-					return nil
-				}
-			}
-		}
+	response := &whisper.MailServerResponse{RequestID: request.Hash(), Cursor: cursor}
+	if err != nil {
+		response.Error = err.Error()
+	}
+	if err := s.w.SendMailServerResponse(peer, response); err != nil {
+		log.Error(fmt.Sprintf("Failed to send mail server response to peer: %s", err))
 	}
+}
 
-	err = i.Error()
+// processRequest delivers a page of the envelopes matching the query to the
+// peer, returning the cursor of the next page. If the peer is nil, the
+// envelopes are returned instead.
+func (s *WMailServer) processRequest(peer *whisper.Peer, query *Query) ([]*whisper.Envelope, []byte, error) {
+	if query.Limit == 0 || query.Limit > MaxPageSize {
+		query.Limit = MaxPageSize
+	}
+	envelopes, cursor, err := s.storage.Query(query)
 	if err != nil {
-		log.Error(fmt.Sprintf("Level DB iterator error: %s", err))
+		return nil, nil, err
 	}
-
-	return ret
+	if peer == nil {
+		// used for test purposes
+		return envelopes, cursor, nil
+	}
+	for _, envelope := range envelopes {
+		if err := s.w.SendP2PDirect(peer, envelope); err != nil {
+			return nil, nil, err
+		}
+	}
+	return nil, cursor, nil
 }
 
-func (s *WMailServer) validateRequest(peerID []byte, request *whisper.Envelope) (bool, uint32, uint32, []byte) {
+// validateRequest decrypts and parses a request. The payload of the request
+// consists of the lower and upper limits of the time range (4 bytes each),
+// optionally followed by the bloom filter of the requested topics (64 bytes),
+// the maximum number of envelopes to deliver (4 bytes) and the cursor
+// returned with the previous page (36 bytes).
+func (s *WMailServer) validateRequest(peerID []byte, request *whisper.Envelope) (bool, *Query) {
 	if s.pow > 0.0 && request.PoW() < s.pow {
-		return false, 0, 0, nil
+		return false, nil
 	}
 
 	f := whisper.Filter{KeySym: s.key}
 	decrypted := request.Open(&f)
 	if decrypted == nil {
 		log.Warn(fmt.Sprintf("Failed to decrypt p2p request"))
-		return false, 0, 0, nil
+		return false, nil
 	}
 
 	src := crypto.FromECDSAPub(decrypted.Src)
@@ -182,24 +215,76 @@ func (s *WMailServer) validateRequest(peerID []byte, request *whisper.Envelope)
 	// if !bytes.Equal(peerID, src) {
 	if src == nil {
 		log.Warn(fmt.Sprintf("Wrong signature of p2p request"))
-		return false, 0, 0, nil
+		return false, nil
 	}
 
-	var bloom []byte
-	payloadSize := len(decrypted.Payload)
-	if payloadSize < 8 {
+	payload := decrypted.Payload
+	if len(payload) < 8 {
 		log.Warn(fmt.Sprintf("Undersized p2p request"))
-		return false, 0, 0, nil
-	} else if payloadSize == 8 {
-		bloom = whisper.MakeFullNodeBloom()
-	} else if payloadSize < 8+whisper.BloomFilterSize {
+		return false, nil
+	}
+	query := &Query{
+		Lower: binary.BigEndian.Uint32(payload[:4]),
+		Upper: binary.BigEndian.Uint32(payload[4:8]),
+	}
+	payload = payload[8:]
+
+	switch {
+	case len(payload) == 0:
+		query.Bloom = whisper.MakeFullNodeBloom()
+		return true, query
+	case len(payload) < whisper.BloomFilterSize:
 		log.Warn(fmt.Sprintf("Undersized bloom filter in p2p request"))
-		return false, 0, 0, nil
-	} else {
-		bloom = decrypted.Payload[8 : 8+whisper.BloomFilterSize]
+		return false, nil
+	}
+	query.Bloom = payload[:whisper.BloomFilterSize]
+	payload = payload[whisper.BloomFilterSize:]
+
+	if len(payload) >= 4 {
+		query.Limit = binary.BigEndian.Uint32(payload[:4])
+		payload = payload[4:]
 	}
+	switch len(payload) {
+	case 0:
+	case common.HashLength + 4:
+		query.Cursor = payload
+	default:
+		log.Warn(fmt.Sprintf("Invalid cursor in p2p request"))
+		return false, nil
+	}
+	return true, query
+}
+
+// rateLimiter keeps track of the last request of every peer, to enforce a
+// minimum interval between the requests.
+type rateLimiter struct {
+	interval time.Duration
+
+	lock sync.Mutex
+	last map[string]time.Time
+}
+
+func newRateLimiter(interval time.Duration) *rateLimiter {
+	return &rateLimiter{interval: interval, last: make(map[string]time.Time)}
+}
+
+// allow reports whether the peer may send a request now, and if so, records it.
+func (l *rateLimiter) allow(peerID []byte) bool {
+	if l.interval <= 0 {
+		return true
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
 
-	lower := binary.BigEndian.Uint32(decrypted.Payload[:4])
-	upper := binary.BigEndian.Uint32(decrypted.Payload[4:8])
-	return true, lower, upper, bloom
+	now := time.Now()
+	for id, t := range l.last {
+		if now.Sub(t) >= l.interval {
+			delete(l.last, id)
+		}
+	}
+	if _, ok := l.last[string(peerID)]; ok {
+		return false
+	}
+	l.last[string(peerID)] = now
+	return true
 }
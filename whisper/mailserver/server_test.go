@@ -37,10 +37,12 @@ var shh *whisper.Whisper
 var seed = time.Now().Unix()
 
 type ServerTestParams struct {
-	topic whisper.TopicType
-	low   uint32
-	upp   uint32
-	key   *ecdsa.PrivateKey
+	topic  whisper.TopicType
+	low    uint32
+	upp    uint32
+	limit  uint32
+	cursor []byte
+	key    *ecdsa.PrivateKey
 }
 
 func assert(statement bool, text string, t *testing.T) {
@@ -134,8 +136,17 @@ func deliverTest(t *testing.T, server *WMailServer, env *whisper.Envelope) {
 	p.low, p.upp = 0, birth-1
 	singleRequest(t, server, env, p, false)
 
-	p.low = birth - 1
-	p.upp = birth + 1
+	p.low, p.upp = birth-1, birth+1
+	p.limit = 1
+	singleRequest(t, server, env, p, true)
+
+	p.cursor = NewDbKey(birth, env.Hash()).raw
+	singleRequest(t, server, env, p, false)
+
+	p.cursor = NewDbKey(birth-1, env.Hash()).raw
+	singleRequest(t, server, env, p, true)
+
+	p.limit, p.cursor = 0, nil
 	p.topic[0] = 0xFF
 	singleRequest(t, server, env, p, false)
 }
@@ -143,23 +154,32 @@ func deliverTest(t *testing.T, server *WMailServer, env *whisper.Envelope) {
 func singleRequest(t *testing.T, server *WMailServer, env *whisper.Envelope, p *ServerTestParams, expect bool) {
 	request := createRequest(t, p)
 	src := crypto.FromECDSAPub(&p.key.PublicKey)
-	ok, lower, upper, bloom := server.validateRequest(src, request)
+	ok, query := server.validateRequest(src, request)
 	if !ok {
 		t.Fatalf("request validation failed, seed: %d.", seed)
 	}
-	if lower != p.low {
+	if query.Lower != p.low {
 		t.Fatalf("request validation failed (lower bound), seed: %d.", seed)
 	}
-	if upper != p.upp {
+	if query.Upper != p.upp {
 		t.Fatalf("request validation failed (upper bound), seed: %d.", seed)
 	}
 	expectedBloom := whisper.TopicToBloom(p.topic)
-	if !bytes.Equal(bloom, expectedBloom) {
+	if !bytes.Equal(query.Bloom, expectedBloom) {
 		t.Fatalf("request validation failed (topic), seed: %d.", seed)
 	}
+	if query.Limit != p.limit {
+		t.Fatalf("request validation failed (limit), seed: %d.", seed)
+	}
+	if !bytes.Equal(query.Cursor, p.cursor) {
+		t.Fatalf("request validation failed (cursor), seed: %d.", seed)
+	}
 
 	var exist bool
-	mail := server.processRequest(nil, p.low, p.upp, bloom)
+	mail, _, err := server.processRequest(nil, query)
+	if err != nil {
+		t.Fatalf("failed to process request with seed %d: %s.", seed, err)
+	}
 	for _, msg := range mail {
 		if msg.Hash() == env.Hash() {
 			exist = true
@@ -172,10 +192,10 @@ func singleRequest(t *testing.T, server *WMailServer, env *whisper.Envelope, p *
 	}
 
 	src[0]++
-	ok, lower, upper, bloom = server.validateRequest(src, request)
+	ok, query = server.validateRequest(src, request)
 	if !ok {
 		// request should be valid regardless of signature
-		t.Fatalf("request validation false negative, seed: %d (lower: %d, upper: %d).", seed, lower, upper)
+		t.Fatalf("request validation false negative, seed: %d (lower: %d, upper: %d).", seed, p.low, p.upp)
 	}
 }
 
@@ -185,6 +205,12 @@ func createRequest(t *testing.T, p *ServerTestParams) *whisper.Envelope {
 	binary.BigEndian.PutUint32(data, p.low)
 	binary.BigEndian.PutUint32(data[4:], p.upp)
 	data = append(data, bloom...)
+	if p.limit > 0 || p.cursor != nil {
+		limit := make([]byte, 4)
+		binary.BigEndian.PutUint32(limit, p.limit)
+		data = append(data, limit...)
+		data = append(data, p.cursor...)
+	}
 
 	key, err := shh.GetSymKey(keyID)
 	if err != nil {
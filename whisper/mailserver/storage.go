@@ -0,0 +1,397 @@
+// Copyright 2026 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package mailserver
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	whisper "github.com/ethereum/go-ethereum/whisper/whisperv6"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/comparer"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+	"github.com/syndtr/goleveldb/leveldb/memdb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// Archived envelopes are stored under their DBKey (sent time and hash), and
+// indexed by topic under the topic followed by the DBKey, so that both the time
+// range and the topics of a request can be looked up without a full scan.
+var (
+	versionKey     = []byte("version") // versionKey -> rlp(storageVersion)
+	envelopePrefix = []byte("e")       // envelopePrefix + DBKey -> rlp(envelope)
+	topicPrefix    = []byte("t")       // topicPrefix + topic + DBKey -> nil
+)
+
+const (
+	dbKeyLength = common.HashLength + 4
+
+	// storageVersion is the version of the database layout. Databases without
+	// a version were written by older mail servers, which stored the envelopes
+	// under their bare DBKey, and are migrated when opened.
+	storageVersion = uint64(1)
+)
+
+// Query selects archived envelopes.
+type Query struct {
+	Lower, Upper uint32 // Time range the envelopes were sent in, inclusive
+	Bloom        []byte // Bloom filter the envelope topics must match, nil for any topic
+	Limit        uint32 // Maximum number of envelopes to return, 0 for no limit
+	Cursor       []byte // DBKey of the last envelope of the previous page, nil for the first page
+}
+
+// Storage is the archive of a mail server. Implementations must be safe for
+// concurrent use.
+type Storage interface {
+	// Put archives an envelope.
+	Put(env *whisper.Envelope) error
+
+	// Query returns the archived envelopes matching the query, ordered by sent
+	// time and hash. If the page is full, the cursor to request the next page
+	// with is returned too, otherwise the cursor is nil.
+	Query(q *Query) ([]*whisper.Envelope, []byte, error)
+
+	// Close releases the resources held by the storage.
+	Close() error
+}
+
+// database is the sorted key-value store an archive is kept in.
+type database interface {
+	Put(key, value []byte) error
+	Get(key []byte) ([]byte, error)
+	Delete(key []byte) error
+	NewIterator(slice *util.Range) iterator.Iterator
+	Close() error
+}
+
+// NewLevelDBStorage opens (or creates) an archive persisted in a LevelDB
+// database at the given path.
+func NewLevelDBStorage(path string) (Storage, error) {
+	db, err := leveldb.OpenFile(path, &opt.Options{OpenFilesCacheCapacity: 32})
+	if err != nil {
+		return nil, err
+	}
+	a := &archive{db: &levelDB{db}, topics: make(map[whisper.TopicType]struct{})}
+	if err := a.upgrade(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := a.loadTopics(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return a, nil
+}
+
+// NewMemoryStorage creates an archive kept in memory, which is lost when the
+// mail server shuts down.
+func NewMemoryStorage() Storage {
+	return &archive{
+		db:     &memDB{memdb.New(comparer.DefaultComparer, 0)},
+		topics: make(map[whisper.TopicType]struct{}),
+	}
+}
+
+// levelDB adapts a LevelDB database to the database interface.
+type levelDB struct {
+	db *leveldb.DB
+}
+
+func (l *levelDB) Put(key, value []byte) error    { return l.db.Put(key, value, nil) }
+func (l *levelDB) Get(key []byte) ([]byte, error) { return l.db.Get(key, nil) }
+func (l *levelDB) Delete(key []byte) error        { return l.db.Delete(key, nil) }
+func (l *levelDB) Close() error                   { return l.db.Close() }
+
+func (l *levelDB) NewIterator(slice *util.Range) iterator.Iterator {
+	return l.db.NewIterator(slice, nil)
+}
+
+// memDB adapts an in-memory skip list to the database interface.
+type memDB struct {
+	*memdb.DB
+}
+
+func (m *memDB) Close() error {
+	m.Reset()
+	return nil
+}
+
+// archive implements Storage on top of a sorted key-value store.
+type archive struct {
+	db database
+
+	lock   sync.RWMutex                   // guards topics
+	topics map[whisper.TopicType]struct{} // topics of the archived envelopes
+}
+
+// upgrade checks the layout version of the database, migrating the envelopes
+// archived by older mail servers to the current layout. Databases written by
+// newer, unknown versions are rejected.
+func (a *archive) upgrade() error {
+	blob, err := a.db.Get(versionKey)
+	switch {
+	case err == leveldb.ErrNotFound:
+		if err := a.migrateLegacy(); err != nil {
+			return fmt.Errorf("failed to migrate legacy archive: %v", err)
+		}
+		enc, _ := rlp.EncodeToBytes(storageVersion)
+		return a.db.Put(versionKey, enc)
+	case err != nil:
+		return err
+	}
+	var version uint64
+	if err := rlp.DecodeBytes(blob, &version); err != nil {
+		return fmt.Errorf("invalid archive version: %v", err)
+	}
+	if version != storageVersion {
+		return fmt.Errorf("unsupported archive version %d, want %d", version, storageVersion)
+	}
+	return nil
+}
+
+// migrateLegacy moves the envelopes stored under their bare DBKey into the
+// current layout, indexing them by topic. Legacy keys are told apart by their
+// length, which none of the current keys share. An interrupted migration is
+// resumed on the next start, as the version is only written once it is done.
+func (a *archive) migrateLegacy() error {
+	it := a.db.NewIterator(nil)
+	defer it.Release()
+
+	var migrated int
+	for it.Next() {
+		key := it.Key()
+		if len(key) != dbKeyLength {
+			continue
+		}
+		var envelope whisper.Envelope
+		if err := rlp.DecodeBytes(it.Value(), &envelope); err != nil {
+			return fmt.Errorf("invalid envelope %x: %v", key, err)
+		}
+		if err := a.db.Put(append(common.CopyBytes(envelopePrefix), key...), it.Value()); err != nil {
+			return err
+		}
+		if err := a.db.Put(topicKey(envelope.Topic, key), nil); err != nil {
+			return err
+		}
+		if err := a.db.Delete(common.CopyBytes(key)); err != nil {
+			return err
+		}
+		migrated++
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	if migrated > 0 {
+		log.Info("Migrated legacy mail server archive", "envelopes", migrated)
+	}
+	return nil
+}
+
+// loadTopics collects the topics found in the topic index.
+func (a *archive) loadTopics() error {
+	it := a.db.NewIterator(util.BytesPrefix(topicPrefix))
+	defer it.Release()
+
+	for ok := it.First(); ok; {
+		key := it.Key()
+		if len(key) != len(topicPrefix)+whisper.TopicLength+dbKeyLength {
+			return fmt.Errorf("invalid topic index key %x", key)
+		}
+		topic := whisper.BytesToTopic(key[len(topicPrefix):])
+		a.topics[topic] = struct{}{}
+
+		// skip the rest of the entries of this topic
+		next := util.BytesPrefix(key[:len(topicPrefix)+whisper.TopicLength]).Limit
+		if next == nil {
+			break
+		}
+		ok = it.Seek(next)
+	}
+	return it.Error()
+}
+
+// Put archives the envelope and adds it to the topic index.
+func (a *archive) Put(env *whisper.Envelope) error {
+	key := NewDbKey(env.Expiry-env.TTL, env.Hash())
+	blob, err := rlp.EncodeToBytes(env)
+	if err != nil {
+		return err
+	}
+	if err := a.db.Put(append(common.CopyBytes(envelopePrefix), key.raw...), blob); err != nil {
+		return err
+	}
+	if err := a.db.Put(topicKey(env.Topic, key.raw), nil); err != nil {
+		return err
+	}
+	a.lock.Lock()
+	a.topics[env.Topic] = struct{}{}
+	a.lock.Unlock()
+	return nil
+}
+
+// Query returns the envelopes matching the query. Requests for specific topics
+// are served from the topic index, requests for all of them from the time index.
+func (a *archive) Query(q *Query) ([]*whisper.Envelope, []byte, error) {
+	if q.Lower > q.Upper {
+		return nil, nil, nil
+	}
+	var (
+		keys [][]byte
+		err  error
+	)
+	if topics := a.matchingTopics(q.Bloom); topics == nil {
+		keys, err = a.scanTime(q)
+	} else {
+		keys, err = a.scanTopics(q, topics)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	envelopes := make([]*whisper.Envelope, 0, len(keys))
+	for _, key := range keys {
+		blob, err := a.db.Get(append(common.CopyBytes(envelopePrefix), key...))
+		if err != nil {
+			return nil, nil, err
+		}
+		envelope := new(whisper.Envelope)
+		if err := rlp.DecodeBytes(blob, envelope); err != nil {
+			return nil, nil, err
+		}
+		envelopes = append(envelopes, envelope)
+	}
+	var cursor []byte
+	if q.Limit > 0 && len(keys) == int(q.Limit) {
+		cursor = keys[len(keys)-1]
+	}
+	return envelopes, cursor, nil
+}
+
+// Close closes the underlying database.
+func (a *archive) Close() error {
+	return a.db.Close()
+}
+
+// matchingTopics returns the archived topics matching the bloom filter, or nil
+// if the filter matches all topics.
+func (a *archive) matchingTopics(bloom []byte) []whisper.TopicType {
+	if isFullBloom(bloom) {
+		return nil
+	}
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+
+	topics := make([]whisper.TopicType, 0)
+	for topic := range a.topics {
+		if whisper.BloomFilterMatch(bloom, whisper.TopicToBloom(topic)) {
+			topics = append(topics, topic)
+		}
+	}
+	return topics
+}
+
+// scanTime iterates the time index over the queried range, returning the keys
+// of the envelopes matching the bloom filter.
+func (a *archive) scanTime(q *Query) ([][]byte, error) {
+	it := a.db.NewIterator(keyRange(envelopePrefix, q))
+	defer it.Release()
+
+	var keys [][]byte
+	for it.Next() && (q.Limit == 0 || len(keys) < int(q.Limit)) {
+		if q.Bloom != nil {
+			var envelope whisper.Envelope
+			if err := rlp.DecodeBytes(it.Value(), &envelope); err != nil {
+				return nil, err
+			}
+			if !whisper.BloomFilterMatch(q.Bloom, envelope.Bloom()) {
+				continue
+			}
+		}
+		keys = append(keys, common.CopyBytes(it.Key()[len(envelopePrefix):]))
+	}
+	return keys, it.Error()
+}
+
+// scanTopics iterates the index of every topic over the queried range, and
+// merges the keys found in time order.
+func (a *archive) scanTopics(q *Query, topics []whisper.TopicType) ([][]byte, error) {
+	var keys [][]byte
+	for _, topic := range topics {
+		prefix := topicKey(topic, nil)
+		it := a.db.NewIterator(keyRange(prefix, q))
+		for n := 0; it.Next() && (q.Limit == 0 || n < int(q.Limit)); n++ {
+			keys = append(keys, common.CopyBytes(it.Key()[len(prefix):]))
+		}
+		it.Release()
+		if err := it.Error(); err != nil {
+			return nil, err
+		}
+	}
+	sort.Sort(byKey(keys))
+	if q.Limit > 0 && len(keys) > int(q.Limit) {
+		keys = keys[:q.Limit]
+	}
+	return keys, nil
+}
+
+// keyRange returns the range of keys under prefix selected by the time range
+// and cursor of the query.
+func keyRange(prefix []byte, q *Query) *util.Range {
+	start := append(common.CopyBytes(prefix), NewDbKey(q.Lower, common.Hash{}).raw...)
+	if len(q.Cursor) == dbKeyLength {
+		// resume right after the last delivered envelope
+		if after := append(append(common.CopyBytes(prefix), q.Cursor...), 0); bytes.Compare(after, start) > 0 {
+			start = after
+		}
+	}
+	// LevelDB ranges are exclusive, while the Whisper API is inclusive
+	limit := util.BytesPrefix(prefix).Limit
+	if q.Upper < ^uint32(0) {
+		limit = append(common.CopyBytes(prefix), NewDbKey(q.Upper+1, common.Hash{}).raw...)
+	}
+	return &util.Range{Start: start, Limit: limit}
+}
+
+// topicKey returns the topic index key of an envelope.
+func topicKey(topic whisper.TopicType, key []byte) []byte {
+	k := make([]byte, 0, len(topicPrefix)+whisper.TopicLength+len(key))
+	k = append(k, topicPrefix...)
+	k = append(k, topic[:]...)
+	return append(k, key...)
+}
+
+// isFullBloom reports whether the bloom filter matches any topic.
+func isFullBloom(bloom []byte) bool {
+	for _, b := range bloom {
+		if b != 0xff {
+			return false
+		}
+	}
+	return true
+}
+
+type byKey [][]byte
+
+func (k byKey) Len() int           { return len(k) }
+func (k byKey) Less(i, j int) bool { return bytes.Compare(k[i], k[j]) < 0 }
+func (k byKey) Swap(i, j int)      { k[i], k[j] = k[j], k[i] }
@@ -0,0 +1,228 @@
+// Copyright 2026 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package mailserver
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	whisper "github.com/ethereum/go-ethereum/whisper/whisperv6"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+var (
+	testTopicA = whisper.TopicType{0x01, 0x02, 0x03, 0x04}
+	testTopicB = whisper.TopicType{0xa1, 0xb2, 0xc3, 0xd4}
+)
+
+// fillStorage archives an envelope sent every second since base, alternating
+// between two topics.
+func fillStorage(t *testing.T, storage Storage, base uint32, n int) []*whisper.Envelope {
+	envelopes := make([]*whisper.Envelope, n)
+	for i := range envelopes {
+		topic := testTopicA
+		if i%2 == 1 {
+			topic = testTopicB
+		}
+		envelopes[i] = &whisper.Envelope{
+			Expiry: base + uint32(i) + whisper.DefaultTTL,
+			TTL:    whisper.DefaultTTL,
+			Topic:  topic,
+			Data:   []byte{byte(i)},
+		}
+		if err := storage.Put(envelopes[i]); err != nil {
+			t.Fatalf("failed to archive envelope %d: %v", i, err)
+		}
+	}
+	return envelopes
+}
+
+// queryAll pages through all the envelopes matching the query.
+func queryAll(t *testing.T, storage Storage, q *Query) (envelopes []*whisper.Envelope, pages int) {
+	for {
+		page, cursor, err := storage.Query(q)
+		if err != nil {
+			t.Fatalf("query failed: %v", err)
+		}
+		if q.Limit > 0 && len(page) > int(q.Limit) {
+			t.Fatalf("page size mismatch: have %d, want at most %d", len(page), q.Limit)
+		}
+		envelopes = append(envelopes, page...)
+		pages++
+		if cursor == nil {
+			return envelopes, pages
+		}
+		q.Cursor = cursor
+	}
+}
+
+func testStorage(t *testing.T, storage Storage) {
+	base := uint32(time.Now().Unix())
+	all := fillStorage(t, storage, base, 10)
+
+	tests := []struct {
+		query *Query
+		want  []int
+		pages int
+	}{
+		// all topics, served from the time index
+		{&Query{Lower: base, Upper: base + 9}, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, 1},
+		{&Query{Lower: base + 2, Upper: base + 4}, []int{2, 3, 4}, 1},
+		{&Query{Lower: 0, Upper: ^uint32(0)}, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, 1},
+		{&Query{Lower: base, Upper: base + 9, Bloom: whisper.MakeFullNodeBloom(), Limit: 4}, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, 3},
+		{&Query{Lower: base, Upper: base + 9, Limit: 5}, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, 3},
+		{&Query{Lower: base + 10, Upper: base + 20}, nil, 1},
+		{&Query{Lower: base + 5, Upper: base + 4}, nil, 1},
+
+		// specific topics, served from the topic index
+		{&Query{Lower: base, Upper: base + 9, Bloom: whisper.TopicToBloom(testTopicA)}, []int{0, 2, 4, 6, 8}, 1},
+		{&Query{Lower: base + 3, Upper: ^uint32(0), Bloom: whisper.TopicToBloom(testTopicB), Limit: 2}, []int{3, 5, 7, 9}, 3},
+		{&Query{Lower: base, Upper: base + 9, Bloom: whisper.TopicToBloom(whisper.TopicType{0xff})}, nil, 1},
+	}
+	for i, tt := range tests {
+		have, pages := queryAll(t, storage, tt.query)
+		if len(have) != len(tt.want) {
+			t.Fatalf("test %d: envelope count mismatch: have %d, want %d", i, len(have), len(tt.want))
+		}
+		for j, env := range have {
+			if env.Hash() != all[tt.want[j]].Hash() {
+				t.Fatalf("test %d: envelope %d mismatch: have %x, want %x", i, j, env.Hash(), all[tt.want[j]].Hash())
+			}
+		}
+		if pages != tt.pages {
+			t.Fatalf("test %d: page count mismatch: have %d, want %d", i, pages, tt.pages)
+		}
+	}
+}
+
+func TestMemoryStorage(t *testing.T) {
+	storage := NewMemoryStorage()
+	defer storage.Close()
+
+	testStorage(t, storage)
+}
+
+func TestLevelDBStorage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "whisper-storage-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	storage, err := NewLevelDBStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStorage(t, storage)
+	storage.Close()
+
+	// the topic index must survive a restart
+	storage, err = NewLevelDBStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer storage.Close()
+
+	envelopes, _, err := storage.Query(&Query{Lower: 0, Upper: ^uint32(0), Bloom: whisper.TopicToBloom(testTopicB)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(envelopes) != 5 {
+		t.Fatalf("envelope count mismatch after reopen: have %d, want %d", len(envelopes), 5)
+	}
+}
+
+// Tests that archives written by older mail servers, which stored envelopes
+// under their bare DBKey, are migrated to the indexed layout when opened.
+func TestLevelDBStorageMigration(t *testing.T) {
+	dir, err := ioutil.TempDir("", "whisper-storage-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := leveldb.OpenFile(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy := NewMemoryStorage()
+	all := fillStorage(t, legacy, uint32(time.Now().Unix()), 10)
+	legacy.Close()
+	for _, env := range all {
+		blob, _ := rlp.EncodeToBytes(env)
+		if err := db.Put(NewDbKey(env.Expiry-env.TTL, env.Hash()).raw, blob, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.Close()
+
+	storage, err := NewLevelDBStorage(dir)
+	if err != nil {
+		t.Fatalf("failed to open legacy archive: %v", err)
+	}
+	envelopes, _ := queryAll(t, storage, &Query{Lower: 0, Upper: ^uint32(0), Bloom: whisper.TopicToBloom(testTopicA)})
+	if len(envelopes) != 5 {
+		t.Fatalf("envelope count mismatch after migration: have %d, want %d", len(envelopes), 5)
+	}
+	for i, env := range envelopes {
+		if env.Hash() != all[2*i].Hash() {
+			t.Errorf("envelope %d mismatch: have %x, want %x", i, env.Hash(), all[2*i].Hash())
+		}
+	}
+	storage.Close()
+
+	// archives of unknown versions must be rejected
+	db, err = leveldb.OpenFile(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, _ := rlp.EncodeToBytes(storageVersion + 1)
+	if err := db.Put(versionKey, enc, nil); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	if storage, err := NewLevelDBStorage(dir); err == nil {
+		storage.Close()
+		t.Fatal("opened archive of unknown version")
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	limiter := newRateLimiter(50 * time.Millisecond)
+	peerA, peerB := []byte{0x01}, []byte{0x02}
+
+	if !limiter.allow(peerA) {
+		t.Fatal("first request of peer A rejected")
+	}
+	if limiter.allow(peerA) {
+		t.Fatal("second request of peer A allowed within the interval")
+	}
+	if !limiter.allow(peerB) {
+		t.Fatal("first request of peer B rejected")
+	}
+	time.Sleep(60 * time.Millisecond)
+	if !limiter.allow(peerA) {
+		t.Fatal("request of peer A rejected after the interval")
+	}
+	if !newRateLimiter(0).allow(peerA) {
+		t.Fatal("request rejected with the rate limit disabled")
+	}
+}
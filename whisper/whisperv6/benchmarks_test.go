@@ -17,10 +17,15 @@
 package whisperv6
 
 import (
+	"crypto/rand"
 	"crypto/sha256"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
 	"golang.org/x/crypto/pbkdf2"
 )
 
@@ -206,3 +211,151 @@ func BenchmarkPoW(b *testing.B) {
 		}
 	}
 }
+
+// meteredRW counts the bytes written to a message pipe.
+type meteredRW struct {
+	p2p.MsgReadWriter
+	bytes *uint64
+}
+
+func (rw meteredRW) WriteMsg(msg p2p.Msg) error {
+	atomic.AddUint64(rw.bytes, uint64(msg.Size))
+	return rw.MsgReadWriter.WriteMsg(msg)
+}
+
+// simNode is a whisper node of an in-memory simulated network.
+type simNode struct {
+	id  enode.ID
+	shh *Whisper
+}
+
+func newSimNode() *simNode {
+	node := &simNode{shh: New(&Config{MaxMessageSize: DefaultMaxMessageSize})}
+	rand.Read(node.id[:])
+	node.shh.Start(nil)
+	return node
+}
+
+// connect links two simulated nodes through a message pipe, counting the bytes
+// they send to each other. It returns a function to disconnect them.
+func (node *simNode) connect(remote *simNode, bytes *uint64) func() {
+	rw1, rw2 := p2p.MsgPipe()
+	go node.shh.HandlePeer(p2p.NewPeer(remote.id, "", nil), meteredRW{rw1, bytes})
+	go remote.shh.HandlePeer(p2p.NewPeer(node.id, "", nil), meteredRW{rw2, bytes})
+	return func() {
+		rw1.Close()
+		rw2.Close()
+	}
+}
+
+// newSimEnvelope creates a unique envelope with the given topic. The PoW of
+// the envelope is not computed, the simulated nodes do not require any.
+func newSimEnvelope(topic TopicType) *Envelope {
+	env := &Envelope{
+		Expiry: uint32(time.Now().Unix()) + DefaultTTL,
+		TTL:    DefaultTTL,
+		Topic:  topic,
+		Data:   make([]byte, 256),
+	}
+	rand.Read(env.Data)
+	return env
+}
+
+// waitEnvelopes waits until the node has received all the envelopes.
+func waitEnvelopes(node *simNode, envelopes []*Envelope, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for _, env := range envelopes {
+		for !node.shh.isEnvelopeCached(env.Hash()) {
+			if time.Now().After(deadline) {
+				return false
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+	return true
+}
+
+// Bandwidth benchmarks of a simulated network of 50 nodes: 10 relays forwarding
+// all envelopes, and 40 leaves, each of them interested in one of 8 topics and
+// connected to one relay. In every round, each leaf posts an envelope, and the
+// round completes when every leaf received the envelopes of its topic.
+const (
+	simRelays = 10
+	simLeaves = 40
+	simTopics = 8
+)
+
+// Leaves receive every envelope.
+func BenchmarkBandwidthFullNode(b *testing.B) {
+	benchmarkBandwidth(b, func(*Whisper, TopicType) {})
+}
+
+// Leaves receive the envelopes matching the bloom filter of their topic.
+func BenchmarkBandwidthBloomFilter(b *testing.B) {
+	benchmarkBandwidth(b, func(shh *Whisper, topic TopicType) {
+		shh.SetBloomFilter(TopicToBloom(topic))
+	})
+}
+
+// Leaves receive the envelopes of their topic only.
+func BenchmarkBandwidthTopicInterest(b *testing.B) {
+	benchmarkBandwidth(b, func(shh *Whisper, topic TopicType) {
+		shh.SetTopicInterest([]TopicType{topic})
+	})
+}
+
+func benchmarkBandwidth(b *testing.B, subscribe func(*Whisper, TopicType)) {
+	var (
+		bytes  uint64
+		relays = make([]*simNode, simRelays)
+		leaves = make([]*simNode, simLeaves)
+		topics = make([]TopicType, simTopics)
+		closes []func()
+	)
+	for i := range topics {
+		topics[i] = TopicType{0x5e, 0xed, byte(i), 0x00}
+	}
+	for i := range relays {
+		relays[i] = newSimNode()
+		defer relays[i].shh.Stop()
+	}
+	for i := range relays {
+		closes = append(closes, relays[i].connect(relays[(i+1)%simRelays], &bytes))
+		closes = append(closes, relays[i].connect(relays[(i+3)%simRelays], &bytes))
+	}
+	for i := range leaves {
+		leaves[i] = newSimNode()
+		defer leaves[i].shh.Stop()
+
+		subscribe(leaves[i].shh, topics[i%simTopics])
+		closes = append(closes, leaves[i].connect(relays[i%simRelays], &bytes))
+	}
+	defer func() {
+		for _, close := range closes {
+			close()
+		}
+	}()
+	// let the handshakes complete before measuring
+	time.Sleep(2 * transmissionCycle)
+	atomic.StoreUint64(&bytes, 0)
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		sent := make([][]*Envelope, simTopics)
+		for i, leaf := range leaves {
+			env := newSimEnvelope(topics[i%simTopics])
+			if err := leaf.shh.Send(env); err != nil {
+				b.Fatalf("failed to send envelope: %v", err)
+			}
+			sent[i%simTopics] = append(sent[i%simTopics], env)
+		}
+		for i, leaf := range leaves {
+			if !waitEnvelopes(leaf, sent[i%simTopics], 10*time.Second) {
+				b.Fatalf("leaf %d did not receive the envelopes of its topic", i)
+			}
+		}
+	}
+	b.StopTimer()
+
+	b.Logf("%d bytes transferred per envelope posted", atomic.LoadUint64(&bytes)/uint64(b.N*simLeaves))
+}
@@ -34,6 +34,8 @@ package whisperv6
 
 import (
 	"time"
+
+	"github.com/ethereum/go-ethereum/common"
 )
 
 // Whisper protocol parameters
@@ -43,13 +45,22 @@ const (
 	ProtocolName       = "shh"     // Nickname of the protocol in geth
 
 	// whisper protocol message codes, according to EIP-627
-	statusCode           = 0   // used by whisper protocol
-	messagesCode         = 1   // normal whisper message
-	powRequirementCode   = 2   // PoW requirement
-	bloomFilterExCode    = 3   // bloom filter exchange
-	p2pRequestCode       = 126 // peer-to-peer message, used by Dapp protocol
-	p2pMessageCode       = 127 // peer-to-peer message (to be consumed by the peer, but not forwarded any further)
-	NumberOfMessageCodes = 128
+	statusCode         = 0   // used by whisper protocol
+	messagesCode       = 1   // normal whisper message
+	powRequirementCode = 2   // PoW requirement
+	bloomFilterExCode  = 3   // bloom filter exchange
+	topicInterestCode  = 4   // topic interest exchange
+	p2pRequestCode     = 126 // peer-to-peer message, used by Dapp protocol
+	p2pMessageCode     = 127 // peer-to-peer message (to be consumed by the peer, but not forwarded any further)
+
+	// p2pRequestCompleteCode signals that a mail server has processed a p2p
+	// request. It is an extension outside of the code space defined by EIP-627,
+	// taken from the unused codes below the EIP-627 peer-to-peer codes. Peers not
+	// implementing it ignore the message like any other unknown code, so it needs
+	// no protocol version bump, but clients can't rely on receiving it from
+	// mail servers that don't support it.
+	p2pRequestCompleteCode = 125
+	NumberOfMessageCodes   = 128
 
 	SizeMask      = byte(3) // mask used to extract the size of payload size field from the flags
 	signatureFlag = byte(4)
@@ -73,6 +84,14 @@ const (
 
 	expirationCycle   = time.Second
 	transmissionCycle = 300 * time.Millisecond
+	scoreDecayCycle   = time.Minute
+
+	MaxTopicInterest = 10000 // maximum number of topics a peer may declare interest in
+
+	// peers sending too many invalid or duplicate envelopes are dropped
+	maxInvalidEnvelopes = 10  // number of invalid envelopes tolerated within a score decay cycle
+	maxDuplicateRatio   = 0.5 // ratio of envelopes the peer sent more than once
+	minScoreSample      = 20  // number of envelopes needed before the duplicate ratio is enforced
 
 	DefaultTTL           = 50 // seconds
 	DefaultSyncAllowance = 10 // seconds
@@ -83,8 +102,24 @@ const (
 // to the peers. Any implementation must ensure that both
 // functions are thread-safe. Also, they must return ASAP.
 // DeliverMail should use directMessagesCode for delivery,
-// in order to bypass the expiry checks.
+// in order to bypass the expiry checks, and signal the
+// completion of the request with SendMailServerResponse.
 type MailServer interface {
 	Archive(env *Envelope)
 	DeliverMail(whisperPeer *Peer, request *Envelope)
 }
+
+// MailServerResponse is sent by a mail server once it has finished processing
+// a request for historic messages.
+type MailServerResponse struct {
+	RequestID common.Hash // Hash of the request envelope
+	Cursor    []byte      // Cursor to request the next page with, empty if all messages were delivered
+	Error     string      // Reason the request was rejected, empty if it was served
+}
+
+// MailServerResponseEvent is posted when a trusted peer signals the completion
+// of a request for historic messages.
+type MailServerResponseEvent struct {
+	Peer     []byte              // ID of the mail server peer
+	Response *MailServerResponse // Response of the mail server
+}
@@ -132,6 +132,24 @@ func (fs *Filters) removeFromTopicMatchers(watcher *Filter) {
 	}
 }
 
+// topics returns the topics the installed filters are interested in, or nil if
+// some filter is interested in all of them.
+func (fs *Filters) topics() []TopicType {
+	fs.mutex.RLock()
+	defer fs.mutex.RUnlock()
+
+	if len(fs.allTopicsMatcher) > 0 {
+		return nil
+	}
+	topics := make([]TopicType, 0, len(fs.topicMatcher))
+	for topic, watchers := range fs.topicMatcher {
+		if len(watchers) > 0 {
+			topics = append(topics, topic)
+		}
+	}
+	return topics
+}
+
 // getWatchersByTopic returns a slice containing the filters that
 // match a specific topic
 func (fs *Filters) getWatchersByTopic(topic TopicType) []*Filter {
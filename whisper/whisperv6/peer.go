@@ -40,8 +40,11 @@ type Peer struct {
 	bloomMu        sync.Mutex
 	bloomFilter    []byte
 	fullNode       bool
+	topicInterest  map[TopicType]struct{} // Topics the peer is interested in, nil if it relies on its bloom filter
 
-	known mapset.Set // Messages already known by the peer to avoid wasting bandwidth
+	known    mapset.Set // Messages already known by the peer to avoid wasting bandwidth
+	received mapset.Set // Messages received from the peer to detect repeated ones
+	score    peerScore  // Quality of the envelopes received from the peer
 
 	quit chan struct{}
 }
@@ -55,6 +58,7 @@ func newPeer(host *Whisper, remote *p2p.Peer, rw p2p.MsgReadWriter) *Peer {
 		trusted:        false,
 		powRequirement: 0.0,
 		known:          mapset.NewSet(),
+		received:       mapset.NewSet(),
 		quit:           make(chan struct{}),
 		bloomFilter:    MakeFullNodeBloom(),
 		fullNode:       true,
@@ -85,8 +89,9 @@ func (peer *Peer) handshake() error {
 		pow := peer.host.MinPow()
 		powConverted := math.Float64bits(pow)
 		bloom := peer.host.BloomFilter()
+		topics := peer.host.TopicInterest()
 
-		errc <- p2p.SendItems(peer.ws, statusCode, ProtocolVersion, powConverted, bloom, isLightNode)
+		errc <- p2p.SendItems(peer.ws, statusCode, ProtocolVersion, powConverted, bloom, isLightNode, topics)
 	}()
 
 	// Fetch the remote status packet and verify protocol match
@@ -135,6 +140,14 @@ func (peer *Peer) handshake() error {
 		return fmt.Errorf("peer [%x] is useless: two light client communication restricted", peer.ID())
 	}
 
+	var topics []TopicType
+	if err := s.Decode(&topics); err == nil {
+		if len(topics) > MaxTopicInterest {
+			return fmt.Errorf("peer [%x] sent bad status message: too many topics of interest %d", peer.ID(), len(topics))
+		}
+		peer.setTopicInterest(topics)
+	}
+
 	if err := <-errc; err != nil {
 		return fmt.Errorf("peer [%x] failed to send status packet: %v", peer.ID(), err)
 	}
@@ -147,6 +160,7 @@ func (peer *Peer) update() {
 	// Start the tickers for the updates
 	expire := time.NewTicker(expirationCycle)
 	transmit := time.NewTicker(transmissionCycle)
+	decay := time.NewTicker(scoreDecayCycle)
 
 	// Loop and transmit until termination is requested
 	for {
@@ -160,6 +174,9 @@ func (peer *Peer) update() {
 				return
 			}
 
+		case <-decay.C:
+			peer.score.decay()
+
 		case <-peer.quit:
 			return
 		}
@@ -176,19 +193,27 @@ func (peer *Peer) marked(envelope *Envelope) bool {
 	return peer.known.Contains(envelope.Hash())
 }
 
-// expire iterates over all the known envelopes in the host and removes all
-// expired (unknown) ones from the known list.
+// receive records an envelope received from the peer, returning whether the
+// peer already sent the same envelope before.
+func (peer *Peer) receive(envelope *Envelope) bool {
+	return !peer.received.Add(envelope.Hash())
+}
+
+// expire iterates over all the known and received envelopes in the host and
+// removes all expired (unknown) ones from the lists.
 func (peer *Peer) expire() {
-	unmark := make(map[common.Hash]struct{})
-	peer.known.Each(func(v interface{}) bool {
-		if !peer.host.isEnvelopeCached(v.(common.Hash)) {
-			unmark[v.(common.Hash)] = struct{}{}
+	for _, set := range []mapset.Set{peer.known, peer.received} {
+		unmark := make(map[common.Hash]struct{})
+		set.Each(func(v interface{}) bool {
+			if !peer.host.isEnvelopeCached(v.(common.Hash)) {
+				unmark[v.(common.Hash)] = struct{}{}
+			}
+			return true
+		})
+		// Dump all known but no longer cached
+		for hash := range unmark {
+			set.Remove(hash)
 		}
-		return true
-	})
-	// Dump all known but no longer cached
-	for hash := range unmark {
-		peer.known.Remove(hash)
 	}
 }
 
@@ -198,7 +223,7 @@ func (peer *Peer) broadcast() error {
 	envelopes := peer.host.Envelopes()
 	bundle := make([]*Envelope, 0, len(envelopes))
 	for _, envelope := range envelopes {
-		if !peer.marked(envelope) && envelope.PoW() >= peer.powRequirement && peer.bloomMatch(envelope) {
+		if !peer.marked(envelope) && envelope.PoW() >= peer.powRequirement && peer.interested(envelope) {
 			bundle = append(bundle, envelope)
 		}
 	}
@@ -234,6 +259,10 @@ func (peer *Peer) notifyAboutBloomFilterChange(bloom []byte) error {
 	return p2p.Send(peer.ws, bloomFilterExCode, bloom)
 }
 
+func (peer *Peer) notifyAboutTopicInterestChange(topics []TopicType) error {
+	return p2p.Send(peer.ws, topicInterestCode, topics)
+}
+
 func (peer *Peer) bloomMatch(env *Envelope) bool {
 	peer.bloomMu.Lock()
 	defer peer.bloomMu.Unlock()
@@ -250,6 +279,40 @@ func (peer *Peer) setBloomFilter(bloom []byte) {
 	}
 }
 
+// interested checks if the peer wants to receive the envelope, by its topic
+// interest if it declared one, or else by its bloom filter.
+func (peer *Peer) interested(env *Envelope) bool {
+	peer.bloomMu.Lock()
+	interest := peer.topicInterest
+	peer.bloomMu.Unlock()
+
+	if interest == nil {
+		return peer.bloomMatch(env)
+	}
+	_, ok := interest[env.Topic]
+	return ok
+}
+
+// setTopicInterest sets the topics the peer is interested in, an empty list
+// meaning that it relies on its bloom filter.
+func (peer *Peer) setTopicInterest(topics []TopicType) {
+	var interest map[TopicType]struct{}
+	if len(topics) > 0 {
+		interest = make(map[TopicType]struct{}, len(topics))
+		for _, topic := range topics {
+			interest[topic] = struct{}{}
+		}
+	}
+	peer.bloomMu.Lock()
+	defer peer.bloomMu.Unlock()
+	peer.topicInterest = interest
+}
+
+// Score returns the quality statistics of the envelopes received from the peer.
+func (peer *Peer) Score() PeerScore {
+	return peer.score.snapshot()
+}
+
 func MakeFullNodeBloom() []byte {
 	bloom := make([]byte, BloomFilterSize)
 	for i := 0; i < BloomFilterSize; i++ {
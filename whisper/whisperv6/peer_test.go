@@ -20,6 +20,7 @@ import (
 	"bytes"
 	"crypto/ecdsa"
 	"fmt"
+	"io/ioutil"
 	mrand "math/rand"
 	"sync"
 	"sync/atomic"
@@ -566,3 +567,171 @@ func (stub *rwStub) ReadMsg() (p2p.Msg, error) {
 func (stub *rwStub) WriteMsg(m p2p.Msg) error {
 	return nil
 }
+
+func TestTopicInterest(t *testing.T) {
+	var (
+		bytes  uint64
+		topicA = TopicType{0x01, 0x02, 0x03, 0x04}
+		topicB = TopicType{0x05, 0x06, 0x07, 0x08}
+	)
+	relay, leaf, full := newSimNode(), newSimNode(), newSimNode()
+	defer relay.shh.Stop()
+	defer leaf.shh.Stop()
+	defer full.shh.Stop()
+
+	if err := leaf.shh.SetTopicInterest([]TopicType{topicA}); err != nil {
+		t.Fatalf("failed to set topic interest: %v", err)
+	}
+	defer leaf.connect(relay, &bytes)()
+	defer full.connect(relay, &bytes)()
+
+	envA, envB := newSimEnvelope(topicA), newSimEnvelope(topicB)
+	relay.shh.Send(envA)
+	relay.shh.Send(envB)
+
+	if !waitEnvelopes(full, []*Envelope{envA, envB}, 5*time.Second) {
+		t.Fatal("full node did not receive the envelopes")
+	}
+	if !waitEnvelopes(leaf, []*Envelope{envA}, 5*time.Second) {
+		t.Fatal("leaf did not receive the envelope of its topic")
+	}
+	time.Sleep(2 * transmissionCycle)
+	if leaf.shh.isEnvelopeCached(envB.Hash()) {
+		t.Fatal("leaf received an envelope outside of its topic interest")
+	}
+
+	// clearing the interest falls back to the bloom filter
+	if err := leaf.shh.SetTopicInterest(nil); err != nil {
+		t.Fatalf("failed to clear topic interest: %v", err)
+	}
+	if !waitEnvelopes(leaf, []*Envelope{envB}, 5*time.Second) {
+		t.Fatal("leaf did not receive the envelope after clearing its topic interest")
+	}
+}
+
+func TestLightClientTopicInterest(t *testing.T) {
+	shh := New(&DefaultConfig)
+	shh.SetLightClientMode(true)
+
+	if interest := shh.TopicInterest(); len(interest) != 0 {
+		t.Fatalf("topic interest mismatch: have %v, want none", interest)
+	}
+	topic := TopicType{0x01, 0x02, 0x03, 0x04}
+	id, err := shh.Subscribe(&Filter{KeySym: make([]byte, aesKeyLength), Topics: [][]byte{topic[:]}})
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	if interest := shh.TopicInterest(); len(interest) != 1 || interest[0] != topic {
+		t.Fatalf("topic interest mismatch: have %v, want [%v]", interest, topic)
+	}
+	if !BloomFilterMatch(shh.BloomFilter(), TopicToBloom(topic)) {
+		t.Fatal("bloom filter does not match the topic of interest")
+	}
+	if err := shh.Unsubscribe(id); err != nil {
+		t.Fatalf("failed to unsubscribe: %v", err)
+	}
+	if interest := shh.TopicInterest(); len(interest) != 0 {
+		t.Fatalf("topic interest mismatch after unsubscribe: have %v, want none", interest)
+	}
+}
+
+// latencyRW delivers the messages written to a message pipe asynchronously and
+// after a fixed delay, like a network link would, so that both ends of the pipe
+// may have messages in flight at the same time.
+type latencyRW struct {
+	p2p.MsgReadWriter
+	latency time.Duration
+	queue   chan latencyMsg
+}
+
+type latencyMsg struct {
+	msg     p2p.Msg
+	deliver time.Time
+}
+
+func newLatencyRW(rw p2p.MsgReadWriter, latency time.Duration) *latencyRW {
+	delayed := &latencyRW{MsgReadWriter: rw, latency: latency, queue: make(chan latencyMsg, 1024)}
+	go func() {
+		for req := range delayed.queue {
+			time.Sleep(time.Until(req.deliver))
+			if err := rw.WriteMsg(req.msg); err != nil {
+				return
+			}
+		}
+	}()
+	return delayed
+}
+
+func (rw *latencyRW) WriteMsg(msg p2p.Msg) error {
+	payload, err := ioutil.ReadAll(msg.Payload)
+	if err != nil {
+		return err
+	}
+	msg.Payload = bytes.NewReader(payload)
+	rw.queue <- latencyMsg{msg: msg, deliver: time.Now().Add(rw.latency)}
+	return nil
+}
+
+// Tests that relays connected in a triangle don't count the envelopes they
+// forward to each other at the same time as duplicates.
+func TestTriangleRelayDuplicates(t *testing.T) {
+	relays := []*simNode{newSimNode(), newSimNode(), newSimNode()}
+	for _, relay := range relays {
+		defer relay.shh.Stop()
+	}
+	for i := range relays {
+		local, remote := relays[i], relays[(i+1)%len(relays)]
+
+		rw1, rw2 := p2p.MsgPipe()
+		defer rw1.Close()
+		defer rw2.Close()
+
+		go local.shh.HandlePeer(p2p.NewPeer(remote.id, "", nil), newLatencyRW(rw1, transmissionCycle))
+		go remote.shh.HandlePeer(p2p.NewPeer(local.id, "", nil), newLatencyRW(rw2, transmissionCycle))
+	}
+	var envelopes []*Envelope
+	for i := 0; i < 4*minScoreSample; i++ {
+		env := newSimEnvelope(TopicType{})
+		if err := relays[i%len(relays)].shh.Send(env); err != nil {
+			t.Fatalf("failed to send envelope %d: %v", i, err)
+		}
+		envelopes = append(envelopes, env)
+	}
+	for i, relay := range relays {
+		if !waitEnvelopes(relay, envelopes, 5*time.Second) {
+			t.Fatalf("relay %d: did not receive all envelopes", i)
+		}
+	}
+	// Let the relays gossip the envelopes around for a few more cycles
+	time.Sleep(4 * transmissionCycle)
+
+	for i, relay := range relays {
+		scores := relay.shh.PeerScores()
+		if len(scores) != len(relays)-1 {
+			t.Fatalf("relay %d: peer count mismatch: have %d, want %d", i, len(scores), len(relays)-1)
+		}
+		for id, score := range scores {
+			if score.Duplicates != 0 {
+				t.Errorf("relay %d: duplicates from peer %x: have %v, want %v", i, id[:4], score.Duplicates, 0)
+			}
+		}
+	}
+}
+
+// Tests that only envelopes received from a peer more than once are counted as
+// duplicates, not the ones sent to it.
+func TestPeerReceiveDuplicates(t *testing.T) {
+	p := newPeer(New(&DefaultConfig), p2p.NewPeer(enode.ID{}, "test", nil), nil)
+
+	sent, received := newSimEnvelope(TopicType{}), newSimEnvelope(TopicType{})
+	p.mark(sent)
+	if p.receive(sent) {
+		t.Fatal("envelope sent to the peer counted as duplicate")
+	}
+	if p.receive(received) {
+		t.Fatal("first envelope received from the peer counted as duplicate")
+	}
+	if !p.receive(received) {
+		t.Fatal("envelope received twice from the peer not counted as duplicate")
+	}
+}
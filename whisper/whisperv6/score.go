@@ -0,0 +1,111 @@
+// Copyright 2026 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package whisperv6
+
+import (
+	"fmt"
+	"sync"
+)
+
+// PeerScore is a snapshot of the quality of the envelopes received from a peer.
+// The counters decay over time, so that they reflect the recent behaviour of
+// the peer rather than its whole history.
+type PeerScore struct {
+	Envelopes  float64 // Number of envelopes received
+	Invalid    float64 // Number of envelopes rejected as invalid
+	Duplicates float64 // Number of envelopes the peer sent more than once
+	PoW        float64 // Average PoW of the new envelopes received
+}
+
+// Rank returns a single figure to order the peers by: the average PoW of the
+// new envelopes, discounted by the rate of invalid and duplicate envelopes.
+func (s PeerScore) Rank() float64 {
+	if s.Envelopes == 0 {
+		return 0
+	}
+	return s.PoW * (1 - (s.Invalid+s.Duplicates)/s.Envelopes)
+}
+
+// peerScore tracks the quality of the envelopes received from a peer.
+type peerScore struct {
+	lock       sync.Mutex
+	envelopes  float64
+	invalid    float64
+	duplicates float64
+	pow        float64 // total PoW of the new envelopes
+	fresh      float64 // number of new envelopes
+}
+
+// record accounts for an envelope received from the peer.
+func (s *peerScore) record(env *Envelope, duplicate, invalid bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.envelopes++
+	switch {
+	case invalid:
+		s.invalid++
+	case duplicate:
+		s.duplicates++
+	default:
+		s.pow += env.PoW()
+		s.fresh++
+	}
+}
+
+// abusive returns an error if the peer sent too many invalid or duplicate
+// envelopes recently.
+func (s *peerScore) abusive() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.invalid > maxInvalidEnvelopes {
+		return fmt.Errorf("too many invalid envelopes: %.0f", s.invalid)
+	}
+	if s.envelopes >= minScoreSample && s.duplicates/s.envelopes > maxDuplicateRatio {
+		return fmt.Errorf("too many duplicate envelopes: %.0f of %.0f", s.duplicates, s.envelopes)
+	}
+	return nil
+}
+
+// decay halves the counters, so that past behaviour weighs less and less.
+func (s *peerScore) decay() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.envelopes /= 2
+	s.invalid /= 2
+	s.duplicates /= 2
+	s.pow /= 2
+	s.fresh /= 2
+}
+
+// snapshot returns the current statistics.
+func (s *peerScore) snapshot() PeerScore {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	score := PeerScore{
+		Envelopes:  s.envelopes,
+		Invalid:    s.invalid,
+		Duplicates: s.duplicates,
+	}
+	if s.fresh > 0 {
+		score.PoW = s.pow / s.fresh
+	}
+	return score
+}
@@ -0,0 +1,86 @@
+// Copyright 2026 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package whisperv6
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPeerScoreInvalid(t *testing.T) {
+	var score peerScore
+	env := newSimEnvelope(TopicType{})
+
+	for i := 0; i < maxInvalidEnvelopes; i++ {
+		score.record(env, false, true)
+	}
+	if err := score.abusive(); err != nil {
+		t.Fatalf("peer dropped within the invalid envelope allowance: %v", err)
+	}
+	score.record(env, false, true)
+	if err := score.abusive(); err == nil {
+		t.Fatal("peer not dropped after too many invalid envelopes")
+	}
+	// the invalid envelopes are forgiven over time
+	score.decay()
+	if err := score.abusive(); err != nil {
+		t.Fatalf("peer dropped after score decay: %v", err)
+	}
+}
+
+func TestPeerScoreDuplicates(t *testing.T) {
+	var score peerScore
+	env := newSimEnvelope(TopicType{})
+
+	// a high duplicate rate is tolerated until enough envelopes were received
+	for i := 0; i < minScoreSample-1; i++ {
+		score.record(env, true, false)
+	}
+	if err := score.abusive(); err != nil {
+		t.Fatalf("peer dropped before the minimum sample: %v", err)
+	}
+	score.record(env, true, false)
+	if err := score.abusive(); err == nil {
+		t.Fatal("peer not dropped after too many duplicate envelopes")
+	}
+	// enough new envelopes restore the ratio
+	for i := 0; i < minScoreSample; i++ {
+		score.record(env, false, false)
+	}
+	if err := score.abusive(); err != nil {
+		t.Fatalf("peer dropped with a tolerable duplicate rate: %v", err)
+	}
+}
+
+func TestPeerScoreRank(t *testing.T) {
+	var good, bad peerScore
+	env := newSimEnvelope(TopicType{})
+
+	for i := 0; i < 10; i++ {
+		good.record(env, false, false)
+		bad.record(env, i%2 == 0, false)
+	}
+	if have := good.snapshot().PoW; math.Abs(have-env.PoW()) > 1e-9*env.PoW() {
+		t.Fatalf("average PoW mismatch: have %v, want %v", have, env.PoW())
+	}
+	if good.snapshot().Rank() <= bad.snapshot().Rank() {
+		t.Fatalf("rank mismatch: have %v <= %v", good.snapshot().Rank(), bad.snapshot().Rank())
+	}
+	if have := (PeerScore{}).Rank(); have != 0 {
+		t.Fatalf("rank of unknown peer mismatch: have %v, want 0", have)
+	}
+}
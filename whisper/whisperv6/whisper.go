@@ -29,8 +29,10 @@ import (
 	mapset "github.com/deckarep/golang-set"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/syndtr/goleveldb/leveldb/errors"
@@ -57,6 +59,7 @@ const (
 	bloomFilterToleranceIdx                         // Bloom filter tolerated by the whisper node for a limited time
 	lightClientModeIdx                              // Light client mode. (does not forward any messages)
 	restrictConnectionBetweenLightClientsIdx        // Restrict connection between two light clients
+	topicInterestIdx                                // Topics of interest for this node
 )
 
 // Whisper represents a dark communication interface through the Ethereum
@@ -87,7 +90,8 @@ type Whisper struct {
 	statsMu sync.Mutex // guard stats
 	stats   Statistics // Statistics of whisper node
 
-	mailServer MailServer // MailServer interface
+	mailServer         MailServer // MailServer interface
+	mailServerResponse event.Feed // Feed of the request completion signals sent by mail servers
 }
 
 // New creates a Whisper client ready to communicate through the Ethereum P2P network.
@@ -252,6 +256,41 @@ func (whisper *Whisper) SetBloomFilter(bloom []byte) error {
 	return nil
 }
 
+// TopicInterest returns the topics this node is interested in, or nil if it
+// relies on its bloom filter.
+func (whisper *Whisper) TopicInterest() []TopicType {
+	val, exist := whisper.settings.Load(topicInterestIdx)
+	if !exist || val == nil {
+		return nil
+	}
+	return val.([]TopicType)
+}
+
+// SetTopicInterest limits the envelopes forwarded to this node by its peers
+// to the given topics, which is more precise than a bloom filter. An empty list
+// restores the forwarding by bloom filter. The bloom filter is extended to
+// match the topics if necessary.
+func (whisper *Whisper) SetTopicInterest(topics []TopicType) error {
+	if len(topics) > MaxTopicInterest {
+		return fmt.Errorf("too many topics of interest: %d", len(topics))
+	}
+
+	interest := make([]TopicType, len(topics))
+	copy(interest, topics)
+
+	aggregate := make([]byte, BloomFilterSize)
+	for _, t := range interest {
+		aggregate = addBloom(aggregate, TopicToBloom(t))
+	}
+	if !BloomFilterMatch(whisper.BloomFilter(), aggregate) {
+		whisper.SetBloomFilter(addBloom(whisper.BloomFilter(), aggregate))
+	}
+
+	whisper.settings.Store(topicInterestIdx, interest)
+	whisper.notifyPeersAboutTopicInterestChange(interest)
+	return nil
+}
+
 // SetMinimumPoW sets the minimal PoW required by this node
 func (whisper *Whisper) SetMinimumPoW(val float64) error {
 	if val < 0.0 {
@@ -280,6 +319,9 @@ func (whisper *Whisper) SetMinimumPowTest(val float64) {
 //SetLightClientMode makes node light client (does not forward any messages)
 func (whisper *Whisper) SetLightClientMode(v bool) {
 	whisper.settings.Store(lightClientModeIdx, v)
+	if v {
+		whisper.updateTopicInterest()
+	}
 }
 
 //LightClientMode indicates is this node is light client (does not forward any messages)
@@ -330,6 +372,30 @@ func (whisper *Whisper) notifyPeersAboutBloomFilterChange(bloom []byte) {
 	}
 }
 
+func (whisper *Whisper) notifyPeersAboutTopicInterestChange(topics []TopicType) {
+	arr := whisper.getPeers()
+	for _, p := range arr {
+		err := p.notifyAboutTopicInterestChange(topics)
+		if err != nil {
+			// allow one retry
+			err = p.notifyAboutTopicInterestChange(topics)
+		}
+		if err != nil {
+			log.Warn("failed to notify peer about new topic interest", "peer", p.ID(), "error", err)
+		}
+	}
+}
+
+// PeerScores returns the quality statistics of the envelopes received from
+// every connected peer.
+func (whisper *Whisper) PeerScores() map[enode.ID]PeerScore {
+	scores := make(map[enode.ID]PeerScore)
+	for _, p := range whisper.getPeers() {
+		scores[p.peer.ID()] = p.Score()
+	}
+	return scores
+}
+
 func (whisper *Whisper) getPeers() []*Peer {
 	arr := make([]*Peer, len(whisper.peers))
 	i := 0
@@ -369,7 +435,9 @@ func (whisper *Whisper) AllowP2PMessagesFromPeer(peerID []byte) error {
 // RequestHistoricMessages sends a message with p2pRequestCode to a specific peer,
 // which is known to implement MailServer interface, and is supposed to process this
 // request and respond with a number of peer-to-peer messages (possibly expired),
-// which are not supposed to be forwarded any further.
+// which are not supposed to be forwarded any further, followed by a response
+// delivered to SubscribeMailServerResponses once the request is processed (if
+// the mail server supports signalling it, see p2pRequestCompleteCode).
 // The whisper protocol is agnostic of the format and contents of envelope.
 func (whisper *Whisper) RequestHistoricMessages(peerID []byte, envelope *Envelope) error {
 	p, err := whisper.getPeer(peerID)
@@ -380,6 +448,18 @@ func (whisper *Whisper) RequestHistoricMessages(peerID []byte, envelope *Envelop
 	return p2p.Send(p.ws, p2pRequestCode, envelope)
 }
 
+// SendMailServerResponse signals a peer that its request for historic messages
+// was processed by the mail server.
+func (whisper *Whisper) SendMailServerResponse(peer *Peer, response *MailServerResponse) error {
+	return p2p.Send(peer.ws, p2pRequestCompleteCode, response)
+}
+
+// SubscribeMailServerResponses subscribes to the completion signals of the
+// requests for historic messages sent by trusted mail servers.
+func (whisper *Whisper) SubscribeMailServerResponses(ch chan<- MailServerResponseEvent) event.Subscription {
+	return whisper.mailServerResponse.Subscribe(ch)
+}
+
 // SendP2PMessage sends a peer-to-peer message to a specific peer.
 func (whisper *Whisper) SendP2PMessage(peerID []byte, envelope *Envelope) error {
 	p, err := whisper.getPeer(peerID)
@@ -577,6 +657,9 @@ func (whisper *Whisper) Subscribe(f *Filter) (string, error) {
 	s, err := whisper.filters.Install(f)
 	if err == nil {
 		whisper.updateBloomFilter(f)
+		if whisper.LightClientMode() {
+			whisper.updateTopicInterest()
+		}
 	}
 	return s, err
 }
@@ -598,6 +681,19 @@ func (whisper *Whisper) updateBloomFilter(f *Filter) {
 	}
 }
 
+// updateTopicInterest declares the topics of the installed filters as the topic
+// interest of the node, so that light clients only receive what they watch.
+func (whisper *Whisper) updateTopicInterest() {
+	if whisper.filters == nil {
+		return
+	}
+	topics := whisper.filters.topics()
+	if len(topics) > MaxTopicInterest {
+		topics = nil
+	}
+	whisper.SetTopicInterest(topics)
+}
+
 // GetFilter returns the filter by id.
 func (whisper *Whisper) GetFilter(id string) *Filter {
 	return whisper.filters.Get(id)
@@ -609,6 +705,9 @@ func (whisper *Whisper) Unsubscribe(id string) error {
 	if !ok {
 		return fmt.Errorf("Unsubscribe: Invalid ID")
 	}
+	if whisper.LightClientMode() {
+		whisper.updateTopicInterest()
+	}
 	return nil
 }
 
@@ -693,23 +792,28 @@ func (whisper *Whisper) runMessageLoop(p *Peer, rw p2p.MsgReadWriter) error {
 			var envelopes []*Envelope
 			if err := packet.Decode(&envelopes); err != nil {
 				log.Warn("failed to decode envelopes, peer will be disconnected", "peer", p.peer.ID(), "err", err)
+				p.peer.Report(p2p.InvalidMessage)
 				return errors.New("invalid envelopes")
 			}
 
-			trouble := false
 			for _, env := range envelopes {
+				// Envelopes we sent to the peer may cross the same ones coming
+				// back, only count those the peer itself sent repeatedly
+				duplicate := p.receive(env)
 				cached, err := whisper.add(env, whisper.LightClientMode())
 				if err != nil {
-					trouble = true
-					log.Error("bad envelope received, peer will be disconnected", "peer", p.peer.ID(), "err", err)
+					log.Debug("bad envelope received", "peer", p.peer.ID(), "err", err)
 				}
 				if cached {
 					p.mark(env)
 				}
+				p.score.record(env, duplicate, err != nil)
 			}
 
-			if trouble {
-				return errors.New("invalid envelope")
+			if err := p.score.abusive(); err != nil {
+				log.Warn("abusive peer will be disconnected", "peer", p.peer.ID(), "err", err)
+				p.peer.Report(p2p.InvalidMessage)
+				return err
 			}
 		case powRequirementCode:
 			s := rlp.NewStream(packet.Payload, uint64(packet.Size))
@@ -736,6 +840,18 @@ func (whisper *Whisper) runMessageLoop(p *Peer, rw p2p.MsgReadWriter) error {
 				return errors.New("invalid bloom filter exchange message")
 			}
 			p.setBloomFilter(bloom)
+		case topicInterestCode:
+			var topics []TopicType
+			err := packet.Decode(&topics)
+			if err == nil && len(topics) > MaxTopicInterest {
+				err = fmt.Errorf("too many topics of interest %d", len(topics))
+			}
+
+			if err != nil {
+				log.Warn("failed to decode topic interest message, peer will be disconnected", "peer", p.peer.ID(), "err", err)
+				return errors.New("invalid topic interest message")
+			}
+			p.setTopicInterest(topics)
 		case p2pMessageCode:
 			// peer-to-peer message, sent directly to peer bypassing PoW checks, etc.
 			// this message is not supposed to be forwarded to other peers, and
@@ -749,6 +865,17 @@ func (whisper *Whisper) runMessageLoop(p *Peer, rw p2p.MsgReadWriter) error {
 				}
 				whisper.postEvent(&envelope, true)
 			}
+		case p2pRequestCompleteCode:
+			// signals that a mail server has finished processing a request,
+			// only accepted from the trusted peer like the delivered messages.
+			if p.trusted {
+				var response MailServerResponse
+				if err := packet.Decode(&response); err != nil {
+					log.Warn("failed to decode mail server response, peer will be disconnected", "peer", p.peer.ID(), "err", err)
+					return errors.New("invalid mail server response")
+				}
+				whisper.mailServerResponse.Send(MailServerResponseEvent{Peer: p.ID(), Response: &response})
+			}
 		case p2pRequestCode:
 			// Must be processed if mail server is implemented. Otherwise ignore.
 			if whisper.mailServer != nil {